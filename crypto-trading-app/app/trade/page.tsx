@@ -84,8 +84,11 @@ function TradeContent() {
     try {
       setPlacing(true)
       
+      // The server prices the order from a signed quote, not from the displayed price
+      const quote = await ordersApiService.getQuote("buy", selectedCrypto.symbol)
+
       // Calculate total cost
-      const totalCost = qty * selectedCrypto.price
+      const totalCost = qty * parseFloat(quote.price)
       
       // Create order payload
       const orderData: OrderRequest = {
@@ -93,7 +96,7 @@ function TradeContent() {
         crypto_symbol: selectedCrypto.symbol,
         quantity: qty.toString(),
         order_kind: "market",
-        market_price: selectedCrypto.price.toString()  // Send current market price from frontend
+        quote_id: quote.quote_id
       }
       
       console.log('=== BUY ORDER PLACED ===')
@@ -171,8 +174,11 @@ function TradeContent() {
     try {
       setPlacing(true)
       
+      // The server prices the order from a signed quote, not from the displayed price
+      const quote = await ordersApiService.getQuote("sell", selectedCrypto.symbol)
+
       // Calculate total value
-      const totalValue = qty * selectedCrypto.price
+      const totalValue = qty * parseFloat(quote.price)
       
       // Create order payload
       const orderData: OrderRequest = {
@@ -180,7 +186,7 @@ function TradeContent() {
         crypto_symbol: selectedCrypto.symbol,
         quantity: qty.toString(),
         order_kind: "market",
-        market_price: selectedCrypto.price.toString()  // Send current market price from frontend
+        quote_id: quote.quote_id
       }
       
       console.log('=== SELL ORDER PLACED ===')
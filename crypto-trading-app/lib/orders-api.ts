@@ -6,7 +6,18 @@ export interface OrderRequest {
   quantity: string
   order_kind: "market" | "limit"  // Changed from order_type to order_kind
   limit_price?: string  // Price for limit orders
-  market_price?: string  // Current market price from frontend
+  quote_id?: string  // Signed quote from getQuote (market orders)
+  max_slippage?: string  // Max accepted deviation from the quoted price, e.g. "0.01" = 1%
+}
+
+export interface QuoteResponse {
+  quote_id: string
+  user_id: number
+  crypto_symbol: string
+  type: "buy" | "sell"
+  price: string
+  issued_at: string
+  expires_at: string
 }
 
 export interface OrderResponse {
@@ -30,6 +41,36 @@ class OrdersApiService {
     this.baseUrl = process.env.NEXT_PUBLIC_ORDERS_API_URL || 'http://localhost:8002'
   }
 
+  // Market orders are priced by the server: request a short-lived signed quote first
+  async getQuote(type: "buy" | "sell", cryptoSymbol: string): Promise<QuoteResponse> {
+    try {
+      const response = await fetch(`${this.baseUrl}/api/v1/orders/quotes`, {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${localStorage.getItem('crypto_access_token')}`
+        },
+        body: JSON.stringify({ type, crypto_symbol: cryptoSymbol })
+      })
+
+      if (!response.ok) {
+        let errorMessage = 'Failed to get quote'
+        try {
+          const errorData = await response.json()
+          errorMessage = errorData.message || errorData.error || errorMessage
+        } catch (parseError) {
+          errorMessage = response.statusText || errorMessage
+        }
+        throw new Error(errorMessage)
+      }
+
+      return await response.json()
+    } catch (error) {
+      console.error('Error getting quote:', error)
+      throw error
+    }
+  }
+
   async createOrder(orderData: OrderRequest): Promise<OrderResponse> {
     try {
       const response = await fetch(`${this.baseUrl}/api/v1/orders`, {
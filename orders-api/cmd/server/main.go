@@ -54,6 +54,9 @@ func main() {
 	defer db.Close()
 
 	orderRepo := repositories.NewOrderRepository(db)
+	outboxRepo := repositories.NewOutboxRepository(db)
+	fillRepo := repositories.NewFillRepository(db)
+	planRepo := repositories.NewRecurringPlanRepository(db)
 
 	// Test database connection
 	if err := db.Client.Ping(ctx, nil); err != nil {
@@ -90,18 +93,25 @@ func main() {
 	logger.Info("⚙️ Initializing business services (simplified)...")
 
 	// Initialize portfolio client (optional - won't fail if not available)
-	portfolioClient := clients.NewPortfolioClient(&clients.PortfolioClientConfig{
-		BaseURL: os.Getenv("PORTFOLIO_API_BASE_URL"),
-		APIKey:  os.Getenv("PORTFOLIO_API_KEY"),
-		Timeout: 10 * time.Second,
-	})
+	portfolioClient := clients.NewPortfolioClient(cfg.ToPortfolioClientConfig())
+
+	// Fee schedule: config defaults until an admin saves one, shared by all replicas
+	feeService := services.NewFeeService(
+		repositories.NewFeeScheduleRepository(db),
+		orderRepo,
+		cfg.ToDefaultFeeSchedule(),
+		services.FeeServiceConfig{
+			VolumeWindow: cfg.Fee.VolumeWindow,
+			CacheTTL:     cfg.Fee.CacheTTL,
+		},
+	)
 
 	// Create execution service (simplified - no concurrency)
 	executionService := services.NewExecutionService(
 		userClient,
 		userBalanceClient,
 		marketClient,
-		nil, // No necesitamos fee calculator separado
+		feeService,
 	)
 	
 	// Set portfolio client in execution service
@@ -110,12 +120,31 @@ func main() {
 	// Create market service adapter
 	marketService := &marketServiceAdapter{marketClient: marketClient}
 
-	// Create event publisher adapter (puede ser nil)
+	// Pre-trade risk limits per user role: config defaults until an admin saves a policy
+	riskService := services.NewRiskService(
+		repositories.NewRiskPolicyRepository(db),
+		orderRepo,
+		executionService,
+		marketService,
+		cfg.ToDefaultRiskPolicy(),
+		services.RiskServiceConfig{CacheTTL: cfg.Risk.CacheTTL},
+	)
+
+	// Create event publisher: with the outbox, events are written in the same
+	// transaction as the order and the relay publishes them to RabbitMQ
 	var eventPublisher services.EventPublisher
-	if publisher != nil {
+	var planPublisher services.PlanEventPublisher
+	switch {
+	case cfg.Outbox.Enabled:
+		outboxPublisher := messaging.NewOutboxPublisher(outboxRepo)
+		eventPublisher = outboxPublisher
+		planPublisher = outboxPublisher
+	case publisher != nil:
 		eventPublisher = &eventPublisherAdapter{publisher: publisher}
-	} else {
+		planPublisher = publisher
+	default:
 		eventPublisher = &noopPublisher{} // No-op si no hay RabbitMQ
+		planPublisher = &noopPublisher{}
 	}
 
 	// Initialize simplified order service (no orchestrator, no workers)
@@ -125,15 +154,129 @@ func main() {
 		marketService,
 		eventPublisher,
 	)
+	if cfg.Outbox.Enabled {
+		// Requires MongoDB running as a replica set
+		orderService.SetTransactor(db)
+	}
+	orderService.SetFillRepository(fillRepo)
+	// Market orders are priced from signed server-side quotes, never from the client
+	orderService.SetQuoteService(services.NewQuoteService(marketService, services.QuoteServiceConfig{
+		Secret:   cfg.Quote.SigningSecret,
+		TTL:      cfg.Quote.TTL,
+		Required: cfg.Quote.Required,
+	}))
+	orderService.SetDefaultMaxSlippage(cfg.Quote.MaxSlippage)
+	if cfg.Risk.Enabled {
+		orderService.SetRiskChecker(riskService)
+	}
+
+	// Recurring buy (DCA) plans place market orders through the order service
+	planService := services.NewRecurringPlanService(planRepo, orderService, marketService, planPublisher)
 
 	logger.Info("✅ Business services initialized (simplified, no concurrency)")
 
+	// Start limit order matcher (safe to run on every replica)
+	if cfg.Matcher.Enabled {
+		matcher := services.NewLimitOrderMatcher(
+			orderRepo,
+			marketClient,
+			orderService,
+			services.LimitOrderMatcherConfig{
+				Interval:  cfg.Matcher.Interval,
+				BatchSize: cfg.Matcher.BatchSize,
+				LockTTL:   cfg.Matcher.LockTTL,
+			},
+		)
+		matcher.Start(ctx)
+		defer matcher.Stop()
+		logger.Infof("🎯 Limit order matcher started (interval: %s)", cfg.Matcher.Interval)
+	}
+
+	// Start execution saga recoverer (resumes or compensates executions interrupted by a crash)
+	if cfg.Saga.RecoveryEnabled {
+		recoverer := services.NewSagaRecoverer(
+			orderRepo,
+			orderService.ExecutionSaga(),
+			services.SagaRecovererConfig{
+				Interval:   cfg.Saga.RecoveryInterval,
+				StaleAfter: cfg.Saga.StaleAfter,
+				BatchSize:  cfg.Saga.BatchSize,
+				LockTTL:    cfg.Saga.LockTTL,
+			},
+		)
+		recoverer.Start(ctx)
+		defer recoverer.Stop()
+		logger.Infof("♻️ Execution saga recoverer started (stale after: %s)", cfg.Saga.StaleAfter)
+	}
+
+	// Start order expiry sweeper (expires gtd orders past expires_at and releases their funds)
+	if cfg.Expiry.Enabled {
+		sweeper := services.NewOrderExpirySweeper(
+			orderRepo,
+			orderService,
+			services.OrderExpirySweeperConfig{
+				Interval:       cfg.Expiry.Interval,
+				BatchSize:      cfg.Expiry.BatchSize,
+				LockTTL:        cfg.Expiry.LockTTL,
+				ImmediateGrace: cfg.Expiry.ImmediateGrace,
+			},
+		)
+		sweeper.Start(ctx)
+		defer sweeper.Stop()
+		logger.Infof("⌛ Order expiry sweeper started (interval: %s)", cfg.Expiry.Interval)
+	}
+
+	// Start recurring plan scheduler (places the market order of every due plan run)
+	if cfg.RecurringPlans.Enabled {
+		scheduler := services.NewRecurringPlanScheduler(
+			planRepo,
+			planService,
+			services.RecurringPlanSchedulerConfig{
+				Interval:  cfg.RecurringPlans.Interval,
+				BatchSize: cfg.RecurringPlans.BatchSize,
+				LockTTL:   cfg.RecurringPlans.LockTTL,
+			},
+		)
+		scheduler.Start(ctx)
+		defer scheduler.Stop()
+		logger.Infof("🔁 Recurring plan scheduler started (interval: %s)", cfg.RecurringPlans.Interval)
+	}
+
+	// Start outbox relay (publishes stored order events with publisher confirms)
+	if cfg.Outbox.Enabled {
+		if publisher != nil {
+			relay := services.NewOutboxRelay(
+				outboxRepo,
+				publisher,
+				services.OutboxRelayConfig{
+					Interval:       cfg.Outbox.RelayInterval,
+					BatchSize:      cfg.Outbox.BatchSize,
+					LockTTL:        cfg.Outbox.LockTTL,
+					MaxAttempts:    cfg.Outbox.MaxAttempts,
+					RetryBaseDelay: cfg.Outbox.RetryBaseDelay,
+					RetryMaxDelay:  cfg.Outbox.RetryMaxDelay,
+				},
+			)
+			relay.Start(ctx)
+			defer relay.Stop()
+			logger.Infof("📤 Outbox relay started (interval: %s)", cfg.Outbox.RelayInterval)
+		} else {
+			logger.Warn("RabbitMQ not available: order events stay in the outbox until the relay can publish them")
+		}
+	}
+
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(cfg.ToAuthConfig())
 	loggingMiddleware := middleware.NewLoggingMiddleware(logger, cfg.ToLoggingConfig())
+	auditRepo := repositories.NewAdminAuditRepository(db)
+	auditMiddleware := middleware.NewAuditMiddleware(auditRepo, logger)
 
 	// Initialize handlers
 	orderHandler := handlers.NewOrderHandler(orderService)
+	feeHandler := handlers.NewFeeHandler(feeService)
+	riskHandler := handlers.NewRiskHandler(riskService)
+	planHandler := handlers.NewRecurringPlanHandler(planService)
+	auditHandler := handlers.NewAuditHandler(auditRepo)
 	healthHandler := handlers.NewHealthHandler(
 		orderRepo,
 		userClient,
@@ -147,9 +290,14 @@ func main() {
 	logger.Info("🛣️ Setting up HTTP routes...")
 	router := routes.NewRouter(
 		orderHandler,
+		feeHandler,
+		riskHandler,
+		planHandler,
+		auditHandler,
 		healthHandler,
 		authMiddleware,
 		loggingMiddleware,
+		auditMiddleware,
 		&routes.RouterConfig{
 			Debug:          cfg.Server.Debug,
 			CORSEnabled:    cfg.Server.CORSEnabled,
@@ -387,14 +535,26 @@ func (e *eventPublisherAdapter) PublishOrderExecuted(ctx context.Context, order
 	return e.publisher.PublishOrderExecuted(ctx, order)
 }
 
+func (e *eventPublisherAdapter) PublishOrderPartiallyFilled(ctx context.Context, order *Order) error {
+	return e.publisher.PublishOrderPartiallyFilled(ctx, order)
+}
+
 func (e *eventPublisherAdapter) PublishOrderCancelled(ctx context.Context, order *Order, reason string) error {
 	return e.publisher.PublishOrderCancelled(ctx, order, reason)
 }
 
+func (e *eventPublisherAdapter) PublishOrderAmended(ctx context.Context, order *Order, previous *Order) error {
+	return e.publisher.PublishOrderAmended(ctx, order, previous)
+}
+
 func (e *eventPublisherAdapter) PublishOrderFailed(ctx context.Context, order *Order, reason string) error {
 	return e.publisher.PublishOrderFailed(ctx, order, reason)
 }
 
+func (e *eventPublisherAdapter) PublishOrderExpired(ctx context.Context, order *Order, reason string) error {
+	return e.publisher.PublishOrderExpired(ctx, order, reason)
+}
+
 // noopPublisher is a no-op publisher when RabbitMQ is not available
 type noopPublisher struct{}
 
@@ -408,15 +568,35 @@ func (n *noopPublisher) PublishOrderExecuted(ctx context.Context, order *Order)
 	return nil
 }
 
+func (n *noopPublisher) PublishOrderPartiallyFilled(ctx context.Context, order *Order) error {
+	log.Println("No-op: Order partially filled event (RabbitMQ not available)")
+	return nil
+}
+
 func (n *noopPublisher) PublishOrderCancelled(ctx context.Context, order *Order, reason string) error {
 	log.Println("No-op: Order cancelled event (RabbitMQ not available)")
 	return nil
 }
 
+func (n *noopPublisher) PublishOrderAmended(ctx context.Context, order *Order, previous *Order) error {
+	log.Println("No-op: Order amended event (RabbitMQ not available)")
+	return nil
+}
+
 func (n *noopPublisher) PublishOrderFailed(ctx context.Context, order *Order, reason string) error {
 	log.Println("No-op: Order failed event (RabbitMQ not available)")
 	return nil
 }
 
+func (n *noopPublisher) PublishOrderExpired(ctx context.Context, order *Order, reason string) error {
+	log.Println("No-op: Order expired event (RabbitMQ not available)")
+	return nil
+}
+
+func (n *noopPublisher) PublishPlanRunSkipped(ctx context.Context, plan *models.RecurringPlan, run *models.PlanRun) error {
+	log.Println("No-op: Recurring plan run skipped event (RabbitMQ not available)")
+	return nil
+}
+
 // Type alias to avoid import issues
 type Order = models.Order
@@ -3,6 +3,7 @@ module orders-api
 go 1.21
 
 require (
+	cryptosim/jwks v0.0.0
 	github.com/gin-contrib/cors v1.4.0
 	github.com/gin-gonic/gin v1.9.1
 	github.com/golang-jwt/jwt/v4 v4.5.2
@@ -51,3 +52,5 @@ require (
 	google.golang.org/protobuf v1.30.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace cryptosim/jwks => ../pkg/jwks
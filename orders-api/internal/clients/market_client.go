@@ -226,7 +226,8 @@ func (c *MarketClient) GetCandlestickData(ctx context.Context, symbol string, in
 	return candleResp.Candles, nil
 }
 
-func (c *MarketClient) GetOrderBook(ctx context.Context, symbol string, depth int) (*OrderBookData, error) {
+// GetOrderBook obtiene los primeros depth niveles del libro de symbol
+func (c *MarketClient) GetOrderBook(ctx context.Context, symbol string, depth int) (*models.OrderBook, error) {
 	url := fmt.Sprintf("%s/api/market/orderbook/%s?depth=%d", c.baseURL, symbol, depth)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -243,6 +244,10 @@ func (c *MarketClient) GetOrderBook(ctx context.Context, symbol string, depth in
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("order book not available for %s: status %d", symbol, resp.StatusCode)
+	}
+
 	var orderBookResp OrderBookResponse
 	if err := json.NewDecoder(resp.Body).Decode(&orderBookResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -252,7 +257,27 @@ func (c *MarketClient) GetOrderBook(ctx context.Context, symbol string, depth in
 		return nil, fmt.Errorf("market service error: %s", orderBookResp.Error)
 	}
 
-	return orderBookResp.OrderBook, nil
+	if orderBookResp.OrderBook == nil {
+		return nil, fmt.Errorf("order book not available for %s", symbol)
+	}
+
+	return orderBookResp.OrderBook.toModel(), nil
+}
+
+// toModel convierte la respuesta al libro que usa la ejecución
+func (d *OrderBookData) toModel() *models.OrderBook {
+	book := &models.OrderBook{Symbol: d.Symbol}
+	for _, level := range d.Bids {
+		if level != nil {
+			book.Bids = append(book.Bids, models.OrderBookLevel{Price: level.Price, Quantity: level.Quantity})
+		}
+	}
+	for _, level := range d.Asks {
+		if level != nil {
+			book.Asks = append(book.Asks, models.OrderBookLevel{Price: level.Price, Quantity: level.Quantity})
+		}
+	}
+	return book
 }
 
 func (c *MarketClient) GetMultiplePrices(ctx context.Context, symbols []string) (map[string]*models.PriceResult, error) {
@@ -5,32 +5,53 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/shopspring/decimal"
+	"orders-api/internal/models"
 )
 
-// PortfolioClient handles communication with Portfolio API
+// PortfolioClient handles communication with Portfolio API. Internal calls carry a
+// service token from Users API with the holdings scopes.
 type PortfolioClient struct {
 	baseURL    string
 	httpClient *http.Client
-	apiKey     string
+	tokens     *ServiceTokenSource
 }
 
-// PortfolioClientConfig configuration for portfolio client
+// PortfolioClientConfig configuration for portfolio client. TokenURL is the Users
+// API that exchanges the orders-api credentials for service tokens.
 type PortfolioClientConfig struct {
-	BaseURL string
-	APIKey  string
-	Timeout time.Duration
+	BaseURL       string
+	TokenURL      string
+	ServiceName   string
+	ServiceSecret string
+	Timeout       time.Duration
 }
 
 // UpdateHoldingRequest request payload to update holdings
 type UpdateHoldingRequest struct {
-	Symbol     string  `json:"symbol"`
-	Quantity   float64 `json:"quantity"`
-	Price      float64 `json:"price"`
-	OrderType  string  `json:"order_type"` // "buy" or "sell"
+	Symbol        string  `json:"symbol"`
+	Quantity      float64 `json:"quantity"`
+	Price         float64 `json:"price"`
+	OrderType     string  `json:"order_type"` // "buy" or "sell"
+	OrderID       string  `json:"order_id,omitempty"`
+	ReservationID string  `json:"reservation_id,omitempty"` // Partial fills: reservation the sell is taken from
+}
+
+// ReserveHoldingRequest request payload to reserve holdings for a sell order
+type ReserveHoldingRequest struct {
+	Quantity float64 `json:"quantity"`
+}
+
+// HoldingResponse holding quantities as returned by Portfolio API
+type HoldingResponse struct {
+	Symbol            string          `json:"symbol"`
+	Quantity          decimal.Decimal `json:"quantity"`
+	ReservedQuantity  decimal.Decimal `json:"reserved_quantity"`
+	AvailableQuantity decimal.Decimal `json:"available_quantity"`
 }
 
 // NewPortfolioClient creates a new portfolio client
@@ -39,57 +60,158 @@ func NewPortfolioClient(config *PortfolioClientConfig) *PortfolioClient {
 		config.Timeout = 10 * time.Second
 	}
 
+	httpClient := &http.Client{
+		Timeout: config.Timeout,
+	}
+
 	return &PortfolioClient{
-		baseURL: config.BaseURL,
-		apiKey:  config.APIKey,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		baseURL:    config.BaseURL,
+		httpClient: httpClient,
+		tokens:     NewServiceTokenSource(config.TokenURL, config.ServiceName, config.ServiceSecret, ScopesPortfolio, httpClient),
 	}
 }
 
-// UpdateHoldings updates a user's holdings after an order execution
+// UpdateHoldings updates a user's holdings after an order execution (or one pass of a
+// partially filled order). tradeID makes the update idempotent; for sells, portfolio-api
+// consumes the quantity from the reservation taken for reservationID.
 // This signature matches the PortfolioClient interface in execution_service.go
-func (c *PortfolioClient) UpdateHoldings(ctx context.Context, userID int64, symbol string, quantity, price decimal.Decimal, orderType string) error {
-	url := fmt.Sprintf("%s/api/portfolio/%d/holdings", c.baseURL, userID)
+func (c *PortfolioClient) UpdateHoldings(ctx context.Context, userID int64, symbol string, quantity, price decimal.Decimal, orderType, tradeID, reservationID string) error {
+	path := fmt.Sprintf("/api/internal/portfolio/%d/holdings", userID)
 
 	req := UpdateHoldingRequest{
 		Symbol:    symbol,
 		Quantity:  quantity.InexactFloat64(),
 		Price:     price.InexactFloat64(),
 		OrderType: orderType,
+		OrderID:   tradeID,
+	}
+	if reservationID != tradeID {
+		req.ReservationID = reservationID
 	}
 
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+	if err := c.doRequest(ctx, "POST", path, req, nil); err != nil {
+		return fmt.Errorf("failed to update holdings: %w", err)
+	}
+
+	fmt.Printf("✅ Portfolio holdings updated: User %d, %s %s @ %f\n",
+		userID, orderType, symbol, price.InexactFloat64())
+	return nil
+}
+
+// ReserveHoldings sets the quantity of symbol reserved for a pending sell order.
+// The reservation is keyed by orderID, so retrying (or resizing after an amend) is safe.
+// Returns an "insufficient holdings" error if the user does not own enough of the asset.
+func (c *PortfolioClient) ReserveHoldings(ctx context.Context, userID int64, symbol, orderID string, quantity decimal.Decimal) error {
+	path := fmt.Sprintf("/api/internal/portfolio/%d/holdings/%s/reservations/%s", userID, symbol, orderID)
+
+	req := ReserveHoldingRequest{
+		Quantity: quantity.InexactFloat64(),
+	}
+
+	if err := c.doRequest(ctx, "PUT", path, req, nil); err != nil {
+		return fmt.Errorf("failed to reserve holdings: %w", err)
+	}
+
+	return nil
+}
+
+// GetHolding returns the quantities of symbol owned by the user, including what is
+// reserved by pending sell orders. A user without the asset gets zero quantities.
+func (c *PortfolioClient) GetHolding(ctx context.Context, userID int64, symbol string) (*models.HoldingResult, error) {
+	path := fmt.Sprintf("/api/internal/portfolio/%d/holdings/%s", userID, symbol)
+
+	var resp HoldingResponse
+	if err := c.doRequest(ctx, "GET", path, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get holding: %w", err)
+	}
+
+	return &models.HoldingResult{
+		Symbol:    resp.Symbol,
+		Quantity:  resp.Quantity,
+		Reserved:  resp.ReservedQuantity,
+		Available: resp.AvailableQuantity,
+	}, nil
+}
+
+// ReleaseHoldings frees the holdings reserved for a sell order that will not execute
+func (c *PortfolioClient) ReleaseHoldings(ctx context.Context, userID int64, symbol, orderID string) error {
+	path := fmt.Sprintf("/api/internal/portfolio/%d/holdings/%s/reservations/%s", userID, symbol, orderID)
+
+	if err := c.doRequest(ctx, "DELETE", path, nil, nil); err != nil {
+		return fmt.Errorf("failed to release holdings: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	return nil
+}
+
+// RevertHoldings undoes the holdings change applied for orderID.
+// If the order was never applied, portfolio-api ignores any later update for it.
+func (c *PortfolioClient) RevertHoldings(ctx context.Context, userID int64, orderID string) error {
+	path := fmt.Sprintf("/api/internal/portfolio/%d/trades/%s/revert", userID, orderID)
+
+	if err := c.doRequest(ctx, "POST", path, nil, nil); err != nil {
+		return fmt.Errorf("failed to revert holdings: %w", err)
+	}
+
+	return nil
+}
+
+// doRequest sends an authenticated internal request to Portfolio API and decodes the
+// response into out when it is not nil
+func (c *PortfolioClient) doRequest(ctx context.Context, method, path string, payload interface{}, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get service token: %w", err)
+	}
+
+	httpReq.Header.Set("Authorization", "Bearer "+token)
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("X-Internal-Service", "orders-api")
-	httpReq.Header.Set("X-API-Key", c.apiKey)
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		fmt.Printf("⚠️ Portfolio API connection failed (non-critical): %v\n", err)
-		return nil // Don't fail the order if portfolio update fails
+		return fmt.Errorf("portfolio API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		fmt.Printf("⚠️ Portfolio API returned status %d (non-critical)\n", resp.StatusCode)
-		return nil // Don't fail the order if portfolio update fails
+	if resp.StatusCode == http.StatusUnauthorized {
+		// The token may be signed with a key Users API no longer publishes;
+		// the next request asks for a new one
+		c.tokens.Invalidate()
 	}
 
-	fmt.Printf("✅ Portfolio holdings updated: User %d, %s %s @ %f\n", 
-		userID, orderType, symbol, price.InexactFloat64())
-	return nil
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusNoContent {
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("failed to decode portfolio API response: %w", err)
+			}
+		}
+		return nil
+	}
+
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+
+	if resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("insufficient holdings: %s", apiErr.Error)
+	}
+
+	return fmt.Errorf("portfolio API returned status %d: %s", resp.StatusCode, apiErr.Error)
 }
 
 // HealthCheck verifies connectivity with Portfolio API
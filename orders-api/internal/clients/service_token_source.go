@@ -0,0 +1,112 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// serviceTokenRefreshMargin renueva el token un poco antes de que venza, para no
+// mandar a Users API un token que expira en tránsito
+const serviceTokenRefreshMargin = time.Minute
+
+// Scopes que pide cada cliente: un token por servicio destino, así el token que
+// recibe un servicio no sirve para llamar al otro
+const (
+	ScopesUsers     = "user:verify"
+	ScopesBalance   = "balance:read balance:write"
+	ScopesPortfolio = "holdings:read holdings:write"
+)
+
+// ServiceTokenSource obtiene de Users API el token de servicio con el que
+// orders-api llama a los endpoints internos (de Users API y de Portfolio API).
+// Cambia las credenciales propias del servicio por un token con los scopes
+// pedidos y lo reutiliza hasta poco antes de que expire.
+type ServiceTokenSource struct {
+	baseURL    string
+	service    string
+	secret     string
+	scope      string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+type serviceTokenResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		Scope       string `json:"scope"`
+	} `json:"data"`
+	Error string `json:"error"`
+}
+
+// NewServiceTokenSource pide los tokens a la Users API de baseURL. scope son los
+// scopes separados por espacio; vacío pide todos los del servicio.
+func NewServiceTokenSource(baseURL, service, secret, scope string, httpClient *http.Client) *ServiceTokenSource {
+	return &ServiceTokenSource{
+		baseURL:    baseURL,
+		service:    service,
+		secret:     secret,
+		scope:      scope,
+		httpClient: httpClient,
+	}
+}
+
+// Token devuelve el token vigente o pide uno nuevo
+func (s *ServiceTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Until(s.expiresAt) > serviceTokenRefreshMargin {
+		return s.token, nil
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"service": s.service,
+		"secret":  s.secret,
+		"scope":   s.scope,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.baseURL+"/api/internal/token", bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokenResp serviceTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK || tokenResp.Data.AccessToken == "" {
+		return "", fmt.Errorf("users API rejected service credentials (status %d): %s", resp.StatusCode, tokenResp.Error)
+	}
+
+	s.token = tokenResp.Data.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(tokenResp.Data.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// Invalidate descarta el token en cache, por ejemplo cuando Users API lo rechazó
+func (s *ServiceTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
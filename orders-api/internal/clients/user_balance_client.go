@@ -18,13 +18,16 @@ import (
 type UserBalanceClient struct {
 	baseURL    string
 	httpClient *http.Client
-	apiKey     string
+	tokens     *ServiceTokenSource
 }
 
+// UserBalanceConfig incluye las credenciales propias de orders-api, que se
+// cambian en Users API por un token de servicio para los endpoints internos
 type UserBalanceConfig struct {
-	BaseURL string
-	APIKey  string
-	Timeout time.Duration
+	BaseURL       string
+	ServiceName   string
+	ServiceSecret string
+	Timeout       time.Duration
 }
 
 type APIResponse struct {
@@ -33,17 +36,19 @@ type APIResponse struct {
 }
 
 type UserBalanceResponse struct {
-	ID             int32   `json:"id"`
-	Username       string  `json:"username"`
-	Email          string  `json:"email"`
-	FirstName      *string `json:"first_name"`
-	LastName       *string `json:"last_name"`
-	Role           string  `json:"role"`
-	InitialBalance float64 `json:"initial_balance"`
-	CreatedAt      string  `json:"created_at"`
-	UpdatedAt      string  `json:"updated_at"`
-	LastLogin      *string `json:"last_login"`
-	IsActive       bool    `json:"is_active"`
+	ID               int32   `json:"id"`
+	Username         string  `json:"username"`
+	Email            string  `json:"email"`
+	FirstName        *string `json:"first_name"`
+	LastName         *string `json:"last_name"`
+	Role             string  `json:"role"`
+	InitialBalance   float64 `json:"initial_balance"`
+	ReservedBalance  float64 `json:"reserved_balance"`
+	AvailableBalance float64 `json:"available_balance"`
+	CreatedAt        string  `json:"created_at"`
+	UpdatedAt        string  `json:"updated_at"`
+	LastLogin        *string `json:"last_login"`
+	IsActive         bool    `json:"is_active"`
 }
 
 func NewUserBalanceClient(config *UserBalanceConfig) *UserBalanceClient {
@@ -51,27 +56,40 @@ func NewUserBalanceClient(config *UserBalanceConfig) *UserBalanceClient {
 		config.Timeout = 15 * time.Second
 	}
 
+	httpClient := &http.Client{
+		Timeout: config.Timeout,
+	}
+
 	return &UserBalanceClient{
-		baseURL: config.BaseURL,
-		apiKey:  config.APIKey,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		baseURL:    config.BaseURL,
+		httpClient: httpClient,
+		tokens:     NewServiceTokenSource(config.BaseURL, config.ServiceName, config.ServiceSecret, ScopesBalance, httpClient),
 	}
 }
 
 // CheckBalance verifica si el usuario tiene suficiente balance para la orden
 func (c *UserBalanceClient) CheckBalance(ctx context.Context, userID int, amount decimal.Decimal, userToken string) (*models.BalanceResult, error) {
-	// Obtener información del usuario desde Users API
-	user, err := c.GetUser(ctx, userID, userToken)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
-	}
+	var availableBalance decimal.Decimal
+	if userToken != "" {
+		// Obtener información del usuario desde Users API
+		user, err := c.GetUser(ctx, userID, userToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
 
-	fmt.Printf("💰 CheckBalance: User %d, InitialBalance: %f\n", userID, user.InitialBalance)
+		fmt.Printf("💰 CheckBalance: User %d, InitialBalance: %f, Available: %f\n", userID, user.InitialBalance, user.AvailableBalance)
 
-	// Convertir balance a decimal
-	availableBalance := decimal.NewFromFloat(user.InitialBalance)
+		// Convertir balance disponible (sin fondos reservados) a decimal
+		availableBalance = decimal.NewFromFloat(user.AvailableBalance)
+	} else {
+		// Sin token del usuario (workers en segundo plano) se usa el endpoint
+		// interno con el token de servicio
+		summary, err := c.GetBalance(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get balance: %w", err)
+		}
+		availableBalance = decimal.NewFromFloat(summary.Available)
+	}
 
 	// Verificar si tiene suficiente balance
 	hasSufficient := availableBalance.GreaterThanOrEqual(amount)
@@ -93,55 +111,130 @@ func (c *UserBalanceClient) CheckBalance(ctx context.Context, userID int, amount
 	return result, nil
 }
 
-// LockFunds simula el lock de fondos (no hace nada real en el sistema simplificado)
-func (c *UserBalanceClient) LockFunds(ctx context.Context, userID int, amount decimal.Decimal) error {
-	// En el sistema simplificado, solo verificamos que tenga suficiente balance
-	balance, err := c.CheckBalance(ctx, userID, amount, "")
-	if err != nil {
-		return fmt.Errorf("failed to check balance: %w", err)
-	}
+// HoldResponse respuesta de los endpoints internos de holds de Users API
+type HoldResponse struct {
+	ID             int32   `json:"id"`
+	UserID         int32   `json:"user_id"`
+	OrderID        string  `json:"order_id"`
+	Amount         float64 `json:"amount"`
+	CapturedAmount float64 `json:"captured_amount"`
+	Status         string  `json:"status"`
+	TransactionID  string  `json:"transaction_id"`
+}
+
+type holdAPIResponse struct {
+	Success bool         `json:"success"`
+	Data    HoldResponse `json:"data"`
+	Error   string       `json:"error"`
+}
 
-	if !balance.HasSufficient {
-		return fmt.Errorf("insufficient balance: required %s, available %s",
-			balance.Required.String(), balance.Available.String())
+// LockFunds reserva fondos en Users API para una orden de compra pendiente.
+// La reserva se identifica por orderID, por lo que reintentar es seguro.
+func (c *UserBalanceClient) LockFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) error {
+	path := fmt.Sprintf("/api/users/%d/balance/holds", userID)
+	payload := map[string]interface{}{
+		"order_id": orderID,
+		"amount":   amount.InexactFloat64(),
 	}
 
-	// En un sistema real, aquí se actualizaría el balance del usuario
-	// Por ahora, solo logueamos la operación
-	fmt.Printf("LOCK FUNDS: User %d, Amount %s USD\n", userID, amount.String())
+	if _, err := c.doHoldRequest(ctx, "POST", path, payload); err != nil {
+		return fmt.Errorf("failed to lock funds: %w", err)
+	}
 
 	return nil
 }
 
-// ReleaseFunds simula el release de fondos (no hace nada real en el sistema simplificado)
-func (c *UserBalanceClient) ReleaseFunds(ctx context.Context, userID int, amount decimal.Decimal) error {
-	// En el sistema simplificado, solo logueamos la operación
-	fmt.Printf("RELEASE FUNDS: User %d, Amount %s USD\n", userID, amount.String())
+// AdjustFunds cambia el monto reservado de una orden pendiente que fue modificada
+func (c *UserBalanceClient) AdjustFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) error {
+	path := fmt.Sprintf("/api/users/%d/balance/holds/%s", userID, orderID)
+	payload := map[string]interface{}{
+		"amount": amount.InexactFloat64(),
+	}
+
+	if _, err := c.doHoldRequest(ctx, "PUT", path, payload); err != nil {
+		return fmt.Errorf("failed to adjust funds: %w", err)
+	}
+
 	return nil
 }
 
-// UpdateBalance actualiza el balance del usuario en la base de datos
-func (c *UserBalanceClient) UpdateBalance(ctx context.Context, userID int, newBalance decimal.Decimal) error {
-	url := fmt.Sprintf("%s/api/users/%d/balance", c.baseURL, userID)
+// CaptureFunds convierte la reserva de la orden en un débito por el monto final
+func (c *UserBalanceClient) CaptureFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) (string, error) {
+	path := fmt.Sprintf("/api/users/%d/balance/holds/%s/capture", userID, orderID)
+	payload := map[string]interface{}{
+		"amount": amount.InexactFloat64(),
+	}
 
-	payload := map[string]float64{
-		"amount": newBalance.InexactFloat64(),
+	hold, err := c.doHoldRequest(ctx, "POST", path, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to capture funds: %w", err)
 	}
 
-	jsonData, err := json.Marshal(payload)
+	return hold.TransactionID, nil
+}
+
+// CaptureFundsPartial debita el monto de un fill de la orden y achica su reserva en la
+// misma medida; la reserva sigue activa por lo que falta llenar. Users API aplica cada
+// fillID una sola vez, así que se puede reintentar.
+func (c *UserBalanceClient) CaptureFundsPartial(ctx context.Context, userID int, orderID, fillID string, amount decimal.Decimal) (string, error) {
+	path := fmt.Sprintf("/api/users/%d/balance/holds/%s/capture", userID, orderID)
+	payload := map[string]interface{}{
+		"amount":  amount.InexactFloat64(),
+		"fill_id": fillID,
+	}
+
+	hold, err := c.doHoldRequest(ctx, "POST", path, payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return "", fmt.Errorf("failed to capture funds: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, strings.NewReader(string(jsonData)))
+	return hold.TransactionID, nil
+}
+
+// ReleaseFunds libera la reserva de una orden cancelada o fallida
+func (c *UserBalanceClient) ReleaseFunds(ctx context.Context, userID int, orderID string) error {
+	path := fmt.Sprintf("/api/users/%d/balance/holds/%s/release", userID, orderID)
+
+	if _, err := c.doHoldRequest(ctx, "POST", path, nil); err != nil {
+		return fmt.Errorf("failed to release funds: %w", err)
+	}
+
+	return nil
+}
+
+// doHoldRequest envía un request a los endpoints internos de holds
+func (c *UserBalanceClient) doHoldRequest(ctx context.Context, method, path string, payload interface{}) (*HoldResponse, error) {
+	var apiResponse holdAPIResponse
+	if err := c.doInternalRequest(ctx, method, path, payload, &apiResponse, &apiResponse.Error); err != nil {
+		return nil, err
+	}
+	return &apiResponse.Data, nil
+}
+
+// doInternalRequest envía un request autenticado como servicio interno y decodifica
+// la respuesta en out. errMsg apunta al campo de error de out.
+func (c *UserBalanceClient) doInternalRequest(ctx context.Context, method, path string, payload interface{}, out interface{}, errMsg *string) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = strings.NewReader(string(jsonData))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get service token: %w", err)
+	}
+
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Internal-Service", "orders-api")
-	req.Header.Set("X-API-Key", c.apiKey)
+	req.Header.Set("Authorization", "Bearer "+token)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -149,68 +242,113 @@ func (c *UserBalanceClient) UpdateBalance(ctx context.Context, userID int, newBa
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		fmt.Printf("❌ UpdateBalance response status %d: %s\n", resp.StatusCode, string(bodyBytes))
-		return fmt.Errorf("failed to update balance: status %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusUnauthorized {
+		// El token pudo quedar firmado con una clave que Users API ya no publica;
+		// el próximo request pide uno nuevo
+		c.tokens.Invalidate()
 	}
 
-	fmt.Printf("✅ Balance updated: User %d, New Balance %s USD\n", userID, newBalance.String())
-	return nil
-}
-
-// ProcessTransaction procesa una transacción actualizando el balance del usuario
-func (c *UserBalanceClient) ProcessTransaction(ctx context.Context, userID int, amount decimal.Decimal, transactionType, orderID, description string) (string, error) {
-	fmt.Printf("💰 ProcessTransaction called: User %d, Type %s, Amount %s\n", userID, transactionType, amount.String())
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response (status %d): %w", resp.StatusCode, err)
+	}
 
-	// Obtener token del contexto
-	userToken := ""
-	if token := ctx.Value("user_token"); token != nil {
-		userToken = token.(string)
+	if resp.StatusCode == http.StatusConflict && strings.Contains(*errMsg, "insufficient funds") {
+		return fmt.Errorf("insufficient balance: %s", *errMsg)
 	}
 
-	// Obtener usuario actual
-	user, err := c.GetUser(ctx, userID, userToken)
-	if err != nil {
-		return "", fmt.Errorf("failed to get user: %w", err)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("users API returned status %d: %s", resp.StatusCode, *errMsg)
 	}
 
-	// Calcular nuevo balance
-	currentBalance := decimal.NewFromFloat(user.InitialBalance)
-	var newBalance decimal.Decimal
+	return nil
+}
 
+// TransactionResponse resultado de aplicar un movimiento en el ledger de Users API
+type TransactionResponse struct {
+	TransactionID string  `json:"transaction_id"`
+	UserID        int32   `json:"user_id"`
+	Type          string  `json:"type"`
+	Delta         float64 `json:"delta"`
+	BalanceAfter  float64 `json:"balance_after"`
+	OrderID       string  `json:"order_id"`
+}
+
+type transactionAPIResponse struct {
+	Success bool                `json:"success"`
+	Data    TransactionResponse `json:"data"`
+	Error   string              `json:"error"`
+}
+
+// ProcessTransaction aplica un movimiento de balance en Users API.
+// Users API suma el delta dentro de una transacción con lock de fila y lo
+// registra en su ledger, por lo que órdenes concurrentes no pisan el saldo.
+// Si hay orderID se envía una idempotency key derivada de la orden, así un
+// reintento no aplica el movimiento dos veces.
+func (c *UserBalanceClient) ProcessTransaction(ctx context.Context, userID int, amount decimal.Decimal, transactionType, orderID, description string) (string, error) {
+	var delta decimal.Decimal
 	switch transactionType {
 	case "buy", "purchase":
 		// Para compras, restamos el monto del balance
-		newBalance = currentBalance.Sub(amount)
+		transactionType = "buy"
+		delta = amount.Neg()
 	case "sell", "sale":
 		// Para ventas, sumamos el monto al balance
-		newBalance = currentBalance.Add(amount)
+		transactionType = "sell"
+		delta = amount
+	case "adjustment":
+		// Ajustes compensatorios: el monto ya viene con signo
+		delta = amount
 	default:
 		return "", fmt.Errorf("unknown transaction type: %s", transactionType)
 	}
 
-	// Verificar que el balance no sea negativo
-	if newBalance.LessThan(decimal.Zero) {
-		return "", fmt.Errorf("insufficient balance: would result in negative balance")
+	payload := map[string]interface{}{
+		"type":        transactionType,
+		"delta":       delta.InexactFloat64(),
+		"order_id":    orderID,
+		"description": description,
+	}
+	if orderID != "" {
+		payload["idempotency_key"] = fmt.Sprintf("order-%s-%s", orderID, transactionType)
 	}
 
-	// Actualizar el balance en la base de datos
-	fmt.Printf("🔄 Processing transaction: User %d, Type %s, Amount %s, Current Balance %s, New Balance %s\n",
-		userID, transactionType, amount.String(), currentBalance.String(), newBalance.String())
-
-	if err := c.UpdateBalance(ctx, userID, newBalance); err != nil {
-		fmt.Printf("❌ Failed to update balance: %v\n", err)
-		return "", fmt.Errorf("failed to update balance: %w", err)
+	var apiResponse transactionAPIResponse
+	path := fmt.Sprintf("/api/users/%d/balance/transactions", userID)
+	if err := c.doInternalRequest(ctx, "POST", path, payload, &apiResponse, &apiResponse.Error); err != nil {
+		return "", fmt.Errorf("failed to process transaction: %w", err)
 	}
 
-	// Generar ID de transacción simulado
-	transactionID := fmt.Sprintf("tx_%d_%d", userID, time.Now().Unix())
+	fmt.Printf("✅ Transaction %s applied: User %d, Type %s, Delta %s, New Balance %.2f\n",
+		apiResponse.Data.TransactionID, userID, transactionType, delta.String(), apiResponse.Data.BalanceAfter)
+
+	return apiResponse.Data.TransactionID, nil
+}
+
+// BalanceSummary desglose del balance que devuelve el endpoint interno de Users API
+type BalanceSummary struct {
+	UserID    int32   `json:"user_id"`
+	Total     float64 `json:"total"`
+	Reserved  float64 `json:"reserved"`
+	Available float64 `json:"available"`
+}
+
+type balanceAPIResponse struct {
+	Success bool           `json:"success"`
+	Data    BalanceSummary `json:"data"`
+	Error   string         `json:"error"`
+}
 
-	return transactionID, nil
+// GetBalance obtiene el balance del usuario con el token de servicio (scope balance:read)
+func (c *UserBalanceClient) GetBalance(ctx context.Context, userID int) (*BalanceSummary, error) {
+	var apiResponse balanceAPIResponse
+	path := fmt.Sprintf("/api/users/%d/balance", userID)
+	if err := c.doInternalRequest(ctx, "GET", path, nil, &apiResponse, &apiResponse.Error); err != nil {
+		return nil, err
+	}
+	return &apiResponse.Data, nil
 }
 
-// GetUser obtiene la información del usuario desde Users API
+// GetUser obtiene la información del usuario desde Users API con el token del usuario
 func (c *UserBalanceClient) GetUser(ctx context.Context, userID int, userToken string) (*UserBalanceResponse, error) {
 	url := fmt.Sprintf("%s/api/users/%d", c.baseURL, userID)
 
@@ -219,12 +357,7 @@ func (c *UserBalanceClient) GetUser(ctx context.Context, userID int, userToken s
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Use user token if provided, otherwise use API key
-	authToken := c.apiKey
-	if userToken != "" {
-		authToken = userToken
-	}
-	req.Header.Set("Authorization", "Bearer "+authToken)
+	req.Header.Set("Authorization", "Bearer "+userToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
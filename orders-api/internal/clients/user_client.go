@@ -13,13 +13,14 @@ import (
 type UserClient struct {
 	baseURL    string
 	httpClient *http.Client
-	apiKey     string
+	tokens     *ServiceTokenSource
 }
 
 type UserClientConfig struct {
-	BaseURL string
-	APIKey  string
-	Timeout time.Duration
+	BaseURL       string
+	ServiceName   string
+	ServiceSecret string
+	Timeout       time.Duration
 }
 
 type UserResponse struct {
@@ -28,6 +29,14 @@ type UserResponse struct {
 	Error  string    `json:"error,omitempty"`
 }
 
+// userVerificationResponse respuesta de /api/users/:id/verify
+type userVerificationResponse struct {
+	Exists   bool   `json:"exists"`
+	UserID   int    `json:"user_id"`
+	Role     string `json:"role"`
+	IsActive bool   `json:"is_active"`
+}
+
 type UserData struct {
 	ID           int    `json:"id"`
 	Email        string `json:"email"`
@@ -48,13 +57,26 @@ func NewUserClient(config *UserClientConfig) *UserClient {
 		config.Timeout = 10 * time.Second
 	}
 
+	httpClient := &http.Client{
+		Timeout: config.Timeout,
+	}
+
 	return &UserClient{
-		baseURL: config.BaseURL,
-		apiKey:  config.APIKey,
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
+		baseURL:    config.BaseURL,
+		httpClient: httpClient,
+		tokens:     NewServiceTokenSource(config.BaseURL, config.ServiceName, config.ServiceSecret, ScopesUsers, httpClient),
+	}
+}
+
+// authorize agrega el token de servicio de orders-api al request
+func (c *UserClient) authorize(ctx context.Context, req *http.Request) error {
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get service token: %w", err)
 	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
 }
 
 func (c *UserClient) VerifyUser(ctx context.Context, userID int) (*models.ValidationResult, error) {
@@ -65,7 +87,9 @@ func (c *UserClient) VerifyUser(ctx context.Context, userID int) (*models.Valida
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if err := c.authorize(ctx, req); err != nil {
+		return nil, err
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
@@ -74,39 +98,36 @@ func (c *UserClient) VerifyUser(ctx context.Context, userID int) (*models.Valida
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return &models.ValidationResult{
-			IsValid: false,
-			Message: fmt.Sprintf("user service returned status %d", resp.StatusCode),
-		}, nil
+	if resp.StatusCode == http.StatusUnauthorized {
+		c.tokens.Invalidate()
 	}
 
-	var userResp UserResponse
-	if err := json.NewDecoder(resp.Body).Decode(&userResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	// Un 404 es un usuario que no existe; cualquier otro status es una falla de
+	// Users API y se reporta como error, no como usuario inválido
+	if resp.StatusCode == http.StatusNotFound {
+		return &models.ValidationResult{IsValid: false, Message: "user not found"}, nil
 	}
-
-	if userResp.Error != "" {
-		return &models.ValidationResult{
-			IsValid: false,
-			Message: userResp.Error,
-		}, nil
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user service returned status %d", resp.StatusCode)
 	}
 
-	if userResp.User == nil {
-		return &models.ValidationResult{
-			IsValid: false,
-			Message: "user not found",
-		}, nil
+	var verification userVerificationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&verification); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	validationResult := &models.ValidationResult{
-		IsValid: c.validateUserStatus(userResp.User),
-		UserID:  userResp.User.ID,
-		Message: c.getValidationMessage(userResp.User),
+	switch {
+	case !verification.Exists:
+		return &models.ValidationResult{IsValid: false, Message: "user not found"}, nil
+	case !verification.IsActive:
+		return &models.ValidationResult{IsValid: false, UserID: verification.UserID, Message: "user account is inactive"}, nil
 	}
 
-	return validationResult, nil
+	return &models.ValidationResult{
+		IsValid: true,
+		UserID:  verification.UserID,
+		Message: "user is valid for trading",
+	}, nil
 }
 
 func (c *UserClient) GetUserProfile(ctx context.Context, userID int) (*UserData, error) {
@@ -117,7 +138,9 @@ func (c *UserClient) GetUserProfile(ctx context.Context, userID int) (*UserData,
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if err := c.authorize(ctx, req); err != nil {
+		return nil, err
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
@@ -150,7 +173,9 @@ func (c *UserClient) CheckUserPermissions(ctx context.Context, userID int, actio
 		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	if err := c.authorize(ctx, req); err != nil {
+		return false, err
+	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
@@ -183,42 +208,6 @@ func (c *UserClient) CheckUserPermissions(ctx context.Context, userID int, actio
 	return result.Allowed, nil
 }
 
-func (c *UserClient) validateUserStatus(user *UserData) bool {
-	if !user.IsActive || user.IsSuspended {
-		return false
-	}
-
-	if user.Status != "active" {
-		return false
-	}
-
-	if user.KYCStatus != "verified" && user.KYCStatus != "approved" {
-		return false
-	}
-
-	return true
-}
-
-func (c *UserClient) getValidationMessage(user *UserData) string {
-	if !user.IsActive {
-		return "user account is inactive"
-	}
-
-	if user.IsSuspended {
-		return "user account is suspended"
-	}
-
-	if user.Status != "active" {
-		return fmt.Sprintf("user status is %s", user.Status)
-	}
-
-	if user.KYCStatus != "verified" && user.KYCStatus != "approved" {
-		return fmt.Sprintf("user KYC status is %s", user.KYCStatus)
-	}
-
-	return "user is valid for trading"
-}
-
 func (c *UserClient) HealthCheck(ctx context.Context) error {
 	url := fmt.Sprintf("%s/health", c.baseURL)
 
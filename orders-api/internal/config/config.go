@@ -9,16 +9,25 @@ import (
 	"github.com/shopspring/decimal"
 	"orders-api/internal/clients"
 	"orders-api/internal/middleware"
+	"orders-api/internal/models"
 )
 
 type Config struct {
-	Server    *ServerConfig    `json:"server"`
-	Database  *DatabaseConfig  `json:"database"`
-	Auth      *AuthConfig      `json:"auth"`
-	Logging   *LoggingConfig   `json:"logging"`
-	Messaging *MessagingConfig `json:"messaging"`
-	Clients   *ClientsConfig   `json:"clients"`
-	// Execution y Fee configs ya no se usan en sistema simplificado
+	Server         *ServerConfig         `json:"server"`
+	Database       *DatabaseConfig       `json:"database"`
+	Auth           *AuthConfig           `json:"auth"`
+	Logging        *LoggingConfig        `json:"logging"`
+	Messaging      *MessagingConfig      `json:"messaging"`
+	Clients        *ClientsConfig        `json:"clients"`
+	Matcher        *MatcherConfig        `json:"matcher"`
+	Saga           *SagaConfig           `json:"saga"`
+	Expiry         *ExpiryConfig         `json:"expiry"`
+	RecurringPlans *RecurringPlansConfig `json:"recurring_plans"`
+	Outbox         *OutboxConfig         `json:"outbox"`
+	Quote          *QuoteConfig          `json:"quote"`
+	Fee            *FeeConfig            `json:"fee"`
+	Risk           *RiskConfig           `json:"risk"`
+	// Execution config ya no se usa en sistema simplificado
 	// Worker config ya no se usa (sin orchestrator)
 }
 
@@ -47,7 +56,8 @@ type DatabaseConfig struct {
 }
 
 type AuthConfig struct {
-	SecretKey       string   `json:"secret_key"`
+	JWKSURL         string        `json:"jwks_url"`
+	JWKSCacheTTL    time.Duration `json:"jwks_cache_ttl"`
 	Issuer          string   `json:"issuer"`
 	Audience        string   `json:"audience"`
 	TokenExpiry     time.Duration `json:"token_expiry"`
@@ -82,8 +92,13 @@ type MessagingConfig struct {
 }
 
 type ClientsConfig struct {
-	UserAPI   *ClientConfig `json:"user_api"`
-	MarketAPI *ClientConfig `json:"market_api"`
+	UserAPI      *ClientConfig `json:"user_api"`
+	MarketAPI    *ClientConfig `json:"market_api"`
+	PortfolioAPI *ClientConfig `json:"portfolio_api"`
+	// Credenciales propias de orders-api para los endpoints internos de Users API
+	// y de Portfolio API (el token de servicio lo emite Users API)
+	ServiceName   string `json:"service_name"`
+	ServiceSecret string `json:"-"`
 }
 
 type ClientConfig struct {
@@ -92,6 +107,59 @@ type ClientConfig struct {
 	Timeout time.Duration `json:"timeout"`
 }
 
+// MatcherConfig configura el worker que ejecuta órdenes limit pendientes
+type MatcherConfig struct {
+	Enabled   bool          `json:"enabled"`
+	Interval  time.Duration `json:"interval"`
+	BatchSize int           `json:"batch_size"`
+	LockTTL   time.Duration `json:"lock_ttl"`
+}
+
+// SagaConfig configura el worker que retoma ejecuciones cortadas por una caída
+type SagaConfig struct {
+	RecoveryEnabled  bool          `json:"recovery_enabled"`
+	RecoveryInterval time.Duration `json:"recovery_interval"`
+	StaleAfter       time.Duration `json:"stale_after"`
+	BatchSize        int           `json:"batch_size"`
+	LockTTL          time.Duration `json:"lock_ttl"`
+}
+
+// ExpiryConfig configura el worker que expira órdenes gtd vencidas
+type ExpiryConfig struct {
+	Enabled        bool          `json:"enabled"`
+	Interval       time.Duration `json:"interval"`
+	BatchSize      int           `json:"batch_size"`
+	LockTTL        time.Duration `json:"lock_ttl"`
+	ImmediateGrace time.Duration `json:"immediate_grace"` // Tras este tiempo una IOC/FOK que quedó abierta se expira
+}
+
+// RecurringPlansConfig configura el scheduler de planes de compra recurrente
+type RecurringPlansConfig struct {
+	Enabled   bool          `json:"enabled"`
+	Interval  time.Duration `json:"interval"`
+	BatchSize int           `json:"batch_size"`
+	LockTTL   time.Duration `json:"lock_ttl"`
+}
+
+// OutboxConfig configura el outbox de eventos y el relay que los publica en RabbitMQ
+type OutboxConfig struct {
+	Enabled        bool          `json:"enabled"`
+	RelayInterval  time.Duration `json:"relay_interval"`
+	BatchSize      int           `json:"batch_size"`
+	LockTTL        time.Duration `json:"lock_ttl"`
+	MaxAttempts    int           `json:"max_attempts"`
+	RetryBaseDelay time.Duration `json:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `json:"retry_max_delay"`
+}
+
+// QuoteConfig configura las cotizaciones firmadas y el slippage de las órdenes market
+type QuoteConfig struct {
+	SigningSecret string          `json:"-"`
+	TTL           time.Duration   `json:"ttl"`
+	Required      bool            `json:"required"`
+	MaxSlippage   decimal.Decimal `json:"max_slippage"` // Slippage por defecto si la orden no pide uno (0 = sin límite)
+}
+
 type ExecutionConfig struct {
 	MaxWorkers       int             `json:"max_workers"`
 	QueueSize        int             `json:"queue_size"`
@@ -102,13 +170,25 @@ type ExecutionConfig struct {
 	MaxExecutionTime time.Duration   `json:"max_execution_time"`
 }
 
+// FeeConfig tabla de comisiones por defecto (rige hasta que un admin guarde otra)
 type FeeConfig struct {
-	BaseFeePercentage decimal.Decimal            `json:"base_fee_percentage"`
-	MakerFee          decimal.Decimal            `json:"maker_fee"`
-	TakerFee          decimal.Decimal            `json:"taker_fee"`
-	MinimumFee        decimal.Decimal            `json:"minimum_fee"`
-	MaximumFee        decimal.Decimal            `json:"maximum_fee"`
-	VIPDiscounts      map[string]decimal.Decimal `json:"vip_discounts"`
+	MakerFee     decimal.Decimal `json:"maker_fee"`
+	TakerFee     decimal.Decimal `json:"taker_fee"`
+	MinimumFee   decimal.Decimal `json:"minimum_fee"`
+	MaximumFee   decimal.Decimal `json:"maximum_fee"` // 0 = sin tope
+	VolumeWindow time.Duration   `json:"volume_window"`
+	CacheTTL     time.Duration   `json:"cache_ttl"`
+}
+
+// RiskConfig límites de riesgo pre-trade por defecto (rol "user"), hasta que un admin
+// guarde una política
+type RiskConfig struct {
+	Enabled          bool            `json:"enabled"`
+	MaxOrderNotional decimal.Decimal `json:"max_order_notional"` // 0 = sin límite
+	MaxDailyVolume   decimal.Decimal `json:"max_daily_volume"`   // 0 = sin límite
+	MaxOpenOrders    int             `json:"max_open_orders"`    // 0 = sin límite
+	PriceBand        decimal.Decimal `json:"price_band"`         // 0 = sin banda
+	CacheTTL         time.Duration   `json:"cache_ttl"`
 }
 
 type WorkerConfig struct {
@@ -121,13 +201,21 @@ type WorkerConfig struct {
 
 func LoadConfig() (*Config, error) {
 	config := &Config{
-		Server:    loadServerConfig(),
-		Database:  loadDatabaseConfig(),
-		Auth:      loadAuthConfig(),
-		Logging:   loadLoggingConfig(),
-		Messaging: loadMessagingConfig(),
-		Clients:   loadClientsConfig(),
-		// Execution, Fee y Worker configs eliminados en sistema simplificado
+		Server:         loadServerConfig(),
+		Database:       loadDatabaseConfig(),
+		Auth:           loadAuthConfig(),
+		Logging:        loadLoggingConfig(),
+		Messaging:      loadMessagingConfig(),
+		Clients:        loadClientsConfig(),
+		Matcher:        loadMatcherConfig(),
+		Saga:           loadSagaConfig(),
+		Expiry:         loadExpiryConfig(),
+		RecurringPlans: loadRecurringPlansConfig(),
+		Outbox:         loadOutboxConfig(),
+		Quote:          loadQuoteConfig(),
+		Fee:            loadFeeConfig(),
+		Risk:           loadRiskConfig(),
+		// Execution y Worker configs eliminados en sistema simplificado
 	}
 
 	return config, nil
@@ -166,10 +254,11 @@ func loadDatabaseConfig() *DatabaseConfig {
 
 func loadAuthConfig() *AuthConfig {
 	return &AuthConfig{
-		SecretKey:   getEnv("JWT_SECRET_KEY", "your-super-secret-key-change-this-in-production"),
-		Issuer:      getEnv("JWT_ISSUER", "orders-api"),
-		Audience:    getEnv("JWT_AUDIENCE", "cryptosim"),
-		TokenExpiry: getEnvAsDuration("JWT_TOKEN_EXPIRY", 24*time.Hour),
+		JWKSURL:      getEnv("JWKS_URL", "http://localhost:8001/.well-known/jwks.json"),
+		JWKSCacheTTL: getEnvAsDuration("JWKS_CACHE_TTL", 5*time.Minute),
+		Issuer:       getEnv("JWT_ISSUER", "users-api"),
+		Audience:     getEnv("JWT_AUDIENCE", "cryptosim"),
+		TokenExpiry:  getEnvAsDuration("JWT_TOKEN_EXPIRY", 24*time.Hour),
 		SkipPaths: getEnvAsSlice("AUTH_SKIP_PATHS", []string{
 			"/health",
 			"/health/live",
@@ -221,7 +310,6 @@ func loadClientsConfig() *ClientsConfig {
 	return &ClientsConfig{
 		UserAPI: &ClientConfig{
 			BaseURL: getEnv("USER_API_BASE_URL", "http://localhost:8081"),
-			APIKey:  getEnv("USER_API_KEY", "user-api-key"),
 			Timeout: getEnvAsDuration("USER_API_TIMEOUT", 10*time.Second),
 		},
 		MarketAPI: &ClientConfig{
@@ -229,6 +317,72 @@ func loadClientsConfig() *ClientsConfig {
 			APIKey:  getEnv("MARKET_API_KEY", "market-api-key"),
 			Timeout: getEnvAsDuration("MARKET_API_TIMEOUT", 10*time.Second),
 		},
+		PortfolioAPI: &ClientConfig{
+			BaseURL: getEnv("PORTFOLIO_API_BASE_URL", ""),
+			Timeout: getEnvAsDuration("PORTFOLIO_API_TIMEOUT", 10*time.Second),
+		},
+		ServiceName:   getEnv("SERVICE_NAME", "orders-api"),
+		ServiceSecret: getEnv("SERVICE_SECRET", ""),
+	}
+}
+
+func loadMatcherConfig() *MatcherConfig {
+	return &MatcherConfig{
+		Enabled:   getEnvAsBool("MATCHER_ENABLED", true),
+		Interval:  getEnvAsDuration("MATCHER_INTERVAL", 5*time.Second),
+		BatchSize: getEnvAsInt("MATCHER_BATCH_SIZE", 100),
+		LockTTL:   getEnvAsDuration("MATCHER_LOCK_TTL", 30*time.Second),
+	}
+}
+
+func loadSagaConfig() *SagaConfig {
+	return &SagaConfig{
+		RecoveryEnabled:  getEnvAsBool("SAGA_RECOVERY_ENABLED", true),
+		RecoveryInterval: getEnvAsDuration("SAGA_RECOVERY_INTERVAL", 30*time.Second),
+		StaleAfter:       getEnvAsDuration("SAGA_STALE_AFTER", 2*time.Minute),
+		BatchSize:        getEnvAsInt("SAGA_RECOVERY_BATCH_SIZE", 50),
+		LockTTL:          getEnvAsDuration("SAGA_RECOVERY_LOCK_TTL", 30*time.Second),
+	}
+}
+
+func loadExpiryConfig() *ExpiryConfig {
+	return &ExpiryConfig{
+		Enabled:        getEnvAsBool("ORDER_EXPIRY_ENABLED", true),
+		Interval:       getEnvAsDuration("ORDER_EXPIRY_INTERVAL", 10*time.Second),
+		BatchSize:      getEnvAsInt("ORDER_EXPIRY_BATCH_SIZE", 100),
+		LockTTL:        getEnvAsDuration("ORDER_EXPIRY_LOCK_TTL", 30*time.Second),
+		ImmediateGrace: getEnvAsDuration("ORDER_EXPIRY_IMMEDIATE_GRACE", time.Minute),
+	}
+}
+
+func loadRecurringPlansConfig() *RecurringPlansConfig {
+	return &RecurringPlansConfig{
+		Enabled:   getEnvAsBool("RECURRING_PLANS_ENABLED", true),
+		Interval:  getEnvAsDuration("RECURRING_PLANS_INTERVAL", 30*time.Second),
+		BatchSize: getEnvAsInt("RECURRING_PLANS_BATCH_SIZE", 50),
+		LockTTL:   getEnvAsDuration("RECURRING_PLANS_LOCK_TTL", time.Minute),
+	}
+}
+
+func loadOutboxConfig() *OutboxConfig {
+	return &OutboxConfig{
+		Enabled:        getEnvAsBool("OUTBOX_ENABLED", true),
+		RelayInterval:  getEnvAsDuration("OUTBOX_RELAY_INTERVAL", time.Second),
+		BatchSize:      getEnvAsInt("OUTBOX_BATCH_SIZE", 100),
+		LockTTL:        getEnvAsDuration("OUTBOX_LOCK_TTL", 30*time.Second),
+		MaxAttempts:    getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 10),
+		RetryBaseDelay: getEnvAsDuration("OUTBOX_RETRY_BASE_DELAY", time.Second),
+		RetryMaxDelay:  getEnvAsDuration("OUTBOX_RETRY_MAX_DELAY", 5*time.Minute),
+	}
+}
+
+func loadQuoteConfig() *QuoteConfig {
+	return &QuoteConfig{
+		// Debe ser la misma en todas las réplicas para que cualquiera acepte las cotizaciones de otra
+		SigningSecret: getEnv("QUOTE_SIGNING_SECRET", ""),
+		TTL:           getEnvAsDuration("QUOTE_TTL", 15*time.Second),
+		Required:      getEnvAsBool("QUOTE_REQUIRED", false),
+		MaxSlippage:   getEnvAsDecimal("MARKET_MAX_SLIPPAGE", decimal.NewFromFloat(0.02)),
 	}
 }
 
@@ -245,19 +399,24 @@ func loadExecutionConfig() *ExecutionConfig {
 }
 
 func loadFeeConfig() *FeeConfig {
-	vipDiscounts := make(map[string]decimal.Decimal)
-	vipDiscounts["bronze"] = getEnvAsDecimal("FEE_VIP_BRONZE", decimal.NewFromFloat(0.05))
-	vipDiscounts["silver"] = getEnvAsDecimal("FEE_VIP_SILVER", decimal.NewFromFloat(0.10))
-	vipDiscounts["gold"] = getEnvAsDecimal("FEE_VIP_GOLD", decimal.NewFromFloat(0.15))
-	vipDiscounts["platinum"] = getEnvAsDecimal("FEE_VIP_PLATINUM", decimal.NewFromFloat(0.25))
-
 	return &FeeConfig{
-		BaseFeePercentage: getEnvAsDecimal("FEE_BASE_PERCENTAGE", decimal.NewFromFloat(0.001)),
-		MakerFee:          getEnvAsDecimal("FEE_MAKER", decimal.NewFromFloat(0.0008)),
-		TakerFee:          getEnvAsDecimal("FEE_TAKER", decimal.NewFromFloat(0.0012)),
-		MinimumFee:        getEnvAsDecimal("FEE_MINIMUM", decimal.NewFromFloat(0.01)),
-		MaximumFee:        getEnvAsDecimal("FEE_MAXIMUM", decimal.NewFromFloat(1000.0)),
-		VIPDiscounts:      vipDiscounts,
+		MakerFee:     getEnvAsDecimal("FEE_MAKER", decimal.NewFromFloat(0.0008)),
+		TakerFee:     getEnvAsDecimal("FEE_TAKER", decimal.NewFromFloat(0.0012)),
+		MinimumFee:   getEnvAsDecimal("FEE_MINIMUM", decimal.NewFromFloat(0.01)),
+		MaximumFee:   getEnvAsDecimal("FEE_MAXIMUM", decimal.NewFromFloat(1000.0)),
+		VolumeWindow: getEnvAsDuration("FEE_VOLUME_WINDOW", 30*24*time.Hour),
+		CacheTTL:     getEnvAsDuration("FEE_SCHEDULE_CACHE_TTL", 30*time.Second),
+	}
+}
+
+func loadRiskConfig() *RiskConfig {
+	return &RiskConfig{
+		Enabled:          getEnvAsBool("RISK_ENABLED", true),
+		MaxOrderNotional: getEnvAsDecimal("RISK_MAX_ORDER_NOTIONAL", decimal.NewFromInt(100000)),
+		MaxDailyVolume:   getEnvAsDecimal("RISK_MAX_DAILY_VOLUME", decimal.NewFromInt(500000)),
+		MaxOpenOrders:    getEnvAsInt("RISK_MAX_OPEN_ORDERS", 50),
+		PriceBand:        getEnvAsDecimal("RISK_PRICE_BAND", decimal.NewFromFloat(0.2)),
+		CacheTTL:         getEnvAsDuration("RISK_POLICY_CACHE_TTL", 30*time.Second),
 	}
 }
 
@@ -275,17 +434,29 @@ func loadWorkerConfig() *WorkerConfig {
 
 func (c *Config) ToUserClientConfig() *clients.UserClientConfig {
 	return &clients.UserClientConfig{
-		BaseURL: c.Clients.UserAPI.BaseURL,
-		APIKey:  c.Clients.UserAPI.APIKey,
-		Timeout: c.Clients.UserAPI.Timeout,
+		BaseURL:       c.Clients.UserAPI.BaseURL,
+		ServiceName:   c.Clients.ServiceName,
+		ServiceSecret: c.Clients.ServiceSecret,
+		Timeout:       c.Clients.UserAPI.Timeout,
 	}
 }
 
 func (c *Config) ToUserBalanceClientConfig() *clients.UserBalanceConfig {
 	return &clients.UserBalanceConfig{
-		BaseURL: c.Clients.UserAPI.BaseURL,
-		APIKey:  c.Clients.UserAPI.APIKey,
-		Timeout: c.Clients.UserAPI.Timeout,
+		BaseURL:       c.Clients.UserAPI.BaseURL,
+		ServiceName:   c.Clients.ServiceName,
+		ServiceSecret: c.Clients.ServiceSecret,
+		Timeout:       c.Clients.UserAPI.Timeout,
+	}
+}
+
+func (c *Config) ToPortfolioClientConfig() *clients.PortfolioClientConfig {
+	return &clients.PortfolioClientConfig{
+		BaseURL:       c.Clients.PortfolioAPI.BaseURL,
+		TokenURL:      c.Clients.UserAPI.BaseURL,
+		ServiceName:   c.Clients.ServiceName,
+		ServiceSecret: c.Clients.ServiceSecret,
+		Timeout:       c.Clients.PortfolioAPI.Timeout,
 	}
 }
 
@@ -312,12 +483,38 @@ func (c *Config) ToMessagingConfig() *messaging.MessagingConfig {
 }
 */
 
-// ToExecutionConfig y ToFeeConfig ya no se usan en el sistema simplificado
-// Las fees se calculan directamente en el ExecutionService (0.1% fijo)
+// ToExecutionConfig ya no se usa en el sistema simplificado
+
+// ToDefaultFeeSchedule arma la tabla de comisiones que rige mientras no se guarde otra
+func (c *Config) ToDefaultFeeSchedule() *models.FeeSchedule {
+	return &models.FeeSchedule{
+		ID:         models.FeeScheduleID,
+		MakerRate:  c.Fee.MakerFee,
+		TakerRate:  c.Fee.TakerFee,
+		MinimumFee: c.Fee.MinimumFee,
+		MaximumFee: c.Fee.MaximumFee,
+	}
+}
+
+// ToDefaultRiskPolicy arma la política de riesgo que rige mientras no se guarde otra
+func (c *Config) ToDefaultRiskPolicy() *models.RiskPolicy {
+	return &models.RiskPolicy{
+		ID: models.RiskPolicyID,
+		Roles: map[string]models.RiskLimits{
+			models.DefaultRiskRole: {
+				MaxOrderNotional: c.Risk.MaxOrderNotional,
+				MaxDailyVolume:   c.Risk.MaxDailyVolume,
+				MaxOpenOrders:    c.Risk.MaxOpenOrders,
+				PriceBand:        c.Risk.PriceBand,
+			},
+		},
+	}
+}
 
 func (c *Config) ToAuthConfig() *middleware.AuthConfig {
 	return &middleware.AuthConfig{
-		SecretKey:       c.Auth.SecretKey,
+		JWKSURL:         c.Auth.JWKSURL,
+		JWKSCacheTTL:    c.Auth.JWKSCacheTTL,
 		Issuer:          c.Auth.Issuer,
 		Audience:        c.Auth.Audience,
 		SkipPaths:       c.Auth.SkipPaths,
@@ -408,13 +605,47 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// minQuoteSecretLength largo mínimo de la clave HMAC de las cotizaciones
+const minQuoteSecretLength = 32
+
+// placeholderSecrets valores de ejemplo de los archivos .env y la documentación:
+// son públicos, así que nunca sirven como secreto real
+var placeholderSecrets = map[string]bool{
+	"change-me":                                           true,
+	"change-this-quote-signing-secret":                    true,
+	"your-quote-signing-secret":                           true,
+	"your-quote-signing-secret-change-this-in-production": true,
+	"your-super-secret-key-change-this-in-production":     true,
+	"orders-api-secret-change-in-production":              true,
+}
+
+func isPlaceholderSecret(secret string) bool {
+	return placeholderSecrets[secret]
+}
+
 func (c *Config) Validate() error {
 	if c.Database.URI == "" {
 		return fmt.Errorf("database URI is required")
 	}
 
-	if c.Auth.SecretKey == "" || c.Auth.SecretKey == "your-super-secret-key-change-this-in-production" {
-		return fmt.Errorf("JWT secret key must be set and changed from default")
+	if c.Auth.JWKSURL == "" {
+		return fmt.Errorf("JWKS URL is required")
+	}
+
+	if c.Clients.ServiceSecret == "" {
+		return fmt.Errorf("service secret is required to call the users API")
+	}
+
+	if isPlaceholderSecret(c.Clients.ServiceSecret) {
+		return fmt.Errorf("service secret must be changed from default")
+	}
+
+	if c.Quote.SigningSecret == "" || isPlaceholderSecret(c.Quote.SigningSecret) {
+		return fmt.Errorf("quote signing secret must be set and changed from default")
+	}
+
+	if len(c.Quote.SigningSecret) < minQuoteSecretLength {
+		return fmt.Errorf("quote signing secret must be at least %d characters", minQuoteSecretLength)
 	}
 
 	if c.Server.Port <= 0 || c.Server.Port > 65535 {
@@ -425,6 +656,42 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("messaging URL is required")
 	}
 
+	if c.Matcher.Enabled && c.Matcher.Interval <= 0 {
+		return fmt.Errorf("matcher interval must be positive")
+	}
+
+	if c.Saga.RecoveryEnabled && c.Saga.RecoveryInterval <= 0 {
+		return fmt.Errorf("saga recovery interval must be positive")
+	}
+
+	if c.Expiry.Enabled && c.Expiry.Interval <= 0 {
+		return fmt.Errorf("order expiry interval must be positive")
+	}
+
+	if c.RecurringPlans.Enabled && c.RecurringPlans.Interval <= 0 {
+		return fmt.Errorf("recurring plans interval must be positive")
+	}
+
+	if c.Outbox.Enabled && c.Outbox.RelayInterval <= 0 {
+		return fmt.Errorf("outbox relay interval must be positive")
+	}
+
+	if err := c.ToDefaultFeeSchedule().Validate(); err != nil {
+		return fmt.Errorf("invalid default fee schedule: %w", err)
+	}
+
+	if err := c.ToDefaultRiskPolicy().Validate(); err != nil {
+		return fmt.Errorf("invalid default risk policy: %w", err)
+	}
+
+	if c.Quote.TTL <= 0 {
+		return fmt.Errorf("quote TTL must be positive")
+	}
+
+	if c.Quote.MaxSlippage.IsNegative() {
+		return fmt.Errorf("market max slippage cannot be negative")
+	}
+
 	return nil
 }
 
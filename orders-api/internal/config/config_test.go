@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate_QuoteSigningSecret(t *testing.T) {
+	t.Setenv("SERVICE_SECRET", "5f0c2b8e9d7a4c1e8b3f6a2d9c4e7b1a")
+
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr string
+	}{
+		{"unset", "", "quote signing secret must be set and changed from default"},
+		{"compose placeholder", "change-this-quote-signing-secret", "quote signing secret must be set and changed from default"},
+		{"JWT placeholder", "your-super-secret-key-change-this-in-production", "quote signing secret must be set and changed from default"},
+		{"too short", "s3cr3t", "quote signing secret must be at least 32 characters"},
+		{"random secret", "0b4f7e2a9c6d1f8e3a5b7c9d2e4f6a8b", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("QUOTE_SIGNING_SECRET", tt.secret)
+			cfg, err := LoadConfig()
+			require.NoError(t, err)
+
+			err = cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_ServiceSecret(t *testing.T) {
+	t.Setenv("QUOTE_SIGNING_SECRET", "0b4f7e2a9c6d1f8e3a5b7c9d2e4f6a8b")
+
+	t.Setenv("SERVICE_SECRET", "orders-api-secret-change-in-production")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.EqualError(t, cfg.Validate(), "service secret must be changed from default")
+
+	t.Setenv("SERVICE_SECRET", "5f0c2b8e9d7a4c1e8b3f6a2d9c4e7b1a")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// maxBulkFailOrders órdenes que se pueden fallar en una misma request
+const maxBulkFailOrders = 100
+
+// AdminOrderFilterRequest filtros del listado de órdenes de todos los usuarios
+type AdminOrderFilterRequest struct {
+	OrderFilterRequest
+	UserID *int `json:"user_id,omitempty"` // Nil lista las órdenes de todos los usuarios
+}
+
+// ForceCancelOrderRequest cancelación de una orden de cualquier usuario por un admin
+type ForceCancelOrderRequest struct {
+	Reason string `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// BulkFailOrdersRequest marca como fallidas órdenes trabadas
+type BulkFailOrdersRequest struct {
+	OrderIDs []string `json:"order_ids" binding:"required,min=1,max=100,dive,required"`
+	Reason   string   `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// Validate verifica la request cuando no pasa por el binding de gin
+func (r *BulkFailOrdersRequest) Validate() error {
+	if len(r.OrderIDs) == 0 || len(r.OrderIDs) > maxBulkFailOrders {
+		return fmt.Errorf("order_ids must have between 1 and %d orders", maxBulkFailOrders)
+	}
+	if strings.TrimSpace(r.Reason) == "" {
+		return fmt.Errorf("reason is required")
+	}
+	return nil
+}
+
+// SkippedOrder orden que la operación masiva no tocó y por qué
+type SkippedOrder struct {
+	OrderID string `json:"order_id"`
+	Reason  string `json:"reason"`
+}
+
+// BulkFailResult resultado de fallar órdenes en masa
+type BulkFailResult struct {
+	Failed  []string       `json:"failed"`
+	Skipped []SkippedOrder `json:"skipped"`
+}
+
+// CryptoStats volumen y comisiones de un símbolo
+type CryptoStats struct {
+	Symbol         string          `json:"symbol"`
+	TotalOrders    int64           `json:"total_orders"`
+	ExecutedOrders int64           `json:"executed_orders"`
+	TotalVolume    decimal.Decimal `json:"total_volume"` // USD llenados (incluye fills parciales)
+	FeeRevenue     decimal.Decimal `json:"fee_revenue"`
+}
+
+// AdminStatistics estadísticas globales de órdenes para el panel de admin
+type AdminStatistics struct {
+	TotalOrders      int64            `json:"total_orders"`
+	OrdersByStatus   map[string]int64 `json:"orders_by_status"`
+	TotalVolume      decimal.Decimal  `json:"total_volume"` // USD llenados (incluye fills parciales)
+	FeeRevenue       decimal.Decimal  `json:"fee_revenue"`  // Comisiones cobradas por los fills
+	AverageOrderSize decimal.Decimal  `json:"average_order_size"`
+	OrdersToday      int64            `json:"orders_today"` // Creadas desde las 00:00 UTC
+	VolumeToday      decimal.Decimal  `json:"volume_today"` // Llenado en órdenes creadas hoy
+	FeeRevenueToday  decimal.Decimal  `json:"fee_revenue_today"`
+	Symbols          []CryptoStats    `json:"symbols"` // De mayor a menor volumen
+	GeneratedAt      time.Time        `json:"generated_at"`
+}
@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"orders-api/internal/models"
+)
+
+// ExportFormat formato del export del historial de órdenes
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatJSON ExportFormat = "json"
+)
+
+// OrderExportRequest export de las órdenes del usuario con algo llenado
+type OrderExportRequest struct {
+	Format ExportFormat `json:"format"`
+	From   *time.Time   `json:"from,omitempty"` // Último fill >= From
+	To     *time.Time   `json:"to,omitempty"`   // Último fill < To
+}
+
+// Validate verifica formato y rango (sin formato se exporta CSV)
+func (r *OrderExportRequest) Validate() error {
+	if r.Format == "" {
+		r.Format = ExportFormatCSV
+	}
+	if r.Format != ExportFormatCSV && r.Format != ExportFormatJSON {
+		return fmt.Errorf("format must be csv or json")
+	}
+
+	if r.From != nil && r.To != nil && !r.From.Before(*r.To) {
+		return fmt.Errorf("from must be before to")
+	}
+
+	return nil
+}
+
+// OrderExportColumns encabezado del CSV, en el orden de OrderExportRow.CSVRecord
+var OrderExportColumns = []string{
+	"order_number", "symbol", "side", "status", "quantity",
+	"filled_quantity", "avg_fill_price", "total", "fee", "executed_at",
+}
+
+// OrderExportRow lo llenado de una orden en el export. Las órdenes que terminaron
+// canceladas, vencidas o fallidas después de un fill exportan solo lo llenado.
+type OrderExportRow struct {
+	OrderNumber    string          `json:"order_number"`
+	Symbol         string          `json:"symbol"`
+	Side           string          `json:"side"`
+	Status         string          `json:"status"`
+	Quantity       decimal.Decimal `json:"quantity"` // Cantidad pedida
+	FilledQuantity decimal.Decimal `json:"filled_quantity"`
+	AvgFillPrice   decimal.Decimal `json:"avg_fill_price"` // Promedio ponderado de los fills
+	Total          decimal.Decimal `json:"total"`          // Monto de lo llenado
+	Fee            decimal.Decimal `json:"fee"`            // Comisión de lo llenado
+	ExecutedAt     time.Time       `json:"executed_at"`    // Último fill
+}
+
+// NewOrderExportRow arma la fila de una orden con algo llenado. Las ejecutadas
+// anteriores a los fills parciales no tienen filled_*: se usan cantidad, precio,
+// total y comisión de la orden.
+func NewOrderExportRow(order *models.Order) OrderExportRow {
+	row := OrderExportRow{
+		OrderNumber:    order.OrderNumber,
+		Symbol:         order.CryptoSymbol,
+		Side:           string(order.Type),
+		Status:         string(order.Status),
+		Quantity:       order.Quantity,
+		FilledQuantity: order.FilledQuantity,
+		Total:          order.FilledAmount,
+		Fee:            order.FilledFee,
+		ExecutedAt:     order.LastFilledAt().UTC(),
+	}
+	if order.AvgFillPrice != nil {
+		row.AvgFillPrice = *order.AvgFillPrice
+	}
+
+	if !order.FilledQuantity.IsPositive() {
+		row.FilledQuantity = order.Quantity
+		row.AvgFillPrice = order.Price
+		row.Total = order.TotalAmount
+		row.Fee = order.Fee
+	}
+
+	return row
+}
+
+// CSVRecord retorna la fila con las columnas de OrderExportColumns
+func (r OrderExportRow) CSVRecord() []string {
+	return []string{
+		r.OrderNumber,
+		r.Symbol,
+		r.Side,
+		r.Status,
+		r.Quantity.String(),
+		r.FilledQuantity.String(),
+		r.AvgFillPrice.String(),
+		r.Total.String(),
+		r.Fee.String(),
+		r.ExecutedAt.Format(time.RFC3339),
+	}
+}
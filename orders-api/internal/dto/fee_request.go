@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"github.com/shopspring/decimal"
+
+	"orders-api/internal/models"
+)
+
+// UpdateFeeScheduleRequest nueva tabla de comisiones; reemplaza completa a la vigente
+type UpdateFeeScheduleRequest struct {
+	MakerRate       decimal.Decimal            `json:"maker_rate"`
+	TakerRate       decimal.Decimal            `json:"taker_rate"`
+	MinimumFee      decimal.Decimal            `json:"minimum_fee"`
+	MaximumFee      decimal.Decimal            `json:"maximum_fee"` // 0 = sin tope
+	VolumeTiers     []models.FeeTier           `json:"volume_tiers"`
+	SymbolOverrides []models.SymbolFeeOverride `json:"symbol_overrides"`
+	Promotions      []models.FeePromotion      `json:"promotions"`
+	Version         *int64                     `json:"version,omitempty"` // Si viene, la tabla debe seguir en esa versión
+}
+
+// ToSchedule arma la tabla normalizada a partir de la request (sin validar)
+func (r *UpdateFeeScheduleRequest) ToSchedule() *models.FeeSchedule {
+	schedule := &models.FeeSchedule{
+		ID:              models.FeeScheduleID,
+		MakerRate:       r.MakerRate,
+		TakerRate:       r.TakerRate,
+		MinimumFee:      r.MinimumFee,
+		MaximumFee:      r.MaximumFee,
+		VolumeTiers:     r.VolumeTiers,
+		SymbolOverrides: r.SymbolOverrides,
+		Promotions:      r.Promotions,
+	}
+	schedule.Normalize()
+	return schedule
+}
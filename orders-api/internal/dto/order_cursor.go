@@ -0,0 +1,119 @@
+package dto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"orders-api/internal/models"
+)
+
+// OrderSortField campo por el que se ordena el listado de órdenes
+type OrderSortField string
+
+const (
+	OrderSortCreatedAt OrderSortField = "created_at"
+	OrderSortTotal     OrderSortField = "total_amount"
+	OrderSortPrice     OrderSortField = "price"
+)
+
+// ParseOrderSortField acepta los nombres de la query (total es alias de total_amount)
+func ParseOrderSortField(value string) OrderSortField {
+	if value == "total" {
+		return OrderSortTotal
+	}
+	return OrderSortField(value)
+}
+
+// IsValid indica si se puede ordenar por el campo
+func (f OrderSortField) IsValid() bool {
+	return f == OrderSortCreatedAt || f == OrderSortTotal || f == OrderSortPrice
+}
+
+// SortOrder sentido del orden
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// OrderCursor posición después de la última orden de una página: el valor del campo
+// de orden y el _id que desempata. Viaja opaco (JSON en base64) y solo sirve para
+// el mismo orden con el que se emitió.
+type OrderCursor struct {
+	SortBy    OrderSortField `json:"s"`
+	SortOrder SortOrder      `json:"o"`
+	Value     string         `json:"v"` // created_at en RFC 3339 o el número del campo
+	ID        string         `json:"id"`
+}
+
+// NewOrderCursor arma el cursor que sigue después de order
+func NewOrderCursor(order *models.Order, sortBy OrderSortField, sortOrder SortOrder) *OrderCursor {
+	cursor := &OrderCursor{SortBy: sortBy, SortOrder: sortOrder, ID: order.ID.Hex()}
+
+	switch sortBy {
+	case OrderSortTotal:
+		cursor.Value = order.TotalAmount.String()
+	case OrderSortPrice:
+		cursor.Value = order.Price.String()
+	default:
+		cursor.Value = order.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return cursor
+}
+
+// Encode serializa el cursor para la respuesta
+func (c *OrderCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeOrderCursor lee un cursor recibido en la query
+func DecodeOrderCursor(encoded string) (*OrderCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var cursor OrderCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if !cursor.SortBy.IsValid() || (cursor.SortOrder != SortAsc && cursor.SortOrder != SortDesc) {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if _, err := primitive.ObjectIDFromHex(cursor.ID); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if _, err := cursor.SortValue(); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &cursor, nil
+}
+
+// SortValue retorna el valor del campo de orden como se guarda en Mongo
+// (time.Time para created_at, float64 para los montos)
+func (c *OrderCursor) SortValue() (interface{}, error) {
+	if c.SortBy == OrderSortCreatedAt {
+		return time.Parse(time.RFC3339Nano, c.Value)
+	}
+
+	value, err := decimal.NewFromString(c.Value)
+	if err != nil {
+		return nil, err
+	}
+	return value.InexactFloat64(), nil
+}
+
+// ObjectID retorna el _id de la última orden de la página
+func (c *OrderCursor) ObjectID() primitive.ObjectID {
+	id, _ := primitive.ObjectIDFromHex(c.ID)
+	return id
+}
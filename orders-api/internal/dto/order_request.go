@@ -3,6 +3,7 @@ package dto
 import (
 	"fmt"
 	"orders-api/internal/models"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -12,74 +13,228 @@ type CreateOrderRequest struct {
 	Type         models.OrderType `json:"type" binding:"required,oneof=buy sell"`
 	CryptoSymbol string           `json:"crypto_symbol" binding:"required,min=2,max=10"`
 	Quantity     string           `json:"quantity" binding:"required"` // String para evitar problemas de parseo JSON
-	OrderKind    models.OrderKind `json:"order_kind" binding:"required,oneof=market limit"`
-	LimitPrice   string           `json:"limit_price,omitempty"`  // Solo requerido para limit orders
-	MarketPrice  string           `json:"market_price,omitempty"` // Precio de mercado desde el frontend
+	OrderKind    models.OrderKind `json:"order_kind" binding:"required,oneof=market limit stop_market stop_limit take_profit oco"`
+	LimitPrice   string           `json:"limit_price,omitempty"`  // Requerido para limit, stop_limit y oco
+	TriggerPrice string           `json:"trigger_price,omitempty"` // Requerido para stop_market, stop_limit, take_profit y oco
+	QuoteID      string           `json:"quote_id,omitempty"`     // Cotización firmada (solo market)
+	MaxSlippage  string           `json:"max_slippage,omitempty"` // Desvío máximo aceptado contra el precio cotizado, ej 0.01 = 1% (solo market)
+	IdempotencyKey string         `json:"-"`                      // Se completa desde el header Idempotency-Key
+	TimeInForce  models.TimeInForce `json:"time_in_force,omitempty" binding:"omitempty,oneof=gtc ioc fok gtd"` // Default gtc
+	ExpiresAt    *time.Time       `json:"expires_at,omitempty"`   // Requerido para gtd (RFC 3339)
 }
 
+// MaxSlippageLimit desvío máximo que se puede pedir en max_slippage (50%)
+var MaxSlippageLimit = decimal.NewFromFloat(0.5)
+
+// MaxIdempotencyKeyLength largo máximo aceptado para el header Idempotency-Key
+const MaxIdempotencyKeyLength = 128
+
 // Validate valida la request y retorna los valores parseados
-func (r *CreateOrderRequest) Validate() (quantity decimal.Decimal, limitPrice *decimal.Decimal, marketPrice *decimal.Decimal, err error) {
+func (r *CreateOrderRequest) Validate() (quantity decimal.Decimal, limitPrice *decimal.Decimal, triggerPrice *decimal.Decimal, maxSlippage *decimal.Decimal, err error) {
 	// Parsear y validar quantity
 	quantity, err = decimal.NewFromString(r.Quantity)
 	if err != nil {
-		return decimal.Zero, nil, nil, fmt.Errorf("invalid quantity format: must be a valid number")
+		return decimal.Zero, nil, nil, nil, fmt.Errorf("invalid quantity format: must be a valid number")
 	}
 
 	if quantity.LessThanOrEqual(decimal.Zero) {
-		return decimal.Zero, nil, nil, fmt.Errorf("quantity must be greater than zero")
+		return decimal.Zero, nil, nil, nil, fmt.Errorf("quantity must be greater than zero")
+	}
+
+	if len(r.IdempotencyKey) > MaxIdempotencyKeyLength {
+		return decimal.Zero, nil, nil, nil, fmt.Errorf("idempotency key exceeds maximum length (%d)", MaxIdempotencyKeyLength)
 	}
 
 	// Validación de cantidad máxima
 	maxQuantity := decimal.NewFromInt(1000000)
 	if quantity.GreaterThan(maxQuantity) {
-		return decimal.Zero, nil, nil, fmt.Errorf("quantity exceeds maximum allowed (1,000,000)")
+		return decimal.Zero, nil, nil, nil, fmt.Errorf("quantity exceeds maximum allowed (1,000,000)")
 	}
 
-	// Validar limit price para órdenes limit
-	if r.OrderKind == models.OrderKindLimit {
+	// Validar limit price para órdenes limit, stop_limit y oco
+	if r.OrderKind.HasLimitPrice() {
 		if r.LimitPrice == "" {
-			return decimal.Zero, nil, nil, fmt.Errorf("limit_price is required for limit orders")
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("limit_price is required for %s orders", r.OrderKind)
 		}
 
 		price, err := decimal.NewFromString(r.LimitPrice)
 		if err != nil {
-			return decimal.Zero, nil, nil, fmt.Errorf("invalid limit_price format: must be a valid number")
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("invalid limit_price format: must be a valid number")
 		}
 
 		if price.LessThanOrEqual(decimal.Zero) {
-			return decimal.Zero, nil, nil, fmt.Errorf("limit_price must be greater than zero")
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("limit_price must be greater than zero")
 		}
 
 		limitPrice = &price
 	}
 
-	// Validar market price si viene desde el frontend
-	if r.MarketPrice != "" {
-		price, err := decimal.NewFromString(r.MarketPrice)
+	// Validar trigger price para órdenes condicionales
+	if r.OrderKind.HasTriggerPrice() {
+		if r.TriggerPrice == "" {
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("trigger_price is required for %s orders", r.OrderKind)
+		}
+
+		price, err := decimal.NewFromString(r.TriggerPrice)
 		if err != nil {
-			return decimal.Zero, nil, nil, fmt.Errorf("invalid market_price format: must be a valid number")
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("invalid trigger_price format: must be a valid number")
 		}
 
 		if price.LessThanOrEqual(decimal.Zero) {
-			return decimal.Zero, nil, nil, fmt.Errorf("market_price must be greater than zero")
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("trigger_price must be greater than zero")
+		}
+
+		triggerPrice = &price
+	} else if r.TriggerPrice != "" {
+		return decimal.Zero, nil, nil, nil, fmt.Errorf("trigger_price is not allowed for %s orders", r.OrderKind)
+	}
+
+	// En una OCO la pata limit toma ganancia y la pata stop corta pérdida:
+	// venta con limit arriba del stop, compra con limit abajo del stop
+	if r.OrderKind == models.OrderKindOCO {
+		if r.Type == models.OrderTypeSell && !limitPrice.GreaterThan(*triggerPrice) {
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("limit_price must be above trigger_price for sell oco orders")
+		}
+		if r.Type == models.OrderTypeBuy && !limitPrice.LessThan(*triggerPrice) {
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("limit_price must be below trigger_price for buy oco orders")
 		}
+	}
 
-		marketPrice = &price
+	// Cotización y slippage solo aplican a órdenes market
+	if r.OrderKind != models.OrderKindMarket {
+		if r.QuoteID != "" {
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("quote_id is only allowed for market orders")
+		}
+		if r.MaxSlippage != "" {
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("max_slippage is only allowed for market orders")
+		}
 	}
 
-	return quantity, limitPrice, marketPrice, nil
+	if r.MaxSlippage != "" {
+		slippage, err := decimal.NewFromString(r.MaxSlippage)
+		if err != nil {
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("invalid max_slippage format: must be a valid number")
+		}
+
+		if slippage.LessThanOrEqual(decimal.Zero) || slippage.GreaterThan(MaxSlippageLimit) {
+			return decimal.Zero, nil, nil, nil, fmt.Errorf("max_slippage must be greater than zero and at most %s", MaxSlippageLimit.String())
+		}
+
+		maxSlippage = &slippage
+	}
+
+	if err := r.validateTimeInForce(time.Now()); err != nil {
+		return decimal.Zero, nil, nil, nil, err
+	}
+
+	return quantity, limitPrice, triggerPrice, maxSlippage, nil
 }
 
-// OrderFilterRequest para filtrar y paginar órdenes
+// validateTimeInForce valida el time in force contra el tipo de orden y el vencimiento de las gtd
+func (r *CreateOrderRequest) validateTimeInForce(now time.Time) error {
+	switch r.GetTimeInForce() {
+	case models.TimeInForceGTC:
+	case models.TimeInForceIOC, models.TimeInForceFOK:
+		// Solo las órdenes que pueden ejecutarse al crearse tienen una primera pasada
+		if r.OrderKind != models.OrderKindMarket && r.OrderKind != models.OrderKindLimit {
+			return fmt.Errorf("time_in_force %s is only allowed for market and limit orders", r.TimeInForce)
+		}
+	case models.TimeInForceGTD:
+		if r.OrderKind == models.OrderKindMarket {
+			return fmt.Errorf("time_in_force gtd is not allowed for market orders")
+		}
+		if r.ExpiresAt == nil {
+			return fmt.Errorf("expires_at is required for gtd orders")
+		}
+		if !r.ExpiresAt.After(now) {
+			return fmt.Errorf("expires_at must be in the future")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid time_in_force: %s", r.TimeInForce)
+	}
+
+	if r.ExpiresAt != nil {
+		return fmt.Errorf("expires_at is only allowed for gtd orders")
+	}
+	return nil
+}
+
+// GetTimeInForce retorna el time in force pedido o gtc si no viene
+func (r *CreateOrderRequest) GetTimeInForce() models.TimeInForce {
+	if r.TimeInForce == "" {
+		return models.TimeInForceGTC
+	}
+	return r.TimeInForce
+}
+
+// AmendOrderRequest request para modificar precio y/o cantidad de una orden limit pendiente
+type AmendOrderRequest struct {
+	LimitPrice string `json:"limit_price,omitempty"`
+	Quantity   string `json:"quantity,omitempty"`
+	Version    *int64 `json:"version,omitempty"` // Si viene, la orden debe seguir en esa versión
+}
+
+// Validate valida la request y retorna solo los valores que se quieren cambiar
+func (r *AmendOrderRequest) Validate() (quantity *decimal.Decimal, limitPrice *decimal.Decimal, err error) {
+	if r.LimitPrice == "" && r.Quantity == "" {
+		return nil, nil, fmt.Errorf("limit_price or quantity is required")
+	}
+
+	if r.Quantity != "" {
+		q, err := decimal.NewFromString(r.Quantity)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid quantity format: must be a valid number")
+		}
+
+		if q.LessThanOrEqual(decimal.Zero) {
+			return nil, nil, fmt.Errorf("quantity must be greater than zero")
+		}
+
+		if q.GreaterThan(decimal.NewFromInt(1000000)) {
+			return nil, nil, fmt.Errorf("quantity exceeds maximum allowed (1,000,000)")
+		}
+
+		quantity = &q
+	}
+
+	if r.LimitPrice != "" {
+		price, err := decimal.NewFromString(r.LimitPrice)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid limit_price format: must be a valid number")
+		}
+
+		if price.LessThanOrEqual(decimal.Zero) {
+			return nil, nil, fmt.Errorf("limit_price must be greater than zero")
+		}
+
+		limitPrice = &price
+	}
+
+	return quantity, limitPrice, nil
+}
+
+// OrderFilterRequest para filtrar y paginar órdenes. Con Cursor se pagina por
+// keyset (Page se ignora); sin él, por offset.
 type OrderFilterRequest struct {
 	Status       *models.OrderStatus `json:"status,omitempty"`
 	CryptoSymbol *string             `json:"crypto_symbol,omitempty"`
 	Type         *models.OrderType   `json:"type,omitempty"`
+	OrderKind    *models.OrderKind   `json:"order_kind,omitempty"`
+	From         *time.Time          `json:"from,omitempty"`       // created_at >= From
+	To           *time.Time          `json:"to,omitempty"`         // created_at < To
+	MinTotal     *decimal.Decimal    `json:"min_total,omitempty"`  // total_amount >= MinTotal
+	MaxTotal     *decimal.Decimal    `json:"max_total,omitempty"`  // total_amount <= MaxTotal
+	SortBy       OrderSortField      `json:"sort_by,omitempty"`    // created_at (default), total_amount o price
+	SortOrder    SortOrder           `json:"sort_order,omitempty"` // desc (default) o asc
+	Cursor       string              `json:"cursor,omitempty"`     // next_cursor de la página anterior
 	Page         int                 `json:"page,omitempty"`
 	Limit        int                 `json:"limit,omitempty"`
+
+	cursor *OrderCursor // Cursor decodificado por Validate
 }
 
-// SetDefaults establece valores por defecto para paginación
+// SetDefaults establece valores por defecto para paginación y orden
 func (r *OrderFilterRequest) SetDefaults() {
 	if r.Page <= 0 {
 		r.Page = 1
@@ -88,13 +243,72 @@ func (r *OrderFilterRequest) SetDefaults() {
 	if r.Limit <= 0 || r.Limit > 100 {
 		r.Limit = 20
 	}
+
+	if r.SortBy == "" {
+		r.SortBy = OrderSortCreatedAt
+	}
+
+	if r.SortOrder == "" {
+		r.SortOrder = SortDesc
+	}
 }
 
-// GetOffset calcula el offset para la query de base de datos
+// Validate verifica los filtros y decodifica el cursor (llamar después de SetDefaults)
+func (r *OrderFilterRequest) Validate() error {
+	if !r.SortBy.IsValid() {
+		return fmt.Errorf("sort_by must be one of created_at, total or price")
+	}
+	if r.SortOrder != SortAsc && r.SortOrder != SortDesc {
+		return fmt.Errorf("sort_order must be asc or desc")
+	}
+
+	if r.From != nil && r.To != nil && !r.From.Before(*r.To) {
+		return fmt.Errorf("from must be before to")
+	}
+
+	if (r.MinTotal != nil && r.MinTotal.IsNegative()) || (r.MaxTotal != nil && r.MaxTotal.IsNegative()) {
+		return fmt.Errorf("min_total and max_total cannot be negative")
+	}
+	if r.MinTotal != nil && r.MaxTotal != nil && r.MinTotal.GreaterThan(*r.MaxTotal) {
+		return fmt.Errorf("min_total cannot be greater than max_total")
+	}
+
+	r.cursor = nil
+	if r.Cursor != "" {
+		cursor, err := DecodeOrderCursor(r.Cursor)
+		if err != nil {
+			return err
+		}
+		if cursor.SortBy != r.SortBy || cursor.SortOrder != r.SortOrder {
+			return fmt.Errorf("cursor was issued for a different sort, start again without it")
+		}
+		r.cursor = cursor
+	}
+
+	return nil
+}
+
+// After retorna la posición desde la que sigue la página (nil sin cursor)
+func (r *OrderFilterRequest) After() *OrderCursor {
+	return r.cursor
+}
+
+// GetOffset calcula el offset para la query de base de datos (0 con cursor)
 func (r *OrderFilterRequest) GetOffset() int {
+	if r.Cursor != "" {
+		return 0
+	}
 	return (r.Page - 1) * r.Limit
 }
 
+// OrderPage página de órdenes con el cursor para pedir la siguiente
+type OrderPage struct {
+	Orders     []models.Order
+	Total      int64  // Órdenes que cumplen los filtros, sin contar el cursor
+	HasMore    bool   // Hay órdenes después de esta página
+	NextCursor string // Vacío en la última página
+}
+
 // OrdersSummary resumen de las órdenes del usuario
 type OrdersSummary struct {
 	TotalOrders     int64           `json:"total_orders"`
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"orders-api/internal/models"
+)
+
+// MinPlanAmount y MaxPlanAmount límites del monto en USD de cada corrida de un plan
+var (
+	MinPlanAmount = decimal.NewFromInt(1)
+	MaxPlanAmount = decimal.NewFromInt(100000)
+)
+
+// CreateRecurringPlanRequest request para crear un plan de compra recurrente.
+// Ej: "comprar 100 USD de BTC todos los lunes" = weekly con start_at un lunes.
+type CreateRecurringPlanRequest struct {
+	CryptoSymbol string               `json:"crypto_symbol" binding:"required,min=2,max=10"`
+	Amount       string               `json:"amount" binding:"required"` // USD por corrida
+	Frequency    models.PlanFrequency `json:"frequency" binding:"required,oneof=daily weekly monthly"`
+	StartAt      *time.Time           `json:"start_at,omitempty"` // Primera corrida (default: ahora)
+}
+
+// Validate valida la request y retorna el monto y la primera corrida
+func (r *CreateRecurringPlanRequest) Validate(now time.Time) (amount decimal.Decimal, firstRun time.Time, err error) {
+	amount, err = decimal.NewFromString(r.Amount)
+	if err != nil {
+		return decimal.Zero, time.Time{}, fmt.Errorf("invalid amount format: must be a valid number")
+	}
+
+	if amount.LessThan(MinPlanAmount) || amount.GreaterThan(MaxPlanAmount) {
+		return decimal.Zero, time.Time{}, fmt.Errorf("amount must be between %s and %s", MinPlanAmount.String(), MaxPlanAmount.String())
+	}
+
+	if !r.Frequency.IsValid() {
+		return decimal.Zero, time.Time{}, fmt.Errorf("invalid frequency: %s", r.Frequency)
+	}
+
+	firstRun = now
+	if r.StartAt != nil {
+		if r.StartAt.Before(now.Add(-time.Minute)) {
+			return decimal.Zero, time.Time{}, fmt.Errorf("start_at must not be in the past")
+		}
+		firstRun = *r.StartAt
+	}
+
+	return amount, firstRun, nil
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"orders-api/internal/models"
+)
+
+// UpdateRiskPolicyRequest nueva política de riesgo; reemplaza completa a la vigente
+type UpdateRiskPolicyRequest struct {
+	Roles   map[string]models.RiskLimits `json:"roles" binding:"required"` // Rol -> límites ("user" aplica al resto)
+	Version *int64                       `json:"version,omitempty"`        // Si viene, la política debe seguir en esa versión
+}
+
+// ToPolicy arma la política normalizada a partir de la request (sin validar)
+func (r *UpdateRiskPolicyRequest) ToPolicy() *models.RiskPolicy {
+	policy := &models.RiskPolicy{
+		ID:    models.RiskPolicyID,
+		Roles: r.Roles,
+	}
+	policy.Normalize()
+	return policy
+}
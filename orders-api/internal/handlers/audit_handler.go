@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"orders-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	auditRepo repositories.AdminAuditRepository
+}
+
+func NewAuditHandler(auditRepo repositories.AdminAuditRepository) *AuditHandler {
+	return &AuditHandler{
+		auditRepo: auditRepo,
+	}
+}
+
+// ListAuditLog returns the latest admin actions, optionally filtered by admin_id
+// and since (RFC 3339)
+func (h *AuditHandler) ListAuditLog(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	var adminID *int
+	if raw := c.Query("admin_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid admin_id"})
+			return
+		}
+		adminID = &id
+	}
+
+	var since *time.Time
+	if raw := c.Query("since"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid since, expected RFC 3339"})
+			return
+		}
+		since = &t
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	entries, err := h.auditRepo.List(ctx, adminID, since, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "total": len(entries)})
+}
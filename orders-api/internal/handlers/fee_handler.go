@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/repositories"
+	"orders-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeeHandler struct {
+	feeService *services.FeeService
+}
+
+func NewFeeHandler(feeService *services.FeeService) *FeeHandler {
+	return &FeeHandler{
+		feeService: feeService,
+	}
+}
+
+// GetFeeSchedule returns the fee schedule currently in force
+func (h *FeeHandler) GetFeeSchedule(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	schedule, err := h.feeService.GetSchedule(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateFeeSchedule replaces the fee schedule; new orders pick it up right away on
+// this replica and within the cache TTL on the others
+func (h *FeeHandler) UpdateFeeSchedule(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req dto.UpdateFeeScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	schedule, err := h.feeService.UpdateSchedule(ctx, &req, adminID.(int))
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrFeeScheduleConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "fee schedule was modified concurrently, reload and retry"})
+		case strings.Contains(err.Error(), "validation error"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
@@ -2,19 +2,28 @@ package handlers
 
 import (
 	"context"
-	"log"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"orders-api/internal/dto"
+	"orders-api/internal/middleware"
 	"orders-api/internal/models"
+	"orders-api/internal/repositories"
 	"orders-api/internal/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 )
 
+// exportFlushEvery rows written between flushes of a streamed export
+const exportFlushEvery = 200
+
 type OrderHandler struct {
 	orderService services.OrderService
 }
@@ -26,17 +35,50 @@ func NewOrderHandler(orderService services.OrderService) *OrderHandler {
 }
 
 type CreateOrderRequest struct {
+	Type         string     `json:"type" binding:"required,oneof=buy sell"`
+	OrderKind    string     `json:"order_kind" binding:"required,oneof=market limit stop_market stop_limit take_profit oco"`
+	CryptoSymbol string     `json:"crypto_symbol" binding:"required"`
+	Quantity     string     `json:"quantity" binding:"required"`
+	OrderPrice   string     `json:"order_price,omitempty"`
+	TriggerPrice string     `json:"trigger_price,omitempty"`                                           // Stop / take profit price for conditional kinds
+	QuoteID      string     `json:"quote_id,omitempty"`                                                // Signed quote from POST /orders/quotes (market only)
+	MaxSlippage  string     `json:"max_slippage,omitempty"`                                            // Max accepted deviation from the quoted price, e.g. 0.01 (market only)
+	TimeInForce  string     `json:"time_in_force,omitempty" binding:"omitempty,oneof=gtc ioc fok gtd"` // Defaults to gtc
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`                                              // Required for gtd, RFC 3339
+}
+
+func (r *CreateOrderRequest) toDTO() *dto.CreateOrderRequest {
+	return &dto.CreateOrderRequest{
+		Type:         models.OrderType(r.Type),
+		CryptoSymbol: r.CryptoSymbol,
+		Quantity:     r.Quantity,
+		OrderKind:    models.OrderKind(r.OrderKind),
+		LimitPrice:   r.OrderPrice,
+		TriggerPrice: r.TriggerPrice,
+		QuoteID:      r.QuoteID,
+		MaxSlippage:  r.MaxSlippage,
+		TimeInForce:  models.TimeInForce(r.TimeInForce),
+		ExpiresAt:    r.ExpiresAt,
+	}
+}
+
+type CreateQuoteRequest struct {
 	Type         string `json:"type" binding:"required,oneof=buy sell"`
-	OrderKind    string `json:"order_kind" binding:"required,oneof=market limit"`
 	CryptoSymbol string `json:"crypto_symbol" binding:"required"`
-	Quantity     string `json:"quantity" binding:"required"`
-	OrderPrice   string `json:"order_price,omitempty"`
-	MarketPrice  string `json:"market_price,omitempty"` // Market price from frontend
 }
 
 type UpdateOrderRequest struct {
 	OrderPrice string `json:"order_price,omitempty"`
 	Quantity   string `json:"quantity,omitempty"`
+	Version    *int64 `json:"version,omitempty"` // Versión leída por el cliente; si cambió se responde 409
+}
+
+func (r *UpdateOrderRequest) toDTO() *dto.AmendOrderRequest {
+	return &dto.AmendOrderRequest{
+		LimitPrice: r.OrderPrice,
+		Quantity:   r.Quantity,
+		Version:    r.Version,
+	}
 }
 
 type OrderResponse struct {
@@ -54,10 +96,31 @@ type OrderResponse struct {
 	TotalAmount    string     `json:"total_amount"`
 	Fee            string     `json:"fee"`
 	FeePercentage  string     `json:"fee_percentage"`
+	FeeType        string     `json:"fee_type,omitempty"` // maker or taker
+	FeeRule        string     `json:"fee_rule,omitempty"` // Fee schedule rule applied to the order
 	CreatedAt      time.Time  `json:"created_at"`
 	ExecutedAt     *time.Time `json:"executed_at,omitempty"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 	CancelledAt    *time.Time `json:"cancelled_at,omitempty"`
+	Version        int64      `json:"version"`
+	TriggerPrice   string     `json:"trigger_price,omitempty"`
+	TriggeredAt    *time.Time `json:"triggered_at,omitempty"`
+	FilledLeg      string     `json:"filled_leg,omitempty"`
+	FilledQuantity string     `json:"filled_quantity"`
+	RemainingQty   string     `json:"remaining_quantity"`
+	AvgFillPrice   string     `json:"avg_fill_price,omitempty"` // Volume-weighted average of the fills
+	FillCount      int        `json:"fill_count"`
+	TimeInForce    string     `json:"time_in_force"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	ExpiredAt      *time.Time `json:"expired_at,omitempty"`
+	// ExecutionCost is the price, slippage and fee breakdown of the last execution pass
+	ExecutionCost *models.ExecutionCost `json:"execution_cost,omitempty"`
+}
+
+type FillListResponse struct {
+	OrderID string        `json:"order_id"`
+	Fills   []models.Fill `json:"fills"`
+	Total   int           `json:"total"`
 }
 
 type OrderListResponse struct {
@@ -66,6 +129,8 @@ type OrderListResponse struct {
 	Page       int                `json:"page"`
 	PageSize   int                `json:"page_size"`
 	TotalPages int64              `json:"total_pages"`
+	HasMore    bool               `json:"has_more"`
+	NextCursor string             `json:"next_cursor,omitempty"` // Pass as ?cursor= to get the next page
 	Summary    *dto.OrdersSummary `json:"summary,omitempty"`
 }
 
@@ -79,6 +144,7 @@ type ExecutionResponse struct {
 	MarketPrice    map[string]interface{}   `json:"market_price,omitempty"`
 	FeeCalculation map[string]interface{}   `json:"fee_calculation,omitempty"`
 	Steps          []map[string]interface{} `json:"steps,omitempty"`
+	Cost           *models.ExecutionCost    `json:"cost,omitempty"`
 }
 
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
@@ -94,10 +160,6 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// DEBUG: Log the received request
-	log.Printf("🔍 Handler received request - MarketPrice: '%s', OrderKind: %s, Quantity: %s, Type: %s",
-		req.MarketPrice, req.OrderKind, req.Quantity, req.Type)
-
 	quantity, err := decimal.NewFromString(req.Quantity)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quantity format"})
@@ -117,18 +179,36 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	if userToken, exists := c.Get("user_token"); exists {
 		ctx = context.WithValue(ctx, "user_token", userToken)
 	}
-
-	dtoReq := &dto.CreateOrderRequest{
-		Type:         models.OrderType(req.Type),
-		CryptoSymbol: req.CryptoSymbol,
-		Quantity:     req.Quantity,
-		OrderKind:    models.OrderKind(req.OrderKind),
-		LimitPrice:   req.OrderPrice,
-		MarketPrice:  req.MarketPrice, // Pass market price from frontend
+	// Pre-trade risk limits depend on the user's role
+	if userRole, exists := c.Get("user_role"); exists {
+		ctx = context.WithValue(ctx, "user_role", userRole)
 	}
 
+	dtoReq := req.toDTO()
+	// Retries with the same key return the original order instead of creating a new one
+	dtoReq.IdempotencyKey = strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+
 	createdOrder, err := h.orderService.CreateOrder(ctx, dtoReq, userID.(int))
 	if err != nil {
+		if writeRiskRejection(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidQuote) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrQuoteExpired) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "quote_expired"})
+			return
+		}
+		if strings.Contains(err.Error(), "insufficient balance") || strings.Contains(err.Error(), "insufficient holdings") {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrOrderValidation) || errors.Is(err, services.ErrInvalidSymbol) || errors.Is(err, services.ErrTradingSuspended) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -137,6 +217,80 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	c.JSON(http.StatusCreated, response)
 }
 
+// SimulateOrder dry-runs an order: same validation, pricing, fee and balance/holdings
+// checks as CreateOrder, without saving the order or reserving funds. Orders that would
+// be rejected still get 200 with rejection_reason set.
+func (h *OrderHandler) SimulateOrder(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	if userToken, exists := c.Get("user_token"); exists {
+		ctx = context.WithValue(ctx, "user_token", userToken)
+	}
+	// Pre-trade risk limits depend on the user's role
+	if userRole, exists := c.Get("user_role"); exists {
+		ctx = context.WithValue(ctx, "user_role", userRole)
+	}
+
+	simulation, err := h.orderService.SimulateOrder(ctx, req.toDTO(), userID.(int))
+	if err != nil {
+		if errors.Is(err, services.ErrPortfolioUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, simulation)
+}
+
+// CreateQuote returns a signed, short-lived price quote to attach to a market order
+func (h *OrderHandler) CreateQuote(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req CreateQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	quote, err := h.orderService.CreateQuote(ctx, userID.(int), req.CryptoSymbol, models.OrderType(req.Type))
+	if err != nil {
+		msg := err.Error()
+		switch {
+		case errors.Is(err, services.ErrInvalidSymbol), errors.Is(err, services.ErrTradingSuspended):
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		case strings.Contains(msg, "quotes are not enabled"):
+			c.JSON(http.StatusNotImplemented, gin.H{"error": msg})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, quote)
+}
+
 func (h *OrderHandler) GetOrder(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
@@ -168,6 +322,42 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+func (h *OrderHandler) GetOrderFills(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	fills, err := h.orderService.GetOrderFills(ctx, orderID, userID.(int))
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+		case strings.Contains(err.Error(), "access denied"):
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, &FillListResponse{
+		OrderID: orderID,
+		Fills:   fills,
+		Total:   len(fills),
+	})
+}
+
 func (h *OrderHandler) ListUserOrders(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -175,11 +365,148 @@ func (h *OrderHandler) ListUserOrders(c *gin.Context) {
 		return
 	}
 
+	filter, err := orderFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	page, summary, err := h.orderService.ListUserOrders(ctx, userID.(int), filter)
+	if err != nil {
+		writeListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.newOrderListResponse(page, filter, summary))
+}
+
+// ExportOrders streams every order of the authenticated user with filled quantity
+// (executed, or cancelled/expired/failed after a partial fill) as CSV or JSON
+// (?format=csv|json&from=&to=, RFC 3339 over the last fill). Rows are written as
+// they are read from Mongo, so the whole history is never held in memory.
+func (h *OrderHandler) ExportOrders(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	req := &dto.OrderExportRequest{Format: dto.ExportFormat(strings.ToLower(c.Query("format")))}
+	for param, target := range map[string]**time.Time{"from": &req.From, "to": &req.To} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param + ", expected RFC 3339"})
+				return
+			}
+			*target = &t
+		}
+	}
+	// Validate before the first byte is written: afterwards the status can no longer change
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	filename := fmt.Sprintf("orders-%d-%s.%s", userID.(int), time.Now().UTC().Format("20060102"), req.Format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+
+	var err error
+	if req.Format == dto.ExportFormatJSON {
+		err = h.exportJSON(ctx, c, userID.(int), req)
+	} else {
+		err = h.exportCSV(ctx, c, userID.(int), req)
+	}
+	if err != nil {
+		// Headers are gone: cut the stream so the client sees an incomplete download
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+func (h *OrderHandler) exportCSV(ctx context.Context, c *gin.Context, userID int, req *dto.OrderExportRequest) error {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.Write(dto.OrderExportColumns); err != nil {
+		return err
+	}
+
+	rows := 0
+	err := h.orderService.ExportFilledOrders(ctx, userID, req, func(row dto.OrderExportRow) error {
+		if err := writer.Write(row.CSVRecord()); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushEvery == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+		return writer.Error()
+	})
+
+	writer.Flush()
+	c.Writer.Flush()
+	if err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func (h *OrderHandler) exportJSON(ctx context.Context, c *gin.Context, userID int, req *dto.OrderExportRequest) error {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	if _, err := c.Writer.WriteString("["); err != nil {
+		return err
+	}
+
+	rows := 0
+	err := h.orderService.ExportFilledOrders(ctx, userID, req, func(row dto.OrderExportRow) error {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if rows > 0 {
+			if _, err := c.Writer.WriteString(","); err != nil {
+				return err
+			}
+		}
+		if _, err := c.Writer.Write(data); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		c.Writer.Flush()
+		return err
+	}
+
+	_, err = c.Writer.WriteString("]")
+	c.Writer.Flush()
+	return err
+}
+
+// orderFilterFromQuery reads the pagination, filter and sort query params:
+// page, page_size (or limit), cursor, status, type, symbol, order_kind, from, to
+// (RFC 3339), min_total, max_total, sort_by (created_at, total, price) and sort_order
+func orderFilterFromQuery(c *gin.Context) (*dto.OrderFilterRequest, error) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", c.DefaultQuery("limit", "50")))
 	status := c.Query("status")
 	orderType := c.Query("type")
 	symbol := c.Query("symbol")
+	orderKind := c.Query("order_kind")
 
 	if page < 1 {
 		page = 1
@@ -188,59 +515,80 @@ func (h *OrderHandler) ListUserOrders(c *gin.Context) {
 		pageSize = 50
 	}
 
-	var statusPtr *models.OrderStatus
-	if status != "" {
-		statusPtr = (*models.OrderStatus)(&status)
+	filter := &dto.OrderFilterRequest{
+		SortBy:    dto.ParseOrderSortField(c.Query("sort_by")),
+		SortOrder: dto.SortOrder(c.Query("sort_order")),
+		Cursor:    c.Query("cursor"),
+		Limit:     pageSize,
+		Page:      page,
 	}
 
-	var symbolPtr *string
+	if status != "" {
+		filter.Status = (*models.OrderStatus)(&status)
+	}
 	if symbol != "" {
-		symbolPtr = &symbol
+		filter.CryptoSymbol = &symbol
 	}
-
-	var typePtr *models.OrderType
 	if orderType != "" {
-		typePtr = (*models.OrderType)(&orderType)
+		filter.Type = (*models.OrderType)(&orderType)
+	}
+	if orderKind != "" {
+		filter.OrderKind = (*models.OrderKind)(&orderKind)
 	}
 
-	filter := &dto.OrderFilterRequest{
-		Status:       statusPtr,
-		CryptoSymbol: symbolPtr,
-		Type:         typePtr,
-		Limit:        pageSize,
-		Page:         page,
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if raw := c.Query(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s, expected RFC 3339", param)
+			}
+			*target = &t
+		}
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
-	defer cancel()
+	for param, target := range map[string]**decimal.Decimal{"min_total": &filter.MinTotal, "max_total": &filter.MaxTotal} {
+		if raw := c.Query(param); raw != "" {
+			value, err := decimal.NewFromString(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: must be a valid number", param)
+			}
+			*target = &value
+		}
+	}
 
-	orders, total, summary, err := h.orderService.ListUserOrders(ctx, userID.(int), filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	return filter, nil
+}
+
+// writeListError answers 400 for invalid filters and 500 otherwise
+func writeListError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrOrderValidation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
 
-	responses := make([]*OrderResponse, len(orders))
-	for i, order := range orders {
+// newOrderListResponse builds the list response; total_pages is only meaningful
+// for offset pagination, cursor clients follow next_cursor while has_more is true
+func (h *OrderHandler) newOrderListResponse(page *dto.OrderPage, filter *dto.OrderFilterRequest, summary *dto.OrdersSummary) *OrderListResponse {
+	responses := make([]*OrderResponse, len(page.Orders))
+	for i, order := range page.Orders {
 		responses[i] = h.convertToOrderResponse(&order)
 	}
 
-	totalPages := (total + int64(pageSize) - 1) / int64(pageSize)
-
-	response := &OrderListResponse{
+	return &OrderListResponse{
 		Orders:     responses,
-		Total:      total,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
+		Total:      page.Total,
+		Page:       filter.Page,
+		PageSize:   filter.Limit,
+		TotalPages: (page.Total + int64(filter.Limit) - 1) / int64(filter.Limit),
+		HasMore:    page.HasMore,
+		NextCursor: page.NextCursor,
 		Summary:    summary,
 	}
-
-	c.JSON(http.StatusOK, response)
 }
 
-// UpdateOrder comentado - no está en OrderServiceSimple (sistema simplificado)
-/*
+// UpdateOrder modifica precio y/o cantidad de una orden limit pendiente del usuario
 func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
@@ -263,47 +611,40 @@ func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
 	defer cancel()
 
-	existingOrder, err := h.orderService.GetOrder(ctx, orderID, userID.(int))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
-		return
+	if userRole, exists := c.Get("user_role"); exists {
+		ctx = context.WithValue(ctx, "user_role", userRole)
 	}
 
-	if existingOrder.UserID != userID.(int) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	updatedOrder, err := h.orderService.AmendOrder(ctx, orderID, userID.(int), req.toDTO())
+	if err != nil {
+		h.writeOrderChangeError(c, err)
 		return
 	}
 
-	updates := make(map[string]interface{})
+	response := h.convertToOrderResponse(updatedOrder)
+	c.JSON(http.StatusOK, response)
+}
 
-	if req.OrderPrice != "" {
-		orderPrice, err := decimal.NewFromString(req.OrderPrice)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order price format"})
-			return
-		}
-		updates["order_price"] = orderPrice
+// AdminUpdateOrder modifica una orden limit pendiente de cualquier usuario
+func (h *OrderHandler) AdminUpdateOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
 	}
 
-	if req.Quantity != "" {
-		quantity, err := decimal.NewFromString(req.Quantity)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid quantity format"})
-			return
-		}
-		if quantity.LessThanOrEqual(decimal.Zero) {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "quantity must be greater than zero"})
-			return
-		}
-		updates["quantity"] = quantity
+	var req UpdateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	dtoReq := &dto.UpdateOrderRequest{}
-	// Convert updates map to DTO fields as needed
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
 
-	updatedOrder, err := h.orderService.UpdateOrder(ctx, orderID, dtoReq, userID.(int))
+	updatedOrder, err := h.orderService.AdminAmendOrder(ctx, orderID, req.toDTO())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		h.writeOrderChangeError(c, err)
 		return
 	}
 
@@ -329,26 +670,191 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
 	defer cancel()
 
-	existingOrder, err := h.orderService.GetOrder(ctx, orderID, userID.(int))
+	if err := h.orderService.CancelOrder(ctx, orderID, userID.(int), reason); err != nil {
+		h.writeOrderChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled successfully"})
+}
+
+// AdminCancelOrder cancela una orden pendiente de cualquier usuario
+func (h *OrderHandler) AdminCancelOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	reason := c.DefaultQuery("reason", "cancelled by admin")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	if err := h.orderService.AdminCancelOrder(ctx, orderID, reason); err != nil {
+		h.writeOrderChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled successfully"})
+}
+
+// AdminListOrders lists the orders of every user, optionally filtered by user_id
+func (h *OrderHandler) AdminListOrders(c *gin.Context) {
+	baseFilter, err := orderFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter := &dto.AdminOrderFilterRequest{OrderFilterRequest: *baseFilter}
+
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := strconv.Atoi(raw)
+		if err != nil || userID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	page, err := h.orderService.AdminListOrders(ctx, filter)
+	if err != nil {
+		writeListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.newOrderListResponse(page, &filter.OrderFilterRequest, nil))
+}
+
+// AdminGetOrder returns an order of any user
+func (h *OrderHandler) AdminGetOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	order, err := h.orderService.AdminGetOrder(ctx, orderID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
 		return
 	}
 
-	if existingOrder.UserID != userID.(int) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	c.JSON(http.StatusOK, h.convertToOrderResponse(order))
+}
+
+// AdminForceCancelOrder cancels an open order of any user; the reason is mandatory
+// and is sent to the user in the cancellation event
+func (h *OrderHandler) AdminForceCancelOrder(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order ID is required"})
+		return
+	}
+
+	var req dto.ForceCancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	defer cancel()
+
+	if err := h.orderService.AdminCancelOrder(ctx, orderID, req.Reason); err != nil {
+		h.writeOrderChangeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled successfully", "order_id": orderID, "reason": req.Reason})
+}
+
+// AdminBulkFailOrders marks stuck orders as failed and releases their reserved funds.
+// Orders that cannot be failed are reported as skipped with the reason.
+func (h *OrderHandler) AdminBulkFailOrders(c *gin.Context) {
+	var req dto.BulkFailOrdersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	result, err := h.orderService.AdminFailOrders(ctx, &req)
+	if err != nil {
+		h.writeOrderChangeError(c, err)
 		return
 	}
 
-	err = h.orderService.CancelOrder(ctx, orderID, userID.(int), reason)
+	c.Set(middleware.AuditDetailsKey, map[string]interface{}{
+		"failed":  result.Failed,
+		"skipped": result.Skipped,
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// GetAdminStatistics returns order counts, filled volume, fee revenue and the per-symbol breakdown
+func (h *OrderHandler) GetAdminStatistics(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	stats, err := h.orderService.GetAdminStatistics(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled successfully"})
+	c.JSON(http.StatusOK, stats)
+}
+
+// writeOrderChangeError traduce los errores de cancel/amend a códigos HTTP
+func (h *OrderHandler) writeOrderChangeError(c *gin.Context, err error) {
+	if writeRiskRejection(c, err) {
+		return
+	}
+
+	msg := err.Error()
+	switch {
+	case errors.Is(err, repositories.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "order was modified concurrently, reload and retry"})
+	case strings.Contains(msg, "access denied"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	case strings.Contains(msg, "order not found"):
+		c.JSON(http.StatusNotFound, gin.H{"error": "order not found"})
+	case errors.Is(err, services.ErrOrderValidation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	case strings.Contains(msg, "cannot be"):
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+	case strings.Contains(msg, "insufficient balance"), strings.Contains(msg, "insufficient holdings"):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
+
+// writeRiskRejection answers 422 with the reason code when err is a pre-trade risk
+// rejection, and reports whether it did
+func writeRiskRejection(c *gin.Context, err error) bool {
+	var rejection *models.RiskRejection
+	if !errors.As(err, &rejection) {
+		return false
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":  err.Error(),
+		"code":   rejection.Code,
+		"limit":  rejection.Limit,
+		"actual": rejection.Actual,
+	})
+	return true
 }
-*/
 
 // ExecuteOrder comentado - no está en OrderServiceSimple (sistema simplificado)
 /*
@@ -404,11 +910,42 @@ func (h *OrderHandler) convertToOrderResponse(order *models.Order) *OrderRespons
 		OrderPrice:    order.Price.String(), // Simplificado: solo Price
 		TotalAmount:   order.TotalAmount.String(),
 		Fee:           order.Fee.String(),
-		FeePercentage: "0.1", // Siempre 0.1% en sistema simplificado
+		FeePercentage: "0.1", // Órdenes anteriores a la tabla de comisiones
 		CreatedAt:     order.CreatedAt,
 		UpdatedAt:     order.UpdatedAt,
 		ExecutedAt:    order.ExecutedAt,
+		Version:       order.Version,
+		TriggeredAt:   order.TriggeredAt,
+		FilledLeg:     order.FilledLeg,
 		// CancelledAt eliminado en modelo simplificado
+		FilledQuantity: order.FilledQuantity.String(),
+		RemainingQty:   order.RemainingQuantity().String(),
+		FillCount:      order.FillCount,
+		TimeInForce:    string(models.TimeInForceGTC),
+		ExpiresAt:      order.ExpiresAt,
+		ExpiredAt:      order.ExpiredAt,
+	}
+
+	if order.TimeInForce != "" {
+		response.TimeInForce = string(order.TimeInForce)
+	}
+
+	if order.AvgFillPrice != nil {
+		response.AvgFillPrice = order.AvgFillPrice.String()
+	}
+
+	if order.TriggerPrice != nil {
+		response.TriggerPrice = order.TriggerPrice.String()
+	}
+
+	if order.Saga != nil {
+		response.ExecutionCost = order.Saga.Cost
+	}
+
+	if order.FeeDetail != nil {
+		response.FeePercentage = order.FeeDetail.FeePercentage.Mul(decimal.NewFromInt(100)).String()
+		response.FeeType = string(order.FeeDetail.FeeType)
+		response.FeeRule = order.FeeDetail.Rule
 	}
 
 	return response
@@ -421,6 +958,7 @@ func (h *OrderHandler) convertToExecutionResponse(result *models.ExecutionResult
 		Success:       result.Success,
 		Error:         result.Error,
 		ExecutionTime: result.ExecutionTime,
+		Cost:          result.Cost,
 	}
 
 	// Modelo simplificado no tiene estos detalles
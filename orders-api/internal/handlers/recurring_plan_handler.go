@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+	"orders-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecurringPlanHandler struct {
+	planService *services.RecurringPlanService
+}
+
+func NewRecurringPlanHandler(planService *services.RecurringPlanService) *RecurringPlanHandler {
+	return &RecurringPlanHandler{
+		planService: planService,
+	}
+}
+
+// CreatePlan creates a recurring buy plan for the authenticated user
+func (h *RecurringPlanHandler) CreatePlan(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req dto.CreateRecurringPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	plan, err := h.planService.CreatePlan(ctx, &req, userID.(int))
+	if err != nil {
+		h.writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// ListPlans lists the authenticated user's recurring plans
+func (h *RecurringPlanHandler) ListPlans(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	plans, err := h.planService.ListPlans(ctx, userID.(int))
+	if err != nil {
+		h.writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plans": plans, "total": len(plans)})
+}
+
+// GetPlan returns one of the authenticated user's plans
+func (h *RecurringPlanHandler) GetPlan(c *gin.Context) {
+	h.withPlan(c, h.planService.GetPlan)
+}
+
+// PausePlan stops a plan from running until it is resumed
+func (h *RecurringPlanHandler) PausePlan(c *gin.Context) {
+	h.withPlan(c, h.planService.PausePlan)
+}
+
+// ResumePlan reactivates a paused plan from its next future run
+func (h *RecurringPlanHandler) ResumePlan(c *gin.Context) {
+	h.withPlan(c, h.planService.ResumePlan)
+}
+
+// DeletePlan deletes a plan; orders it already placed are left untouched
+func (h *RecurringPlanHandler) DeletePlan(c *gin.Context) {
+	planID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := h.planService.DeletePlan(ctx, planID, userID.(int)); err != nil {
+		h.writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring plan deleted successfully"})
+}
+
+// ListPlanRuns returns the plan's execution history, newest first
+func (h *RecurringPlanHandler) ListPlanRuns(c *gin.Context) {
+	planID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	runs, err := h.planService.ListPlanRuns(ctx, planID, userID.(int))
+	if err != nil {
+		h.writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"plan_id": planID, "runs": runs, "total": len(runs)})
+}
+
+func (h *RecurringPlanHandler) withPlan(c *gin.Context, action func(context.Context, string, int) (*models.RecurringPlan, error)) {
+	planID := c.Param("id")
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	plan, err := action(ctx, planID, userID.(int))
+	if err != nil {
+		h.writePlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *RecurringPlanHandler) writePlanError(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, repositories.ErrPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "recurring plan not found"})
+	case strings.Contains(msg, "access denied"):
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	case errors.Is(err, services.ErrOrderValidation),
+		errors.Is(err, services.ErrInvalidSymbol),
+		errors.Is(err, services.ErrTradingSuspended):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
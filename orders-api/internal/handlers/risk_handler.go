@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/repositories"
+	"orders-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RiskHandler struct {
+	riskService *services.RiskService
+}
+
+func NewRiskHandler(riskService *services.RiskService) *RiskHandler {
+	return &RiskHandler{
+		riskService: riskService,
+	}
+}
+
+// GetRiskPolicy returns the pre-trade risk limits currently in force, per role
+func (h *RiskHandler) GetRiskPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.riskService.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateRiskPolicy replaces the risk limits; new orders pick them up right away on
+// this replica and within the cache TTL on the others
+func (h *RiskHandler) UpdateRiskPolicy(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req dto.UpdateRiskPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.riskService.UpdatePolicy(ctx, &req, adminID.(int))
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrRiskPolicyConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "risk policy was modified concurrently, reload and retry"})
+		case strings.Contains(err.Error(), "validation error"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
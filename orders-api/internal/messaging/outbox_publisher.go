@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// OutboxPublisher implementa los Publish* guardando el evento en el outbox en lugar
+// de mandarlo a RabbitMQ. Llamado con el contexto de la transacción de la orden,
+// el evento queda escrito solo si la orden también; el relay lo publica después.
+type OutboxPublisher struct {
+	outbox repositories.OutboxRepository
+}
+
+// NewOutboxPublisher crea un publisher que escribe en el outbox
+func NewOutboxPublisher(outbox repositories.OutboxRepository) *OutboxPublisher {
+	return &OutboxPublisher{outbox: outbox}
+}
+
+// PublishOrderCreated guarda el evento de orden creada
+func (p *OutboxPublisher) PublishOrderCreated(ctx context.Context, order *models.Order) error {
+	return p.add(ctx, order, "orders.created", NewOrderEvent("created", order))
+}
+
+// PublishOrderExecuted guarda el evento de orden ejecutada
+func (p *OutboxPublisher) PublishOrderExecuted(ctx context.Context, order *models.Order) error {
+	return p.add(ctx, order, "orders.executed", NewOrderEvent("executed", order))
+}
+
+// PublishOrderPartiallyFilled guarda el evento de orden parcialmente llenada
+func (p *OutboxPublisher) PublishOrderPartiallyFilled(ctx context.Context, order *models.Order) error {
+	return p.add(ctx, order, "orders.partially_filled", NewOrderEvent("partially_filled", order))
+}
+
+// PublishOrderCancelled guarda el evento de orden cancelada
+func (p *OutboxPublisher) PublishOrderCancelled(ctx context.Context, order *models.Order, reason string) error {
+	event := NewOrderEvent("cancelled", order)
+	event.ErrorMessage = reason
+
+	return p.add(ctx, order, "orders.cancelled", event)
+}
+
+// PublishOrderAmended guarda el evento de orden modificada con los valores anteriores
+func (p *OutboxPublisher) PublishOrderAmended(ctx context.Context, order *models.Order, previous *models.Order) error {
+	event := NewOrderEvent("amended", order)
+	event.PreviousPrice = previous.Price.String()
+	event.PreviousQuantity = previous.Quantity.String()
+
+	return p.add(ctx, order, "orders.amended", event)
+}
+
+// PublishOrderFailed guarda el evento de orden fallida
+func (p *OutboxPublisher) PublishOrderFailed(ctx context.Context, order *models.Order, reason string) error {
+	event := NewOrderEvent("failed", order)
+	event.ErrorMessage = reason
+
+	return p.add(ctx, order, "orders.failed", event)
+}
+
+// PublishOrderExpired guarda el evento de orden vencida por su time in force
+func (p *OutboxPublisher) PublishOrderExpired(ctx context.Context, order *models.Order, reason string) error {
+	event := NewOrderEvent("expired", order)
+	event.ErrorMessage = reason
+
+	return p.add(ctx, order, "orders.expired", event)
+}
+
+// PublishPlanRunSkipped guarda la notificación de una corrida de un plan recurrente
+// salteada por saldo insuficiente
+func (p *OutboxPublisher) PublishPlanRunSkipped(ctx context.Context, plan *models.RecurringPlan, run *models.PlanRun) error {
+	body, err := json.Marshal(NewPlanRunEvent("run_skipped", plan, run))
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.outbox.Add(ctx, models.NewPlanRunOutboxEvent(run, "recurring_plans.run_skipped", body))
+}
+
+func (p *OutboxPublisher) add(ctx context.Context, order *models.Order, routingKey string, event *OrderEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	return p.outbox.Add(ctx, models.NewOutboxEvent(order, routingKey, body))
+}
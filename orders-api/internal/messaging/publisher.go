@@ -5,34 +5,84 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/streadway/amqp"
 	"orders-api/internal/models"
 )
 
-// Publisher simplificado para eventos de órdenes
+// confirmTimeout tiempo máximo de espera del ack de RabbitMQ por mensaje
+const confirmTimeout = 5 * time.Second
+
+// Publisher simplificado para eventos de órdenes.
+// El canal está en modo confirm: cada publicación espera el ack del broker.
 type Publisher struct {
 	connection *amqp.Connection
 	channel    *amqp.Channel
 	exchange   string
+
+	mu       sync.Mutex // serializa publicación + espera del ack
+	confirms chan amqp.Confirmation
+	lastTag  uint64 // delivery tag de la última publicación
 }
 
 // OrderEvent evento simplificado de orden
 type OrderEvent struct {
-	EventType     string    `json:"event_type"` // created, executed, cancelled, failed
-	OrderID       string    `json:"order_id"`
-	OrderNumber   string    `json:"order_number"`
-	UserID        int       `json:"user_id"`
-	Type          string    `json:"type"`   // buy, sell
-	Status        string    `json:"status"` // pending, executed, cancelled, failed
-	CryptoSymbol  string    `json:"crypto_symbol"`
-	Quantity      string    `json:"quantity"`
-	Price         string    `json:"price"`
-	TotalAmount   string    `json:"total_amount"`
-	Fee           string    `json:"fee"`
-	Timestamp     time.Time `json:"timestamp"`
-	ErrorMessage  string    `json:"error_message,omitempty"`
+	EventType        string     `json:"event_type"` // created, partially_filled, executed, cancelled, amended, failed, expired
+	OrderID          string     `json:"order_id"`
+	OrderNumber      string     `json:"order_number"`
+	UserID           int        `json:"user_id"`
+	Type             string     `json:"type"`   // buy, sell
+	Status           string     `json:"status"` // pending, partially_filled, executed, cancelled, failed, expired
+	CryptoSymbol     string     `json:"crypto_symbol"`
+	Quantity         string     `json:"quantity"`
+	Price            string     `json:"price"`
+	TotalAmount      string     `json:"total_amount"`
+	Fee              string     `json:"fee"`
+	Timestamp        time.Time  `json:"timestamp"`
+	ErrorMessage     string     `json:"error_message,omitempty"`
+	Version          int64      `json:"version"`                     // Versión de la orden luego del cambio
+	PreviousPrice    string     `json:"previous_price,omitempty"`    // Solo en amended
+	PreviousQuantity string     `json:"previous_quantity,omitempty"` // Solo en amended
+	FilledQuantity   string     `json:"filled_quantity,omitempty"`   // Con fills: cantidad llenada hasta ahora
+	AvgFillPrice     string     `json:"avg_fill_price,omitempty"`    // Con fills: precio promedio ponderado
+	TimeInForce      string     `json:"time_in_force,omitempty"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"` // Solo en órdenes gtd
+}
+
+// PlanRunEvent notificación de una corrida de un plan recurrente
+type PlanRunEvent struct {
+	EventType    string    `json:"event_type"` // run_skipped
+	PlanID       string    `json:"plan_id"`
+	RunID        string    `json:"run_id"`
+	UserID       int       `json:"user_id"`
+	CryptoSymbol string    `json:"crypto_symbol"`
+	Amount       string    `json:"amount"`
+	Frequency    string    `json:"frequency"`
+	Status       string    `json:"status"` // executed, skipped, failed
+	ScheduledAt  time.Time `json:"scheduled_at"`
+	NextRunAt    time.Time `json:"next_run_at"`
+	Reason       string    `json:"reason,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// NewPlanRunEvent arma la notificación de una corrida de un plan
+func NewPlanRunEvent(eventType string, plan *models.RecurringPlan, run *models.PlanRun) *PlanRunEvent {
+	return &PlanRunEvent{
+		EventType:    eventType,
+		PlanID:       plan.ID.Hex(),
+		RunID:        run.RunID,
+		UserID:       plan.UserID,
+		CryptoSymbol: plan.CryptoSymbol,
+		Amount:       plan.Amount.String(),
+		Frequency:    string(plan.Frequency),
+		Status:       string(run.Status),
+		ScheduledAt:  run.ScheduledAt,
+		NextRunAt:    plan.NextRunAfter(run.RunAt),
+		Reason:       run.Reason,
+		Timestamp:    time.Now(),
+	}
 }
 
 // NewPublisher crea un nuevo publisher simplificado
@@ -66,19 +116,26 @@ func NewPublisher(rabbitmqURL string) (*Publisher, error) {
 		return nil, fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
 	log.Printf("RabbitMQ publisher initialized with exchange: %s", exchangeName)
 
 	return &Publisher{
 		connection: conn,
 		channel:    ch,
 		exchange:   exchangeName,
+		confirms:   ch.NotifyPublish(make(chan amqp.Confirmation, 1)),
 	}, nil
 }
 
-// PublishOrderCreated publica evento de orden creada
-func (p *Publisher) PublishOrderCreated(ctx context.Context, order *models.Order) error {
+// NewOrderEvent arma el evento de una orden (sin los campos propios de amended)
+func NewOrderEvent(eventType string, order *models.Order) *OrderEvent {
 	event := &OrderEvent{
-		EventType:    "created",
+		EventType:    eventType,
 		OrderID:      order.ID.Hex(),
 		OrderNumber:  order.OrderNumber,
 		UserID:       order.UserID,
@@ -90,73 +147,84 @@ func (p *Publisher) PublishOrderCreated(ctx context.Context, order *models.Order
 		TotalAmount:  order.TotalAmount.String(),
 		Fee:          order.Fee.String(),
 		Timestamp:    time.Now(),
+		Version:      order.Version,
+		TimeInForce:  string(order.TimeInForce),
+		ExpiresAt:    order.ExpiresAt,
 	}
+	if order.AvgFillPrice != nil {
+		event.FilledQuantity = order.FilledQuantity.String()
+		event.AvgFillPrice = order.AvgFillPrice.String()
+	}
+	return event
+}
 
-	return p.publish("orders.created", event)
+// PublishOrderCreated publica evento de orden creada
+func (p *Publisher) PublishOrderCreated(ctx context.Context, order *models.Order) error {
+	return p.publish("orders.created", NewOrderEvent("created", order))
 }
 
 // PublishOrderExecuted publica evento de orden ejecutada
 func (p *Publisher) PublishOrderExecuted(ctx context.Context, order *models.Order) error {
-	event := &OrderEvent{
-		EventType:    "executed",
-		OrderID:      order.ID.Hex(),
-		OrderNumber:  order.OrderNumber,
-		UserID:       order.UserID,
-		Type:         string(order.Type),
-		Status:       string(order.Status),
-		CryptoSymbol: order.CryptoSymbol,
-		Quantity:     order.Quantity.String(),
-		Price:        order.Price.String(),
-		TotalAmount:  order.TotalAmount.String(),
-		Fee:          order.Fee.String(),
-		Timestamp:    time.Now(),
-	}
+	return p.publish("orders.executed", NewOrderEvent("executed", order))
+}
 
-	return p.publish("orders.executed", event)
+// PublishOrderPartiallyFilled publica evento de orden parcialmente llenada
+func (p *Publisher) PublishOrderPartiallyFilled(ctx context.Context, order *models.Order) error {
+	return p.publish("orders.partially_filled", NewOrderEvent("partially_filled", order))
 }
 
 // PublishOrderCancelled publica evento de orden cancelada
 func (p *Publisher) PublishOrderCancelled(ctx context.Context, order *models.Order, reason string) error {
-	event := &OrderEvent{
-		EventType:    "cancelled",
-		OrderID:      order.ID.Hex(),
-		OrderNumber:  order.OrderNumber,
-		UserID:       order.UserID,
-		Type:         string(order.Type),
-		Status:       string(order.Status),
-		CryptoSymbol: order.CryptoSymbol,
-		Quantity:     order.Quantity.String(),
-		Price:        order.Price.String(),
-		TotalAmount:  order.TotalAmount.String(),
-		Fee:          order.Fee.String(),
-		Timestamp:    time.Now(),
-		ErrorMessage: reason,
-	}
+	event := NewOrderEvent("cancelled", order)
+	event.ErrorMessage = reason
 
 	return p.publish("orders.cancelled", event)
 }
 
+// PublishOrderAmended publica evento de orden modificada con los valores anteriores
+func (p *Publisher) PublishOrderAmended(ctx context.Context, order *models.Order, previous *models.Order) error {
+	event := NewOrderEvent("amended", order)
+	event.PreviousPrice = previous.Price.String()
+	event.PreviousQuantity = previous.Quantity.String()
+
+	return p.publish("orders.amended", event)
+}
+
 // PublishOrderFailed publica evento de orden fallida
 func (p *Publisher) PublishOrderFailed(ctx context.Context, order *models.Order, reason string) error {
-	event := &OrderEvent{
-		EventType:    "failed",
-		OrderID:      order.ID.Hex(),
-		OrderNumber:  order.OrderNumber,
-		UserID:       order.UserID,
-		Type:         string(order.Type),
-		Status:       string(order.Status),
-		CryptoSymbol: order.CryptoSymbol,
-		Quantity:     order.Quantity.String(),
-		Price:        order.Price.String(),
-		TotalAmount:  order.TotalAmount.String(),
-		Fee:          order.Fee.String(),
-		Timestamp:    time.Now(),
-		ErrorMessage: reason,
-	}
+	event := NewOrderEvent("failed", order)
+	event.ErrorMessage = reason
 
 	return p.publish("orders.failed", event)
 }
 
+// PublishOrderExpired publica evento de orden vencida por su time in force
+func (p *Publisher) PublishOrderExpired(ctx context.Context, order *models.Order, reason string) error {
+	event := NewOrderEvent("expired", order)
+	event.ErrorMessage = reason
+
+	return p.publish("orders.expired", event)
+}
+
+// PublishPlanRunSkipped publica la notificación de una corrida de un plan recurrente
+// salteada por saldo insuficiente
+func (p *Publisher) PublishPlanRunSkipped(ctx context.Context, plan *models.RecurringPlan, run *models.PlanRun) error {
+	body, err := json.Marshal(NewPlanRunEvent("run_skipped", plan, run))
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, confirmTimeout)
+	defer cancel()
+
+	if err := p.PublishMessage(ctx, "recurring_plans.run_skipped", run.RunID, body); err != nil {
+		return err
+	}
+
+	log.Printf("Published event: recurring_plans.run_skipped for plan %s", plan.ID.Hex())
+	return nil
+}
+
 // publish publica un evento al exchange
 func (p *Publisher) publish(routingKey string, event *OrderEvent) error {
 	body, err := json.Marshal(event)
@@ -164,25 +232,62 @@ func (p *Publisher) publish(routingKey string, event *OrderEvent) error {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	err = p.channel.Publish(
-		p.exchange,  // exchange
-		routingKey,  // routing key
-		false,       // mandatory
-		false,       // immediate
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+
+	if err := p.PublishMessage(ctx, routingKey, "", body); err != nil {
+		return err
+	}
+
+	log.Printf("Published event: %s for order %s", routingKey, event.OrderID)
+	return nil
+}
+
+// PublishMessage publica un mensaje ya serializado y espera la confirmación del broker.
+// messageID viaja como MessageId para que los consumidores descarten duplicados.
+func (p *Publisher) PublishMessage(ctx context.Context, routingKey, messageID string, body []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.channel.Publish(
+		p.exchange, // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
 		amqp.Publishing{
 			ContentType:  "application/json",
 			DeliveryMode: amqp.Persistent, // mensajes persistentes
+			MessageId:    messageID,
 			Timestamp:    time.Now(),
 			Body:         body,
 		},
 	)
-
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
-	log.Printf("Published event: %s for order %s", routingKey, event.OrderID)
-	return nil
+	// El broker numera los mensajes del canal en orden desde 1
+	p.lastTag++
+	tag := p.lastTag
+
+	for {
+		select {
+		case confirmation, ok := <-p.confirms:
+			if !ok {
+				return fmt.Errorf("channel closed before confirming message")
+			}
+			// Acks atrasados de publicaciones cuyo tiempo de espera ya venció
+			if confirmation.DeliveryTag < tag {
+				continue
+			}
+			if !confirmation.Ack {
+				return fmt.Errorf("message was rejected by the broker")
+			}
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for publish confirmation: %w", ctx.Err())
+		}
+	}
 }
 
 // Close cierra la conexión
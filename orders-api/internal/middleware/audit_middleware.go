@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"orders-api/internal/models"
+)
+
+// AuditDetailsKey is the gin context key handlers use to attach details (a
+// map[string]interface{}) to the audit entry of the current admin action
+const AuditDetailsKey = "audit_details"
+
+// AuditRecorder stores admin audit entries
+type AuditRecorder interface {
+	Record(ctx context.Context, entry *models.AdminAuditEntry) error
+}
+
+type AuditMiddleware struct {
+	recorder    AuditRecorder
+	logger      *logrus.Logger
+	maxBodySize int64
+}
+
+func NewAuditMiddleware(recorder AuditRecorder, logger *logrus.Logger) *AuditMiddleware {
+	return &AuditMiddleware{
+		recorder:    recorder,
+		logger:      logger,
+		maxBodySize: 4096,
+	}
+}
+
+// AuditAdminActions records every state-changing request (anything but GET, HEAD
+// and OPTIONS) that reaches the admin routes, whether it succeeded or not. It must
+// run after the auth middleware so the admin is known.
+func (a *AuditMiddleware) AuditAdminActions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		var requestBody []byte
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err == nil {
+				c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+				requestBody = body
+				if int64(len(requestBody)) > a.maxBodySize {
+					requestBody = requestBody[:a.maxBodySize]
+				}
+			}
+		}
+
+		c.Next()
+
+		entry := &models.AdminAuditEntry{
+			Action:     c.Request.Method + " " + c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Request:    string(requestBody),
+			StatusCode: c.Writer.Status(),
+			ClientIP:   c.ClientIP(),
+			CreatedAt:  time.Now(),
+		}
+		entry.Success = entry.StatusCode >= 200 && entry.StatusCode < 300
+
+		if userID, exists := c.Get("user_id"); exists {
+			entry.AdminID, _ = userID.(int)
+		}
+		if email, exists := c.Get("user_email"); exists {
+			entry.AdminEmail, _ = email.(string)
+		}
+		if requestID, exists := c.Get("request_id"); exists {
+			entry.RequestID, _ = requestID.(string)
+		}
+		if details, exists := c.Get(AuditDetailsKey); exists {
+			entry.Details, _ = details.(map[string]interface{})
+		}
+		if len(c.Params) > 0 {
+			entry.Params = make(map[string]string, len(c.Params))
+			for _, param := range c.Params {
+				entry.Params[param.Key] = param.Value
+			}
+		}
+
+		// The request context may already be cancelled once the response is written
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := a.recorder.Record(ctx, entry); err != nil {
+			a.logger.WithFields(logrus.Fields{
+				"admin_id":    entry.AdminID,
+				"action":      entry.Action,
+				"path":        entry.Path,
+				"status_code": entry.StatusCode,
+			}).WithError(err).Error("Failed to record admin action")
+		}
+	}
+}
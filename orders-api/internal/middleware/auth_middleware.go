@@ -7,12 +7,13 @@ import (
 	"strings"
 	"time"
 
+	"cryptosim/jwks"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 type AuthMiddleware struct {
-	secretKey       []byte
+	jwks            *jwks.Cache
 	issuer          string
 	audience        string
 	skipPaths       map[string]bool
@@ -27,8 +28,11 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// AuthConfig configures token verification. Tokens are signed by users-api with
+// asymmetric keys that are fetched from JWKSURL and cached for JWKSCacheTTL.
 type AuthConfig struct {
-	SecretKey       string
+	JWKSURL         string
+	JWKSCacheTTL    time.Duration
 	Issuer          string
 	Audience        string
 	SkipPaths       []string
@@ -47,7 +51,7 @@ func NewAuthMiddleware(config *AuthConfig) *AuthMiddleware {
 	}
 
 	return &AuthMiddleware{
-		secretKey:       []byte(config.SecretKey),
+		jwks:            jwks.NewCache(config.JWKSURL, config.JWKSCacheTTL),
 		issuer:          config.Issuer,
 		audience:        config.Audience,
 		skipPaths:       skipPaths,
@@ -124,12 +128,10 @@ func (a *AuthMiddleware) ValidateToken() gin.HandlerFunc {
 
 func (a *AuthMiddleware) parseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return a.secretKey, nil
-	})
+		// The key is picked by the kid header and must match the signing method
+		kid, _ := token.Header["kid"].(string)
+		return a.jwks.Key(kid, token.Method.Alg())
+	}, jwt.WithValidMethods([]string{"RS256", "EdDSA"}))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -358,9 +360,10 @@ func (a *AuthMiddleware) ValidateOwnership() gin.HandlerFunc {
 
 func DefaultAuthConfig() *AuthConfig {
 	return &AuthConfig{
-		SecretKey: "your-secret-key-here", // Should come from environment
-		Issuer:    "orders-api",
-		Audience:  "cryptosim",
+		JWKSURL:      "http://localhost:8001/.well-known/jwks.json", // Should come from environment
+		JWKSCacheTTL: 5 * time.Minute,
+		Issuer:       "users-api",
+		Audience:     "cryptosim",
 		SkipPaths: []string{
 			"/health",
 			"/health/live",
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cryptosim/jwks"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testJWKSServer serves the public part of keys the way users-api does
+type testJWKSServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    []jwks.JSONWebKey
+	fetches int32
+}
+
+func newTestJWKSServer(t *testing.T) *testJWKSServer {
+	s := &testJWKSServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.fetches, 1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(jwks.Set{Keys: s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testJWKSServer) publish(kid string, key crypto.Signer) {
+	jwk := jwks.JSONWebKey{Use: "sig", Kid: kid}
+	switch public := key.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty, jwk.Alg = "RSA", "RS256"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty, jwk.Alg, jwk.Crv = "OKP", "EdDSA", "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, jwk)
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, issuer string) string {
+	claims := &Claims{
+		UserID: 7,
+		Role:   "normal",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{"cryptosim"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func newTestAuthMiddleware(url string) *AuthMiddleware {
+	return NewAuthMiddleware(&AuthConfig{JWKSURL: url, JWKSCacheTTL: time.Minute, Issuer: "users-api", Audience: "cryptosim"})
+}
+
+func TestAuthMiddleware_ParseToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	server := newTestJWKSServer(t)
+	server.publish("rsa-1", rsaKey)
+	server.publish("ed-1", edKey)
+	auth := newTestAuthMiddleware(server.URL)
+
+	t.Run("RS256", func(t *testing.T) {
+		claims, err := auth.parseToken(signTestToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, "users-api"))
+		require.NoError(t, err)
+		assert.Equal(t, 7, claims.UserID)
+	})
+
+	t.Run("EdDSA", func(t *testing.T) {
+		claims, err := auth.parseToken(signTestToken(t, jwt.SigningMethodEdDSA, "ed-1", edKey, "users-api"))
+		require.NoError(t, err)
+		assert.Equal(t, 7, claims.UserID)
+	})
+
+	t.Run("keys are cached", func(t *testing.T) {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&server.fetches))
+	})
+
+	t.Run("HMAC tokens are rejected", func(t *testing.T) {
+		_, err := auth.parseToken(signTestToken(t, jwt.SigningMethodHS256, "rsa-1", []byte("shared-secret"), "users-api"))
+		assert.Error(t, err)
+	})
+
+	t.Run("key of another algorithm is rejected", func(t *testing.T) {
+		_, err := auth.parseToken(signTestToken(t, jwt.SigningMethodEdDSA, "rsa-1", edKey, "users-api"))
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong issuer", func(t *testing.T) {
+		_, err := auth.parseToken(signTestToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, "someone-else"))
+		assert.EqualError(t, err, "invalid issuer")
+	})
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminAuditEntry registro de una acción de un admin sobre la API de órdenes.
+// Se guarda una por cada request de escritura a las rutas /admin, salga bien o no.
+type AdminAuditEntry struct {
+	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	AdminID    int                    `bson:"admin_id" json:"admin_id"`
+	AdminEmail string                 `bson:"admin_email,omitempty" json:"admin_email,omitempty"`
+	Action     string                 `bson:"action" json:"action"` // Método y ruta, ej: POST /api/v1/admin/orders/:id/cancel
+	Path       string                 `bson:"path" json:"path"`     // Ruta pedida con los IDs reales
+	Params     map[string]string      `bson:"params,omitempty" json:"params,omitempty"`
+	Request    string                 `bson:"request,omitempty" json:"request,omitempty"` // Body de la request (truncado)
+	Details    map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"` // Lo que el handler agregó (ej: órdenes salteadas)
+	StatusCode int                    `bson:"status_code" json:"status_code"`
+	Success    bool                   `bson:"success" json:"success"` // Respondió 2xx
+	ClientIP   string                 `bson:"client_ip" json:"client_ip"`
+	RequestID  string                 `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+}
@@ -10,13 +10,39 @@ import (
 type ExecutionResult struct {
 	Success       bool            `json:"success"`
 	OrderID       string          `json:"order_id"`
-	ExecutedPrice decimal.Decimal `json:"executed_price"`
+	ExecutedPrice decimal.Decimal `json:"executed_price"` // Precio promedio ponderado de los fills
+	Quantity      decimal.Decimal `json:"quantity"`       // Cantidad que llena esta pasada
 	TotalAmount   decimal.Decimal `json:"total_amount"`
 	Fee           decimal.Decimal `json:"fee"`
+	FeeDetail     *FeeResult      `json:"fee_detail,omitempty"` // Regla de comisión aplicada
+	Fills         []Fill          `json:"fills,omitempty"`      // Niveles del libro que toma la pasada
+	Cost          *ExecutionCost  `json:"cost,omitempty"`       // Desglose de precio, slippage y comisión
 	ExecutionTime time.Duration   `json:"execution_time"`
 	Error         string          `json:"error,omitempty"`
 }
 
+// Orígenes del precio de una pasada
+const (
+	PriceSourceOrderBook = "order_book" // Se recorrieron los niveles del libro
+	PriceSourceLastPrice = "last_price" // Sin libro: todo al último precio de mercado
+)
+
+// ExecutionCost desglose del costo de una pasada. El slippage se mide contra el
+// último precio de mercado y es positivo cuando el usuario paga más (compras) o
+// recibe menos (ventas) que a ese precio.
+type ExecutionCost struct {
+	ReferencePrice decimal.Decimal `bson:"reference_price" json:"reference_price"` // Último precio de mercado
+	AveragePrice   decimal.Decimal `bson:"average_price" json:"average_price"`     // Promedio ponderado de los fills
+	Slippage       decimal.Decimal `bson:"slippage" json:"slippage"`               // Relativo: 0.002 = 0.2%
+	SlippageCost   decimal.Decimal `bson:"slippage_cost" json:"slippage_cost"`     // Diferencia contra llenar todo al precio de referencia
+	Notional       decimal.Decimal `bson:"notional" json:"notional"`               // Cantidad * precio promedio
+	Fee            decimal.Decimal `bson:"fee" json:"fee"`
+	Total          decimal.Decimal `bson:"total" json:"total"`                     // Lo que paga (compra) o recibe (venta) el usuario
+	LevelsConsumed int             `bson:"levels_consumed" json:"levels_consumed"` // Niveles del libro tomados
+	BookExhausted  bool            `bson:"book_exhausted" json:"book_exhausted"`   // El libro no alcanzó y el resto se llenó al peor nivel
+	Source         string          `bson:"source" json:"source"`                   // order_book o last_price
+}
+
 // ValidationResult resultado de validar un usuario
 type ValidationResult struct {
 	IsValid bool   `json:"is_valid"`
@@ -33,6 +59,46 @@ type BalanceResult struct {
 	Message       string          `json:"message"`
 }
 
+// HoldingResult cantidades de un activo del usuario en Portfolio API
+type HoldingResult struct {
+	Symbol    string          `json:"symbol"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Reserved  decimal.Decimal `json:"reserved"`  // Reservado por órdenes de venta pendientes
+	Available decimal.Decimal `json:"available"` // Lo que todavía se puede vender
+}
+
+// OrderSimulation resultado de simular una orden sin guardarla ni mover fondos.
+// Si la orden sería rechazada, RejectionReason dice por qué.
+type OrderSimulation struct {
+	Type              OrderType       `json:"type"`
+	OrderKind         OrderKind       `json:"order_kind"`
+	CryptoSymbol      string          `json:"crypto_symbol"`
+	Quantity          decimal.Decimal `json:"quantity"`
+	EstimatedPrice    decimal.Decimal `json:"estimated_price"`    // Promedio de los fills, o el límite si la orden queda esperando
+	EstimatedQuantity decimal.Decimal `json:"estimated_quantity"` // Lo que se llenaría ahora, o toda la orden si queda esperando
+	EstimatedFee      decimal.Decimal `json:"estimated_fee"`
+	EstimatedTotal    decimal.Decimal `json:"estimated_total"` // Lo que paga (compra) o recibe (venta) el usuario
+	Slippage          decimal.Decimal `json:"slippage"`
+	WouldFill         bool            `json:"would_fill"` // Se ejecutaría apenas creada
+	Warnings          []string        `json:"warnings"`
+	FeeDetail         *FeeResult      `json:"fee_detail,omitempty"`
+	Cost              *ExecutionCost  `json:"cost,omitempty"`
+	AvailableBalance  decimal.Decimal `json:"available_balance"`
+	ResultingBalance  decimal.Decimal `json:"resulting_balance"` // Saldo disponible después de crear (y ejecutar) la orden
+	// Solo ventas
+	AvailableHoldings *decimal.Decimal `json:"available_holdings,omitempty"`
+	ResultingHoldings *decimal.Decimal `json:"resulting_holdings,omitempty"`
+	RejectionReason   string           `json:"rejection_reason,omitempty"`
+	RejectionCode     RiskReasonCode   `json:"rejection_code,omitempty"` // Solo rechazos de los límites de riesgo
+}
+
+// Reject marca la simulación como rechazada
+func (s *OrderSimulation) Reject(reason string) *OrderSimulation {
+	s.WouldFill = false
+	s.RejectionReason = reason
+	return s
+}
+
 // PriceResult resultado de obtener precio de mercado
 type PriceResult struct {
 	Symbol      string          `json:"symbol"`
@@ -40,9 +106,26 @@ type PriceResult struct {
 	Timestamp   time.Time       `json:"timestamp"`
 }
 
-// FeeResult resultado del cálculo de comisiones
+// FeeResult resultado del cálculo de comisiones. Se guarda en la orden para saber
+// qué regla de la tabla se aplicó.
 type FeeResult struct {
-	TotalFee      decimal.Decimal `json:"total_fee"`
-	FeePercentage decimal.Decimal `json:"fee_percentage"` // 0.1% = 0.001
-	FeeType       string          `json:"fee_type"`       // "taker"
+	TotalFee      decimal.Decimal `bson:"total_fee" json:"total_fee"`
+	FeePercentage decimal.Decimal `bson:"fee_percentage" json:"fee_percentage"` // 0.1% = 0.001
+	FeeType       FeeType         `bson:"fee_type" json:"fee_type"`             // maker o taker
+	Rule          string          `bson:"rule" json:"rule"`                     // base, tier:<nombre>, symbol:<símbolo> o promotion:<nombre>
+	Volume        decimal.Decimal `bson:"volume" json:"volume"`                 // Volumen de 30 días con el que se eligió el escalón
+}
+
+// OrderBookLevel nivel de precio del libro de órdenes
+type OrderBookLevel struct {
+	Price    decimal.Decimal `json:"price"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
+// OrderBook profundidad del libro de un símbolo. Bids de mayor a menor precio,
+// asks de menor a mayor: el primer nivel es siempre el mejor.
+type OrderBook struct {
+	Symbol string           `json:"symbol"`
+	Bids   []OrderBookLevel `json:"bids"`
+	Asks   []OrderBookLevel `json:"asks"`
 }
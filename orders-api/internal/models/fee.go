@@ -0,0 +1,234 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FeeType lado de la liquidez que paga la orden
+type FeeType string
+
+const (
+	FeeTypeMaker FeeType = "maker" // Espera en el libro (limit): agrega liquidez
+	FeeTypeTaker FeeType = "taker" // Se ejecuta a mercado: toma liquidez
+)
+
+// FeeScheduleID la tabla de comisiones es un único documento
+const FeeScheduleID = "default"
+
+// MaxFeeRate tasa máxima que acepta la tabla de comisiones (5%)
+var MaxFeeRate = decimal.NewFromFloat(0.05)
+
+// Reglas que se registran en FeeResult.Rule
+const (
+	FeeRuleBase      = "base"
+	FeeRuleTier      = "tier"      // tier:<nombre>
+	FeeRuleSymbol    = "symbol"    // symbol:<símbolo>
+	FeeRulePromotion = "promotion" // promotion:<nombre>
+)
+
+// FeeTier escalón de tasas según el volumen operado por el usuario en la ventana (30 días)
+type FeeTier struct {
+	Name      string          `bson:"name" json:"name"`
+	MinVolume decimal.Decimal `bson:"min_volume" json:"min_volume"` // Volumen (USD) desde el que aplica
+	MakerRate decimal.Decimal `bson:"maker_rate" json:"maker_rate"`
+	TakerRate decimal.Decimal `bson:"taker_rate" json:"taker_rate"`
+}
+
+// SymbolFeeOverride tasas propias de un símbolo; reemplazan a la base y a los escalones
+type SymbolFeeOverride struct {
+	Symbol    string          `bson:"symbol" json:"symbol"`
+	MakerRate decimal.Decimal `bson:"maker_rate" json:"maker_rate"`
+	TakerRate decimal.Decimal `bson:"taker_rate" json:"taker_rate"`
+}
+
+// FeePromotion ventana sin comisión para todos los símbolos o solo para los listados
+type FeePromotion struct {
+	Name     string    `bson:"name" json:"name"`
+	Symbols  []string  `bson:"symbols,omitempty" json:"symbols,omitempty"` // Vacío: todos los símbolos
+	StartsAt time.Time `bson:"starts_at" json:"starts_at"`
+	EndsAt   time.Time `bson:"ends_at" json:"ends_at"`
+}
+
+// IsActive verifica si la promoción aplica al símbolo en el momento dado
+func (p *FeePromotion) IsActive(symbol string, now time.Time) bool {
+	if now.Before(p.StartsAt) || !now.Before(p.EndsAt) {
+		return false
+	}
+	if len(p.Symbols) == 0 {
+		return true
+	}
+	for _, s := range p.Symbols {
+		if strings.EqualFold(s, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// FeeSchedule tabla de comisiones vigente. Los admins la cambian en caliente y cada
+// orden guarda en FeeDetail la regla que se le aplicó.
+// Prioridad: promoción activa, override del símbolo, escalón de volumen, tasa base.
+type FeeSchedule struct {
+	ID              string              `bson:"_id" json:"-"`
+	MakerRate       decimal.Decimal     `bson:"maker_rate" json:"maker_rate"`
+	TakerRate       decimal.Decimal     `bson:"taker_rate" json:"taker_rate"`
+	MinimumFee      decimal.Decimal     `bson:"minimum_fee" json:"minimum_fee"` // No aplica en promociones
+	MaximumFee      decimal.Decimal     `bson:"maximum_fee" json:"maximum_fee"` // 0 = sin tope
+	VolumeTiers     []FeeTier           `bson:"volume_tiers" json:"volume_tiers"`
+	SymbolOverrides []SymbolFeeOverride `bson:"symbol_overrides" json:"symbol_overrides"`
+	Promotions      []FeePromotion      `bson:"promotions" json:"promotions"`
+	Version         int64               `bson:"version" json:"version"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+	UpdatedBy       int                 `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+}
+
+// DefaultFeeSchedule tabla histórica: 0.1% maker y taker con mínimo de 0.01
+func DefaultFeeSchedule() *FeeSchedule {
+	return &FeeSchedule{
+		ID:         FeeScheduleID,
+		MakerRate:  decimal.NewFromFloat(0.001),
+		TakerRate:  decimal.NewFromFloat(0.001),
+		MinimumFee: decimal.NewFromFloat(0.01),
+	}
+}
+
+// Calculate calcula la comisión de amount para el símbolo y lado dados; volume es
+// lo operado por el usuario en la ventana de los escalones
+func (s *FeeSchedule) Calculate(symbol string, feeType FeeType, volume, amount decimal.Decimal, now time.Time) *FeeResult {
+	for i := range s.Promotions {
+		if s.Promotions[i].IsActive(symbol, now) {
+			return &FeeResult{
+				TotalFee:      decimal.Zero,
+				FeePercentage: decimal.Zero,
+				FeeType:       feeType,
+				Rule:          FeeRulePromotion + ":" + s.Promotions[i].Name,
+				Volume:        volume,
+			}
+		}
+	}
+
+	rate, rule := s.rateFor(symbol, feeType, volume)
+
+	fee := amount.Mul(rate)
+	if fee.LessThan(s.MinimumFee) {
+		fee = s.MinimumFee
+	}
+	if s.MaximumFee.IsPositive() && fee.GreaterThan(s.MaximumFee) {
+		fee = s.MaximumFee
+	}
+
+	return &FeeResult{
+		TotalFee:      fee,
+		FeePercentage: rate,
+		FeeType:       feeType,
+		Rule:          rule,
+		Volume:        volume,
+	}
+}
+
+// rateFor retorna la tasa y la regla que la define, sin contar promociones
+func (s *FeeSchedule) rateFor(symbol string, feeType FeeType, volume decimal.Decimal) (decimal.Decimal, string) {
+	pick := func(maker, taker decimal.Decimal) decimal.Decimal {
+		if feeType == FeeTypeMaker {
+			return maker
+		}
+		return taker
+	}
+
+	for _, o := range s.SymbolOverrides {
+		if strings.EqualFold(o.Symbol, symbol) {
+			return pick(o.MakerRate, o.TakerRate), FeeRuleSymbol + ":" + o.Symbol
+		}
+	}
+
+	// Los escalones están ordenados por MinVolume: aplica el más alto alcanzado
+	for i := len(s.VolumeTiers) - 1; i >= 0; i-- {
+		tier := s.VolumeTiers[i]
+		if volume.GreaterThanOrEqual(tier.MinVolume) {
+			return pick(tier.MakerRate, tier.TakerRate), FeeRuleTier + ":" + tier.Name
+		}
+	}
+
+	return pick(s.MakerRate, s.TakerRate), FeeRuleBase
+}
+
+// Normalize ordena los escalones por volumen y pasa los símbolos a mayúsculas
+func (s *FeeSchedule) Normalize() {
+	sort.SliceStable(s.VolumeTiers, func(i, j int) bool {
+		return s.VolumeTiers[i].MinVolume.LessThan(s.VolumeTiers[j].MinVolume)
+	})
+	for i := range s.SymbolOverrides {
+		s.SymbolOverrides[i].Symbol = strings.ToUpper(strings.TrimSpace(s.SymbolOverrides[i].Symbol))
+	}
+	for i := range s.Promotions {
+		for j := range s.Promotions[i].Symbols {
+			s.Promotions[i].Symbols[j] = strings.ToUpper(strings.TrimSpace(s.Promotions[i].Symbols[j]))
+		}
+	}
+}
+
+// Validate verifica que la tabla sea consistente (llamar después de Normalize)
+func (s *FeeSchedule) Validate() error {
+	if err := validateFeeRates("base", s.MakerRate, s.TakerRate); err != nil {
+		return err
+	}
+	if s.MinimumFee.IsNegative() || s.MaximumFee.IsNegative() {
+		return fmt.Errorf("minimum_fee and maximum_fee cannot be negative")
+	}
+	if s.MaximumFee.IsPositive() && s.MaximumFee.LessThan(s.MinimumFee) {
+		return fmt.Errorf("maximum_fee must be greater than or equal to minimum_fee")
+	}
+
+	tierNames := make(map[string]bool)
+	for i, tier := range s.VolumeTiers {
+		if tier.Name == "" || tierNames[tier.Name] {
+			return fmt.Errorf("volume tier names must be unique and non-empty")
+		}
+		tierNames[tier.Name] = true
+		if !tier.MinVolume.IsPositive() {
+			return fmt.Errorf("volume tier %s: min_volume must be greater than zero", tier.Name)
+		}
+		if i > 0 && tier.MinVolume.Equal(s.VolumeTiers[i-1].MinVolume) {
+			return fmt.Errorf("volume tiers %s and %s have the same min_volume", s.VolumeTiers[i-1].Name, tier.Name)
+		}
+		if err := validateFeeRates("volume tier "+tier.Name, tier.MakerRate, tier.TakerRate); err != nil {
+			return err
+		}
+	}
+
+	symbols := make(map[string]bool)
+	for _, o := range s.SymbolOverrides {
+		if o.Symbol == "" || symbols[o.Symbol] {
+			return fmt.Errorf("symbol overrides must have unique, non-empty symbols")
+		}
+		symbols[o.Symbol] = true
+		if err := validateFeeRates("symbol "+o.Symbol, o.MakerRate, o.TakerRate); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range s.Promotions {
+		if p.Name == "" {
+			return fmt.Errorf("promotions must have a name")
+		}
+		if !p.EndsAt.After(p.StartsAt) {
+			return fmt.Errorf("promotion %s: ends_at must be after starts_at", p.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateFeeRates(scope string, maker, taker decimal.Decimal) error {
+	for _, rate := range []decimal.Decimal{maker, taker} {
+		if rate.IsNegative() || rate.GreaterThan(MaxFeeRate) {
+			return fmt.Errorf("%s: fee rates must be between 0 and %s", scope, MaxFeeRate.String())
+		}
+	}
+	return nil
+}
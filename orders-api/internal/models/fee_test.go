@@ -0,0 +1,104 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFeeSchedule() *FeeSchedule {
+	rate := func(r float64) decimal.Decimal { return decimal.NewFromFloat(r) }
+	now := time.Now()
+
+	return &FeeSchedule{
+		MakerRate:  rate(0.001),
+		TakerRate:  rate(0.002),
+		MinimumFee: rate(0.01),
+		MaximumFee: decimal.NewFromInt(100),
+		VolumeTiers: []FeeTier{
+			{Name: "silver", MinVolume: decimal.NewFromInt(10000), MakerRate: rate(0.0008), TakerRate: rate(0.0015)},
+			{Name: "gold", MinVolume: decimal.NewFromInt(100000), MakerRate: rate(0.0005), TakerRate: rate(0.001)},
+		},
+		SymbolOverrides: []SymbolFeeOverride{
+			{Symbol: "USDT", MakerRate: rate(0.0001), TakerRate: rate(0.0002)},
+		},
+		Promotions: []FeePromotion{
+			{Name: "sol-launch", Symbols: []string{"SOL"}, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+			{Name: "expired", StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)},
+		},
+	}
+}
+
+func TestFeeSchedule_Calculate(t *testing.T) {
+	schedule := newTestFeeSchedule()
+	now := time.Now()
+	amount := decimal.NewFromInt(10000)
+
+	t.Run("base rate by side", func(t *testing.T) {
+		maker := schedule.Calculate("BTC", FeeTypeMaker, decimal.Zero, amount, now)
+		assert.True(t, maker.TotalFee.Equal(decimal.NewFromInt(10)))
+		assert.Equal(t, FeeRuleBase, maker.Rule)
+
+		taker := schedule.Calculate("BTC", FeeTypeTaker, decimal.Zero, amount, now)
+		assert.True(t, taker.TotalFee.Equal(decimal.NewFromInt(20)))
+		assert.Equal(t, FeeTypeTaker, taker.FeeType)
+	})
+
+	t.Run("highest volume tier reached", func(t *testing.T) {
+		result := schedule.Calculate("BTC", FeeTypeTaker, decimal.NewFromInt(250000), amount, now)
+		assert.True(t, result.TotalFee.Equal(decimal.NewFromInt(10)))
+		assert.Equal(t, "tier:gold", result.Rule)
+	})
+
+	t.Run("symbol override beats tiers", func(t *testing.T) {
+		result := schedule.Calculate("usdt", FeeTypeTaker, decimal.NewFromInt(250000), amount, now)
+		assert.True(t, result.TotalFee.Equal(decimal.NewFromInt(2)))
+		assert.Equal(t, "symbol:USDT", result.Rule)
+	})
+
+	t.Run("active promotion is free and skips the minimum", func(t *testing.T) {
+		result := schedule.Calculate("SOL", FeeTypeTaker, decimal.Zero, decimal.NewFromInt(1), now)
+		assert.True(t, result.TotalFee.IsZero())
+		assert.Equal(t, "promotion:sol-launch", result.Rule)
+	})
+
+	t.Run("minimum and maximum fee", func(t *testing.T) {
+		small := schedule.Calculate("BTC", FeeTypeTaker, decimal.Zero, decimal.NewFromInt(1), now)
+		assert.True(t, small.TotalFee.Equal(decimal.NewFromFloat(0.01)))
+
+		large := schedule.Calculate("BTC", FeeTypeTaker, decimal.Zero, decimal.NewFromInt(1000000), now)
+		assert.True(t, large.TotalFee.Equal(decimal.NewFromInt(100)))
+	})
+}
+
+func TestFeeSchedule_Validate(t *testing.T) {
+	schedule := newTestFeeSchedule()
+	schedule.Normalize()
+	assert.NoError(t, schedule.Validate())
+
+	tooHigh := newTestFeeSchedule()
+	tooHigh.TakerRate = decimal.NewFromFloat(0.5)
+	assert.Error(t, tooHigh.Validate())
+
+	sameVolume := newTestFeeSchedule()
+	sameVolume.VolumeTiers[1].MinVolume = sameVolume.VolumeTiers[0].MinVolume
+	assert.Error(t, sameVolume.Validate())
+
+	badWindow := newTestFeeSchedule()
+	badWindow.Promotions[0].EndsAt = badWindow.Promotions[0].StartsAt
+	assert.Error(t, badWindow.Validate())
+}
+
+func TestOrder_FeeTypeAt(t *testing.T) {
+	price := decimal.NewFromInt(100)
+
+	assert.Equal(t, FeeTypeTaker, (&Order{OrderKind: OrderKindMarket}).FeeTypeAt(price))
+	assert.Equal(t, FeeTypeMaker, (&Order{OrderKind: OrderKindLimit}).FeeTypeAt(price))
+	assert.Equal(t, FeeTypeTaker, (&Order{OrderKind: OrderKindStopMarket}).FeeTypeAt(price))
+
+	oco := newConditionalOrder(OrderTypeSell, OrderKindOCO, 110, 90)
+	assert.Equal(t, FeeTypeMaker, oco.FeeTypeAt(decimal.NewFromInt(110)))
+	assert.Equal(t, FeeTypeTaker, oco.FeeTypeAt(decimal.NewFromInt(90)))
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Fill ejecución parcial de una orden contra un nivel del libro. Una orden puede
+// llenarse en varias pasadas y cada pasada puede tomar varios niveles.
+type Fill struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	FillID     string             `bson:"fill_id" json:"fill_id"` // <order_id>-<n>, único por orden
+	OrderID    string             `bson:"order_id" json:"order_id"`
+	UserID     int                `bson:"user_id" json:"user_id"`
+	Sequence   int                `bson:"sequence" json:"sequence"` // 1, 2, 3... en el orden en que se llenó
+	Price      decimal.Decimal    `bson:"price" json:"price"`
+	Quantity   decimal.Decimal    `bson:"quantity" json:"quantity"`
+	Amount     decimal.Decimal    `bson:"amount" json:"amount"`       // Price * Quantity
+	Fee        decimal.Decimal    `bson:"fee" json:"fee"`             // Parte de la comisión de la pasada
+	Liquidity  FeeType            `bson:"liquidity" json:"liquidity"` // maker o taker
+	ExecutedAt time.Time          `bson:"executed_at" json:"executed_at"`
+}
+
+// NewFillID arma el identificador del fill número sequence de la orden
+func NewFillID(orderID string, sequence int) string {
+	return fmt.Sprintf("%s-%d", orderID, sequence)
+}
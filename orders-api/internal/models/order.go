@@ -11,6 +11,7 @@ import (
 type OrderType string
 type OrderStatus string
 type OrderKind string
+type TimeInForce string
 
 // OrderType define si es compra o venta
 const (
@@ -21,40 +22,226 @@ const (
 // OrderStatus define el estado de la orden
 const (
 	OrderStatusPending   OrderStatus = "pending"   // Orden creada, esperando ejecución
+	OrderStatusTriggered OrderStatus = "triggered" // Orden condicional cuyo precio de disparo se alcanzó
+	OrderStatusExecuting OrderStatus = "executing" // Saga de ejecución en curso (ver Order.Saga)
+	OrderStatusPartiallyFilled OrderStatus = "partially_filled" // Se llenó una parte; el resto sigue esperando
 	OrderStatusExecuted  OrderStatus = "executed"  // Orden ejecutada exitosamente
 	OrderStatusCancelled OrderStatus = "cancelled" // Orden cancelada por el usuario
 	OrderStatusFailed    OrderStatus = "failed"    // Orden falló durante ejecución
+	OrderStatusExpired   OrderStatus = "expired"   // Venció su time in force sin llenarse (o con un llenado parcial)
 )
 
 // OrderKind define el tipo de orden
 const (
-	OrderKindMarket OrderKind = "market" // Se ejecuta al precio actual de mercado
-	OrderKindLimit  OrderKind = "limit"  // Se ejecuta solo si se alcanza el precio límite
+	OrderKindMarket     OrderKind = "market"      // Se ejecuta al precio actual de mercado
+	OrderKindLimit      OrderKind = "limit"       // Se ejecuta solo si se alcanza el precio límite
+	OrderKindStopMarket OrderKind = "stop_market" // Al alcanzar el stop se ejecuta a mercado
+	OrderKindStopLimit  OrderKind = "stop_limit"  // Al alcanzar el stop pasa a esperar su precio límite
+	OrderKindTakeProfit OrderKind = "take_profit" // Al alcanzar el objetivo de ganancia se ejecuta a mercado
+	OrderKindOCO        OrderKind = "oco"         // Pata limit + pata stop: la primera que se cumple cancela la otra
 )
 
+// TimeInForce define hasta cuándo sigue abierta una orden
+const (
+	TimeInForceGTC TimeInForce = "gtc" // Good till cancelled: abierta hasta que se ejecuta o se cancela (default)
+	TimeInForceIOC TimeInForce = "ioc" // Immediate or cancel: lo que no se llena en la primera pasada expira
+	TimeInForceFOK TimeInForce = "fok" // Fill or kill: se llena entera en la primera pasada o expira sin ejecutarse
+	TimeInForceGTD TimeInForce = "gtd" // Good till date: abierta hasta expires_at
+)
+
+// IsImmediate indica si la orden se juega solo en su primera pasada (IOC y FOK)
+func (t TimeInForce) IsImmediate() bool {
+	return t == TimeInForceIOC || t == TimeInForceFOK
+}
+
+// Patas de una orden OCO
+const (
+	OCOLegLimit = "limit"
+	OCOLegStop  = "stop"
+)
+
+// SagaStep paso alcanzado por la saga de ejecución de una orden
+type SagaStep string
+
+// Pasos de la saga: primero se mueve el saldo en Users API, después los holdings
+// en Portfolio API y por último se marca la orden como ejecutada
+const (
+	SagaStepStarted         SagaStep = "started"          // Precio fijado, todavía no se tocó nada
+	SagaStepBalanceApplied  SagaStep = "balance_applied"  // Users API debitó (compra) o acreditó (venta)
+	SagaStepHoldingsApplied SagaStep = "holdings_applied" // Portfolio API actualizó los holdings
+	SagaStepCompleted       SagaStep = "completed"        // Orden ejecutada
+	SagaStepCompensating    SagaStep = "compensating"     // Revirtiendo los pasos aplicados
+	SagaStepCompensated     SagaStep = "compensated"      // Pasos revertidos, orden fallida
+)
+
+// ExecutionSaga estado persistido de la ejecución de una orden.
+// Permite retomar o compensar la ejecución si la instancia se cae a mitad de camino.
+type ExecutionSaga struct {
+	Step          SagaStep        `bson:"step" json:"step"`
+	Reference     string          `bson:"reference,omitempty" json:"reference,omitempty"` // ID de la pasada en Users y Portfolio API
+	Quantity      decimal.Decimal `bson:"quantity" json:"quantity"` // Cantidad que llena esta pasada
+	Final         bool            `bson:"final" json:"final"` // La pasada completa la orden
+	Fills         []Fill          `bson:"fills,omitempty" json:"fills,omitempty"` // Fills de la pasada, se guardan al completarla
+	ExecutedPrice decimal.Decimal `bson:"executed_price" json:"executed_price"` // Precio promedio de la pasada
+	TotalAmount   decimal.Decimal `bson:"total_amount" json:"total_amount"`
+	Fee           decimal.Decimal `bson:"fee" json:"fee"`
+	Cost          *ExecutionCost  `bson:"cost,omitempty" json:"cost,omitempty"` // Desglose de slippage de la pasada
+	BalanceDelta  decimal.Decimal `bson:"balance_delta" json:"balance_delta"` // Movimiento aplicado en Users API (negativo en compras)
+	FailedStep    SagaStep        `bson:"failed_step,omitempty" json:"failed_step,omitempty"` // Último paso completo antes de compensar
+	LastError     string          `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	Attempts      int             `bson:"attempts" json:"attempts"` // Veces que se retomó tras una caída
+	StartedAt     time.Time       `bson:"started_at" json:"started_at"`
+	UpdatedAt     time.Time       `bson:"updated_at" json:"updated_at"`
+}
+
+// IsFinished indica si la saga terminó (ejecutada o compensada)
+func (s *ExecutionSaga) IsFinished() bool {
+	return s.Step == SagaStepCompleted || s.Step == SagaStepCompensated
+}
+
+// HasBalanceApplied indica si el saldo ya se movió en Users API
+func (s *ExecutionSaga) HasBalanceApplied() bool {
+	return !s.BalanceDelta.IsZero()
+}
+
+// HasLimitPrice indica si el tipo de orden usa limit_price
+func (k OrderKind) HasLimitPrice() bool {
+	return k == OrderKindLimit || k == OrderKindStopLimit || k == OrderKindOCO
+}
+
+// HasTriggerPrice indica si el tipo de orden usa trigger_price
+func (k OrderKind) HasTriggerPrice() bool {
+	return k == OrderKindStopMarket || k == OrderKindStopLimit || k == OrderKindTakeProfit || k == OrderKindOCO
+}
+
 // Order representa una orden de compra/venta simplificada
 type Order struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	OrderNumber  string             `bson:"order_number" json:"order_number"` // Ej: ORD-2025-a1b2c3d4
 	UserID       int                `bson:"user_id" json:"user_id"`
 	Type         OrderType          `bson:"type" json:"type"`                 // buy o sell
-	Status       OrderStatus        `bson:"status" json:"status"`             // pending, executed, cancelled, failed
+	Status       OrderStatus        `bson:"status" json:"status"`             // pending, triggered, executing, partially_filled, executed, cancelled, failed, expired
 	CryptoSymbol string             `bson:"crypto_symbol" json:"crypto_symbol"` // BTC, ETH, etc
 	CryptoName   string             `bson:"crypto_name" json:"crypto_name"`     // Bitcoin, Ethereum, etc
-	Quantity     decimal.Decimal    `bson:"quantity" json:"quantity"`           // Cantidad a comprar/vender
+	Quantity     decimal.Decimal    `bson:"quantity" json:"quantity"` // Cantidad a comprar/vender
 	OrderKind    OrderKind          `bson:"order_kind" json:"order_kind"`       // market o limit
 	Price        decimal.Decimal    `bson:"price" json:"price"`                 // Precio de ejecución
 	TotalAmount  decimal.Decimal    `bson:"total_amount" json:"total_amount"`   // Quantity * Price
-	Fee          decimal.Decimal    `bson:"fee" json:"fee"`                     // Comisión (0.1%)
+	Fee          decimal.Decimal    `bson:"fee" json:"fee"`                     // Comisión según la tabla vigente
+	FeeDetail    *FeeResult         `bson:"fee_detail,omitempty" json:"fee_detail,omitempty"` // Tasa, lado y regla de comisión aplicados
 	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
 	ExecutedAt   *time.Time         `bson:"executed_at,omitempty" json:"executed_at,omitempty"`
 	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
 	ErrorMessage string             `bson:"error_message,omitempty" json:"error_message,omitempty"` // Si falla
+	LockedBy     string             `bson:"locked_by,omitempty" json:"-"`                           // Instancia que tomó la orden para ejecutarla
+	LockedUntil  *time.Time         `bson:"locked_until,omitempty" json:"-"`                        // Vencimiento del lock de ejecución
+	IdempotencyKey string           `bson:"idempotency_key,omitempty" json:"idempotency_key,omitempty"` // Header Idempotency-Key del cliente
+	Version      int64              `bson:"version" json:"version"`                                 // Se incrementa en cada escritura (control optimista)
+	TriggerPrice *decimal.Decimal   `bson:"trigger_price,omitempty" json:"trigger_price,omitempty"` // Stop / take profit
+	TriggeredAt  *time.Time         `bson:"triggered_at,omitempty" json:"triggered_at,omitempty"`
+	FilledLeg    string             `bson:"filled_leg,omitempty" json:"filled_leg,omitempty"` // Pata OCO que se ejecutó: limit o stop
+	Saga         *ExecutionSaga     `bson:"saga,omitempty" json:"saga,omitempty"`             // Estado de la ejecución en curso o terminada
+	MaxSlippage  *decimal.Decimal   `bson:"max_slippage,omitempty" json:"max_slippage,omitempty"` // Market: desvío máximo contra Price (cotizado)
+	FilledQuantity decimal.Decimal  `bson:"filled_quantity" json:"filled_quantity"`                 // Suma de las cantidades de los fills
+	FilledAmount   decimal.Decimal  `bson:"filled_amount" json:"filled_amount"`                     // Suma de los montos de los fills
+	FilledFee      decimal.Decimal  `bson:"filled_fee" json:"filled_fee"`                           // Comisión cobrada por los fills
+	AvgFillPrice   *decimal.Decimal `bson:"avg_fill_price,omitempty" json:"avg_fill_price,omitempty"` // Precio promedio ponderado por volumen
+	FillCount      int              `bson:"fill_count" json:"fill_count"`                           // Fills en la colección order_fills
+	LastFillAt     *time.Time       `bson:"last_fill_at,omitempty" json:"last_fill_at,omitempty"`   // Último fill, aunque la orden después se cancele o venza
+	TimeInForce    TimeInForce      `bson:"time_in_force,omitempty" json:"time_in_force,omitempty"` // gtc, ioc, fok o gtd (vacío en órdenes viejas = gtc)
+	ExpiresAt      *time.Time       `bson:"expires_at,omitempty" json:"expires_at,omitempty"`       // Vencimiento de las órdenes gtd
+	ExpiredAt      *time.Time       `bson:"expired_at,omitempty" json:"expired_at,omitempty"`
+}
+
+// IsAmendable verifica si se puede modificar precio y cantidad de la orden
+func (o *Order) IsAmendable() bool {
+	return o.Status == OrderStatusPending && o.OrderKind == OrderKindLimit
 }
 
 // IsCancellable verifica si la orden puede ser cancelada
 func (o *Order) IsCancellable() bool {
-	return o.Status == OrderStatusPending
+	return o.IsOpen()
+}
+
+// IsOpen verifica si la orden sigue esperando ejecución (pendiente, disparada o parcialmente llenada)
+func (o *Order) IsOpen() bool {
+	return o.Status == OrderStatusPending || o.Status == OrderStatusTriggered || o.Status == OrderStatusPartiallyFilled
+}
+
+// IsExpiredAt indica si una orden gtd ya pasó su vencimiento
+func (o *Order) IsExpiredAt(now time.Time) bool {
+	return o.ExpiresAt != nil && !o.ExpiresAt.After(now)
+}
+
+// RestsOnBook indica si la orden puede seguir esperando a que el matcher la ejecute.
+// Las IOC/FOK solo tienen su primera pasada y las gtd vencidas las expira el sweeper.
+func (o *Order) RestsOnBook(now time.Time) bool {
+	return o.IsOpen() && !o.TimeInForce.IsImmediate() && !o.IsExpiredAt(now)
+}
+
+// MarkExpired marca la orden abierta como vencida; lo que ya se llenó queda en los fills
+func (o *Order) MarkExpired(now time.Time) {
+	o.Status = OrderStatusExpired
+	o.ExpiredAt = &now
+	o.UpdatedAt = now
+}
+
+// RemainingQuantity retorna la cantidad que falta llenar
+func (o *Order) RemainingQuantity() decimal.Decimal {
+	return o.Quantity.Sub(o.FilledQuantity)
+}
+
+// ExecutionRef identifica la pasada de ejecución en curso ante Users y Portfolio API.
+// Las sagas anteriores a los fills parciales usaban el ID de la orden.
+func (o *Order) ExecutionRef() string {
+	if o.Saga != nil && o.Saga.Reference != "" {
+		return o.Saga.Reference
+	}
+	return o.ID.Hex()
+}
+
+// ApplyFills suma los fills de una pasada a la orden y recalcula el precio promedio
+// ponderado por volumen. La orden queda executed si ya no falta cantidad y
+// partially_filled si no.
+func (o *Order) ApplyFills(fills []Fill, now time.Time) {
+	for _, fill := range fills {
+		o.FilledQuantity = o.FilledQuantity.Add(fill.Quantity)
+		o.FilledAmount = o.FilledAmount.Add(fill.Amount)
+		o.FilledFee = o.FilledFee.Add(fill.Fee)
+		o.FillCount++
+	}
+	if len(fills) > 0 {
+		o.LastFillAt = &now
+	}
+
+	if o.FilledQuantity.IsPositive() {
+		avg := o.FilledAmount.Div(o.FilledQuantity)
+		o.AvgFillPrice = &avg
+	}
+
+	o.UpdatedAt = now
+	if o.RemainingQuantity().IsPositive() {
+		o.Status = OrderStatusPartiallyFilled
+		return
+	}
+
+	o.Status = OrderStatusExecuted
+	o.Price = *o.AvgFillPrice
+	o.TotalAmount = o.FilledAmount
+	o.Fee = o.FilledFee
+	o.ExecutedAt = &now
+}
+
+// LastFilledAt momento del último fill. Las órdenes anteriores a last_fill_at usan
+// executed_at y, si tampoco lo tienen, updated_at (el mismo orden que el export en Mongo).
+func (o *Order) LastFilledAt() time.Time {
+	if o.LastFillAt != nil {
+		return *o.LastFillAt
+	}
+	if o.ExecutedAt != nil {
+		return *o.ExecutedAt
+	}
+	return o.UpdatedAt
 }
 
 // IsExecuted verifica si la orden fue ejecutada
@@ -66,7 +253,129 @@ func (o *Order) IsExecuted() bool {
 func (o *Order) IsFinal() bool {
 	return o.Status == OrderStatusExecuted ||
 		o.Status == OrderStatusCancelled ||
-		o.Status == OrderStatusFailed
+		o.Status == OrderStatusFailed ||
+		o.Status == OrderStatusExpired
+}
+
+// IsLocked verifica si una instancia tiene tomada la orden para ejecutarla
+func (o *Order) IsLocked(now time.Time) bool {
+	return o.LockedUntil != nil && o.LockedUntil.After(now)
+}
+
+// IsLimitPriceReached verifica si el precio de mercado cruzó el precio límite
+// Compra: mercado <= límite. Venta: mercado >= límite.
+func (o *Order) IsLimitPriceReached(marketPrice decimal.Decimal) bool {
+	if !o.OrderKind.HasLimitPrice() {
+		return true
+	}
+	if o.Type == OrderTypeBuy {
+		return marketPrice.LessThanOrEqual(o.Price)
+	}
+	return marketPrice.GreaterThanOrEqual(o.Price)
+}
+
+// IsTriggerReached verifica si el precio de mercado alcanzó el precio de disparo.
+// Stop (y la pata stop de OCO): compra si mercado >= stop, venta si mercado <= stop.
+// Take profit: compra si mercado <= objetivo, venta si mercado >= objetivo.
+func (o *Order) IsTriggerReached(marketPrice decimal.Decimal) bool {
+	if !o.OrderKind.HasTriggerPrice() || o.TriggerPrice == nil {
+		return true
+	}
+
+	risesToTrigger := o.Type == OrderTypeBuy
+	if o.OrderKind == OrderKindTakeProfit {
+		risesToTrigger = !risesToTrigger
+	}
+
+	if risesToTrigger {
+		return marketPrice.GreaterThanOrEqual(*o.TriggerPrice)
+	}
+	return marketPrice.LessThanOrEqual(*o.TriggerPrice)
+}
+
+// ShouldTrigger indica si una orden stop / take profit pendiente debe pasar a triggered
+func (o *Order) ShouldTrigger(marketPrice decimal.Decimal) bool {
+	if o.Status != OrderStatusPending || o.OrderKind == OrderKindOCO || !o.OrderKind.HasTriggerPrice() {
+		return false
+	}
+	return o.IsTriggerReached(marketPrice)
+}
+
+// IsExecutableAt verifica si la orden se puede ejecutar al precio de mercado dado.
+// Una orden parcialmente llenada ya se disparó y, si es OCO, sigue solo por su pata limit.
+func (o *Order) IsExecutableAt(marketPrice decimal.Decimal) bool {
+	triggered := o.Status == OrderStatusTriggered || o.Status == OrderStatusPartiallyFilled || o.IsTriggerReached(marketPrice)
+
+	switch o.OrderKind {
+	case OrderKindStopMarket, OrderKindTakeProfit:
+		return triggered
+	case OrderKindStopLimit:
+		return triggered && o.IsLimitPriceReached(marketPrice)
+	case OrderKindOCO:
+		if o.Status == OrderStatusPartiallyFilled {
+			return o.IsLimitPriceReached(marketPrice)
+		}
+		return o.IsLimitPriceReached(marketPrice) || o.IsTriggerReached(marketPrice)
+	default:
+		return o.IsLimitPriceReached(marketPrice)
+	}
+}
+
+// ExceedsSlippage verifica si ejecutar al precio dado se desvía de Price (el precio
+// cotizado) más de MaxSlippage en contra del usuario: más caro en compras, más barato en ventas
+func (o *Order) ExceedsSlippage(executionPrice decimal.Decimal) bool {
+	if o.MaxSlippage == nil || o.Price.IsZero() {
+		return false
+	}
+
+	if o.Type == OrderTypeBuy {
+		return executionPrice.GreaterThan(o.Price.Mul(decimal.NewFromInt(1).Add(*o.MaxSlippage)))
+	}
+	return executionPrice.LessThan(o.Price.Mul(decimal.NewFromInt(1).Sub(*o.MaxSlippage)))
+}
+
+// MarkTriggered pasa la orden a triggered
+func (o *Order) MarkTriggered(now time.Time) {
+	o.Status = OrderStatusTriggered
+	o.TriggeredAt = &now
+	o.UpdatedAt = now
+}
+
+// FilledLegAt indica qué pata de una OCO se ejecuta al precio dado; la limit tiene prioridad.
+// Una OCO parcialmente llenada sigue por la pata que empezó a llenarse.
+func (o *Order) FilledLegAt(marketPrice decimal.Decimal) string {
+	if o.OrderKind != OrderKindOCO {
+		return ""
+	}
+	if o.Status == OrderStatusPartiallyFilled && o.FilledLeg != "" {
+		return o.FilledLeg
+	}
+	if o.IsLimitPriceReached(marketPrice) {
+		return OCOLegLimit
+	}
+	return OCOLegStop
+}
+
+// IsLimitBoundAt indica si ejecutándose al precio dado la orden solo puede llenarse a su
+// precio límite o mejor (limit, stop_limit y la pata limit de una OCO)
+func (o *Order) IsLimitBoundAt(marketPrice decimal.Decimal) bool {
+	switch o.OrderKind {
+	case OrderKindLimit, OrderKindStopLimit:
+		return true
+	case OrderKindOCO:
+		return o.FilledLegAt(marketPrice) == OCOLegLimit
+	default:
+		return false
+	}
+}
+
+// FeeTypeAt indica si la orden paga como maker o taker ejecutándose al precio dado:
+// las limit esperan en el libro (maker); las market y las que disparan a mercado, taker
+func (o *Order) FeeTypeAt(marketPrice decimal.Decimal) FeeType {
+	if o.IsLimitBoundAt(marketPrice) {
+		return FeeTypeMaker
+	}
+	return FeeTypeTaker
 }
 
 // CalculateTotalWithFee calcula el total incluyendo la comisión
@@ -74,6 +383,18 @@ func (o *Order) CalculateTotalWithFee() decimal.Decimal {
 	return o.TotalAmount.Add(o.Fee)
 }
 
+// ReservationAmount lo que se reserva para una compra: el total con comisión. Una
+// market con MaxSlippage puede llenarse en el libro hasta ese desvío sobre el precio
+// cotizado (ExceedsSlippage), así que reserva ese peor caso; la captura debita lo
+// ejecutado y libera el resto de la reserva.
+func (o *Order) ReservationAmount() decimal.Decimal {
+	total := o.CalculateTotalWithFee()
+	if o.Type == OrderTypeBuy && o.OrderKind == OrderKindMarket && o.MaxSlippage != nil && o.MaxSlippage.IsPositive() {
+		return total.Mul(decimal.NewFromInt(1).Add(*o.MaxSlippage))
+	}
+	return total
+}
+
 // NewOrderNumber genera un número de orden único
 func NewOrderNumber() string {
 	return "ORD-" + time.Now().Format("20060102") + "-" + primitive.NewObjectID().Hex()[:8]
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newConditionalOrder(orderType OrderType, kind OrderKind, limit, trigger int64) *Order {
+	triggerPrice := decimal.NewFromInt(trigger)
+	return &Order{
+		Type:         orderType,
+		OrderKind:    kind,
+		Status:       OrderStatusPending,
+		Price:        decimal.NewFromInt(limit),
+		TriggerPrice: &triggerPrice,
+	}
+}
+
+func TestOrder_IsTriggerReached(t *testing.T) {
+	price := func(p int64) decimal.Decimal { return decimal.NewFromInt(p) }
+
+	sellStop := newConditionalOrder(OrderTypeSell, OrderKindStopMarket, 0, 100)
+	assert.True(t, sellStop.IsTriggerReached(price(99)))
+	assert.False(t, sellStop.IsTriggerReached(price(101)))
+
+	buyStop := newConditionalOrder(OrderTypeBuy, OrderKindStopMarket, 0, 100)
+	assert.True(t, buyStop.IsTriggerReached(price(101)))
+	assert.False(t, buyStop.IsTriggerReached(price(99)))
+
+	sellTakeProfit := newConditionalOrder(OrderTypeSell, OrderKindTakeProfit, 0, 100)
+	assert.True(t, sellTakeProfit.IsTriggerReached(price(101)))
+	assert.False(t, sellTakeProfit.IsTriggerReached(price(99)))
+
+	buyTakeProfit := newConditionalOrder(OrderTypeBuy, OrderKindTakeProfit, 0, 100)
+	assert.True(t, buyTakeProfit.IsTriggerReached(price(99)))
+	assert.False(t, buyTakeProfit.IsTriggerReached(price(101)))
+}
+
+func TestOrder_IsExecutableAt(t *testing.T) {
+	price := func(p int64) decimal.Decimal { return decimal.NewFromInt(p) }
+
+	t.Run("stop limit needs trigger and limit", func(t *testing.T) {
+		order := newConditionalOrder(OrderTypeSell, OrderKindStopLimit, 95, 100)
+
+		assert.True(t, order.ShouldTrigger(price(92)))
+		assert.False(t, order.IsExecutableAt(price(92)))
+		assert.True(t, order.IsExecutableAt(price(97)))
+
+		order.Status = OrderStatusTriggered
+		assert.False(t, order.ShouldTrigger(price(92)))
+		assert.True(t, order.IsExecutableAt(price(105)))
+	})
+
+	t.Run("oco fills the first leg reached", func(t *testing.T) {
+		order := newConditionalOrder(OrderTypeSell, OrderKindOCO, 120, 90)
+
+		assert.False(t, order.IsExecutableAt(price(100)))
+		assert.False(t, order.ShouldTrigger(price(80)))
+
+		assert.True(t, order.IsExecutableAt(price(125)))
+		assert.Equal(t, OCOLegLimit, order.FilledLegAt(price(125)))
+
+		assert.True(t, order.IsExecutableAt(price(85)))
+		assert.Equal(t, OCOLegStop, order.FilledLegAt(price(85)))
+	})
+
+	t.Run("market orders always execute", func(t *testing.T) {
+		order := &Order{Type: OrderTypeBuy, OrderKind: OrderKindMarket, Status: OrderStatusPending}
+
+		assert.True(t, order.IsExecutableAt(price(1)))
+		assert.Empty(t, order.FilledLegAt(price(1)))
+	})
+}
+
+func TestOrder_ExceedsSlippage(t *testing.T) {
+	price := func(p int64) decimal.Decimal { return decimal.NewFromInt(p) }
+	slippage := decimal.NewFromFloat(0.02)
+
+	buy := &Order{Type: OrderTypeBuy, OrderKind: OrderKindMarket, Price: price(100), MaxSlippage: &slippage}
+	assert.False(t, buy.ExceedsSlippage(price(102)))
+	assert.True(t, buy.ExceedsSlippage(price(103)))
+	assert.False(t, buy.ExceedsSlippage(price(90)))
+
+	sell := &Order{Type: OrderTypeSell, OrderKind: OrderKindMarket, Price: price(100), MaxSlippage: &slippage}
+	assert.False(t, sell.ExceedsSlippage(price(98)))
+	assert.True(t, sell.ExceedsSlippage(price(97)))
+	assert.False(t, sell.ExceedsSlippage(price(110)))
+
+	unprotected := &Order{Type: OrderTypeBuy, OrderKind: OrderKindMarket, Price: price(100)}
+	assert.False(t, unprotected.ExceedsSlippage(price(1000)))
+}
+
+func TestOrder_ReservationAmount(t *testing.T) {
+	slippage := decimal.NewFromFloat(0.05)
+
+	// 1 BTC cotizado a 50000 con 50 de comisión: con 5% de slippage puede costar 52552.5
+	buy := &Order{Type: OrderTypeBuy, OrderKind: OrderKindMarket, TotalAmount: decimal.NewFromInt(50000), Fee: decimal.NewFromInt(50), MaxSlippage: &slippage}
+	assert.True(t, buy.ReservationAmount().Equal(decimal.NewFromFloat(52552.5)))
+
+	unprotected := &Order{Type: OrderTypeBuy, OrderKind: OrderKindMarket, TotalAmount: decimal.NewFromInt(50000), Fee: decimal.NewFromInt(50)}
+	assert.True(t, unprotected.ReservationAmount().Equal(decimal.NewFromInt(50050)))
+
+	// Una limit nunca paga más que su precio límite
+	limit := &Order{Type: OrderTypeBuy, OrderKind: OrderKindLimit, TotalAmount: decimal.NewFromInt(50000), Fee: decimal.NewFromInt(50), MaxSlippage: &slippage}
+	assert.True(t, limit.ReservationAmount().Equal(decimal.NewFromInt(50050)))
+}
+
+func TestOrder_ApplyFills(t *testing.T) {
+	price := func(p int64) decimal.Decimal { return decimal.NewFromInt(p) }
+	fill := func(p int64, qty float64) Fill {
+		quantity := decimal.NewFromFloat(qty)
+		return Fill{Price: price(p), Quantity: quantity, Amount: price(p).Mul(quantity), Fee: decimal.NewFromInt(1)}
+	}
+	now := time.Now()
+
+	order := &Order{Type: OrderTypeBuy, OrderKind: OrderKindLimit, Status: OrderStatusPending, Price: price(110), Quantity: decimal.NewFromInt(2)}
+
+	order.ApplyFills([]Fill{fill(100, 0.5), fill(110, 0.5)}, now)
+	assert.Equal(t, OrderStatusPartiallyFilled, order.Status)
+	assert.True(t, order.RemainingQuantity().Equal(decimal.NewFromInt(1)))
+	assert.True(t, order.AvgFillPrice.Equal(price(105)))
+	assert.True(t, order.IsOpen())
+	assert.True(t, order.IsExecutableAt(price(110)))
+	assert.Nil(t, order.ExecutedAt)
+
+	order.ApplyFills([]Fill{fill(105, 1)}, now)
+	assert.Equal(t, OrderStatusExecuted, order.Status)
+	assert.True(t, order.RemainingQuantity().IsZero())
+	assert.True(t, order.Price.Equal(price(105)))
+	assert.True(t, order.TotalAmount.Equal(price(210)))
+	assert.True(t, order.Fee.Equal(decimal.NewFromInt(3)))
+	assert.Equal(t, 3, order.FillCount)
+	assert.NotNil(t, order.ExecutedAt)
+}
+
+func TestOrder_IsLimitBoundAt(t *testing.T) {
+	price := func(p int64) decimal.Decimal { return decimal.NewFromInt(p) }
+
+	assert.True(t, (&Order{OrderKind: OrderKindLimit}).IsLimitBoundAt(price(100)))
+	assert.False(t, (&Order{OrderKind: OrderKindMarket}).IsLimitBoundAt(price(100)))
+
+	oco := newConditionalOrder(OrderTypeSell, OrderKindOCO, 120, 90)
+	assert.True(t, oco.IsLimitBoundAt(price(125)))
+	assert.False(t, oco.IsLimitBoundAt(price(85)))
+}
+
+func TestOrder_RestsOnBook(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	assert.True(t, (&Order{Status: OrderStatusPending}).RestsOnBook(now))
+	assert.True(t, (&Order{Status: OrderStatusPartiallyFilled, TimeInForce: TimeInForceGTD, ExpiresAt: &future}).RestsOnBook(now))
+	assert.False(t, (&Order{Status: OrderStatusPending, TimeInForce: TimeInForceGTD, ExpiresAt: &past}).RestsOnBook(now))
+	assert.False(t, (&Order{Status: OrderStatusPending, TimeInForce: TimeInForceIOC}).RestsOnBook(now))
+	assert.False(t, (&Order{Status: OrderStatusExpired}).RestsOnBook(now))
+}
+
+func TestOrder_MarkExpired(t *testing.T) {
+	now := time.Now()
+	order := &Order{Status: OrderStatusPartiallyFilled, Quantity: decimal.NewFromInt(2), FilledQuantity: decimal.NewFromInt(1)}
+
+	order.MarkExpired(now)
+
+	assert.Equal(t, OrderStatusExpired, order.Status)
+	assert.Equal(t, &now, order.ExpiredAt)
+	assert.True(t, order.IsFinal())
+	assert.False(t, order.IsCancellable())
+}
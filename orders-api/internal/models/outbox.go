@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxStatus estado de un evento en el outbox
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"   // Esperando que el relay lo publique
+	OutboxStatusPublished OutboxStatus = "published" // RabbitMQ confirmó la publicación
+	OutboxStatusFailed    OutboxStatus = "failed"    // Agotó los reintentos, requiere revisión
+)
+
+// OutboxEvent evento de orden guardado en la misma transacción que la orden.
+// El relay lo publica en RabbitMQ; EventID viaja como MessageId para que los
+// consumidores descarten las entregas repetidas.
+type OutboxEvent struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EventID       string             `bson:"event_id" json:"event_id"`
+	OrderID       string             `bson:"order_id" json:"order_id"`       // Vacío en eventos de planes recurrentes
+	RoutingKey    string             `bson:"routing_key" json:"routing_key"` // orders.created, orders.executed, etc
+	Payload       []byte             `bson:"payload" json:"-"`               // Cuerpo JSON del mensaje
+	Status        OutboxStatus       `bson:"status" json:"status"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	LastError     string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	LockedBy      string             `bson:"locked_by,omitempty" json:"-"`
+	LockedUntil   *time.Time         `bson:"locked_until,omitempty" json:"-"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	PublishedAt   *time.Time         `bson:"published_at,omitempty" json:"published_at,omitempty"`
+}
+
+// NewOutboxEvent crea un evento pendiente para la orden. El EventID combina orden,
+// routing key y versión, así cada escritura de la orden genera un evento distinto.
+func NewOutboxEvent(order *Order, routingKey string, payload []byte) *OutboxEvent {
+	now := time.Now()
+	orderID := order.ID.Hex()
+
+	return &OutboxEvent{
+		ID:            primitive.NewObjectID(),
+		EventID:       fmt.Sprintf("%s:%s:%d", orderID, routingKey, order.Version),
+		OrderID:       orderID,
+		RoutingKey:    routingKey,
+		Payload:       payload,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+// NewPlanRunOutboxEvent crea un evento pendiente para la corrida de un plan recurrente.
+// Cada corrida genera un solo evento por routing key.
+func NewPlanRunOutboxEvent(run *PlanRun, routingKey string, payload []byte) *OutboxEvent {
+	now := time.Now()
+
+	return &OutboxEvent{
+		ID:            primitive.NewObjectID(),
+		EventID:       fmt.Sprintf("%s:%s", run.RunID, routingKey),
+		RoutingKey:    routingKey,
+		Payload:       payload,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
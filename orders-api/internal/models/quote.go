@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Quote cotización firmada por orders-api. El cliente la pide antes de crear una
+// orden market y manda su QuoteID; la orden toma el precio de la cotización.
+type Quote struct {
+	QuoteID      string          `json:"quote_id"` // Token firmado: payload + firma HMAC
+	UserID       int             `json:"user_id"`
+	CryptoSymbol string          `json:"crypto_symbol"`
+	Type         OrderType       `json:"type"`
+	Price        decimal.Decimal `json:"price"`
+	IssuedAt     time.Time       `json:"issued_at"`
+	ExpiresAt    time.Time       `json:"expires_at"`
+}
+
+// IsExpired verifica si la cotización ya venció
+func (q *Quote) IsExpired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
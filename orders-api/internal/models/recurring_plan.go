@@ -0,0 +1,122 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PlanFrequency cada cuánto compra un plan recurrente
+type PlanFrequency string
+
+const (
+	PlanFrequencyDaily   PlanFrequency = "daily"
+	PlanFrequencyWeekly  PlanFrequency = "weekly"
+	PlanFrequencyMonthly PlanFrequency = "monthly"
+)
+
+// Next retorna la corrida siguiente a t
+func (f PlanFrequency) Next(t time.Time) time.Time {
+	switch f {
+	case PlanFrequencyWeekly:
+		return t.AddDate(0, 0, 7)
+	case PlanFrequencyMonthly:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// IsValid indica si la frecuencia es una de las soportadas
+func (f PlanFrequency) IsValid() bool {
+	return f == PlanFrequencyDaily || f == PlanFrequencyWeekly || f == PlanFrequencyMonthly
+}
+
+// PlanStatus estado de un plan recurrente
+type PlanStatus string
+
+const (
+	PlanStatusActive PlanStatus = "active" // El scheduler lo corre en cada next_run_at
+	PlanStatusPaused PlanStatus = "paused" // No corre hasta que el usuario lo reanude
+)
+
+// PlanRunStatus resultado de una corrida de un plan
+type PlanRunStatus string
+
+const (
+	PlanRunStatusExecuted PlanRunStatus = "executed" // Se creó la orden market
+	PlanRunStatusSkipped  PlanRunStatus = "skipped"  // Saldo insuficiente: se saltea y se notifica
+	PlanRunStatusFailed   PlanRunStatus = "failed"   // No se pudo crear la orden (precio, símbolo, etc)
+)
+
+// RecurringPlan plan de compra recurrente (dollar-cost averaging): compra Amount USD
+// de CryptoSymbol con una orden market cada Frequency a partir de NextRunAt
+type RecurringPlan struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        int                `bson:"user_id" json:"user_id"`
+	CryptoSymbol  string             `bson:"crypto_symbol" json:"crypto_symbol"`
+	Amount        decimal.Decimal    `bson:"amount" json:"amount"` // USD por corrida (sin comisión)
+	Frequency     PlanFrequency      `bson:"frequency" json:"frequency"`
+	Status        PlanStatus         `bson:"status" json:"status"`
+	NextRunAt     time.Time          `bson:"next_run_at" json:"next_run_at"`
+	LastRunAt     *time.Time         `bson:"last_run_at,omitempty" json:"last_run_at,omitempty"`
+	LastRunStatus PlanRunStatus      `bson:"last_run_status,omitempty" json:"last_run_status,omitempty"`
+	RunCount      int                `bson:"run_count" json:"run_count"`   // Corridas con orden creada
+	SkipCount     int                `bson:"skip_count" json:"skip_count"` // Corridas salteadas por saldo
+	FailCount     int                `bson:"fail_count" json:"fail_count"`
+	LockedBy      string             `bson:"locked_by,omitempty" json:"-"` // Instancia del scheduler que lo está corriendo
+	LockedUntil   *time.Time         `bson:"locked_until,omitempty" json:"-"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// NextRunAfter retorna la primera corrida posterior a now. Las corridas que se
+// perdieron (scheduler caído, plan pausado) no se recuperan.
+func (p *RecurringPlan) NextRunAfter(now time.Time) time.Time {
+	next := p.NextRunAt
+	for !next.After(now) {
+		next = p.Frequency.Next(next)
+	}
+	return next
+}
+
+// RecordRun suma la corrida a los contadores del plan y lo pasa a la siguiente
+func (p *RecurringPlan) RecordRun(run *PlanRun, now time.Time) {
+	switch run.Status {
+	case PlanRunStatusExecuted:
+		p.RunCount++
+	case PlanRunStatusSkipped:
+		p.SkipCount++
+	default:
+		p.FailCount++
+	}
+
+	p.LastRunAt = &run.RunAt
+	p.LastRunStatus = run.Status
+	p.NextRunAt = p.NextRunAfter(now)
+	p.UpdatedAt = now
+}
+
+// PlanRun corrida de un plan recurrente (colección recurring_plan_runs)
+type PlanRun struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	RunID       string             `bson:"run_id" json:"run_id"` // <plan_id>-<unix de scheduled_at>, único por corrida
+	PlanID      string             `bson:"plan_id" json:"plan_id"`
+	UserID      int                `bson:"user_id" json:"user_id"`
+	Status      PlanRunStatus      `bson:"status" json:"status"`
+	ScheduledAt time.Time          `bson:"scheduled_at" json:"scheduled_at"`
+	RunAt       time.Time          `bson:"run_at" json:"run_at"`
+	Amount      decimal.Decimal    `bson:"amount" json:"amount"`
+	Price       decimal.Decimal    `bson:"price" json:"price"` // Precio usado para calcular la cantidad
+	Quantity    decimal.Decimal    `bson:"quantity" json:"quantity"`
+	OrderID     string             `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	OrderStatus OrderStatus        `bson:"order_status,omitempty" json:"order_status,omitempty"`
+	Reason      string             `bson:"reason,omitempty" json:"reason,omitempty"` // Motivo de skipped / failed
+}
+
+// NewPlanRunID arma el identificador de la corrida del plan programada en scheduledAt
+func NewPlanRunID(planID string, scheduledAt time.Time) string {
+	return fmt.Sprintf("%s-%d", planID, scheduledAt.Unix())
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanFrequency_Next(t *testing.T) {
+	start := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, 2, 1, 9, 0, 0, 0, time.UTC), PlanFrequencyDaily.Next(start))
+	assert.Equal(t, time.Date(2025, 2, 7, 9, 0, 0, 0, time.UTC), PlanFrequencyWeekly.Next(start))
+	assert.Equal(t, time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC), PlanFrequencyMonthly.Next(start))
+}
+
+func TestRecurringPlan_NextRunAfter(t *testing.T) {
+	plan := &RecurringPlan{
+		Frequency: PlanFrequencyWeekly,
+		NextRunAt: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC), // Lunes
+	}
+
+	// Corridas perdidas se saltean y se mantiene el día de la semana
+	now := time.Date(2025, 1, 22, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 1, 27, 9, 0, 0, 0, time.UTC), plan.NextRunAfter(now))
+
+	// Una corrida futura queda como está
+	now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, plan.NextRunAt, plan.NextRunAfter(now))
+}
+
+func TestRecurringPlan_RecordRun(t *testing.T) {
+	scheduled := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	now := scheduled.Add(time.Minute)
+	plan := &RecurringPlan{Frequency: PlanFrequencyDaily, NextRunAt: scheduled}
+
+	plan.RecordRun(&PlanRun{Status: PlanRunStatusExecuted, RunAt: now}, now)
+	plan.RecordRun(&PlanRun{Status: PlanRunStatusSkipped, RunAt: now}, now)
+	plan.RecordRun(&PlanRun{Status: PlanRunStatusFailed, RunAt: now}, now)
+
+	assert.Equal(t, 1, plan.RunCount)
+	assert.Equal(t, 1, plan.SkipCount)
+	assert.Equal(t, 1, plan.FailCount)
+	assert.Equal(t, PlanRunStatusFailed, plan.LastRunStatus)
+	assert.Equal(t, scheduled.AddDate(0, 0, 1), plan.NextRunAt)
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RiskReasonCode motivo por el que el chequeo pre-trade rechaza una orden
+type RiskReasonCode string
+
+const (
+	RiskReasonMaxOrderNotional  RiskReasonCode = "max_order_notional"  // Monto de la orden mayor al permitido
+	RiskReasonMaxDailyVolume    RiskReasonCode = "max_daily_volume"    // Superaría el volumen operado del día
+	RiskReasonMaxOpenOrders     RiskReasonCode = "max_open_orders"     // Ya tiene el máximo de órdenes abiertas
+	RiskReasonSymbolPositionCap RiskReasonCode = "symbol_position_cap" // Superaría la posición máxima del símbolo
+	RiskReasonPriceBand         RiskReasonCode = "price_band"          // Precio límite muy lejos del de mercado
+)
+
+// RiskPolicyID la política de riesgo es un único documento
+const RiskPolicyID = "default"
+
+// DefaultRiskRole rol cuyos límites aplican a los roles sin límites propios
+const DefaultRiskRole = "user"
+
+// SymbolPositionCap cantidad máxima del símbolo que puede llegar a tener el usuario
+type SymbolPositionCap struct {
+	Symbol      string          `bson:"symbol" json:"symbol"`
+	MaxQuantity decimal.Decimal `bson:"max_quantity" json:"max_quantity"`
+}
+
+// RiskLimits límites pre-trade de un rol. Un límite en cero no se controla.
+type RiskLimits struct {
+	MaxOrderNotional decimal.Decimal     `bson:"max_order_notional" json:"max_order_notional"` // USD por orden
+	MaxDailyVolume   decimal.Decimal     `bson:"max_daily_volume" json:"max_daily_volume"`     // USD ejecutados por día (UTC)
+	MaxOpenOrders    int                 `bson:"max_open_orders" json:"max_open_orders"`
+	PriceBand        decimal.Decimal     `bson:"price_band" json:"price_band"` // Desvío máximo del precio límite (0.1 = 10%)
+	PositionCaps     []SymbolPositionCap `bson:"position_caps" json:"position_caps"`
+}
+
+// PositionCap retorna la posición máxima del símbolo, si tiene una
+func (l *RiskLimits) PositionCap(symbol string) (decimal.Decimal, bool) {
+	for _, c := range l.PositionCaps {
+		if strings.EqualFold(c.Symbol, symbol) {
+			return c.MaxQuantity, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// RiskPolicy límites pre-trade por rol de usuario. Los admins la cambian en caliente.
+type RiskPolicy struct {
+	ID        string                `bson:"_id" json:"-"`
+	Roles     map[string]RiskLimits `bson:"roles" json:"roles"` // Rol -> límites; "user" aplica a los roles sin entrada
+	Version   int64                 `bson:"version" json:"version"`
+	UpdatedAt time.Time             `bson:"updated_at" json:"updated_at"`
+	UpdatedBy int                   `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+}
+
+// LimitsFor retorna los límites del rol (o los del rol por defecto)
+func (p *RiskPolicy) LimitsFor(role string) RiskLimits {
+	if limits, ok := p.Roles[strings.ToLower(role)]; ok {
+		return limits
+	}
+	return p.Roles[DefaultRiskRole]
+}
+
+// Normalize pasa los roles a minúsculas y los símbolos a mayúsculas
+func (p *RiskPolicy) Normalize() {
+	roles := make(map[string]RiskLimits, len(p.Roles))
+	for role, limits := range p.Roles {
+		for i := range limits.PositionCaps {
+			limits.PositionCaps[i].Symbol = strings.ToUpper(strings.TrimSpace(limits.PositionCaps[i].Symbol))
+		}
+		roles[strings.ToLower(strings.TrimSpace(role))] = limits
+	}
+	p.Roles = roles
+}
+
+// Validate verifica que la política sea consistente (llamar después de Normalize)
+func (p *RiskPolicy) Validate() error {
+	for role, limits := range p.Roles {
+		if role == "" {
+			return fmt.Errorf("role names cannot be empty")
+		}
+		if limits.MaxOrderNotional.IsNegative() || limits.MaxDailyVolume.IsNegative() || limits.MaxOpenOrders < 0 {
+			return fmt.Errorf("role %s: limits cannot be negative", role)
+		}
+		if limits.PriceBand.IsNegative() || limits.PriceBand.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+			return fmt.Errorf("role %s: price_band must be between 0 and 1", role)
+		}
+
+		symbols := make(map[string]bool)
+		for _, c := range limits.PositionCaps {
+			if c.Symbol == "" || symbols[c.Symbol] {
+				return fmt.Errorf("role %s: position caps must have unique, non-empty symbols", role)
+			}
+			symbols[c.Symbol] = true
+			if !c.MaxQuantity.IsPositive() {
+				return fmt.Errorf("role %s: position cap for %s must be greater than zero", role, c.Symbol)
+			}
+		}
+	}
+
+	return nil
+}
+
+// OpenExposure lo que el usuario ya tiene comprometido en órdenes abiertas
+type OpenExposure struct {
+	OpenOrders         int64           `json:"open_orders"`
+	PendingBuyQuantity decimal.Decimal `json:"pending_buy_quantity"` // Lo que falta llenar de sus compras abiertas del símbolo
+}
+
+// RiskRejection rechazo del chequeo pre-trade con su código de motivo
+type RiskRejection struct {
+	Code    RiskReasonCode  `json:"code"`
+	Message string          `json:"message"`
+	Limit   decimal.Decimal `json:"limit"`
+	Actual  decimal.Decimal `json:"actual"` // Valor que habría alcanzado con la orden
+}
+
+func (r *RiskRejection) Error() string {
+	return "risk limit exceeded: " + r.Message
+}
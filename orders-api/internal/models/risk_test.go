@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRiskPolicy_LimitsFor(t *testing.T) {
+	policy := &RiskPolicy{Roles: map[string]RiskLimits{
+		DefaultRiskRole: {MaxOpenOrders: 10},
+		"vip":           {MaxOpenOrders: 100},
+	}}
+
+	assert.Equal(t, 100, policy.LimitsFor("VIP").MaxOpenOrders)
+	assert.Equal(t, 10, policy.LimitsFor("trader").MaxOpenOrders)
+	assert.Equal(t, 10, policy.LimitsFor("").MaxOpenOrders)
+}
+
+func TestRiskPolicy_Validate(t *testing.T) {
+	valid := func() *RiskPolicy {
+		return &RiskPolicy{Roles: map[string]RiskLimits{
+			" User ": {
+				PriceBand:    decimal.NewFromFloat(0.1),
+				PositionCaps: []SymbolPositionCap{{Symbol: " btc", MaxQuantity: decimal.NewFromInt(1)}},
+			},
+		}}
+	}
+
+	policy := valid()
+	policy.Normalize()
+	assert.NoError(t, policy.Validate())
+	limits := policy.Roles["user"]
+	maxQuantity, ok := limits.PositionCap("BTC")
+	assert.True(t, ok)
+	assert.True(t, maxQuantity.Equal(decimal.NewFromInt(1)))
+
+	policy = valid()
+	limits = policy.Roles[" User "]
+	limits.PriceBand = decimal.NewFromInt(1)
+	policy.Roles[" User "] = limits
+	policy.Normalize()
+	assert.Error(t, policy.Validate())
+
+	policy = valid()
+	limits = policy.Roles[" User "]
+	limits.PositionCaps = append(limits.PositionCaps, SymbolPositionCap{Symbol: "BTC", MaxQuantity: decimal.NewFromInt(2)})
+	policy.Roles[" User "] = limits
+	policy.Normalize()
+	assert.Error(t, policy.Validate())
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"orders-api/internal/models"
+	"orders-api/pkg/database"
+)
+
+// AdminAuditRepository persiste el registro de acciones de los admins
+type AdminAuditRepository interface {
+	Record(ctx context.Context, entry *models.AdminAuditEntry) error
+	List(ctx context.Context, adminID *int, since *time.Time, limit int) ([]models.AdminAuditEntry, error)
+}
+
+type adminAuditRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewAdminAuditRepository(db *database.Database) AdminAuditRepository {
+	return &adminAuditRepository{
+		db:         db,
+		collection: db.GetCollection("admin_audit_log"),
+	}
+}
+
+// Record guarda una acción (el registro es append-only)
+func (r *adminAuditRepository) Record(ctx context.Context, entry *models.AdminAuditEntry) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	if _, err := r.collection.InsertOne(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record admin action: %w", err)
+	}
+
+	return nil
+}
+
+// List retorna las acciones más recientes primero, opcionalmente de un admin y desde since
+func (r *adminAuditRepository) List(ctx context.Context, adminID *int, since *time.Time, limit int) ([]models.AdminAuditEntry, error) {
+	filter := bson.M{}
+	if adminID != nil {
+		filter["admin_id"] = *adminID
+	}
+	if since != nil {
+		filter["created_at"] = bson.M{"$gte": *since}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{"created_at", -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list admin actions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AdminAuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode admin actions: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"orders-api/internal/models"
+	"orders-api/pkg/database"
+)
+
+// ErrFeeScheduleConflict indica que la tabla de comisiones cambió desde que se leyó
+var ErrFeeScheduleConflict = errors.New("fee schedule was modified concurrently")
+
+// FeeScheduleRepository persiste la tabla de comisiones (un único documento)
+type FeeScheduleRepository interface {
+	Get(ctx context.Context) (*models.FeeSchedule, error)
+	Save(ctx context.Context, schedule *models.FeeSchedule) error
+}
+
+type feeScheduleRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewFeeScheduleRepository(db *database.Database) FeeScheduleRepository {
+	return &feeScheduleRepository{
+		db:         db,
+		collection: db.GetCollection("fee_schedules"),
+	}
+}
+
+// Get retorna la tabla guardada o nil si todavía no se guardó ninguna
+func (r *feeScheduleRepository) Get(ctx context.Context) (*models.FeeSchedule, error) {
+	var schedule models.FeeSchedule
+	err := r.collection.FindOne(ctx, bson.M{"_id": models.FeeScheduleID}).Decode(&schedule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get fee schedule: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+// Save guarda la tabla con control optimista sobre Version (0 = todavía no existe)
+// e incrementa la versión. Retorna ErrFeeScheduleConflict si otra escritura ganó.
+func (r *feeScheduleRepository) Save(ctx context.Context, schedule *models.FeeSchedule) error {
+	expected := schedule.Version
+	schedule.ID = models.FeeScheduleID
+	schedule.Version = expected + 1
+
+	if expected == 0 {
+		if _, err := r.collection.InsertOne(ctx, schedule); err != nil {
+			schedule.Version = expected
+			if mongo.IsDuplicateKeyError(err) {
+				return ErrFeeScheduleConflict
+			}
+			return fmt.Errorf("failed to save fee schedule: %w", err)
+		}
+		return nil
+	}
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": models.FeeScheduleID, "version": expected}, schedule)
+	if err != nil {
+		schedule.Version = expected
+		return fmt.Errorf("failed to save fee schedule: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		schedule.Version = expected
+		return ErrFeeScheduleConflict
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"orders-api/internal/models"
+	"orders-api/pkg/database"
+)
+
+// FillRepository persiste los fills de las órdenes (colección order_fills).
+// Add recibe el contexto de la transacción de la orden para escribir ambos juntos.
+type FillRepository interface {
+	Add(ctx context.Context, fills []models.Fill) error
+	ListByOrder(ctx context.Context, orderID string) ([]models.Fill, error)
+}
+
+type fillRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewFillRepository(db *database.Database) FillRepository {
+	return &fillRepository{
+		db:         db,
+		collection: db.GetCollection("order_fills"),
+	}
+}
+
+// Add guarda los fills de una pasada. Cada fill se inserta por su FillID, así que
+// repetir la escritura al retomar una saga no los duplica.
+func (r *fillRepository) Add(ctx context.Context, fills []models.Fill) error {
+	for i := range fills {
+		_, err := r.collection.UpdateOne(ctx,
+			bson.M{"fill_id": fills[i].FillID},
+			bson.M{"$setOnInsert": fills[i]},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save fill %s: %w", fills[i].FillID, err)
+		}
+	}
+	return nil
+}
+
+// ListByOrder retorna los fills de la orden en el orden en que se llenaron
+func (r *fillRepository) ListByOrder(ctx context.Context, orderID string) ([]models.Fill, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"order_id": orderID}, options.Find().SetSort(bson.D{{"sequence", 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fills: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	fills := []models.Fill{}
+	if err := cursor.All(ctx, &fills); err != nil {
+		return nil, fmt.Errorf("failed to decode fills: %w", err)
+	}
+
+	return fills, nil
+}
@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -17,19 +19,34 @@ import (
 	"orders-api/pkg/database"
 )
 
+// ErrVersionConflict indica que la orden cambió desde que se leyó (otra escritura ganó)
+var ErrVersionConflict = errors.New("order was modified concurrently")
+
+// ErrDuplicateIdempotencyKey indica que el usuario ya creó una orden con la misma idempotency key
+var ErrDuplicateIdempotencyKey = errors.New("duplicate idempotency key")
+
 type OrderRepository interface {
 	Create(ctx context.Context, order *models.Order) error
 	GetByID(ctx context.Context, id string) (*models.Order, error)
 	GetByOrderNumber(ctx context.Context, orderNumber string) (*models.Order, error)
+	GetByIdempotencyKey(ctx context.Context, userID int, key string) (*models.Order, error)
 	Update(ctx context.Context, order *models.Order) error
 	Delete(ctx context.Context, id string) error
-	ListByUser(ctx context.Context, userID int, filter *dto.OrderFilterRequest) ([]models.Order, int64, error)
-	// ListAll y GetAdminStatistics eliminados en sistema simplificado (funciones admin no necesarias)
+	ListByUser(ctx context.Context, userID int, filter *dto.OrderFilterRequest) (*dto.OrderPage, error)
+	ListAll(ctx context.Context, filter *dto.AdminOrderFilterRequest) (*dto.OrderPage, error)
+	GetAdminStatistics(ctx context.Context) (*dto.AdminStatistics, error)
+	StreamFilled(ctx context.Context, userID int, from, to *time.Time, fn func(order *models.Order) error) error
 	GetOrdersSummary(ctx context.Context, userID int) (*dto.OrdersSummary, error)
+	GetUserVolume(ctx context.Context, userID int, since time.Time) (decimal.Decimal, error)
+	GetOpenExposure(ctx context.Context, userID int, symbol string) (*models.OpenExposure, error)
 	UpdateStatus(ctx context.Context, id string, status models.OrderStatus) error
-	GetPendingOrders(ctx context.Context, limit int) ([]models.Order, error)
+	GetPendingOrders(ctx context.Context, afterID primitive.ObjectID, now time.Time, limit int) ([]models.Order, error)
 	GetOrdersByStatus(ctx context.Context, status models.OrderStatus, limit int) ([]models.Order, error)
 	BulkUpdateStatus(ctx context.Context, orderIDs []string, status models.OrderStatus) error
+	ClaimOrder(ctx context.Context, id string, owner string, ttl time.Duration) (*models.Order, error)
+	ReleaseOrderClaim(ctx context.Context, id string, owner string) error
+	ClaimStalledExecution(ctx context.Context, staleBefore time.Time, owner string, ttl time.Duration) (*models.Order, error)
+	ClaimExpiredOrder(ctx context.Context, now time.Time, immediateBefore time.Time, owner string, ttl time.Duration) (*models.Order, error)
 }
 
 type orderRepository struct {
@@ -51,6 +68,7 @@ func (r *orderRepository) Create(ctx context.Context, order *models.Order) error
 
 	order.CreatedAt = time.Now()
 	order.UpdatedAt = time.Now()
+	order.Version = 1
 
 	if order.OrderNumber == "" {
 		order.OrderNumber = models.NewOrderNumber()
@@ -59,6 +77,9 @@ func (r *orderRepository) Create(ctx context.Context, order *models.Order) error
 	_, err := r.collection.InsertOne(ctx, order)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
+			if order.IdempotencyKey != "" && strings.Contains(err.Error(), "idempotency_key") {
+				return ErrDuplicateIdempotencyKey
+			}
 			return fmt.Errorf("order with number %s already exists", order.OrderNumber)
 		}
 		return fmt.Errorf("failed to create order: %w", err)
@@ -87,6 +108,23 @@ func (r *orderRepository) GetByID(ctx context.Context, id string) (*models.Order
 	return &order, nil
 }
 
+// GetByIdempotencyKey busca la orden creada por el usuario con esa key.
+// Retorna nil sin error si no existe.
+func (r *orderRepository) GetByIdempotencyKey(ctx context.Context, userID int, key string) (*models.Order, error) {
+	var order models.Order
+	filter := bson.M{"user_id": userID, "idempotency_key": key}
+
+	err := r.collection.FindOne(ctx, filter).Decode(&order)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get order by idempotency key: %w", err)
+	}
+
+	return &order, nil
+}
+
 func (r *orderRepository) GetByOrderNumber(ctx context.Context, orderNumber string) (*models.Order, error) {
 	var order models.Order
 	filter := bson.M{"order_number": orderNumber}
@@ -102,29 +140,48 @@ func (r *orderRepository) GetByOrderNumber(ctx context.Context, orderNumber stri
 	return &order, nil
 }
 
+// Update guarda la orden solo si su versión no cambió desde que se leyó.
+// Si otra escritura ganó retorna ErrVersionConflict y la orden queda con su versión original.
 func (r *orderRepository) Update(ctx context.Context, order *models.Order) error {
 	objectID, err := primitive.ObjectIDFromHex(order.ID.Hex())
 	if err != nil {
 		return fmt.Errorf("invalid order ID: %w", err)
 	}
 
+	expectedVersion := order.Version
+	order.Version = expectedVersion + 1
 	order.UpdatedAt = time.Now()
 
-	filter := bson.M{"_id": objectID}
+	filter := bson.M{"_id": objectID, "version": versionFilter(expectedVersion)}
 	update := bson.M{"$set": order}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
+		order.Version = expectedVersion
 		return fmt.Errorf("failed to update order: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
+		order.Version = expectedVersion
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": objectID})
+		if err == nil && count > 0 {
+			return ErrVersionConflict
+		}
 		return fmt.Errorf("order not found")
 	}
 
 	return nil
 }
 
+// versionFilter matchea la versión esperada; las órdenes creadas antes de
+// agregar el campo no lo tienen y equivalen a la versión 0
+func versionFilter(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
 func (r *orderRepository) Delete(ctx context.Context, id string) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -144,16 +201,15 @@ func (r *orderRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *orderRepository) ListByUser(ctx context.Context, userID int, filter *dto.OrderFilterRequest) ([]models.Order, int64, error) {
+func (r *orderRepository) ListByUser(ctx context.Context, userID int, filter *dto.OrderFilterRequest) (*dto.OrderPage, error) {
 	mongoFilter := bson.M{"user_id": userID}
 	r.applyFilters(mongoFilter, filter)
 
 	return r.executeQuery(ctx, mongoFilter, filter)
 }
 
-// ListAll comentado - función admin no necesaria en sistema simplificado
-/*
-func (r *orderRepository) ListAll(ctx context.Context, filter *dto.AdminOrderFilterRequest) ([]models.Order, int64, error) {
+// ListAll lista las órdenes de todos los usuarios (o de filter.UserID) para los admins
+func (r *orderRepository) ListAll(ctx context.Context, filter *dto.AdminOrderFilterRequest) (*dto.OrderPage, error) {
 	mongoFilter := bson.M{}
 
 	if filter.UserID != nil {
@@ -164,7 +220,6 @@ func (r *orderRepository) ListAll(ctx context.Context, filter *dto.AdminOrderFil
 
 	return r.executeQuery(ctx, mongoFilter, &filter.OrderFilterRequest)
 }
-*/
 
 func (r *orderRepository) applyFilters(mongoFilter bson.M, filter *dto.OrderFilterRequest) {
 	if filter.Status != nil {
@@ -179,30 +234,74 @@ func (r *orderRepository) applyFilters(mongoFilter bson.M, filter *dto.OrderFilt
 		mongoFilter["type"] = *filter.Type
 	}
 
-	// Filtros de fecha From/To eliminados en sistema simplificado
-	// Se puede agregar después si se necesita
+	if filter.OrderKind != nil {
+		mongoFilter["order_kind"] = *filter.OrderKind
+	}
+
+	if filter.From != nil || filter.To != nil {
+		createdAt := bson.M{}
+		if filter.From != nil {
+			createdAt["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			createdAt["$lt"] = *filter.To
+		}
+		mongoFilter["created_at"] = createdAt
+	}
+
+	// Los montos se guardan como double
+	if filter.MinTotal != nil || filter.MaxTotal != nil {
+		total := bson.M{}
+		if filter.MinTotal != nil {
+			total["$gte"] = filter.MinTotal.InexactFloat64()
+		}
+		if filter.MaxTotal != nil {
+			total["$lte"] = filter.MaxTotal.InexactFloat64()
+		}
+		mongoFilter["total_amount"] = total
+	}
 }
 
-func (r *orderRepository) executeQuery(ctx context.Context, mongoFilter bson.M, filter *dto.OrderFilterRequest) ([]models.Order, int64, error) {
+// executeQuery cuenta las órdenes que cumplen el filtro y trae una página ordenada
+// por el campo pedido y _id (desempate estable). Con cursor sigue después de la
+// última orden de la página anterior. Pide una orden de más para saber si hay otra página.
+func (r *orderRepository) executeQuery(ctx context.Context, mongoFilter bson.M, filter *dto.OrderFilterRequest) (*dto.OrderPage, error) {
 	total, err := r.collection.CountDocuments(ctx, mongoFilter)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count orders: %w", err)
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	direction := -1
+	comparison := "$lt"
+	if filter.SortOrder == dto.SortAsc {
+		direction = 1
+		comparison = "$gt"
+	}
+	sortField := string(filter.SortBy)
+
+	if after := filter.After(); after != nil {
+		value, err := after.SortValue()
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		mongoFilter["$or"] = []bson.M{
+			{sortField: bson.M{comparison: value}},
+			{sortField: value, "_id": bson.M{comparison: after.ObjectID()}},
+		}
 	}
 
 	findOptions := options.Find()
 	findOptions.SetSkip(int64(filter.GetOffset()))
-	findOptions.SetLimit(int64(filter.Limit))
-
-	// Sort por created_at descendente por defecto (más recientes primero)
-	findOptions.SetSort(bson.D{{"created_at", -1}})
+	findOptions.SetLimit(int64(filter.Limit + 1))
+	findOptions.SetSort(bson.D{{sortField, direction}, {"_id", direction}})
 
 	cursor, err := r.collection.Find(ctx, mongoFilter, findOptions)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to find orders: %w", err)
+		return nil, fmt.Errorf("failed to find orders: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	var orders []models.Order
+	orders := []models.Order{}
 	for cursor.Next(ctx) {
 		var order models.Order
 		if err := cursor.Decode(&order); err != nil {
@@ -214,10 +313,99 @@ func (r *orderRepository) executeQuery(ctx context.Context, mongoFilter bson.M,
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, 0, fmt.Errorf("cursor error: %w", err)
+		return nil, fmt.Errorf("cursor error: %w", err)
+	}
+
+	page := &dto.OrderPage{Orders: orders, Total: total}
+	if len(orders) > filter.Limit {
+		page.Orders = orders[:filter.Limit]
+		page.HasMore = true
+		last := &page.Orders[len(page.Orders)-1]
+		page.NextCursor = dto.NewOrderCursor(last, filter.SortBy, filter.SortOrder).Encode()
+	}
+
+	return page, nil
+}
+
+// exportBatchSize órdenes que trae Mongo por batch al exportar
+const exportBatchSize = 500
+
+// StreamFilled recorre las órdenes del usuario con algo llenado, en cualquier estado
+// (ejecutadas, y también parcialmente llenadas, canceladas, vencidas o fallidas
+// después de un fill), por momento del último fill (y _id) ascendente, y llama a fn
+// con cada una. Las ejecutadas anteriores a los fills parciales no tienen
+// filled_quantity y entran por su estado. Usa el cursor de Mongo, así la memoria no
+// crece con el historial; si fn retorna error el recorrido se corta con ese error.
+func (r *orderRepository) StreamFilled(ctx context.Context, userID int, from, to *time.Time, fn func(order *models.Order) error) error {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"user_id": userID,
+			"$or": []bson.M{
+				{"filled_quantity": bson.M{"$gt": 0}},
+				{"status": models.OrderStatusExecuted},
+			},
+		}},
+		// Mismo orden que Order.LastFilledAt
+		{"$addFields": bson.M{
+			"filled_at": bson.M{"$ifNull": []interface{}{"$last_fill_at", "$executed_at", "$updated_at"}},
+		}},
+	}
+	if from != nil || to != nil {
+		filledAt := bson.M{}
+		if from != nil {
+			filledAt["$gte"] = *from
+		}
+		if to != nil {
+			filledAt["$lt"] = *to
+		}
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"filled_at": filledAt}})
+	}
+	pipeline = append(pipeline,
+		bson.M{"$sort": bson.D{{"filled_at", 1}, {"_id", 1}}},
+		bson.M{"$project": bson.M{
+			"order_number":    1,
+			"crypto_symbol":   1,
+			"type":            1,
+			"status":          1,
+			"quantity":        1,
+			"price":           1,
+			"total_amount":    1,
+			"fee":             1,
+			"filled_quantity": 1,
+			"filled_amount":   1,
+			"filled_fee":      1,
+			"avg_fill_price":  1,
+			"last_fill_at":    1,
+			"executed_at":     1,
+			"updated_at":      1,
+		}},
+	)
+
+	aggregateOptions := options.Aggregate().
+		SetBatchSize(exportBatchSize).
+		SetAllowDiskUse(true)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline, aggregateOptions)
+	if err != nil {
+		return fmt.Errorf("failed to find filled orders: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var order models.Order
+		if err := cursor.Decode(&order); err != nil {
+			return fmt.Errorf("failed to decode order: %w", err)
+		}
+		if err := fn(&order); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("cursor error: %w", err)
 	}
 
-	return orders, total, nil
+	return nil
 }
 
 func (r *orderRepository) GetOrdersSummary(ctx context.Context, userID int) (*dto.OrdersSummary, error) {
@@ -261,39 +449,151 @@ func (r *orderRepository) GetOrdersSummary(ctx context.Context, userID int) (*dt
 	return summary, nil
 }
 
-// GetAdminStatistics comentado - función admin no necesaria en sistema simplificado
-/*
+// GetUserVolume suma lo operado por el usuario desde since (volumen con el que se
+// elige el escalón de comisiones)
+func (r *orderRepository) GetUserVolume(ctx context.Context, userID int, since time.Time) (decimal.Decimal, error) {
+	pipeline := userVolumePipeline(userID, since)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("failed to get user volume: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to decode user volume: %w", err)
+	}
+
+	if len(results) == 0 {
+		return decimal.Zero, nil
+	}
+
+	return parseDecimalFromBSON(results[0]["volume"]), nil
+}
+
+// userVolumePipeline suma filled_amount de las órdenes con algo llenado cuyo último
+// fill es desde since, en cualquier estado: así cuentan los fills parciales de
+// órdenes abiertas y de las que después se cancelaron, vencieron o fallaron (el
+// resto de una IOC, por ejemplo). Las ejecutadas anteriores a los fills parciales
+// no tienen last_fill_at ni filled_amount y entran por executed_at y total_amount.
+func userVolumePipeline(userID int, since time.Time) []bson.M {
+	return []bson.M{
+		{"$match": bson.M{
+			"user_id": userID,
+			"$or": []bson.M{
+				{
+					"filled_quantity": bson.M{"$gt": 0},
+					"last_fill_at":    bson.M{"$gte": since},
+				},
+				{
+					"last_fill_at": bson.M{"$exists": false},
+					"status":       models.OrderStatusExecuted,
+					"executed_at":  bson.M{"$gte": since},
+				},
+			},
+		}},
+		{"$group": bson.M{
+			"_id": nil,
+			"volume": bson.M{"$sum": bson.M{"$cond": []interface{}{
+				bson.M{"$gt": []interface{}{"$filled_quantity", 0}},
+				"$filled_amount",
+				"$total_amount",
+			}}},
+		}},
+	}
+}
+
+// GetOpenExposure cuenta las órdenes abiertas del usuario y suma lo que falta llenar
+// de sus compras abiertas de symbol (chequeos pre-trade de riesgo)
+func (r *orderRepository) GetOpenExposure(ctx context.Context, userID int, symbol string) (*models.OpenExposure, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"user_id": userID,
+			"status": bson.M{"$in": []models.OrderStatus{
+				models.OrderStatusPending,
+				models.OrderStatusTriggered,
+				models.OrderStatusPartiallyFilled,
+			}},
+		}},
+		{"$group": bson.M{
+			"_id":         nil,
+			"open_orders": bson.M{"$sum": 1},
+			"pending_buy_quantity": bson.M{"$sum": bson.M{"$cond": []interface{}{
+				bson.M{"$and": []interface{}{
+					bson.M{"$eq": []interface{}{"$type", models.OrderTypeBuy}},
+					bson.M{"$eq": []interface{}{"$crypto_symbol", symbol}},
+				}},
+				bson.M{"$subtract": []interface{}{"$quantity", bson.M{"$ifNull": []interface{}{"$filled_quantity", 0}}}},
+				0,
+			}}},
+		}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open exposure: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode open exposure: %w", err)
+	}
+
+	if len(results) == 0 {
+		return &models.OpenExposure{}, nil
+	}
+
+	return &models.OpenExposure{
+		OpenOrders:         parseInt64FromBSON(results[0]["open_orders"]),
+		PendingBuyQuantity: parseDecimalFromBSON(results[0]["pending_buy_quantity"]),
+	}, nil
+}
+
+// GetAdminStatistics calcula las estadísticas globales de órdenes. Volumen y
+// comisiones salen de lo llenado (filled_amount / filled_fee), así cuentan los
+// fills parciales de órdenes que después se cancelaron o vencieron.
 func (r *orderRepository) GetAdminStatistics(ctx context.Context) (*dto.AdminStatistics, error) {
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	filled := bson.M{"filled_quantity": bson.M{"$gt": 0}}
 
 	pipeline := []bson.M{
 		{"$facet": bson.M{
-			"overall": []bson.M{
+			"by_status": []bson.M{
+				{"$group": bson.M{
+					"_id":          "$status",
+					"total_orders": bson.M{"$sum": 1},
+				}},
+			},
+			"filled": []bson.M{
+				{"$match": filled},
 				{"$group": bson.M{
-					"_id":           nil,
-					"total_orders":  bson.M{"$sum": 1},
-					"total_volume":  bson.M{"$sum": "$total_amount"},
-					"total_fees":    bson.M{"$sum": "$fee"},
-					"avg_order":     bson.M{"$avg": "$total_amount"},
+					"_id":          nil,
+					"total_volume": bson.M{"$sum": "$filled_amount"},
+					"total_fees":   bson.M{"$sum": "$filled_fee"},
+					"avg_order":    bson.M{"$avg": "$filled_amount"},
 				}},
 			},
 			"today": []bson.M{
 				{"$match": bson.M{"created_at": bson.M{"$gte": today}}},
 				{"$group": bson.M{
-					"_id":           nil,
-					"orders_today":  bson.M{"$sum": 1},
-					"volume_today":  bson.M{"$sum": "$total_amount"},
+					"_id":          nil,
+					"orders_today": bson.M{"$sum": 1},
+					"volume_today": bson.M{"$sum": "$filled_amount"},
+					"fees_today":   bson.M{"$sum": "$filled_fee"},
 				}},
 			},
-			"top_cryptos": []bson.M{
+			"by_symbol": []bson.M{
 				{"$group": bson.M{
-					"_id":           "$crypto_symbol",
-					"total_orders":  bson.M{"$sum": 1},
-					"total_volume":  bson.M{"$sum": "$total_amount"},
+					"_id":             "$crypto_symbol",
+					"total_orders":    bson.M{"$sum": 1},
+					"executed_orders": bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$eq": []interface{}{"$status", models.OrderStatusExecuted}}, 1, 0}}},
+					"total_volume":    bson.M{"$sum": "$filled_amount"},
+					"total_fees":      bson.M{"$sum": "$filled_fee"},
 				}},
-				{"$sort": bson.M{"total_volume": -1}},
-				{"$limit": 10},
+				{"$sort": bson.D{{"total_volume", -1}, {"_id", 1}}},
 			},
 		}},
 	}
@@ -309,45 +609,46 @@ func (r *orderRepository) GetAdminStatistics(ctx context.Context) (*dto.AdminSta
 		return nil, fmt.Errorf("failed to decode statistics: %w", err)
 	}
 
+	stats := &dto.AdminStatistics{
+		OrdersByStatus: make(map[string]int64),
+		Symbols:        []dto.CryptoStats{},
+		GeneratedAt:    now,
+	}
 	if len(results) == 0 {
-		return &dto.AdminStatistics{}, nil
+		return stats, nil
 	}
-
 	result := results[0]
-	stats := &dto.AdminStatistics{}
-
-	if overall, ok := result["overall"].([]interface{}); ok && len(overall) > 0 {
-		if overallData, ok := overall[0].(bson.M); ok {
-			stats.TotalOrders = parseInt64FromBSON(overallData["total_orders"])
-			stats.TotalVolume = parseDecimalFromBSON(overallData["total_volume"])
-			stats.TotalFeesCollected = parseDecimalFromBSON(overallData["total_fees"])
-			stats.AverageOrderSize = parseDecimalFromBSON(overallData["avg_order"])
-		}
+
+	for _, row := range facetRows(result["by_status"]) {
+		count := parseInt64FromBSON(row["total_orders"])
+		stats.OrdersByStatus[parseStringFromBSON(row["_id"])] = count
+		stats.TotalOrders += count
 	}
 
-	if today, ok := result["today"].([]interface{}); ok && len(today) > 0 {
-		if todayData, ok := today[0].(bson.M); ok {
-			stats.OrdersToday = parseInt64FromBSON(todayData["orders_today"])
-			stats.VolumeToday = parseDecimalFromBSON(todayData["volume_today"])
-		}
+	if rows := facetRows(result["filled"]); len(rows) > 0 {
+		stats.TotalVolume = parseDecimalFromBSON(rows[0]["total_volume"])
+		stats.FeeRevenue = parseDecimalFromBSON(rows[0]["total_fees"])
+		stats.AverageOrderSize = parseDecimalFromBSON(rows[0]["avg_order"])
 	}
 
-	if topCryptos, ok := result["top_cryptos"].([]interface{}); ok {
-		stats.TopCryptocurrencies = make([]dto.CryptoStats, len(topCryptos))
-		for i, crypto := range topCryptos {
-			if cryptoData, ok := crypto.(bson.M); ok {
-				stats.TopCryptocurrencies[i] = dto.CryptoStats{
-					Symbol:      parseStringFromBSON(cryptoData["_id"]),
-					TotalOrders: parseInt64FromBSON(cryptoData["total_orders"]),
-					TotalVolume: parseDecimalFromBSON(cryptoData["total_volume"]),
-				}
-			}
-		}
+	if rows := facetRows(result["today"]); len(rows) > 0 {
+		stats.OrdersToday = parseInt64FromBSON(rows[0]["orders_today"])
+		stats.VolumeToday = parseDecimalFromBSON(rows[0]["volume_today"])
+		stats.FeeRevenueToday = parseDecimalFromBSON(rows[0]["fees_today"])
+	}
+
+	for _, row := range facetRows(result["by_symbol"]) {
+		stats.Symbols = append(stats.Symbols, dto.CryptoStats{
+			Symbol:         parseStringFromBSON(row["_id"]),
+			TotalOrders:    parseInt64FromBSON(row["total_orders"]),
+			ExecutedOrders: parseInt64FromBSON(row["executed_orders"]),
+			TotalVolume:    parseDecimalFromBSON(row["total_volume"]),
+			FeeRevenue:     parseDecimalFromBSON(row["total_fees"]),
+		})
 	}
 
 	return stats, nil
 }
-*/
 
 func (r *orderRepository) UpdateStatus(ctx context.Context, id string, status models.OrderStatus) error {
 	objectID, err := primitive.ObjectIDFromHex(id)
@@ -361,6 +662,7 @@ func (r *orderRepository) UpdateStatus(ctx context.Context, id string, status mo
 			"status":     status,
 			"updated_at": time.Now(),
 		},
+		"$inc": bson.M{"version": 1},
 	}
 
 	if status == models.OrderStatusExecuted {
@@ -381,9 +683,28 @@ func (r *orderRepository) UpdateStatus(ctx context.Context, id string, status mo
 	return nil
 }
 
-func (r *orderRepository) GetPendingOrders(ctx context.Context, limit int) ([]models.Order, error) {
-	filter := bson.M{"status": models.OrderStatusPending}
-	findOptions := options.Find().SetLimit(int64(limit)).SetSort(bson.D{{"created_at", 1}})
+// openStatuses estados de las órdenes que el matcher puede ejecutar
+var openStatuses = []models.OrderStatus{models.OrderStatusPending, models.OrderStatusTriggered, models.OrderStatusPartiallyFilled}
+
+// GetPendingOrders retorna una página de las órdenes que esperan en el libro a que
+// el matcher las ejecute: abiertas, no market, no IOC/FOK y sin vencer. Se pagina
+// por _id desde afterID (NilObjectID para la primera página), así una pasada
+// recorre todas las órdenes abiertas aunque las más viejas no crucen nunca.
+func (r *orderRepository) GetPendingOrders(ctx context.Context, afterID primitive.ObjectID, now time.Time, limit int) ([]models.Order, error) {
+	filter := bson.M{
+		"status":        bson.M{"$in": openStatuses},
+		"order_kind":    bson.M{"$ne": models.OrderKindMarket},
+		"time_in_force": bson.M{"$nin": []models.TimeInForce{models.TimeInForceIOC, models.TimeInForceFOK}},
+		"$or": []bson.M{
+			{"expires_at": bson.M{"$exists": false}},
+			{"expires_at": nil},
+			{"expires_at": bson.M{"$gt": now}},
+		},
+	}
+	if !afterID.IsZero() {
+		filter["_id"] = bson.M{"$gt": afterID}
+	}
+	findOptions := options.Find().SetLimit(int64(limit)).SetSort(bson.D{{"_id", 1}})
 
 	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
@@ -433,6 +754,7 @@ func (r *orderRepository) BulkUpdateStatus(ctx context.Context, orderIDs []strin
 			"status":     status,
 			"updated_at": time.Now(),
 		},
+		"$inc": bson.M{"version": 1},
 	}
 
 	if status == models.OrderStatusCancelled {
@@ -451,6 +773,145 @@ func (r *orderRepository) BulkUpdateStatus(ctx context.Context, orderIDs []strin
 	return nil
 }
 
+// ClaimOrder toma una orden pendiente para ejecutarla de forma exclusiva.
+// El lock vence después de ttl, por lo que una réplica caída no deja la orden bloqueada.
+// Retorna nil sin error si la orden ya no está pendiente o la tiene otra instancia.
+func (r *orderRepository) ClaimOrder(ctx context.Context, id string, owner string, ttl time.Duration) (*models.Order, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID: %w", err)
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		"_id":    objectID,
+		"status": bson.M{"$in": openStatuses},
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lt": now}},
+			{"locked_by": owner},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"locked_by":    owner,
+			"locked_until": now.Add(ttl),
+		},
+		// Incrementar la versión invalida cualquier amend/cancel leído antes del claim
+		"$inc": bson.M{"version": 1},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var order models.Order
+	err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&order)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim order: %w", err)
+	}
+
+	return &order, nil
+}
+
+// ReleaseOrderClaim libera el lock de ejecución si todavía pertenece a owner
+func (r *orderRepository) ReleaseOrderClaim(ctx context.Context, id string, owner string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid order ID: %w", err)
+	}
+
+	filter := bson.M{"_id": objectID, "locked_by": owner}
+	update := bson.M{"$unset": bson.M{"locked_by": "", "locked_until": ""}}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to release order claim: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimStalledExecution toma la orden con la saga de ejecución más vieja sin avances
+// desde staleBefore (la instancia que la ejecutaba se cayó). Retorna nil si no hay.
+func (r *orderRepository) ClaimStalledExecution(ctx context.Context, staleBefore time.Time, owner string, ttl time.Duration) (*models.Order, error) {
+	now := time.Now()
+	filter := bson.M{
+		"status":     models.OrderStatusExecuting,
+		"updated_at": bson.M{"$lt": staleBefore},
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"locked_by":    owner,
+			"locked_until": now.Add(ttl),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetSort(bson.D{{"updated_at", 1}})
+
+	var order models.Order
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&order)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim stalled execution: %w", err)
+	}
+
+	return &order, nil
+}
+
+// ClaimExpiredOrder toma una orden abierta que ya venció: una gtd con expires_at pasado
+// o una IOC/FOK creada antes de immediateBefore que quedó abierta (la instancia que la
+// creó se cayó antes de expirarla). Retorna nil si no hay ninguna libre.
+func (r *orderRepository) ClaimExpiredOrder(ctx context.Context, now time.Time, immediateBefore time.Time, owner string, ttl time.Duration) (*models.Order, error) {
+	filter := bson.M{
+		"status": bson.M{"$in": openStatuses},
+		"$and": []bson.M{
+			{"$or": []bson.M{
+				{"expires_at": bson.M{"$lte": now}},
+				{
+					"time_in_force": bson.M{"$in": []models.TimeInForce{models.TimeInForceIOC, models.TimeInForceFOK}},
+					"created_at":    bson.M{"$lt": immediateBefore},
+				},
+			}},
+			{"$or": []bson.M{
+				{"locked_until": bson.M{"$exists": false}},
+				{"locked_until": bson.M{"$lt": now}},
+			}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"locked_by":    owner,
+			"locked_until": now.Add(ttl),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetSort(bson.D{{"expires_at", 1}})
+
+	var order models.Order
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&order)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim expired order: %w", err)
+	}
+
+	return &order, nil
+}
+
 // Helper functions for parsing BSON data
 func parseDecimalFromBSON(value interface{}) decimal.Decimal {
 	switch v := value.(type) {
@@ -481,6 +942,26 @@ func parseInt64FromBSON(value interface{}) int64 {
 	}
 }
 
+// facetRows retorna los documentos de una salida de $facet
+func facetRows(value interface{}) []bson.M {
+	var rows []bson.M
+	switch v := value.(type) {
+	case bson.A:
+		for _, item := range v {
+			if row, ok := item.(bson.M); ok {
+				rows = append(rows, row)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if row, ok := item.(bson.M); ok {
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
+
 func parseStringFromBSON(value interface{}) string {
 	if s, ok := value.(string); ok {
 		return s
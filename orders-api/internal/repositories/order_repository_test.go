@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"orders-api/internal/models"
+)
+
+func TestUserVolumePipeline(t *testing.T) {
+	since := time.Date(2026, 9, 16, 0, 0, 0, 0, time.UTC)
+	pipeline := userVolumePipeline(7, since)
+	require.Len(t, pipeline, 2)
+
+	match := pipeline[0]["$match"].(bson.M)
+	assert.Equal(t, 7, match["user_id"])
+	branches := match["$or"].([]bson.M)
+	require.Len(t, branches, 2)
+
+	t.Run("fills count in any status, by last fill", func(t *testing.T) {
+		// Partially filled, and cancelled/expired/failed after a fill (e.g. the rest of an IOC)
+		fills := branches[0]
+		assert.NotContains(t, fills, "status")
+		assert.Equal(t, bson.M{"$gt": 0}, fills["filled_quantity"])
+		assert.Equal(t, bson.M{"$gte": since}, fills["last_fill_at"])
+	})
+
+	t.Run("executed orders from before partial fills", func(t *testing.T) {
+		legacy := branches[1]
+		assert.Equal(t, bson.M{"$exists": false}, legacy["last_fill_at"])
+		assert.Equal(t, models.OrderStatusExecuted, legacy["status"])
+		assert.Equal(t, bson.M{"$gte": since}, legacy["executed_at"])
+	})
+
+	t.Run("sums the filled amount", func(t *testing.T) {
+		group := pipeline[1]["$group"].(bson.M)
+		volume := group["volume"].(bson.M)["$sum"].(bson.M)["$cond"].([]interface{})
+		assert.Equal(t, "$filled_amount", volume[1])
+		assert.Equal(t, "$total_amount", volume[2])
+	})
+}
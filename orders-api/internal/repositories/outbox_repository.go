@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"orders-api/internal/models"
+	"orders-api/pkg/database"
+)
+
+// ErrOutboxLockLost indica que el lock del evento venció y lo tomó otra réplica
+var ErrOutboxLockLost = errors.New("outbox event lock lost")
+
+// OutboxRepository persiste los eventos de órdenes pendientes de publicar.
+// Add recibe el contexto de la transacción de la orden para escribir ambos juntos.
+type OutboxRepository interface {
+	Add(ctx context.Context, event *models.OutboxEvent) error
+	ClaimBatch(ctx context.Context, owner string, ttl time.Duration, limit int) ([]*models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, event *models.OutboxEvent) error
+	MarkFailed(ctx context.Context, event *models.OutboxEvent) error
+}
+
+type outboxRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewOutboxRepository(db *database.Database) OutboxRepository {
+	return &outboxRepository{
+		db:         db,
+		collection: db.GetCollection("order_outbox"),
+	}
+}
+
+func (r *outboxRepository) Add(ctx context.Context, event *models.OutboxEvent) error {
+	if _, err := r.collection.InsertOne(ctx, event); err != nil {
+		return fmt.Errorf("failed to add outbox event: %w", err)
+	}
+	return nil
+}
+
+// ClaimBatch toma hasta limit eventos para publicar, como mucho uno por orden: el
+// más viejo sin publicar de cada orden, si ya puede reintentarse y nadie tiene su
+// lock. Así los consumidores reciben los eventos de cada orden en el orden en que se
+// escribieron: mientras el primero espera su reintento, lo tiene otra réplica o
+// quedó failed, los siguientes de esa orden no se toman. Una sola agregación trae la
+// cabeza de cada orden, así el costo por pasada no crece con los eventos atrasados.
+func (r *outboxRepository) ClaimBatch(ctx context.Context, owner string, ttl time.Duration, limit int) ([]*models.OutboxEvent, error) {
+	cursor, err := r.collection.Aggregate(ctx, outboxHeadsPipeline(), options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find outbox events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var heads []models.OutboxEvent
+	if err := cursor.All(ctx, &heads); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox events: %w", err)
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"locked_by":    owner,
+			"locked_until": now.Add(ttl),
+		},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var claimed []*models.OutboxEvent
+	for _, candidate := range pickClaimable(heads, now, limit) {
+		filter := claimableFilter(now)
+		filter["_id"] = candidate.ID
+
+		var event models.OutboxEvent
+		err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&event)
+		if err == mongo.ErrNoDocuments {
+			// Otra réplica lo tomó entre la agregación y el update
+			continue
+		}
+		if err != nil {
+			return claimed, fmt.Errorf("failed to claim outbox event: %w", err)
+		}
+		claimed = append(claimed, &event)
+	}
+
+	return claimed, nil
+}
+
+// outboxHeadsPipeline trae el evento más viejo sin publicar (pending o failed) de cada
+// orden, sin el payload. Los eventos de planes recurrentes no tienen orden y cada uno
+// es su propia cabeza.
+func outboxHeadsPipeline() []bson.M {
+	return []bson.M{
+		{"$match": bson.M{"status": bson.M{"$in": []models.OutboxStatus{models.OutboxStatusPending, models.OutboxStatusFailed}}}},
+		{"$project": bson.M{"payload": 0}},
+		{"$sort": bson.D{{"created_at", 1}, {"_id", 1}}},
+		{"$group": bson.M{
+			"_id": bson.M{"$cond": []interface{}{
+				bson.M{"$eq": []interface{}{"$order_id", ""}}, "$_id", "$order_id",
+			}},
+			"head": bson.M{"$first": "$$ROOT"},
+		}},
+		{"$replaceRoot": bson.M{"newRoot": "$head"}},
+		{"$sort": bson.D{{"created_at", 1}, {"_id", 1}}},
+	}
+}
+
+// pickClaimable elige, de los eventos sin publicar ordenados por creación, hasta limit
+// que se pueden tomar ahora. El primer evento de cada orden la bloquea si no se puede
+// tomar (espera su reintento, tiene un lock vigente o quedó failed); los siguientes
+// de esa orden nunca se eligen antes que él.
+func pickClaimable(events []models.OutboxEvent, now time.Time, limit int) []models.OutboxEvent {
+	var picked []models.OutboxEvent
+	seen := make(map[string]bool)
+
+	for _, event := range events {
+		if len(picked) >= limit {
+			break
+		}
+		if event.OrderID != "" {
+			if seen[event.OrderID] {
+				continue
+			}
+			seen[event.OrderID] = true
+		}
+
+		if event.Status != models.OutboxStatusPending || event.NextAttemptAt.After(now) {
+			continue
+		}
+		if event.LockedUntil != nil && !event.LockedUntil.Before(now) {
+			continue
+		}
+		picked = append(picked, event)
+	}
+
+	return picked
+}
+
+// claimableFilter eventos pendientes que ya pueden reintentarse y cuyo lock venció (o no tienen)
+func claimableFilter(now time.Time) bson.M {
+	return bson.M{
+		"status":          models.OutboxStatusPending,
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lt": now}},
+		},
+	}
+}
+
+// lockedByFilter el evento, solo si sigue tomado por quien lo reclamó
+func lockedByFilter(event *models.OutboxEvent) bson.M {
+	return bson.M{"_id": event.ID, "locked_by": event.LockedBy}
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, event *models.OutboxEvent) error {
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"status":       models.OutboxStatusPublished,
+			"attempts":     event.Attempts,
+			"published_at": now,
+		},
+		"$unset": bson.M{"locked_by": "", "locked_until": "", "last_error": ""},
+	}
+
+	// Solo quien tiene el lock registra el resultado; si venció, el de la otra réplica vale
+	result, err := r.collection.UpdateOne(ctx, lockedByFilter(event), update)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox event as published: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrOutboxLockLost
+	}
+
+	event.Status = models.OutboxStatusPublished
+	event.PublishedAt = &now
+	return nil
+}
+
+// MarkFailed guarda el intento fallido con el próximo reintento (o el estado failed)
+func (r *outboxRepository) MarkFailed(ctx context.Context, event *models.OutboxEvent) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":          event.Status,
+			"attempts":        event.Attempts,
+			"next_attempt_at": event.NextAttemptAt,
+			"last_error":      event.LastError,
+		},
+		"$unset": bson.M{"locked_by": "", "locked_until": ""},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, lockedByFilter(event), update)
+	if err != nil {
+		return fmt.Errorf("failed to record outbox event failure: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrOutboxLockLost
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"orders-api/internal/models"
+)
+
+func TestPickClaimable(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Minute)
+	earlier := now.Add(-time.Minute)
+
+	// newEvent arma un evento pendiente de orderID creado en el orden de las llamadas
+	created := now.Add(-time.Hour)
+	newEvent := func(orderID, routingKey string) models.OutboxEvent {
+		created = created.Add(time.Second)
+		return models.OutboxEvent{
+			ID:            primitive.NewObjectID(),
+			EventID:       orderID + ":" + routingKey,
+			OrderID:       orderID,
+			RoutingKey:    routingKey,
+			Status:        models.OutboxStatusPending,
+			NextAttemptAt: created,
+			CreatedAt:     created,
+		}
+	}
+	eventIDs := func(events []models.OutboxEvent) []string {
+		ids := []string{}
+		for _, event := range events {
+			ids = append(ids, event.EventID)
+		}
+		return ids
+	}
+
+	t.Run("later event of an order waits behind an earlier one that is retrying", func(t *testing.T) {
+		retrying := newEvent("order-1", "orders.created")
+		retrying.Attempts = 2
+		retrying.NextAttemptAt = later
+		executed := newEvent("order-1", "orders.executed")
+		other := newEvent("order-2", "orders.created")
+
+		picked := pickClaimable([]models.OutboxEvent{retrying, executed, other}, now, 10)
+
+		assert.Equal(t, []string{"order-2:orders.created"}, eventIDs(picked))
+	})
+
+	t.Run("claim held by another replica blocks the rest of that order", func(t *testing.T) {
+		held := newEvent("order-1", "orders.created")
+		held.LockedBy = "replica-b"
+		held.LockedUntil = &later
+		executed := newEvent("order-1", "orders.executed")
+
+		assert.Empty(t, pickClaimable([]models.OutboxEvent{held, executed}, now, 10))
+	})
+
+	t.Run("expired claim is taken again before the rest of the order", func(t *testing.T) {
+		abandoned := newEvent("order-1", "orders.created")
+		abandoned.LockedBy = "replica-b"
+		abandoned.LockedUntil = &earlier
+		executed := newEvent("order-1", "orders.executed")
+
+		picked := pickClaimable([]models.OutboxEvent{abandoned, executed}, now, 10)
+
+		assert.Equal(t, []string{"order-1:orders.created"}, eventIDs(picked))
+	})
+
+	t.Run("failed event blocks the rest of its order", func(t *testing.T) {
+		failed := newEvent("order-1", "orders.created")
+		failed.Status = models.OutboxStatusFailed
+		executed := newEvent("order-1", "orders.executed")
+
+		assert.Empty(t, pickClaimable([]models.OutboxEvent{failed, executed}, now, 10))
+	})
+
+	t.Run("plan run events do not block each other", func(t *testing.T) {
+		retrying := newEvent("", "recurring_plans.run_failed")
+		retrying.NextAttemptAt = later
+		next := newEvent("", "recurring_plans.run_executed")
+
+		picked := pickClaimable([]models.OutboxEvent{retrying, next}, now, 10)
+
+		assert.Equal(t, []string{":recurring_plans.run_executed"}, eventIDs(picked))
+	})
+
+	t.Run("one event per order up to the limit", func(t *testing.T) {
+		events := []models.OutboxEvent{
+			newEvent("order-1", "orders.created"),
+			newEvent("order-1", "orders.executed"),
+			newEvent("order-2", "orders.created"),
+			newEvent("order-3", "orders.created"),
+		}
+
+		picked := pickClaimable(events, now, 2)
+
+		assert.Equal(t, []string{"order-1:orders.created", "order-2:orders.created"}, eventIDs(picked))
+	})
+}
+
+func TestLockedByFilter(t *testing.T) {
+	event := &models.OutboxEvent{ID: primitive.NewObjectID(), LockedBy: "replica-a"}
+
+	// MarkPublished y MarkFailed no matchean el evento si ahora lo tiene otra réplica
+	assert.Equal(t, bson.M{"_id": event.ID, "locked_by": "replica-a"}, lockedByFilter(event))
+}
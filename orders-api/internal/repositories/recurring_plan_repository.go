@@ -0,0 +1,228 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"orders-api/internal/models"
+	"orders-api/pkg/database"
+)
+
+// ErrPlanNotFound indica que el plan no existe (o no es del usuario)
+var ErrPlanNotFound = errors.New("recurring plan not found")
+
+// RecurringPlanRepository persiste los planes de compra recurrente (colección
+// recurring_plans) y el historial de sus corridas (colección recurring_plan_runs)
+type RecurringPlanRepository interface {
+	Create(ctx context.Context, plan *models.RecurringPlan) error
+	GetByID(ctx context.Context, id string) (*models.RecurringPlan, error)
+	ListByUser(ctx context.Context, userID int) ([]models.RecurringPlan, error)
+	UpdateStatus(ctx context.Context, id string, status models.PlanStatus, nextRunAt time.Time) error
+	Delete(ctx context.Context, id string) error
+	ClaimDuePlan(ctx context.Context, now time.Time, owner string, ttl time.Duration) (*models.RecurringPlan, error)
+	FinishRun(ctx context.Context, plan *models.RecurringPlan, owner string) error
+	AddRun(ctx context.Context, run *models.PlanRun) error
+	ListRuns(ctx context.Context, planID string, limit int) ([]models.PlanRun, error)
+}
+
+type recurringPlanRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+	runs       *mongo.Collection
+}
+
+func NewRecurringPlanRepository(db *database.Database) RecurringPlanRepository {
+	return &recurringPlanRepository{
+		db:         db,
+		collection: db.GetCollection("recurring_plans"),
+		runs:       db.GetCollection("recurring_plan_runs"),
+	}
+}
+
+func (r *recurringPlanRepository) Create(ctx context.Context, plan *models.RecurringPlan) error {
+	if plan.ID.IsZero() {
+		plan.ID = primitive.NewObjectID()
+	}
+
+	if _, err := r.collection.InsertOne(ctx, plan); err != nil {
+		return fmt.Errorf("failed to create recurring plan: %w", err)
+	}
+	return nil
+}
+
+func (r *recurringPlanRepository) GetByID(ctx context.Context, id string) (*models.RecurringPlan, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrPlanNotFound
+	}
+
+	var plan models.RecurringPlan
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&plan)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPlanNotFound
+		}
+		return nil, fmt.Errorf("failed to get recurring plan: %w", err)
+	}
+
+	return &plan, nil
+}
+
+func (r *recurringPlanRepository) ListByUser(ctx context.Context, userID int) ([]models.RecurringPlan, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{"created_at", -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recurring plans: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	plans := []models.RecurringPlan{}
+	if err := cursor.All(ctx, &plans); err != nil {
+		return nil, fmt.Errorf("failed to decode recurring plans: %w", err)
+	}
+
+	return plans, nil
+}
+
+// UpdateStatus pausa o reanuda el plan. No toca el lock ni los contadores, así que
+// no pisa una corrida en curso.
+func (r *recurringPlanRepository) UpdateStatus(ctx context.Context, id string, status models.PlanStatus, nextRunAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrPlanNotFound
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set": bson.M{
+			"status":      status,
+			"next_run_at": nextRunAt,
+			"updated_at":  time.Now(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update recurring plan: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrPlanNotFound
+	}
+
+	return nil
+}
+
+// Delete borra el plan; el historial de corridas se conserva
+func (r *recurringPlanRepository) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrPlanNotFound
+	}
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("failed to delete recurring plan: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrPlanNotFound
+	}
+
+	return nil
+}
+
+// ClaimDuePlan toma un plan activo cuya próxima corrida ya llegó, con un lock que
+// vence en ttl. Retorna nil si no hay ninguno libre.
+func (r *recurringPlanRepository) ClaimDuePlan(ctx context.Context, now time.Time, owner string, ttl time.Duration) (*models.RecurringPlan, error) {
+	filter := bson.M{
+		"status":      models.PlanStatusActive,
+		"next_run_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"locked_by":    owner,
+			"locked_until": now.Add(ttl),
+		},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetSort(bson.D{{"next_run_at", 1}})
+
+	var plan models.RecurringPlan
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&plan)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim recurring plan: %w", err)
+	}
+
+	return &plan, nil
+}
+
+// FinishRun guarda la próxima corrida y los contadores del plan y libera el lock,
+// solo si todavía pertenece a owner. El status no se toca: si el usuario lo pausó
+// durante la corrida queda pausado.
+func (r *recurringPlanRepository) FinishRun(ctx context.Context, plan *models.RecurringPlan, owner string) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": plan.ID, "locked_by": owner},
+		bson.M{
+			"$set": bson.M{
+				"next_run_at":     plan.NextRunAt,
+				"last_run_at":     plan.LastRunAt,
+				"last_run_status": plan.LastRunStatus,
+				"run_count":       plan.RunCount,
+				"skip_count":      plan.SkipCount,
+				"fail_count":      plan.FailCount,
+				"updated_at":      plan.UpdatedAt,
+			},
+			"$unset": bson.M{"locked_by": "", "locked_until": ""},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish recurring plan run: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("recurring plan %s is no longer locked by %s", plan.ID.Hex(), owner)
+	}
+
+	return nil
+}
+
+// AddRun guarda la corrida por su RunID, así que repetirla tras una caída no la duplica
+func (r *recurringPlanRepository) AddRun(ctx context.Context, run *models.PlanRun) error {
+	_, err := r.runs.UpdateOne(ctx,
+		bson.M{"run_id": run.RunID},
+		bson.M{"$setOnInsert": run},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save plan run %s: %w", run.RunID, err)
+	}
+	return nil
+}
+
+// ListRuns retorna las últimas corridas del plan, de la más nueva a la más vieja
+func (r *recurringPlanRepository) ListRuns(ctx context.Context, planID string, limit int) ([]models.PlanRun, error) {
+	opts := options.Find().SetSort(bson.D{{"scheduled_at", -1}}).SetLimit(int64(limit))
+
+	cursor, err := r.runs.Find(ctx, bson.M{"plan_id": planID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plan runs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	runs := []models.PlanRun{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("failed to decode plan runs: %w", err)
+	}
+
+	return runs, nil
+}
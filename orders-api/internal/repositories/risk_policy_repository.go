@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"orders-api/internal/models"
+	"orders-api/pkg/database"
+)
+
+// ErrRiskPolicyConflict indica que la política de riesgo cambió desde que se leyó
+var ErrRiskPolicyConflict = errors.New("risk policy was modified concurrently")
+
+// RiskPolicyRepository persiste la política de riesgo (un único documento)
+type RiskPolicyRepository interface {
+	Get(ctx context.Context) (*models.RiskPolicy, error)
+	Save(ctx context.Context, schedule *models.RiskPolicy) error
+}
+
+type riskPolicyRepository struct {
+	db         *database.Database
+	collection *mongo.Collection
+}
+
+func NewRiskPolicyRepository(db *database.Database) RiskPolicyRepository {
+	return &riskPolicyRepository{
+		db:         db,
+		collection: db.GetCollection("risk_policies"),
+	}
+}
+
+// Get retorna la política guardada o nil si todavía no se guardó ninguna
+func (r *riskPolicyRepository) Get(ctx context.Context) (*models.RiskPolicy, error) {
+	var schedule models.RiskPolicy
+	err := r.collection.FindOne(ctx, bson.M{"_id": models.RiskPolicyID}).Decode(&schedule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get risk policy: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+// Save guarda la política con control optimista sobre Version (0 = todavía no existe)
+// e incrementa la versión. Retorna ErrRiskPolicyConflict si otra escritura ganó.
+func (r *riskPolicyRepository) Save(ctx context.Context, schedule *models.RiskPolicy) error {
+	expected := schedule.Version
+	schedule.ID = models.RiskPolicyID
+	schedule.Version = expected + 1
+
+	if expected == 0 {
+		if _, err := r.collection.InsertOne(ctx, schedule); err != nil {
+			schedule.Version = expected
+			if mongo.IsDuplicateKeyError(err) {
+				return ErrRiskPolicyConflict
+			}
+			return fmt.Errorf("failed to save risk policy: %w", err)
+		}
+		return nil
+	}
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": models.RiskPolicyID, "version": expected}, schedule)
+	if err != nil {
+		schedule.Version = expected
+		return fmt.Errorf("failed to save risk policy: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		schedule.Version = expected
+		return ErrRiskPolicyConflict
+	}
+
+	return nil
+}
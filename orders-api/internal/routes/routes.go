@@ -12,11 +12,16 @@ import (
 )
 
 type Router struct {
-	engine         *gin.Engine
-	orderHandler   *handlers.OrderHandler
-	healthHandler  *handlers.HealthHandler
-	authMiddleware *middleware.AuthMiddleware
-	logMiddleware  *middleware.LoggingMiddleware
+	engine          *gin.Engine
+	orderHandler    *handlers.OrderHandler
+	feeHandler      *handlers.FeeHandler
+	riskHandler     *handlers.RiskHandler
+	planHandler     *handlers.RecurringPlanHandler
+	auditHandler    *handlers.AuditHandler
+	healthHandler   *handlers.HealthHandler
+	authMiddleware  *middleware.AuthMiddleware
+	logMiddleware   *middleware.LoggingMiddleware
+	auditMiddleware *middleware.AuditMiddleware
 }
 
 type RouterConfig struct {
@@ -29,9 +34,14 @@ type RouterConfig struct {
 
 func NewRouter(
 	orderHandler *handlers.OrderHandler,
+	feeHandler *handlers.FeeHandler,
+	riskHandler *handlers.RiskHandler,
+	planHandler *handlers.RecurringPlanHandler,
+	auditHandler *handlers.AuditHandler,
 	healthHandler *handlers.HealthHandler,
 	authMiddleware *middleware.AuthMiddleware,
 	logMiddleware *middleware.LoggingMiddleware,
+	auditMiddleware *middleware.AuditMiddleware,
 	config *RouterConfig,
 ) *Router {
 	if !config.Debug {
@@ -41,11 +51,16 @@ func NewRouter(
 	engine := gin.New()
 
 	return &Router{
-		engine:         engine,
-		orderHandler:   orderHandler,
-		healthHandler:  healthHandler,
-		authMiddleware: authMiddleware,
-		logMiddleware:  logMiddleware,
+		engine:          engine,
+		orderHandler:    orderHandler,
+		feeHandler:      feeHandler,
+		riskHandler:     riskHandler,
+		planHandler:     planHandler,
+		auditHandler:    auditHandler,
+		healthHandler:   healthHandler,
+		authMiddleware:  authMiddleware,
+		logMiddleware:   logMiddleware,
+		auditMiddleware: auditMiddleware,
 	}
 }
 
@@ -107,6 +122,7 @@ func (r *Router) setupGlobalMiddleware(config *RouterConfig) {
 				"Authorization",
 				"X-Requested-With",
 				"X-Request-ID",
+				"Idempotency-Key",
 			}
 		}
 
@@ -143,14 +159,33 @@ func (r *Router) setupAPIRoutes(v1 *gin.RouterGroup) {
 	orders := v1.Group("/orders")
 	{
 		orders.POST("", r.orderHandler.CreateOrder)
+		orders.POST("/quotes", r.orderHandler.CreateQuote)
+		orders.POST("/simulate", r.orderHandler.SimulateOrder)
 		orders.GET("", r.orderHandler.ListUserOrders)
+		orders.GET("/export", r.orderHandler.ExportOrders)
 		orders.GET("/:id", r.orderHandler.GetOrder)
-		// UpdateOrder, CancelOrder y ExecuteOrder comentados - no en sistema simplificado
-		// orders.PUT("/:id", r.orderHandler.UpdateOrder)
-		// orders.DELETE("/:id", r.orderHandler.CancelOrder)
+		orders.GET("/:id/fills", r.orderHandler.GetOrderFills)
+		orders.PUT("/:id", r.orderHandler.UpdateOrder)
+		orders.DELETE("/:id", r.orderHandler.CancelOrder)
+		// ExecuteOrder comentado - no en sistema simplificado
 		// orders.POST("/:id/execute", r.orderHandler.ExecuteOrder)
 	}
 
+	// Recurring buy (DCA) plans
+	plans := v1.Group("/recurring-plans")
+	{
+		plans.POST("", r.planHandler.CreatePlan)
+		plans.GET("", r.planHandler.ListPlans)
+		plans.GET("/:id", r.planHandler.GetPlan)
+		plans.GET("/:id/runs", r.planHandler.ListPlanRuns)
+		plans.POST("/:id/pause", r.planHandler.PausePlan)
+		plans.POST("/:id/resume", r.planHandler.ResumePlan)
+		plans.DELETE("/:id", r.planHandler.DeletePlan)
+	}
+
+	// Fee schedule in force (maker/taker rates, volume tiers, promotions)
+	v1.GET("/fees", r.feeHandler.GetFeeSchedule)
+
 	// User-specific order endpoints
 	users := v1.Group("/users/:user_id")
 	users.Use(r.authMiddleware.ValidateOwnership())
@@ -165,15 +200,28 @@ func (r *Router) setupAPIRoutes(v1 *gin.RouterGroup) {
 	// Admin endpoints (require admin role)
 	admin := v1.Group("/admin")
 	admin.Use(r.authMiddleware.RequireRole("admin"))
+	// Every state-changing admin request is recorded in the audit log
+	admin.Use(r.auditMiddleware.AuditAdminActions())
 	{
 		adminOrders := admin.Group("/orders")
 		{
-			adminOrders.GET("", r.orderHandler.ListUserOrders)
-			adminOrders.GET("/:id", r.orderHandler.GetOrder)
-			// UpdateOrder y CancelOrder comentados - no en sistema simplificado
-			// adminOrders.PUT("/:id", r.orderHandler.UpdateOrder)
-			// adminOrders.DELETE("/:id", r.orderHandler.CancelOrder)
+			adminOrders.GET("", r.orderHandler.AdminListOrders)
+			adminOrders.POST("/bulk-fail", r.orderHandler.AdminBulkFailOrders)
+			adminOrders.GET("/:id", r.orderHandler.AdminGetOrder)
+			adminOrders.PUT("/:id", r.orderHandler.AdminUpdateOrder)
+			adminOrders.POST("/:id/cancel", r.orderHandler.AdminForceCancelOrder)
+			adminOrders.DELETE("/:id", r.orderHandler.AdminCancelOrder)
 		}
+
+		admin.GET("/statistics", r.orderHandler.GetAdminStatistics)
+		admin.GET("/audit-log", r.auditHandler.ListAuditLog)
+
+		admin.GET("/fees", r.feeHandler.GetFeeSchedule)
+		admin.PUT("/fees", r.feeHandler.UpdateFeeSchedule)
+
+		// Pre-trade risk limits per user role
+		admin.GET("/risk-limits", r.riskHandler.GetRiskPolicy)
+		admin.PUT("/risk-limits", r.riskHandler.UpdateRiskPolicy)
 	}
 }
 
@@ -257,6 +305,7 @@ func DefaultRouterConfig() *RouterConfig {
 			"Authorization",
 			"X-Requested-With",
 			"X-Request-ID",
+			"Idempotency-Key",
 			"Accept",
 			"Accept-Encoding",
 			"Accept-Language",
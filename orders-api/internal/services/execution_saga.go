@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// SagaCoordinator ejecuta una pasada de una orden como saga: mueve el saldo en Users
+// API, después los holdings en Portfolio API y por último guarda los fills y marca la
+// orden como ejecutada (o parcialmente llenada si el libro no alcanzó).
+// Cada paso se persiste en order.Saga antes de seguir; si un paso falla se revierten
+// los anteriores. Una saga cortada por una caída queda en status executing y la
+// retoma SagaRecoverer.
+type SagaCoordinator struct {
+	orderRepo        repositories.OrderRepository
+	fillRepo         repositories.FillRepository // nil: los fills quedan solo en order.Saga
+	executionService *ExecutionService
+	publisher        EventPublisher
+	tx               Transactor
+}
+
+// NewSagaCoordinator crea el coordinador de ejecución
+func NewSagaCoordinator(
+	orderRepo repositories.OrderRepository,
+	executionService *ExecutionService,
+	publisher EventPublisher,
+) *SagaCoordinator {
+	return &SagaCoordinator{
+		orderRepo:        orderRepo,
+		executionService: executionService,
+		publisher:        publisher,
+		tx:               noTransaction{},
+	}
+}
+
+// Execute ejecuta una pasada de una orden abierta contra el libro al precio actual.
+// Retorna ErrLimitPriceNotReached (sin tocar la orden) si el precio se movió y
+// ErrFillOrKillNotFilled si es una FOK que el libro no llena entera. Una falla
+// transitoria de otro servicio tampoco toca una orden que espera en el libro.
+func (c *SagaCoordinator) Execute(ctx context.Context, order *models.Order) error {
+	result, err := c.executionService.PrepareExecution(ctx, order)
+	if err != nil {
+		// El precio se movió antes de ejecutar (o el libro no llena una FOK): la orden sigue pendiente
+		if errors.Is(err, ErrLimitPriceNotReached) || errors.Is(err, ErrFillOrKillNotFilled) {
+			return err
+		}
+
+		// Users o Market API no respondieron: la orden limit / condicional sigue
+		// abierta con su reserva y el matcher la reintenta en la próxima pasada
+		if !isRejection(err) && order.OrderKind != models.OrderKindMarket && order.RestsOnBook(time.Now()) {
+			return err
+		}
+
+		// Todavía no se aplicó nada: alcanza con liberar la reserva
+		c.failOrder(ctx, order, err.Error(), true)
+		return err
+	}
+
+	previous := *order
+	now := time.Now()
+	final := result.Quantity.GreaterThanOrEqual(order.RemainingQuantity())
+
+	// Una orden que se llena entera de una vez se identifica por su ID, como antes
+	// de los fills parciales; cada pasada de una orden partida, por su primer fill
+	reference := ""
+	if !final || order.FillCount > 0 {
+		reference = result.Fills[0].FillID
+	}
+
+	order.FilledLeg = order.FilledLegAt(result.Cost.ReferencePrice)
+	order.Status = models.OrderStatusExecuting
+	order.FeeDetail = result.FeeDetail
+	order.Saga = &models.ExecutionSaga{
+		Step:          models.SagaStepStarted,
+		Reference:     reference,
+		Quantity:      result.Quantity,
+		Final:         final,
+		Fills:         result.Fills,
+		ExecutedPrice: result.ExecutedPrice,
+		TotalAmount:   result.TotalAmount,
+		Fee:           result.Fee,
+		Cost:          result.Cost,
+		StartedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	// Si otra instancia cambió la orden (cancel, amend, otra ejecución) no se toca nada
+	if err := c.orderRepo.Update(ctx, order); err != nil {
+		*order = previous
+		return fmt.Errorf("failed to start execution: %w", err)
+	}
+
+	return c.run(ctx, order)
+}
+
+// Resume retoma una saga que quedó a mitad de camino, desde el último paso persistido
+func (c *SagaCoordinator) Resume(ctx context.Context, order *models.Order) error {
+	if order.Status != models.OrderStatusExecuting || order.Saga == nil || order.Saga.IsFinished() {
+		return fmt.Errorf("order %s has no execution in progress (status: %s)", order.ID.Hex(), order.Status)
+	}
+
+	order.Saga.Attempts++
+	upgradeLegacySaga(order)
+	log.Printf("♻️ Resuming execution of order %s from step %s (attempt %d)", order.OrderNumber, order.Saga.Step, order.Saga.Attempts)
+
+	return c.run(ctx, order)
+}
+
+// run avanza la saga paso a paso. Todos los pasos son idempotentes, así que
+// repetir uno que llegó a aplicarse antes de una caída no duplica movimientos.
+func (c *SagaCoordinator) run(ctx context.Context, order *models.Order) error {
+	saga := order.Saga
+
+	for {
+		switch saga.Step {
+		case models.SagaStepStarted:
+			delta, err := c.executionService.ApplyBalance(ctx, order, saga)
+			if err != nil {
+				return c.compensate(ctx, order, err)
+			}
+			saga.BalanceDelta = delta
+			if err := c.advance(ctx, order, models.SagaStepBalanceApplied); err != nil {
+				return err
+			}
+
+		case models.SagaStepBalanceApplied:
+			if err := c.executionService.ApplyHoldings(ctx, order, saga); err != nil {
+				return c.compensate(ctx, order, err)
+			}
+			if err := c.advance(ctx, order, models.SagaStepHoldingsApplied); err != nil {
+				return err
+			}
+
+		case models.SagaStepHoldingsApplied:
+			return c.complete(ctx, order)
+
+		case models.SagaStepCompensating:
+			return c.runCompensation(ctx, order)
+
+		default:
+			return nil
+		}
+	}
+}
+
+// advance persiste el paso alcanzado. Si falla, la orden queda en el paso anterior
+// y el recoverer lo repite.
+func (c *SagaCoordinator) advance(ctx context.Context, order *models.Order, step models.SagaStep) error {
+	previous := order.Saga.Step
+	order.Saga.Step = step
+
+	if err := c.save(ctx, order); err != nil {
+		order.Saga.Step = previous
+		return fmt.Errorf("failed to persist execution step %s: %w", step, err)
+	}
+	return nil
+}
+
+// complete guarda los fills de la pasada y marca la orden como ejecutada o, si
+// todavía falta cantidad, como parcialmente llenada (el matcher la vuelve a tomar)
+func (c *SagaCoordinator) complete(ctx context.Context, order *models.Order) error {
+	saga := order.Saga
+	previous := *order
+
+	order.ApplyFills(saga.Fills, time.Now())
+	saga.Step = models.SagaStepCompleted
+
+	publish := c.publisher.PublishOrderExecuted
+	if order.Status == models.OrderStatusPartiallyFilled {
+		publish = c.publisher.PublishOrderPartiallyFilled
+	}
+
+	err := writeWithEvent(ctx, c.tx, order, func(txCtx context.Context) error {
+		if c.fillRepo != nil {
+			if err := c.fillRepo.Add(txCtx, saga.Fills); err != nil {
+				return err
+			}
+		}
+		return c.save(txCtx, order)
+	}, func(txCtx context.Context) error {
+		return publish(txCtx, order)
+	})
+	if err != nil {
+		// Saldo y holdings ya están aplicados: el recoverer termina la orden
+		*order = previous
+		saga.Step = models.SagaStepHoldingsApplied
+		return fmt.Errorf("failed to update executed order: %w", err)
+	}
+
+	if order.Status == models.OrderStatusPartiallyFilled {
+		log.Printf("🧩 Order %s partially filled: %s of %s at avg %s", order.OrderNumber,
+			order.FilledQuantity.String(), order.Quantity.String(), order.AvgFillPrice.String())
+	}
+
+	return nil
+}
+
+// compensate registra la falla de un paso y revierte los pasos ya aplicados
+func (c *SagaCoordinator) compensate(ctx context.Context, order *models.Order, cause error) error {
+	saga := order.Saga
+	saga.FailedStep = saga.Step
+	saga.LastError = cause.Error()
+	saga.Step = models.SagaStepCompensating
+	order.ErrorMessage = cause.Error()
+
+	if err := c.save(ctx, order); err != nil {
+		// Con un conflicto de versión otra instancia retomó la saga
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			return fmt.Errorf("%v (compensation left to another instance): %w", cause, err)
+		}
+		log.Printf("Warning: failed to persist compensation of order %s: %v", order.ID.Hex(), err)
+	}
+
+	if err := c.runCompensation(ctx, order); err != nil {
+		return fmt.Errorf("%w (compensation pending: %v)", cause, err)
+	}
+	return fmt.Errorf("%w (execution rolled back)", cause)
+}
+
+// runCompensation deshace holdings y saldo de la pasada y deja la orden fallida (o,
+// con fills de pasadas anteriores, cancelado lo que faltaba llenar). Si una
+// reversión falla la saga queda en compensating para que el recoverer reintente.
+func (c *SagaCoordinator) runCompensation(ctx context.Context, order *models.Order) error {
+	saga := order.Saga
+
+	// Falló o quedó en duda el paso de holdings: revertirlo (es seguro aunque no se haya aplicado)
+	if saga.FailedStep == models.SagaStepBalanceApplied || saga.FailedStep == models.SagaStepHoldingsApplied {
+		if err := c.executionService.RevertHoldings(ctx, order); err != nil {
+			return c.compensationFailed(ctx, order, err)
+		}
+	}
+
+	if saga.HasBalanceApplied() {
+		if err := c.executionService.RevertBalance(ctx, order, saga.BalanceDelta); err != nil {
+			return c.compensationFailed(ctx, order, err)
+		}
+	}
+
+	saga.Step = models.SagaStepCompensated
+	// En compras con saldo aplicado la reserva ya se capturó y la devolución la hizo el ajuste;
+	// una captura parcial deja la reserva activa por lo que faltaba llenar
+	release := order.Type == models.OrderTypeSell || !saga.HasBalanceApplied() || !saga.Final
+	c.failOrder(ctx, order, order.ErrorMessage, release)
+
+	log.Printf("↩️ Execution of order %s rolled back after failing at %s", order.OrderNumber, saga.FailedStep)
+	return nil
+}
+
+func (c *SagaCoordinator) compensationFailed(ctx context.Context, order *models.Order, err error) error {
+	order.Saga.LastError = err.Error()
+	if saveErr := c.save(ctx, order); saveErr != nil {
+		log.Printf("Warning: failed to persist compensation error of order %s: %v", order.ID.Hex(), saveErr)
+	}
+	return err
+}
+
+// isRejection indica si el error de PrepareExecution rechaza la orden en forma
+// definitiva (usuario inválido, slippage) en vez de ser una falla transitoria
+func isRejection(err error) bool {
+	return errors.Is(err, ErrUserNotAllowed) || errors.Is(err, ErrSlippageExceeded)
+}
+
+// failOrder marca la orden como fallida, libera su reserva si corresponde y publica el
+// evento. Si pasadas anteriores ya llenaron parte de la orden, esos fills están
+// liquidados en Users y Portfolio API: la orden no queda fallida sino que se cancela lo
+// que faltaba llenar (y se libera solo esa reserva), igual que una cancelación del usuario.
+func (c *SagaCoordinator) failOrder(ctx context.Context, order *models.Order, reason string, releaseFunds bool) {
+	if order.FillCount > 0 {
+		c.cancelRemainder(ctx, order, reason, releaseFunds)
+		return
+	}
+
+	order.Status = models.OrderStatusFailed
+	order.ErrorMessage = reason
+
+	if releaseFunds {
+		if err := c.executionService.ReleaseFunds(ctx, order); err != nil {
+			log.Printf("Warning: failed to release funds for order %s: %v", order.ID.Hex(), err)
+		}
+	}
+
+	err := c.saveWithEvent(ctx, order, func(txCtx context.Context) error {
+		return c.publisher.PublishOrderFailed(txCtx, order, reason)
+	})
+	if err != nil {
+		log.Printf("Warning: failed to mark order %s as failed: %v", order.ID.Hex(), err)
+	}
+}
+
+// cancelRemainder cierra lo que falta llenar de una orden con fills: la orden queda
+// cancelada con sus fills y la reserva que se libera es la de la cantidad restante
+func (c *SagaCoordinator) cancelRemainder(ctx context.Context, order *models.Order, reason string, releaseFunds bool) {
+	order.Status = models.OrderStatusCancelled
+	order.ErrorMessage = reason
+	order.UpdatedAt = time.Now()
+
+	if releaseFunds {
+		if err := c.executionService.ReleaseFunds(ctx, order); err != nil {
+			log.Printf("Warning: failed to release remaining funds for order %s: %v", order.ID.Hex(), err)
+		}
+	}
+
+	err := c.saveWithEvent(ctx, order, func(txCtx context.Context) error {
+		return c.publisher.PublishOrderCancelled(txCtx, order, reason)
+	})
+	if err != nil {
+		log.Printf("Warning: failed to cancel the rest of order %s: %v", order.ID.Hex(), err)
+		return
+	}
+
+	log.Printf("✂️ Order %s keeps %s of %s filled, the rest was cancelled: %s", order.OrderNumber,
+		order.FilledQuantity.String(), order.Quantity.String(), reason)
+}
+
+func (c *SagaCoordinator) save(ctx context.Context, order *models.Order) error {
+	if order.Saga != nil {
+		order.Saga.UpdatedAt = time.Now()
+	}
+	return c.orderRepo.Update(ctx, order)
+}
+
+// saveWithEvent guarda la orden y su evento en la misma transacción
+func (c *SagaCoordinator) saveWithEvent(ctx context.Context, order *models.Order, publish func(ctx context.Context) error) error {
+	return writeWithEvent(ctx, c.tx, order, func(txCtx context.Context) error {
+		return c.save(txCtx, order)
+	}, publish)
+}
+
+// upgradeLegacySaga completa una saga iniciada antes de los fills parciales: esas
+// sagas llenaban toda la orden en una pasada identificada por el ID de la orden
+func upgradeLegacySaga(order *models.Order) {
+	saga := order.Saga
+	if len(saga.Fills) > 0 {
+		return
+	}
+
+	saga.Quantity = order.RemainingQuantity()
+	saga.Final = true
+	fill := newFill(order, 0, saga.ExecutedPrice, saga.Quantity, order.FeeTypeAt(saga.ExecutedPrice))
+	fill.Fee = saga.Fee
+	saga.Fills = []models.Fill{fill}
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+type sagaTestDeps struct {
+	repo      *MockOrderRepository
+	balance   *MockUserBalanceClient
+	portfolio *MockPortfolioClient
+	publisher *MockEventPublisher
+}
+
+func newSagaTestCoordinator() (*SagaCoordinator, sagaTestDeps) {
+	service, balanceClient, portfolioClient := newExecutionTestService()
+	deps := sagaTestDeps{
+		repo:      new(MockOrderRepository),
+		balance:   balanceClient,
+		portfolio: portfolioClient,
+		publisher: new(MockEventPublisher),
+	}
+	return NewSagaCoordinator(deps.repo, service, deps.publisher), deps
+}
+
+// newExecutingOrder arma una orden que quedó con la saga en step
+func newExecutingOrder(orderType models.OrderType, step models.SagaStep, delta int64) *models.Order {
+	order := newMarketOrder(orderType)
+	order.Status = models.OrderStatusExecuting
+	order.Saga = newSaga()
+	order.Saga.Step = step
+	order.Saga.BalanceDelta = decimal.NewFromInt(delta)
+	return order
+}
+
+func TestSagaCoordinator_Execute(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sell runs every step and completes", func(t *testing.T) {
+		coordinator, deps := newSagaTestCoordinator()
+		order := newMarketOrder(models.OrderTypeSell)
+		orderID := order.ID.Hex()
+
+		var steps []models.SagaStep
+		deps.repo.On("Update", ctx, order).Run(func(args mock.Arguments) {
+			steps = append(steps, args.Get(1).(*models.Order).Saga.Step)
+		}).Return(nil)
+		deps.portfolio.On("ReserveHoldings", ctx, int64(1), "BTC", orderID, order.Quantity).Return(nil)
+		deps.balance.On("ProcessTransaction", ctx, 1, decimalEq(49950), "sell", orderID, mock.Anything).Return("tx-1", nil)
+		deps.portfolio.On("UpdateHoldings", ctx, int64(1), "BTC", order.Quantity, decimal.NewFromInt(50000), "sell", orderID, orderID).Return(nil)
+		deps.publisher.On("PublishOrderExecuted", ctx, order).Return(nil)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.OrderStatusExecuted, order.Status)
+		assert.True(t, order.Price.Equal(decimal.NewFromInt(50000)))
+		assert.NotNil(t, order.ExecutedAt)
+		assert.Equal(t, []models.SagaStep{
+			models.SagaStepStarted,
+			models.SagaStepBalanceApplied,
+			models.SagaStepHoldingsApplied,
+			models.SagaStepCompleted,
+		}, steps)
+		deps.publisher.AssertExpectations(t)
+	})
+
+	t.Run("portfolio failure reverts the sell credit", func(t *testing.T) {
+		coordinator, deps := newSagaTestCoordinator()
+		order := newMarketOrder(models.OrderTypeSell)
+		orderID := order.ID.Hex()
+
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.portfolio.On("ReserveHoldings", ctx, int64(1), "BTC", orderID, order.Quantity).Return(nil)
+		deps.balance.On("ProcessTransaction", ctx, 1, decimalEq(49950), "sell", orderID, mock.Anything).Return("tx-1", nil)
+		deps.portfolio.On("UpdateHoldings", ctx, int64(1), "BTC", order.Quantity, decimal.NewFromInt(50000), "sell", orderID, orderID).
+			Return(errors.New("portfolio API returned status 500"))
+		deps.portfolio.On("RevertHoldings", ctx, int64(1), orderID).Return(nil)
+		deps.balance.On("ProcessTransaction", ctx, 1, decimalEq(-49950), "adjustment", orderID, mock.Anything).Return("tx-2", nil)
+		deps.portfolio.On("ReleaseHoldings", ctx, int64(1), "BTC", orderID).Return(nil)
+		deps.publisher.On("PublishOrderFailed", ctx, order, mock.Anything).Return(nil)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "execution rolled back")
+		assert.Equal(t, models.OrderStatusFailed, order.Status)
+		assert.Equal(t, models.SagaStepCompensated, order.Saga.Step)
+		assert.Equal(t, models.SagaStepBalanceApplied, order.Saga.FailedStep)
+		deps.balance.AssertExpectations(t)
+		deps.portfolio.AssertExpectations(t)
+	})
+
+	t.Run("portfolio failure refunds the buy without releasing the captured hold", func(t *testing.T) {
+		coordinator, deps := newSagaTestCoordinator()
+		order := newMarketOrder(models.OrderTypeBuy)
+		orderID := order.ID.Hex()
+
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.balance.On("CaptureFunds", ctx, 1, orderID, decimalEq(50050)).Return("tx-1", nil)
+		deps.portfolio.On("UpdateHoldings", ctx, int64(1), "BTC", order.Quantity, decimal.NewFromInt(50000), "buy", orderID, orderID).
+			Return(errors.New("portfolio API request failed"))
+		deps.portfolio.On("RevertHoldings", ctx, int64(1), orderID).Return(nil)
+		deps.balance.On("ProcessTransaction", ctx, 1, decimalEq(50050), "adjustment", orderID, mock.Anything).Return("tx-2", nil)
+		deps.publisher.On("PublishOrderFailed", ctx, order, mock.Anything).Return(nil)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.Error(t, err)
+		assert.Equal(t, models.OrderStatusFailed, order.Status)
+		deps.balance.AssertExpectations(t)
+		deps.balance.AssertNotCalled(t, "ReleaseFunds", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed compensation leaves the saga compensating", func(t *testing.T) {
+		coordinator, deps := newSagaTestCoordinator()
+		order := newMarketOrder(models.OrderTypeSell)
+		orderID := order.ID.Hex()
+
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.portfolio.On("ReserveHoldings", ctx, int64(1), "BTC", orderID, order.Quantity).Return(nil)
+		deps.balance.On("ProcessTransaction", ctx, 1, decimalEq(49950), "sell", orderID, mock.Anything).Return("tx-1", nil)
+		deps.portfolio.On("UpdateHoldings", ctx, int64(1), "BTC", order.Quantity, decimal.NewFromInt(50000), "sell", orderID, orderID).
+			Return(errors.New("portfolio API returned status 500"))
+		deps.portfolio.On("RevertHoldings", ctx, int64(1), orderID).Return(nil)
+		deps.balance.On("ProcessTransaction", ctx, 1, decimalEq(-49950), "adjustment", orderID, mock.Anything).
+			Return("", errors.New("users API unavailable"))
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "compensation pending")
+		assert.Equal(t, models.OrderStatusExecuting, order.Status)
+		assert.Equal(t, models.SagaStepCompensating, order.Saga.Step)
+		deps.publisher.AssertNotCalled(t, "PublishOrderFailed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("thin book leaves the limit order partially filled", func(t *testing.T) {
+		service := newBookTestService(50000, &models.OrderBook{
+			Symbol: "BTC",
+			Asks:   []models.OrderBookLevel{{Price: decimal.NewFromInt(50000), Quantity: decimal.NewFromFloat(0.5)}},
+		})
+		balanceClient, portfolioClient := new(MockUserBalanceClient), new(MockPortfolioClient)
+		service.userBalanceClient = balanceClient
+		service.SetPortfolioClient(portfolioClient)
+		repo, publisher := new(MockOrderRepository), new(MockEventPublisher)
+		coordinator := NewSagaCoordinator(repo, service, publisher)
+
+		order := newBookOrder(models.OrderTypeBuy, 50000, 2)
+		orderID := order.ID.Hex()
+		fillID := models.NewFillID(orderID, 1)
+
+		repo.On("Update", ctx, order).Return(nil)
+		balanceClient.On("CaptureFundsPartial", ctx, 1, orderID, fillID, mock.Anything).Return("tx-1", nil)
+		portfolioClient.On("UpdateHoldings", ctx, int64(1), "BTC", mock.Anything, decimal.NewFromInt(50000), "buy", fillID, orderID).Return(nil)
+		publisher.On("PublishOrderPartiallyFilled", ctx, order).Return(nil)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.OrderStatusPartiallyFilled, order.Status)
+		assert.True(t, order.FilledQuantity.Equal(decimal.NewFromFloat(0.5)))
+		assert.True(t, order.RemainingQuantity().Equal(decimal.NewFromFloat(1.5)))
+		assert.Equal(t, 1, order.FillCount)
+		assert.Nil(t, order.ExecutedAt)
+		balanceClient.AssertNotCalled(t, "CaptureFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		publisher.AssertNotCalled(t, "PublishOrderExecuted", mock.Anything, mock.Anything)
+		portfolioClient.AssertExpectations(t)
+	})
+
+	t.Run("second pass failing after a committed fill keeps the fill and cancels the rest", func(t *testing.T) {
+		service := newBookTestService(50000, &models.OrderBook{
+			Symbol: "BTC",
+			Bids:   []models.OrderBookLevel{{Price: decimal.NewFromInt(50000), Quantity: decimal.NewFromInt(5)}},
+		})
+		balanceClient, portfolioClient := new(MockUserBalanceClient), new(MockPortfolioClient)
+		service.userBalanceClient = balanceClient
+		service.SetPortfolioClient(portfolioClient)
+		repo, publisher := new(MockOrderRepository), new(MockEventPublisher)
+		coordinator := NewSagaCoordinator(repo, service, publisher)
+
+		// La primera pasada llenó 0.5 de 2 BTC y ya se liquidó en users-api y portfolio-api
+		order := newBookOrder(models.OrderTypeSell, 50000, 2)
+		order.Status = models.OrderStatusPartiallyFilled
+		order.FilledQuantity = decimal.NewFromFloat(0.5)
+		order.FilledAmount = decimal.NewFromInt(25000)
+		order.FillCount = 1
+		orderID := order.ID.Hex()
+		remaining := decimal.NewFromFloat(1.5)
+
+		repo.On("Update", ctx, order).Return(nil)
+		portfolioClient.On("ReserveHoldings", ctx, int64(1), "BTC", orderID, remaining).Return(nil)
+		balanceClient.On("ProcessTransaction", ctx, 1, mock.Anything, "sell", mock.Anything, mock.Anything).Return("tx-2", nil)
+		portfolioClient.On("UpdateHoldings", ctx, int64(1), "BTC", remaining, decimal.NewFromInt(50000), "sell", mock.Anything, orderID).
+			Return(errors.New("portfolio API returned status 500"))
+		portfolioClient.On("RevertHoldings", ctx, int64(1), mock.Anything).Return(nil)
+		balanceClient.On("ProcessTransaction", ctx, 1, mock.Anything, "adjustment", mock.Anything, mock.Anything).Return("tx-3", nil)
+		portfolioClient.On("ReleaseHoldings", ctx, int64(1), "BTC", orderID).Return(nil)
+		publisher.On("PublishOrderCancelled", ctx, order, mock.Anything).Return(nil)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "execution rolled back")
+		assert.Equal(t, models.OrderStatusCancelled, order.Status)
+		assert.Equal(t, models.SagaStepCompensated, order.Saga.Step)
+		assert.True(t, order.FilledQuantity.Equal(decimal.NewFromFloat(0.5)))
+		assert.Equal(t, 1, order.FillCount)
+		portfolioClient.AssertExpectations(t)
+		publisher.AssertExpectations(t)
+		publisher.AssertNotCalled(t, "PublishOrderFailed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrent change aborts before touching balances", func(t *testing.T) {
+		coordinator, deps := newSagaTestCoordinator()
+		order := newMarketOrder(models.OrderTypeBuy)
+
+		deps.repo.On("Update", ctx, order).Return(repositories.ErrVersionConflict)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.ErrorIs(t, err, repositories.ErrVersionConflict)
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+		assert.Nil(t, order.Saga)
+		deps.balance.AssertNotCalled(t, "CaptureFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestSagaCoordinator_Execute_PrepareFailures(t *testing.T) {
+	ctx := context.Background()
+
+	// newCoordinator arma un coordinador cuya verificación de usuario responde validation, err
+	newCoordinator := func(validation *models.ValidationResult, err error) (*SagaCoordinator, sagaTestDeps) {
+		userClient := new(MockUserClient)
+		userClient.On("VerifyUser", mock.Anything, 1).Return(validation, err)
+		deps := sagaTestDeps{
+			repo:      new(MockOrderRepository),
+			balance:   new(MockUserBalanceClient),
+			portfolio: new(MockPortfolioClient),
+			publisher: new(MockEventPublisher),
+		}
+		service := NewExecutionService(userClient, deps.balance, new(MockMarketClient), nil)
+		service.SetPortfolioClient(deps.portfolio)
+		return NewSagaCoordinator(deps.repo, service, deps.publisher), deps
+	}
+
+	t.Run("transient failure leaves a resting limit order open", func(t *testing.T) {
+		coordinator, deps := newCoordinator(nil, errors.New("request failed: context deadline exceeded"))
+		order := newBookOrder(models.OrderTypeBuy, 50000, 1)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.Error(t, err)
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+		deps.balance.AssertNotCalled(t, "ReleaseFunds", mock.Anything, mock.Anything, mock.Anything)
+		deps.repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("transient failure keeps a partially filled order", func(t *testing.T) {
+		coordinator, deps := newCoordinator(nil, errors.New("user service returned status 503"))
+		order := newBookOrder(models.OrderTypeBuy, 50000, 2)
+		order.Status = models.OrderStatusPartiallyFilled
+		order.FilledQuantity = decimal.NewFromInt(1)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.Error(t, err)
+		assert.Equal(t, models.OrderStatusPartiallyFilled, order.Status)
+		deps.balance.AssertNotCalled(t, "ReleaseFunds", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rejection after a committed fill cancels only the rest", func(t *testing.T) {
+		coordinator, deps := newCoordinator(&models.ValidationResult{IsValid: false, Message: "user account is inactive"}, nil)
+		order := newBookOrder(models.OrderTypeBuy, 50000, 2)
+		order.Status = models.OrderStatusPartiallyFilled
+		order.FilledQuantity = decimal.NewFromInt(1)
+		order.FillCount = 1
+		orderID := order.ID.Hex()
+
+		deps.balance.On("ReleaseFunds", ctx, 1, orderID).Return(nil)
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.publisher.On("PublishOrderCancelled", ctx, order, mock.Anything).Return(nil)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.ErrorIs(t, err, ErrUserNotAllowed)
+		assert.Equal(t, models.OrderStatusCancelled, order.Status)
+		assert.True(t, order.FilledQuantity.Equal(decimal.NewFromInt(1)))
+		deps.balance.AssertExpectations(t)
+		deps.publisher.AssertNotCalled(t, "PublishOrderFailed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("inactive user fails the limit order and releases its hold", func(t *testing.T) {
+		coordinator, deps := newCoordinator(&models.ValidationResult{IsValid: false, Message: "user account is inactive"}, nil)
+		order := newBookOrder(models.OrderTypeBuy, 50000, 1)
+		orderID := order.ID.Hex()
+
+		deps.balance.On("ReleaseFunds", ctx, 1, orderID).Return(nil)
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.publisher.On("PublishOrderFailed", ctx, order, mock.Anything).Return(nil)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.ErrorIs(t, err, ErrUserNotAllowed)
+		assert.Equal(t, models.OrderStatusFailed, order.Status)
+		deps.balance.AssertExpectations(t)
+	})
+
+	t.Run("transient failure still fails a market order", func(t *testing.T) {
+		coordinator, deps := newCoordinator(nil, errors.New("request failed: connection refused"))
+		order := newMarketOrder(models.OrderTypeBuy)
+		orderID := order.ID.Hex()
+
+		deps.balance.On("ReleaseFunds", ctx, 1, orderID).Return(nil)
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.publisher.On("PublishOrderFailed", ctx, order, mock.Anything).Return(nil)
+
+		err := coordinator.Execute(ctx, order)
+
+		assert.Error(t, err)
+		assert.Equal(t, models.OrderStatusFailed, order.Status)
+		deps.balance.AssertExpectations(t)
+	})
+}
+
+func TestSagaCoordinator_Resume(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("from balance_applied applies holdings and completes", func(t *testing.T) {
+		coordinator, deps := newSagaTestCoordinator()
+		order := newExecutingOrder(models.OrderTypeBuy, models.SagaStepBalanceApplied, -50050)
+		orderID := order.ID.Hex()
+
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.portfolio.On("UpdateHoldings", ctx, int64(1), "BTC", order.Quantity, decimal.NewFromInt(50000), "buy", orderID, orderID).Return(nil)
+		deps.publisher.On("PublishOrderExecuted", ctx, order).Return(nil)
+
+		err := coordinator.Resume(ctx, order)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.OrderStatusExecuted, order.Status)
+		assert.Equal(t, 1, order.Saga.Attempts)
+		deps.balance.AssertNotCalled(t, "CaptureFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("from holdings_applied only marks the order executed", func(t *testing.T) {
+		coordinator, deps := newSagaTestCoordinator()
+		order := newExecutingOrder(models.OrderTypeSell, models.SagaStepHoldingsApplied, 49950)
+
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.publisher.On("PublishOrderExecuted", ctx, order).Return(nil)
+
+		err := coordinator.Resume(ctx, order)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.SagaStepCompleted, order.Saga.Step)
+		deps.portfolio.AssertNotCalled(t, "UpdateHoldings", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("retries a pending compensation", func(t *testing.T) {
+		coordinator, deps := newSagaTestCoordinator()
+		order := newExecutingOrder(models.OrderTypeSell, models.SagaStepCompensating, 49950)
+		order.Saga.FailedStep = models.SagaStepBalanceApplied
+		order.ErrorMessage = "failed to update holdings"
+		orderID := order.ID.Hex()
+
+		deps.repo.On("Update", ctx, order).Return(nil)
+		deps.portfolio.On("RevertHoldings", ctx, int64(1), orderID).Return(nil)
+		deps.balance.On("ProcessTransaction", ctx, 1, decimalEq(-49950), "adjustment", orderID, mock.Anything).Return("tx-2", nil)
+		deps.portfolio.On("ReleaseHoldings", ctx, int64(1), "BTC", orderID).Return(nil)
+		deps.publisher.On("PublishOrderFailed", ctx, order, "failed to update holdings").Return(nil)
+
+		err := coordinator.Resume(ctx, order)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.OrderStatusFailed, order.Status)
+		assert.Equal(t, models.SagaStepCompensated, order.Saga.Step)
+		deps.balance.AssertExpectations(t)
+	})
+
+	t.Run("rejects orders without an execution in progress", func(t *testing.T) {
+		coordinator, _ := newSagaTestCoordinator()
+
+		err := coordinator.Resume(ctx, newMarketOrder(models.OrderTypeBuy))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestSagaRecoverer_RecoverOnce(t *testing.T) {
+	ctx := context.Background()
+	coordinator, deps := newSagaTestCoordinator()
+	order := newExecutingOrder(models.OrderTypeSell, models.SagaStepHoldingsApplied, 49950)
+
+	recoverer := NewSagaRecoverer(deps.repo, coordinator, SagaRecovererConfig{StaleAfter: time.Minute})
+
+	deps.repo.On("ClaimStalledExecution", ctx, mock.Anything, recoverer.instanceID, recoverer.config.LockTTL).Return(order, nil).Once()
+	deps.repo.On("ClaimStalledExecution", ctx, mock.Anything, recoverer.instanceID, recoverer.config.LockTTL).Return(nil, nil).Once()
+	deps.repo.On("Update", ctx, order).Return(nil)
+	deps.publisher.On("PublishOrderExecuted", ctx, order).Return(nil)
+
+	finished, err := recoverer.RecoverOnce(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, finished)
+	assert.Equal(t, models.OrderStatusExecuted, order.Status)
+	deps.repo.AssertExpectations(t)
+}
@@ -2,19 +2,40 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/shopspring/decimal"
 	"orders-api/internal/models"
 )
 
+// ErrLimitPriceNotReached indica que el precio de mercado ya no cruza el precio límite
+var ErrLimitPriceNotReached = errors.New("limit price not reached")
+
+// ErrTriggerPriceNotReached indica que una orden stop / take profit todavía no se disparó
+var ErrTriggerPriceNotReached = errors.New("trigger price not reached")
+
+// ErrSlippageExceeded indica que el precio de mercado se alejó del cotizado más de lo aceptado
+var ErrSlippageExceeded = errors.New("max slippage exceeded")
+
+// ErrFillOrKillNotFilled indica que el libro no alcanza para llenar entera una orden FOK
+var ErrFillOrKillNotFilled = errors.New("fill or kill order cannot be filled entirely")
+
+// ErrUserNotAllowed indica que el usuario no existe o su cuenta no puede operar
+var ErrUserNotAllowed = errors.New("user not allowed to trade")
+
+// orderBookDepth niveles del libro que se piden para llenar una orden
+const orderBookDepth = 20
+
 // ExecutionService servicio simplificado para ejecutar órdenes
 type ExecutionService struct {
 	userClient        UserClient
 	userBalanceClient UserBalanceClient
 	marketClient      MarketClient
 	portfolioClient   PortfolioClient
+	fees              FeeCalculator
 }
 
 // UserClient interface para validar usuarios
@@ -26,30 +47,48 @@ type UserClient interface {
 type UserBalanceClient interface {
 	CheckBalance(ctx context.Context, userID int, amount decimal.Decimal, userToken string) (*models.BalanceResult, error)
 	ProcessTransaction(ctx context.Context, userID int, amount decimal.Decimal, transactionType, orderID, description string) (string, error)
+	LockFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) error
+	AdjustFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) error
+	CaptureFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) (string, error)
+	CaptureFundsPartial(ctx context.Context, userID int, orderID, fillID string, amount decimal.Decimal) (string, error)
+	ReleaseFunds(ctx context.Context, userID int, orderID string) error
 }
 
-// MarketClient interface para obtener precios
+// MarketClient interface para obtener precios y profundidad del libro
 type MarketClient interface {
 	GetCurrentPrice(ctx context.Context, symbol string) (*models.PriceResult, error)
+	GetOrderBook(ctx context.Context, symbol string, depth int) (*models.OrderBook, error)
 }
 
-// PortfolioClient interface para actualizar holdings
+// PortfolioClient interface para reservar y actualizar holdings
 type PortfolioClient interface {
-	UpdateHoldings(ctx context.Context, userID int64, symbol string, quantity, price decimal.Decimal, orderType string) error
+	ReserveHoldings(ctx context.Context, userID int64, symbol, orderID string, quantity decimal.Decimal) error
+	ReleaseHoldings(ctx context.Context, userID int64, symbol, orderID string) error
+	UpdateHoldings(ctx context.Context, userID int64, symbol string, quantity, price decimal.Decimal, orderType, tradeID, reservationID string) error
+	RevertHoldings(ctx context.Context, userID int64, orderID string) error
+	GetHolding(ctx context.Context, userID int64, symbol string) (*models.HoldingResult, error)
 }
 
+// ErrPortfolioUnavailable indica que no hay cliente de Portfolio API para validar holdings
+var ErrPortfolioUnavailable = errors.New("portfolio service not configured")
+
 // NewExecutionService crea una nueva instancia del servicio de ejecución
 func NewExecutionService(
 	userClient UserClient,
 	userBalanceClient UserBalanceClient,
 	marketClient MarketClient,
-	feeCalculator interface{}, // No se usa, pero lo dejamos para compatibilidad con main.go
+	feeCalculator FeeCalculator, // nil: tabla por defecto (0.1% con mínimo de 0.01)
 ) *ExecutionService {
+	if feeCalculator == nil {
+		feeCalculator = staticFeeCalculator{schedule: models.DefaultFeeSchedule()}
+	}
+
 	return &ExecutionService{
 		userClient:        userClient,
 		userBalanceClient: userBalanceClient,
 		marketClient:      marketClient,
 		portfolioClient:   nil, // Will be set later
+		fees:              feeCalculator,
 	}
 }
 
@@ -58,15 +97,19 @@ func (s *ExecutionService) SetPortfolioClient(pc PortfolioClient) {
 	s.portfolioClient = pc
 }
 
-// ExecuteOrder ejecuta una orden de manera síncrona y simplificada
-func (s *ExecutionService) ExecuteOrder(ctx context.Context, order *models.Order) (*models.ExecutionResult, error) {
+// PrepareExecution verifica usuario y precio y arma los fills de la próxima pasada con
+// su monto y comisión. No modifica nada: los movimientos los aplican los pasos de la saga.
+func (s *ExecutionService) PrepareExecution(ctx context.Context, order *models.Order) (*models.ExecutionResult, error) {
 	start := time.Now()
 
 	// 1. Verificar usuario
-	_, err := s.userClient.VerifyUser(ctx, order.UserID)
+	validation, err := s.userClient.VerifyUser(ctx, order.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("user validation failed: %w", err)
 	}
+	if !validation.IsValid {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotAllowed, validation.Message)
+	}
 
 	// 2. Obtener precio de mercado
 	priceResult, err := s.marketClient.GetCurrentPrice(ctx, order.CryptoSymbol)
@@ -74,79 +117,346 @@ func (s *ExecutionService) ExecuteOrder(ctx context.Context, order *models.Order
 		return nil, fmt.Errorf("failed to get market price: %w", err)
 	}
 
-	// 3. Calcular monto total
-	totalAmount := order.Quantity.Mul(priceResult.MarketPrice)
+	// Las órdenes limit y condicionales solo se ejecutan si el precio sigue cruzado
+	if !order.IsExecutableAt(priceResult.MarketPrice) {
+		return nil, ErrLimitPriceNotReached
+	}
 
-	// 4. Calcular comisión
-	fee := totalAmount.Mul(decimal.NewFromFloat(0.001)) // 0.1%
-	minFee := decimal.NewFromFloat(0.01)
-	if fee.LessThan(minFee) {
-		fee = minFee
+	// 3. Repartir la cantidad pendiente entre los niveles del libro
+	plan := s.planFills(ctx, order, priceResult.MarketPrice)
+	fills := plan.fills
+	if len(fills) == 0 {
+		// El precio cruzó pero el libro no tiene niveles dentro del límite
+		return nil, ErrLimitPriceNotReached
 	}
 
-	// Get user token from context
-	userToken := ""
-	if token := ctx.Value("user_token"); token != nil {
-		userToken = token.(string)
+	// 4. Calcular monto total y precio promedio de la pasada
+	quantity, totalAmount := decimal.Zero, decimal.Zero
+	for _, fill := range fills {
+		quantity = quantity.Add(fill.Quantity)
+		totalAmount = totalAmount.Add(fill.Amount)
 	}
 
-	// 5. Verificar balance y procesar transacción según el tipo
-	if order.Type == models.OrderTypeBuy {
-		// Para COMPRAS: verificar si tiene suficiente dinero
-		requiredAmount := totalAmount.Add(fee)
-		balanceResult, err := s.userBalanceClient.CheckBalance(ctx, order.UserID, requiredAmount, userToken)
-		if err != nil {
-			return nil, fmt.Errorf("balance check failed: %w", err)
+	// Una FOK se llena entera con lo que hay en el libro o no se ejecuta
+	if order.TimeInForce == models.TimeInForceFOK && (quantity.LessThan(order.RemainingQuantity()) || plan.exhausted) {
+		return nil, fmt.Errorf("%w: book covers %s of %s", ErrFillOrKillNotFilled,
+			quantity.String(), order.RemainingQuantity().String())
+	}
+
+	executedPrice := fills[0].Price
+	if len(fills) > 1 {
+		executedPrice = totalAmount.Div(quantity).Round(8)
+	}
+
+	// Las órdenes market no se ejecutan si el precio promedio se alejó demasiado del cotizado
+	if order.ExceedsSlippage(executedPrice) {
+		return nil, fmt.Errorf("%w: quoted %s, average fill %s (max %s)", ErrSlippageExceeded,
+			order.Price.String(), executedPrice.String(), order.MaxSlippage.String())
+	}
+
+	// 5. Calcular comisión con la tabla vigente sobre toda la pasada
+	feeType := order.FeeTypeAt(priceResult.MarketPrice)
+	fee, err := s.fees.CalculateFee(ctx, order, feeType, totalAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate fee: %w", err)
+	}
+	splitFee(fills, fee.TotalFee, totalAmount)
+
+	return &models.ExecutionResult{
+		Success:       true,
+		OrderID:       order.ID.Hex(),
+		ExecutedPrice: executedPrice,
+		Quantity:      quantity,
+		TotalAmount:   totalAmount,
+		Fee:           fee.TotalFee,
+		FeeDetail:     fee,
+		Fills:         fills,
+		Cost:          newExecutionCost(order.Type, priceResult.MarketPrice, executedPrice, quantity, totalAmount, fee.TotalFee, plan),
+		ExecutionTime: time.Since(start),
+	}, nil
+}
+
+// fillPlan fills de una pasada y de dónde salió su precio
+type fillPlan struct {
+	fills     []models.Fill
+	source    string // models.PriceSourceOrderBook o models.PriceSourceLastPrice
+	levels    int    // Niveles del libro tomados
+	exhausted bool   // El libro no alcanzó para una orden market
+}
+
+// planFills reparte la cantidad pendiente de la orden entre los niveles del libro.
+// Las órdenes limit solo toman niveles a su precio límite o mejor y lo que no
+// alcanza queda para otra pasada. Las market toman niveles hasta completar la
+// cantidad y, si la profundidad pedida no alcanza, el resto se llena al peor nivel.
+// Si no hay libro (market-data-api no siempre lo expone) se llena al precio de mercado.
+func (s *ExecutionService) planFills(ctx context.Context, order *models.Order, marketPrice decimal.Decimal) fillPlan {
+	remaining := order.RemainingQuantity()
+	feeType := order.FeeTypeAt(marketPrice)
+	limitBound := order.IsLimitBoundAt(marketPrice)
+	single := fillPlan{
+		fills:  []models.Fill{newFill(order, 0, marketPrice, remaining, feeType)},
+		source: models.PriceSourceLastPrice,
+	}
+
+	book, err := s.marketClient.GetOrderBook(ctx, order.CryptoSymbol, orderBookDepth)
+	if err != nil {
+		log.Printf("Warning: order book unavailable for %s, filling at market price: %v", order.CryptoSymbol, err)
+		return single
+	}
+
+	// Una compra toma asks y una venta toma bids, del mejor nivel hacia afuera
+	levels := book.Asks
+	if order.Type == models.OrderTypeSell {
+		levels = book.Bids
+	}
+	if len(levels) == 0 {
+		return single
+	}
+
+	plan := fillPlan{source: models.PriceSourceOrderBook}
+	var worst decimal.Decimal
+	for _, level := range levels {
+		if !remaining.IsPositive() || (limitBound && !order.IsLimitPriceReached(level.Price)) {
+			break
+		}
+		quantity := decimal.Min(level.Quantity, remaining)
+		if !quantity.IsPositive() {
+			continue
 		}
+		plan.fills = append(plan.fills, newFill(order, len(plan.fills), level.Price, quantity, feeType))
+		plan.levels++
+		remaining = remaining.Sub(quantity)
+		worst = level.Price
+	}
 
-		if !balanceResult.HasSufficient {
-			return nil, fmt.Errorf("insufficient balance: required %s, available %s",
-				requiredAmount.String(), balanceResult.Available.String())
+	// Una orden market no queda abierta: lo que excede la profundidad pedida se llena al peor nivel
+	if !limitBound && remaining.IsPositive() {
+		if len(plan.fills) == 0 {
+			return single
 		}
+		plan.fills = append(plan.fills, newFill(order, len(plan.fills), worst, remaining, feeType))
+		plan.exhausted = true
+	}
 
-		// Deduct del balance (comprar)
-		_, err = s.userBalanceClient.ProcessTransaction(ctx, order.UserID, requiredAmount, "buy", order.ID.Hex(), fmt.Sprintf("Buy %s %s at %s", order.Quantity.String(), order.CryptoSymbol, priceResult.MarketPrice.String()))
-		if err != nil {
-			return nil, fmt.Errorf("failed to process transaction: %w", err)
+	return plan
+}
+
+// newExecutionCost arma el desglose de costo de una pasada contra el precio de referencia
+func newExecutionCost(orderType models.OrderType, reference, average, quantity, notional, fee decimal.Decimal, plan fillPlan) *models.ExecutionCost {
+	atReference := reference.Mul(quantity)
+	slippageCost := notional.Sub(atReference)
+	total := notional.Add(fee)
+	if orderType == models.OrderTypeSell {
+		slippageCost = slippageCost.Neg()
+		total = notional.Sub(fee)
+	}
+
+	slippage := decimal.Zero
+	if atReference.IsPositive() {
+		slippage = slippageCost.Div(atReference).Round(8)
+	}
+
+	return &models.ExecutionCost{
+		ReferencePrice: reference,
+		AveragePrice:   average,
+		Slippage:       slippage,
+		SlippageCost:   slippageCost,
+		Notional:       notional,
+		Fee:            fee,
+		Total:          total,
+		LevelsConsumed: plan.levels,
+		BookExhausted:  plan.exhausted,
+		Source:         plan.source,
+	}
+}
+
+// newFill arma el fill número index de la pasada (la numeración sigue la de la orden)
+func newFill(order *models.Order, index int, price, quantity decimal.Decimal, liquidity models.FeeType) models.Fill {
+	sequence := order.FillCount + index + 1
+	return models.Fill{
+		FillID:     models.NewFillID(order.ID.Hex(), sequence),
+		OrderID:    order.ID.Hex(),
+		UserID:     order.UserID,
+		Sequence:   sequence,
+		Price:      price,
+		Quantity:   quantity,
+		Amount:     price.Mul(quantity),
+		Liquidity:  liquidity,
+		ExecutedAt: time.Now(),
+	}
+}
+
+// splitFee reparte la comisión de la pasada entre sus fills en proporción al monto;
+// el último se lleva el redondeo para que la suma dé exacta
+func splitFee(fills []models.Fill, fee, totalAmount decimal.Decimal) {
+	assigned := decimal.Zero
+	for i := range fills {
+		if i == len(fills)-1 || totalAmount.IsZero() {
+			fills[i].Fee = fee.Sub(assigned)
+			continue
 		}
+		fills[i].Fee = fee.Mul(fills[i].Amount).Div(totalAmount).Round(8)
+		assigned = assigned.Add(fills[i].Fee)
+	}
+}
+
+// EstimateFee calcula la comisión de la orden sobre su TotalAmount al precio de la orden
+// (la que se reserva al crearla o modificarla) y la guarda en Fee y FeeDetail
+func (s *ExecutionService) EstimateFee(ctx context.Context, order *models.Order) error {
+	fee, err := s.fees.CalculateFee(ctx, order, order.FeeTypeAt(order.Price), order.TotalAmount)
+	if err != nil {
+		return fmt.Errorf("failed to calculate fee: %w", err)
+	}
 
-		// Actualizar holdings en el portfolio
-		if s.portfolioClient != nil {
-			err = s.portfolioClient.UpdateHoldings(ctx, int64(order.UserID), order.CryptoSymbol, order.Quantity, priceResult.MarketPrice, "buy")
-			if err != nil {
-				// Log error but don't fail the order execution
-				fmt.Printf("⚠️ Failed to update portfolio holdings: %v\n", err)
-			}
+	order.Fee = fee.TotalFee
+	order.FeeDetail = fee
+	return nil
+}
+
+// ApplyBalance mueve el saldo de la pasada en Users API y retorna el delta aplicado
+// (negativo en compras). Es idempotente: la captura de la reserva y la transacción de
+// venta se identifican por la pasada, así que se puede reintentar al retomar una saga.
+func (s *ExecutionService) ApplyBalance(ctx context.Context, order *models.Order, saga *models.ExecutionSaga) (decimal.Decimal, error) {
+	if order.Type == models.OrderTypeBuy {
+		// Para COMPRAS: convertir la reserva hecha al crear la orden en un débito.
+		// La última pasada captura y libera lo que sobre; las anteriores solo descuentan su parte.
+		requiredAmount := saga.TotalAmount.Add(saga.Fee)
+		var err error
+		if saga.Final {
+			_, err = s.userBalanceClient.CaptureFunds(ctx, order.UserID, order.ID.Hex(), requiredAmount)
+		} else {
+			_, err = s.userBalanceClient.CaptureFundsPartial(ctx, order.UserID, order.ID.Hex(), order.ExecutionRef(), requiredAmount)
 		}
-	} else if order.Type == models.OrderTypeSell {
-		// Para VENTAS: agregar dinero al balance (después de descontar fee)
-		netAmount := totalAmount.Sub(fee)
-		
-		// Para ventas, pasamos el monto positivo y ProcessTransaction lo suma al balance
-		_, err = s.userBalanceClient.ProcessTransaction(ctx, order.UserID, netAmount, "sell", order.ID.Hex(), fmt.Sprintf("Sell %s %s at %s", order.Quantity.String(), order.CryptoSymbol, priceResult.MarketPrice.String()))
 		if err != nil {
-			return nil, fmt.Errorf("failed to process transaction: %w", err)
+			return decimal.Zero, fmt.Errorf("failed to process transaction: %w", err)
 		}
+		return requiredAmount.Neg(), nil
+	}
+
+	// Para VENTAS: verificar y reservar la tenencia antes de acreditar dinero.
+	// La reserva es por orden, así que repetirla sobre la tomada al crear la orden no duplica nada.
+	// Con fills anteriores la reserva ya se achicó a lo que falta llenar.
+	if err := s.reserveHoldings(ctx, order); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to reserve holdings: %w", err)
+	}
+
+	// Agregar dinero al balance (después de descontar fee)
+	netAmount := saga.TotalAmount.Sub(saga.Fee)
+	description := fmt.Sprintf("Sell %s %s at %s", saga.Quantity.String(), order.CryptoSymbol, saga.ExecutedPrice.String())
+	if _, err := s.userBalanceClient.ProcessTransaction(ctx, order.UserID, netAmount, "sell", order.ExecutionRef(), description); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to process transaction: %w", err)
+	}
+	return netAmount, nil
+}
+
+// ApplyHoldings aplica la pasada en Portfolio API (en ventas consume la reserva de la orden).
+// Portfolio API ignora una pasada ya aplicada, así que se puede reintentar.
+func (s *ExecutionService) ApplyHoldings(ctx context.Context, order *models.Order, saga *models.ExecutionSaga) error {
+	if err := s.updateHoldings(ctx, order, saga); err != nil {
+		return fmt.Errorf("failed to update holdings: %w", err)
+	}
+	return nil
+}
+
+// RevertHoldings deshace en Portfolio API los holdings de la pasada. Si nunca se
+// aplicaron, Portfolio API marca la pasada para ignorar una actualización tardía.
+func (s *ExecutionService) RevertHoldings(ctx context.Context, order *models.Order) error {
+	if s.portfolioClient == nil {
+		return nil
+	}
+	return s.portfolioClient.RevertHoldings(ctx, int64(order.UserID), order.ExecutionRef())
+}
+
+// RevertBalance registra en Users API un ajuste que deshace delta (devolución en
+// compras, reversión del crédito en ventas). La idempotency key es por pasada.
+func (s *ExecutionService) RevertBalance(ctx context.Context, order *models.Order, delta decimal.Decimal) error {
+	quantity := order.Quantity
+	if order.Saga != nil {
+		quantity = order.Saga.Quantity
+	}
+	description := fmt.Sprintf("Reversal of %s %s %s: execution rolled back", order.Type, quantity.String(), order.CryptoSymbol)
+
+	if _, err := s.userBalanceClient.ProcessTransaction(ctx, order.UserID, delta.Neg(), "adjustment", order.ExecutionRef(), description); err != nil {
+		return fmt.Errorf("failed to revert balance: %w", err)
+	}
+	return nil
+}
+
+// CheckBalance consulta sin reservar nada si el usuario tiene amount disponible en Users API
+func (s *ExecutionService) CheckBalance(ctx context.Context, order *models.Order, amount decimal.Decimal) (*models.BalanceResult, error) {
+	userToken, _ := ctx.Value("user_token").(string)
+
+	balance, err := s.userBalanceClient.CheckBalance(ctx, order.UserID, amount, userToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check balance: %w", err)
+	}
+	return balance, nil
+}
+
+// CheckHoldings consulta sin reservar nada cuánto del activo de la orden puede vender el usuario
+func (s *ExecutionService) CheckHoldings(ctx context.Context, order *models.Order) (*models.HoldingResult, error) {
+	if s.portfolioClient == nil {
+		return nil, ErrPortfolioUnavailable
+	}
+
+	holding, err := s.portfolioClient.GetHolding(ctx, int64(order.UserID), order.CryptoSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check holdings: %w", err)
+	}
+	return holding, nil
+}
+
+// ReserveFunds reserva lo que la orden compromete: el total (monto + comisión, con el
+// slippage máximo en las market) en Users API para compras, la cantidad a vender en
+// Portfolio API para ventas
+func (s *ExecutionService) ReserveFunds(ctx context.Context, order *models.Order) error {
+	if order.Type == models.OrderTypeSell {
+		return s.reserveHoldings(ctx, order)
+	}
+
+	return s.userBalanceClient.LockFunds(ctx, order.UserID, order.ID.Hex(), order.ReservationAmount())
+}
+
+// AdjustReservedFunds ajusta la reserva de una orden modificada a su nuevo total o cantidad
+func (s *ExecutionService) AdjustReservedFunds(ctx context.Context, order *models.Order) error {
+	if order.Type == models.OrderTypeSell {
+		return s.reserveHoldings(ctx, order)
+	}
 
-		// Actualizar holdings en el portfolio
-		if s.portfolioClient != nil {
-			err = s.portfolioClient.UpdateHoldings(ctx, int64(order.UserID), order.CryptoSymbol, order.Quantity, priceResult.MarketPrice, "sell")
-			if err != nil {
-				// Log error but don't fail the order execution
-				fmt.Printf("⚠️ Failed to update portfolio holdings: %v\n", err)
-			}
+	return s.userBalanceClient.AdjustFunds(ctx, order.UserID, order.ID.Hex(), order.ReservationAmount())
+}
+
+// ReleaseFunds libera la reserva de una orden que no se va a ejecutar
+func (s *ExecutionService) ReleaseFunds(ctx context.Context, order *models.Order) error {
+	if order.Type == models.OrderTypeSell {
+		if s.portfolioClient == nil {
+			return nil
 		}
+		return s.portfolioClient.ReleaseHoldings(ctx, int64(order.UserID), order.CryptoSymbol, order.ID.Hex())
 	}
 
-	// 6. Crear resultado exitoso
-	result := &models.ExecutionResult{
-		Success:       true,
-		OrderID:       order.ID.Hex(),
-		ExecutedPrice: priceResult.MarketPrice,
-		TotalAmount:   totalAmount,
-		Fee:           fee,
-		ExecutionTime: time.Since(start),
+	return s.userBalanceClient.ReleaseFunds(ctx, order.UserID, order.ID.Hex())
+}
+
+// reserveHoldings fija la reserva de holdings de una venta en la cantidad que falta llenar.
+// Falla si el usuario no tiene disponible esa cantidad del activo.
+func (s *ExecutionService) reserveHoldings(ctx context.Context, order *models.Order) error {
+	if s.portfolioClient == nil {
+		return ErrPortfolioUnavailable
+	}
+
+	return s.portfolioClient.ReserveHoldings(ctx, int64(order.UserID), order.CryptoSymbol, order.ID.Hex(), order.RemainingQuantity())
+}
+
+// updateHoldings aplica la pasada en Portfolio API (en ventas consume de la reserva de la orden)
+func (s *ExecutionService) updateHoldings(ctx context.Context, order *models.Order, saga *models.ExecutionSaga) error {
+	if s.portfolioClient == nil {
+		if order.Type == models.OrderTypeSell {
+			return ErrPortfolioUnavailable
+		}
+		return nil
 	}
 
-	return result, nil
+	return s.portfolioClient.UpdateHoldings(ctx, int64(order.UserID), order.CryptoSymbol, saga.Quantity, saga.ExecutedPrice,
+		string(order.Type), order.ExecutionRef(), order.ID.Hex())
 }
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"orders-api/internal/models"
+)
+
+func newExecutionTestService() (*ExecutionService, *MockUserBalanceClient, *MockPortfolioClient) {
+	userClient := new(MockUserClient)
+	userClient.On("VerifyUser", mock.Anything, 1).Return(&models.ValidationResult{IsValid: true}, nil)
+
+	marketClient := new(MockMarketClient)
+	marketClient.On("GetCurrentPrice", mock.Anything, "BTC").Return(&models.PriceResult{Symbol: "BTC", MarketPrice: decimal.NewFromInt(50000)}, nil)
+	marketClient.On("GetOrderBook", mock.Anything, "BTC", orderBookDepth).Return(nil, errors.New("order book not available"))
+
+	balanceClient := new(MockUserBalanceClient)
+	portfolioClient := new(MockPortfolioClient)
+
+	service := NewExecutionService(userClient, balanceClient, marketClient, nil)
+	service.SetPortfolioClient(portfolioClient)
+
+	return service, balanceClient, portfolioClient
+}
+
+// decimalEq compara decimales por valor (Equal) y no por representación
+func decimalEq(v int64) interface{} {
+	return mock.MatchedBy(func(d decimal.Decimal) bool { return d.Equal(decimal.NewFromInt(v)) })
+}
+
+func newMarketOrder(orderType models.OrderType) *models.Order {
+	return &models.Order{
+		ID:           primitive.NewObjectID(),
+		UserID:       1,
+		Type:         orderType,
+		Status:       models.OrderStatusPending,
+		OrderKind:    models.OrderKindMarket,
+		CryptoSymbol: "BTC",
+		Quantity:     decimal.NewFromInt(1),
+	}
+}
+
+// newSaga arma el estado de una saga recién iniciada que llena 1 BTC a 50000 (total 50000, fee 50)
+func newSaga() *models.ExecutionSaga {
+	return &models.ExecutionSaga{
+		Step:          models.SagaStepStarted,
+		Quantity:      decimal.NewFromInt(1),
+		Final:         true,
+		ExecutedPrice: decimal.NewFromInt(50000),
+		TotalAmount:   decimal.NewFromInt(50000),
+		Fee:           decimal.NewFromInt(50),
+	}
+}
+
+func TestExecutionService_PrepareExecution(t *testing.T) {
+	ctx := context.Background()
+	service, balanceClient, _ := newExecutionTestService()
+
+	result, err := service.PrepareExecution(ctx, newMarketOrder(models.OrderTypeBuy))
+
+	assert.NoError(t, err)
+	assert.True(t, result.ExecutedPrice.Equal(decimal.NewFromInt(50000)))
+	assert.True(t, result.TotalAmount.Equal(decimal.NewFromInt(50000)))
+	assert.True(t, result.Fee.Equal(decimal.NewFromInt(50)))
+	balanceClient.AssertNotCalled(t, "CaptureFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExecutionService_PrepareExecution_SlippageExceeded(t *testing.T) {
+	ctx := context.Background()
+	service, _, _ := newExecutionTestService()
+
+	order := newMarketOrder(models.OrderTypeBuy)
+	order.Price = decimal.NewFromInt(48000)
+	slippage := decimal.NewFromFloat(0.02)
+	order.MaxSlippage = &slippage
+
+	_, err := service.PrepareExecution(ctx, order)
+
+	assert.ErrorIs(t, err, ErrSlippageExceeded)
+}
+
+// newBookTestService arma un ExecutionService con el libro de BTC dado (nil: libro no disponible)
+func newBookTestService(marketPrice int64, book *models.OrderBook) *ExecutionService {
+	userClient := new(MockUserClient)
+	userClient.On("VerifyUser", mock.Anything, 1).Return(&models.ValidationResult{IsValid: true}, nil)
+
+	marketClient := new(MockMarketClient)
+	marketClient.On("GetCurrentPrice", mock.Anything, "BTC").Return(&models.PriceResult{Symbol: "BTC", MarketPrice: decimal.NewFromInt(marketPrice)}, nil)
+	if book != nil {
+		marketClient.On("GetOrderBook", mock.Anything, "BTC", orderBookDepth).Return(book, nil)
+	} else {
+		marketClient.On("GetOrderBook", mock.Anything, "BTC", orderBookDepth).Return(nil, errors.New("order book not available"))
+	}
+
+	return NewExecutionService(userClient, new(MockUserBalanceClient), marketClient, nil)
+}
+
+// newBookOrder arma una orden limit de BTC por quantity
+func newBookOrder(orderType models.OrderType, limit int64, quantity float64) *models.Order {
+	order := newLimitOrder(orderType, "BTC", limit)
+	order.Quantity = decimal.NewFromFloat(quantity)
+	return &order
+}
+
+func TestExecutionService_PrepareExecution_OrderBook(t *testing.T) {
+	ctx := context.Background()
+	level := func(price int64, qty float64) models.OrderBookLevel {
+		return models.OrderBookLevel{Price: decimal.NewFromInt(price), Quantity: decimal.NewFromFloat(qty)}
+	}
+
+	t.Run("limit buy takes asks up to its limit", func(t *testing.T) {
+		service := newBookTestService(50000, &models.OrderBook{
+			Symbol: "BTC",
+			Asks:   []models.OrderBookLevel{level(50000, 0.5), level(50100, 1), level(50200, 5)},
+		})
+		order := newBookOrder(models.OrderTypeBuy, 50100, 2)
+
+		result, err := service.PrepareExecution(ctx, order)
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Fills, 2)
+		assert.True(t, result.Quantity.Equal(decimal.NewFromFloat(1.5)))
+		assert.True(t, result.TotalAmount.Equal(decimal.NewFromInt(75100)))
+		assert.Equal(t, models.NewFillID(order.ID.Hex(), 1), result.Fills[0].FillID)
+		assert.Equal(t, 2, result.Fills[1].Sequence)
+		assert.True(t, result.Fills[0].Fee.Add(result.Fills[1].Fee).Equal(result.Fee))
+	})
+
+	t.Run("partially filled order continues the numbering", func(t *testing.T) {
+		service := newBookTestService(50000, &models.OrderBook{
+			Symbol: "BTC",
+			Bids:   []models.OrderBookLevel{level(50000, 3)},
+		})
+		order := newBookOrder(models.OrderTypeSell, 49000, 2)
+		order.FilledQuantity = decimal.NewFromFloat(1.5)
+		order.FillCount = 2
+
+		result, err := service.PrepareExecution(ctx, order)
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Fills, 1)
+		assert.True(t, result.Quantity.Equal(decimal.NewFromFloat(0.5)))
+		assert.Equal(t, 3, result.Fills[0].Sequence)
+	})
+
+	t.Run("no level within the limit", func(t *testing.T) {
+		service := newBookTestService(50000, &models.OrderBook{
+			Symbol: "BTC",
+			Asks:   []models.OrderBookLevel{level(50500, 1)},
+		})
+
+		_, err := service.PrepareExecution(ctx, newBookOrder(models.OrderTypeBuy, 50100, 1))
+
+		assert.ErrorIs(t, err, ErrLimitPriceNotReached)
+	})
+
+	t.Run("fok the book cannot fill entirely", func(t *testing.T) {
+		service := newBookTestService(50000, &models.OrderBook{
+			Symbol: "BTC",
+			Asks:   []models.OrderBookLevel{level(50000, 0.5), level(50100, 1)},
+		})
+		order := newBookOrder(models.OrderTypeBuy, 50100, 2)
+		order.TimeInForce = models.TimeInForceFOK
+
+		_, err := service.PrepareExecution(ctx, order)
+
+		assert.ErrorIs(t, err, ErrFillOrKillNotFilled)
+	})
+
+	t.Run("fok fills when the book covers it", func(t *testing.T) {
+		service := newBookTestService(50000, &models.OrderBook{
+			Symbol: "BTC",
+			Asks:   []models.OrderBookLevel{level(50000, 0.5), level(50100, 1)},
+		})
+		order := newBookOrder(models.OrderTypeBuy, 50100, 1.5)
+		order.TimeInForce = models.TimeInForceFOK
+
+		result, err := service.PrepareExecution(ctx, order)
+
+		assert.NoError(t, err)
+		assert.True(t, result.Quantity.Equal(decimal.NewFromFloat(1.5)))
+	})
+
+	t.Run("without order book fills at market price", func(t *testing.T) {
+		service := newBookTestService(50000, nil)
+
+		result, err := service.PrepareExecution(ctx, newBookOrder(models.OrderTypeBuy, 50100, 2))
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Fills, 1)
+		assert.True(t, result.Quantity.Equal(decimal.NewFromInt(2)))
+		assert.True(t, result.ExecutedPrice.Equal(decimal.NewFromInt(50000)))
+	})
+}
+
+func TestExecutionService_PrepareExecution_MarketSlippage(t *testing.T) {
+	ctx := context.Background()
+	level := func(price int64, qty float64) models.OrderBookLevel {
+		return models.OrderBookLevel{Price: decimal.NewFromInt(price), Quantity: decimal.NewFromFloat(qty)}
+	}
+	book := &models.OrderBook{
+		Symbol: "BTC",
+		Bids:   []models.OrderBookLevel{level(49900, 2)},
+		Asks:   []models.OrderBookLevel{level(50000, 1), level(50100, 1), level(50300, 0.5)},
+	}
+
+	t.Run("large buy walks the asks", func(t *testing.T) {
+		service := newBookTestService(50000, book)
+		order := newMarketOrder(models.OrderTypeBuy)
+		order.Quantity = decimal.NewFromInt(3)
+
+		result, err := service.PrepareExecution(ctx, order)
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Fills, 4)
+		assert.True(t, result.Quantity.Equal(decimal.NewFromInt(3)))
+		assert.True(t, result.TotalAmount.Equal(decimal.NewFromInt(150400)))
+		assert.True(t, result.ExecutedPrice.Equal(decimal.RequireFromString("50133.33333333")))
+
+		cost := result.Cost
+		assert.Equal(t, models.PriceSourceOrderBook, cost.Source)
+		assert.Equal(t, 3, cost.LevelsConsumed)
+		assert.True(t, cost.BookExhausted)
+		assert.True(t, cost.SlippageCost.Equal(decimal.NewFromInt(400)))
+		assert.True(t, cost.Slippage.Equal(decimal.RequireFromString("0.00266667")))
+		assert.True(t, cost.Total.Equal(result.TotalAmount.Add(result.Fee)))
+	})
+
+	t.Run("sell slippage is measured against the last price", func(t *testing.T) {
+		service := newBookTestService(50000, book)
+
+		result, err := service.PrepareExecution(ctx, newMarketOrder(models.OrderTypeSell))
+
+		assert.NoError(t, err)
+		assert.True(t, result.ExecutedPrice.Equal(decimal.NewFromInt(49900)))
+		assert.True(t, result.Cost.SlippageCost.Equal(decimal.NewFromInt(100)))
+		assert.True(t, result.Cost.Slippage.Equal(decimal.NewFromFloat(0.002)))
+		assert.True(t, result.Cost.Total.Equal(result.TotalAmount.Sub(result.Fee)))
+		assert.False(t, result.Cost.BookExhausted)
+	})
+
+	t.Run("average price beyond max slippage is rejected", func(t *testing.T) {
+		service := newBookTestService(50000, book)
+		order := newMarketOrder(models.OrderTypeBuy)
+		order.Quantity = decimal.NewFromInt(3)
+		order.Price = decimal.NewFromInt(50000)
+		slippage := decimal.NewFromFloat(0.001)
+		order.MaxSlippage = &slippage
+
+		_, err := service.PrepareExecution(ctx, order)
+
+		assert.ErrorIs(t, err, ErrSlippageExceeded)
+	})
+
+	t.Run("without order book fills at the last price", func(t *testing.T) {
+		service := newBookTestService(50000, nil)
+
+		result, err := service.PrepareExecution(ctx, newMarketOrder(models.OrderTypeBuy))
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PriceSourceLastPrice, result.Cost.Source)
+		assert.True(t, result.Cost.Slippage.IsZero())
+	})
+}
+
+func TestExecutionService_ApplyBalance(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("buy captures the reservation", func(t *testing.T) {
+		service, balanceClient, _ := newExecutionTestService()
+		order := newMarketOrder(models.OrderTypeBuy)
+
+		balanceClient.On("CaptureFunds", ctx, 1, order.ID.Hex(), decimalEq(50050)).Return("tx-1", nil)
+
+		delta, err := service.ApplyBalance(ctx, order, newSaga())
+
+		assert.NoError(t, err)
+		assert.True(t, delta.Equal(decimal.NewFromInt(-50050)))
+		balanceClient.AssertExpectations(t)
+	})
+
+	t.Run("partial buy captures only the pass", func(t *testing.T) {
+		service, balanceClient, _ := newExecutionTestService()
+		order := newMarketOrder(models.OrderTypeBuy)
+		saga := newSaga()
+		saga.Final = false
+		saga.Reference = models.NewFillID(order.ID.Hex(), 1)
+		order.Saga = saga
+
+		balanceClient.On("CaptureFundsPartial", ctx, 1, order.ID.Hex(), saga.Reference, decimalEq(50050)).Return("tx-1", nil)
+
+		_, err := service.ApplyBalance(ctx, order, saga)
+
+		assert.NoError(t, err)
+		balanceClient.AssertExpectations(t)
+		balanceClient.AssertNotCalled(t, "CaptureFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("sell reserves holdings before crediting cash", func(t *testing.T) {
+		service, balanceClient, portfolioClient := newExecutionTestService()
+		order := newMarketOrder(models.OrderTypeSell)
+		orderID := order.ID.Hex()
+
+		portfolioClient.On("ReserveHoldings", ctx, int64(1), "BTC", orderID, order.Quantity).Return(nil)
+		balanceClient.On("ProcessTransaction", ctx, 1, decimalEq(49950), "sell", orderID, mock.Anything).Return("tx-1", nil)
+
+		delta, err := service.ApplyBalance(ctx, order, newSaga())
+
+		assert.NoError(t, err)
+		assert.True(t, delta.Equal(decimal.NewFromInt(49950)))
+		balanceClient.AssertExpectations(t)
+		portfolioClient.AssertExpectations(t)
+	})
+
+	t.Run("insufficient holdings never credits cash", func(t *testing.T) {
+		service, balanceClient, portfolioClient := newExecutionTestService()
+		order := newMarketOrder(models.OrderTypeSell)
+
+		portfolioClient.On("ReserveHoldings", ctx, int64(1), "BTC", order.ID.Hex(), order.Quantity).
+			Return(errors.New("insufficient holdings: available 0.5 BTC"))
+
+		delta, err := service.ApplyBalance(ctx, order, newSaga())
+
+		assert.Error(t, err)
+		assert.True(t, delta.IsZero())
+		assert.Contains(t, err.Error(), "insufficient holdings")
+		balanceClient.AssertNotCalled(t, "ProcessTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("sell without portfolio client is rejected", func(t *testing.T) {
+		service, balanceClient, _ := newExecutionTestService()
+		service.SetPortfolioClient(nil)
+
+		_, err := service.ApplyBalance(ctx, newMarketOrder(models.OrderTypeSell), newSaga())
+
+		assert.ErrorIs(t, err, ErrPortfolioUnavailable)
+		balanceClient.AssertNotCalled(t, "ProcessTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestExecutionService_RevertBalance(t *testing.T) {
+	ctx := context.Background()
+	service, balanceClient, _ := newExecutionTestService()
+	order := newMarketOrder(models.OrderTypeBuy)
+
+	// Deshacer el débito de una compra es un ajuste positivo
+	balanceClient.On("ProcessTransaction", ctx, 1, decimalEq(50050), "adjustment", order.ID.Hex(), mock.Anything).Return("tx-2", nil)
+
+	assert.NoError(t, service.RevertBalance(ctx, order, decimal.NewFromInt(-50050)))
+	balanceClient.AssertExpectations(t)
+}
+
+func TestExecutionService_ReserveFunds_Sell(t *testing.T) {
+	ctx := context.Background()
+	service, balanceClient, portfolioClient := newExecutionTestService()
+	order := newMarketOrder(models.OrderTypeSell)
+	orderID := order.ID.Hex()
+
+	portfolioClient.On("ReserveHoldings", ctx, int64(1), "BTC", orderID, order.Quantity).Return(nil)
+	portfolioClient.On("ReleaseHoldings", ctx, int64(1), "BTC", orderID).Return(nil)
+
+	assert.NoError(t, service.ReserveFunds(ctx, order))
+	assert.NoError(t, service.ReleaseFunds(ctx, order))
+
+	portfolioClient.AssertExpectations(t)
+	balanceClient.AssertNotCalled(t, "LockFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExecutionService_ReserveFunds_MarketBuy(t *testing.T) {
+	ctx := context.Background()
+	service, balanceClient, _ := newExecutionTestService()
+
+	// Cotizada a 50000 con 2% de slippage máximo: el libro puede llenarla hasta 51000
+	slippage := decimal.NewFromFloat(0.02)
+	order := newMarketOrder(models.OrderTypeBuy)
+	order.Price = decimal.NewFromInt(50000)
+	order.TotalAmount = decimal.NewFromInt(50000)
+	order.Fee = decimal.NewFromInt(50)
+	order.MaxSlippage = &slippage
+
+	balanceClient.On("LockFunds", ctx, 1, order.ID.Hex(), decimalEq(51051)).Return(nil)
+
+	assert.NoError(t, service.ReserveFunds(ctx, order))
+	balanceClient.AssertExpectations(t)
+}
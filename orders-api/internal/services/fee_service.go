@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// FeeCalculator calcula la comisión de una orden por amount como maker o taker
+type FeeCalculator interface {
+	CalculateFee(ctx context.Context, order *models.Order, feeType models.FeeType, amount decimal.Decimal) (*models.FeeResult, error)
+}
+
+// staticFeeCalculator aplica siempre la misma tabla, sin escalones por volumen
+type staticFeeCalculator struct {
+	schedule *models.FeeSchedule
+}
+
+func (c staticFeeCalculator) CalculateFee(ctx context.Context, order *models.Order, feeType models.FeeType, amount decimal.Decimal) (*models.FeeResult, error) {
+	return c.schedule.Calculate(order.CryptoSymbol, feeType, decimal.Zero, amount, time.Now()), nil
+}
+
+// FeeServiceConfig configuración de la tabla de comisiones
+type FeeServiceConfig struct {
+	VolumeWindow time.Duration // Ventana del volumen de los escalones (30 días)
+	CacheTTL     time.Duration // Cada cuánto se relee la tabla (cambios hechos en otra réplica)
+}
+
+// FeeService calcula comisiones con la tabla guardada en Mongo. Los admins la
+// cambian en caliente; mientras nadie la guarde rige la tabla por defecto de la
+// configuración. Cada réplica la cachea CacheTTL.
+type FeeService struct {
+	scheduleRepo repositories.FeeScheduleRepository
+	orderRepo    repositories.OrderRepository
+	defaults     *models.FeeSchedule
+	config       FeeServiceConfig
+
+	mu       sync.RWMutex
+	schedule *models.FeeSchedule
+	loadedAt time.Time
+}
+
+// NewFeeService crea el servicio de comisiones
+func NewFeeService(
+	scheduleRepo repositories.FeeScheduleRepository,
+	orderRepo repositories.OrderRepository,
+	defaults *models.FeeSchedule,
+	config FeeServiceConfig,
+) *FeeService {
+	if defaults == nil {
+		defaults = models.DefaultFeeSchedule()
+	}
+	if config.VolumeWindow <= 0 {
+		config.VolumeWindow = 30 * 24 * time.Hour
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 30 * time.Second
+	}
+
+	return &FeeService{
+		scheduleRepo: scheduleRepo,
+		orderRepo:    orderRepo,
+		defaults:     defaults,
+		config:       config,
+	}
+}
+
+// GetSchedule retorna la tabla vigente (cacheada)
+func (s *FeeService) GetSchedule(ctx context.Context) (*models.FeeSchedule, error) {
+	s.mu.RLock()
+	schedule, loadedAt := s.schedule, s.loadedAt
+	s.mu.RUnlock()
+
+	if schedule != nil && time.Since(loadedAt) < s.config.CacheTTL {
+		return schedule, nil
+	}
+
+	fresh, err := s.load(ctx)
+	if err != nil {
+		// Mejor cobrar con la tabla anterior que frenar las órdenes
+		if schedule != nil {
+			log.Printf("Warning: failed to reload fee schedule, using cached version %d: %v", schedule.Version, err)
+			return schedule, nil
+		}
+		return nil, err
+	}
+
+	return fresh, nil
+}
+
+// UpdateSchedule reemplaza la tabla vigente. Con req.Version se rechaza si otra
+// escritura la cambió desde que el admin la leyó.
+func (s *FeeService) UpdateSchedule(ctx context.Context, req *dto.UpdateFeeScheduleRequest, adminID int) (*models.FeeSchedule, error) {
+	schedule := req.ToSchedule()
+	if err := schedule.Validate(); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	current, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Version != nil && *req.Version != current.Version {
+		return nil, fmt.Errorf("failed to update fee schedule: %w", repositories.ErrFeeScheduleConflict)
+	}
+
+	schedule.Version = current.Version
+	schedule.UpdatedAt = time.Now()
+	schedule.UpdatedBy = adminID
+
+	if err := s.scheduleRepo.Save(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to update fee schedule: %w", err)
+	}
+
+	s.cache(schedule)
+	log.Printf("💸 Fee schedule updated to version %d by admin %d", schedule.Version, adminID)
+
+	return schedule, nil
+}
+
+// CalculateFee calcula la comisión con la tabla vigente y el volumen de 30 días del usuario
+func (s *FeeService) CalculateFee(ctx context.Context, order *models.Order, feeType models.FeeType, amount decimal.Decimal) (*models.FeeResult, error) {
+	schedule, err := s.GetSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	volume, err := s.orderRepo.GetUserVolume(ctx, order.UserID, now.Add(-s.config.VolumeWindow))
+	if err != nil {
+		// Sin volumen se cobra la tasa base, nunca menos de lo que corresponde
+		log.Printf("Warning: failed to get 30-day volume for user %d, using base fee tier: %v", order.UserID, err)
+		volume = decimal.Zero
+	}
+
+	return schedule.Calculate(order.CryptoSymbol, feeType, volume, amount, now), nil
+}
+
+// load lee la tabla guardada (o la por defecto si no hay) y la cachea
+func (s *FeeService) load(ctx context.Context) (*models.FeeSchedule, error) {
+	schedule, err := s.scheduleRepo.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		defaults := *s.defaults
+		schedule = &defaults
+	}
+
+	s.cache(schedule)
+	return schedule, nil
+}
+
+func (s *FeeService) cache(schedule *models.FeeSchedule) {
+	s.mu.Lock()
+	s.schedule = schedule
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+type MockFeeScheduleRepository struct {
+	mock.Mock
+}
+
+func (m *MockFeeScheduleRepository) Get(ctx context.Context) (*models.FeeSchedule, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FeeSchedule), args.Error(1)
+}
+
+func (m *MockFeeScheduleRepository) Save(ctx context.Context, schedule *models.FeeSchedule) error {
+	args := m.Called(ctx, schedule)
+	if args.Error(0) == nil {
+		schedule.Version++
+	}
+	return args.Error(0)
+}
+
+func TestFeeService_CalculateFee(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("uses the 30-day volume tier", func(t *testing.T) {
+		scheduleRepo := new(MockFeeScheduleRepository)
+		orderRepo := new(MockOrderRepository)
+		schedule := models.DefaultFeeSchedule()
+		schedule.VolumeTiers = []models.FeeTier{
+			{Name: "vip", MinVolume: decimal.NewFromInt(50000), MakerRate: decimal.Zero, TakerRate: decimal.NewFromFloat(0.0005)},
+		}
+		scheduleRepo.On("Get", ctx).Return(schedule, nil).Once()
+		orderRepo.On("GetUserVolume", ctx, 1, mock.AnythingOfType("time.Time")).Return(decimal.NewFromInt(60000), nil)
+
+		fees := NewFeeService(scheduleRepo, orderRepo, nil, FeeServiceConfig{})
+		result, err := fees.CalculateFee(ctx, newMarketOrder(models.OrderTypeBuy), models.FeeTypeTaker, decimal.NewFromInt(50000))
+
+		require.NoError(t, err)
+		assert.True(t, result.TotalFee.Equal(decimal.NewFromInt(25)))
+		assert.Equal(t, "tier:vip", result.Rule)
+
+		// La segunda orden usa la tabla cacheada
+		_, err = fees.CalculateFee(ctx, newMarketOrder(models.OrderTypeBuy), models.FeeTypeTaker, decimal.NewFromInt(50000))
+		require.NoError(t, err)
+		scheduleRepo.AssertExpectations(t)
+	})
+
+	t.Run("falls back to the base rate when volume is unavailable", func(t *testing.T) {
+		scheduleRepo := new(MockFeeScheduleRepository)
+		orderRepo := new(MockOrderRepository)
+		scheduleRepo.On("Get", ctx).Return(nil, nil)
+		orderRepo.On("GetUserVolume", ctx, 1, mock.AnythingOfType("time.Time")).Return(decimal.Zero, errors.New("timeout"))
+
+		fees := NewFeeService(scheduleRepo, orderRepo, nil, FeeServiceConfig{})
+		result, err := fees.CalculateFee(ctx, newMarketOrder(models.OrderTypeBuy), models.FeeTypeTaker, decimal.NewFromInt(50000))
+
+		require.NoError(t, err)
+		assert.True(t, result.TotalFee.Equal(decimal.NewFromInt(50)))
+		assert.Equal(t, models.FeeRuleBase, result.Rule)
+	})
+}
+
+func TestFeeService_UpdateSchedule(t *testing.T) {
+	ctx := context.Background()
+	validRequest := func() *dto.UpdateFeeScheduleRequest {
+		return &dto.UpdateFeeScheduleRequest{
+			MakerRate:  decimal.NewFromFloat(0.0005),
+			TakerRate:  decimal.NewFromFloat(0.001),
+			MinimumFee: decimal.NewFromFloat(0.01),
+			Promotions: []models.FeePromotion{
+				{Name: "weekend", StartsAt: time.Now(), EndsAt: time.Now().Add(48 * time.Hour)},
+			},
+		}
+	}
+
+	t.Run("saves and serves the new schedule", func(t *testing.T) {
+		scheduleRepo := new(MockFeeScheduleRepository)
+		scheduleRepo.On("Get", ctx).Return(nil, nil)
+		scheduleRepo.On("Save", ctx, mock.AnythingOfType("*models.FeeSchedule")).Return(nil)
+
+		fees := NewFeeService(scheduleRepo, nil, nil, FeeServiceConfig{})
+		schedule, err := fees.UpdateSchedule(ctx, validRequest(), 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), schedule.Version)
+		assert.Equal(t, 7, schedule.UpdatedBy)
+
+		current, err := fees.GetSchedule(ctx)
+		require.NoError(t, err)
+		assert.Same(t, schedule, current)
+	})
+
+	t.Run("stale version is rejected", func(t *testing.T) {
+		scheduleRepo := new(MockFeeScheduleRepository)
+		stored := models.DefaultFeeSchedule()
+		stored.Version = 3
+		scheduleRepo.On("Get", ctx).Return(stored, nil)
+
+		req := validRequest()
+		staleVersion := int64(2)
+		req.Version = &staleVersion
+
+		fees := NewFeeService(scheduleRepo, nil, nil, FeeServiceConfig{})
+		_, err := fees.UpdateSchedule(ctx, req, 7)
+
+		assert.ErrorIs(t, err, repositories.ErrFeeScheduleConflict)
+		scheduleRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid schedule is rejected", func(t *testing.T) {
+		req := validRequest()
+		req.TakerRate = decimal.NewFromFloat(0.2)
+
+		fees := NewFeeService(new(MockFeeScheduleRepository), nil, nil, FeeServiceConfig{})
+		_, err := fees.UpdateSchedule(ctx, req, 7)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "validation error")
+	})
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// PendingOrderExecutor ejecuta una orden pendiente ya tomada por el matcher
+type PendingOrderExecutor interface {
+	ExecutePendingOrder(ctx context.Context, order *models.Order) error
+}
+
+// LimitOrderMatcherConfig configuración del matcher de órdenes limit
+type LimitOrderMatcherConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	LockTTL   time.Duration
+}
+
+// LimitOrderMatcher revisa periódicamente las órdenes limit y condicionales
+// (stop, take profit, oco) abiertas y dispara o ejecuta las que cruzaron su precio.
+// Cada orden se toma con un lock en Mongo, así varias réplicas pueden correr el
+// matcher sin ejecutar dos veces la misma orden.
+type LimitOrderMatcher struct {
+	orderRepo    repositories.OrderRepository
+	marketClient MarketClient
+	executor     PendingOrderExecutor
+	config       LimitOrderMatcherConfig
+	instanceID   string
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewLimitOrderMatcher crea una nueva instancia del matcher
+func NewLimitOrderMatcher(
+	orderRepo repositories.OrderRepository,
+	marketClient MarketClient,
+	executor PendingOrderExecutor,
+	config LimitOrderMatcherConfig,
+) *LimitOrderMatcher {
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 30 * time.Second
+	}
+
+	return &LimitOrderMatcher{
+		orderRepo:    orderRepo,
+		marketClient: marketClient,
+		executor:     executor,
+		config:       config,
+		instanceID:   newInstanceID(),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start inicia el loop del matcher en background
+func (m *LimitOrderMatcher) Start(ctx context.Context) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(m.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.stopCh:
+				return
+			case <-ticker.C:
+				if _, err := m.MatchOnce(ctx); err != nil {
+					log.Printf("Warning: limit order matcher run failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop detiene el matcher y espera a que termine la ejecución en curso
+func (m *LimitOrderMatcher) Stop() {
+	close(m.stopCh)
+	m.wg.Wait()
+}
+
+// MatchOnce ejecuta una pasada del matcher y retorna cuántas órdenes se ejecutaron.
+// Recorre todas las órdenes en el libro de a BatchSize, así las que no cruzan no
+// tapan a las más nuevas.
+func (m *LimitOrderMatcher) MatchOnce(ctx context.Context) (int, error) {
+	// Un solo precio por crypto en toda la pasada; nil si no se pudo obtener
+	prices := make(map[string]*models.PriceResult)
+	now := time.Now()
+	afterID := primitive.NilObjectID
+	executed := 0
+
+	for {
+		page, err := m.orderRepo.GetPendingOrders(ctx, afterID, now, m.config.BatchSize)
+		if err != nil {
+			return executed, fmt.Errorf("failed to get pending orders: %w", err)
+		}
+
+		for i := range page {
+			price, fetched := prices[page[i].CryptoSymbol]
+			if !fetched {
+				price, err = m.marketClient.GetCurrentPrice(ctx, page[i].CryptoSymbol)
+				if err != nil {
+					log.Printf("Warning: matcher could not get price for %s: %v", page[i].CryptoSymbol, err)
+					price = nil
+				}
+				prices[page[i].CryptoSymbol] = price
+			}
+			if price == nil {
+				continue
+			}
+
+			if !page[i].IsExecutableAt(price.MarketPrice) && !page[i].ShouldTrigger(price.MarketPrice) {
+				continue
+			}
+			if m.tryExecute(ctx, page[i].ID.Hex()) {
+				executed++
+			}
+		}
+
+		if len(page) < m.config.BatchSize {
+			return executed, nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+// tryExecute toma la orden y la ejecuta; retorna true si quedó ejecutada
+func (m *LimitOrderMatcher) tryExecute(ctx context.Context, orderID string) bool {
+	order, err := m.orderRepo.ClaimOrder(ctx, orderID, m.instanceID, m.config.LockTTL)
+	if err != nil {
+		log.Printf("Warning: matcher failed to claim order %s: %v", orderID, err)
+		return false
+	}
+	if order == nil {
+		// Otra réplica la tomó o ya no está pendiente
+		return false
+	}
+
+	err = m.executor.ExecutePendingOrder(ctx, order)
+	if err == nil {
+		log.Printf("✅ %s order %s executed at %s", order.OrderKind, order.OrderNumber, order.Price.String())
+		return true
+	}
+
+	priceMoved := errors.Is(err, ErrLimitPriceNotReached) || errors.Is(err, ErrTriggerPriceNotReached)
+	if !priceMoved {
+		log.Printf("Warning: matcher failed to execute order %s: %v", orderID, err)
+	}
+
+	// Si el precio se movió o falló otro servicio antes de tocarla, la orden sigue
+	// abierta: se suelta para que la próxima pasada la vuelva a evaluar
+	if priceMoved || order.IsOpen() {
+		if releaseErr := m.orderRepo.ReleaseOrderClaim(ctx, orderID, m.instanceID); releaseErr != nil {
+			log.Printf("Warning: matcher failed to release order %s: %v", orderID, releaseErr)
+		}
+	}
+	return false
+}
+
+// newInstanceID identifica a esta réplica en los locks de órdenes
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "orders-api"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"orders-api/internal/models"
+)
+
+type MockMarketClient struct {
+	mock.Mock
+}
+
+func (m *MockMarketClient) GetCurrentPrice(ctx context.Context, symbol string) (*models.PriceResult, error) {
+	args := m.Called(ctx, symbol)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PriceResult), args.Error(1)
+}
+
+func (m *MockMarketClient) GetOrderBook(ctx context.Context, symbol string, depth int) (*models.OrderBook, error) {
+	args := m.Called(ctx, symbol, depth)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OrderBook), args.Error(1)
+}
+
+type MockPendingOrderExecutor struct {
+	mock.Mock
+}
+
+func (m *MockPendingOrderExecutor) ExecutePendingOrder(ctx context.Context, order *models.Order) error {
+	args := m.Called(ctx, order)
+	return args.Error(0)
+}
+
+func newLimitOrder(orderType models.OrderType, symbol string, price int64) models.Order {
+	return models.Order{
+		ID:           primitive.NewObjectID(),
+		OrderNumber:  models.NewOrderNumber(),
+		UserID:       1,
+		Type:         orderType,
+		Status:       models.OrderStatusPending,
+		CryptoSymbol: symbol,
+		Quantity:     decimal.NewFromFloat(0.1),
+		OrderKind:    models.OrderKindLimit,
+		Price:        decimal.NewFromInt(price),
+	}
+}
+
+func TestLimitOrderMatcher_MatchOnce(t *testing.T) {
+	ctx := context.Background()
+	config := LimitOrderMatcherConfig{BatchSize: 50}
+
+	t.Run("executes crossed orders only", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketClient)
+		mockExecutor := new(MockPendingOrderExecutor)
+
+		crossedBuy := newLimitOrder(models.OrderTypeBuy, "BTC", 50000)
+		notCrossedSell := newLimitOrder(models.OrderTypeSell, "BTC", 52000)
+
+		mockRepo.On("GetPendingOrders", ctx, primitive.NilObjectID, mock.AnythingOfType("time.Time"), 50).Return([]models.Order{crossedBuy, notCrossedSell}, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(&models.PriceResult{Symbol: "BTC", MarketPrice: decimal.NewFromInt(49500)}, nil).Once()
+
+		matcher := NewLimitOrderMatcher(mockRepo, mockMarket, mockExecutor, config)
+		claimed := crossedBuy
+		mockRepo.On("ClaimOrder", ctx, crossedBuy.ID.Hex(), matcher.instanceID, matcher.config.LockTTL).Return(&claimed, nil)
+		mockExecutor.On("ExecutePendingOrder", ctx, &claimed).Return(nil)
+
+		executed, err := matcher.MatchOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, executed)
+		mockRepo.AssertExpectations(t)
+		mockMarket.AssertExpectations(t)
+		mockExecutor.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "ClaimOrder", ctx, notCrossedSell.ID.Hex(), mock.Anything, mock.Anything)
+	})
+
+	t.Run("pages past orders that do not cross", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketClient)
+		mockExecutor := new(MockPendingOrderExecutor)
+
+		oldest := newLimitOrder(models.OrderTypeBuy, "BTC", 40000)
+		older := newLimitOrder(models.OrderTypeBuy, "BTC", 41000)
+		crossed := newLimitOrder(models.OrderTypeBuy, "BTC", 50000)
+
+		mockRepo.On("GetPendingOrders", ctx, primitive.NilObjectID, mock.AnythingOfType("time.Time"), 2).Return([]models.Order{oldest, older}, nil).Once()
+		mockRepo.On("GetPendingOrders", ctx, older.ID, mock.AnythingOfType("time.Time"), 2).Return([]models.Order{crossed}, nil).Once()
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(&models.PriceResult{Symbol: "BTC", MarketPrice: decimal.NewFromInt(49500)}, nil).Once()
+
+		matcher := NewLimitOrderMatcher(mockRepo, mockMarket, mockExecutor, LimitOrderMatcherConfig{BatchSize: 2})
+		claimed := crossed
+		mockRepo.On("ClaimOrder", ctx, crossed.ID.Hex(), matcher.instanceID, mock.Anything).Return(&claimed, nil)
+		mockExecutor.On("ExecutePendingOrder", ctx, &claimed).Return(nil)
+
+		executed, err := matcher.MatchOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, executed)
+		mockRepo.AssertExpectations(t)
+		mockMarket.AssertExpectations(t)
+	})
+
+	t.Run("claims stop orders whose trigger was reached", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketClient)
+		mockExecutor := new(MockPendingOrderExecutor)
+
+		stopLoss := newLimitOrder(models.OrderTypeSell, "BTC", 45000)
+		stopLoss.OrderKind = models.OrderKindStopMarket
+		trigger := decimal.NewFromInt(45000)
+		stopLoss.TriggerPrice = &trigger
+
+		takeProfit := newLimitOrder(models.OrderTypeSell, "BTC", 60000)
+		takeProfit.OrderKind = models.OrderKindTakeProfit
+		target := decimal.NewFromInt(60000)
+		takeProfit.TriggerPrice = &target
+
+		mockRepo.On("GetPendingOrders", ctx, primitive.NilObjectID, mock.AnythingOfType("time.Time"), 50).Return([]models.Order{stopLoss, takeProfit}, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(&models.PriceResult{Symbol: "BTC", MarketPrice: decimal.NewFromInt(44000)}, nil)
+
+		matcher := NewLimitOrderMatcher(mockRepo, mockMarket, mockExecutor, config)
+		claimed := stopLoss
+		mockRepo.On("ClaimOrder", ctx, stopLoss.ID.Hex(), matcher.instanceID, mock.Anything).Return(&claimed, nil)
+		mockExecutor.On("ExecutePendingOrder", ctx, &claimed).Return(nil)
+
+		executed, err := matcher.MatchOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, executed)
+		mockRepo.AssertNotCalled(t, "ClaimOrder", ctx, takeProfit.ID.Hex(), mock.Anything, mock.Anything)
+	})
+
+	t.Run("skips orders claimed by another replica", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketClient)
+		mockExecutor := new(MockPendingOrderExecutor)
+
+		order := newLimitOrder(models.OrderTypeSell, "ETH", 3000)
+
+		mockRepo.On("GetPendingOrders", ctx, primitive.NilObjectID, mock.AnythingOfType("time.Time"), 50).Return([]models.Order{order}, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "ETH").Return(&models.PriceResult{Symbol: "ETH", MarketPrice: decimal.NewFromInt(3100)}, nil)
+		mockRepo.On("ClaimOrder", ctx, order.ID.Hex(), mock.Anything, mock.Anything).Return(nil, nil)
+
+		matcher := NewLimitOrderMatcher(mockRepo, mockMarket, mockExecutor, config)
+		executed, err := matcher.MatchOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, executed)
+		mockExecutor.AssertNotCalled(t, "ExecutePendingOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("releases claim when price moved away", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketClient)
+		mockExecutor := new(MockPendingOrderExecutor)
+
+		order := newLimitOrder(models.OrderTypeBuy, "SOL", 100)
+
+		mockRepo.On("GetPendingOrders", ctx, primitive.NilObjectID, mock.AnythingOfType("time.Time"), 50).Return([]models.Order{order}, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "SOL").Return(&models.PriceResult{Symbol: "SOL", MarketPrice: decimal.NewFromInt(99)}, nil)
+
+		matcher := NewLimitOrderMatcher(mockRepo, mockMarket, mockExecutor, config)
+		claimed := order
+		mockRepo.On("ClaimOrder", ctx, order.ID.Hex(), matcher.instanceID, mock.Anything).Return(&claimed, nil)
+		mockExecutor.On("ExecutePendingOrder", ctx, &claimed).Return(ErrLimitPriceNotReached)
+		mockRepo.On("ReleaseOrderClaim", ctx, order.ID.Hex(), matcher.instanceID).Return(nil)
+
+		executed, err := matcher.MatchOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, executed)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("releases claim when another service fails", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketClient)
+		mockExecutor := new(MockPendingOrderExecutor)
+
+		order := newLimitOrder(models.OrderTypeBuy, "SOL", 100)
+
+		mockRepo.On("GetPendingOrders", ctx, primitive.NilObjectID, mock.AnythingOfType("time.Time"), 50).Return([]models.Order{order}, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "SOL").Return(&models.PriceResult{Symbol: "SOL", MarketPrice: decimal.NewFromInt(99)}, nil)
+
+		matcher := NewLimitOrderMatcher(mockRepo, mockMarket, mockExecutor, config)
+		claimed := order
+		mockRepo.On("ClaimOrder", ctx, order.ID.Hex(), matcher.instanceID, mock.Anything).Return(&claimed, nil)
+		mockExecutor.On("ExecutePendingOrder", ctx, &claimed).Return(errors.New("user validation failed: request failed"))
+		mockRepo.On("ReleaseOrderClaim", ctx, order.ID.Hex(), matcher.instanceID).Return(nil)
+
+		executed, err := matcher.MatchOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, executed)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("skips symbol when price is unavailable", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketClient)
+		mockExecutor := new(MockPendingOrderExecutor)
+
+		order := newLimitOrder(models.OrderTypeBuy, "ADA", 1)
+
+		mockRepo.On("GetPendingOrders", ctx, primitive.NilObjectID, mock.AnythingOfType("time.Time"), 50).Return([]models.Order{order}, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "ADA").Return(nil, errors.New("market unavailable"))
+
+		matcher := NewLimitOrderMatcher(mockRepo, mockMarket, mockExecutor, config)
+		executed, err := matcher.MatchOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, executed)
+		mockRepo.AssertNotCalled(t, "ClaimOrder", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockRepo.On("GetPendingOrders", ctx, primitive.NilObjectID, mock.AnythingOfType("time.Time"), 50).Return(nil, errors.New("database error"))
+
+		matcher := NewLimitOrderMatcher(mockRepo, new(MockMarketClient), new(MockPendingOrderExecutor), config)
+		_, err := matcher.MatchOnce(ctx)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// OrderExpirer expira una orden abierta ya tomada por el sweeper
+type OrderExpirer interface {
+	ExpireOrder(ctx context.Context, order *models.Order, reason string) error
+}
+
+// OrderExpirySweeperConfig configuración del sweeper de órdenes vencidas
+type OrderExpirySweeperConfig struct {
+	Interval       time.Duration
+	BatchSize      int
+	LockTTL        time.Duration
+	ImmediateGrace time.Duration // Tras este tiempo una IOC/FOK que quedó abierta se expira
+}
+
+// OrderExpirySweeper expira las órdenes gtd que pasaron su expires_at y libera sus
+// fondos reservados. También levanta las IOC/FOK que quedaron abiertas porque la
+// instancia que las creó se cayó antes de expirarlas.
+// Cada orden se toma con un lock en Mongo, así varias réplicas pueden correrlo.
+type OrderExpirySweeper struct {
+	orderRepo  repositories.OrderRepository
+	expirer    OrderExpirer
+	config     OrderExpirySweeperConfig
+	instanceID string
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewOrderExpirySweeper crea una nueva instancia del sweeper
+func NewOrderExpirySweeper(
+	orderRepo repositories.OrderRepository,
+	expirer OrderExpirer,
+	config OrderExpirySweeperConfig,
+) *OrderExpirySweeper {
+	if config.Interval <= 0 {
+		config.Interval = 10 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 30 * time.Second
+	}
+	if config.ImmediateGrace <= 0 {
+		config.ImmediateGrace = time.Minute
+	}
+
+	return &OrderExpirySweeper{
+		orderRepo:  orderRepo,
+		expirer:    expirer,
+		config:     config,
+		instanceID: newInstanceID(),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Start inicia el loop del sweeper en background; la primera pasada es inmediata
+// para expirar lo que venció mientras el servicio estaba caído
+func (s *OrderExpirySweeper) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.SweepOnce(ctx); err != nil {
+				log.Printf("Warning: order expiry sweeper run failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop detiene el sweeper y espera a que termine la pasada en curso
+func (s *OrderExpirySweeper) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+// SweepOnce expira hasta BatchSize órdenes vencidas y retorna cuántas expiró
+func (s *OrderExpirySweeper) SweepOnce(ctx context.Context) (int, error) {
+	expired := 0
+
+	for i := 0; i < s.config.BatchSize; i++ {
+		now := time.Now()
+		order, err := s.orderRepo.ClaimExpiredOrder(ctx, now, now.Add(-s.config.ImmediateGrace), s.instanceID, s.config.LockTTL)
+		if err != nil {
+			return expired, fmt.Errorf("failed to claim expired order: %w", err)
+		}
+		if order == nil {
+			break
+		}
+
+		if err := s.expirer.ExpireOrder(ctx, order, expiryReason(order)); err != nil {
+			// Si el update no llegó a guardarse se vuelve a tomar cuando venza el lock
+			log.Printf("Warning: failed to expire order %s: %v", order.ID.Hex(), err)
+			if order.Status != models.OrderStatusExpired {
+				continue
+			}
+		}
+
+		expired++
+	}
+
+	return expired, nil
+}
+
+// expiryReason motivo del vencimiento que se publica en el evento
+func expiryReason(order *models.Order) string {
+	if order.TimeInForce.IsImmediate() {
+		return fmt.Sprintf("%s order left open after its first pass", order.TimeInForce)
+	}
+	return fmt.Sprintf("order expired at %s", order.ExpiresAt.UTC().Format(time.RFC3339))
+}
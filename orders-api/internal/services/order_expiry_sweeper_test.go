@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"orders-api/internal/models"
+)
+
+type MockOrderExpirer struct {
+	mock.Mock
+}
+
+func (m *MockOrderExpirer) ExpireOrder(ctx context.Context, order *models.Order, reason string) error {
+	args := m.Called(ctx, order, reason)
+	return args.Error(0)
+}
+
+func TestOrderExpirySweeper_SweepOnce(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockOrderRepository)
+	mockExpirer := new(MockOrderExpirer)
+
+	expiresAt := time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC)
+	gtd := newBookOrder(models.OrderTypeBuy, 50000, 1)
+	gtd.TimeInForce = models.TimeInForceGTD
+	gtd.ExpiresAt = &expiresAt
+	ioc := newBookOrder(models.OrderTypeSell, 50000, 1)
+	ioc.TimeInForce = models.TimeInForceIOC
+
+	sweeper := NewOrderExpirySweeper(mockRepo, mockExpirer, OrderExpirySweeperConfig{})
+
+	mockRepo.On("ClaimExpiredOrder", ctx, mock.Anything, mock.Anything, sweeper.instanceID, sweeper.config.LockTTL).Return(gtd, nil).Once()
+	mockRepo.On("ClaimExpiredOrder", ctx, mock.Anything, mock.Anything, sweeper.instanceID, sweeper.config.LockTTL).Return(ioc, nil).Once()
+	mockRepo.On("ClaimExpiredOrder", ctx, mock.Anything, mock.Anything, sweeper.instanceID, sweeper.config.LockTTL).Return(nil, nil).Once()
+	mockExpirer.On("ExpireOrder", ctx, gtd, "order expired at 2025-01-02T15:00:00Z").Return(nil)
+	mockExpirer.On("ExpireOrder", ctx, ioc, "ioc order left open after its first pass").Return(errors.New("version conflict"))
+
+	expired, err := sweeper.SweepOnce(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	mockRepo.AssertExpectations(t)
+	mockExpirer.AssertExpectations(t)
+}
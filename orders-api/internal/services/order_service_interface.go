@@ -9,7 +9,18 @@ import (
 // OrderService interface que define las operaciones de órdenes
 type OrderService interface {
 	CreateOrder(ctx context.Context, req *dto.CreateOrderRequest, userID int) (*models.Order, error)
+	SimulateOrder(ctx context.Context, req *dto.CreateOrderRequest, userID int) (*models.OrderSimulation, error)
 	GetOrder(ctx context.Context, orderID string, userID int) (*models.Order, error)
-	ListUserOrders(ctx context.Context, userID int, filter *dto.OrderFilterRequest) ([]models.Order, int64, *dto.OrdersSummary, error)
+	GetOrderFills(ctx context.Context, orderID string, userID int) ([]models.Fill, error)
+	ListUserOrders(ctx context.Context, userID int, filter *dto.OrderFilterRequest) (*dto.OrderPage, *dto.OrdersSummary, error)
+	ExportFilledOrders(ctx context.Context, userID int, req *dto.OrderExportRequest, fn func(row dto.OrderExportRow) error) error
 	CancelOrder(ctx context.Context, orderID string, userID int, reason string) error
+	AmendOrder(ctx context.Context, orderID string, userID int, req *dto.AmendOrderRequest) (*models.Order, error)
+	AdminCancelOrder(ctx context.Context, orderID string, reason string) error
+	AdminAmendOrder(ctx context.Context, orderID string, req *dto.AmendOrderRequest) (*models.Order, error)
+	AdminListOrders(ctx context.Context, filter *dto.AdminOrderFilterRequest) (*dto.OrderPage, error)
+	AdminGetOrder(ctx context.Context, orderID string) (*models.Order, error)
+	AdminFailOrders(ctx context.Context, req *dto.BulkFailOrdersRequest) (*dto.BulkFailResult, error)
+	GetAdminStatistics(ctx context.Context) (*dto.AdminStatistics, error)
+	CreateQuote(ctx context.Context, userID int, symbol string, orderType models.OrderType) (*models.Quote, error)
 }
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -14,12 +15,27 @@ import (
 	"orders-api/internal/repositories"
 )
 
+// ErrOrderValidation indica que la orden o el pedido tiene datos inválidos (se responde 400)
+var ErrOrderValidation = errors.New("validation error")
+
+// ErrInvalidSymbol indica que Market API no reconoce el símbolo de la orden
+var ErrInvalidSymbol = errors.New("invalid crypto symbol")
+
+// ErrTradingSuspended indica que el símbolo existe pero no se puede operar
+var ErrTradingSuspended = errors.New("trading is suspended")
+
 // OrderServiceSimple servicio simplificado de órdenes (sin concurrencia compleja)
 type OrderServiceSimple struct {
 	orderRepo        repositories.OrderRepository
+	fillRepo         repositories.FillRepository
 	executionService *ExecutionService
 	marketService    MarketService
 	publisher        EventPublisher
+	saga             *SagaCoordinator
+	tx               Transactor
+	quotes           *QuoteService
+	risk             RiskChecker
+	maxSlippage      decimal.Decimal // Slippage por defecto de las órdenes market (0 = sin límite)
 }
 
 // MarketService interface para servicios de mercado
@@ -40,8 +56,42 @@ type CryptoInfo struct {
 type EventPublisher interface {
 	PublishOrderCreated(ctx context.Context, order *models.Order) error
 	PublishOrderExecuted(ctx context.Context, order *models.Order) error
+	PublishOrderPartiallyFilled(ctx context.Context, order *models.Order) error
 	PublishOrderCancelled(ctx context.Context, order *models.Order, reason string) error
+	PublishOrderAmended(ctx context.Context, order *models.Order, previous *models.Order) error
 	PublishOrderFailed(ctx context.Context, order *models.Order, reason string) error
+	PublishOrderExpired(ctx context.Context, order *models.Order, reason string) error
+}
+
+// Transactor ejecuta fn en una transacción de la base; las escrituras hechas con el
+// contexto recibido (orden y evento del outbox) se confirman juntas o ninguna
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// noTransaction ejecuta fn directamente (publisher directo, sin outbox)
+type noTransaction struct{}
+
+func (noTransaction) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// writeWithEvent escribe la orden y publica su evento dentro de la misma transacción.
+// Si la transacción se descarta, la versión de la orden vuelve a la leída.
+func writeWithEvent(ctx context.Context, tx Transactor, order *models.Order, write, publish func(ctx context.Context) error) error {
+	version := order.Version
+	err := tx.RunInTransaction(ctx, func(txCtx context.Context) error {
+		// La transacción puede reintentar fn ante errores transitorios
+		order.Version = version
+		if err := write(txCtx); err != nil {
+			return err
+		}
+		return publish(txCtx)
+	})
+	if err != nil {
+		order.Version = version
+	}
+	return err
 }
 
 // NewOrderServiceSimple crea una instancia del servicio simplificado
@@ -56,147 +106,461 @@ func NewOrderServiceSimple(
 		executionService: executionService,
 		marketService:    marketService,
 		publisher:        publisher,
+		saga:             NewSagaCoordinator(orderRepo, executionService, publisher),
+		tx:               noTransaction{},
+	}
+}
+
+// SetTransactor hace que cada escritura de orden y su evento vayan en una transacción
+// (necesario cuando el publisher escribe en el outbox)
+func (s *OrderServiceSimple) SetTransactor(tx Transactor) {
+	s.tx = tx
+	s.saga.tx = tx
+}
+
+// SetFillRepository guarda los fills de cada pasada en su propia colección
+func (s *OrderServiceSimple) SetFillRepository(fillRepo repositories.FillRepository) {
+	s.fillRepo = fillRepo
+	s.saga.fillRepo = fillRepo
+}
+
+// SetQuoteService habilita las cotizaciones firmadas para órdenes market
+func (s *OrderServiceSimple) SetQuoteService(quotes *QuoteService) {
+	s.quotes = quotes
+}
+
+// SetRiskChecker habilita los límites de riesgo pre-trade de las órdenes de usuario
+func (s *OrderServiceSimple) SetRiskChecker(risk RiskChecker) {
+	s.risk = risk
+}
+
+// SetDefaultMaxSlippage fija el slippage máximo de las órdenes market que no piden uno
+func (s *OrderServiceSimple) SetDefaultMaxSlippage(maxSlippage decimal.Decimal) {
+	s.maxSlippage = maxSlippage
+}
+
+// CreateQuote emite una cotización firmada para crear una orden market
+func (s *OrderServiceSimple) CreateQuote(ctx context.Context, userID int, symbol string, orderType models.OrderType) (*models.Quote, error) {
+	if s.quotes == nil {
+		return nil, fmt.Errorf("quotes are not enabled")
+	}
+
+	cryptoInfo, err := s.marketService.ValidateSymbol(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSymbol, err)
 	}
+	if !cryptoInfo.IsActive {
+		return nil, fmt.Errorf("%w for %s", ErrTradingSuspended, symbol)
+	}
+
+	return s.quotes.CreateQuote(ctx, userID, symbol, orderType)
+}
+
+// ExecutionSaga retorna el coordinador que ejecuta las órdenes del servicio
+func (s *OrderServiceSimple) ExecutionSaga() *SagaCoordinator {
+	return s.saga
 }
 
 // CreateOrder crea y ejecuta una orden de forma simplificada
 func (s *OrderServiceSimple) CreateOrder(ctx context.Context, req *dto.CreateOrderRequest, userID int) (*models.Order, error) {
-	// DEBUG: Log request
-	log.Printf("🔍 CreateOrder received - Symbol: %s, MarketPrice field: '%s', OrderKind: %s",
-		req.CryptoSymbol, req.MarketPrice, req.OrderKind)
-
 	// 1. Validar request y parsear valores
-	quantity, limitPrice, marketPrice, err := req.Validate()
+	quantity, limitPrice, triggerPrice, maxSlippage, err := req.Validate()
 	if err != nil {
-		return nil, fmt.Errorf("validation error: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrOrderValidation, err)
 	}
 
-	// DEBUG: Log parsed values
-	if marketPrice != nil {
-		log.Printf("🔍 Parsed marketPrice: %s", marketPrice.String())
-	} else {
-		log.Printf("🔍 Parsed marketPrice is nil")
+	// Si el cliente reintenta con la misma Idempotency-Key, devolver la orden original
+	if req.IdempotencyKey != "" {
+		existing, err := s.orderRepo.GetByIdempotencyKey(ctx, userID, req.IdempotencyKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if existing != nil {
+			log.Printf("🔁 Replaying order %s for idempotency key %s", existing.ID.Hex(), req.IdempotencyKey)
+			return existing, nil
+		}
+	}
+
+	// 2-5. Validar símbolo, determinar precio y armar la orden con su comisión estimada
+	order, err := s.prepareOrder(ctx, req, userID, quantity, limitPrice, triggerPrice, maxSlippage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Límites de riesgo del rol del usuario, antes de reservar nada
+	if err := s.checkRisk(ctx, order, nil); err != nil {
+		return nil, err
+	}
+
+	// 6. Reservar fondos para compras o holdings para ventas (falla si no alcanzan)
+	if err := s.executionService.ReserveFunds(ctx, order); err != nil {
+		return nil, fmt.Errorf("failed to reserve funds: %w", err)
+	}
+
+	// 7. Guardar en base de datos junto con el evento de creación
+	err = writeWithEvent(ctx, s.tx, order,
+		func(txCtx context.Context) error { return s.orderRepo.Create(txCtx, order) },
+		func(txCtx context.Context) error { return s.publisher.PublishOrderCreated(txCtx, order) },
+	)
+	if err != nil {
+		if releaseErr := s.executionService.ReleaseFunds(ctx, order); releaseErr != nil {
+			log.Printf("Warning: failed to release funds for unsaved order %s: %v", order.ID.Hex(), releaseErr)
+		}
+		// Un request concurrente con la misma key ganó la carrera: devolver su orden
+		if errors.Is(err, repositories.ErrDuplicateIdempotencyKey) {
+			existing, getErr := s.orderRepo.GetByIdempotencyKey(ctx, userID, req.IdempotencyKey)
+			if getErr != nil {
+				return nil, fmt.Errorf("failed to load order for idempotency key: %w", getErr)
+			}
+			if existing != nil {
+				return existing, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to save order: %w", err)
+	}
+
+	// 8. Si es market order o IOC/FOK, ejecutar inmediatamente de forma síncrona
+	if req.OrderKind == models.OrderKindMarket || order.TimeInForce.IsImmediate() {
+		// Get user token from context if available
+		execCtx := ctx
+		if userToken := ctx.Value("user_token"); userToken != nil {
+			execCtx = context.WithValue(execCtx, "user_token", userToken)
+		}
+
+		execErr := s.executeOrderSync(execCtx, order)
+		if execErr != nil {
+			log.Printf("Warning: failed to execute %s order: %v", order.OrderKind, execErr)
+			// La orden queda en pending, el usuario puede ver el error
+		}
+
+		// IOC/FOK: lo que no se llenó en la primera pasada expira y se libera su reserva
+		if order.TimeInForce.IsImmediate() && order.IsOpen() {
+			if err := s.ExpireOrder(execCtx, order, immediateExpiryReason(order, execErr)); err != nil {
+				// El sweeper la expira cuando pase ImmediateGrace
+				log.Printf("Warning: failed to expire %s order %s: %v", order.TimeInForce, order.ID.Hex(), err)
+			}
+		}
+	}
+
+	return order, nil
+}
+
+// SimulateOrder corre las validaciones, el precio, la comisión y los chequeos de saldo
+// y holdings de CreateOrder sin guardar la orden ni reservar fondos. Si la orden sería
+// rechazada se informa en RejectionReason; solo retorna error si falla una dependencia.
+func (s *OrderServiceSimple) SimulateOrder(ctx context.Context, req *dto.CreateOrderRequest, userID int) (*models.OrderSimulation, error) {
+	sim := &models.OrderSimulation{
+		Type:         req.Type,
+		OrderKind:    req.OrderKind,
+		CryptoSymbol: req.CryptoSymbol,
+		Warnings:     []string{},
 	}
 
+	quantity, limitPrice, triggerPrice, maxSlippage, err := req.Validate()
+	if err != nil {
+		return sim.Reject(fmt.Sprintf("%v: %v", ErrOrderValidation, err)), nil
+	}
+	sim.Quantity = quantity
+
+	order, err := s.prepareOrder(ctx, req, userID, quantity, limitPrice, triggerPrice, maxSlippage)
+	if err != nil {
+		if isOrderRejection(err) {
+			return sim.Reject(err.Error()), nil
+		}
+		return nil, err
+	}
+
+	if err := s.checkRisk(ctx, order, nil); err != nil {
+		var rejection *models.RiskRejection
+		if errors.As(err, &rejection) {
+			sim.Reject(err.Error())
+			sim.RejectionCode = rejection.Code
+			return sim, nil
+		}
+		return nil, err
+	}
+
+	// Precio y comisión de una ejecución ahora; si no se ejecutaría, los de la orden
+	result, err := s.executionService.PrepareExecution(ctx, order)
+	switch {
+	case err == nil:
+		sim.WouldFill = true
+		sim.EstimatedPrice = result.ExecutedPrice
+		sim.EstimatedQuantity = result.Quantity
+		sim.EstimatedFee = result.Fee
+		sim.EstimatedTotal = result.Cost.Total
+		sim.Slippage = result.Cost.Slippage
+		sim.FeeDetail = result.FeeDetail
+		sim.Cost = result.Cost
+		if result.Quantity.LessThan(order.Quantity) {
+			rest := "stay open"
+			if order.TimeInForce.IsImmediate() {
+				rest = "expire"
+			}
+			sim.Warnings = append(sim.Warnings, fmt.Sprintf("only %s of %s would fill now; the rest would %s",
+				result.Quantity.String(), order.Quantity.String(), rest))
+		}
+		if result.Cost.BookExhausted {
+			sim.Warnings = append(sim.Warnings, "order book depth exhausted: the rest is priced at the worst level taken")
+		}
+	case errors.Is(err, ErrFillOrKillNotFilled) || (errors.Is(err, ErrLimitPriceNotReached) && order.TimeInForce.IsImmediate()):
+		sim.EstimatedPrice = order.Price
+		sim.FeeDetail = order.FeeDetail
+		sim.Warnings = append(sim.Warnings, fmt.Sprintf("%s order would expire without filling: %v", order.TimeInForce, err))
+	case errors.Is(err, ErrLimitPriceNotReached):
+		sim.EstimatedPrice = order.Price
+		sim.EstimatedQuantity = order.Quantity
+		sim.EstimatedFee = order.Fee
+		sim.EstimatedTotal = order.CalculateTotalWithFee()
+		if order.Type == models.OrderTypeSell {
+			sim.EstimatedTotal = order.TotalAmount.Sub(order.Fee)
+		}
+		sim.FeeDetail = order.FeeDetail
+		if order.OrderKind.HasTriggerPrice() && order.OrderKind != models.OrderKindOCO {
+			sim.Warnings = append(sim.Warnings, fmt.Sprintf("order would wait for the trigger price %s", order.TriggerPrice.String()))
+		} else {
+			sim.Warnings = append(sim.Warnings, fmt.Sprintf("order would stay open until the price reaches %s", order.Price.String()))
+		}
+	case errors.Is(err, ErrSlippageExceeded):
+		return sim.Reject(err.Error()), nil
+	default:
+		return nil, err
+	}
+
+	if order.Type == models.OrderTypeSell {
+		return s.simulateSell(ctx, order, sim)
+	}
+	return s.simulateBuy(ctx, order, sim)
+}
+
+// simulateBuy verifica que el saldo alcance para la reserva de la orden y, si se
+// ejecutaría, para lo que costaría llenarla
+func (s *OrderServiceSimple) simulateBuy(ctx context.Context, order *models.Order, sim *models.OrderSimulation) (*models.OrderSimulation, error) {
+	required := order.ReservationAmount()
+	if sim.WouldFill {
+		required = decimal.Max(required, sim.EstimatedTotal)
+	}
+
+	balance, err := s.executionService.CheckBalance(ctx, order, required)
+	if err != nil {
+		return nil, err
+	}
+	sim.AvailableBalance = balance.Available
+
+	if !balance.HasSufficient {
+		return sim.Reject(fmt.Sprintf("insufficient balance: need %s, have %s", required.String(), balance.Available.String())), nil
+	}
+
+	// Una orden llena paga lo ejecutado; una abierta deja su reserva bloqueada.
+	// Una IOC/FOK libera lo que no llenó, así que solo paga lo ejecutado.
+	spent := order.ReservationAmount()
+	if sim.WouldFill && (sim.EstimatedQuantity.GreaterThanOrEqual(order.Quantity) || order.TimeInForce.IsImmediate()) {
+		spent = sim.EstimatedTotal
+	} else if order.TimeInForce.IsImmediate() {
+		spent = decimal.Zero
+	}
+	sim.ResultingBalance = balance.Available.Sub(spent)
+	return sim, nil
+}
+
+// simulateSell verifica que el usuario tenga la cantidad a vender sin reservar
+func (s *OrderServiceSimple) simulateSell(ctx context.Context, order *models.Order, sim *models.OrderSimulation) (*models.OrderSimulation, error) {
+	holding, err := s.executionService.CheckHoldings(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+
+	available := holding.Available
+	sim.AvailableHoldings = &available
+	if available.LessThan(order.Quantity) {
+		return sim.Reject(fmt.Sprintf("insufficient holdings: %s %s available, %s requested",
+			available.String(), order.CryptoSymbol, order.Quantity.String())), nil
+	}
+	sold := order.Quantity
+	if order.TimeInForce.IsImmediate() {
+		sold = sim.EstimatedQuantity
+		if !sim.WouldFill {
+			sold = decimal.Zero
+		}
+	}
+	resulting := available.Sub(sold)
+	sim.ResultingHoldings = &resulting
+
+	balance, err := s.executionService.CheckBalance(ctx, order, decimal.Zero)
+	if err != nil {
+		return nil, err
+	}
+	sim.AvailableBalance = balance.Available
+	sim.ResultingBalance = balance.Available
+	if sim.WouldFill {
+		sim.ResultingBalance = balance.Available.Add(sim.EstimatedTotal)
+	}
+	return sim, nil
+}
+
+// checkRisk controla los límites de riesgo del rol que viene en el contexto (el del
+// usuario autenticado; sin rol aplican los del rol por defecto)
+func (s *OrderServiceSimple) checkRisk(ctx context.Context, order *models.Order, previous *models.Order) error {
+	if s.risk == nil {
+		return nil
+	}
+
+	role, _ := ctx.Value("user_role").(string)
+	return s.risk.CheckOrder(ctx, order, previous, role)
+}
+
+// isOrderRejection indica si el error de armar una orden es un rechazo de la orden
+// (datos inválidos, cotización vencida, símbolo suspendido) y no una falla de una dependencia
+func isOrderRejection(err error) bool {
+	return errors.Is(err, ErrOrderValidation) ||
+		errors.Is(err, ErrInvalidSymbol) ||
+		errors.Is(err, ErrTradingSuspended) ||
+		errors.Is(err, ErrInvalidQuote) ||
+		errors.Is(err, ErrQuoteExpired)
+}
+
+// prepareOrder valida el símbolo, determina el precio de la orden y la arma con su
+// comisión estimada, sin guardarla ni reservar fondos
+func (s *OrderServiceSimple) prepareOrder(ctx context.Context, req *dto.CreateOrderRequest, userID int,
+	quantity decimal.Decimal, limitPrice, triggerPrice, maxSlippage *decimal.Decimal) (*models.Order, error) {
 	// 2. Validar símbolo de crypto
 	cryptoInfo, err := s.marketService.ValidateSymbol(ctx, req.CryptoSymbol)
 	if err != nil {
-		return nil, fmt.Errorf("invalid crypto symbol: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSymbol, err)
 	}
 
 	if !cryptoInfo.IsActive {
-		return nil, fmt.Errorf("trading is suspended for %s", req.CryptoSymbol)
+		return nil, fmt.Errorf("%w for %s", ErrTradingSuspended, req.CryptoSymbol)
 	}
 
 	// 3. Determinar precio de la orden
 	var orderPrice decimal.Decimal
-	if req.OrderKind == models.OrderKindLimit {
-		// Para limit orders, usar el precio límite
+	if req.OrderKind.HasLimitPrice() {
+		// Para limit, stop_limit y oco, usar el precio límite
 		orderPrice = *limitPrice
-	} else if marketPrice != nil {
-		// Para market orders, usar el precio del frontend si está disponible
-		log.Printf("📊 Using market price from frontend: %s for %s", marketPrice.String(), req.CryptoSymbol)
-		orderPrice = *marketPrice
+	} else if req.OrderKind.HasTriggerPrice() {
+		// Para stop_market y take_profit, estimar con el precio de disparo
+		orderPrice = *triggerPrice
 	} else {
-		// Si no viene precio del frontend, obtener del backend
-		log.Printf("⚠️ No market price from frontend, fetching from backend for %s", req.CryptoSymbol)
-		currentPrice, err := s.marketService.GetCurrentPrice(ctx, req.CryptoSymbol)
+		// Para market orders, el precio de una cotización firmada o el actual del backend
+		orderPrice, err = s.marketOrderPrice(ctx, req, userID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get current price: %w", err)
+			return nil, err
+		}
+		if maxSlippage == nil && s.maxSlippage.IsPositive() {
+			defaultSlippage := s.maxSlippage
+			maxSlippage = &defaultSlippage
 		}
-		orderPrice = currentPrice
 	}
 
-	// 4. Calcular monto total y comisión
+	// 4. Calcular monto total
 	totalAmount := quantity.Mul(orderPrice)
-	fee := totalAmount.Mul(decimal.NewFromFloat(0.001)) // 0.1%
-	minFee := decimal.NewFromFloat(0.01)
-	if fee.LessThan(minFee) {
-		fee = minFee
-	}
 
 	// 5. Crear orden
 	order := &models.Order{
-		ID:           primitive.NewObjectID(),
-		OrderNumber:  models.NewOrderNumber(),
-		UserID:       userID,
-		Type:         req.Type,
-		Status:       models.OrderStatusPending,
-		CryptoSymbol: req.CryptoSymbol,
-		CryptoName:   cryptoInfo.Name,
-		Quantity:     quantity,
-		OrderKind:    req.OrderKind,
-		Price:        orderPrice,
-		TotalAmount:  totalAmount,
-		Fee:          fee,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:             primitive.NewObjectID(),
+		OrderNumber:    models.NewOrderNumber(),
+		UserID:         userID,
+		Type:           req.Type,
+		Status:         models.OrderStatusPending,
+		CryptoSymbol:   req.CryptoSymbol,
+		CryptoName:     cryptoInfo.Name,
+		Quantity:       quantity,
+		OrderKind:      req.OrderKind,
+		Price:          orderPrice,
+		TotalAmount:    totalAmount,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		IdempotencyKey: req.IdempotencyKey,
+		TriggerPrice:   triggerPrice,
+		MaxSlippage:    maxSlippage,
+		TimeInForce:    req.GetTimeInForce(),
+		ExpiresAt:      req.ExpiresAt,
 	}
 
-	// 6. Guardar en base de datos
-	if err := s.orderRepo.Create(ctx, order); err != nil {
-		return nil, fmt.Errorf("failed to save order: %w", err)
+	// Las órdenes condicionales no pueden dispararse apenas se crean
+	if order.OrderKind.HasTriggerPrice() {
+		currentPrice, err := s.marketService.GetCurrentPrice(ctx, req.CryptoSymbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current price: %w", err)
+		}
+		if order.IsTriggerReached(currentPrice) {
+			return nil, fmt.Errorf("%w: trigger_price %s would trigger immediately (current price %s)", ErrOrderValidation,
+				triggerPrice.String(), currentPrice.String())
+		}
 	}
 
-	// 7. Publicar evento de creación (no bloquea si falla)
-	if err := s.publisher.PublishOrderCreated(ctx, order); err != nil {
-		log.Printf("Warning: failed to publish order created event: %v", err)
+	// Comisión estimada con la tabla vigente (se recalcula al ejecutar)
+	if err := s.executionService.EstimateFee(ctx, order); err != nil {
+		return nil, err
 	}
 
-	// 8. Si es market order, ejecutar inmediatamente de forma síncrona
-	if req.OrderKind == models.OrderKindMarket {
-		// Get user token from context if available
-		execCtx := ctx
-		if userToken := ctx.Value("user_token"); userToken != nil {
-			execCtx = context.WithValue(execCtx, "user_token", userToken)
+	return order, nil
+}
+
+// marketOrderPrice retorna el precio de referencia de una orden market: el de su
+// cotización firmada (que se verifica) o, si no trae y no es obligatoria, el actual
+func (s *OrderServiceSimple) marketOrderPrice(ctx context.Context, req *dto.CreateOrderRequest, userID int) (decimal.Decimal, error) {
+	if req.QuoteID == "" {
+		if s.quotes != nil && s.quotes.IsRequired() {
+			return decimal.Zero, fmt.Errorf("%w: quote_id is required for market orders", ErrOrderValidation)
 		}
 
-		if err := s.executeOrderSync(execCtx, order); err != nil {
-			log.Printf("Warning: failed to execute market order: %v", err)
-			// La orden queda en pending, el usuario puede ver el error
+		currentPrice, err := s.marketService.GetCurrentPrice(ctx, req.CryptoSymbol)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("failed to get current price: %w", err)
 		}
+		return currentPrice, nil
 	}
 
-	return order, nil
+	if s.quotes == nil {
+		return decimal.Zero, fmt.Errorf("%w: quotes are not enabled", ErrOrderValidation)
+	}
+
+	quote, err := s.quotes.VerifyQuote(req.QuoteID, userID, req.CryptoSymbol, req.Type)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	log.Printf("📊 Using quote %s for %s (expires %s)", quote.Price.String(), req.CryptoSymbol, quote.ExpiresAt.Format(time.RFC3339))
+	return quote.Price, nil
 }
 
-// executeOrderSync ejecuta una orden de forma síncrona
+// executeOrderSync ejecuta una orden de forma síncrona mediante la saga de ejecución
 func (s *OrderServiceSimple) executeOrderSync(ctx context.Context, order *models.Order) error {
 	// Ejecutar orden con timeout
 	execCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	result, err := s.executionService.ExecuteOrder(execCtx, order)
-	if err != nil {
-		// Marcar orden como fallida
-		order.Status = models.OrderStatusFailed
-		order.ErrorMessage = err.Error()
-		order.UpdatedAt = time.Now()
+	return s.saga.Execute(execCtx, order)
+}
 
-		s.orderRepo.Update(ctx, order)
-		s.publisher.PublishOrderFailed(ctx, order, err.Error())
-		return err
+// ExecutePendingOrder ejecuta una orden abierta cuyo precio fue alcanzado.
+// Las órdenes stop / take profit pendientes primero pasan a triggered; una
+// stop_limit disparada queda esperando su precio límite.
+// Retorna ErrTriggerPriceNotReached o ErrLimitPriceNotReached si el precio se movió.
+func (s *OrderServiceSimple) ExecutePendingOrder(ctx context.Context, order *models.Order) error {
+	if !order.IsOpen() {
+		return fmt.Errorf("order is not pending (status: %s)", order.Status)
 	}
 
-	// Actualizar orden con resultado exitoso
-	order.Status = models.OrderStatusExecuted
-	order.Price = result.ExecutedPrice
-	order.TotalAmount = result.TotalAmount
-	order.Fee = result.Fee
-	now := time.Now()
-	order.ExecutedAt = &now
-	order.UpdatedAt = now
-
-	if err := s.orderRepo.Update(ctx, order); err != nil {
-		return fmt.Errorf("failed to update executed order: %w", err)
-	}
+	if order.Status == models.OrderStatusPending && order.OrderKind.HasTriggerPrice() && order.OrderKind != models.OrderKindOCO {
+		price, err := s.marketService.GetCurrentPrice(ctx, order.CryptoSymbol)
+		if err != nil {
+			return fmt.Errorf("failed to get current price: %w", err)
+		}
+		if !order.ShouldTrigger(price) {
+			return ErrTriggerPriceNotReached
+		}
 
-	// Publicar evento de ejecución
-	if err := s.publisher.PublishOrderExecuted(ctx, order); err != nil {
-		log.Printf("Warning: failed to publish order executed event: %v", err)
+		order.MarkTriggered(time.Now())
+		if err := s.orderRepo.Update(ctx, order); err != nil {
+			return fmt.Errorf("failed to mark order as triggered: %w", err)
+		}
+		log.Printf("🎯 Order %s triggered at %s (trigger %s)", order.OrderNumber, price.String(), order.TriggerPrice.String())
 	}
 
-	return nil
+	return s.executeOrderSync(ctx, order)
 }
 
 // GetOrder obtiene una orden por ID
@@ -213,13 +577,35 @@ func (s *OrderServiceSimple) GetOrder(ctx context.Context, orderID string, userI
 	return order, nil
 }
 
-// ListUserOrders lista las órdenes de un usuario con filtros
-func (s *OrderServiceSimple) ListUserOrders(ctx context.Context, userID int, filter *dto.OrderFilterRequest) ([]models.Order, int64, *dto.OrdersSummary, error) {
+// GetOrderFills retorna los fills de una orden del usuario en el orden en que se llenaron
+func (s *OrderServiceSimple) GetOrderFills(ctx context.Context, orderID string, userID int) ([]models.Fill, error) {
+	order, err := s.GetOrder(ctx, orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.fillRepo == nil {
+		return nil, fmt.Errorf("fill history is not enabled")
+	}
+
+	fills, err := s.fillRepo.ListByOrder(ctx, order.ID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fills: %w", err)
+	}
+
+	return fills, nil
+}
+
+// ListUserOrders lista una página de órdenes del usuario con filtros
+func (s *OrderServiceSimple) ListUserOrders(ctx context.Context, userID int, filter *dto.OrderFilterRequest) (*dto.OrderPage, *dto.OrdersSummary, error) {
 	filter.SetDefaults()
+	if err := filter.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrOrderValidation, err)
+	}
 
-	orders, total, err := s.orderRepo.ListByUser(ctx, userID, filter)
+	page, err := s.orderRepo.ListByUser(ctx, userID, filter)
 	if err != nil {
-		return nil, 0, nil, fmt.Errorf("failed to list orders: %w", err)
+		return nil, nil, fmt.Errorf("failed to list orders: %w", err)
 	}
 
 	summary, err := s.orderRepo.GetOrdersSummary(ctx, userID)
@@ -228,35 +614,301 @@ func (s *OrderServiceSimple) ListUserOrders(ctx context.Context, userID int, fil
 		summary = &dto.OrdersSummary{}
 	}
 
-	return orders, total, summary, nil
+	return page, summary, nil
+}
+
+// ExportFilledOrders recorre las órdenes del usuario con algo llenado en el rango pedido
+// (por último fill) y llama a fn con la fila de cada una, sin cargarlas todas en memoria
+func (s *OrderServiceSimple) ExportFilledOrders(ctx context.Context, userID int, req *dto.OrderExportRequest, fn func(row dto.OrderExportRow) error) error {
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrOrderValidation, err)
+	}
+
+	return s.orderRepo.StreamFilled(ctx, userID, req.From, req.To, func(order *models.Order) error {
+		return fn(dto.NewOrderExportRow(order))
+	})
 }
 
-// CancelOrder cancela una orden pendiente
+// CancelOrder cancela una orden pendiente del usuario
 func (s *OrderServiceSimple) CancelOrder(ctx context.Context, orderID string, userID int, reason string) error {
+	order, err := s.GetOrder(ctx, orderID, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.cancelOrder(ctx, order, reason)
+}
+
+// AdminCancelOrder cancela una orden pendiente de cualquier usuario
+func (s *OrderServiceSimple) AdminCancelOrder(ctx context.Context, orderID string, reason string) error {
 	order, err := s.orderRepo.GetByID(ctx, orderID)
 	if err != nil {
 		return fmt.Errorf("order not found: %w", err)
 	}
 
-	if order.UserID != userID {
-		return fmt.Errorf("access denied: order does not belong to user")
-	}
+	return s.cancelOrder(ctx, order, reason)
+}
 
+// cancelOrder marca la orden como cancelada y libera los fondos reservados.
+// El update usa la versión de la orden, así que si el matcher la tomó
+// en el medio la cancelación falla con ErrVersionConflict.
+func (s *OrderServiceSimple) cancelOrder(ctx context.Context, order *models.Order, reason string) error {
 	if !order.IsCancellable() {
 		return fmt.Errorf("order cannot be cancelled (status: %s)", order.Status)
 	}
 
+	if order.IsLocked(time.Now()) {
+		return fmt.Errorf("order cannot be cancelled: execution in progress")
+	}
+
+	previousStatus := order.Status
 	order.Status = models.OrderStatusCancelled
 	order.UpdatedAt = time.Now()
 
-	if err := s.orderRepo.Update(ctx, order); err != nil {
+	err := writeWithEvent(ctx, s.tx, order,
+		func(txCtx context.Context) error { return s.orderRepo.Update(txCtx, order) },
+		func(txCtx context.Context) error { return s.publisher.PublishOrderCancelled(txCtx, order, reason) },
+	)
+	if err != nil {
+		order.Status = previousStatus
 		return fmt.Errorf("failed to cancel order: %w", err)
 	}
 
-	// Publicar evento de cancelación
-	if err := s.publisher.PublishOrderCancelled(ctx, order, reason); err != nil {
-		log.Printf("Warning: failed to publish order cancelled event: %v", err)
+	// Devolver los fondos reservados (la liberación es idempotente, se puede reintentar)
+	releaseErr := s.executionService.ReleaseFunds(ctx, order)
+
+	if releaseErr != nil {
+		return fmt.Errorf("order cancelled but failed to release reserved funds: %w", releaseErr)
+	}
+
+	return nil
+}
+
+// ExpireOrder marca como vencida una orden abierta (una gtd que pasó expires_at o lo que
+// quedó sin llenar de una IOC/FOK) y libera lo que seguía reservado. Los fills previos
+// se mantienen. No chequea el lock porque el sweeper la llama con la orden ya tomada;
+// el update usa la versión de la orden, así que pierde contra un fill concurrente.
+func (s *OrderServiceSimple) ExpireOrder(ctx context.Context, order *models.Order, reason string) error {
+	if !order.IsOpen() {
+		return fmt.Errorf("order cannot be expired (status: %s)", order.Status)
+	}
+
+	previous := *order
+	order.MarkExpired(time.Now())
+
+	err := writeWithEvent(ctx, s.tx, order,
+		func(txCtx context.Context) error { return s.orderRepo.Update(txCtx, order) },
+		func(txCtx context.Context) error { return s.publisher.PublishOrderExpired(txCtx, order, reason) },
+	)
+	if err != nil {
+		*order = previous
+		return fmt.Errorf("failed to expire order: %w", err)
+	}
+
+	log.Printf("⌛ Order %s expired with %s of %s filled: %s", order.OrderNumber,
+		order.FilledQuantity.String(), order.Quantity.String(), reason)
+
+	// Devolver lo que quedaba reservado (la liberación es idempotente, se puede reintentar)
+	if err := s.executionService.ReleaseFunds(ctx, order); err != nil {
+		return fmt.Errorf("order expired but failed to release reserved funds: %w", err)
 	}
 
 	return nil
 }
+
+// immediateExpiryReason arma el motivo de vencimiento de una IOC/FOK tras su primera pasada
+func immediateExpiryReason(order *models.Order, execErr error) string {
+	if execErr != nil && (errors.Is(execErr, ErrFillOrKillNotFilled) || errors.Is(execErr, ErrLimitPriceNotReached)) {
+		return fmt.Sprintf("%s order not filled: %v", order.TimeInForce, execErr)
+	}
+	return fmt.Sprintf("%s order: %s of %s left unfilled after the first pass", order.TimeInForce,
+		order.RemainingQuantity().String(), order.Quantity.String())
+}
+
+// AmendOrder modifica precio y/o cantidad de una orden limit pendiente del usuario
+func (s *OrderServiceSimple) AmendOrder(ctx context.Context, orderID string, userID int, req *dto.AmendOrderRequest) (*models.Order, error) {
+	order, err := s.GetOrder(ctx, orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.amendOrder(ctx, order, req, true)
+}
+
+// AdminAmendOrder modifica una orden limit pendiente de cualquier usuario
+func (s *OrderServiceSimple) AdminAmendOrder(ctx context.Context, orderID string, req *dto.AmendOrderRequest) (*models.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("order not found: %w", err)
+	}
+
+	return s.amendOrder(ctx, order, req, false)
+}
+
+// amendOrder recalcula total y comisión, ajusta la reserva de fondos y guarda
+// la orden con control optimista. Si el update pierde contra un fill, la
+// reserva vuelve a su monto anterior. Con checkRisk la orden modificada tiene que
+// cumplir los límites de riesgo del usuario (los admins no los tienen).
+func (s *OrderServiceSimple) amendOrder(ctx context.Context, order *models.Order, req *dto.AmendOrderRequest, checkRisk bool) (*models.Order, error) {
+	quantity, limitPrice, err := req.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOrderValidation, err)
+	}
+
+	if !order.IsAmendable() {
+		return nil, fmt.Errorf("order cannot be amended (status: %s, kind: %s)", order.Status, order.OrderKind)
+	}
+
+	if order.IsLocked(time.Now()) {
+		return nil, fmt.Errorf("order cannot be amended: execution in progress")
+	}
+
+	if req.Version != nil && *req.Version != order.Version {
+		return nil, fmt.Errorf("failed to amend order: %w", repositories.ErrVersionConflict)
+	}
+
+	previous := *order
+	if quantity != nil {
+		order.Quantity = *quantity
+	}
+	if limitPrice != nil {
+		order.Price = *limitPrice
+	}
+	order.TotalAmount = order.Quantity.Mul(order.Price)
+	if checkRisk {
+		if err := s.checkRisk(ctx, order, &previous); err != nil {
+			*order = previous
+			return nil, err
+		}
+	}
+	if err := s.executionService.EstimateFee(ctx, order); err != nil {
+		*order = previous
+		return nil, err
+	}
+
+	if err := s.executionService.AdjustReservedFunds(ctx, order); err != nil {
+		*order = previous
+		return nil, fmt.Errorf("failed to adjust reserved funds: %w", err)
+	}
+
+	err = writeWithEvent(ctx, s.tx, order,
+		func(txCtx context.Context) error { return s.orderRepo.Update(txCtx, order) },
+		func(txCtx context.Context) error { return s.publisher.PublishOrderAmended(txCtx, order, &previous) },
+	)
+	if err != nil {
+		if adjustErr := s.executionService.AdjustReservedFunds(ctx, &previous); adjustErr != nil {
+			log.Printf("Warning: failed to restore reserved funds for order %s: %v", order.ID.Hex(), adjustErr)
+		}
+		*order = previous
+		return nil, fmt.Errorf("failed to amend order: %w", err)
+	}
+
+	return order, nil
+}
+
+// AdminListOrders lista una página de órdenes de todos los usuarios (o de filter.UserID)
+func (s *OrderServiceSimple) AdminListOrders(ctx context.Context, filter *dto.AdminOrderFilterRequest) (*dto.OrderPage, error) {
+	filter.SetDefaults()
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOrderValidation, err)
+	}
+
+	page, err := s.orderRepo.ListAll(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+
+	return page, nil
+}
+
+// AdminGetOrder obtiene una orden de cualquier usuario
+func (s *OrderServiceSimple) AdminGetOrder(ctx context.Context, orderID string) (*models.Order, error) {
+	order, err := s.orderRepo.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("order not found: %w", err)
+	}
+
+	return order, nil
+}
+
+// AdminFailOrders marca como fallidas órdenes trabadas de cualquier usuario y libera
+// sus reservas. Cada orden se procesa por separado: las que no se pueden fallar se
+// informan en Skipped con el motivo y no frenan al resto.
+func (s *OrderServiceSimple) AdminFailOrders(ctx context.Context, req *dto.BulkFailOrdersRequest) (*dto.BulkFailResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOrderValidation, err)
+	}
+
+	result := &dto.BulkFailResult{
+		Failed:  []string{},
+		Skipped: []dto.SkippedOrder{},
+	}
+	seen := make(map[string]bool, len(req.OrderIDs))
+
+	for _, orderID := range req.OrderIDs {
+		if seen[orderID] {
+			continue
+		}
+		seen[orderID] = true
+
+		order, err := s.orderRepo.GetByID(ctx, orderID)
+		if err != nil {
+			result.Skipped = append(result.Skipped, dto.SkippedOrder{OrderID: orderID, Reason: "order not found"})
+			continue
+		}
+
+		if err := s.failStuckOrder(ctx, order, req.Reason); err != nil {
+			result.Skipped = append(result.Skipped, dto.SkippedOrder{OrderID: orderID, Reason: err.Error()})
+			continue
+		}
+
+		result.Failed = append(result.Failed, orderID)
+	}
+
+	log.Printf("🧹 Admin bulk fail: %d orders failed, %d skipped (%s)", len(result.Failed), len(result.Skipped), req.Reason)
+	return result, nil
+}
+
+// failStuckOrder marca la orden como fallida y libera su reserva. No toca órdenes
+// finales, tomadas por una instancia, ni ejecuciones con la saga a medio camino:
+// esas las termina o compensa el recoverer, fallarlas dejaría saldo o holdings
+// movidos sin orden que los respalde.
+func (s *OrderServiceSimple) failStuckOrder(ctx context.Context, order *models.Order, reason string) error {
+	if order.IsFinal() {
+		return fmt.Errorf("order cannot be failed (status: %s)", order.Status)
+	}
+
+	if order.IsLocked(time.Now()) {
+		return fmt.Errorf("order cannot be failed: execution in progress")
+	}
+
+	if order.Status == models.OrderStatusExecuting && order.Saga != nil && !order.Saga.IsFinished() {
+		return fmt.Errorf("order cannot be failed: execution stopped at step %s, the saga recoverer has to finish it", order.Saga.Step)
+	}
+
+	previousStatus, previousError := order.Status, order.ErrorMessage
+	order.Status = models.OrderStatusFailed
+	order.ErrorMessage = reason
+	order.UpdatedAt = time.Now()
+
+	err := writeWithEvent(ctx, s.tx, order,
+		func(txCtx context.Context) error { return s.orderRepo.Update(txCtx, order) },
+		func(txCtx context.Context) error { return s.publisher.PublishOrderFailed(txCtx, order, reason) },
+	)
+	if err != nil {
+		order.Status, order.ErrorMessage = previousStatus, previousError
+		return fmt.Errorf("failed to mark order as failed: %w", err)
+	}
+
+	// La orden ya quedó fallida; la liberación es idempotente y se puede reintentar
+	if err := s.executionService.ReleaseFunds(ctx, order); err != nil {
+		log.Printf("Warning: failed to release funds for order %s: %v", order.ID.Hex(), err)
+	}
+
+	return nil
+}
+
+// GetAdminStatistics retorna volumen, comisiones cobradas y el desglose por símbolo
+func (s *OrderServiceSimple) GetAdminStatistics(ctx context.Context) (*dto.AdminStatistics, error) {
+	return s.orderRepo.GetAdminStatistics(ctx)
+}
@@ -3,15 +3,20 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"orders-api/internal/dto"
 	"orders-api/internal/models"
+	"orders-api/internal/repositories"
 )
 
 // Mock implementations
@@ -37,12 +42,40 @@ func (m *MockOrderRepository) GetByID(ctx context.Context, orderID string) (*mod
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
-func (m *MockOrderRepository) ListByUser(ctx context.Context, userID int, filter *dto.OrderFilterRequest) ([]models.Order, int64, error) {
+func (m *MockOrderRepository) ListByUser(ctx context.Context, userID int, filter *dto.OrderFilterRequest) (*dto.OrderPage, error) {
 	args := m.Called(ctx, userID, filter)
 	if args.Get(0) == nil {
-		return nil, args.Get(1).(int64), args.Error(2)
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.OrderPage), args.Error(1)
+}
+
+func (m *MockOrderRepository) ListAll(ctx context.Context, filter *dto.AdminOrderFilterRequest) (*dto.OrderPage, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.OrderPage), args.Error(1)
+}
+
+func (m *MockOrderRepository) GetAdminStatistics(ctx context.Context) (*dto.AdminStatistics, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.AdminStatistics), args.Error(1)
+}
+
+func (m *MockOrderRepository) StreamFilled(ctx context.Context, userID int, from, to *time.Time, fn func(order *models.Order) error) error {
+	args := m.Called(ctx, userID, from, to, fn)
+	if orders, ok := args.Get(0).([]models.Order); ok {
+		for i := range orders {
+			if err := fn(&orders[i]); err != nil {
+				return err
+			}
+		}
 	}
-	return args.Get(0).([]models.Order), args.Get(1).(int64), args.Error(2)
+	return args.Error(1)
 }
 
 func (m *MockOrderRepository) GetOrdersSummary(ctx context.Context, userID int) (*dto.OrdersSummary, error) {
@@ -53,6 +86,19 @@ func (m *MockOrderRepository) GetOrdersSummary(ctx context.Context, userID int)
 	return args.Get(0).(*dto.OrdersSummary), args.Error(1)
 }
 
+func (m *MockOrderRepository) GetUserVolume(ctx context.Context, userID int, since time.Time) (decimal.Decimal, error) {
+	args := m.Called(ctx, userID, since)
+	return args.Get(0).(decimal.Decimal), args.Error(1)
+}
+
+func (m *MockOrderRepository) GetOpenExposure(ctx context.Context, userID int, symbol string) (*models.OpenExposure, error) {
+	args := m.Called(ctx, userID, symbol)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OpenExposure), args.Error(1)
+}
+
 func (m *MockOrderRepository) Delete(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -66,13 +112,21 @@ func (m *MockOrderRepository) GetByOrderNumber(ctx context.Context, orderNumber
 	return args.Get(0).(*models.Order), args.Error(1)
 }
 
+func (m *MockOrderRepository) GetByIdempotencyKey(ctx context.Context, userID int, key string) (*models.Order, error) {
+	args := m.Called(ctx, userID, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
 func (m *MockOrderRepository) UpdateStatus(ctx context.Context, id string, status models.OrderStatus) error {
 	args := m.Called(ctx, id, status)
 	return args.Error(0)
 }
 
-func (m *MockOrderRepository) GetPendingOrders(ctx context.Context, limit int) ([]models.Order, error) {
-	args := m.Called(ctx, limit)
+func (m *MockOrderRepository) GetPendingOrders(ctx context.Context, afterID primitive.ObjectID, now time.Time, limit int) ([]models.Order, error) {
+	args := m.Called(ctx, afterID, now, limit)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -92,6 +146,35 @@ func (m *MockOrderRepository) BulkUpdateStatus(ctx context.Context, orderIDs []s
 	return args.Error(0)
 }
 
+func (m *MockOrderRepository) ClaimOrder(ctx context.Context, id string, owner string, ttl time.Duration) (*models.Order, error) {
+	args := m.Called(ctx, id, owner, ttl)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) ReleaseOrderClaim(ctx context.Context, id string, owner string) error {
+	args := m.Called(ctx, id, owner)
+	return args.Error(0)
+}
+
+func (m *MockOrderRepository) ClaimStalledExecution(ctx context.Context, staleBefore time.Time, owner string, ttl time.Duration) (*models.Order, error) {
+	args := m.Called(ctx, staleBefore, owner, ttl)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+func (m *MockOrderRepository) ClaimExpiredOrder(ctx context.Context, now time.Time, immediateBefore time.Time, owner string, ttl time.Duration) (*models.Order, error) {
+	args := m.Called(ctx, now, immediateBefore, owner, ttl)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
 type MockMarketService struct {
 	mock.Mock
 }
@@ -123,19 +206,131 @@ func (m *MockEventPublisher) PublishOrderExecuted(ctx context.Context, order *mo
 	return args.Error(0)
 }
 
+func (m *MockEventPublisher) PublishOrderPartiallyFilled(ctx context.Context, order *models.Order) error {
+	args := m.Called(ctx, order)
+	return args.Error(0)
+}
+
 func (m *MockEventPublisher) PublishOrderCancelled(ctx context.Context, order *models.Order, reason string) error {
 	args := m.Called(ctx, order, reason)
 	return args.Error(0)
 }
 
+func (m *MockEventPublisher) PublishOrderAmended(ctx context.Context, order *models.Order, previous *models.Order) error {
+	args := m.Called(ctx, order, previous)
+	return args.Error(0)
+}
+
 func (m *MockEventPublisher) PublishOrderFailed(ctx context.Context, order *models.Order, reason string) error {
 	args := m.Called(ctx, order, reason)
 	return args.Error(0)
 }
 
+func (m *MockEventPublisher) PublishOrderExpired(ctx context.Context, order *models.Order, reason string) error {
+	args := m.Called(ctx, order, reason)
+	return args.Error(0)
+}
+
+type MockUserClient struct {
+	mock.Mock
+}
+
+func (m *MockUserClient) VerifyUser(ctx context.Context, userID int) (*models.ValidationResult, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ValidationResult), args.Error(1)
+}
+
+type MockUserBalanceClient struct {
+	mock.Mock
+}
+
+func (m *MockUserBalanceClient) CheckBalance(ctx context.Context, userID int, amount decimal.Decimal, userToken string) (*models.BalanceResult, error) {
+	args := m.Called(ctx, userID, amount, userToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceResult), args.Error(1)
+}
+
+func (m *MockUserBalanceClient) ProcessTransaction(ctx context.Context, userID int, amount decimal.Decimal, transactionType, orderID, description string) (string, error) {
+	args := m.Called(ctx, userID, amount, transactionType, orderID, description)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserBalanceClient) LockFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) error {
+	args := m.Called(ctx, userID, orderID, amount)
+	return args.Error(0)
+}
+
+func (m *MockUserBalanceClient) AdjustFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) error {
+	args := m.Called(ctx, userID, orderID, amount)
+	return args.Error(0)
+}
+
+func (m *MockUserBalanceClient) CaptureFunds(ctx context.Context, userID int, orderID string, amount decimal.Decimal) (string, error) {
+	args := m.Called(ctx, userID, orderID, amount)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserBalanceClient) CaptureFundsPartial(ctx context.Context, userID int, orderID, fillID string, amount decimal.Decimal) (string, error) {
+	args := m.Called(ctx, userID, orderID, fillID, amount)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserBalanceClient) ReleaseFunds(ctx context.Context, userID int, orderID string) error {
+	args := m.Called(ctx, userID, orderID)
+	return args.Error(0)
+}
+
+type MockPortfolioClient struct {
+	mock.Mock
+}
+
+func (m *MockPortfolioClient) ReserveHoldings(ctx context.Context, userID int64, symbol, orderID string, quantity decimal.Decimal) error {
+	args := m.Called(ctx, userID, symbol, orderID, quantity)
+	return args.Error(0)
+}
+
+func (m *MockPortfolioClient) ReleaseHoldings(ctx context.Context, userID int64, symbol, orderID string) error {
+	args := m.Called(ctx, userID, symbol, orderID)
+	return args.Error(0)
+}
+
+func (m *MockPortfolioClient) UpdateHoldings(ctx context.Context, userID int64, symbol string, quantity, price decimal.Decimal, orderType, tradeID, reservationID string) error {
+	args := m.Called(ctx, userID, symbol, quantity, price, orderType, tradeID, reservationID)
+	return args.Error(0)
+}
+
+func (m *MockPortfolioClient) RevertHoldings(ctx context.Context, userID int64, orderID string) error {
+	args := m.Called(ctx, userID, orderID)
+	return args.Error(0)
+}
+
+func (m *MockPortfolioClient) GetHolding(ctx context.Context, userID int64, symbol string) (*models.HoldingResult, error) {
+	args := m.Called(ctx, userID, symbol)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HoldingResult), args.Error(1)
+}
+
 // Helper function to create a test execution service with mocked dependencies
+// defaultTestFees comisión histórica: 0.1% con mínimo de 0.01
+var defaultTestFees = staticFeeCalculator{schedule: models.DefaultFeeSchedule()}
+
 func createMockExecutionService() *ExecutionService {
-	return &ExecutionService{}
+	balanceClient := new(MockUserBalanceClient)
+	balanceClient.On("LockFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	balanceClient.On("ReleaseFunds", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	portfolioClient := new(MockPortfolioClient)
+	portfolioClient.On("ReserveHoldings", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	portfolioClient.On("ReleaseHoldings", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	return &ExecutionService{fees: defaultTestFees, userBalanceClient: balanceClient, portfolioClient: portfolioClient}
 }
 
 // Test CreateOrder
@@ -242,7 +437,6 @@ func TestOrderServiceSimple_CreateOrder(t *testing.T) {
 			CryptoSymbol: "INVALID",
 			Quantity:     "1.0",
 			OrderKind:    models.OrderKindMarket,
-			MarketPrice:  "1000.00",
 		}
 
 		mockMarket.On("ValidateSymbol", ctx, "INVALID").Return(nil, errors.New("symbol not found"))
@@ -252,6 +446,7 @@ func TestOrderServiceSimple_CreateOrder(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, order)
 		assert.Contains(t, err.Error(), "invalid crypto symbol")
+		assert.ErrorIs(t, err, ErrInvalidSymbol)
 
 		mockMarket.AssertExpectations(t)
 	})
@@ -269,7 +464,6 @@ func TestOrderServiceSimple_CreateOrder(t *testing.T) {
 			CryptoSymbol: "SUSPENDED",
 			Quantity:     "1.0",
 			OrderKind:    models.OrderKindMarket,
-			MarketPrice:  "100.00",
 		}
 
 		cryptoInfo := &CryptoInfo{
@@ -286,6 +480,7 @@ func TestOrderServiceSimple_CreateOrder(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, order)
 		assert.Contains(t, err.Error(), "trading is suspended")
+		assert.ErrorIs(t, err, ErrTradingSuspended)
 
 		mockMarket.AssertExpectations(t)
 	})
@@ -303,7 +498,6 @@ func TestOrderServiceSimple_CreateOrder(t *testing.T) {
 			CryptoSymbol: "BTC",
 			Quantity:     "0.5",
 			OrderKind:    models.OrderKindMarket,
-			MarketPrice:  "50000.00",
 		}
 
 		cryptoInfo := &CryptoInfo{
@@ -314,6 +508,7 @@ func TestOrderServiceSimple_CreateOrder(t *testing.T) {
 		}
 
 		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(cryptoInfo, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(decimal.NewFromInt(50000), nil)
 		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.Order")).Return(errors.New("database error"))
 
 		order, err := service.CreateOrder(ctx, req, 1)
@@ -325,162 +520,695 @@ func TestOrderServiceSimple_CreateOrder(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 		mockMarket.AssertExpectations(t)
 	})
-}
-
-// Test GetOrder
-func TestOrderServiceSimple_GetOrder(t *testing.T) {
-	ctx := context.Background()
 
-	t.Run("successful order retrieval", func(t *testing.T) {
+	t.Run("insufficient balance to reserve funds", func(t *testing.T) {
 		mockRepo := new(MockOrderRepository)
-		mockExec := createMockExecutionService()
 		mockMarket := new(MockMarketService)
 		mockPublisher := new(MockEventPublisher)
+		balanceClient := new(MockUserBalanceClient)
+		mockExec := &ExecutionService{fees: defaultTestFees, userBalanceClient: balanceClient}
 
 		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
 
-		orderID := primitive.NewObjectID().Hex()
-		expectedOrder := &models.Order{
-			ID:           primitive.NewObjectID(),
-			OrderNumber:  "ORD-123",
-			UserID:       1,
+		req := &dto.CreateOrderRequest{
 			Type:         models.OrderTypeBuy,
 			CryptoSymbol: "BTC",
-			Status:       models.OrderStatusExecuted,
+			Quantity:     "2",
+			OrderKind:    models.OrderKindLimit,
+			LimitPrice:   "50000.00",
 		}
 
-		mockRepo.On("GetByID", ctx, orderID).Return(expectedOrder, nil)
+		cryptoInfo := &CryptoInfo{
+			Symbol:       "BTC",
+			Name:         "Bitcoin",
+			CurrentPrice: decimal.NewFromInt(51000),
+			IsActive:     true,
+		}
 
-		order, err := service.GetOrder(ctx, orderID, 1)
+		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(cryptoInfo, nil)
+		balanceClient.On("LockFunds", ctx, 1, mock.AnythingOfType("string"), mock.MatchedBy(func(amount decimal.Decimal) bool {
+			return amount.Equal(decimal.NewFromInt(100100))
+		})).
+			Return(errors.New("insufficient balance: insufficient funds: required 100100.00, available 1000.00"))
 
-		assert.NoError(t, err)
-		assert.NotNil(t, order)
-		assert.Equal(t, expectedOrder.OrderNumber, order.OrderNumber)
+		order, err := service.CreateOrder(ctx, req, 1)
 
-		mockRepo.AssertExpectations(t)
+		assert.Error(t, err)
+		assert.Nil(t, order)
+		assert.Contains(t, err.Error(), "insufficient balance")
+
+		balanceClient.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
 	})
 
-	t.Run("order not found", func(t *testing.T) {
+	t.Run("replay with same idempotency key returns original order", func(t *testing.T) {
 		mockRepo := new(MockOrderRepository)
-		mockExec := createMockExecutionService()
 		mockMarket := new(MockMarketService)
 		mockPublisher := new(MockEventPublisher)
+		balanceClient := new(MockUserBalanceClient)
+		mockExec := &ExecutionService{fees: defaultTestFees, userBalanceClient: balanceClient}
 
 		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
 
-		orderID := primitive.NewObjectID().Hex()
+		req := &dto.CreateOrderRequest{
+			Type:           models.OrderTypeBuy,
+			CryptoSymbol:   "BTC",
+			Quantity:       "0.5",
+			OrderKind:      models.OrderKindLimit,
+			LimitPrice:     "50000.00",
+			IdempotencyKey: "key-123",
+		}
 
-		mockRepo.On("GetByID", ctx, orderID).Return(nil, errors.New("order not found"))
+		original := &models.Order{
+			ID:             primitive.NewObjectID(),
+			UserID:         1,
+			Status:         models.OrderStatusPending,
+			IdempotencyKey: "key-123",
+		}
 
-		order, err := service.GetOrder(ctx, orderID, 1)
+		mockRepo.On("GetByIdempotencyKey", ctx, 1, "key-123").Return(original, nil)
 
-		assert.Error(t, err)
-		assert.Nil(t, order)
-		assert.Contains(t, err.Error(), "order not found")
+		order, err := service.CreateOrder(ctx, req, 1)
 
-		mockRepo.AssertExpectations(t)
+		assert.NoError(t, err)
+		assert.Equal(t, original, order)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		mockMarket.AssertNotCalled(t, "ValidateSymbol", mock.Anything, mock.Anything)
+		balanceClient.AssertNotCalled(t, "LockFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 	})
 
-	t.Run("access denied - wrong user", func(t *testing.T) {
+	t.Run("concurrent request with same idempotency key wins the insert", func(t *testing.T) {
 		mockRepo := new(MockOrderRepository)
-		mockExec := createMockExecutionService()
 		mockMarket := new(MockMarketService)
 		mockPublisher := new(MockEventPublisher)
+		mockExec := createMockExecutionService()
 
 		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
 
-		orderID := primitive.NewObjectID().Hex()
-		expectedOrder := &models.Order{
-			ID:           primitive.NewObjectID(),
-			OrderNumber:  "ORD-123",
-			UserID:       2,
-			Type:         models.OrderTypeBuy,
-			CryptoSymbol: "BTC",
-			Status:       models.OrderStatusExecuted,
+		req := &dto.CreateOrderRequest{
+			Type:           models.OrderTypeBuy,
+			CryptoSymbol:   "BTC",
+			Quantity:       "0.5",
+			OrderKind:      models.OrderKindLimit,
+			LimitPrice:     "50000.00",
+			IdempotencyKey: "key-456",
 		}
 
-		mockRepo.On("GetByID", ctx, orderID).Return(expectedOrder, nil)
+		cryptoInfo := &CryptoInfo{
+			Symbol:       "BTC",
+			Name:         "Bitcoin",
+			CurrentPrice: decimal.NewFromInt(51000),
+			IsActive:     true,
+		}
 
-		order, err := service.GetOrder(ctx, orderID, 1)
+		winner := &models.Order{
+			ID:             primitive.NewObjectID(),
+			UserID:         1,
+			Status:         models.OrderStatusPending,
+			IdempotencyKey: "key-456",
+		}
 
-		assert.Error(t, err)
-		assert.Nil(t, order)
-		assert.Contains(t, err.Error(), "access denied")
+		mockRepo.On("GetByIdempotencyKey", ctx, 1, "key-456").Return(nil, nil).Once()
+		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(cryptoInfo, nil)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.Order")).Return(repositories.ErrDuplicateIdempotencyKey)
+		mockRepo.On("GetByIdempotencyKey", ctx, 1, "key-456").Return(winner, nil).Once()
+
+		order, err := service.CreateOrder(ctx, req, 1)
 
+		assert.NoError(t, err)
+		assert.Equal(t, winner, order)
 		mockRepo.AssertExpectations(t)
+		mockPublisher.AssertNotCalled(t, "PublishOrderCreated", mock.Anything, mock.Anything)
 	})
-}
-
-// Test CancelOrder
-func TestOrderServiceSimple_CancelOrder(t *testing.T) {
-	ctx := context.Background()
 
-	t.Run("successful order cancellation", func(t *testing.T) {
+	t.Run("stop order that would trigger immediately is rejected", func(t *testing.T) {
 		mockRepo := new(MockOrderRepository)
-		mockExec := createMockExecutionService()
 		mockMarket := new(MockMarketService)
-		mockPublisher := new(MockEventPublisher)
-
-		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), mockMarket, new(MockEventPublisher))
 
-		orderID := primitive.NewObjectID().Hex()
-		order := &models.Order{
-			ID:           primitive.NewObjectID(),
-			OrderNumber:  "ORD-123",
-			UserID:       1,
-			Type:         models.OrderTypeBuy,
+		req := &dto.CreateOrderRequest{
+			Type:         models.OrderTypeSell,
 			CryptoSymbol: "BTC",
-			Status:       models.OrderStatusPending,
+			Quantity:     "0.5",
+			OrderKind:    models.OrderKindStopMarket,
+			TriggerPrice: "52000",
 		}
 
-		mockRepo.On("GetByID", ctx, orderID).Return(order, nil)
-		mockRepo.On("Update", ctx, mock.AnythingOfType("*models.Order")).Return(nil)
-		mockPublisher.On("PublishOrderCancelled", ctx, mock.AnythingOfType("*models.Order"), "user requested").Return(nil)
-
-		err := service.CancelOrder(ctx, orderID, 1, "user requested")
+		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(&CryptoInfo{Symbol: "BTC", Name: "Bitcoin", IsActive: true}, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(decimal.NewFromInt(51000), nil)
 
-		assert.NoError(t, err)
+		order, err := service.CreateOrder(ctx, req, 1)
 
-		mockRepo.AssertExpectations(t)
-		mockPublisher.AssertExpectations(t)
+		assert.Error(t, err)
+		assert.Nil(t, order)
+		assert.Contains(t, err.Error(), "would trigger immediately")
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
 	})
 
-	t.Run("order not found", func(t *testing.T) {
+	t.Run("successful take profit creation", func(t *testing.T) {
 		mockRepo := new(MockOrderRepository)
-		mockExec := createMockExecutionService()
 		mockMarket := new(MockMarketService)
 		mockPublisher := new(MockEventPublisher)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), mockMarket, mockPublisher)
 
-		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
-
-		orderID := primitive.NewObjectID().Hex()
-
-		mockRepo.On("GetByID", ctx, orderID).Return(nil, errors.New("order not found"))
+		req := &dto.CreateOrderRequest{
+			Type:         models.OrderTypeSell,
+			CryptoSymbol: "BTC",
+			Quantity:     "0.5",
+			OrderKind:    models.OrderKindTakeProfit,
+			TriggerPrice: "60000",
+		}
 
-		err := service.CancelOrder(ctx, orderID, 1, "user requested")
+		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(&CryptoInfo{Symbol: "BTC", Name: "Bitcoin", IsActive: true}, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(decimal.NewFromInt(51000), nil)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.Order")).Return(nil)
+		mockPublisher.On("PublishOrderCreated", ctx, mock.AnythingOfType("*models.Order")).Return(nil)
 
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "order not found")
+		order, err := service.CreateOrder(ctx, req, 1)
 
+		assert.NoError(t, err)
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+		assert.True(t, order.TriggerPrice.Equal(decimal.NewFromInt(60000)))
+		assert.True(t, order.Price.Equal(decimal.NewFromInt(60000)))
 		mockRepo.AssertExpectations(t)
 	})
 
-	t.Run("access denied - wrong user", func(t *testing.T) {
-		mockRepo := new(MockOrderRepository)
-		mockExec := createMockExecutionService()
-		mockMarket := new(MockMarketService)
-		mockPublisher := new(MockEventPublisher)
-
-		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+	t.Run("idempotency key too long", func(t *testing.T) {
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
 
-		orderID := primitive.NewObjectID().Hex()
-		order := &models.Order{
-			ID:           primitive.NewObjectID(),
-			OrderNumber:  "ORD-123",
-			UserID:       2,
-			Type:         models.OrderTypeBuy,
-			CryptoSymbol: "BTC",
-			Status:       models.OrderStatusPending,
+		req := &dto.CreateOrderRequest{
+			Type:           models.OrderTypeBuy,
+			CryptoSymbol:   "BTC",
+			Quantity:       "0.5",
+			OrderKind:      models.OrderKindMarket,
+			IdempotencyKey: strings.Repeat("k", dto.MaxIdempotencyKeyLength+1),
+		}
+
+		order, err := service.CreateOrder(ctx, req, 1)
+
+		assert.Error(t, err)
+		assert.Nil(t, order)
+		assert.Contains(t, err.Error(), "idempotency key")
+	})
+
+	t.Run("gtd order requires a future expires_at", func(t *testing.T) {
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+		past := time.Now().Add(-time.Minute)
+
+		req := &dto.CreateOrderRequest{
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Quantity:     "0.5",
+			OrderKind:    models.OrderKindLimit,
+			LimitPrice:   "50000",
+			TimeInForce:  models.TimeInForceGTD,
+			ExpiresAt:    &past,
+		}
+
+		order, err := service.CreateOrder(ctx, req, 1)
+
+		assert.Error(t, err)
+		assert.Nil(t, order)
+		assert.Contains(t, err.Error(), "expires_at must be in the future")
+	})
+
+	t.Run("successful gtd order creation", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), mockMarket, mockPublisher)
+		expiresAt := time.Now().Add(time.Hour)
+
+		req := &dto.CreateOrderRequest{
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Quantity:     "0.5",
+			OrderKind:    models.OrderKindLimit,
+			LimitPrice:   "50000",
+			TimeInForce:  models.TimeInForceGTD,
+			ExpiresAt:    &expiresAt,
+		}
+
+		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(&CryptoInfo{Symbol: "BTC", Name: "Bitcoin", IsActive: true}, nil)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.Order")).Return(nil)
+		mockPublisher.On("PublishOrderCreated", ctx, mock.AnythingOfType("*models.Order")).Return(nil)
+
+		order, err := service.CreateOrder(ctx, req, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+		assert.Equal(t, models.TimeInForceGTD, order.TimeInForce)
+		assert.Equal(t, &expiresAt, order.ExpiresAt)
+	})
+
+	t.Run("fok limit order that cannot fill expires at creation", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+		execService := newBookTestService(51000, nil)
+		balanceClient := execService.userBalanceClient.(*MockUserBalanceClient)
+		service := NewOrderServiceSimple(mockRepo, execService, mockMarket, mockPublisher)
+
+		req := &dto.CreateOrderRequest{
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Quantity:     "0.5",
+			OrderKind:    models.OrderKindLimit,
+			LimitPrice:   "50000",
+			TimeInForce:  models.TimeInForceFOK,
+		}
+
+		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(&CryptoInfo{Symbol: "BTC", Name: "Bitcoin", IsActive: true}, nil)
+		balanceClient.On("LockFunds", ctx, 1, mock.Anything, mock.Anything).Return(nil)
+		balanceClient.On("ReleaseFunds", mock.Anything, 1, mock.Anything).Return(nil)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.Order")).Return(nil)
+		mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Order")).Return(nil)
+		mockPublisher.On("PublishOrderCreated", ctx, mock.AnythingOfType("*models.Order")).Return(nil)
+		mockPublisher.On("PublishOrderExpired", mock.Anything, mock.AnythingOfType("*models.Order"), mock.Anything).Return(nil)
+
+		order, err := service.CreateOrder(ctx, req, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.OrderStatusExpired, order.Status)
+		assert.NotNil(t, order.ExpiredAt)
+		assert.True(t, order.FilledQuantity.IsZero())
+		balanceClient.AssertCalled(t, "ReleaseFunds", mock.Anything, 1, order.ID.Hex())
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("ioc is not allowed for conditional orders", func(t *testing.T) {
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		req := &dto.CreateOrderRequest{
+			Type:         models.OrderTypeSell,
+			CryptoSymbol: "BTC",
+			Quantity:     "0.5",
+			OrderKind:    models.OrderKindStopMarket,
+			TriggerPrice: "45000",
+			TimeInForce:  models.TimeInForceIOC,
+		}
+
+		_, err := service.CreateOrder(ctx, req, 1)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "only allowed for market and limit orders")
+	})
+
+	t.Run("risk rejection reserves nothing", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketService)
+		balanceClient := new(MockUserBalanceClient)
+		mockExec := &ExecutionService{fees: defaultTestFees, userBalanceClient: balanceClient}
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, new(MockEventPublisher))
+		risk, _, _, _ := newRiskTestService(models.RiskLimits{MaxOrderNotional: decimal.NewFromInt(10000)})
+		service.SetRiskChecker(risk)
+
+		req := &dto.CreateOrderRequest{
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Quantity:     "0.5",
+			OrderKind:    models.OrderKindLimit,
+			LimitPrice:   "50000",
+		}
+
+		mockMarket.On("ValidateSymbol", mock.Anything, "BTC").Return(&CryptoInfo{Symbol: "BTC", Name: "Bitcoin", IsActive: true}, nil)
+
+		_, err := service.CreateOrder(context.WithValue(ctx, "user_role", "user"), req, 1)
+
+		var rejection *models.RiskRejection
+		require.ErrorAs(t, err, &rejection)
+		assert.Equal(t, models.RiskReasonMaxOrderNotional, rejection.Code)
+		balanceClient.AssertNotCalled(t, "LockFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestOrderServiceSimple_ExpireOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("partially filled order expires and releases the rest", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockPublisher := new(MockEventPublisher)
+		mockExec := createMockExecutionService()
+		service := NewOrderServiceSimple(mockRepo, mockExec, new(MockMarketService), mockPublisher)
+
+		order := newBookOrder(models.OrderTypeBuy, 50000, 2)
+		order.Status = models.OrderStatusPartiallyFilled
+		order.FilledQuantity = decimal.NewFromInt(1)
+		order.TimeInForce = models.TimeInForceGTD
+
+		mockRepo.On("Update", ctx, order).Return(nil)
+		mockPublisher.On("PublishOrderExpired", ctx, order, "order expired").Return(nil)
+
+		err := service.ExpireOrder(ctx, order, "order expired")
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.OrderStatusExpired, order.Status)
+		assert.True(t, order.FilledQuantity.Equal(decimal.NewFromInt(1)))
+		mockExec.userBalanceClient.(*MockUserBalanceClient).AssertCalled(t, "ReleaseFunds", ctx, 1, order.ID.Hex())
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("version conflict keeps the order open", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockExec := createMockExecutionService()
+		service := NewOrderServiceSimple(mockRepo, mockExec, new(MockMarketService), new(MockEventPublisher))
+
+		order := newBookOrder(models.OrderTypeSell, 50000, 1)
+		mockRepo.On("Update", ctx, order).Return(repositories.ErrVersionConflict)
+
+		err := service.ExpireOrder(ctx, order, "order expired")
+
+		assert.ErrorIs(t, err, repositories.ErrVersionConflict)
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+		assert.Nil(t, order.ExpiredAt)
+		mockExec.portfolioClient.(*MockPortfolioClient).AssertNotCalled(t, "ReleaseHoldings", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("executed order cannot expire", func(t *testing.T) {
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+		order := newBookOrder(models.OrderTypeBuy, 50000, 1)
+		order.Status = models.OrderStatusExecuted
+
+		err := service.ExpireOrder(ctx, order, "order expired")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be expired")
+	})
+}
+
+// Test GetOrder
+func TestOrderServiceSimple_SimulateOrder(t *testing.T) {
+	ctx := context.Background()
+	btc := &CryptoInfo{Symbol: "BTC", Name: "Bitcoin", CurrentPrice: decimal.NewFromInt(50000), IsActive: true}
+
+	newSimulationService := func() (*OrderServiceSimple, *MockOrderRepository, *MockUserBalanceClient, *MockPortfolioClient) {
+		execService, balanceClient, portfolioClient := newExecutionTestService()
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketService)
+		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(btc, nil)
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(decimal.NewFromInt(50000), nil)
+
+		return NewOrderServiceSimple(mockRepo, execService, mockMarket, new(MockEventPublisher)), mockRepo, balanceClient, portfolioClient
+	}
+
+	t.Run("market buy would fill", func(t *testing.T) {
+		service, mockRepo, balanceClient, _ := newSimulationService()
+		balanceClient.On("CheckBalance", ctx, 1, decimalEq(50050), "").
+			Return(&models.BalanceResult{HasSufficient: true, Available: decimal.NewFromInt(100000)}, nil)
+
+		sim, err := service.SimulateOrder(ctx, &dto.CreateOrderRequest{
+			Type: models.OrderTypeBuy, CryptoSymbol: "BTC", Quantity: "1", OrderKind: models.OrderKindMarket,
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.True(t, sim.WouldFill)
+		assert.Empty(t, sim.RejectionReason)
+		assert.True(t, sim.EstimatedPrice.Equal(decimal.NewFromInt(50000)))
+		assert.True(t, sim.EstimatedFee.Equal(decimal.NewFromInt(50)))
+		assert.True(t, sim.EstimatedTotal.Equal(decimal.NewFromInt(50050)))
+		assert.True(t, sim.ResultingBalance.Equal(decimal.NewFromInt(49950)))
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		balanceClient.AssertNotCalled(t, "LockFunds", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("insufficient balance is a rejection", func(t *testing.T) {
+		service, _, balanceClient, _ := newSimulationService()
+		balanceClient.On("CheckBalance", ctx, 1, decimalEq(50050), "").
+			Return(&models.BalanceResult{HasSufficient: false, Available: decimal.NewFromInt(1000)}, nil)
+
+		sim, err := service.SimulateOrder(ctx, &dto.CreateOrderRequest{
+			Type: models.OrderTypeBuy, CryptoSymbol: "BTC", Quantity: "1", OrderKind: models.OrderKindMarket,
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.False(t, sim.WouldFill)
+		assert.Contains(t, sim.RejectionReason, "insufficient balance")
+	})
+
+	t.Run("limit sell rests and checks holdings", func(t *testing.T) {
+		service, _, balanceClient, portfolioClient := newSimulationService()
+		portfolioClient.On("GetHolding", ctx, int64(1), "BTC").
+			Return(&models.HoldingResult{Symbol: "BTC", Quantity: decimal.NewFromInt(2), Available: decimal.NewFromInt(2)}, nil)
+		balanceClient.On("CheckBalance", ctx, 1, decimal.Zero, "").
+			Return(&models.BalanceResult{HasSufficient: true, Available: decimal.NewFromInt(1000)}, nil)
+
+		sim, err := service.SimulateOrder(ctx, &dto.CreateOrderRequest{
+			Type: models.OrderTypeSell, CryptoSymbol: "BTC", Quantity: "1", OrderKind: models.OrderKindLimit, LimitPrice: "60000",
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.False(t, sim.WouldFill)
+		assert.Empty(t, sim.RejectionReason)
+		assert.True(t, sim.EstimatedPrice.Equal(decimal.NewFromInt(60000)))
+		assert.True(t, sim.ResultingHoldings.Equal(decimal.NewFromInt(1)))
+		assert.True(t, sim.ResultingBalance.Equal(decimal.NewFromInt(1000)))
+		assert.Len(t, sim.Warnings, 1)
+	})
+
+	t.Run("insufficient holdings is a rejection", func(t *testing.T) {
+		service, _, _, portfolioClient := newSimulationService()
+		portfolioClient.On("GetHolding", ctx, int64(1), "BTC").
+			Return(&models.HoldingResult{Symbol: "BTC", Available: decimal.NewFromFloat(0.5)}, nil)
+
+		sim, err := service.SimulateOrder(ctx, &dto.CreateOrderRequest{
+			Type: models.OrderTypeSell, CryptoSymbol: "BTC", Quantity: "1", OrderKind: models.OrderKindMarket,
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.Contains(t, sim.RejectionReason, "insufficient holdings")
+	})
+
+	t.Run("invalid request is a rejection", func(t *testing.T) {
+		service, _, _, _ := newSimulationService()
+
+		sim, err := service.SimulateOrder(ctx, &dto.CreateOrderRequest{
+			Type: models.OrderTypeBuy, CryptoSymbol: "BTC", Quantity: "0", OrderKind: models.OrderKindMarket,
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.Contains(t, sim.RejectionReason, "validation error")
+	})
+}
+
+func TestOrderServiceSimple_GetOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful order retrieval", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockExec := createMockExecutionService()
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+
+		orderID := primitive.NewObjectID().Hex()
+		expectedOrder := &models.Order{
+			ID:           primitive.NewObjectID(),
+			OrderNumber:  "ORD-123",
+			UserID:       1,
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Status:       models.OrderStatusExecuted,
+		}
+
+		mockRepo.On("GetByID", ctx, orderID).Return(expectedOrder, nil)
+
+		order, err := service.GetOrder(ctx, orderID, 1)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, order)
+		assert.Equal(t, expectedOrder.OrderNumber, order.OrderNumber)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("order not found", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockExec := createMockExecutionService()
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+
+		orderID := primitive.NewObjectID().Hex()
+
+		mockRepo.On("GetByID", ctx, orderID).Return(nil, errors.New("order not found"))
+
+		order, err := service.GetOrder(ctx, orderID, 1)
+
+		assert.Error(t, err)
+		assert.Nil(t, order)
+		assert.Contains(t, err.Error(), "order not found")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("access denied - wrong user", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockExec := createMockExecutionService()
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+
+		orderID := primitive.NewObjectID().Hex()
+		expectedOrder := &models.Order{
+			ID:           primitive.NewObjectID(),
+			OrderNumber:  "ORD-123",
+			UserID:       2,
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Status:       models.OrderStatusExecuted,
+		}
+
+		mockRepo.On("GetByID", ctx, orderID).Return(expectedOrder, nil)
+
+		order, err := service.GetOrder(ctx, orderID, 1)
+
+		assert.Error(t, err)
+		assert.Nil(t, order)
+		assert.Contains(t, err.Error(), "access denied")
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+// Test CancelOrder
+func TestOrderServiceSimple_CancelOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful order cancellation", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockExec := createMockExecutionService()
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+
+		orderID := primitive.NewObjectID().Hex()
+		order := &models.Order{
+			ID:           primitive.NewObjectID(),
+			OrderNumber:  "ORD-123",
+			UserID:       1,
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Status:       models.OrderStatusPending,
+		}
+
+		mockRepo.On("GetByID", ctx, orderID).Return(order, nil)
+		mockRepo.On("Update", ctx, mock.AnythingOfType("*models.Order")).Return(nil)
+		mockPublisher.On("PublishOrderCancelled", ctx, mock.AnythingOfType("*models.Order"), "user requested").Return(nil)
+
+		err := service.CancelOrder(ctx, orderID, 1, "user requested")
+
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("release of reserved funds fails", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+		balanceClient := new(MockUserBalanceClient)
+		mockExec := &ExecutionService{fees: defaultTestFees, userBalanceClient: balanceClient}
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+
+		orderID := primitive.NewObjectID().Hex()
+		order := &models.Order{
+			ID:           primitive.NewObjectID(),
+			OrderNumber:  "ORD-123",
+			UserID:       1,
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Status:       models.OrderStatusPending,
+		}
+
+		mockRepo.On("GetByID", ctx, orderID).Return(order, nil)
+		mockRepo.On("Update", ctx, order).Return(nil)
+		mockPublisher.On("PublishOrderCancelled", ctx, order, "user requested").Return(nil)
+		balanceClient.On("ReleaseFunds", ctx, 1, order.ID.Hex()).Return(errors.New("users API unavailable"))
+
+		err := service.CancelOrder(ctx, orderID, 1, "user requested")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to release reserved funds")
+		assert.Equal(t, models.OrderStatusCancelled, order.Status)
+
+		mockRepo.AssertExpectations(t)
+		balanceClient.AssertExpectations(t)
+	})
+
+	t.Run("version conflict with a concurrent fill", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+		balanceClient := new(MockUserBalanceClient)
+		mockExec := &ExecutionService{fees: defaultTestFees, userBalanceClient: balanceClient}
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+
+		orderID := primitive.NewObjectID().Hex()
+		order := &models.Order{
+			ID:           primitive.NewObjectID(),
+			UserID:       1,
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Status:       models.OrderStatusPending,
+			Version:      3,
+		}
+
+		mockRepo.On("GetByID", ctx, orderID).Return(order, nil)
+		mockRepo.On("Update", ctx, order).Return(repositories.ErrVersionConflict)
+
+		err := service.CancelOrder(ctx, orderID, 1, "user requested")
+
+		assert.ErrorIs(t, err, repositories.ErrVersionConflict)
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+
+		balanceClient.AssertNotCalled(t, "ReleaseFunds", mock.Anything, mock.Anything, mock.Anything)
+		mockPublisher.AssertNotCalled(t, "PublishOrderCancelled", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("order not found", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockExec := createMockExecutionService()
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+
+		orderID := primitive.NewObjectID().Hex()
+
+		mockRepo.On("GetByID", ctx, orderID).Return(nil, errors.New("order not found"))
+
+		err := service.CancelOrder(ctx, orderID, 1, "user requested")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "order not found")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("access denied - wrong user", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockExec := createMockExecutionService()
+		mockMarket := new(MockMarketService)
+		mockPublisher := new(MockEventPublisher)
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, mockPublisher)
+
+		orderID := primitive.NewObjectID().Hex()
+		order := &models.Order{
+			ID:           primitive.NewObjectID(),
+			OrderNumber:  "ORD-123",
+			UserID:       2,
+			Type:         models.OrderTypeBuy,
+			CryptoSymbol: "BTC",
+			Status:       models.OrderStatusPending,
 		}
 
 		mockRepo.On("GetByID", ctx, orderID).Return(order, nil)
@@ -522,6 +1250,202 @@ func TestOrderServiceSimple_CancelOrder(t *testing.T) {
 	})
 }
 
+// Test ExecutePendingOrder con órdenes condicionales
+func TestOrderServiceSimple_ExecutePendingOrder_Conditional(t *testing.T) {
+	ctx := context.Background()
+
+	newStopLimit := func() *models.Order {
+		trigger := decimal.NewFromInt(45000)
+		return &models.Order{
+			ID:           primitive.NewObjectID(),
+			OrderNumber:  "ORD-STOP",
+			UserID:       1,
+			Type:         models.OrderTypeSell,
+			OrderKind:    models.OrderKindStopLimit,
+			CryptoSymbol: "BTC",
+			Status:       models.OrderStatusPending,
+			Quantity:     decimal.NewFromInt(1),
+			Price:        decimal.NewFromInt(44500),
+			TriggerPrice: &trigger,
+		}
+	}
+
+	t.Run("trigger not reached", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketService)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), mockMarket, new(MockEventPublisher))
+
+		order := newStopLimit()
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(decimal.NewFromInt(46000), nil)
+
+		err := service.ExecutePendingOrder(ctx, order)
+
+		assert.ErrorIs(t, err, ErrTriggerPriceNotReached)
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("stop limit is triggered and waits for its limit price", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockMarket := new(MockMarketService)
+		marketClient := new(MockMarketClient)
+		userClient := new(MockUserClient)
+		mockExec := &ExecutionService{fees: defaultTestFees, userClient: userClient, marketClient: marketClient}
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, mockMarket, new(MockEventPublisher))
+
+		order := newStopLimit()
+		mockMarket.On("GetCurrentPrice", ctx, "BTC").Return(decimal.NewFromInt(44000), nil)
+		mockRepo.On("Update", ctx, order).Return(nil).Once()
+		userClient.On("VerifyUser", mock.Anything, 1).Return(&models.ValidationResult{IsValid: true}, nil)
+		marketClient.On("GetCurrentPrice", mock.Anything, "BTC").Return(&models.PriceResult{Symbol: "BTC", MarketPrice: decimal.NewFromInt(44000)}, nil)
+
+		err := service.ExecutePendingOrder(ctx, order)
+
+		assert.ErrorIs(t, err, ErrLimitPriceNotReached)
+		assert.Equal(t, models.OrderStatusTriggered, order.Status)
+		assert.NotNil(t, order.TriggeredAt)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+// Test AmendOrder
+func TestOrderServiceSimple_AmendOrder(t *testing.T) {
+	ctx := context.Background()
+
+	newPendingLimit := func() *models.Order {
+		return &models.Order{
+			ID:           primitive.NewObjectID(),
+			UserID:       1,
+			Type:         models.OrderTypeBuy,
+			OrderKind:    models.OrderKindLimit,
+			CryptoSymbol: "BTC",
+			Status:       models.OrderStatusPending,
+			Quantity:     decimal.NewFromInt(1),
+			Price:        decimal.NewFromInt(50000),
+			TotalAmount:  decimal.NewFromInt(50000),
+			Fee:          decimal.NewFromInt(50),
+			Version:      2,
+		}
+	}
+
+	t.Run("successful amend resizes hold and publishes event", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockPublisher := new(MockEventPublisher)
+		balanceClient := new(MockUserBalanceClient)
+		mockExec := &ExecutionService{fees: defaultTestFees, userBalanceClient: balanceClient}
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, new(MockMarketService), mockPublisher)
+
+		order := newPendingLimit()
+		orderID := order.ID.Hex()
+
+		mockRepo.On("GetByID", ctx, orderID).Return(order, nil)
+		balanceClient.On("AdjustFunds", ctx, 1, orderID, mock.MatchedBy(func(amount decimal.Decimal) bool {
+			return amount.Equal(decimal.NewFromInt(96096)) // 2 * 48000 + 0.1%
+		})).Return(nil)
+		mockRepo.On("Update", ctx, order).Return(nil)
+		mockPublisher.On("PublishOrderAmended", ctx, order, mock.MatchedBy(func(previous *models.Order) bool {
+			return previous.Price.Equal(decimal.NewFromInt(50000)) && previous.Quantity.Equal(decimal.NewFromInt(1))
+		})).Return(nil)
+
+		version := int64(2)
+		amended, err := service.AmendOrder(ctx, orderID, 1, &dto.AmendOrderRequest{
+			LimitPrice: "48000",
+			Quantity:   "2",
+			Version:    &version,
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, amended.Price.Equal(decimal.NewFromInt(48000)))
+		assert.True(t, amended.TotalAmount.Equal(decimal.NewFromInt(96000)))
+		assert.True(t, amended.Fee.Equal(decimal.NewFromInt(96)))
+
+		mockRepo.AssertExpectations(t)
+		balanceClient.AssertExpectations(t)
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("stale version is rejected", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		order := newPendingLimit()
+		mockRepo.On("GetByID", ctx, order.ID.Hex()).Return(order, nil)
+
+		version := int64(1)
+		amended, err := service.AmendOrder(ctx, order.ID.Hex(), 1, &dto.AmendOrderRequest{LimitPrice: "48000", Version: &version})
+
+		assert.ErrorIs(t, err, repositories.ErrVersionConflict)
+		assert.Nil(t, amended)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("losing the race against a fill restores the hold", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockPublisher := new(MockEventPublisher)
+		balanceClient := new(MockUserBalanceClient)
+		mockExec := &ExecutionService{fees: defaultTestFees, userBalanceClient: balanceClient}
+
+		service := NewOrderServiceSimple(mockRepo, mockExec, new(MockMarketService), mockPublisher)
+
+		order := newPendingLimit()
+		orderID := order.ID.Hex()
+
+		mockRepo.On("GetByID", ctx, orderID).Return(order, nil)
+		balanceClient.On("AdjustFunds", ctx, 1, orderID, mock.MatchedBy(func(amount decimal.Decimal) bool {
+			return amount.Equal(decimal.NewFromInt(60060))
+		})).Return(nil).Once()
+		mockRepo.On("Update", ctx, order).Return(repositories.ErrVersionConflict)
+		balanceClient.On("AdjustFunds", ctx, 1, orderID, mock.MatchedBy(func(amount decimal.Decimal) bool {
+			return amount.Equal(decimal.NewFromInt(50050))
+		})).Return(nil).Once()
+
+		amended, err := service.AmendOrder(ctx, orderID, 1, &dto.AmendOrderRequest{LimitPrice: "60000"})
+
+		assert.ErrorIs(t, err, repositories.ErrVersionConflict)
+		assert.Nil(t, amended)
+		assert.True(t, order.Price.Equal(decimal.NewFromInt(50000)))
+		balanceClient.AssertExpectations(t)
+		mockPublisher.AssertNotCalled(t, "PublishOrderAmended", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("market orders cannot be amended", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		order := newPendingLimit()
+		order.OrderKind = models.OrderKindMarket
+		mockRepo.On("GetByID", ctx, order.ID.Hex()).Return(order, nil)
+
+		amended, err := service.AmendOrder(ctx, order.ID.Hex(), 1, &dto.AmendOrderRequest{Quantity: "3"})
+
+		assert.Error(t, err)
+		assert.Nil(t, amended)
+		assert.Contains(t, err.Error(), "cannot be amended")
+	})
+
+	t.Run("admin can amend orders of other users", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockPublisher := new(MockEventPublisher)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), new(MockMarketService), mockPublisher)
+
+		order := newPendingLimit()
+		order.UserID = 7
+		order.Type = models.OrderTypeSell
+
+		mockRepo.On("GetByID", ctx, order.ID.Hex()).Return(order, nil)
+		mockRepo.On("Update", ctx, order).Return(nil)
+		mockPublisher.On("PublishOrderAmended", ctx, order, mock.AnythingOfType("*models.Order")).Return(nil)
+
+		amended, err := service.AdminAmendOrder(ctx, order.ID.Hex(), &dto.AmendOrderRequest{Quantity: "0.5"})
+
+		assert.NoError(t, err)
+		assert.True(t, amended.Quantity.Equal(decimal.NewFromFloat(0.5)))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
 // Test ListUserOrders
 func TestOrderServiceSimple_ListUserOrders(t *testing.T) {
 	ctx := context.Background()
@@ -567,15 +1491,15 @@ func TestOrderServiceSimple_ListUserOrders(t *testing.T) {
 			TotalVolume:     decimal.NewFromInt(100000),
 		}
 
-		mockRepo.On("ListByUser", ctx, 1, filter).Return(expectedOrders, int64(2), nil)
+		mockRepo.On("ListByUser", ctx, 1, filter).Return(&dto.OrderPage{Orders: expectedOrders, Total: 2}, nil)
 		mockRepo.On("GetOrdersSummary", ctx, 1).Return(summary, nil)
 
-		orders, total, resultSummary, err := service.ListUserOrders(ctx, 1, filter)
+		page, resultSummary, err := service.ListUserOrders(ctx, 1, filter)
 
 		assert.NoError(t, err)
-		assert.NotNil(t, orders)
-		assert.Equal(t, 2, len(orders))
-		assert.Equal(t, int64(2), total)
+		assert.NotNil(t, page.Orders)
+		assert.Equal(t, 2, len(page.Orders))
+		assert.Equal(t, int64(2), page.Total)
 		assert.Equal(t, summary.TotalOrders, resultSummary.TotalOrders)
 
 		mockRepo.AssertExpectations(t)
@@ -604,17 +1528,250 @@ func TestOrderServiceSimple_ListUserOrders(t *testing.T) {
 			TotalVolume:     decimal.Zero,
 		}
 
-		mockRepo.On("ListByUser", ctx, 1, filter).Return(emptyOrders, int64(0), nil)
+		mockRepo.On("ListByUser", ctx, 1, filter).Return(&dto.OrderPage{Orders: emptyOrders, Total: 0}, nil)
 		mockRepo.On("GetOrdersSummary", ctx, 1).Return(summary, nil)
 
-		orders, total, resultSummary, err := service.ListUserOrders(ctx, 1, filter)
+		page, resultSummary, err := service.ListUserOrders(ctx, 1, filter)
 
 		assert.NoError(t, err)
-		assert.NotNil(t, orders)
-		assert.Equal(t, 0, len(orders))
-		assert.Equal(t, int64(0), total)
+		assert.NotNil(t, page.Orders)
+		assert.Equal(t, 0, len(page.Orders))
+		assert.Equal(t, int64(0), page.Total)
 		assert.Equal(t, int64(0), resultSummary.TotalOrders)
 
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestOrderServiceSimple_ListUserOrders_Cursor(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("cursor from a page is accepted for the next one", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		last := newBookOrder(models.OrderTypeBuy, 50000, 1)
+		last.TotalAmount = decimal.NewFromInt(5000)
+		cursor := dto.NewOrderCursor(last, dto.OrderSortTotal, dto.SortAsc).Encode()
+
+		filter := &dto.OrderFilterRequest{SortBy: dto.OrderSortTotal, SortOrder: dto.SortAsc, Cursor: cursor, Page: 3, Limit: 10}
+		mockRepo.On("ListByUser", ctx, 1, filter).Return(&dto.OrderPage{Orders: []models.Order{}}, nil)
+		mockRepo.On("GetOrdersSummary", ctx, 1).Return(&dto.OrdersSummary{}, nil)
+
+		_, _, err := service.ListUserOrders(ctx, 1, filter)
+
+		require.NoError(t, err)
+		require.NotNil(t, filter.After())
+		assert.Equal(t, last.ID.Hex(), filter.After().ID)
+		assert.Equal(t, 5000.0, mustSortValue(t, filter.After()))
+		assert.Equal(t, 0, filter.GetOffset())
+	})
+
+	t.Run("cursor issued for another sort is rejected", func(t *testing.T) {
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		cursor := dto.NewOrderCursor(newBookOrder(models.OrderTypeBuy, 50000, 1), dto.OrderSortPrice, dto.SortDesc).Encode()
+
+		_, _, err := service.ListUserOrders(ctx, 1, &dto.OrderFilterRequest{Cursor: cursor})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "validation error")
+	})
+
+	t.Run("garbage cursor and inverted ranges are rejected", func(t *testing.T) {
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+		minTotal, maxTotal := decimal.NewFromInt(100), decimal.NewFromInt(10)
+
+		for _, filter := range []*dto.OrderFilterRequest{
+			{Cursor: "not-a-cursor"},
+			{MinTotal: &minTotal, MaxTotal: &maxTotal},
+			{SortBy: "quantity"},
+		} {
+			_, _, err := service.ListUserOrders(ctx, 1, filter)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "validation error")
+			assert.ErrorIs(t, err, ErrOrderValidation)
+		}
+	})
+}
+
+func mustSortValue(t *testing.T, cursor *dto.OrderCursor) interface{} {
+	value, err := cursor.SortValue()
+	require.NoError(t, err)
+	return value
+}
+
+func TestOrderServiceSimple_ExportFilledOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("streams the filled part of every order", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		filledAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		executed := newBookOrder(models.OrderTypeBuy, 50000, 0.5)
+		executed.ApplyFills([]models.Fill{{Quantity: decimal.NewFromFloat(0.5), Amount: decimal.NewFromInt(25000), Fee: decimal.NewFromInt(25)}}, filledAt)
+		// Partially filled, then expired: only the filled part is exported
+		expired := newBookOrder(models.OrderTypeBuy, 50000, 2)
+		expired.ApplyFills([]models.Fill{
+			{Quantity: decimal.NewFromFloat(0.5), Amount: decimal.NewFromInt(24000), Fee: decimal.NewFromInt(24)},
+			{Quantity: decimal.NewFromFloat(0.5), Amount: decimal.NewFromInt(25000), Fee: decimal.NewFromInt(25)},
+		}, filledAt)
+		expired.MarkExpired(filledAt.Add(time.Hour))
+		legacy := newBookOrder(models.OrderTypeSell, 3000, 2)
+		legacy.Status = models.OrderStatusExecuted
+		legacy.CryptoSymbol = "ETH"
+		legacy.ExecutedAt = &filledAt
+
+		from := filledAt.Add(-time.Hour)
+		req := &dto.OrderExportRequest{From: &from}
+		mockRepo.On("StreamFilled", ctx, 1, &from, (*time.Time)(nil), mock.Anything).
+			Return([]models.Order{*executed, *expired, *legacy}, nil)
+
+		var rows []dto.OrderExportRow
+		err := service.ExportFilledOrders(ctx, 1, req, func(row dto.OrderExportRow) error {
+			rows = append(rows, row)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, dto.ExportFormatCSV, req.Format)
+		require.Len(t, rows, 3)
+		assert.Equal(t, []string{executed.OrderNumber, "BTC", "buy", "executed", "0.5", "0.5", "50000", "25000", "25", "2025-03-01T12:00:00Z"}, rows[0].CSVRecord())
+		assert.Equal(t, []string{expired.OrderNumber, "BTC", "buy", "expired", "2", "1", "49000", "49000", "49", "2025-03-01T12:00:00Z"}, rows[1].CSVRecord())
+		assert.True(t, rows[2].FilledQuantity.Equal(decimal.NewFromInt(2)), "legacy orders fall back to the requested quantity")
+		assert.True(t, rows[2].AvgFillPrice.Equal(decimal.NewFromInt(3000)))
+		assert.Equal(t, "sell", rows[2].Side)
+	})
+
+	t.Run("write error stops the stream", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		orders := []models.Order{*newBookOrder(models.OrderTypeBuy, 50000, 1), *newBookOrder(models.OrderTypeBuy, 50000, 1)}
+		mockRepo.On("StreamFilled", ctx, 1, mock.Anything, mock.Anything, mock.Anything).Return(orders, nil)
+
+		calls := 0
+		err := service.ExportFilledOrders(ctx, 1, &dto.OrderExportRequest{Format: dto.ExportFormatJSON}, func(row dto.OrderExportRow) error {
+			calls++
+			return errors.New("client went away")
+		})
+
+		assert.EqualError(t, err, "client went away")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("unknown format is a validation error", func(t *testing.T) {
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		err := service.ExportFilledOrders(ctx, 1, &dto.OrderExportRequest{Format: "xlsx"}, func(row dto.OrderExportRow) error { return nil })
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "validation error")
+	})
+}
+
+func TestOrderServiceSimple_AdminFailOrders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fails stuck orders and skips the ones it cannot touch", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockPublisher := new(MockEventPublisher)
+		mockExec := createMockExecutionService()
+		service := NewOrderServiceSimple(mockRepo, mockExec, new(MockMarketService), mockPublisher)
+
+		pending := newBookOrder(models.OrderTypeBuy, 50000, 1)
+		executed := newBookOrder(models.OrderTypeBuy, 50000, 1)
+		executed.Status = models.OrderStatusExecuted
+		midSaga := newBookOrder(models.OrderTypeBuy, 50000, 1)
+		midSaga.Status = models.OrderStatusExecuting
+		midSaga.Saga = &models.ExecutionSaga{Step: models.SagaStepBalanceApplied}
+		missingID := primitive.NewObjectID().Hex()
+
+		for _, order := range []*models.Order{pending, executed, midSaga} {
+			mockRepo.On("GetByID", ctx, order.ID.Hex()).Return(order, nil)
+		}
+		mockRepo.On("GetByID", ctx, missingID).Return(nil, errors.New("order not found"))
+		mockRepo.On("Update", ctx, pending).Return(nil).Once()
+		mockPublisher.On("PublishOrderFailed", ctx, pending, "stuck after outage").Return(nil).Once()
+
+		result, err := service.AdminFailOrders(ctx, &dto.BulkFailOrdersRequest{
+			OrderIDs: []string{pending.ID.Hex(), executed.ID.Hex(), midSaga.ID.Hex(), missingID, pending.ID.Hex()},
+			Reason:   "stuck after outage",
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{pending.ID.Hex()}, result.Failed)
+		require.Len(t, result.Skipped, 3)
+		assert.Contains(t, result.Skipped[0].Reason, "status: executed")
+		assert.Contains(t, result.Skipped[1].Reason, "saga recoverer")
+		assert.Equal(t, "order not found", result.Skipped[2].Reason)
+
+		assert.Equal(t, models.OrderStatusFailed, pending.Status)
+		assert.Equal(t, "stuck after outage", pending.ErrorMessage)
+		assert.Equal(t, models.OrderStatusExecuting, midSaga.Status)
+		mockExec.userBalanceClient.(*MockUserBalanceClient).AssertCalled(t, "ReleaseFunds", ctx, 1, pending.ID.Hex())
+		mockRepo.AssertExpectations(t)
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("version conflict leaves the order untouched", func(t *testing.T) {
+		mockRepo := new(MockOrderRepository)
+		mockExec := createMockExecutionService()
+		service := NewOrderServiceSimple(mockRepo, mockExec, new(MockMarketService), new(MockEventPublisher))
+
+		order := newBookOrder(models.OrderTypeSell, 50000, 1)
+		mockRepo.On("GetByID", ctx, order.ID.Hex()).Return(order, nil)
+		mockRepo.On("Update", ctx, order).Return(repositories.ErrVersionConflict)
+
+		result, err := service.AdminFailOrders(ctx, &dto.BulkFailOrdersRequest{
+			OrderIDs: []string{order.ID.Hex()},
+			Reason:   "stuck",
+		})
+
+		require.NoError(t, err)
+		assert.Empty(t, result.Failed)
+		require.Len(t, result.Skipped, 1)
+		assert.Contains(t, result.Skipped[0].Reason, "modified concurrently")
+		assert.Equal(t, models.OrderStatusPending, order.Status)
+		mockExec.portfolioClient.(*MockPortfolioClient).AssertNotCalled(t, "ReleaseHoldings", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty request is a validation error", func(t *testing.T) {
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+		_, err := service.AdminFailOrders(ctx, &dto.BulkFailOrdersRequest{Reason: "stuck"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "validation error")
+	})
+}
+
+func TestOrderServiceSimple_AdminListOrders(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockOrderRepository)
+	service := NewOrderServiceSimple(mockRepo, createMockExecutionService(), new(MockMarketService), new(MockEventPublisher))
+
+	userID := 7
+	filter := &dto.AdminOrderFilterRequest{UserID: &userID}
+	mockRepo.On("ListAll", ctx, filter).Return(&dto.OrderPage{Orders: []models.Order{{UserID: 7}}, Total: 1}, nil)
+
+	page, err := service.AdminListOrders(ctx, filter)
+
+	require.NoError(t, err)
+	assert.Len(t, page.Orders, 1)
+	assert.Equal(t, int64(1), page.Total)
+	assert.Equal(t, 1, filter.Page)
+	assert.Equal(t, 20, filter.Limit)
+}
+
+func TestIsOrderRejection(t *testing.T) {
+	// Los rechazos se reconocen por el error envuelto, no por el texto del mensaje
+	assert.True(t, isOrderRejection(fmt.Errorf("%w: quantity must be positive", ErrOrderValidation)))
+	assert.True(t, isOrderRejection(fmt.Errorf("%w: %w", ErrInvalidSymbol, errors.New("not listed"))))
+	assert.True(t, isOrderRejection(fmt.Errorf("%w for BTC", ErrTradingSuspended)))
+	assert.True(t, isOrderRejection(fmt.Errorf("create order: %w", ErrQuoteExpired)))
+
+	assert.False(t, isOrderRejection(errors.New("validation error: texto sin envolver")))
+	assert.False(t, isOrderRejection(errors.New("database unavailable")))
+}
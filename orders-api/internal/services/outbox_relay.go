@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// MessagePublisher publica un mensaje ya serializado y espera la confirmación del broker
+type MessagePublisher interface {
+	PublishMessage(ctx context.Context, routingKey, messageID string, body []byte) error
+}
+
+// OutboxRelayConfig configuración del relay del outbox
+type OutboxRelayConfig struct {
+	Interval       time.Duration
+	BatchSize      int
+	LockTTL        time.Duration
+	MaxAttempts    int
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// OutboxRelay publica en RabbitMQ los eventos guardados en el outbox junto con las
+// órdenes. Un evento se marca publicado recién cuando el broker confirma; si falla
+// se reintenta con backoff exponencial hasta MaxAttempts, y los eventos siguientes
+// de la misma orden esperan a que se publique (también si quedó failed). Cada evento se toma con un lock en
+// Mongo, así varias réplicas pueden correr el relay.
+type OutboxRelay struct {
+	outboxRepo repositories.OutboxRepository
+	publisher  MessagePublisher
+	config     OutboxRelayConfig
+	instanceID string
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewOutboxRelay crea una nueva instancia del relay
+func NewOutboxRelay(
+	outboxRepo repositories.OutboxRepository,
+	publisher MessagePublisher,
+	config OutboxRelayConfig,
+) *OutboxRelay {
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 30 * time.Second
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 10
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = time.Second
+	}
+	if config.RetryMaxDelay <= 0 {
+		config.RetryMaxDelay = 5 * time.Minute
+	}
+
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		config:     config,
+		instanceID: newInstanceID(),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Start inicia el loop del relay en background
+func (r *OutboxRelay) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+				if _, err := r.RelayOnce(ctx); err != nil {
+					log.Printf("Warning: outbox relay run failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop detiene el relay y espera a que termine la pasada en curso
+func (r *OutboxRelay) Stop() {
+	close(r.stopCh)
+	r.wg.Wait()
+}
+
+// RelayOnce publica hasta BatchSize eventos pendientes y retorna cuántos se publicaron.
+// Un evento cuyo lock venció antes de llegar su turno se deja para la próxima pasada:
+// puede tenerlo otra réplica.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	published := 0
+
+	events, err := r.outboxRepo.ClaimBatch(ctx, r.instanceID, r.config.LockTTL, r.config.BatchSize)
+	for _, event := range events {
+		if event.LockedUntil != nil && !time.Now().Before(*event.LockedUntil) {
+			continue
+		}
+		if r.relay(ctx, event) {
+			published++
+		}
+	}
+
+	if err != nil {
+		return published, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	return published, nil
+}
+
+// relay publica un evento y registra el resultado; retorna true si se publicó
+func (r *OutboxRelay) relay(ctx context.Context, event *models.OutboxEvent) bool {
+	event.Attempts++
+
+	publishErr := r.publisher.PublishMessage(ctx, event.RoutingKey, event.EventID, event.Payload)
+	if publishErr == nil {
+		if err := r.outboxRepo.MarkPublished(ctx, event); err != nil {
+			// Al vencer el lock se vuelve a publicar; el consumidor lo descarta por EventID
+			log.Printf("Warning: event %s was published but could not be marked: %v", event.EventID, err)
+		}
+		return true
+	}
+
+	event.LastError = publishErr.Error()
+	if event.Attempts >= r.config.MaxAttempts {
+		event.Status = models.OutboxStatusFailed
+		log.Printf("❌ Giving up on event %s after %d attempts, later events of its order are held until it is set back to pending: %v",
+			event.EventID, event.Attempts, publishErr)
+	} else {
+		event.NextAttemptAt = time.Now().Add(r.retryDelay(event.Attempts))
+		log.Printf("Warning: failed to publish event %s (attempt %d): %v", event.EventID, event.Attempts, publishErr)
+	}
+
+	if err := r.outboxRepo.MarkFailed(ctx, event); err != nil {
+		log.Printf("Warning: failed to record outbox failure for event %s: %v", event.EventID, err)
+	}
+	return false
+}
+
+// retryDelay backoff exponencial: base, 2*base, 4*base... hasta RetryMaxDelay
+func (r *OutboxRelay) retryDelay(attempts int) time.Duration {
+	delay := r.config.RetryBaseDelay
+	for i := 1; i < attempts && delay < r.config.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.config.RetryMaxDelay {
+		delay = r.config.RetryMaxDelay
+	}
+	return delay
+}
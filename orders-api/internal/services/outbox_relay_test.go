@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) Add(ctx context.Context, event *models.OutboxEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) ClaimBatch(ctx context.Context, owner string, ttl time.Duration, limit int) ([]*models.OutboxEvent, error) {
+	args := m.Called(ctx, owner, ttl, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.OutboxEvent), args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkPublished(ctx context.Context, event *models.OutboxEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) MarkFailed(ctx context.Context, event *models.OutboxEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+type MockMessagePublisher struct {
+	mock.Mock
+}
+
+func (m *MockMessagePublisher) PublishMessage(ctx context.Context, routingKey, messageID string, body []byte) error {
+	args := m.Called(ctx, routingKey, messageID, body)
+	return args.Error(0)
+}
+
+func newOutboxTestEvent() *models.OutboxEvent {
+	order := newMarketOrder(models.OrderTypeBuy)
+	order.Version = 1
+	return models.NewOutboxEvent(order, "orders.created", []byte(`{"event_type":"created"}`))
+}
+
+func TestOutboxRelay_RelayOnce(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publishes with the event ID and marks it published", func(t *testing.T) {
+		outbox := new(MockOutboxRepository)
+		publisher := new(MockMessagePublisher)
+		relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{})
+		event := newOutboxTestEvent()
+
+		outbox.On("ClaimBatch", ctx, relay.instanceID, relay.config.LockTTL, relay.config.BatchSize).Return([]*models.OutboxEvent{event}, nil).Once()
+		publisher.On("PublishMessage", ctx, "orders.created", event.EventID, event.Payload).Return(nil)
+		outbox.On("MarkPublished", ctx, event).Return(nil)
+
+		published, err := relay.RelayOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, published)
+		assert.Equal(t, 1, event.Attempts)
+		outbox.AssertExpectations(t)
+	})
+
+	t.Run("failed publish is retried with backoff", func(t *testing.T) {
+		outbox := new(MockOutboxRepository)
+		publisher := new(MockMessagePublisher)
+		relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{RetryBaseDelay: time.Minute})
+		event := newOutboxTestEvent()
+		event.Attempts = 2
+
+		outbox.On("ClaimBatch", ctx, relay.instanceID, relay.config.LockTTL, relay.config.BatchSize).Return([]*models.OutboxEvent{event}, nil).Once()
+		publisher.On("PublishMessage", ctx, "orders.created", event.EventID, event.Payload).
+			Return(errors.New("timed out waiting for publish confirmation"))
+		outbox.On("MarkFailed", ctx, event).Return(nil)
+
+		published, err := relay.RelayOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+		assert.Equal(t, models.OutboxStatusPending, event.Status)
+		assert.Equal(t, 3, event.Attempts)
+		assert.WithinDuration(t, time.Now().Add(4*time.Minute), event.NextAttemptAt, 5*time.Second)
+		assert.Contains(t, event.LastError, "publish confirmation")
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		outbox := new(MockOutboxRepository)
+		publisher := new(MockMessagePublisher)
+		relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{MaxAttempts: 3})
+		event := newOutboxTestEvent()
+		event.Attempts = 2
+
+		outbox.On("ClaimBatch", ctx, relay.instanceID, relay.config.LockTTL, relay.config.BatchSize).Return([]*models.OutboxEvent{event}, nil).Once()
+		publisher.On("PublishMessage", ctx, "orders.created", event.EventID, event.Payload).Return(errors.New("channel closed"))
+		outbox.On("MarkFailed", ctx, event).Return(nil)
+
+		_, err := relay.RelayOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.OutboxStatusFailed, event.Status)
+	})
+}
+
+func TestOutboxRelay_RetryDelay(t *testing.T) {
+	relay := NewOutboxRelay(nil, nil, OutboxRelayConfig{RetryBaseDelay: time.Second, RetryMaxDelay: 10 * time.Second})
+
+	assert.Equal(t, time.Second, relay.retryDelay(1))
+	assert.Equal(t, 4*time.Second, relay.retryDelay(3))
+	assert.Equal(t, 10*time.Second, relay.retryDelay(8))
+}
+
+// failingTransactor simula una transacción que se descarta después de ejecutar fn
+type failingTransactor struct{}
+
+func (failingTransactor) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	return errors.New("transaction aborted")
+}
+
+func TestWriteWithEvent_RestoresVersionWhenTransactionFails(t *testing.T) {
+	ctx := context.Background()
+	order := newMarketOrder(models.OrderTypeBuy)
+	order.Version = 3
+
+	err := writeWithEvent(ctx, failingTransactor{}, order,
+		func(context.Context) error { order.Version++; return nil },
+		func(context.Context) error { return nil },
+	)
+
+	assert.Error(t, err)
+	assert.Equal(t, int64(3), order.Version)
+}
+
+func TestOutboxRelay_RelayOnce_Locks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("claim that expired before its turn is left for the next pass", func(t *testing.T) {
+		outbox := new(MockOutboxRepository)
+		publisher := new(MockMessagePublisher)
+		relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{})
+		expired := time.Now().Add(-time.Second)
+		event := newOutboxTestEvent()
+		event.LockedBy = relay.instanceID
+		event.LockedUntil = &expired
+
+		outbox.On("ClaimBatch", ctx, relay.instanceID, relay.config.LockTTL, relay.config.BatchSize).Return([]*models.OutboxEvent{event}, nil).Once()
+
+		published, err := relay.RelayOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, published)
+		publisher.AssertNotCalled(t, "PublishMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("lost lock on mark published is not recorded as a failure", func(t *testing.T) {
+		outbox := new(MockOutboxRepository)
+		publisher := new(MockMessagePublisher)
+		relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{})
+		event := newOutboxTestEvent()
+		event.LockedBy = relay.instanceID
+
+		outbox.On("ClaimBatch", ctx, relay.instanceID, relay.config.LockTTL, relay.config.BatchSize).Return([]*models.OutboxEvent{event}, nil).Once()
+		publisher.On("PublishMessage", ctx, "orders.created", event.EventID, event.Payload).Return(nil)
+		outbox.On("MarkPublished", ctx, event).Return(repositories.ErrOutboxLockLost)
+
+		_, err := relay.RelayOnce(ctx)
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, models.OutboxStatusPublished, event.Status)
+		outbox.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything)
+	})
+
+	t.Run("claim error still relays the events already claimed", func(t *testing.T) {
+		outbox := new(MockOutboxRepository)
+		publisher := new(MockMessagePublisher)
+		relay := NewOutboxRelay(outbox, publisher, OutboxRelayConfig{})
+		event := newOutboxTestEvent()
+
+		outbox.On("ClaimBatch", ctx, relay.instanceID, relay.config.LockTTL, relay.config.BatchSize).
+			Return([]*models.OutboxEvent{event}, errors.New("connection reset")).Once()
+		publisher.On("PublishMessage", ctx, "orders.created", event.EventID, event.Payload).Return(nil)
+		outbox.On("MarkPublished", ctx, event).Return(nil)
+
+		published, err := relay.RelayOnce(ctx)
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, published)
+	})
+}
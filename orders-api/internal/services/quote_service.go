@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"orders-api/internal/models"
+)
+
+// ErrInvalidQuote indica una cotización mal formada, adulterada o de otra orden
+var ErrInvalidQuote = errors.New("invalid quote")
+
+// ErrQuoteExpired indica que la cotización venció y hay que pedir otra
+var ErrQuoteExpired = errors.New("quote expired")
+
+// QuoteServiceConfig configuración de las cotizaciones firmadas
+type QuoteServiceConfig struct {
+	Secret   string        // Clave HMAC con la que se firman las cotizaciones
+	TTL      time.Duration // Validez de cada cotización
+	Required bool          // Si las órdenes market deben traer quote_id
+}
+
+// QuoteService emite y verifica cotizaciones firmadas. Son stateless: el QuoteID
+// lleva los datos de la cotización y su firma, así cualquier réplica la verifica.
+type QuoteService struct {
+	marketService MarketService
+	secret        []byte
+	ttl           time.Duration
+	required      bool
+}
+
+// quotePayload datos firmados dentro del QuoteID
+type quotePayload struct {
+	UserID    int    `json:"u"`
+	Symbol    string `json:"s"`
+	Type      string `json:"t"`
+	Price     string `json:"p"`
+	IssuedAt  int64  `json:"i"`
+	ExpiresAt int64  `json:"e"`
+	Nonce     string `json:"n"`
+}
+
+// NewQuoteService crea el servicio de cotizaciones
+func NewQuoteService(marketService MarketService, config QuoteServiceConfig) *QuoteService {
+	if config.TTL <= 0 {
+		config.TTL = 15 * time.Second
+	}
+
+	return &QuoteService{
+		marketService: marketService,
+		secret:        []byte(config.Secret),
+		ttl:           config.TTL,
+		required:      config.Required,
+	}
+}
+
+// IsRequired indica si las órdenes market deben traer una cotización
+func (s *QuoteService) IsRequired() bool {
+	return s.required
+}
+
+// CreateQuote cotiza symbol al precio actual de mercado para el usuario y lado dados
+func (s *QuoteService) CreateQuote(ctx context.Context, userID int, symbol string, orderType models.OrderType) (*models.Quote, error) {
+	price, err := s.marketService.GetCurrentPrice(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current price: %w", err)
+	}
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate quote nonce: %w", err)
+	}
+
+	now := time.Now()
+	quote := &models.Quote{
+		UserID:       userID,
+		CryptoSymbol: symbol,
+		Type:         orderType,
+		Price:        price,
+		IssuedAt:     now,
+		ExpiresAt:    now.Add(s.ttl),
+	}
+
+	payload, err := json.Marshal(quotePayload{
+		UserID:    userID,
+		Symbol:    symbol,
+		Type:      string(orderType),
+		Price:     price.String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: quote.ExpiresAt.Unix(),
+		Nonce:     hex.EncodeToString(nonce),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode quote: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	quote.QuoteID = encoded + "." + s.sign(encoded)
+	// Unix trunca a segundos: la respuesta muestra el mismo vencimiento que se verifica
+	quote.ExpiresAt = time.Unix(quote.ExpiresAt.Unix(), 0)
+
+	return quote, nil
+}
+
+// VerifyQuote valida firma y vencimiento del QuoteID y que corresponda al usuario,
+// símbolo y lado de la orden. Retorna ErrInvalidQuote o ErrQuoteExpired.
+func (s *QuoteService) VerifyQuote(quoteID string, userID int, symbol string, orderType models.OrderType) (*models.Quote, error) {
+	encoded, signature, found := strings.Cut(quoteID, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(s.sign(encoded))) {
+		return nil, fmt.Errorf("%w: signature mismatch", ErrInvalidQuote)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidQuote)
+	}
+
+	var payload quotePayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidQuote)
+	}
+
+	price, err := decimal.NewFromString(payload.Price)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed price", ErrInvalidQuote)
+	}
+
+	quote := &models.Quote{
+		QuoteID:      quoteID,
+		UserID:       payload.UserID,
+		CryptoSymbol: payload.Symbol,
+		Type:         models.OrderType(payload.Type),
+		Price:        price,
+		IssuedAt:     time.Unix(payload.IssuedAt, 0),
+		ExpiresAt:    time.Unix(payload.ExpiresAt, 0),
+	}
+
+	if quote.UserID != userID || !strings.EqualFold(quote.CryptoSymbol, symbol) || quote.Type != orderType {
+		return nil, fmt.Errorf("%w: quote was issued for a different user, symbol or side", ErrInvalidQuote)
+	}
+
+	if quote.IsExpired(time.Now()) {
+		return nil, fmt.Errorf("%w at %s", ErrQuoteExpired, quote.ExpiresAt.Format(time.RFC3339))
+	}
+
+	return quote, nil
+}
+
+func (s *QuoteService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/models"
+)
+
+func newQuoteTestService(ttl time.Duration) (*QuoteService, *MockMarketService) {
+	market := new(MockMarketService)
+	market.On("GetCurrentPrice", context.Background(), "BTC").Return(decimal.NewFromInt(50000), nil)
+
+	return NewQuoteService(market, QuoteServiceConfig{Secret: "test-secret", TTL: ttl}), market
+}
+
+func TestQuoteService_CreateAndVerify(t *testing.T) {
+	ctx := context.Background()
+	quotes, _ := newQuoteTestService(time.Minute)
+
+	quote, err := quotes.CreateQuote(ctx, 1, "BTC", models.OrderTypeBuy)
+	require.NoError(t, err)
+	assert.True(t, quote.Price.Equal(decimal.NewFromInt(50000)))
+
+	verified, err := quotes.VerifyQuote(quote.QuoteID, 1, "BTC", models.OrderTypeBuy)
+	require.NoError(t, err)
+	assert.True(t, verified.Price.Equal(quote.Price))
+	assert.Equal(t, quote.ExpiresAt, verified.ExpiresAt)
+}
+
+func TestQuoteService_VerifyRejects(t *testing.T) {
+	ctx := context.Background()
+	quotes, _ := newQuoteTestService(time.Minute)
+
+	quote, err := quotes.CreateQuote(ctx, 1, "BTC", models.OrderTypeBuy)
+	require.NoError(t, err)
+
+	t.Run("tampered payload", func(t *testing.T) {
+		payload, signature, _ := strings.Cut(quote.QuoteID, ".")
+		tampered := payload[:len(payload)-2] + "AA." + signature
+
+		_, err := quotes.VerifyQuote(tampered, 1, "BTC", models.OrderTypeBuy)
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+	})
+
+	t.Run("signed with another secret", func(t *testing.T) {
+		other := NewQuoteService(nil, QuoteServiceConfig{Secret: "other-secret"})
+
+		_, err := other.VerifyQuote(quote.QuoteID, 1, "BTC", models.OrderTypeBuy)
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+	})
+
+	t.Run("different user, symbol or side", func(t *testing.T) {
+		_, err := quotes.VerifyQuote(quote.QuoteID, 2, "BTC", models.OrderTypeBuy)
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+
+		_, err = quotes.VerifyQuote(quote.QuoteID, 1, "ETH", models.OrderTypeBuy)
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+
+		_, err = quotes.VerifyQuote(quote.QuoteID, 1, "BTC", models.OrderTypeSell)
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		shortLived, _ := newQuoteTestService(time.Nanosecond)
+		stale, err := shortLived.CreateQuote(ctx, 1, "BTC", models.OrderTypeBuy)
+		require.NoError(t, err)
+
+		_, err = shortLived.VerifyQuote(stale.QuoteID, 1, "BTC", models.OrderTypeBuy)
+		assert.ErrorIs(t, err, ErrQuoteExpired)
+	})
+}
+
+func TestOrderServiceSimple_CreateOrder_WithQuote(t *testing.T) {
+	ctx := context.Background()
+
+	newService := func(required bool) (*OrderServiceSimple, *QuoteService, *MockMarketService) {
+		quotes, market := newQuoteTestService(time.Minute)
+		quotes.required = required
+		market.On("ValidateSymbol", ctx, "BTC").Return(&CryptoInfo{Symbol: "BTC", Name: "Bitcoin", IsActive: true}, nil)
+
+		service := NewOrderServiceSimple(new(MockOrderRepository), createMockExecutionService(), market, new(MockEventPublisher))
+		service.SetQuoteService(quotes)
+		service.SetDefaultMaxSlippage(decimal.NewFromFloat(0.02))
+		return service, quotes, market
+	}
+
+	t.Run("quote is required", func(t *testing.T) {
+		service, _, _ := newService(true)
+
+		_, err := service.CreateOrder(ctx, &dto.CreateOrderRequest{
+			Type: models.OrderTypeBuy, CryptoSymbol: "BTC", Quantity: "1", OrderKind: models.OrderKindMarket,
+		}, 1)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "quote_id is required")
+	})
+
+	t.Run("tampered quote is rejected", func(t *testing.T) {
+		service, quotes, _ := newService(false)
+		quote, err := quotes.CreateQuote(ctx, 1, "BTC", models.OrderTypeBuy)
+		require.NoError(t, err)
+
+		_, err = service.CreateOrder(ctx, &dto.CreateOrderRequest{
+			Type: models.OrderTypeBuy, CryptoSymbol: "BTC", Quantity: "1", OrderKind: models.OrderKindMarket,
+			QuoteID: quote.QuoteID + "x",
+		}, 1)
+
+		assert.ErrorIs(t, err, ErrInvalidQuote)
+	})
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// PlanRunner hace la corrida programada de un plan ya tomado por el scheduler
+type PlanRunner interface {
+	RunPlan(ctx context.Context, plan *models.RecurringPlan) (*models.PlanRun, error)
+}
+
+// RecurringPlanSchedulerConfig configuración del scheduler de planes recurrentes
+type RecurringPlanSchedulerConfig struct {
+	Interval  time.Duration
+	BatchSize int
+	LockTTL   time.Duration
+}
+
+// RecurringPlanScheduler corre periódicamente los planes recurrentes cuya próxima
+// corrida ya llegó. Cada plan se toma con un lock en Mongo, así varias réplicas
+// pueden correrlo sin comprar dos veces en la misma corrida.
+type RecurringPlanScheduler struct {
+	planRepo   repositories.RecurringPlanRepository
+	runner     PlanRunner
+	config     RecurringPlanSchedulerConfig
+	instanceID string
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRecurringPlanScheduler crea una nueva instancia del scheduler
+func NewRecurringPlanScheduler(
+	planRepo repositories.RecurringPlanRepository,
+	runner PlanRunner,
+	config RecurringPlanSchedulerConfig,
+) *RecurringPlanScheduler {
+	if config.Interval <= 0 {
+		config.Interval = 30 * time.Second
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = time.Minute
+	}
+
+	return &RecurringPlanScheduler{
+		planRepo:   planRepo,
+		runner:     runner,
+		config:     config,
+		instanceID: newInstanceID(),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Start inicia el loop del scheduler en background
+func (s *RecurringPlanScheduler) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.RunDueOnce(ctx); err != nil {
+				log.Printf("Warning: recurring plan scheduler run failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop detiene el scheduler y espera a que termine la pasada en curso
+func (s *RecurringPlanScheduler) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+// RunDueOnce corre hasta BatchSize planes vencidos y retorna cuántas corridas hizo
+func (s *RecurringPlanScheduler) RunDueOnce(ctx context.Context) (int, error) {
+	runs := 0
+
+	for i := 0; i < s.config.BatchSize; i++ {
+		plan, err := s.planRepo.ClaimDuePlan(ctx, time.Now(), s.instanceID, s.config.LockTTL)
+		if err != nil {
+			return runs, fmt.Errorf("failed to claim recurring plan: %w", err)
+		}
+		if plan == nil {
+			break
+		}
+
+		run, err := s.runner.RunPlan(ctx, plan)
+		if err != nil {
+			// El lock vence y otra pasada repite la corrida (la orden es idempotente)
+			log.Printf("Warning: failed to run recurring plan %s: %v", plan.ID.Hex(), err)
+			continue
+		}
+
+		if err := s.planRepo.FinishRun(ctx, plan, s.instanceID); err != nil {
+			log.Printf("Warning: failed to save run %s of recurring plan %s: %v", run.RunID, plan.ID.Hex(), err)
+			continue
+		}
+
+		log.Printf("🔁 Recurring plan %s run %s: %s", plan.ID.Hex(), run.RunID, run.Status)
+		runs++
+	}
+
+	return runs, nil
+}
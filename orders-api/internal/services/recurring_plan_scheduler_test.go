@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"orders-api/internal/models"
+)
+
+type MockPlanRunner struct {
+	mock.Mock
+}
+
+func (m *MockPlanRunner) RunPlan(ctx context.Context, plan *models.RecurringPlan) (*models.PlanRun, error) {
+	args := m.Called(ctx, plan)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.PlanRun), args.Error(1)
+}
+
+func TestRecurringPlanScheduler_RunDueOnce(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRecurringPlanRepository)
+	mockRunner := new(MockPlanRunner)
+
+	first, second := newTestPlan(), newTestPlan()
+	scheduler := NewRecurringPlanScheduler(mockRepo, mockRunner, RecurringPlanSchedulerConfig{})
+
+	mockRepo.On("ClaimDuePlan", ctx, mock.Anything, scheduler.instanceID, scheduler.config.LockTTL).Return(first, nil).Once()
+	mockRepo.On("ClaimDuePlan", ctx, mock.Anything, scheduler.instanceID, scheduler.config.LockTTL).Return(second, nil).Once()
+	mockRepo.On("ClaimDuePlan", ctx, mock.Anything, scheduler.instanceID, scheduler.config.LockTTL).Return(nil, nil).Once()
+	mockRunner.On("RunPlan", ctx, first).Return(&models.PlanRun{RunID: "run-1", Status: models.PlanRunStatusSkipped}, nil)
+	mockRunner.On("RunPlan", ctx, second).Return(nil, errors.New("failed to save plan run"))
+	mockRepo.On("FinishRun", ctx, first, scheduler.instanceID).Return(nil)
+
+	runs, err := scheduler.RunDueOnce(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "FinishRun", ctx, second, scheduler.instanceID)
+	mockRunner.AssertExpectations(t)
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// planRunHistoryLimit corridas que se devuelven del historial de un plan
+const planRunHistoryLimit = 100
+
+// PlanOrderCreator crea las órdenes de los planes como si las pidiera el usuario
+type PlanOrderCreator interface {
+	CreateQuote(ctx context.Context, userID int, symbol string, orderType models.OrderType) (*models.Quote, error)
+	CreateOrder(ctx context.Context, req *dto.CreateOrderRequest, userID int) (*models.Order, error)
+}
+
+// PlanEventPublisher publica las notificaciones de los planes recurrentes
+type PlanEventPublisher interface {
+	PublishPlanRunSkipped(ctx context.Context, plan *models.RecurringPlan, run *models.PlanRun) error
+}
+
+// RecurringPlanService administra los planes de compra recurrente (DCA) y corre
+// cada corrida creando una orden market por el monto del plan
+type RecurringPlanService struct {
+	planRepo      repositories.RecurringPlanRepository
+	orders        PlanOrderCreator
+	marketService MarketService
+	publisher     PlanEventPublisher
+}
+
+// NewRecurringPlanService crea el servicio de planes recurrentes
+func NewRecurringPlanService(
+	planRepo repositories.RecurringPlanRepository,
+	orders PlanOrderCreator,
+	marketService MarketService,
+	publisher PlanEventPublisher,
+) *RecurringPlanService {
+	return &RecurringPlanService{
+		planRepo:      planRepo,
+		orders:        orders,
+		marketService: marketService,
+		publisher:     publisher,
+	}
+}
+
+// CreatePlan crea un plan activo cuya primera corrida es start_at (o ahora)
+func (s *RecurringPlanService) CreatePlan(ctx context.Context, req *dto.CreateRecurringPlanRequest, userID int) (*models.RecurringPlan, error) {
+	now := time.Now()
+	amount, firstRun, err := req.Validate(now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOrderValidation, err)
+	}
+
+	cryptoInfo, err := s.marketService.ValidateSymbol(ctx, req.CryptoSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSymbol, err)
+	}
+	if !cryptoInfo.IsActive {
+		return nil, fmt.Errorf("%w for %s", ErrTradingSuspended, req.CryptoSymbol)
+	}
+
+	plan := &models.RecurringPlan{
+		UserID:       userID,
+		CryptoSymbol: req.CryptoSymbol,
+		Amount:       amount,
+		Frequency:    req.Frequency,
+		Status:       models.PlanStatusActive,
+		NextRunAt:    firstRun,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := s.planRepo.Create(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// ListPlans lista los planes del usuario
+func (s *RecurringPlanService) ListPlans(ctx context.Context, userID int) ([]models.RecurringPlan, error) {
+	return s.planRepo.ListByUser(ctx, userID)
+}
+
+// GetPlan obtiene un plan del usuario
+func (s *RecurringPlanService) GetPlan(ctx context.Context, planID string, userID int) (*models.RecurringPlan, error) {
+	plan, err := s.planRepo.GetByID(ctx, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.UserID != userID {
+		return nil, fmt.Errorf("access denied: plan does not belong to user")
+	}
+
+	return plan, nil
+}
+
+// PausePlan deja de correr el plan hasta que se reanude
+func (s *RecurringPlanService) PausePlan(ctx context.Context, planID string, userID int) (*models.RecurringPlan, error) {
+	plan, err := s.GetPlan(ctx, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.planRepo.UpdateStatus(ctx, planID, models.PlanStatusPaused, plan.NextRunAt); err != nil {
+		return nil, err
+	}
+
+	plan.Status = models.PlanStatusPaused
+	return plan, nil
+}
+
+// ResumePlan reactiva el plan desde su próxima corrida futura; las corridas que
+// cayeron mientras estaba pausado no se hacen
+func (s *RecurringPlanService) ResumePlan(ctx context.Context, planID string, userID int) (*models.RecurringPlan, error) {
+	plan, err := s.GetPlan(ctx, planID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	nextRunAt := plan.NextRunAt
+	if plan.Status == models.PlanStatusPaused {
+		nextRunAt = plan.NextRunAfter(time.Now())
+	}
+
+	if err := s.planRepo.UpdateStatus(ctx, planID, models.PlanStatusActive, nextRunAt); err != nil {
+		return nil, err
+	}
+
+	plan.Status = models.PlanStatusActive
+	plan.NextRunAt = nextRunAt
+	return plan, nil
+}
+
+// DeletePlan borra el plan; las órdenes ya creadas no se tocan
+func (s *RecurringPlanService) DeletePlan(ctx context.Context, planID string, userID int) error {
+	if _, err := s.GetPlan(ctx, planID, userID); err != nil {
+		return err
+	}
+
+	return s.planRepo.Delete(ctx, planID)
+}
+
+// ListPlanRuns retorna el historial de corridas del plan, de la más nueva a la más vieja
+func (s *RecurringPlanService) ListPlanRuns(ctx context.Context, planID string, userID int) ([]models.PlanRun, error) {
+	if _, err := s.GetPlan(ctx, planID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.planRepo.ListRuns(ctx, planID, planRunHistoryLimit)
+}
+
+// RunPlan hace la corrida programada del plan: cotiza, calcula la cantidad que compra
+// el monto del plan y crea la orden market. Con saldo insuficiente la corrida se
+// saltea y se publica una notificación. La corrida queda en el historial y el plan
+// pasa a su próxima corrida (lo guarda el scheduler).
+// La orden usa una idempotency key por corrida, así que repetirla tras una caída
+// devuelve la orden ya creada.
+func (s *RecurringPlanService) RunPlan(ctx context.Context, plan *models.RecurringPlan) (*models.PlanRun, error) {
+	now := time.Now()
+	run := &models.PlanRun{
+		RunID:       models.NewPlanRunID(plan.ID.Hex(), plan.NextRunAt),
+		PlanID:      plan.ID.Hex(),
+		UserID:      plan.UserID,
+		ScheduledAt: plan.NextRunAt,
+		RunAt:       now,
+		Amount:      plan.Amount,
+	}
+
+	s.placeOrder(ctx, plan, run)
+
+	if err := s.planRepo.AddRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	if run.Status == models.PlanRunStatusSkipped {
+		if err := s.publisher.PublishPlanRunSkipped(ctx, plan, run); err != nil {
+			log.Printf("Warning: failed to publish skipped run %s: %v", run.RunID, err)
+		}
+	}
+
+	plan.RecordRun(run, now)
+	return run, nil
+}
+
+// placeOrder crea la orden de la corrida y completa su resultado en run
+func (s *RecurringPlanService) placeOrder(ctx context.Context, plan *models.RecurringPlan, run *models.PlanRun) {
+	quote, err := s.orders.CreateQuote(ctx, plan.UserID, plan.CryptoSymbol, models.OrderTypeBuy)
+	if err != nil {
+		run.Status = models.PlanRunStatusFailed
+		run.Reason = fmt.Sprintf("failed to quote %s: %v", plan.CryptoSymbol, err)
+		return
+	}
+
+	run.Price = quote.Price
+	run.Quantity = plan.Amount.Div(quote.Price).RoundDown(8)
+	if !run.Quantity.IsPositive() {
+		run.Status = models.PlanRunStatusFailed
+		run.Reason = fmt.Sprintf("amount %s buys no %s at %s", plan.Amount.String(), plan.CryptoSymbol, quote.Price.String())
+		return
+	}
+
+	order, err := s.orders.CreateOrder(ctx, &dto.CreateOrderRequest{
+		Type:           models.OrderTypeBuy,
+		CryptoSymbol:   plan.CryptoSymbol,
+		Quantity:       run.Quantity.String(),
+		OrderKind:      models.OrderKindMarket,
+		QuoteID:        quote.QuoteID,
+		IdempotencyKey: "plan-" + run.RunID,
+	}, plan.UserID)
+	switch {
+	case err == nil:
+		run.Status = models.PlanRunStatusExecuted
+		run.OrderID = order.ID.Hex()
+		run.OrderStatus = order.Status
+	case strings.Contains(err.Error(), "insufficient balance"):
+		run.Status = models.PlanRunStatusSkipped
+		run.Reason = err.Error()
+	default:
+		run.Status = models.PlanRunStatusFailed
+		run.Reason = err.Error()
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/models"
+)
+
+type MockRecurringPlanRepository struct {
+	mock.Mock
+}
+
+func (m *MockRecurringPlanRepository) Create(ctx context.Context, plan *models.RecurringPlan) error {
+	args := m.Called(ctx, plan)
+	return args.Error(0)
+}
+
+func (m *MockRecurringPlanRepository) GetByID(ctx context.Context, id string) (*models.RecurringPlan, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecurringPlan), args.Error(1)
+}
+
+func (m *MockRecurringPlanRepository) ListByUser(ctx context.Context, userID int) ([]models.RecurringPlan, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.RecurringPlan), args.Error(1)
+}
+
+func (m *MockRecurringPlanRepository) UpdateStatus(ctx context.Context, id string, status models.PlanStatus, nextRunAt time.Time) error {
+	args := m.Called(ctx, id, status, nextRunAt)
+	return args.Error(0)
+}
+
+func (m *MockRecurringPlanRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRecurringPlanRepository) ClaimDuePlan(ctx context.Context, now time.Time, owner string, ttl time.Duration) (*models.RecurringPlan, error) {
+	args := m.Called(ctx, now, owner, ttl)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecurringPlan), args.Error(1)
+}
+
+func (m *MockRecurringPlanRepository) FinishRun(ctx context.Context, plan *models.RecurringPlan, owner string) error {
+	args := m.Called(ctx, plan, owner)
+	return args.Error(0)
+}
+
+func (m *MockRecurringPlanRepository) AddRun(ctx context.Context, run *models.PlanRun) error {
+	args := m.Called(ctx, run)
+	return args.Error(0)
+}
+
+func (m *MockRecurringPlanRepository) ListRuns(ctx context.Context, planID string, limit int) ([]models.PlanRun, error) {
+	args := m.Called(ctx, planID, limit)
+	return args.Get(0).([]models.PlanRun), args.Error(1)
+}
+
+type MockPlanOrderCreator struct {
+	mock.Mock
+}
+
+func (m *MockPlanOrderCreator) CreateQuote(ctx context.Context, userID int, symbol string, orderType models.OrderType) (*models.Quote, error) {
+	args := m.Called(ctx, userID, symbol, orderType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Quote), args.Error(1)
+}
+
+func (m *MockPlanOrderCreator) CreateOrder(ctx context.Context, req *dto.CreateOrderRequest, userID int) (*models.Order, error) {
+	args := m.Called(ctx, req, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Order), args.Error(1)
+}
+
+type MockPlanEventPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPlanEventPublisher) PublishPlanRunSkipped(ctx context.Context, plan *models.RecurringPlan, run *models.PlanRun) error {
+	args := m.Called(ctx, plan, run)
+	return args.Error(0)
+}
+
+func newTestPlan() *models.RecurringPlan {
+	return &models.RecurringPlan{
+		ID:           primitive.NewObjectID(),
+		UserID:       1,
+		CryptoSymbol: "BTC",
+		Amount:       decimal.NewFromInt(100),
+		Frequency:    models.PlanFrequencyWeekly,
+		Status:       models.PlanStatusActive,
+		NextRunAt:    time.Now().Add(-time.Minute),
+	}
+}
+
+func TestRecurringPlanService_CreatePlan(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo := new(MockRecurringPlanRepository)
+		mockMarket := new(MockMarketService)
+		service := NewRecurringPlanService(mockRepo, new(MockPlanOrderCreator), mockMarket, new(MockPlanEventPublisher))
+
+		mockMarket.On("ValidateSymbol", ctx, "BTC").Return(&CryptoInfo{Symbol: "BTC", IsActive: true}, nil)
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.RecurringPlan")).Return(nil)
+
+		plan, err := service.CreatePlan(ctx, &dto.CreateRecurringPlanRequest{
+			CryptoSymbol: "BTC",
+			Amount:       "100",
+			Frequency:    models.PlanFrequencyWeekly,
+		}, 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PlanStatusActive, plan.Status)
+		assert.True(t, plan.Amount.Equal(decimal.NewFromInt(100)))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("amount out of range", func(t *testing.T) {
+		service := NewRecurringPlanService(new(MockRecurringPlanRepository), new(MockPlanOrderCreator), new(MockMarketService), new(MockPlanEventPublisher))
+
+		_, err := service.CreatePlan(ctx, &dto.CreateRecurringPlanRequest{
+			CryptoSymbol: "BTC",
+			Amount:       "0.5",
+			Frequency:    models.PlanFrequencyDaily,
+		}, 1)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "validation error")
+	})
+}
+
+func TestRecurringPlanService_RunPlan(t *testing.T) {
+	ctx := context.Background()
+	quote := &models.Quote{QuoteID: "quote-token", Price: decimal.NewFromInt(40000)}
+
+	t.Run("executed", func(t *testing.T) {
+		mockRepo := new(MockRecurringPlanRepository)
+		mockOrders := new(MockPlanOrderCreator)
+		mockPublisher := new(MockPlanEventPublisher)
+		service := NewRecurringPlanService(mockRepo, mockOrders, new(MockMarketService), mockPublisher)
+		plan := newTestPlan()
+		scheduled := plan.NextRunAt
+		order := &models.Order{ID: primitive.NewObjectID(), Status: models.OrderStatusExecuted}
+
+		mockOrders.On("CreateQuote", ctx, 1, "BTC", models.OrderTypeBuy).Return(quote, nil)
+		mockOrders.On("CreateOrder", ctx, mock.MatchedBy(func(req *dto.CreateOrderRequest) bool {
+			return req.OrderKind == models.OrderKindMarket &&
+				req.Quantity == "0.0025" &&
+				req.QuoteID == "quote-token" &&
+				req.IdempotencyKey == "plan-"+models.NewPlanRunID(plan.ID.Hex(), scheduled)
+		}), 1).Return(order, nil)
+		mockRepo.On("AddRun", ctx, mock.AnythingOfType("*models.PlanRun")).Return(nil)
+
+		run, err := service.RunPlan(ctx, plan)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PlanRunStatusExecuted, run.Status)
+		assert.Equal(t, order.ID.Hex(), run.OrderID)
+		assert.Equal(t, 1, plan.RunCount)
+		assert.True(t, plan.NextRunAt.After(time.Now()))
+		mockOrders.AssertExpectations(t)
+		mockPublisher.AssertNotCalled(t, "PublishPlanRunSkipped", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("insufficient balance skips and notifies", func(t *testing.T) {
+		mockRepo := new(MockRecurringPlanRepository)
+		mockOrders := new(MockPlanOrderCreator)
+		mockPublisher := new(MockPlanEventPublisher)
+		service := NewRecurringPlanService(mockRepo, mockOrders, new(MockMarketService), mockPublisher)
+		plan := newTestPlan()
+
+		mockOrders.On("CreateQuote", ctx, 1, "BTC", models.OrderTypeBuy).Return(quote, nil)
+		mockOrders.On("CreateOrder", ctx, mock.Anything, 1).Return(nil, errors.New("insufficient balance: required 101, available 50"))
+		mockRepo.On("AddRun", ctx, mock.AnythingOfType("*models.PlanRun")).Return(nil)
+		mockPublisher.On("PublishPlanRunSkipped", ctx, plan, mock.AnythingOfType("*models.PlanRun")).Return(nil)
+
+		run, err := service.RunPlan(ctx, plan)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PlanRunStatusSkipped, run.Status)
+		assert.Contains(t, run.Reason, "insufficient balance")
+		assert.Equal(t, 1, plan.SkipCount)
+		mockPublisher.AssertExpectations(t)
+	})
+
+	t.Run("quote failure is recorded as failed", func(t *testing.T) {
+		mockRepo := new(MockRecurringPlanRepository)
+		mockOrders := new(MockPlanOrderCreator)
+		mockPublisher := new(MockPlanEventPublisher)
+		service := NewRecurringPlanService(mockRepo, mockOrders, new(MockMarketService), mockPublisher)
+		plan := newTestPlan()
+
+		mockOrders.On("CreateQuote", ctx, 1, "BTC", models.OrderTypeBuy).Return(nil, errors.New("market unavailable"))
+		mockRepo.On("AddRun", ctx, mock.AnythingOfType("*models.PlanRun")).Return(nil)
+
+		run, err := service.RunPlan(ctx, plan)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PlanRunStatusFailed, run.Status)
+		assert.Equal(t, 1, plan.FailCount)
+		mockOrders.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRecurringPlanService_ResumePlan(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRecurringPlanRepository)
+	service := NewRecurringPlanService(mockRepo, new(MockPlanOrderCreator), new(MockMarketService), new(MockPlanEventPublisher))
+	plan := newTestPlan()
+	plan.Status = models.PlanStatusPaused
+	plan.NextRunAt = time.Now().AddDate(0, 0, -10)
+
+	mockRepo.On("GetByID", ctx, plan.ID.Hex()).Return(plan, nil)
+	mockRepo.On("UpdateStatus", ctx, plan.ID.Hex(), models.PlanStatusActive, mock.MatchedBy(func(next time.Time) bool {
+		return next.After(time.Now())
+	})).Return(nil)
+
+	resumed, err := service.ResumePlan(ctx, plan.ID.Hex(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.PlanStatusActive, resumed.Status)
+	mockRepo.AssertExpectations(t)
+
+	_, err = service.ResumePlan(ctx, plan.ID.Hex(), 2)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+}
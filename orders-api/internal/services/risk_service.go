@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+// HoldingsChecker consulta la posición del usuario en el activo de la orden
+type HoldingsChecker interface {
+	CheckHoldings(ctx context.Context, order *models.Order) (*models.HoldingResult, error)
+}
+
+// RiskChecker controla los límites pre-trade de una orden antes de reservar fondos
+// y ejecutarla. previous es la orden antes de modificarla (nil si es nueva).
+type RiskChecker interface {
+	CheckOrder(ctx context.Context, order *models.Order, previous *models.Order, role string) error
+}
+
+// RiskServiceConfig configuración de la política de riesgo
+type RiskServiceConfig struct {
+	CacheTTL time.Duration // Cada cuánto se relee la política (cambios hechos en otra réplica)
+}
+
+// RiskService aplica los límites pre-trade del rol del usuario: monto por orden,
+// volumen diario, órdenes abiertas, posición por símbolo y banda de precio. La
+// política se guarda en Mongo y los admins la cambian en caliente; mientras nadie
+// la guarde rige la de la configuración. Cada réplica la cachea CacheTTL.
+type RiskService struct {
+	policyRepo    repositories.RiskPolicyRepository
+	orderRepo     repositories.OrderRepository
+	holdings      HoldingsChecker
+	marketService MarketService
+	defaults      *models.RiskPolicy
+	config        RiskServiceConfig
+
+	mu       sync.RWMutex
+	policy   *models.RiskPolicy
+	loadedAt time.Time
+}
+
+// NewRiskService crea el servicio de límites de riesgo
+func NewRiskService(
+	policyRepo repositories.RiskPolicyRepository,
+	orderRepo repositories.OrderRepository,
+	holdings HoldingsChecker,
+	marketService MarketService,
+	defaults *models.RiskPolicy,
+	config RiskServiceConfig,
+) *RiskService {
+	if defaults == nil {
+		defaults = &models.RiskPolicy{ID: models.RiskPolicyID}
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = 30 * time.Second
+	}
+
+	return &RiskService{
+		policyRepo:    policyRepo,
+		orderRepo:     orderRepo,
+		holdings:      holdings,
+		marketService: marketService,
+		defaults:      defaults,
+		config:        config,
+	}
+}
+
+// GetPolicy retorna la política vigente (cacheada)
+func (s *RiskService) GetPolicy(ctx context.Context) (*models.RiskPolicy, error) {
+	s.mu.RLock()
+	policy, loadedAt := s.policy, s.loadedAt
+	s.mu.RUnlock()
+
+	if policy != nil && time.Since(loadedAt) < s.config.CacheTTL {
+		return policy, nil
+	}
+
+	fresh, err := s.load(ctx)
+	if err != nil {
+		// Mejor controlar con la política anterior que frenar las órdenes
+		if policy != nil {
+			log.Printf("Warning: failed to reload risk policy, using cached version %d: %v", policy.Version, err)
+			return policy, nil
+		}
+		return nil, err
+	}
+
+	return fresh, nil
+}
+
+// UpdatePolicy reemplaza la política vigente. Con req.Version se rechaza si otra
+// escritura la cambió desde que el admin la leyó.
+func (s *RiskService) UpdatePolicy(ctx context.Context, req *dto.UpdateRiskPolicyRequest, adminID int) (*models.RiskPolicy, error) {
+	policy := req.ToPolicy()
+	if err := policy.Validate(); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	current, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.Version != nil && *req.Version != current.Version {
+		return nil, fmt.Errorf("failed to update risk policy: %w", repositories.ErrRiskPolicyConflict)
+	}
+
+	policy.Version = current.Version
+	policy.UpdatedAt = time.Now()
+	policy.UpdatedBy = adminID
+
+	if err := s.policyRepo.Save(ctx, policy); err != nil {
+		return nil, fmt.Errorf("failed to update risk policy: %w", err)
+	}
+
+	s.cache(policy)
+	log.Printf("🛡️ Risk policy updated to version %d by admin %d", policy.Version, adminID)
+
+	return policy, nil
+}
+
+// CheckOrder controla la orden contra los límites del rol. Retorna un
+// *models.RiskRejection si algún límite no se cumple; cualquier otro error es una
+// falla al consultar (y la orden tampoco se acepta).
+func (s *RiskService) CheckOrder(ctx context.Context, order *models.Order, previous *models.Order, role string) error {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check risk limits: %w", err)
+	}
+	limits := policy.LimitsFor(role)
+
+	// 1. Monto de la orden
+	if limits.MaxOrderNotional.IsPositive() && order.TotalAmount.GreaterThan(limits.MaxOrderNotional) {
+		return &models.RiskRejection{
+			Code:    models.RiskReasonMaxOrderNotional,
+			Message: fmt.Sprintf("order notional %s exceeds the maximum of %s", order.TotalAmount.String(), limits.MaxOrderNotional.String()),
+			Limit:   limits.MaxOrderNotional,
+			Actual:  order.TotalAmount,
+		}
+	}
+
+	// 2. Banda de precio de las órdenes limit
+	if limits.PriceBand.IsPositive() && order.OrderKind == models.OrderKindLimit {
+		if err := s.checkPriceBand(ctx, order, limits.PriceBand); err != nil {
+			return err
+		}
+	}
+
+	// 3. Órdenes abiertas (una modificación no abre otra)
+	capQuantity, hasCap := limits.PositionCap(order.CryptoSymbol)
+	hasCap = hasCap && order.Type == models.OrderTypeBuy
+	var exposure *models.OpenExposure
+	if (limits.MaxOpenOrders > 0 && previous == nil) || hasCap {
+		exposure, err = s.orderRepo.GetOpenExposure(ctx, order.UserID, order.CryptoSymbol)
+		if err != nil {
+			return fmt.Errorf("failed to check risk limits: %w", err)
+		}
+	}
+	if limits.MaxOpenOrders > 0 && previous == nil && exposure.OpenOrders >= int64(limits.MaxOpenOrders) {
+		return &models.RiskRejection{
+			Code:    models.RiskReasonMaxOpenOrders,
+			Message: fmt.Sprintf("already %d open orders, the maximum is %d", exposure.OpenOrders, limits.MaxOpenOrders),
+			Limit:   decimal.NewFromInt(int64(limits.MaxOpenOrders)),
+			Actual:  decimal.NewFromInt(exposure.OpenOrders + 1),
+		}
+	}
+
+	// 4. Volumen operado en el día (UTC)
+	if limits.MaxDailyVolume.IsPositive() {
+		now := time.Now().UTC()
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		volume, err := s.orderRepo.GetUserVolume(ctx, order.UserID, startOfDay)
+		if err != nil {
+			return fmt.Errorf("failed to check risk limits: %w", err)
+		}
+		projected := volume.Add(order.TotalAmount)
+		if projected.GreaterThan(limits.MaxDailyVolume) {
+			return &models.RiskRejection{
+				Code: models.RiskReasonMaxDailyVolume,
+				Message: fmt.Sprintf("daily volume would reach %s, the maximum is %s (%s traded today)",
+					projected.String(), limits.MaxDailyVolume.String(), volume.String()),
+				Limit:  limits.MaxDailyVolume,
+				Actual: projected,
+			}
+		}
+	}
+
+	// 5. Posición máxima del símbolo: lo que tiene, más sus compras abiertas, más esta
+	if hasCap {
+		holding, err := s.holdings.CheckHoldings(ctx, order)
+		if err != nil {
+			return fmt.Errorf("failed to check risk limits: %w", err)
+		}
+		pending := exposure.PendingBuyQuantity
+		if previous != nil && previous.Type == models.OrderTypeBuy && previous.IsOpen() {
+			pending = decimal.Max(pending.Sub(previous.RemainingQuantity()), decimal.Zero)
+		}
+		projected := holding.Quantity.Add(pending).Add(order.RemainingQuantity())
+		if projected.GreaterThan(capQuantity) {
+			return &models.RiskRejection{
+				Code: models.RiskReasonSymbolPositionCap,
+				Message: fmt.Sprintf("%s position would reach %s, the maximum is %s",
+					order.CryptoSymbol, projected.String(), capQuantity.String()),
+				Limit:  capQuantity,
+				Actual: projected,
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkPriceBand rechaza precios límite que se desvían del de mercado más que band
+func (s *RiskService) checkPriceBand(ctx context.Context, order *models.Order, band decimal.Decimal) error {
+	marketPrice, err := s.marketService.GetCurrentPrice(ctx, order.CryptoSymbol)
+	if err != nil {
+		return fmt.Errorf("failed to check risk limits: %w", err)
+	}
+	if !marketPrice.IsPositive() {
+		return nil
+	}
+
+	deviation := order.Price.Sub(marketPrice).Abs().Div(marketPrice)
+	if deviation.LessThanOrEqual(band) {
+		return nil
+	}
+
+	return &models.RiskRejection{
+		Code: models.RiskReasonPriceBand,
+		Message: fmt.Sprintf("limit price %s is %s%% away from the market price %s, the maximum is %s%%",
+			order.Price.String(), deviation.Mul(decimal.NewFromInt(100)).StringFixed(2),
+			marketPrice.String(), band.Mul(decimal.NewFromInt(100)).String()),
+		Limit:  band,
+		Actual: deviation.Round(4),
+	}
+}
+
+// load lee la política guardada (o la por defecto si no hay) y la cachea
+func (s *RiskService) load(ctx context.Context) (*models.RiskPolicy, error) {
+	policy, err := s.policyRepo.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		defaults := *s.defaults
+		policy = &defaults
+	}
+
+	s.cache(policy)
+	return policy, nil
+}
+
+func (s *RiskService) cache(policy *models.RiskPolicy) {
+	s.mu.Lock()
+	s.policy = policy
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+}
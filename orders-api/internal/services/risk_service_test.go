@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"orders-api/internal/dto"
+	"orders-api/internal/models"
+	"orders-api/internal/repositories"
+)
+
+type MockRiskPolicyRepository struct {
+	mock.Mock
+}
+
+func (m *MockRiskPolicyRepository) Get(ctx context.Context) (*models.RiskPolicy, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RiskPolicy), args.Error(1)
+}
+
+func (m *MockRiskPolicyRepository) Save(ctx context.Context, policy *models.RiskPolicy) error {
+	args := m.Called(ctx, policy)
+	if args.Error(0) == nil {
+		policy.Version++
+	}
+	return args.Error(0)
+}
+
+type MockHoldingsChecker struct {
+	mock.Mock
+}
+
+func (m *MockHoldingsChecker) CheckHoldings(ctx context.Context, order *models.Order) (*models.HoldingResult, error) {
+	args := m.Called(ctx, order)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HoldingResult), args.Error(1)
+}
+
+// newRiskTestService arma el servicio con la política guardada policy (límites del rol "user")
+func newRiskTestService(limits models.RiskLimits) (*RiskService, *MockOrderRepository, *MockHoldingsChecker, *MockMarketService) {
+	policyRepo := new(MockRiskPolicyRepository)
+	policyRepo.On("Get", mock.Anything).Return(&models.RiskPolicy{
+		ID: models.RiskPolicyID,
+		Roles: map[string]models.RiskLimits{
+			models.DefaultRiskRole: limits,
+			"admin":                {},
+		},
+	}, nil)
+
+	orderRepo := new(MockOrderRepository)
+	holdings := new(MockHoldingsChecker)
+	market := new(MockMarketService)
+	return NewRiskService(policyRepo, orderRepo, holdings, market, nil, RiskServiceConfig{}), orderRepo, holdings, market
+}
+
+// newRiskOrder orden limit de compra de qty BTC a price
+func newRiskOrder(price int64, qty float64) *models.Order {
+	order := newBookOrder(models.OrderTypeBuy, price, qty)
+	order.TotalAmount = order.Quantity.Mul(order.Price)
+	return order
+}
+
+func assertRiskRejection(t *testing.T, err error, code models.RiskReasonCode) *models.RiskRejection {
+	t.Helper()
+	var rejection *models.RiskRejection
+	require.ErrorAs(t, err, &rejection)
+	assert.Equal(t, code, rejection.Code)
+	return rejection
+}
+
+func TestRiskService_CheckOrder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("max order notional", func(t *testing.T) {
+		risk, _, _, _ := newRiskTestService(models.RiskLimits{MaxOrderNotional: decimal.NewFromInt(10000)})
+
+		err := risk.CheckOrder(ctx, newRiskOrder(50000, 0.5), nil, "user")
+
+		rejection := assertRiskRejection(t, err, models.RiskReasonMaxOrderNotional)
+		assert.True(t, rejection.Actual.Equal(decimal.NewFromInt(25000)))
+	})
+
+	t.Run("limits are per role", func(t *testing.T) {
+		risk, _, _, _ := newRiskTestService(models.RiskLimits{MaxOrderNotional: decimal.NewFromInt(10000)})
+
+		assert.NoError(t, risk.CheckOrder(ctx, newRiskOrder(50000, 0.5), nil, "admin"))
+		// Un rol sin límites propios usa los del rol por defecto
+		assertRiskRejection(t, risk.CheckOrder(ctx, newRiskOrder(50000, 0.5), nil, "trader"), models.RiskReasonMaxOrderNotional)
+	})
+
+	t.Run("price band", func(t *testing.T) {
+		risk, _, _, market := newRiskTestService(models.RiskLimits{PriceBand: decimal.NewFromFloat(0.1)})
+		market.On("GetCurrentPrice", ctx, "BTC").Return(decimal.NewFromInt(50000), nil)
+
+		assert.NoError(t, risk.CheckOrder(ctx, newRiskOrder(46000, 0.1), nil, "user"))
+
+		err := risk.CheckOrder(ctx, newRiskOrder(40000, 0.1), nil, "user")
+		rejection := assertRiskRejection(t, err, models.RiskReasonPriceBand)
+		assert.True(t, rejection.Actual.Equal(decimal.NewFromFloat(0.2)))
+	})
+
+	t.Run("max open orders only counts new orders", func(t *testing.T) {
+		risk, orderRepo, _, _ := newRiskTestService(models.RiskLimits{MaxOpenOrders: 3})
+		orderRepo.On("GetOpenExposure", ctx, 1, "BTC").Return(&models.OpenExposure{OpenOrders: 3}, nil)
+
+		assertRiskRejection(t, risk.CheckOrder(ctx, newRiskOrder(50000, 0.1), nil, "user"), models.RiskReasonMaxOpenOrders)
+
+		amended := newRiskOrder(50000, 0.2)
+		assert.NoError(t, risk.CheckOrder(ctx, amended, newRiskOrder(50000, 0.1), "user"))
+	})
+
+	t.Run("max daily volume", func(t *testing.T) {
+		risk, orderRepo, _, _ := newRiskTestService(models.RiskLimits{MaxDailyVolume: decimal.NewFromInt(100000)})
+		orderRepo.On("GetUserVolume", ctx, 1, mock.AnythingOfType("time.Time")).Return(decimal.NewFromInt(90000), nil)
+
+		assert.NoError(t, risk.CheckOrder(ctx, newRiskOrder(50000, 0.2), nil, "user"))
+
+		err := risk.CheckOrder(ctx, newRiskOrder(50000, 0.3), nil, "user")
+		rejection := assertRiskRejection(t, err, models.RiskReasonMaxDailyVolume)
+		assert.True(t, rejection.Actual.Equal(decimal.NewFromInt(105000)))
+	})
+
+	t.Run("symbol position cap counts holdings and open buys", func(t *testing.T) {
+		risk, orderRepo, holdings, _ := newRiskTestService(models.RiskLimits{
+			PositionCaps: []models.SymbolPositionCap{{Symbol: "BTC", MaxQuantity: decimal.NewFromInt(2)}},
+		})
+		orderRepo.On("GetOpenExposure", ctx, 1, "BTC").Return(&models.OpenExposure{OpenOrders: 1, PendingBuyQuantity: decimal.NewFromFloat(0.5)}, nil)
+		holdings.On("CheckHoldings", ctx, mock.Anything).Return(&models.HoldingResult{Symbol: "BTC", Quantity: decimal.NewFromInt(1)}, nil)
+
+		assert.NoError(t, risk.CheckOrder(ctx, newRiskOrder(50000, 0.5), nil, "user"))
+
+		err := risk.CheckOrder(ctx, newRiskOrder(50000, 0.6), nil, "user")
+		rejection := assertRiskRejection(t, err, models.RiskReasonSymbolPositionCap)
+		assert.True(t, rejection.Actual.Equal(decimal.NewFromFloat(2.1)))
+
+		// Al modificar una compra abierta su cantidad anterior no se cuenta dos veces
+		assert.NoError(t, risk.CheckOrder(ctx, newRiskOrder(50000, 1), newRiskOrder(50000, 0.5), "user"))
+
+		// Las ventas no suman posición
+		sell := newRiskOrder(50000, 5)
+		sell.Type = models.OrderTypeSell
+		assert.NoError(t, risk.CheckOrder(ctx, sell, nil, "user"))
+	})
+
+	t.Run("lookup failure is not a rejection", func(t *testing.T) {
+		risk, orderRepo, _, _ := newRiskTestService(models.RiskLimits{MaxOpenOrders: 3})
+		orderRepo.On("GetOpenExposure", ctx, 1, "BTC").Return(nil, errors.New("timeout"))
+
+		err := risk.CheckOrder(ctx, newRiskOrder(50000, 0.1), nil, "user")
+
+		var rejection *models.RiskRejection
+		assert.Error(t, err)
+		assert.False(t, errors.As(err, &rejection))
+	})
+}
+
+func TestRiskService_UpdatePolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("saves the normalized policy", func(t *testing.T) {
+		policyRepo := new(MockRiskPolicyRepository)
+		policyRepo.On("Get", ctx).Return(nil, nil)
+		policyRepo.On("Save", ctx, mock.AnythingOfType("*models.RiskPolicy")).Return(nil)
+
+		risk := NewRiskService(policyRepo, nil, nil, nil, nil, RiskServiceConfig{})
+		policy, err := risk.UpdatePolicy(ctx, &dto.UpdateRiskPolicyRequest{
+			Roles: map[string]models.RiskLimits{
+				"User": {
+					MaxOpenOrders: 10,
+					PositionCaps:  []models.SymbolPositionCap{{Symbol: "btc", MaxQuantity: decimal.NewFromInt(5)}},
+				},
+			},
+		}, 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), policy.Version)
+		limits := policy.LimitsFor("user")
+		assert.Equal(t, 10, limits.MaxOpenOrders)
+		assert.Equal(t, "BTC", limits.PositionCaps[0].Symbol)
+	})
+
+	t.Run("stale version is rejected", func(t *testing.T) {
+		policyRepo := new(MockRiskPolicyRepository)
+		policyRepo.On("Get", ctx).Return(&models.RiskPolicy{ID: models.RiskPolicyID, Version: 3}, nil)
+		staleVersion := int64(2)
+
+		risk := NewRiskService(policyRepo, nil, nil, nil, nil, RiskServiceConfig{})
+		_, err := risk.UpdatePolicy(ctx, &dto.UpdateRiskPolicyRequest{Roles: map[string]models.RiskLimits{}, Version: &staleVersion}, 7)
+
+		assert.ErrorIs(t, err, repositories.ErrRiskPolicyConflict)
+		policyRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"orders-api/internal/repositories"
+)
+
+// SagaRecovererConfig configuración del recoverer de sagas de ejecución
+type SagaRecovererConfig struct {
+	Interval   time.Duration
+	StaleAfter time.Duration
+	BatchSize  int
+	LockTTL    time.Duration
+}
+
+// SagaRecoverer busca órdenes que quedaron en status executing sin avances
+// (la instancia que las ejecutaba se cayó) y retoma o compensa su saga.
+// Cada orden se toma con un lock en Mongo, así varias réplicas pueden correrlo.
+type SagaRecoverer struct {
+	orderRepo  repositories.OrderRepository
+	saga       *SagaCoordinator
+	config     SagaRecovererConfig
+	instanceID string
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewSagaRecoverer crea una nueva instancia del recoverer
+func NewSagaRecoverer(
+	orderRepo repositories.OrderRepository,
+	saga *SagaCoordinator,
+	config SagaRecovererConfig,
+) *SagaRecoverer {
+	if config.Interval <= 0 {
+		config.Interval = 30 * time.Second
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = 2 * time.Minute
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 50
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 30 * time.Second
+	}
+
+	return &SagaRecoverer{
+		orderRepo:  orderRepo,
+		saga:       saga,
+		config:     config,
+		instanceID: newInstanceID(),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// Start inicia el loop del recoverer en background; la primera pasada es inmediata
+// para retomar lo que dejó la instancia anterior
+func (r *SagaRecoverer) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := r.RecoverOnce(ctx); err != nil {
+				log.Printf("Warning: saga recoverer run failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop detiene el recoverer y espera a que termine la pasada en curso
+func (r *SagaRecoverer) Stop() {
+	close(r.stopCh)
+	r.wg.Wait()
+}
+
+// RecoverOnce retoma hasta BatchSize sagas trabadas y retorna cuántas terminaron
+// (ejecutadas o compensadas)
+func (r *SagaRecoverer) RecoverOnce(ctx context.Context) (int, error) {
+	finished := 0
+
+	for i := 0; i < r.config.BatchSize; i++ {
+		order, err := r.orderRepo.ClaimStalledExecution(ctx, time.Now().Add(-r.config.StaleAfter), r.instanceID, r.config.LockTTL)
+		if err != nil {
+			return finished, fmt.Errorf("failed to claim stalled execution: %w", err)
+		}
+		if order == nil {
+			break
+		}
+
+		if err := r.saga.Resume(ctx, order); err != nil {
+			// Si quedó a mitad de camino se vuelve a intentar cuando pase StaleAfter
+			log.Printf("Warning: failed to recover execution of order %s: %v", order.ID.Hex(), err)
+		}
+
+		if order.Saga != nil && order.Saga.IsFinished() {
+			finished++
+		}
+	}
+
+	return finished, nil
+}
@@ -97,12 +97,30 @@ func (d *Database) CreateIndexes() error {
 			},
 			Options: options.Index().SetName("status_created_idx"),
 		},
+		{
+			// Páginas del matcher sobre las órdenes abiertas
+			Keys: bson.D{
+				{"status", 1},
+				{"_id", 1},
+			},
+			Options: options.Index().SetName("status_id_idx"),
+		},
 		{
 			Keys: bson.D{
 				{"order_number", 1},
 			},
 			Options: options.Index().SetUnique(true).SetName("order_number_unique_idx"),
 		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+				{"idempotency_key", 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"idempotency_key": bson.M{"$exists": true}}).
+				SetName("user_idempotency_key_unique_idx"),
+		},
 		{
 			Keys: bson.D{
 				{"crypto_symbol", 1},
@@ -150,6 +168,89 @@ func (d *Database) CreateIndexes() error {
 			},
 			Options: options.Index().SetName("updated_at_idx"),
 		},
+		{
+			Keys: bson.D{
+				{"status", 1},
+				{"updated_at", 1},
+			},
+			Options: options.Index().SetName("status_updated_idx"),
+		},
+		{
+			// Volumen de 30 días de las órdenes anteriores a last_fill_at
+			Keys: bson.D{
+				{"user_id", 1},
+				{"status", 1},
+				{"executed_at", -1},
+			},
+			Options: options.Index().SetName("user_status_executed_idx"),
+		},
+		{
+			// Volumen de 30 días para los escalones de comisiones
+			Keys: bson.D{
+				{"user_id", 1},
+				{"last_fill_at", -1},
+			},
+			Options: options.Index().SetName("user_last_fill_idx"),
+		},
+		{
+			// Sweeper de órdenes gtd vencidas
+			Keys: bson.D{
+				{"status", 1},
+				{"expires_at", 1},
+			},
+			Options: options.Index().SetName("status_expires_idx"),
+		},
+		{
+			// Listados paginados por cursor: orden estable por campo y _id
+			Keys: bson.D{
+				{"user_id", 1},
+				{"created_at", -1},
+				{"_id", -1},
+			},
+			Options: options.Index().SetName("user_created_id_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+				{"total_amount", -1},
+				{"_id", -1},
+			},
+			Options: options.Index().SetName("user_total_id_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+				{"price", -1},
+				{"_id", -1},
+			},
+			Options: options.Index().SetName("user_price_id_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+				{"status", 1},
+				{"created_at", -1},
+				{"_id", -1},
+			},
+			Options: options.Index().SetName("user_status_created_id_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+				{"order_kind", 1},
+				{"created_at", -1},
+				{"_id", -1},
+			},
+			Options: options.Index().SetName("user_kind_created_id_idx"),
+		},
+		{
+			// Listado de admins sin filtro de usuario
+			Keys: bson.D{
+				{"created_at", -1},
+				{"_id", -1},
+			},
+			Options: options.Index().SetName("created_id_idx"),
+		},
 	}
 
 	_, err := ordersCollection.Indexes().CreateMany(ctx, indexes)
@@ -157,6 +258,118 @@ func (d *Database) CreateIndexes() error {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
 
+	outboxIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"event_id", 1},
+			},
+			Options: options.Index().SetUnique(true).SetName("event_id_unique_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"status", 1},
+				{"next_attempt_at", 1},
+				{"created_at", 1},
+			},
+			Options: options.Index().SetName("status_next_attempt_idx"),
+		},
+		{
+			// Eventos sin publicar en orden de creación, de donde el relay saca la cabeza de cada orden
+			Keys: bson.D{
+				{"status", 1},
+				{"created_at", 1},
+				{"_id", 1},
+			},
+			Options: options.Index().SetName("status_created_id_idx"),
+		},
+		{
+			// Los eventos publicados se borran solos después de 7 días
+			Keys: bson.D{
+				{"published_at", 1},
+			},
+			Options: options.Index().SetName("published_at_ttl_idx").SetExpireAfterSeconds(7 * 24 * 3600),
+		},
+	}
+
+	if _, err := d.Database.Collection("order_outbox").Indexes().CreateMany(ctx, outboxIndexes); err != nil {
+		return fmt.Errorf("failed to create outbox indexes: %w", err)
+	}
+
+	fillIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"fill_id", 1},
+			},
+			Options: options.Index().SetUnique(true).SetName("fill_id_unique_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"order_id", 1},
+				{"sequence", 1},
+			},
+			Options: options.Index().SetName("order_sequence_idx"),
+		},
+	}
+
+	if _, err := d.Database.Collection("order_fills").Indexes().CreateMany(ctx, fillIndexes); err != nil {
+		return fmt.Errorf("failed to create fill indexes: %w", err)
+	}
+
+	planIndexes := []mongo.IndexModel{
+		{
+			// Scheduler: planes activos con corrida vencida
+			Keys: bson.D{
+				{"status", 1},
+				{"next_run_at", 1},
+			},
+			Options: options.Index().SetName("status_next_run_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+				{"created_at", -1},
+			},
+			Options: options.Index().SetName("user_created_idx"),
+		},
+	}
+	if _, err := d.Database.Collection("recurring_plans").Indexes().CreateMany(ctx, planIndexes); err != nil {
+		return fmt.Errorf("failed to create recurring plan indexes: %w", err)
+	}
+
+	runIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"run_id", 1}},
+			Options: options.Index().SetUnique(true).SetName("run_id_unique_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"plan_id", 1},
+				{"scheduled_at", -1},
+			},
+			Options: options.Index().SetName("plan_scheduled_idx"),
+		},
+	}
+	if _, err := d.Database.Collection("recurring_plan_runs").Indexes().CreateMany(ctx, runIndexes); err != nil {
+		return fmt.Errorf("failed to create plan run indexes: %w", err)
+	}
+
+	auditIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"created_at", -1}},
+			Options: options.Index().SetName("created_at_idx"),
+		},
+		{
+			Keys: bson.D{
+				{"admin_id", 1},
+				{"created_at", -1},
+			},
+			Options: options.Index().SetName("admin_created_idx"),
+		},
+	}
+	if _, err := d.Database.Collection("admin_audit_log").Indexes().CreateMany(ctx, auditIndexes); err != nil {
+		return fmt.Errorf("failed to create admin audit indexes: %w", err)
+	}
+
 	log.Println("MongoDB indexes created successfully")
 	return nil
 }
@@ -203,6 +416,15 @@ func (d *Database) ExecuteTransaction(ctx context.Context, fn func(mongo.Session
 	return result, nil
 }
 
+// RunInTransaction ejecuta fn dentro de una transacción; las operaciones que usen
+// el contexto recibido por fn se confirman o descartan juntas
+func (d *Database) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := d.ExecuteTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
 func (d *Database) GetStats() (*DatabaseStats, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
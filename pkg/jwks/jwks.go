@@ -0,0 +1,193 @@
+// Package jwks verifies the tokens users-api signs: it fetches and caches the
+// public keys users-api publishes at /.well-known/jwks.json.
+package jwks
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefetchInterval limits how often an unknown kid triggers a fetch, so
+// tokens with made-up kids cannot flood users-api
+const minRefetchInterval = 10 * time.Second
+
+// Cache keeps the public keys users-api publishes at /.well-known/jwks.json.
+// The set is fetched again when it is older than the TTL or when a token names
+// a kid that is not in it, which happens right after users-api rotates its key.
+type Cache struct {
+	url        string
+	ttl        time.Duration
+	httpClient *http.Client
+
+	fetchMu     sync.Mutex
+	mu          sync.RWMutex
+	keys        map[string]cachedKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+type cachedKey struct {
+	alg string
+	key interface{}
+}
+
+// Set is a JSON Web Key Set as served by users-api
+type Set struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey is a key of the set as defined in RFC 7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+// NewCache returns a cache of the key set at url, fetched again after ttl
+// (5 minutes when ttl is not positive)
+func NewCache(url string, ttl time.Duration) *Cache {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	return &Cache{
+		url:        url,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		keys:       make(map[string]cachedKey),
+	}
+}
+
+// Key returns the public key with kid, which must be published for alg
+func (c *Cache) Key(kid, alg string) (interface{}, error) {
+	if kid == "" {
+		return nil, fmt.Errorf("token has no key id")
+	}
+
+	key, found, fresh := c.lookup(kid)
+	if !found || !fresh {
+		if err := c.refresh(!found); err != nil && !found {
+			return nil, err
+		}
+		// A failed refresh keeps the previous keys, so users-api being briefly
+		// unreachable does not reject every token
+		key, found, _ = c.lookup(kid)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.alg != alg {
+		return nil, fmt.Errorf("unexpected signing method: %v", alg)
+	}
+	return key.key, nil
+}
+
+func (c *Cache) lookup(kid string) (cachedKey, bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	key, found := c.keys[kid]
+	return key, found, time.Since(c.fetchedAt) < c.ttl
+}
+
+// refresh fetches the key set unless another request just did. unknownKID
+// fetches even when the cache has not expired yet.
+func (c *Cache) refresh(unknownKID bool) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	c.mu.RLock()
+	fresh := time.Since(c.fetchedAt) < c.ttl
+	recent := time.Since(c.lastAttempt) < minRefetchInterval
+	c.mu.RUnlock()
+	if recent || (fresh && !unknownKID) {
+		return nil
+	}
+
+	c.mu.Lock()
+	c.lastAttempt = time.Now()
+	c.mu.Unlock()
+
+	keys, err := c.fetch()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Cache) fetch() (map[string]cachedKey, error) {
+	resp, err := c.httpClient.Get(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set Set
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]cachedKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in JWKS: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = cachedKey{alg: jwk.Alg, key: key}
+	}
+	return keys, nil
+}
+
+func (k JSONWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
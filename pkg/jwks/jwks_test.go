@@ -0,0 +1,138 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer serves the public part of keys the way users-api does
+type testServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	set     Set
+	fetches int32
+}
+
+func newTestServer(t *testing.T) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.fetches, 1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		json.NewEncoder(w).Encode(s.set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) publish(kid string, key crypto.Signer) {
+	jwk := JSONWebKey{Use: "sig", Kid: kid}
+	switch public := key.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty, jwk.Alg = "RSA", "RS256"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty, jwk.Alg, jwk.Crv = "OKP", "EdDSA", "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set.Keys = append(s.set.Keys, jwk)
+}
+
+func TestCache_Key(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	server := newTestServer(t)
+	server.publish("rsa-1", rsaKey)
+	server.publish("ed-1", edKey)
+	cache := NewCache(server.URL, time.Minute)
+
+	t.Run("RSA", func(t *testing.T) {
+		key, err := cache.Key("rsa-1", "RS256")
+		require.NoError(t, err)
+		assert.True(t, rsaKey.PublicKey.Equal(key))
+	})
+
+	t.Run("Ed25519", func(t *testing.T) {
+		key, err := cache.Key("ed-1", "EdDSA")
+		require.NoError(t, err)
+		assert.True(t, edKey.Public().(ed25519.PublicKey).Equal(key))
+	})
+
+	t.Run("keys are cached", func(t *testing.T) {
+		assert.Equal(t, int32(1), atomic.LoadInt32(&server.fetches))
+	})
+
+	t.Run("key of another algorithm is rejected", func(t *testing.T) {
+		_, err := cache.Key("rsa-1", "EdDSA")
+		assert.EqualError(t, err, "unexpected signing method: EdDSA")
+	})
+
+	t.Run("token without kid", func(t *testing.T) {
+		_, err := cache.Key("", "RS256")
+		assert.EqualError(t, err, "token has no key id")
+	})
+}
+
+func TestCache_RefetchesUnknownKID(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	server := newTestServer(t)
+	server.publish("old", oldKey)
+	cache := NewCache(server.URL, time.Minute)
+
+	_, err := cache.Key("old", "RS256")
+	require.NoError(t, err)
+
+	// users-api rotated its key some time later: the new kid is fetched right away
+	server.publish("new", newKey)
+	cache.lastAttempt = time.Now().Add(-minRefetchInterval)
+	_, err = cache.Key("new", "RS256")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.fetches))
+
+	// Unknown kids do not refetch again until the minimum interval passed
+	_, err = cache.Key("made-up", "RS256")
+	assert.EqualError(t, err, `unknown signing key "made-up"`)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.fetches))
+}
+
+func TestCache_KeepsKeysWhenFetchFails(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	server := newTestServer(t)
+	server.publish("rsa-1", key)
+	cache := NewCache(server.URL, time.Minute)
+
+	_, err := cache.Key("rsa-1", "RS256")
+	require.NoError(t, err)
+
+	// Expire the cache and take users-api down
+	server.Close()
+	cache.fetchedAt = time.Now().Add(-time.Hour)
+	cache.lastAttempt = time.Time{}
+
+	_, err = cache.Key("rsa-1", "RS256")
+	assert.NoError(t, err)
+}
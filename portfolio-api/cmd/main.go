@@ -11,6 +11,10 @@ import (
 
 	"portfolio-api/internal/config"
 	"portfolio-api/internal/controllers"
+	"portfolio-api/internal/middleware"
+	"portfolio-api/internal/repositories"
+	"portfolio-api/internal/repositories/mongodb"
+	"portfolio-api/pkg/database"
 )
 
 func main() {
@@ -38,16 +42,46 @@ func main() {
 		})
 	})
 
+	// Holdings store used by orders-api to reserve and settle sell orders.
+	// Without it the holdings endpoints answer 503 and orders-api rejects the trades.
+	var holdingsRepo repositories.HoldingsRepository
+	db, err := database.NewMongoDB(cfg.Database)
+	if err != nil {
+		logger.WithError(err).Error("Failed to connect to MongoDB, holdings endpoints disabled")
+	} else {
+		defer db.Disconnect()
+		holdingsRepo = mongodb.NewHoldingsRepository(db)
+	}
+
 	// Initialize portfolio controller
 	// Note: This is a simplified implementation - in production, you would initialize
-	// all dependencies (database, services, etc.)
-	controller := controllers.NewPortfolioController(logger, nil) // nil for now
-	
+	// all dependencies (services, cache, etc.)
+	controller := controllers.NewPortfolioController(logger, holdingsRepo)
+
 	// API routes
 	api := router.Group("/api")
 	{
 		portfolio := api.Group("/portfolio")
+		portfolio.Use(middleware.AuthMiddleware(&middleware.AuthConfig{
+			RequireAuth:  cfg.Auth.RequireAuth,
+			JWKSURL:      cfg.Auth.JWKSURL,
+			JWKSCacheTTL: cfg.Auth.JWKSCacheTTL,
+			Issuer:       cfg.Auth.Issuer,
+			Audience:     cfg.Auth.Audience,
+			SkipPaths:    []string{"/api/portfolio/health"},
+		}))
 		controller.RegisterRoutes(portfolio)
+
+		// Holdings changes from orders-api, never reachable with a user token
+		internal := api.Group("/internal/portfolio")
+		internal.Use(middleware.InternalAuthMiddleware(&middleware.ServiceAuthConfig{
+			JWKSURL:      cfg.Auth.JWKSURL,
+			JWKSCacheTTL: cfg.Auth.JWKSCacheTTL,
+			Issuer:       cfg.Auth.Issuer,
+			Audience:     cfg.Auth.ServiceAudience,
+			Logger:       logger,
+		}))
+		controller.RegisterInternalRoutes(internal)
 	}
 
 	port := cfg.Server.Port
@@ -3,7 +3,9 @@ module portfolio-api
 go 1.21
 
 require (
+	cryptosim/jwks v0.0.0
 	github.com/gin-gonic/gin v1.9.1
+	github.com/golang-jwt/jwt/v5 v5.2.0
 	github.com/joho/godotenv v1.4.0
 	github.com/redis/go-redis/v9 v9.3.0
 	github.com/shopspring/decimal v1.3.1
@@ -53,3 +55,5 @@ require (
 	google.golang.org/protobuf v1.30.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace cryptosim/jwks => ../pkg/jwks
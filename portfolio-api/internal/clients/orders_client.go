@@ -328,6 +328,11 @@ type OrderLevel struct {
 
 // makeRequest performs HTTP request with retry logic
 func (oc *OrdersClient) makeRequest(ctx context.Context, method, url string, body interface{}, response interface{}) error {
+	return oc.doRequest(ctx, method, url, body, response, nil)
+}
+
+// doRequest is makeRequest with extra headers, which replace the defaults
+func (oc *OrdersClient) doRequest(ctx context.Context, method, url string, body interface{}, response interface{}, headers map[string]string) error {
 	var lastErr error
 
 	for attempt := 0; attempt <= oc.retries; attempt++ {
@@ -362,6 +367,9 @@ func (oc *OrdersClient) makeRequest(ctx context.Context, method, url string, bod
 		if oc.apiKey != "" {
 			req.Header.Set("X-API-Key", oc.apiKey)
 		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
 
 		resp, err := oc.httpClient.Do(req)
 		if err != nil {
@@ -475,29 +483,55 @@ type AssetBalance struct {
 	Total     decimal.Decimal `json:"total"`
 }
 
-// SimulateOrder simulates order execution without actually placing it
-func (oc *OrdersClient) SimulateOrder(ctx context.Context, req *CreateOrderRequest) (*OrderSimulation, error) {
-	url := fmt.Sprintf("%s/orders/simulate", oc.baseURL)
+// SimulateOrderRequest is the body of orders-api's POST /api/v1/orders/simulate
+type SimulateOrderRequest struct {
+	Type         string `json:"type"`       // "buy", "sell"
+	OrderKind    string `json:"order_kind"` // "market", "limit", "stop_market", "stop_limit", "take_profit"
+	CryptoSymbol string `json:"crypto_symbol"`
+	Quantity     string `json:"quantity"`
+	OrderPrice   string `json:"order_price,omitempty"`
+	TriggerPrice string `json:"trigger_price,omitempty"`
+	TimeInForce  string `json:"time_in_force,omitempty"` // "gtc", "ioc", "fok", "gtd"
+}
 
-	var response struct {
-		Data OrderSimulation `json:"data"`
-	}
+// SimulateOrder dry-runs an order for the user who owns userToken, without placing it.
+// orders-api reads the user from the token, so the caller's bearer token is forwarded.
+func (oc *OrdersClient) SimulateOrder(ctx context.Context, userToken string, req *SimulateOrderRequest) (*OrderSimulation, error) {
+	url := fmt.Sprintf("%s/api/v1/orders/simulate", oc.baseURL)
 
-	err := oc.makeRequest(ctx, "POST", url, req, &response)
+	var simulation OrderSimulation
+	headers := map[string]string{"Authorization": "Bearer " + userToken}
+	err := oc.doRequest(ctx, "POST", url, req, &simulation, headers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to simulate order: %w", err)
 	}
 
-	return &response.Data, nil
+	return &simulation, nil
 }
 
-// OrderSimulation represents simulated order execution
+// OrderSimulation represents simulated order execution. A rejected order still
+// comes back with RejectionReason set instead of an HTTP error.
 type OrderSimulation struct {
-	EstimatedPrice    decimal.Decimal `json:"estimated_price"`
-	EstimatedQuantity decimal.Decimal `json:"estimated_quantity"`
-	EstimatedFee      decimal.Decimal `json:"estimated_fee"`
-	EstimatedTotal    decimal.Decimal `json:"estimated_total"`
-	Slippage          decimal.Decimal `json:"slippage"`
-	WouldFill         bool            `json:"would_fill"`
-	Warnings          []string        `json:"warnings"`
-}
\ No newline at end of file
+	Type              string           `json:"type"`
+	OrderKind         string           `json:"order_kind"`
+	CryptoSymbol      string           `json:"crypto_symbol"`
+	Quantity          decimal.Decimal  `json:"quantity"`
+	EstimatedPrice    decimal.Decimal  `json:"estimated_price"`
+	EstimatedQuantity decimal.Decimal  `json:"estimated_quantity"`
+	EstimatedFee      decimal.Decimal  `json:"estimated_fee"`
+	EstimatedTotal    decimal.Decimal  `json:"estimated_total"`
+	Slippage          decimal.Decimal  `json:"slippage"`
+	WouldFill         bool             `json:"would_fill"`
+	Warnings          []string         `json:"warnings"`
+	AvailableBalance  decimal.Decimal  `json:"available_balance"`
+	ResultingBalance  decimal.Decimal  `json:"resulting_balance"`
+	AvailableHoldings *decimal.Decimal `json:"available_holdings,omitempty"`
+	ResultingHoldings *decimal.Decimal `json:"resulting_holdings,omitempty"`
+	RejectionReason   string           `json:"rejection_reason,omitempty"`
+	RejectionCode     string           `json:"rejection_code,omitempty"`
+}
+
+// IsRejected reports whether orders-api would reject the order
+func (s *OrderSimulation) IsRejected() bool {
+	return s.RejectionReason != ""
+}
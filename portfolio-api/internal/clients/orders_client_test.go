@@ -0,0 +1,54 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"portfolio-api/internal/config"
+)
+
+func TestOrdersClient_SimulateOrder(t *testing.T) {
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/v1/orders/simulate", r.URL.Path)
+		assert.Equal(t, "Bearer user-token", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		// orders-api returns the simulation unwrapped, even for rejections
+		w.Write([]byte(`{"type":"buy","order_kind":"limit","crypto_symbol":"BTC","quantity":"0.5",` +
+			`"estimated_price":"60000","estimated_total":"30030","would_fill":false,"warnings":[],` +
+			`"rejection_reason":"insufficient balance"}`))
+	}))
+	defer server.Close()
+
+	client := NewOrdersClient(config.ExternalAPIsConfig{
+		OrdersAPI: config.OrdersAPIConfig{BaseURL: server.URL, Timeout: time.Second},
+	})
+
+	simulation, err := client.SimulateOrder(context.Background(), "user-token", &SimulateOrderRequest{
+		Type:         "buy",
+		OrderKind:    "limit",
+		CryptoSymbol: "BTC",
+		Quantity:     "0.5",
+		OrderPrice:   "60000",
+		TimeInForce:  "gtc",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"type": "buy", "order_kind": "limit", "crypto_symbol": "BTC",
+		"quantity": "0.5", "order_price": "60000", "time_in_force": "gtc",
+	}, body)
+	assert.True(t, simulation.EstimatedPrice.Equal(decimal.NewFromInt(60000)))
+	assert.True(t, simulation.IsRejected())
+	assert.Equal(t, "insufficient balance", simulation.RejectionReason)
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
-	"github.com/sirupsen/logrus"
 )
 
 // Config represents the application configuration
@@ -104,7 +103,11 @@ type RabbitMQConfig struct {
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
-	JWTSecret           string        `json:"jwt_secret"`
+	JWKSURL             string        `json:"jwks_url"`
+	JWKSCacheTTL        time.Duration `json:"jwks_cache_ttl"`
+	Issuer              string        `json:"issuer"`
+	Audience            string        `json:"audience"`
+	ServiceAudience     string        `json:"service_audience"` // Audience of the service tokens for the internal routes
 	JWTExpiration       time.Duration `json:"jwt_expiration"`
 	RefreshExpiration   time.Duration `json:"refresh_expiration"`
 	RequireAuth         bool          `json:"require_auth"`
@@ -280,7 +283,11 @@ func Load() *Config {
 		},
 
 		Auth: AuthConfig{
-			JWTSecret:         getEnv("JWT_SECRET", "default-secret-key"),
+			JWKSURL:           getEnv("JWKS_URL", "http://localhost:8001/.well-known/jwks.json"),
+			JWKSCacheTTL:      getEnvDuration("JWKS_CACHE_TTL", 5*time.Minute),
+			Issuer:            getEnv("JWT_ISSUER", "users-api"),
+			Audience:          getEnv("JWT_AUDIENCE", "cryptosim"),
+			ServiceAudience:   getEnv("SERVICE_TOKEN_AUDIENCE", "portfolio-api:internal"),
 			JWTExpiration:     getEnvDuration("JWT_EXPIRATION", 24*time.Hour),
 			RefreshExpiration: getEnvDuration("JWT_REFRESH_EXPIRATION", 7*24*time.Hour),
 			RequireAuth:       getEnvBool("REQUIRE_AUTH", true),
@@ -417,8 +424,12 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("database URI is required")
 	}
 
-	if c.Auth.JWTSecret == "" || c.Auth.JWTSecret == "default-secret-key" {
-		logrus.Warn("Using default JWT secret key, this is not recommended for production")
+	if c.Auth.RequireAuth && c.Auth.JWKSURL == "" {
+		return fmt.Errorf("JWKS URL is required when authentication is enabled")
+	}
+
+	if c.Auth.JWKSURL == "" || c.Auth.ServiceAudience == "" {
+		return fmt.Errorf("JWKS URL and service token audience are required for the internal routes")
 	}
 
 	if c.ExternalAPIs.MarketDataAPI.BaseURL == "" {
@@ -1,26 +1,52 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
+
+	"portfolio-api/internal/middleware"
+	"portfolio-api/internal/models"
+	"portfolio-api/internal/repositories"
 )
 
 type PortfolioController struct {
-	logger *logrus.Logger
+	logger   *logrus.Logger
+	holdings repositories.HoldingsRepository
 }
 
-func NewPortfolioController(logger *logrus.Logger, service interface{}) *PortfolioController {
+// NewPortfolioController creates the controller. holdings may be nil when the
+// database is unavailable; reservation endpoints then answer 503.
+func NewPortfolioController(logger *logrus.Logger, holdings repositories.HoldingsRepository) *PortfolioController {
 	return &PortfolioController{
-		logger: logger,
+		logger:   logger,
+		holdings: holdings,
 	}
 }
 
+// RegisterRoutes registers the read routes users call with their own token
 func (c *PortfolioController) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/health", c.Health)
-	r.POST("/:userId/holdings", c.UpdateHoldings)
+	r.GET("/:userId/holdings/:symbol", c.GetHolding)
+}
+
+// RegisterInternalRoutes registers the routes orders-api uses to reserve and settle
+// holdings. They change what a user owns, so they go on a group that only accepts
+// service tokens, and each route declares the scope it needs.
+func (c *PortfolioController) RegisterInternalRoutes(r *gin.RouterGroup) {
+	read := middleware.RequireScopes(middleware.ScopeHoldingsRead)
+	write := middleware.RequireScopes(middleware.ScopeHoldingsWrite)
+
+	r.GET("/:userId/holdings/:symbol", read, c.GetHolding)
+	r.POST("/:userId/holdings", write, c.UpdateHoldings)
+	r.PUT("/:userId/holdings/:symbol/reservations/:orderId", write, c.ReserveHolding)
+	r.DELETE("/:userId/holdings/:symbol/reservations/:orderId", write, c.ReleaseHolding)
+	r.POST("/:userId/trades/:orderId/revert", write, c.RevertTrade)
 }
 
 func (c *PortfolioController) Health(ctx *gin.Context) {
@@ -32,12 +58,27 @@ type UpdateHoldingsRequest struct {
 	Symbol    string  `json:"symbol" binding:"required"`
 	Quantity  float64 `json:"quantity" binding:"required"`
 	Price     float64 `json:"price" binding:"required"`
-	OrderType string  `json:"order_type" binding:"required"` // "buy" or "sell"
+	OrderType string  `json:"order_type" binding:"required,oneof=buy sell"`
+	OrderID   string  `json:"order_id"` // Sells consume the reservation taken for this order
+	// ReservationID is set when OrderID identifies one fill of the order
+	ReservationID string `json:"reservation_id"`
+}
+
+// ReserveHoldingRequest request payload to reserve holdings for a pending sell order
+type ReserveHoldingRequest struct {
+	Quantity float64 `json:"quantity" binding:"required,gt=0"`
 }
 
-// UpdateHoldings updates user holdings after an order execution
-// This is a stub implementation that just returns OK
-// The full implementation would require the complete portfolio-api infrastructure
+// HoldingResponse holding quantities as seen by orders-api
+type HoldingResponse struct {
+	Symbol            string          `json:"symbol"`
+	Quantity          decimal.Decimal `json:"quantity"`
+	ReservedQuantity  decimal.Decimal `json:"reserved_quantity"`
+	AvailableQuantity decimal.Decimal `json:"available_quantity"`
+}
+
+// UpdateHoldings applies an executed order to the user's holdings.
+// Sells fail with 409 if the user does not own the quantity being sold.
 func (c *PortfolioController) UpdateHoldings(ctx *gin.Context) {
 	userIDParam := ctx.Param("userId")
 	userID, err := parseUserID(userIDParam)
@@ -53,18 +94,151 @@ func (c *PortfolioController) UpdateHoldings(ctx *gin.Context) {
 	}
 
 	// Log the request for debugging
-	c.logger.Infof("Portfolio update request: User %d, Symbol %s, Quantity %f, Price %f, Type %s", 
-		userID, req.Symbol, req.Quantity, req.Price, req.OrderType)
+	c.logger.Infof("Portfolio update request: User %d, Symbol %s, Quantity %f, Price %f, Type %s, Order %s",
+		userID, req.Symbol, req.Quantity, req.Price, req.OrderType, req.OrderID)
+
+	if c.holdings == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "holdings store unavailable"})
+		return
+	}
+
+	holding, err := c.holdings.ApplyTrade(ctx.Request.Context(), userID, &models.HoldingTrade{
+		OrderID:       req.OrderID,
+		ReservationID: req.ReservationID,
+		Symbol:        req.Symbol,
+		OrderType:     req.OrderType,
+		Quantity:      decimal.NewFromFloat(req.Quantity),
+		Price:         decimal.NewFromFloat(req.Price),
+		Timestamp:     time.Now(),
+	})
+	if err != nil {
+		c.writeHoldingError(ctx, err)
+		return
+	}
 
-	// For now, just return success
-	// The full implementation would update the portfolio here
 	ctx.JSON(http.StatusOK, gin.H{
 		"message": "Holdings updated successfully",
 		"user_id": userID,
 		"symbol":  req.Symbol,
+		"holding": toHoldingResponse(holding),
 	})
 }
 
+// GetHolding returns the owned, reserved and available quantity of a symbol
+func (c *PortfolioController) GetHolding(ctx *gin.Context) {
+	userID, err := parseUserID(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if c.holdings == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "holdings store unavailable"})
+		return
+	}
+
+	holding, err := c.holdings.GetHolding(ctx.Request.Context(), userID, ctx.Param("symbol"))
+	if err != nil {
+		c.writeHoldingError(ctx, err)
+		return
+	}
+	if holding == nil {
+		holding = &models.Holding{Symbol: ctx.Param("symbol")}
+	}
+
+	ctx.JSON(http.StatusOK, toHoldingResponse(holding))
+}
+
+// ReserveHolding sets the quantity reserved for a pending sell order.
+// Repeating the call for the same order resizes the reservation.
+func (c *PortfolioController) ReserveHolding(ctx *gin.Context) {
+	userID, err := parseUserID(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req ReserveHoldingRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.holdings == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "holdings store unavailable"})
+		return
+	}
+
+	holding, err := c.holdings.ReserveHolding(ctx.Request.Context(), userID, ctx.Param("symbol"), ctx.Param("orderId"), decimal.NewFromFloat(req.Quantity))
+	if err != nil {
+		c.writeHoldingError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toHoldingResponse(holding))
+}
+
+// ReleaseHolding frees the reservation of a sell order that will not execute
+func (c *PortfolioController) ReleaseHolding(ctx *gin.Context) {
+	userID, err := parseUserID(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if c.holdings == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "holdings store unavailable"})
+		return
+	}
+
+	if err := c.holdings.ReleaseHolding(ctx.Request.Context(), userID, ctx.Param("symbol"), ctx.Param("orderId")); err != nil {
+		c.writeHoldingError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Reservation released"})
+}
+
+// RevertTrade undoes the holdings change of an order whose execution was rolled back
+func (c *PortfolioController) RevertTrade(ctx *gin.Context) {
+	userID, err := parseUserID(ctx.Param("userId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if c.holdings == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "holdings store unavailable"})
+		return
+	}
+
+	if err := c.holdings.RevertTrade(ctx.Request.Context(), userID, ctx.Param("orderId")); err != nil {
+		c.writeHoldingError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Trade reverted"})
+}
+
+func (c *PortfolioController) writeHoldingError(ctx *gin.Context, err error) {
+	if errors.Is(err, models.ErrInsufficientHoldings) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.logger.WithError(err).Error("Holdings operation failed")
+	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+func toHoldingResponse(h *models.Holding) HoldingResponse {
+	return HoldingResponse{
+		Symbol:            h.Symbol,
+		Quantity:          h.Quantity,
+		ReservedQuantity:  h.ReservedQuantity(),
+		AvailableQuantity: h.AvailableQuantity(),
+	}
+}
+
 // parseUserID converts string user ID to int64
 func parseUserID(userIDStr string) (int64, error) {
 	var userID int64
@@ -1,9 +1,210 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cryptosim/jwks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// AuthConfig configures user authentication. User tokens are signed by users-api
+// and verified with the keys published at JWKSURL.
+type AuthConfig struct {
+	RequireAuth  bool
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	Issuer       string
+	Audience     string
+	SkipPaths    []string
+}
+
+// Claims are the claims of the access tokens issued by users-api
+type Claims struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// AuthMiddleware lets through users with a valid token. User tokens only read:
+// holdings change through the internal routes, which take orders-api's
+// credential instead. Users only reach the :userId routes of their own account,
+// unless they are admins.
+func AuthMiddleware(config *AuthConfig) gin.HandlerFunc {
+	keys := jwks.NewCache(config.JWKSURL, config.JWKSCacheTTL)
+
+	skipPaths := make(map[string]bool, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skipPaths[path] = true
+	}
+
+	return func(c *gin.Context) {
+		if !config.RequireAuth || skipPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString == "" || tokenString == c.GetHeader("Authorization") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		claims, err := parseToken(tokenString, keys, config)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token: " + err.Error()})
+			return
+		}
+
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user tokens can only read portfolios"})
+			return
+		}
+
+		if userID := c.Param("userId"); userID != "" && claims.Role != "admin" && userID != strconv.Itoa(claims.UserID) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "you can only access your own portfolio"})
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("user_role", claims.Role)
+		c.Next()
+	}
+}
+
+// Scopes of the internal routes, granted by users-api in the service tokens
+const (
+	ScopeHoldingsRead  = "holdings:read"
+	ScopeHoldingsWrite = "holdings:write"
+)
+
+// ServiceAuthConfig configures the internal routes. Callers send a service token
+// users-api issued to them, signed with the same keys as user tokens and
+// addressed to Audience.
+type ServiceAuthConfig struct {
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	Issuer       string
+	Audience     string
+	Logger       *logrus.Logger
+}
+
+// ServiceClaims are the claims of the service tokens issued by users-api. Scope
+// is space separated as in OAuth 2.0.
+type ServiceClaims struct {
+	Service string `json:"service"`
+	Scope   string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+func (c *ServiceClaims) HasScope(scope string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// InternalAuthMiddleware guards the internal routes: only service tokens get
+// through, never a user token, since the audience differs. It applies even when
+// RequireAuth is off, since these routes change holdings. Every call is logged
+// with the calling service once the handler finished.
+func InternalAuthMiddleware(config *ServiceAuthConfig) gin.HandlerFunc {
+	keys := jwks.NewCache(config.JWKSURL, config.JWKSCacheTTL)
+	logger := config.Logger
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
 
-func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString == "" || tokenString == c.GetHeader("Authorization") {
+			logger.Warnf("[INTERNAL] rejected %s %s from %s: no service token", c.Request.Method, c.Request.URL.Path, c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "service token required"})
+			return
+		}
+
+		claims := &ServiceClaims{}
+		err := verifyToken(tokenString, keys, config.Issuer, config.Audience, claims)
+		if err == nil && claims.Service == "" {
+			err = fmt.Errorf("token has no service")
+		}
+		if err != nil {
+			logger.Warnf("[INTERNAL] rejected %s %s from %s: %v", c.Request.Method, c.Request.URL.Path, c.ClientIP(), err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired service token"})
+			return
+		}
+
+		c.Set("service_name", claims.Service)
+		c.Set("service_claims", claims)
+
+		start := time.Now()
 		c.Next()
+
+		logger.Infof("[INTERNAL] service=%s %s %s status=%d latency=%v",
+			claims.Service, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// RequireScopes lets through service tokens that carry every one of scopes. It
+// runs after InternalAuthMiddleware, so each internal route declares its scopes.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("service_claims")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "service token required"})
+			return
+		}
+
+		claims := value.(*ServiceClaims)
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing scope " + scope})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func parseToken(tokenString string, keys *jwks.Cache, config *AuthConfig) (*Claims, error) {
+	claims := &Claims{}
+	if err := verifyToken(tokenString, keys, config.Issuer, config.Audience, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verifyToken checks the signature of a users-api token with the JWKS and decodes
+// it into claims. An empty issuer or audience is not checked.
+func verifyToken(tokenString string, keys *jwks.Cache, issuer, audience string, claims jwt.Claims) error {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{"RS256", "EdDSA"})}
+	if issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		options = append(options, jwt.WithAudience(audience))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keys.Key(kid, token.Method.Alg())
+	}, options...)
+	if err != nil {
+		return err
+	}
+
+	if !token.Valid {
+		return fmt.Errorf("invalid token claims")
 	}
+	return nil
 }
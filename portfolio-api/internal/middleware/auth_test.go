@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cryptosim/jwks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T) (*gin.Engine, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwks.Set{Keys: []jwks.JSONWebKey{{
+			Kty: "OKP", Crv: "Ed25519", Use: "sig", Alg: "EdDSA", Kid: "key-1",
+			X: base64.RawURLEncoding.EncodeToString(publicKey),
+		}}})
+	}))
+	t.Cleanup(jwksServer.Close)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/portfolio")
+	group.Use(AuthMiddleware(&AuthConfig{
+		RequireAuth:  true,
+		JWKSURL:      jwksServer.URL,
+		JWKSCacheTTL: time.Minute,
+		Issuer:       "users-api",
+		Audience:     "cryptosim",
+		SkipPaths:    []string{"/api/portfolio/health"},
+	}))
+	group.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	group.GET("/:userId/holdings/:symbol", func(c *gin.Context) { c.Status(http.StatusOK) })
+	// A mutating route registered on the user group by mistake
+	group.DELETE("/:userId/holdings/:symbol", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	internal := router.Group("/api/internal/portfolio")
+	internal.Use(InternalAuthMiddleware(&ServiceAuthConfig{
+		JWKSURL:      jwksServer.URL,
+		JWKSCacheTTL: time.Minute,
+		Issuer:       "users-api",
+		Audience:     "portfolio-api:internal",
+	}))
+	internal.POST("/:userId/holdings", RequireScopes(ScopeHoldingsWrite), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router, privateKey
+}
+
+func signTestToken(t *testing.T, key ed25519.PrivateKey, userID int, role string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &Claims{
+		UserID: userID,
+		Role:   role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "users-api",
+			Audience:  jwt.ClaimStrings{"cryptosim"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	token.Header["kid"] = "key-1"
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func signServiceToken(t *testing.T, key ed25519.PrivateKey, audience, scope string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, &ServiceClaims{
+		Service: "orders-api",
+		Scope:   scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "users-api",
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	token.Header["kid"] = "key-1"
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	router, key := newTestRouter(t)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		headers map[string]string
+		status  int
+	}{
+		{"health is public", http.MethodGet, "/api/portfolio/health", nil, http.StatusOK},
+		{"no credentials", http.MethodGet, "/api/portfolio/7/holdings/BTC", nil, http.StatusUnauthorized},
+		{"own portfolio", http.MethodGet, "/api/portfolio/7/holdings/BTC", map[string]string{"Authorization": "Bearer " + signTestToken(t, key, 7, "normal")}, http.StatusOK},
+		{"another user's portfolio", http.MethodGet, "/api/portfolio/8/holdings/BTC", map[string]string{"Authorization": "Bearer " + signTestToken(t, key, 7, "normal")}, http.StatusForbidden},
+		{"admin", http.MethodGet, "/api/portfolio/8/holdings/BTC", map[string]string{"Authorization": "Bearer " + signTestToken(t, key, 1, "admin")}, http.StatusOK},
+		{"user token cannot write", http.MethodDelete, "/api/portfolio/7/holdings/BTC", map[string]string{"Authorization": "Bearer " + signTestToken(t, key, 7, "normal")}, http.StatusForbidden},
+		{"admin token cannot write", http.MethodDelete, "/api/portfolio/8/holdings/BTC", map[string]string{"Authorization": "Bearer " + signTestToken(t, key, 1, "admin")}, http.StatusForbidden},
+		{"shared secret token", http.MethodGet, "/api/portfolio/7/holdings/BTC", map[string]string{"Authorization": "Bearer " + hmacToken(t)}, http.StatusUnauthorized},
+		{"internal call", http.MethodPost, "/api/internal/portfolio/7/holdings", map[string]string{"Authorization": "Bearer " + signServiceToken(t, key, "portfolio-api:internal", "holdings:read holdings:write")}, http.StatusOK},
+		{"internal call without the write scope", http.MethodPost, "/api/internal/portfolio/7/holdings", map[string]string{"Authorization": "Bearer " + signServiceToken(t, key, "portfolio-api:internal", "holdings:read")}, http.StatusForbidden},
+		{"service token for users-api", http.MethodPost, "/api/internal/portfolio/7/holdings", map[string]string{"Authorization": "Bearer " + signServiceToken(t, key, "users-api:internal", "holdings:write")}, http.StatusUnauthorized},
+		{"old shared API key", http.MethodPost, "/api/internal/portfolio/7/holdings", map[string]string{"X-Internal-Service": "orders-api", "X-API-Key": "portfolio-api-key"}, http.StatusUnauthorized},
+		{"user token on internal route", http.MethodPost, "/api/internal/portfolio/7/holdings", map[string]string{"Authorization": "Bearer " + signTestToken(t, key, 7, "admin")}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func hmacToken(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: 7, Role: "admin"})
+	token.Header["kid"] = "key-1"
+
+	signed, err := token.SignedString([]byte("shared-secret"))
+	require.NoError(t, err)
+	return signed
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrInsufficientHoldings is returned when a user does not own enough of an asset
+var ErrInsufficientHoldings = errors.New("insufficient holdings")
+
+// HoldingReservation is a quantity of a holding locked by a pending sell order
+type HoldingReservation struct {
+	OrderID   string          `bson:"order_id" json:"order_id"`
+	Quantity  decimal.Decimal `bson:"quantity" json:"quantity"`
+	CreatedAt time.Time       `bson:"created_at" json:"created_at"`
+}
+
+// HoldingTrade is an executed order applied to a holding
+type HoldingTrade struct {
+	OrderID       string // One per fill when the order fills in parts
+	ReservationID string // Reservation consumed by sells; defaults to OrderID
+	Symbol        string
+	OrderType     string // "buy" or "sell"
+	Quantity      decimal.Decimal
+	Price         decimal.Decimal
+	Timestamp     time.Time
+}
+
+// maxProcessedTrades bounds how many applied orders a portfolio remembers
+const maxProcessedTrades = 500
+
+// ProcessedTrade records an order already applied to the holdings, so that
+// retries are no-ops and the trade can be reverted by order ID
+type ProcessedTrade struct {
+	OrderID   string          `bson:"order_id"`
+	Symbol    string          `bson:"symbol,omitempty"`
+	OrderType string          `bson:"order_type,omitempty"`
+	Quantity  decimal.Decimal `bson:"quantity"`
+	Price     decimal.Decimal `bson:"price"`
+	Reverted  bool            `bson:"reverted"`
+	AppliedAt time.Time       `bson:"applied_at"`
+}
+
+// FindProcessedTrade returns the record of orderID, or nil if it was never applied
+func (p *Portfolio) FindProcessedTrade(orderID string) *ProcessedTrade {
+	for i := range p.ProcessedTrades {
+		if p.ProcessedTrades[i].OrderID == orderID {
+			return &p.ProcessedTrades[i]
+		}
+	}
+	return nil
+}
+
+// RecordTrade remembers an applied order, dropping the oldest records past the limit
+func (p *Portfolio) RecordTrade(trade ProcessedTrade) {
+	p.ProcessedTrades = append(p.ProcessedTrades, trade)
+	if extra := len(p.ProcessedTrades) - maxProcessedTrades; extra > 0 {
+		p.ProcessedTrades = p.ProcessedTrades[extra:]
+	}
+}
+
+// ReservedQuantity returns the quantity locked by pending sell orders
+func (h *Holding) ReservedQuantity() decimal.Decimal {
+	reserved := decimal.Zero
+	for _, r := range h.Reservations {
+		reserved = reserved.Add(r.Quantity)
+	}
+	return reserved
+}
+
+// AvailableQuantity returns the quantity that can still be sold or reserved
+func (h *Holding) AvailableQuantity() decimal.Decimal {
+	return h.Quantity.Sub(h.ReservedQuantity())
+}
+
+// Reserve sets the reservation for orderID to quantity. Calling it again for
+// the same order resizes the reservation instead of adding a new one.
+func (h *Holding) Reserve(orderID string, quantity decimal.Decimal, now time.Time) error {
+	if quantity.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("reservation quantity must be positive")
+	}
+
+	current := h.reservationIndex(orderID)
+	available := h.AvailableQuantity()
+	if current >= 0 {
+		available = available.Add(h.Reservations[current].Quantity)
+	}
+
+	if quantity.GreaterThan(available) {
+		return fmt.Errorf("%w: %s %s available, %s requested", ErrInsufficientHoldings, available.String(), h.Symbol, quantity.String())
+	}
+
+	if current >= 0 {
+		h.Reservations[current].Quantity = quantity
+		return nil
+	}
+
+	h.Reservations = append(h.Reservations, HoldingReservation{
+		OrderID:   orderID,
+		Quantity:  quantity,
+		CreatedAt: now,
+	})
+	return nil
+}
+
+// ReleaseReservation removes the reservation of orderID, returning false if there was none
+func (h *Holding) ReleaseReservation(orderID string) bool {
+	i := h.reservationIndex(orderID)
+	if i < 0 {
+		return false
+	}
+	h.Reservations = append(h.Reservations[:i], h.Reservations[i+1:]...)
+	return true
+}
+
+// ApplyBuy adds an executed buy to the holding and updates its average cost
+func (h *Holding) ApplyBuy(quantity, price decimal.Decimal, now time.Time) {
+	newQuantity := h.Quantity.Add(quantity)
+	cost := quantity.Mul(price)
+
+	if newQuantity.GreaterThan(decimal.Zero) {
+		h.AverageBuyPrice = h.AverageBuyPrice.Mul(h.Quantity).Add(cost).Div(newQuantity)
+	}
+	if h.FirstPurchaseDate.IsZero() {
+		h.FirstPurchaseDate = now
+	}
+
+	h.Quantity = newQuantity
+	h.TotalInvested = h.TotalInvested.Add(cost)
+	h.LastPurchaseDate = now
+	h.TransactionsCount++
+}
+
+// ApplySell removes an executed sell from the holding, consuming the
+// reservation of orderID. A partial fill only shrinks the reservation, the
+// rest stays locked for the order. Quantity reserved by other orders cannot be sold.
+func (h *Holding) ApplySell(orderID string, quantity decimal.Decimal) error {
+	if i := h.reservationIndex(orderID); i >= 0 && h.Reservations[i].Quantity.GreaterThan(quantity) {
+		h.Reservations[i].Quantity = h.Reservations[i].Quantity.Sub(quantity)
+	} else {
+		h.ReleaseReservation(orderID)
+	}
+
+	if available := h.AvailableQuantity(); quantity.GreaterThan(available) {
+		return fmt.Errorf("%w: %s %s available, %s requested", ErrInsufficientHoldings, available.String(), h.Symbol, quantity.String())
+	}
+
+	h.TotalInvested = h.TotalInvested.Sub(h.AverageBuyPrice.Mul(quantity))
+	if h.TotalInvested.LessThan(decimal.Zero) {
+		h.TotalInvested = decimal.Zero
+	}
+	h.Quantity = h.Quantity.Sub(quantity)
+	h.TransactionsCount++
+	return nil
+}
+
+func (h *Holding) reservationIndex(orderID string) int {
+	for i, r := range h.Reservations {
+		if r.OrderID == orderID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHolding_Reserve(t *testing.T) {
+	now := time.Now()
+	holding := &Holding{Symbol: "BTC", Quantity: decimal.NewFromInt(2)}
+
+	assert.NoError(t, holding.Reserve("order-1", decimal.NewFromInt(1), now))
+	assert.True(t, holding.AvailableQuantity().Equal(decimal.NewFromInt(1)))
+
+	// Re-reserving the same order resizes it instead of stacking
+	assert.NoError(t, holding.Reserve("order-1", decimal.NewFromFloat(1.5), now))
+	assert.Len(t, holding.Reservations, 1)
+	assert.True(t, holding.ReservedQuantity().Equal(decimal.NewFromFloat(1.5)))
+
+	err := holding.Reserve("order-2", decimal.NewFromInt(1), now)
+	assert.True(t, errors.Is(err, ErrInsufficientHoldings))
+
+	assert.True(t, holding.ReleaseReservation("order-1"))
+	assert.False(t, holding.ReleaseReservation("order-1"))
+	assert.True(t, holding.AvailableQuantity().Equal(decimal.NewFromInt(2)))
+}
+
+func TestHolding_ApplySell(t *testing.T) {
+	now := time.Now()
+	holding := &Holding{Symbol: "BTC"}
+	holding.ApplyBuy(decimal.NewFromInt(2), decimal.NewFromInt(100), now)
+
+	assert.NoError(t, holding.Reserve("order-1", decimal.NewFromInt(1), now))
+	assert.NoError(t, holding.Reserve("order-2", decimal.NewFromInt(1), now))
+
+	// order-1 consumes its own reservation but cannot touch the one of order-2
+	assert.NoError(t, holding.ApplySell("order-1", decimal.NewFromInt(1)))
+	assert.True(t, holding.Quantity.Equal(decimal.NewFromInt(1)))
+	assert.True(t, holding.TotalInvested.Equal(decimal.NewFromInt(100)))
+	assert.Len(t, holding.Reservations, 1)
+
+	err := holding.ApplySell("order-3", decimal.NewFromInt(1))
+	assert.True(t, errors.Is(err, ErrInsufficientHoldings))
+}
+
+func TestHolding_ApplySell_PartialFill(t *testing.T) {
+	now := time.Now()
+	holding := &Holding{Symbol: "BTC"}
+	holding.ApplyBuy(decimal.NewFromInt(3), decimal.NewFromInt(100), now)
+
+	assert.NoError(t, holding.Reserve("order-1", decimal.NewFromInt(2), now))
+
+	// The first fill leaves the rest of the order still reserved
+	assert.NoError(t, holding.ApplySell("order-1", decimal.NewFromFloat(0.5)))
+	assert.True(t, holding.ReservedQuantity().Equal(decimal.NewFromFloat(1.5)))
+	assert.True(t, holding.AvailableQuantity().Equal(decimal.NewFromInt(1)))
+
+	// The last fill consumes what is left
+	assert.NoError(t, holding.ApplySell("order-1", decimal.NewFromFloat(1.5)))
+	assert.Empty(t, holding.Reservations)
+	assert.True(t, holding.Quantity.Equal(decimal.NewFromInt(1)))
+}
+
+func TestPortfolio_RecordTrade(t *testing.T) {
+	portfolio := NewPortfolio(1)
+	for i := 0; i < maxProcessedTrades+10; i++ {
+		portfolio.RecordTrade(ProcessedTrade{OrderID: fmt.Sprintf("order-%d", i)})
+	}
+
+	assert.Len(t, portfolio.ProcessedTrades, maxProcessedTrades)
+	assert.Nil(t, portfolio.FindProcessedTrade("order-0"))
+	assert.NotNil(t, portfolio.FindProcessedTrade(fmt.Sprintf("order-%d", maxProcessedTrades+9)))
+}
@@ -25,6 +25,8 @@ type Portfolio struct {
 	Metadata              PortfolioMetadata     `bson:"metadata" json:"metadata"`
 	CreatedAt             time.Time             `bson:"created_at" json:"created_at"`
 	UpdatedAt             time.Time             `bson:"updated_at" json:"updated_at"`
+	HoldingsVersion       int64                 `bson:"holdings_version" json:"-"` // Bumped on every holdings write (optimistic locking)
+	ProcessedTrades       []ProcessedTrade      `bson:"processed_trades,omitempty" json:"-"` // Recent orders applied to holdings
 }
 
 // Holding represents a cryptocurrency holding in the portfolio
@@ -49,6 +51,9 @@ type Holding struct {
 	DailyChange            decimal.Decimal  `bson:"daily_change,omitempty" json:"daily_change,omitempty"`
 	DailyChangePercentage  decimal.Decimal  `bson:"daily_change_percentage,omitempty" json:"daily_change_percentage,omitempty"`
 	Category               string           `bson:"category,omitempty" json:"category,omitempty"`
+
+	// Quantity locked by pending sell orders in orders-api
+	Reservations           []HoldingReservation `bson:"reservations,omitempty" json:"reservations,omitempty"`
 }
 
 // CostBasisEntry represents a cost basis entry for FIFO/LIFO calculations
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+
+	"portfolio-api/internal/models"
+)
+
+// HoldingsRepository defines the holding operations used by orders-api to
+// reserve and settle the assets of sell orders
+type HoldingsRepository interface {
+	// GetHolding retrieves a single holding, or nil if the user does not own the asset
+	GetHolding(ctx context.Context, userID int64, symbol string) (*models.Holding, error)
+
+	// ReserveHolding sets the quantity reserved for a pending sell order.
+	// Fails with models.ErrInsufficientHoldings if the available quantity is not enough.
+	ReserveHolding(ctx context.Context, userID int64, symbol, orderID string, quantity decimal.Decimal) (*models.Holding, error)
+
+	// ReleaseHolding removes the reservation of an order; releasing twice is a no-op
+	ReleaseHolding(ctx context.Context, userID int64, symbol, orderID string) error
+
+	// ApplyTrade applies an executed order, consuming its reservation on sells.
+	// Applying the same order twice is a no-op.
+	ApplyTrade(ctx context.Context, userID int64, trade *models.HoldingTrade) (*models.Holding, error)
+
+	// RevertTrade undoes an order applied by ApplyTrade. If the order was never
+	// applied it is marked so that a late ApplyTrade for it is ignored.
+	RevertTrade(ctx context.Context, userID int64, orderID string) error
+}
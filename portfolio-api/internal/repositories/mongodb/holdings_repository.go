@@ -0,0 +1,279 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"portfolio-api/internal/models"
+	"portfolio-api/internal/repositories"
+	"portfolio-api/pkg/database"
+)
+
+// maxHoldingsWriteAttempts bounds the retries when another request modified the portfolio
+const maxHoldingsWriteAttempts = 5
+
+var (
+	errHoldingsVersionConflict = errors.New("portfolio holdings were modified concurrently")
+	errPortfolioNotFound       = errors.New("portfolio not found")
+	errNothingToWrite          = errors.New("nothing to write")
+)
+
+type holdingsRepository struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewHoldingsRepository creates a new MongoDB holdings repository backed by the portfolios collection
+func NewHoldingsRepository(db *database.MongoDB) repositories.HoldingsRepository {
+	return &holdingsRepository{
+		db:         db,
+		collection: db.Collection("portfolios"),
+	}
+}
+
+func (r *holdingsRepository) GetHolding(ctx context.Context, userID int64, symbol string) (*models.Holding, error) {
+	portfolio, err := r.load(ctx, userID)
+	if err != nil || portfolio == nil {
+		return nil, err
+	}
+
+	holding, found := portfolio.GetHoldingBySymbol(normalizeSymbol(symbol))
+	if !found {
+		return nil, nil
+	}
+
+	return holding, nil
+}
+
+func (r *holdingsRepository) ReserveHolding(ctx context.Context, userID int64, symbol, orderID string, quantity decimal.Decimal) (*models.Holding, error) {
+	symbol = normalizeSymbol(symbol)
+
+	var result models.Holding
+	err := r.modify(ctx, userID, false, func(portfolio *models.Portfolio) error {
+		holding, found := portfolio.GetHoldingBySymbol(symbol)
+		if !found {
+			return fmt.Errorf("%w: user does not own %s", models.ErrInsufficientHoldings, symbol)
+		}
+		if err := holding.Reserve(orderID, quantity, time.Now()); err != nil {
+			return err
+		}
+		result = *holding
+		return nil
+	})
+	if errors.Is(err, errPortfolioNotFound) {
+		return nil, fmt.Errorf("%w: user does not own %s", models.ErrInsufficientHoldings, symbol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *holdingsRepository) ReleaseHolding(ctx context.Context, userID int64, symbol, orderID string) error {
+	symbol = normalizeSymbol(symbol)
+
+	err := r.modify(ctx, userID, false, func(portfolio *models.Portfolio) error {
+		holding, found := portfolio.GetHoldingBySymbol(symbol)
+		if !found || !holding.ReleaseReservation(orderID) {
+			return errNothingToWrite
+		}
+		return nil
+	})
+	if errors.Is(err, errNothingToWrite) || errors.Is(err, errPortfolioNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+func (r *holdingsRepository) ApplyTrade(ctx context.Context, userID int64, trade *models.HoldingTrade) (*models.Holding, error) {
+	symbol := normalizeSymbol(trade.Symbol)
+	isBuy := trade.OrderType == "buy"
+
+	var result models.Holding
+	err := r.modify(ctx, userID, isBuy, func(portfolio *models.Portfolio) error {
+		// Retries of an order already applied (or reverted) leave the holdings untouched
+		if trade.OrderID != "" && portfolio.FindProcessedTrade(trade.OrderID) != nil {
+			if holding, found := portfolio.GetHoldingBySymbol(symbol); found {
+				result = *holding
+			}
+			return errNothingToWrite
+		}
+
+		reservationID := trade.ReservationID
+		if reservationID == "" {
+			reservationID = trade.OrderID
+		}
+		holding, err := applyToHolding(portfolio, symbol, trade.OrderType, reservationID, trade.Quantity, trade.Price, trade.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		if trade.OrderID != "" {
+			portfolio.RecordTrade(models.ProcessedTrade{
+				OrderID:   trade.OrderID,
+				Symbol:    symbol,
+				OrderType: trade.OrderType,
+				Quantity:  trade.Quantity,
+				Price:     trade.Price,
+				AppliedAt: trade.Timestamp,
+			})
+		}
+		result = *holding
+		return nil
+	})
+	if errors.Is(err, errPortfolioNotFound) {
+		return nil, fmt.Errorf("%w: user does not own %s", models.ErrInsufficientHoldings, symbol)
+	}
+	if err != nil && !errors.Is(err, errNothingToWrite) {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *holdingsRepository) RevertTrade(ctx context.Context, userID int64, orderID string) error {
+	err := r.modify(ctx, userID, true, func(portfolio *models.Portfolio) error {
+		processed := portfolio.FindProcessedTrade(orderID)
+		if processed == nil {
+			// Never applied: leave a tombstone so a late delivery of the order is ignored
+			portfolio.RecordTrade(models.ProcessedTrade{OrderID: orderID, Reverted: true, AppliedAt: time.Now()})
+			return nil
+		}
+		if processed.Reverted {
+			return errNothingToWrite
+		}
+
+		opposite := "buy"
+		if processed.OrderType == "buy" {
+			opposite = "sell"
+		}
+		if _, err := applyToHolding(portfolio, processed.Symbol, opposite, "", processed.Quantity, processed.Price, time.Now()); err != nil {
+			return err
+		}
+
+		processed.Reverted = true
+		return nil
+	})
+	if errors.Is(err, errNothingToWrite) {
+		return nil
+	}
+
+	return err
+}
+
+// applyToHolding applies a buy or sell to the holding of symbol, creating it on buys
+func applyToHolding(portfolio *models.Portfolio, symbol, orderType, reservationID string, quantity, price decimal.Decimal, now time.Time) (*models.Holding, error) {
+	holding, found := portfolio.GetHoldingBySymbol(symbol)
+
+	if orderType == "buy" {
+		if !found {
+			portfolio.Holdings = append(portfolio.Holdings, models.Holding{Symbol: symbol, CryptoID: strings.ToLower(symbol)})
+			holding = &portfolio.Holdings[len(portfolio.Holdings)-1]
+		}
+		holding.ApplyBuy(quantity, price, now)
+	} else {
+		if !found {
+			return nil, fmt.Errorf("%w: user does not own %s", models.ErrInsufficientHoldings, symbol)
+		}
+		if err := holding.ApplySell(reservationID, quantity); err != nil {
+			return nil, err
+		}
+	}
+
+	holding.CurrentPrice = price
+	holding.CurrentValue = holding.Quantity.Mul(price)
+	portfolio.Metadata.LastOrderProcessed = now
+	portfolio.MarkForRecalculation()
+	return holding, nil
+}
+
+func (r *holdingsRepository) load(ctx context.Context, userID int64) (*models.Portfolio, error) {
+	var portfolio models.Portfolio
+	err := r.collection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&portfolio)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get portfolio by user ID: %w", err)
+	}
+
+	return &portfolio, nil
+}
+
+// modify loads the user's portfolio, applies fn and writes the holdings back
+// only if no other request changed them in between, retrying on conflicts.
+// With create set, a missing portfolio is created first.
+func (r *holdingsRepository) modify(ctx context.Context, userID int64, create bool, fn func(*models.Portfolio) error) error {
+	for attempt := 0; attempt < maxHoldingsWriteAttempts; attempt++ {
+		portfolio, err := r.load(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if portfolio == nil {
+			if !create {
+				return fmt.Errorf("%w for user %d", errPortfolioNotFound, userID)
+			}
+			// A concurrent request may have created it; retry against the existing one
+			if _, err := r.collection.InsertOne(ctx, models.NewPortfolio(userID)); err != nil && !mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("failed to create portfolio: %w", err)
+			}
+			continue
+		}
+
+		if err := fn(portfolio); err != nil {
+			return err
+		}
+
+		err = r.write(ctx, portfolio)
+		if errors.Is(err, errHoldingsVersionConflict) {
+			continue
+		}
+		return err
+	}
+
+	return errHoldingsVersionConflict
+}
+
+func (r *holdingsRepository) write(ctx context.Context, portfolio *models.Portfolio) error {
+	// Portfolios written before holdings_version existed have no such field
+	versionFilter := interface{}(portfolio.HoldingsVersion)
+	if portfolio.HoldingsVersion == 0 {
+		versionFilter = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	filter := bson.M{"_id": portfolio.ID, "holdings_version": versionFilter}
+	update := bson.M{
+		"$set": bson.M{
+			"holdings":         portfolio.Holdings,
+			"processed_trades": portfolio.ProcessedTrades,
+			"metadata":         portfolio.Metadata,
+			"updated_at":       time.Now(),
+		},
+		"$inc": bson.M{"holdings_version": 1},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update holdings: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return errHoldingsVersionConflict
+	}
+
+	return nil
+}
+
+func normalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
@@ -15,6 +15,7 @@ import (
 	"search-api/internal/cache"
 	"search-api/internal/config"
 	"search-api/internal/messaging"
+	"search-api/internal/middleware"
 	"search-api/internal/repositories"
 	"search-api/internal/routes"
 	"search-api/internal/services"
@@ -155,7 +156,13 @@ func main() {
 	router := gin.New()
 
 	// Setup routes
-	routes.SetupRoutes(router, searchService, logger)
+	authConfig := &middleware.AuthConfig{
+		JWKSURL:      cfg.Auth.JWKSURL,
+		JWKSCacheTTL: time.Duration(cfg.Auth.JWKSCacheTTLSeconds) * time.Second,
+		Issuer:       cfg.Auth.Issuer,
+		Audience:     cfg.Auth.Audience,
+	}
+	routes.SetupRoutes(router, searchService, authConfig, logger)
 
 	server := &http.Server{
 		Addr:           fmt.Sprintf(":%d", cfg.Server.Port),
@@ -14,6 +14,7 @@ type Config struct {
 	Solr        SolrConfig
 	Cache       CacheConfig
 	RabbitMQ    RabbitMQConfig
+	Auth        AuthConfig
 	Logging     LoggingConfig
 }
 
@@ -60,6 +61,15 @@ type RabbitMQConfig struct {
 	DeadLetterQueue string
 }
 
+// AuthConfig represents token verification configuration. Tokens are verified
+// with the public keys users-api publishes as a JWKS.
+type AuthConfig struct {
+	JWKSURL             string
+	JWKSCacheTTLSeconds int
+	Issuer              string
+	Audience            string
+}
+
 // LoggingConfig represents logging configuration
 type LoggingConfig struct {
 	Level  string
@@ -106,6 +116,12 @@ func Load() *Config {
 			RetryDelayMs:    getEnvAsInt("RABBITMQ_RETRY_DELAY_MS", 1000),
 			DeadLetterQueue: getEnv("RABBITMQ_DEAD_LETTER_QUEUE", "search_events_dlq"),
 		},
+		Auth: AuthConfig{
+			JWKSURL:             getEnv("JWKS_URL", "http://localhost:8001/.well-known/jwks.json"),
+			JWKSCacheTTLSeconds: getEnvAsInt("JWKS_CACHE_TTL_SECONDS", 300),
+			Issuer:              getEnv("JWT_ISSUER", "users-api"),
+			Audience:            getEnv("JWT_AUDIENCE", "cryptosim"),
+		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
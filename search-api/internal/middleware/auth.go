@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cryptosim/jwks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AuthConfig configures token verification. Tokens are signed by users-api
+// and verified with the public keys it publishes at JWKSURL.
+type AuthConfig struct {
+	JWKSURL      string
+	JWKSCacheTTL time.Duration
+	Issuer       string
+	Audience     string
+}
+
+// Claims represents the claims of the access tokens issued by users-api
+type Claims struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// JWTAuth returns a gin middleware that requires a valid bearer token
+func JWTAuth(config *AuthConfig) gin.HandlerFunc {
+	keys := jwks.NewCache(config.JWKSURL, config.JWKSCacheTTL)
+
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") || strings.TrimPrefix(authHeader, "Bearer ") == "" {
+			abortAuth(c, http.StatusUnauthorized, "UNAUTHORIZED", "Bearer token required")
+			return
+		}
+
+		claims, err := parseToken(strings.TrimPrefix(authHeader, "Bearer "), keys, config)
+		if err != nil {
+			abortAuth(c, http.StatusUnauthorized, "INVALID_TOKEN", err.Error())
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("user_role", claims.Role)
+		c.Next()
+	}
+}
+
+// RequireRole returns a gin middleware that only lets through users with role.
+// It must run after JWTAuth.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userRole, _ := c.Get("user_role"); userRole != role {
+			abortAuth(c, http.StatusForbidden, "FORBIDDEN", fmt.Sprintf("Required role: %s", role))
+			return
+		}
+		c.Next()
+	}
+}
+
+func parseToken(tokenString string, keys *jwks.Cache, config *AuthConfig) (*Claims, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{"RS256", "EdDSA"})}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keys.Key(kid, token.Method.Alg())
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	return claims, nil
+}
+
+func abortAuth(c *gin.Context, status int, code, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+		"success": false,
+	})
+	c.Abort()
+}
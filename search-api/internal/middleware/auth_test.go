@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cryptosim/jwks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAuth_AdminRoutes(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwks.Set{Keys: []jwks.JSONWebKey{{
+			Kty: "RSA", Use: "sig", Alg: "RS256", Kid: "key-1",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer jwksServer.Close()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	admin := router.Group("/api/v1/admin")
+	admin.Use(JWTAuth(&AuthConfig{JWKSURL: jwksServer.URL, JWKSCacheTTL: time.Minute, Issuer: "users-api", Audience: "cryptosim"}))
+	admin.Use(RequireRole("admin"))
+	admin.GET("/system", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	sign := func(role string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, &Claims{
+			UserID: 1,
+			Role:   role,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    "users-api",
+				Audience:  jwt.ClaimStrings{"cryptosim"},
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		})
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return "Bearer " + signed
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"invalid token", "Bearer not-a-token", http.StatusUnauthorized},
+		{"normal user", sign("normal"), http.StatusForbidden},
+		{"admin", sign("admin"), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/system", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 func SetupRoutes(
 	router *gin.Engine,
 	searchService *services.SearchService,
+	authConfig *middleware.AuthConfig,
 	logger *logrus.Logger,
 ) {
 	// Initialize controllers
@@ -43,9 +44,11 @@ func SetupRoutes(
 		searchGroup.GET("/health", adminController.GetHealth)
 	}
 
-	// Admin endpoints with stricter rate limiting
+	// Admin endpoints with stricter rate limiting, for admins of users-api only
 	adminGroup := v1.Group("/admin")
 	adminGroup.Use(middleware.AdminRateLimit())
+	adminGroup.Use(middleware.JWTAuth(authConfig))
+	adminGroup.Use(middleware.RequireRole("admin"))
 	{
 		// Cache management
 		adminGroup.POST("/cache/clear", adminController.ClearCache)
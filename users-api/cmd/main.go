@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 
@@ -10,9 +11,11 @@ import (
 	"users-api/internal/config"
 	"users-api/internal/controllers"
 	"users-api/internal/middleware"
+	"users-api/internal/models"
 	"users-api/internal/repositories"
 	"users-api/internal/services"
 	"users-api/pkg/database"
+	"users-api/pkg/mailer"
 )
 
 // @title Users API
@@ -50,16 +53,49 @@ func main() {
 	userRepo := repositories.NewUserRepository(db.DB)
 	refreshTokenRepo := repositories.NewRefreshTokenRepository(db.DB)
 	loginAttemptRepo := repositories.NewLoginAttemptRepository(db.DB)
+	balanceRepo := repositories.NewBalanceRepository(db.DB)
+	twoFactorRepo := repositories.NewTwoFactorRepository(db.DB)
+	securityEventRepo := repositories.NewSecurityEventRepository(db.DB)
+	signingKeyRepo := repositories.NewSigningKeyRepository(db.DB)
+	accountTokenRepo := repositories.NewAccountTokenRepository(db.DB)
+
+	if err := cfg.CheckMail(); err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+	accountMailer, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+
+	keyService := services.NewKeyService(&cfg.JWT, signingKeyRepo)
+	if err := keyService.Refresh(); err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
 
-	tokenService := services.NewTokenService(&cfg.JWT, refreshTokenRepo)
+	tokenService := services.NewTokenService(&cfg.JWT, refreshTokenRepo, keyService)
 	userService := services.NewUserService(userRepo)
-	authService := services.NewAuthService(userRepo, loginAttemptRepo, tokenService)
+	twoFactorService := services.NewTwoFactorService(twoFactorRepo, userRepo, cfg.TwoFactor.Issuer, cfg.TwoFactor.RequireForAdmin)
+	authService := services.NewAuthService(userRepo, loginAttemptRepo, tokenService, twoFactorService, securityEventRepo, cfg.Account.RequireEmailVerification)
+	accountService := services.NewAccountService(userRepo, accountTokenRepo, tokenService, accountMailer, &cfg.Account)
+	balanceService := services.NewBalanceService(balanceRepo)
+	serviceAuthService := services.NewServiceAuthService(cfg.Internal.ServiceClients, cfg.JWT.ServiceTokenTTL, tokenService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	authController := controllers.NewAuthController(authService, userService)
+	services.NewRefreshTokenCleanupJob(refreshTokenRepo, cfg.Jobs.RefreshTokenCleanupInterval).Start(ctx)
+	keyService.Start(ctx)
+
+	authController := controllers.NewAuthController(authService, userService, accountService)
+	accountController := controllers.NewAccountController(accountService)
 	userController := controllers.NewUserController(userService)
+	balanceController := controllers.NewBalanceController(balanceService)
+	twoFactorController := controllers.NewTwoFactorController(twoFactorService)
 	healthController := controllers.NewHealthController(db)
+	jwksController := controllers.NewJWKSController(keyService)
+	serviceAuthController := controllers.NewServiceAuthController(serviceAuthService)
 
-	router := setupRouter(cfg, authController, userController, healthController, tokenService)
+	router := setupRouter(cfg, authController, accountController, userController, balanceController, twoFactorController, healthController, jwksController, serviceAuthController, tokenService)
 
 	log.Printf("Starting Users API server on port %s", cfg.Server.Port)
 	log.Fatal(http.ListenAndServe(":"+cfg.Server.Port, router))
@@ -68,8 +104,13 @@ func main() {
 func setupRouter(
 	cfg *config.Config,
 	authController *controllers.AuthController,
+	accountController *controllers.AccountController,
 	userController *controllers.UserController,
+	balanceController *controllers.BalanceController,
+	twoFactorController *controllers.TwoFactorController,
 	healthController *controllers.HealthController,
+	jwksController *controllers.JWKSController,
+	serviceAuthController *controllers.ServiceAuthController,
 	tokenService services.TokenService,
 ) *gin.Engine {
 	if cfg.IsProduction() {
@@ -86,20 +127,34 @@ func setupRouter(
 	router.GET("/ready", healthController.Readiness)
 	router.GET("/live", healthController.Liveness)
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/.well-known/jwks.json", jwksController.JWKS)
 
 	api := router.Group("/api")
 	{
+		api.POST("/internal/token", serviceAuthController.IssueToken)
+
 		users := api.Group("/users")
 		{
 			users.POST("/register", authController.Register)
 			users.POST("/login", authController.Login)
+			users.POST("/login/2fa", authController.VerifyTwoFactor)
+			users.POST("/login/2fa/enroll", authController.BeginTwoFactorEnrollment)
 			users.POST("/refresh", authController.RefreshToken)
 			users.POST("/logout", authController.Logout)
+			users.POST("/password/forgot", accountController.ForgotPassword)
+			users.POST("/password/reset", accountController.ResetPassword)
+			users.POST("/email/verify", accountController.VerifyEmail)
+			users.POST("/email/verify/resend", accountController.ResendVerification)
 
 			authenticated := users.Group("")
 			authenticated.Use(middleware.AuthMiddleware(tokenService))
 			{
 				authenticated.POST("/logout-all", authController.LogoutAll)
+				authenticated.GET("/2fa", twoFactorController.GetStatus)
+				authenticated.POST("/2fa/enroll", twoFactorController.Enroll)
+				authenticated.POST("/2fa/confirm", twoFactorController.Confirm)
+				authenticated.POST("/2fa/disable", twoFactorController.Disable)
+				authenticated.POST("/2fa/recovery-codes", twoFactorController.RegenerateRecoveryCodes)
 				authenticated.GET("/:id", userController.GetUser)
 				authenticated.PUT("/:id", userController.UpdateUser)
 				authenticated.PUT("/:id/password", userController.ChangePassword)
@@ -110,14 +165,23 @@ func setupRouter(
 				{
 					admin.GET("", userController.ListUsers)
 					admin.POST("/:id/upgrade", userController.UpgradeUser)
+					admin.GET("/:id/security-events", authController.ListSecurityEvents)
+					admin.GET("/2fa/policies", twoFactorController.ListPolicies)
+					admin.PUT("/2fa/policies/:role", twoFactorController.UpdatePolicy)
 				}
 			}
 
 			internal := users.Group("")
-			internal.Use(middleware.InternalServiceMiddleware())
+			internal.Use(middleware.ServiceAuthMiddleware(tokenService))
 			{
-				internal.GET("/:id/verify", userController.VerifyUser)
-				internal.PUT("/:id/balance", userController.UpdateBalance)
+				internal.GET("/:id/verify", middleware.RequireScopes(models.ScopeUserVerify), userController.VerifyUser)
+				internal.GET("/:id/balance", middleware.RequireScopes(models.ScopeBalanceRead), balanceController.GetBalance)
+				internal.PUT("/:id/balance", middleware.RequireScopes(models.ScopeBalanceWrite), balanceController.SetBalance)
+				internal.POST("/:id/balance/transactions", middleware.RequireScopes(models.ScopeBalanceWrite), balanceController.ApplyTransaction)
+				internal.POST("/:id/balance/holds", middleware.RequireScopes(models.ScopeBalanceWrite), balanceController.HoldFunds)
+				internal.PUT("/:id/balance/holds/:order_id", middleware.RequireScopes(models.ScopeBalanceWrite), balanceController.AdjustHold)
+				internal.POST("/:id/balance/holds/:order_id/capture", middleware.RequireScopes(models.ScopeBalanceWrite), balanceController.CaptureHold)
+				internal.POST("/:id/balance/holds/:order_id/release", middleware.RequireScopes(models.ScopeBalanceWrite), balanceController.ReleaseHold)
 			}
 		}
 	}
@@ -1,21 +1,29 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 	"users-api/internal/models"
+	"users-api/pkg/mailer"
+	"users-api/pkg/utils"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      models.JWTConfig
-	Redis    RedisConfig
-	Internal InternalConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	JWT       models.JWTConfig
+	Redis     RedisConfig
+	Internal  InternalConfig
+	TwoFactor TwoFactorConfig
+	Jobs      JobsConfig
+	Account   models.AccountConfig
+	Mail      mailer.Config
 }
 
 type ServerConfig struct {
@@ -37,8 +45,21 @@ type RedisConfig struct {
 	Password string
 }
 
+// InternalConfig lists the services that may call the internal endpoints. Each
+// one has its own secret and the scopes it may request.
 type InternalConfig struct {
-	APIKey string
+	ServiceClients []models.ServiceClient
+}
+
+// TwoFactorConfig holds the TOTP settings. RequireForAdmin is the default for the
+// admin role until an admin saves a policy through the API.
+type TwoFactorConfig struct {
+	Issuer          string
+	RequireForAdmin bool
+}
+
+type JobsConfig struct {
+	RefreshTokenCleanupInterval time.Duration
 }
 
 func LoadConfig() *Config {
@@ -56,6 +77,56 @@ func LoadConfig() *Config {
 		refreshTTL = 604800
 	}
 
+	challengeTTL, err := strconv.Atoi(getEnv("JWT_CHALLENGE_TTL", "300"))
+	if err != nil {
+		challengeTTL = 300
+	}
+
+	keyRotationInterval, err := strconv.Atoi(getEnv("JWT_KEY_ROTATION_INTERVAL", "2592000"))
+	if err != nil {
+		keyRotationInterval = 2592000
+	}
+
+	keyRefreshInterval, err := strconv.Atoi(getEnv("JWT_KEY_REFRESH_INTERVAL", "60"))
+	if err != nil || keyRefreshInterval <= 0 {
+		keyRefreshInterval = 60
+	}
+
+	serviceTokenTTL, err := strconv.Atoi(getEnv("JWT_SERVICE_TOKEN_TTL", "900"))
+	if err != nil {
+		serviceTokenTTL = 900
+	}
+
+	cleanupInterval, err := strconv.Atoi(getEnv("REFRESH_TOKEN_CLEANUP_INTERVAL", "3600"))
+	if err != nil {
+		cleanupInterval = 3600
+	}
+
+	requireAdmin2FA, err := strconv.ParseBool(getEnv("TWO_FACTOR_REQUIRE_ADMIN", "false"))
+	if err != nil {
+		requireAdmin2FA = false
+	}
+
+	passwordResetTTL, err := strconv.Atoi(getEnv("PASSWORD_RESET_TTL", "3600"))
+	if err != nil {
+		passwordResetTTL = 3600
+	}
+
+	emailVerificationTTL, err := strconv.Atoi(getEnv("EMAIL_VERIFICATION_TTL", "86400"))
+	if err != nil {
+		emailVerificationTTL = 86400
+	}
+
+	accountEmailInterval, err := strconv.Atoi(getEnv("ACCOUNT_EMAIL_RESEND_INTERVAL", "60"))
+	if err != nil {
+		accountEmailInterval = 60
+	}
+
+	requireEmailVerification, err := strconv.ParseBool(getEnv("REQUIRE_EMAIL_VERIFICATION", "false"))
+	if err != nil {
+		requireEmailVerification = false
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Port: getEnv("SERVER_PORT", "8001"),
@@ -69,10 +140,14 @@ func LoadConfig() *Config {
 			Name:     getEnv("DB_NAME", "users_db"),
 		},
 		JWT: models.JWTConfig{
-			SecretKey:       getEnv("JWT_SECRET", "your-super-secret-key-change-in-production"),
-			AccessTokenTTL:  time.Duration(accessTTL) * time.Second,
-			RefreshTokenTTL: time.Duration(refreshTTL) * time.Second,
-			Issuer:          "users-api",
+			SigningAlgorithm:    models.SigningAlgorithm(getEnv("JWT_SIGNING_ALGORITHM", string(models.SigningAlgorithmRS256))),
+			KeyRotationInterval: time.Duration(keyRotationInterval) * time.Second,
+			KeyRefreshInterval:  time.Duration(keyRefreshInterval) * time.Second,
+			AccessTokenTTL:      time.Duration(accessTTL) * time.Second,
+			RefreshTokenTTL:     time.Duration(refreshTTL) * time.Second,
+			ChallengeTokenTTL:   time.Duration(challengeTTL) * time.Second,
+			ServiceTokenTTL:     time.Duration(serviceTokenTTL) * time.Second,
+			Issuer:              "users-api",
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
@@ -80,9 +155,96 @@ func LoadConfig() *Config {
 			Password: getEnv("REDIS_PASSWORD", ""),
 		},
 		Internal: InternalConfig{
-			APIKey: getEnv("INTERNAL_API_KEY", "internal-secret-key"),
+			ServiceClients: loadServiceClients(),
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:          getEnv("TWO_FACTOR_ISSUER", "CryptoSim"),
+			RequireForAdmin: requireAdmin2FA,
+		},
+		Jobs: JobsConfig{
+			RefreshTokenCleanupInterval: time.Duration(cleanupInterval) * time.Second,
+		},
+		Account: models.AccountConfig{
+			BaseURL:                  getEnv("APP_BASE_URL", "http://localhost:3000"),
+			PasswordResetTTL:         time.Duration(passwordResetTTL) * time.Second,
+			EmailVerificationTTL:     time.Duration(emailVerificationTTL) * time.Second,
+			ResendInterval:           time.Duration(accountEmailInterval) * time.Second,
+			RequireEmailVerification: requireEmailVerification,
 		},
+		Mail: mailer.Config{
+			Driver:       getEnv("MAIL_DRIVER", ""),
+			From:         getEnv("MAIL_FROM", "CryptoSim <no-reply@cryptosim.local>"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", ""),
+		},
+	}
+}
+
+// loadServiceClients reads SERVICE_CLIENTS, a comma separated list of service
+// names, and for each of them SERVICE_CLIENT_<NAME>_SECRET and
+// SERVICE_CLIENT_<NAME>_SCOPES, where NAME is the service name in upper case
+// with dashes as underscores. Services without a secret, or with an example
+// one, are left out.
+func loadServiceClients() []models.ServiceClient {
+	var clients []models.ServiceClient
+	for _, name := range strings.Split(getEnv("SERVICE_CLIENTS", "orders-api"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		prefix := "SERVICE_CLIENT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		secret := os.Getenv(prefix + "_SECRET")
+		if secret == "" {
+			log.Printf("Service client %s has no %s_SECRET, it cannot call internal endpoints", name, prefix)
+			continue
+		}
+		if placeholderSecrets[secret] {
+			log.Printf("Service client %s still has the example %s_SECRET, it cannot call internal endpoints until it is changed", name, prefix)
+			continue
+		}
+
+		defaultScopes := ""
+		if name == "orders-api" {
+			defaultScopes = strings.Join([]string{
+				models.ScopeUserVerify, models.ScopeBalanceRead, models.ScopeBalanceWrite,
+				models.ScopeHoldingsRead, models.ScopeHoldingsWrite,
+			}, ",")
+		}
+
+		clients = append(clients, models.ServiceClient{
+			Name:       name,
+			SecretHash: utils.HashToken(secret),
+			Scopes:     strings.FieldsFunc(getEnv(prefix+"_SCOPES", defaultScopes), func(r rune) bool { return r == ',' || r == ' ' }),
+		})
+	}
+	return clients
+}
+
+// CheckMail makes the log mail driver, which never delivers the account emails,
+// an explicit choice outside development. Without MAIL_DRIVER development
+// falls back to log and any other environment refuses to start.
+func (c *Config) CheckMail() error {
+	switch {
+	case c.Mail.Driver == "" && c.IsDevelopment():
+		log.Printf("WARNING: MAIL_DRIVER is not set, account emails are only written to the log")
+		c.Mail.Driver = "log"
+	case c.Mail.Driver == "":
+		return fmt.Errorf("MAIL_DRIVER is required in %s: set it to smtp or file, or to log to only write account emails to the log", c.Server.Env)
+	case c.Mail.Driver == "log" && !c.IsDevelopment():
+		log.Printf("WARNING: MAIL_DRIVER=log in %s, account emails are only written to the log and never delivered", c.Server.Env)
 	}
+	return nil
+}
+
+// placeholderSecrets are the example secrets of the .env files and docs.
+// They are public, so a service using one is left out.
+var placeholderSecrets = map[string]bool{
+	"change-me":                              true,
+	"orders-api-secret-change-in-production": true,
 }
 
 func getEnv(key, defaultValue string) string {
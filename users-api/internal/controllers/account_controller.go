@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"users-api/internal/models"
+	"users-api/internal/services"
+	"users-api/pkg/utils"
+)
+
+type AccountController struct {
+	accountService services.AccountService
+}
+
+func NewAccountController(accountService services.AccountService) *AccountController {
+	return &AccountController{
+		accountService: accountService,
+	}
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Email a single-use reset link. The response is the same whether or not the address has an account.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ForgotPasswordRequest true "Email address"
+// @Success 202 {object} dto.APIResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /api/users/password/forgot [post]
+func (ac *AccountController) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	if err := ac.accountService.RequestPasswordReset(req.Email); err != nil {
+		utils.SendInternalError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusAccepted, "If the address has an account, a reset link was sent to it", nil)
+}
+
+// ResetPassword godoc
+// @Summary Reset the password
+// @Description Set a new password with the token of the reset link. Every session of the user is signed out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/password/reset [post]
+func (ac *AccountController) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	if err := ac.accountService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		if strings.Contains(err.Error(), "invalid new password") {
+			utils.SendValidationError(c, err)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid or expired") || strings.Contains(err.Error(), "deactivated") {
+			utils.SendUnauthorizedError(c, err.Error())
+			return
+		}
+		utils.SendInternalError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Password reset successfully", nil)
+}
+
+// VerifyEmail godoc
+// @Summary Verify the email address
+// @Description Confirm the email address with the token of the verification link
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.VerifyEmailRequest true "Verification token"
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/email/verify [post]
+func (ac *AccountController) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	if err := ac.accountService.VerifyEmail(req.Token); err != nil {
+		if strings.Contains(err.Error(), "invalid or expired") {
+			utils.SendUnauthorizedError(c, err.Error())
+			return
+		}
+		utils.SendInternalError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Email verified successfully", nil)
+}
+
+// ResendVerification godoc
+// @Summary Resend the verification email
+// @Description Email a new verification link to an unverified account. The response is the same for any address.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.ResendVerificationRequest true "Email address"
+// @Success 202 {object} dto.APIResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Router /api/users/email/verify/resend [post]
+func (ac *AccountController) ResendVerification(c *gin.Context) {
+	var req models.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	if err := ac.accountService.ResendVerificationEmail(req.Email); err != nil {
+		utils.SendInternalError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusAccepted, "If the address has an unverified account, a new link was sent to it", nil)
+}
@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,14 +15,16 @@ import (
 )
 
 type AuthController struct {
-	authService services.AuthService
-	userService services.UserService
+	authService    services.AuthService
+	userService    services.UserService
+	accountService services.AccountService
 }
 
-func NewAuthController(authService services.AuthService, userService services.UserService) *AuthController {
+func NewAuthController(authService services.AuthService, userService services.UserService, accountService services.AccountService) *AuthController {
 	return &AuthController{
-		authService: authService,
-		userService: userService,
+		authService:    authService,
+		userService:    userService,
+		accountService: accountService,
 	}
 }
 
@@ -56,6 +61,11 @@ func (ac *AuthController) Register(c *gin.Context) {
 		return
 	}
 
+	// The account exists either way; the user can ask for a new link later
+	if err := ac.accountService.SendVerificationEmail(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
 	userResponse := dto.ToUserResponse(user)
 	utils.SendSuccessResponse(c, http.StatusCreated, "User created successfully", userResponse)
 }
@@ -70,6 +80,7 @@ func (ac *AuthController) Register(c *gin.Context) {
 // @Success 200 {object} dto.APIResponse{data=dto.LoginResponse}
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
 // @Failure 429 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
 // @Router /api/users/login [post]
@@ -93,17 +104,100 @@ func (ac *AuthController) Login(c *gin.Context) {
 			utils.SendUnauthorizedError(c, err.Error())
 			return
 		}
+		if strings.Contains(err.Error(), "not verified") {
+			utils.SendForbiddenError(c, err.Error())
+			return
+		}
 		utils.SendInternalError(c, err)
 		return
 	}
 
 	loginResponse := dto.ToLoginResponse(authResponse)
+	if authResponse.TwoFactorRequired {
+		utils.SendSuccessResponse(c, http.StatusOK, "Two-factor authentication required", loginResponse)
+		return
+	}
 	utils.SendSuccessResponse(c, http.StatusOK, "Login successful", loginResponse)
 }
 
+// VerifyTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token from login and a TOTP or recovery code for JWT tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.VerifyTwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} dto.APIResponse{data=dto.LoginResponse}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 429 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/login/2fa [post]
+func (ac *AuthController) VerifyTwoFactor(c *gin.Context) {
+	var req models.VerifyTwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	authResponse, err := ac.authService.VerifyTwoFactor(req.ChallengeToken, req.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "too many failed"):
+			utils.SendTooManyRequestsError(c, err.Error())
+		case strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "deactivated"):
+			utils.SendUnauthorizedError(c, err.Error())
+		case errors.Is(err, services.ErrTwoFactorNotSetUp):
+			utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			utils.SendInternalError(c, err)
+		}
+		return
+	}
+
+	loginResponse := dto.ToLoginResponse(authResponse)
+	utils.SendSuccessResponse(c, http.StatusOK, "Login successful", loginResponse)
+}
+
+// BeginTwoFactorEnrollment godoc
+// @Summary Set up two-factor authentication during login
+// @Description For users whose role requires 2FA and who have not set it up yet. Confirm it by sending a code to /api/users/login/2fa
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorChallengeRequest true "Challenge token"
+// @Success 200 {object} dto.APIResponse{data=models.TwoFactorEnrollment}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/login/2fa/enroll [post]
+func (ac *AuthController) BeginTwoFactorEnrollment(c *gin.Context) {
+	var req models.TwoFactorChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	enrollment, err := ac.authService.BeginTwoFactorEnrollment(req.ChallengeToken)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "deactivated"):
+			utils.SendUnauthorizedError(c, err.Error())
+		case strings.Contains(err.Error(), "not pending"):
+			utils.SendConflictError(c, err.Error())
+		default:
+			utils.SendInternalError(c, err)
+		}
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Scan the provisioning URI and send a code to complete the login", enrollment)
+}
+
 // RefreshToken godoc
 // @Summary Refresh access token
-// @Description Generate a new access token using refresh token
+// @Description Exchange a refresh token for a new access token and a new refresh token. The presented refresh token stops working; presenting it again revokes the whole session
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -120,7 +214,7 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	tokenPair, err := ac.authService.RefreshToken(req.RefreshToken)
+	tokenPair, err := ac.authService.RefreshToken(req.RefreshToken, c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
 		if strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "expired") || strings.Contains(err.Error(), "revoked") {
 			utils.SendUnauthorizedError(c, err.Error())
@@ -131,8 +225,9 @@ func (ac *AuthController) RefreshToken(c *gin.Context) {
 	}
 
 	refreshResponse := dto.RefreshResponse{
-		AccessToken: tokenPair.AccessToken,
-		ExpiresIn:   tokenPair.ExpiresIn,
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+		ExpiresIn:    tokenPair.ExpiresIn,
 	}
 
 	utils.SendSuccessResponse(c, http.StatusOK, "Token refreshed successfully", refreshResponse)
@@ -188,4 +283,36 @@ func (ac *AuthController) LogoutAll(c *gin.Context) {
 	}
 
 	utils.SendSuccessResponse(c, http.StatusOK, "Logged out from all devices successfully", nil)
-}
\ No newline at end of file
+}
+
+// ListSecurityEvents godoc
+// @Summary List security events of a user (Admin only)
+// @Description Suspicious activity such as reused refresh tokens, newest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Maximum events (default 50, max 100)"
+// @Success 200 {object} dto.APIResponse{data=[]models.SecurityEvent}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/{id}/security-events [get]
+func (ac *AuthController) ListSecurityEvents(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	events, err := ac.authService.ListSecurityEvents(int32(id), limit)
+	if err != nil {
+		utils.SendInternalError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "", events)
+}
@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"users-api/internal/models"
+	"users-api/internal/services"
+	"users-api/pkg/utils"
+)
+
+type BalanceController struct {
+	balanceService services.BalanceService
+}
+
+func NewBalanceController(balanceService services.BalanceService) *BalanceController {
+	return &BalanceController{
+		balanceService: balanceService,
+	}
+}
+
+// GetBalance godoc
+// @Summary Get user balance breakdown (Internal use)
+// @Description Get total, reserved and available balance (for other microservices)
+// @Tags internal
+// @Produce json
+// @Param id path int true "User ID"
+// @Param Authorization header string true "Bearer service token with the balance:read scope"
+// @Success 200 {object} dto.APIResponse{data=models.BalanceSummary}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/{id}/balance [get]
+func (bc *BalanceController) GetBalance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	summary, err := bc.balanceService.GetBalance(int32(id))
+	if err != nil {
+		sendBalanceError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "", summary)
+}
+
+// HoldFunds godoc
+// @Summary Reserve funds for an order (Internal use)
+// @Description Move part of the available balance to reserved for a pending order
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body models.HoldFundsRequest true "Hold data"
+// @Param Authorization header string true "Bearer service token with the balance:write scope"
+// @Success 201 {object} dto.APIResponse{data=models.BalanceHold}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/{id}/balance/holds [post]
+func (bc *BalanceController) HoldFunds(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	var req models.HoldFundsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	hold, err := bc.balanceService.HoldFunds(int32(id), &req)
+	if err != nil {
+		sendBalanceError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusCreated, "Funds reserved successfully", hold)
+}
+
+// AdjustHold godoc
+// @Summary Change the amount of a hold (Internal use)
+// @Description Resize the reservation of an amended pending order
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param order_id path string true "Order ID"
+// @Param request body models.AdjustHoldRequest true "New hold amount"
+// @Param Authorization header string true "Bearer service token with the balance:write scope"
+// @Success 200 {object} dto.APIResponse{data=models.BalanceHold}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/{id}/balance/holds/{order_id} [put]
+func (bc *BalanceController) AdjustHold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	var req models.AdjustHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	hold, err := bc.balanceService.AdjustHold(int32(id), c.Param("order_id"), &req)
+	if err != nil {
+		sendBalanceError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Hold adjusted successfully", hold)
+}
+
+// CaptureHold godoc
+// @Summary Convert a hold into a debit (Internal use)
+// @Description Debit the filled amount and release the order's reservation. With fill_id only that fill is debited and the rest of the hold stays active
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param order_id path string true "Order ID"
+// @Param request body models.CaptureHoldRequest true "Capture data"
+// @Param Authorization header string true "Bearer service token with the balance:write scope"
+// @Success 200 {object} dto.APIResponse{data=models.BalanceHold}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/{id}/balance/holds/{order_id}/capture [post]
+func (bc *BalanceController) CaptureHold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	var req models.CaptureHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	hold, err := bc.balanceService.CaptureHold(int32(id), c.Param("order_id"), &req)
+	if err != nil {
+		sendBalanceError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Hold captured successfully", hold)
+}
+
+// ReleaseHold godoc
+// @Summary Release a hold (Internal use)
+// @Description Return the reserved funds of a cancelled or failed order
+// @Tags internal
+// @Produce json
+// @Param id path int true "User ID"
+// @Param order_id path string true "Order ID"
+// @Param Authorization header string true "Bearer service token with the balance:write scope"
+// @Success 200 {object} dto.APIResponse{data=models.BalanceHold}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/{id}/balance/holds/{order_id}/release [post]
+func (bc *BalanceController) ReleaseHold(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	hold, err := bc.balanceService.ReleaseHold(int32(id), c.Param("order_id"))
+	if err != nil {
+		sendBalanceError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Hold released successfully", hold)
+}
+
+// ApplyTransaction godoc
+// @Summary Apply a balance delta (Internal use)
+// @Description Atomically add or subtract an amount and record it in the ledger
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body models.ApplyTransactionRequest true "Transaction data"
+// @Param Idempotency-Key header string false "Key to make retries safe"
+// @Param Authorization header string true "Bearer service token with the balance:write scope"
+// @Success 200 {object} dto.APIResponse{data=models.BalanceTransactionResult} "Replayed transaction"
+// @Success 201 {object} dto.APIResponse{data=models.BalanceTransactionResult}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/{id}/balance/transactions [post]
+func (bc *BalanceController) ApplyTransaction(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	var req models.ApplyTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		req.IdempotencyKey = key
+	}
+
+	result, err := bc.balanceService.ApplyTransaction(int32(id), &req)
+	if err != nil {
+		sendBalanceError(c, err)
+		return
+	}
+
+	if result.Replayed {
+		utils.SendSuccessResponse(c, http.StatusOK, "Transaction already applied", result)
+		return
+	}
+	utils.SendSuccessResponse(c, http.StatusCreated, "Transaction applied successfully", result)
+}
+
+// SetBalance godoc
+// @Summary Set user balance (Internal use)
+// @Description Set an absolute balance; the difference is recorded as a ledger adjustment
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param request body models.UpdateBalanceRequest true "Balance update data"
+// @Param Authorization header string true "Bearer service token with the balance:write scope"
+// @Success 200 {object} dto.APIResponse{data=models.BalanceTransactionResult}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/{id}/balance [put]
+func (bc *BalanceController) SetBalance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	var req models.UpdateBalanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	result, err := bc.balanceService.SetBalance(int32(id), req.Amount)
+	if err != nil {
+		sendBalanceError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Balance updated successfully", result)
+}
+
+func sendBalanceError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "user not found"):
+		utils.SendNotFoundError(c, "User")
+	case strings.Contains(err.Error(), "hold not found"):
+		utils.SendNotFoundError(c, "Hold")
+	case strings.Contains(err.Error(), "insufficient funds"),
+		strings.Contains(err.Error(), "already"),
+		strings.Contains(err.Error(), "another user"):
+		utils.SendConflictError(c, err.Error())
+	case strings.Contains(err.Error(), "invalid"):
+		utils.SendValidationError(c, err)
+	default:
+		utils.SendInternalError(c, err)
+	}
+}
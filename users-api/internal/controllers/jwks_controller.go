@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"users-api/internal/services"
+)
+
+type JWKSController struct {
+	keyService services.KeyService
+}
+
+func NewJWKSController(keyService services.KeyService) *JWKSController {
+	return &JWKSController{
+		keyService: keyService,
+	}
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys that verify the tokens issued by this service, selected by the kid header of the token
+// @Tags auth
+// @Produce json
+// @Success 200 {object} models.JWKS
+// @Router /.well-known/jwks.json [get]
+func (jc *JWKSController) JWKS(c *gin.Context) {
+	// Verifiers refetch on an unknown kid, so a short cache is enough
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jc.keyService.JWKS())
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"users-api/internal/models"
+	"users-api/internal/services"
+	"users-api/pkg/utils"
+)
+
+type ServiceAuthController struct {
+	serviceAuthService services.ServiceAuthService
+}
+
+func NewServiceAuthController(serviceAuthService services.ServiceAuthService) *ServiceAuthController {
+	return &ServiceAuthController{
+		serviceAuthService: serviceAuthService,
+	}
+}
+
+// IssueToken godoc
+// @Summary Issue a service token
+// @Description Exchange the credentials of a service for a short-lived token to call the internal endpoints with
+// @Tags internal
+// @Accept json
+// @Produce json
+// @Param request body models.ServiceTokenRequest true "Service credentials"
+// @Success 200 {object} models.ServiceToken
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Router /api/internal/token [post]
+func (sc *ServiceAuthController) IssueToken(c *gin.Context) {
+	var req models.ServiceTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	token, err := sc.serviceAuthService.IssueToken(req.Service, req.Secret, req.Scope)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid service credentials") {
+			utils.SendUnauthorizedError(c, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not allowed") {
+			utils.SendForbiddenError(c, err.Error())
+			return
+		}
+		utils.SendInternalError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Service token issued", token)
+}
@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"users-api/internal/models"
+	"users-api/internal/services"
+	"users-api/pkg/utils"
+)
+
+type TwoFactorController struct {
+	twoFactorService services.TwoFactorService
+}
+
+func NewTwoFactorController(twoFactorService services.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// GetStatus godoc
+// @Summary Get two-factor authentication status
+// @Description Whether 2FA is enabled or required for the current user and how many recovery codes are left
+// @Tags two-factor
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} dto.APIResponse{data=models.TwoFactorStatus}
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/2fa [get]
+func (tc *TwoFactorController) GetStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	status, err := tc.twoFactorService.GetStatus(userID.(int32))
+	if err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "", status)
+}
+
+// Enroll godoc
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret, its provisioning URI and recovery codes. 2FA is enabled once a code is confirmed
+// @Tags two-factor
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} dto.APIResponse{data=models.TwoFactorEnrollment}
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/2fa/enroll [post]
+func (tc *TwoFactorController) Enroll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	enrollment, err := tc.twoFactorService.BeginEnrollment(userID.(int32))
+	if err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Scan the provisioning URI and confirm with a code", enrollment)
+}
+
+// Confirm godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enable 2FA with a code from the authenticator app
+// @Tags two-factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/2fa/confirm [post]
+func (tc *TwoFactorController) Confirm(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	if err := tc.twoFactorService.ConfirmEnrollment(userID.(int32), req.Code); err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Two-factor authentication enabled", nil)
+}
+
+// Disable godoc
+// @Summary Disable two-factor authentication
+// @Description Requires the password and a TOTP or recovery code. Not allowed when the user's role requires 2FA
+// @Tags two-factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.DisableTwoFactorRequest true "Password and code"
+// @Success 200 {object} dto.APIResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/2fa/disable [post]
+func (tc *TwoFactorController) Disable(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	if err := tc.twoFactorService.Disable(userID.(int32), req.Password, req.Code); err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes. Requires a TOTP or recovery code
+// @Tags two-factor
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorCodeRequest true "Code"
+// @Success 200 {object} dto.APIResponse{data=[]string}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/2fa/recovery-codes [post]
+func (tc *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		utils.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	codes, err := tc.twoFactorService.RegenerateRecoveryCodes(userID.(int32), req.Code)
+	if err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Recovery codes regenerated", codes)
+}
+
+// ListPolicies godoc
+// @Summary List two-factor policies (Admin only)
+// @Description Whether each role must use two-factor authentication
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} dto.APIResponse{data=[]models.TwoFactorPolicy}
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/2fa/policies [get]
+func (tc *TwoFactorController) ListPolicies(c *gin.Context) {
+	policies, err := tc.twoFactorService.ListPolicies()
+	if err != nil {
+		utils.SendInternalError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "", policies)
+}
+
+// UpdatePolicy godoc
+// @Summary Require two-factor authentication for a role (Admin only)
+// @Description Users of the role without 2FA will have to set it up on their next login
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param role path string true "Role (normal or admin)"
+// @Param request body models.UpdateTwoFactorPolicyRequest true "Policy"
+// @Success 200 {object} dto.APIResponse{data=models.TwoFactorPolicy}
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /api/users/2fa/policies/{role} [put]
+func (tc *TwoFactorController) UpdatePolicy(c *gin.Context) {
+	adminID, exists := c.Get("user_id")
+	if !exists {
+		utils.SendUnauthorizedError(c, "User not authenticated")
+		return
+	}
+
+	var req models.UpdateTwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.SendValidationError(c, err)
+		return
+	}
+
+	policy, err := tc.twoFactorService.SetPolicy(models.UserRole(c.Param("role")), *req.Required, adminID.(int32))
+	if err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	utils.SendSuccessResponse(c, http.StatusOK, "Two-factor policy updated", policy)
+}
+
+func sendTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case strings.Contains(err.Error(), "user not found"):
+		utils.SendNotFoundError(c, "User")
+	case strings.Contains(err.Error(), "enrollment not found"):
+		utils.SendNotFoundError(c, "Two-factor enrollment")
+	case strings.Contains(err.Error(), "required for the"):
+		utils.SendForbiddenError(c, err.Error())
+	case strings.Contains(err.Error(), "already enabled"):
+		utils.SendConflictError(c, err.Error())
+	case strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "not enabled"):
+		utils.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendInternalError(c, err)
+	}
+}
@@ -40,9 +40,12 @@ func NewErrorResponseWithCode(message string, code int) ErrorResponse {
 
 func ToLoginResponse(auth *models.AuthResponse) LoginResponse {
 	return LoginResponse{
-		User:         ToUserResponse(auth.User),
-		AccessToken:  auth.AccessToken,
-		RefreshToken: auth.RefreshToken,
-		ExpiresIn:    auth.ExpiresIn,
+		User:               ToUserResponse(auth.User),
+		AccessToken:        auth.AccessToken,
+		RefreshToken:       auth.RefreshToken,
+		ExpiresIn:          auth.ExpiresIn,
+		TwoFactorRequired:  auth.TwoFactorRequired,
+		EnrollmentRequired: auth.EnrollmentRequired,
+		ChallengeToken:     auth.ChallengeToken,
 	}
 }
\ No newline at end of file
@@ -7,17 +7,20 @@ import (
 )
 
 type UserResponse struct {
-	ID             int32           `json:"id"`
-	Username       string          `json:"username"`
-	Email          string          `json:"email"`
-	FirstName      *string         `json:"first_name"`
-	LastName       *string         `json:"last_name"`
-	Role           models.UserRole `json:"role"`
-	InitialBalance float64         `json:"initial_balance"`
-	CreatedAt      time.Time       `json:"created_at"`
-	LastLogin      *time.Time      `json:"last_login,omitempty"`
-	IsActive       bool            `json:"is_active"`
-	Preferences    string          `json:"preferences,omitempty"`
+	ID               int32           `json:"id"`
+	Username         string          `json:"username"`
+	Email            string          `json:"email"`
+	FirstName        *string         `json:"first_name"`
+	LastName         *string         `json:"last_name"`
+	Role             models.UserRole `json:"role"`
+	InitialBalance   float64         `json:"initial_balance"`
+	ReservedBalance  float64         `json:"reserved_balance"`
+	AvailableBalance float64         `json:"available_balance"`
+	CreatedAt        time.Time       `json:"created_at"`
+	LastLogin        *time.Time      `json:"last_login,omitempty"`
+	IsActive         bool            `json:"is_active"`
+	EmailVerified    bool            `json:"email_verified"`
+	Preferences      string          `json:"preferences,omitempty"`
 }
 
 type UserSummaryResponse struct {
@@ -41,16 +44,23 @@ type PaginationResponse struct {
 	TotalPages int   `json:"total_pages"`
 }
 
+// LoginResponse has the tokens, or only a challenge_token when the login still
+// needs a two-factor code (see /api/users/login/2fa)
 type LoginResponse struct {
-	User         UserResponse `json:"user"`
-	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
-	ExpiresIn    int64        `json:"expires_in"`
+	User               UserResponse `json:"user"`
+	AccessToken        string       `json:"access_token"`
+	RefreshToken       string       `json:"refresh_token"`
+	ExpiresIn          int64        `json:"expires_in"`
+	TwoFactorRequired  bool         `json:"two_factor_required"`
+	EnrollmentRequired bool         `json:"enrollment_required,omitempty"`
+	ChallengeToken     string       `json:"challenge_token,omitempty"`
 }
 
+// RefreshResponse carries the rotated refresh token, which replaces the one sent
 type RefreshResponse struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int64  `json:"expires_in"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 func ToUserResponse(user *models.User) UserResponse {
@@ -58,17 +68,20 @@ func ToUserResponse(user *models.User) UserResponse {
 	prefsJSON, _ := json.Marshal(prefs)
 
 	return UserResponse{
-		ID:             user.ID,
-		Username:       user.Username,
-		Email:          user.Email,
-		FirstName:      user.FirstName,
-		LastName:       user.LastName,
-		Role:           user.Role,
-		InitialBalance: user.InitialBalance,
-		CreatedAt:      user.CreatedAt,
-		LastLogin:      user.LastLogin,
-		IsActive:       user.IsActive,
-		Preferences:    string(prefsJSON),
+		ID:               user.ID,
+		Username:         user.Username,
+		Email:            user.Email,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
+		Role:             user.Role,
+		InitialBalance:   user.InitialBalance,
+		ReservedBalance:  user.ReservedBalance,
+		AvailableBalance: user.AvailableBalance(),
+		CreatedAt:        user.CreatedAt,
+		LastLogin:        user.LastLogin,
+		IsActive:         user.IsActive,
+		EmailVerified:    user.EmailVerified,
+		Preferences:      string(prefsJSON),
 	}
 }
 
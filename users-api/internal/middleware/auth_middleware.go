@@ -1,8 +1,9 @@
 package middleware
 
 import (
-	"os"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"users-api/internal/models"
@@ -62,52 +63,58 @@ func AdminOnlyMiddleware() gin.HandlerFunc {
 	}
 }
 
-func InternalServiceMiddleware() gin.HandlerFunc {
+// ServiceAuthMiddleware authenticates calls to the internal endpoints with the
+// service token the caller got from /api/internal/token. Every call is logged with
+// the calling service once the handler finished.
+func ServiceAuthMiddleware(tokenService services.TokenService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		serviceName := c.GetHeader("X-Internal-Service")
-		apiKey := c.GetHeader("X-API-Key")
-
-		expectedAPIKey := os.Getenv("INTERNAL_API_KEY")
-		if expectedAPIKey == "" {
-			expectedAPIKey = "internal-secret-key"
-		}
-
-		if serviceName == "" || apiKey == "" {
-			utils.SendUnauthorizedError(c, "Internal service headers required")
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString == "" || tokenString == c.GetHeader("Authorization") {
+			log.Printf("[INTERNAL] rejected %s %s from %s: no service token", c.Request.Method, c.Request.URL.Path, c.ClientIP())
+			utils.SendUnauthorizedError(c, "Service token required")
 			c.Abort()
 			return
 		}
 
-		if apiKey != expectedAPIKey {
-			utils.SendUnauthorizedError(c, "Invalid internal API key")
+		claims, err := tokenService.ValidateServiceToken(tokenString)
+		if err != nil {
+			log.Printf("[INTERNAL] rejected %s %s from %s: %v", c.Request.Method, c.Request.URL.Path, c.ClientIP(), err)
+			utils.SendUnauthorizedError(c, "Invalid or expired service token")
 			c.Abort()
 			return
 		}
 
-		allowedServices := []string{
-			"orders-api",
-			"portfolio-api",
-			"wallet-api",
-			"ranking-api",
-			"notifications-api",
-			"audit-api",
-		}
+		c.Set("service_name", claims.Service)
+		c.Set("service_claims", claims)
 
-		serviceAllowed := false
-		for _, allowedService := range allowedServices {
-			if serviceName == allowedService {
-				serviceAllowed = true
-				break
-			}
-		}
+		start := time.Now()
+		c.Next()
 
-		if !serviceAllowed {
-			utils.SendForbiddenError(c, "Service not authorized")
+		log.Printf("[INTERNAL] service=%s %s %s status=%d latency=%v",
+			claims.Service, c.Request.Method, c.Request.URL.Path, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// RequireScopes lets through service tokens that carry every one of scopes. It
+// runs after ServiceAuthMiddleware, so each internal route declares its scopes.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, exists := c.Get("service_claims")
+		if !exists {
+			utils.SendUnauthorizedError(c, "Service token required")
 			c.Abort()
 			return
 		}
 
-		c.Set("service_name", serviceName)
+		claims := value.(*models.ServiceClaims)
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				utils.SendForbiddenError(c, "Missing scope "+scope)
+				c.Abort()
+				return
+			}
+		}
+
 		c.Next()
 	}
 }
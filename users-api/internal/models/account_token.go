@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// AccountTokenPurpose is what an emailed account token can be used for
+type AccountTokenPurpose string
+
+const (
+	AccountTokenPasswordReset     AccountTokenPurpose = "password_reset"
+	AccountTokenEmailVerification AccountTokenPurpose = "email_verification"
+)
+
+// AccountToken is a single-use token sent by email to reset a password or verify
+// an address. Only the hash is stored; the token itself only exists in the email.
+type AccountToken struct {
+	ID        int64               `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int32               `json:"user_id" gorm:"not null;index"`
+	Purpose   AccountTokenPurpose `json:"purpose" gorm:"not null;size:30"`
+	TokenHash string              `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	ExpiresAt time.Time           `json:"expires_at" gorm:"not null;index"`
+	UsedAt    *time.Time          `json:"used_at,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+	User      User                `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (t *AccountToken) TableName() string {
+	return "account_tokens"
+}
+
+// AccountConfig configures the emailed account flows. BaseURL is the frontend
+// the links point to; a new email for the same purpose is not sent again within
+// ResendInterval.
+type AccountConfig struct {
+	BaseURL                  string
+	PasswordResetTTL         time.Duration
+	EmailVerificationTTL     time.Duration
+	ResendInterval           time.Duration
+	RequireEmailVerification bool
+}
+
+func NewAccountConfig() *AccountConfig {
+	return &AccountConfig{
+		BaseURL:              "http://localhost:3000",
+		PasswordResetTTL:     time.Hour,
+		EmailVerificationTTL: time.Hour * 24,
+		ResendInterval:       time.Minute,
+	}
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
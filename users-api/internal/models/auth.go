@@ -6,11 +6,16 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// AuthResponse carries either the token pair or, when the login still needs a
+// second factor, a challenge token to send along with the code
 type AuthResponse struct {
-	User         *User  `json:"user"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int64  `json:"expires_in"`
+	User               *User  `json:"user"`
+	AccessToken        string `json:"access_token"`
+	RefreshToken       string `json:"refresh_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ChallengeToken     string `json:"challenge_token,omitempty"`
 }
 
 type TokenPair struct {
@@ -27,19 +32,29 @@ type CustomClaims struct {
 	jwt.RegisteredClaims
 }
 
+// JWTConfig configures token issuing. Tokens are signed with the active key of
+// SigningAlgorithm, which is replaced every KeyRotationInterval.
 type JWTConfig struct {
-	SecretKey       string
-	AccessTokenTTL  time.Duration
-	RefreshTokenTTL time.Duration
-	Issuer          string
+	SigningAlgorithm    SigningAlgorithm
+	KeyRotationInterval time.Duration
+	KeyRefreshInterval  time.Duration
+	AccessTokenTTL      time.Duration
+	RefreshTokenTTL     time.Duration
+	ChallengeTokenTTL   time.Duration
+	ServiceTokenTTL     time.Duration
+	Issuer              string
 }
 
 func NewJWTConfig() *JWTConfig {
 	return &JWTConfig{
-		SecretKey:       "your-super-secret-key",
-		AccessTokenTTL:  time.Hour,
-		RefreshTokenTTL: time.Hour * 24 * 7,
-		Issuer:          "users-api",
+		SigningAlgorithm:    SigningAlgorithmRS256,
+		KeyRotationInterval: time.Hour * 24 * 30,
+		KeyRefreshInterval:  time.Minute,
+		AccessTokenTTL:      time.Hour,
+		RefreshTokenTTL:     time.Hour * 24 * 7,
+		ChallengeTokenTTL:   time.Minute * 5,
+		ServiceTokenTTL:     time.Minute * 15,
+		Issuer:              "users-api",
 	}
 }
 
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "active"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusReleased HoldStatus = "released"
+)
+
+// BalanceHold reserves part of a user's balance for a pending order.
+// A hold is keyed by order ID so retries from orders-api are idempotent.
+type BalanceHold struct {
+	ID             int32      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID         int32      `json:"user_id" gorm:"not null;index"`
+	OrderID        string     `json:"order_id" gorm:"uniqueIndex;not null;size:64"`
+	Amount         float64    `json:"amount" gorm:"type:decimal(15,2);not null"`
+	CapturedAmount float64    `json:"captured_amount" gorm:"type:decimal(15,2);default:0.00"`
+	Status         HoldStatus `json:"status" gorm:"type:enum('active','captured','released');default:'active';index"`
+	TransactionID  string     `json:"transaction_id,omitempty" gorm:"size:40"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	User           User       `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (h *BalanceHold) TableName() string {
+	return "balance_holds"
+}
+
+func (h *BalanceHold) IsActive() bool {
+	return h.Status == HoldStatusActive
+}
+
+type LedgerEntryType string
+
+const (
+	EntryTypeDebit  LedgerEntryType = "debit"
+	EntryTypeCredit LedgerEntryType = "credit"
+)
+
+type BalanceTransactionType string
+
+const (
+	TransactionTypeDeposit    BalanceTransactionType = "deposit"
+	TransactionTypeWithdrawal BalanceTransactionType = "withdrawal"
+	TransactionTypeBuy        BalanceTransactionType = "buy"
+	TransactionTypeSell       BalanceTransactionType = "sell"
+	TransactionTypeAdjustment BalanceTransactionType = "adjustment"
+)
+
+// Counterpart accounts for the double-entry ledger. User accounts are "user:<id>".
+const (
+	AccountSystemTrading     = "system:trading"
+	AccountSystemCash        = "system:cash"
+	AccountSystemAdjustments = "system:adjustments"
+)
+
+// BalanceTransaction is one ledger entry. Every balance change writes two entries
+// with the same TransactionID: one on the user account and the opposite one on a
+// system account, so debits and credits of a transaction always add up.
+type BalanceTransaction struct {
+	ID            int64                  `json:"id" gorm:"primaryKey;autoIncrement"`
+	TransactionID string                 `json:"transaction_id" gorm:"not null;size:40;index"`
+	Account       string                 `json:"account" gorm:"not null;size:50;index"`
+	UserID        *int32                 `json:"user_id,omitempty" gorm:"index"`
+	EntryType     LedgerEntryType        `json:"entry_type" gorm:"type:enum('debit','credit');not null"`
+	Amount        float64                `json:"amount" gorm:"type:decimal(15,2);not null"`
+	BalanceAfter  *float64               `json:"balance_after,omitempty" gorm:"type:decimal(15,2)"`
+	Type          BalanceTransactionType `json:"type" gorm:"not null;size:20"`
+	OrderID       string                 `json:"order_id,omitempty" gorm:"size:64;index"`
+	Description   string                 `json:"description,omitempty" gorm:"size:255"`
+	// IdempotencyKey is only set on the user entry; NULLs don't collide in the unique index
+	IdempotencyKey *string   `json:"idempotency_key,omitempty" gorm:"size:100;uniqueIndex"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (bt *BalanceTransaction) TableName() string {
+	return "balance_transactions"
+}
+
+// Delta returns the signed amount of the entry from the account's point of view.
+func (bt *BalanceTransaction) Delta() float64 {
+	if bt.EntryType == EntryTypeDebit {
+		return -bt.Amount
+	}
+	return bt.Amount
+}
+
+func UserAccount(userID int32) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func CounterpartAccount(txType BalanceTransactionType) string {
+	switch txType {
+	case TransactionTypeBuy, TransactionTypeSell:
+		return AccountSystemTrading
+	case TransactionTypeDeposit, TransactionTypeWithdrawal:
+		return AccountSystemCash
+	default:
+		return AccountSystemAdjustments
+	}
+}
+
+type BalanceTransactionResult struct {
+	TransactionID string                 `json:"transaction_id"`
+	UserID        int32                  `json:"user_id"`
+	Type          BalanceTransactionType `json:"type"`
+	Delta         float64                `json:"delta"`
+	BalanceAfter  float64                `json:"balance_after"`
+	OrderID       string                 `json:"order_id,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	// Replayed is true when the idempotency key matched an earlier transaction
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+type ApplyTransactionRequest struct {
+	Type        BalanceTransactionType `json:"type" binding:"required,oneof=deposit withdrawal buy sell adjustment"`
+	Delta       float64                `json:"delta" binding:"required"`
+	OrderID     string                 `json:"order_id" binding:"max=64"`
+	Description string                 `json:"description" binding:"max=255"`
+	// IdempotencyKey may also be sent in the Idempotency-Key header
+	IdempotencyKey string `json:"idempotency_key" binding:"max=100"`
+}
+
+type BalanceSummary struct {
+	UserID    int32   `json:"user_id"`
+	Total     float64 `json:"total"`
+	Reserved  float64 `json:"reserved"`
+	Available float64 `json:"available"`
+}
+
+func NewBalanceSummary(user *User) *BalanceSummary {
+	return &BalanceSummary{
+		UserID:    user.ID,
+		Total:     user.InitialBalance,
+		Reserved:  user.ReservedBalance,
+		Available: user.AvailableBalance(),
+	}
+}
+
+type HoldFundsRequest struct {
+	OrderID string  `json:"order_id" binding:"required,max=64"`
+	Amount  float64 `json:"amount" binding:"required,gt=0"`
+}
+
+type AdjustHoldRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+}
+
+type CaptureHoldRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	// FillID captures only one fill of the order and keeps the rest of the hold
+	FillID string `json:"fill_id" binding:"omitempty,max=64"`
+}
+
+// RoundCurrency rounds an amount to cents, matching the decimal(15,2) columns.
+func RoundCurrency(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
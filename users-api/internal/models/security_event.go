@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type SecurityEventType string
+
+const (
+	SecurityEventRefreshTokenReuse SecurityEventType = "refresh_token_reuse"
+)
+
+// SecurityEvent records something suspicious on an account, for admins to review
+type SecurityEvent struct {
+	ID        int64             `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int32             `json:"user_id" gorm:"not null;index"`
+	Type      SecurityEventType `json:"type" gorm:"not null;size:50;index"`
+	FamilyID  string            `json:"family_id,omitempty" gorm:"size:64"`
+	IPAddress string            `json:"ip_address" gorm:"size:45"`
+	UserAgent string            `json:"user_agent" gorm:"type:text"`
+	Details   string            `json:"details,omitempty" gorm:"size:255"`
+	CreatedAt time.Time         `json:"created_at" gorm:"index"`
+}
+
+func (e *SecurityEvent) TableName() string {
+	return "security_events"
+}
+
+// RefreshTokenReuseError is returned when a refresh token that was already
+// rotated is presented again. Its family has been revoked by then.
+type RefreshTokenReuseError struct {
+	UserID   int32
+	FamilyID string
+	TokenID  int32
+}
+
+func (e *RefreshTokenReuseError) Error() string {
+	return fmt.Sprintf("refresh token reuse detected: token family %s revoked", e.FamilyID)
+}
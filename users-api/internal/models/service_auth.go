@@ -0,0 +1,106 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ServiceTokenAudience is the audience of the tokens other services use to call
+// the internal endpoints. It differs from the access token audience so a service
+// token is never accepted as a user token and the other way around.
+const ServiceTokenAudience = "users-api:internal"
+
+// PortfolioServiceTokenAudience is the audience of the service tokens for the
+// internal endpoints of portfolio-api, which verifies them with the JWKS
+const PortfolioServiceTokenAudience = "portfolio-api:internal"
+
+// Scopes of the internal endpoints
+const (
+	ScopeUserVerify   = "user:verify"
+	ScopeBalanceRead  = "balance:read"
+	ScopeBalanceWrite = "balance:write"
+	// portfolio-api
+	ScopeHoldingsRead  = "holdings:read"
+	ScopeHoldingsWrite = "holdings:write"
+)
+
+// ScopeAudience returns the audience of the service that accepts scope. A
+// service token is only valid for the services its scopes belong to.
+func ScopeAudience(scope string) string {
+	if strings.HasPrefix(scope, "holdings:") {
+		return PortfolioServiceTokenAudience
+	}
+	return ServiceTokenAudience
+}
+
+// ServiceTokenAudiences returns the audiences of a token with scopes, in order
+// and without repeats
+func ServiceTokenAudiences(scopes []string) []string {
+	var audiences []string
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		if audience := ScopeAudience(scope); !seen[audience] {
+			seen[audience] = true
+			audiences = append(audiences, audience)
+		}
+	}
+	if len(audiences) == 0 {
+		return []string{ServiceTokenAudience}
+	}
+	return audiences
+}
+
+// ServiceClient is a service allowed to call the internal endpoints. Every
+// service has its own secret, of which only the hash is kept, and the scopes it
+// may request.
+type ServiceClient struct {
+	Name       string
+	SecretHash string
+	Scopes     []string
+}
+
+func (c *ServiceClient) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceClaims are the claims of a service token. Scope is space separated as
+// in OAuth 2.0.
+type ServiceClaims struct {
+	Service string `json:"service"`
+	Scope   string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+func (c *ServiceClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+func (c *ServiceClaims) HasScope(scope string) bool {
+	for _, s := range c.Scopes() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceTokenRequest exchanges the credentials of a service for a token. An
+// empty Scope requests every scope the service is allowed.
+type ServiceTokenRequest struct {
+	Service string `json:"service" binding:"required"`
+	Secret  string `json:"secret" binding:"required"`
+	Scope   string `json:"scope"`
+}
+
+type ServiceToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// SigningAlgorithm is the JWS algorithm of a signing key
+type SigningAlgorithm string
+
+const (
+	SigningAlgorithmRS256 SigningAlgorithm = "RS256"
+	SigningAlgorithmEdDSA SigningAlgorithm = "EdDSA"
+)
+
+type SigningKeyStatus string
+
+const (
+	SigningKeyActive  SigningKeyStatus = "active"  // Signs new tokens
+	SigningKeyRetired SigningKeyStatus = "retired" // Only published so tokens it signed still verify
+)
+
+// SigningKey is a key pair used to sign JWTs. Exactly one key is active at a
+// time; retired keys stay in the JWKS until the tokens they signed have expired.
+type SigningKey struct {
+	KID        string           `json:"kid" gorm:"primaryKey;size:64"`
+	Algorithm  SigningAlgorithm `json:"algorithm" gorm:"not null;size:10"`
+	PrivateKey string           `json:"-" gorm:"type:text;not null"` // PKCS#8 PEM
+	PublicKey  string           `json:"-" gorm:"type:text;not null"` // PKIX PEM
+	Status     SigningKeyStatus `json:"status" gorm:"not null;size:10;index"`
+	CreatedAt  time.Time        `json:"created_at"`
+	RetiredAt  *time.Time       `json:"retired_at,omitempty"`
+	// ExpiresAt is when a retired key is no longer published
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+}
+
+func (k *SigningKey) TableName() string {
+	return "jwt_signing_keys"
+}
+
+// JWK is the public part of a signing key as published in the JWKS (RFC 7517).
+// RSA keys set N and E, Ed25519 keys set Crv and X.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ChallengeTokenAudience is the audience of the short-lived token Authenticate
+// returns when the login still needs a second factor. It differs from the access
+// token audience so a challenge token is never accepted as an access token.
+const ChallengeTokenAudience = "users-api:2fa"
+
+// TwoFactorStep is what a login still needs after the password check
+type TwoFactorStep string
+
+const (
+	TwoFactorStepNone   TwoFactorStep = "none"   // Tokens are issued right away
+	TwoFactorStepVerify TwoFactorStep = "verify" // A TOTP or recovery code is needed
+	TwoFactorStepEnroll TwoFactorStep = "enroll" // The role requires 2FA and the user has not set it up
+)
+
+// TwoFactorAuth is the TOTP setup of a user. It is created unconfirmed when the
+// user starts enrolling and only enforced on login once a code confirms it.
+type TwoFactorAuth struct {
+	UserID int32  `json:"user_id" gorm:"primaryKey"`
+	Secret string `json:"-" gorm:"not null;size:64"`
+	// LastUsedStep is the last accepted TOTP time step; a code is never accepted twice
+	LastUsedStep int64      `json:"-" gorm:"not null;default:0"`
+	Enabled      bool       `json:"enabled" gorm:"not null;default:false"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	User         User       `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (t *TwoFactorAuth) TableName() string {
+	return "user_two_factor"
+}
+
+// TwoFactorRecoveryCode is a single-use code that replaces a TOTP code when the
+// user lost their authenticator. Only the hash is stored.
+type TwoFactorRecoveryCode struct {
+	ID        int32      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    int32      `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	User      User       `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (c *TwoFactorRecoveryCode) TableName() string {
+	return "two_factor_recovery_codes"
+}
+
+// TwoFactorPolicy says whether users of a role must have 2FA. Roles without a
+// row fall back to the configured default.
+type TwoFactorPolicy struct {
+	Role      UserRole  `json:"role" gorm:"primaryKey;size:20"`
+	Required  bool      `json:"required" gorm:"not null;default:false"`
+	UpdatedBy *int32    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (p *TwoFactorPolicy) TableName() string {
+	return "two_factor_policies"
+}
+
+// ChallengeClaims identify the user between the password and the code step of a login
+type ChallengeClaims struct {
+	UserID int32 `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+type TwoFactorEnrollment struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool  `json:"enabled"`
+	Pending                bool  `json:"pending"` // Enrollment started but not confirmed
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type VerifyTwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type UpdateTwoFactorPolicyRequest struct {
+	Required *bool `json:"required" binding:"required"`
+}
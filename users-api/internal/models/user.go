@@ -8,20 +8,23 @@ import (
 )
 
 type User struct {
-	ID             int32          `json:"id" gorm:"primaryKey;autoIncrement"`
-	Username       string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
-	Email          string         `json:"email" gorm:"uniqueIndex;not null;size:100"`
-	PasswordHash   string         `json:"-" gorm:"not null;size:255"`
-	FirstName      *string        `json:"first_name" gorm:"size:50"`
-	LastName       *string        `json:"last_name" gorm:"size:50"`
-	Role           UserRole       `json:"role" gorm:"type:enum('normal','admin');default:'normal'"`
-	InitialBalance float64        `json:"initial_balance" gorm:"type:decimal(15,2);default:100000.00"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
-	LastLogin      *time.Time     `json:"last_login"`
-	IsActive       bool           `json:"is_active" gorm:"default:true"`
-	Preferences    string         `json:"preferences" gorm:"type:json"`
+	ID              int32          `json:"id" gorm:"primaryKey;autoIncrement"`
+	Username        string         `json:"username" gorm:"uniqueIndex;not null;size:50"`
+	Email           string         `json:"email" gorm:"uniqueIndex;not null;size:100"`
+	PasswordHash    string         `json:"-" gorm:"not null;size:255"`
+	FirstName       *string        `json:"first_name" gorm:"size:50"`
+	LastName        *string        `json:"last_name" gorm:"size:50"`
+	Role            UserRole       `json:"role" gorm:"type:enum('normal','admin');default:'normal'"`
+	InitialBalance  float64        `json:"initial_balance" gorm:"type:decimal(15,2);default:100000.00"`
+	ReservedBalance float64        `json:"reserved_balance" gorm:"type:decimal(15,2);default:0.00;not null"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	LastLogin       *time.Time     `json:"last_login"`
+	IsActive        bool           `json:"is_active" gorm:"default:true"`
+	EmailVerified   bool           `json:"email_verified" gorm:"not null;default:false"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	Preferences     string         `json:"preferences" gorm:"type:json"`
 }
 
 type UserRole string
@@ -52,6 +55,10 @@ func (u *User) IsAdmin() bool {
 	return u.Role == RoleAdmin
 }
 
+func (u *User) AvailableBalance() float64 {
+	return RoundCurrency(u.InitialBalance - u.ReservedBalance)
+}
+
 func (u *User) GetFullName() string {
 	if u.FirstName != nil && u.LastName != nil {
 		return *u.FirstName + " " + *u.LastName
@@ -93,14 +100,29 @@ func (u *User) SetPreferences(prefs map[string]interface{}) error {
 	return nil
 }
 
+type RefreshTokenRevokeReason string
+
+const (
+	RevokeReasonRotated       RefreshTokenRevokeReason = "rotated"        // Exchanged for the next token of the family
+	RevokeReasonLogout        RefreshTokenRevokeReason = "logout"         // The session was closed
+	RevokeReasonLogoutAll     RefreshTokenRevokeReason = "logout_all"     // All sessions of the user were closed
+	RevokeReasonReuseDetected RefreshTokenRevokeReason = "reuse_detected" // A rotated token of the family was presented again
+)
+
+// RefreshToken is one link of a token family. Every login starts a family and
+// every refresh revokes the presented token and issues the next one with the same
+// FamilyID, so only the newest token of a family is ever valid.
 type RefreshToken struct {
-	ID        int32     `json:"id" gorm:"primaryKey;autoIncrement"`
-	UserID    int32     `json:"user_id" gorm:"not null;index"`
-	Token     string    `json:"token" gorm:"uniqueIndex;not null;size:500"`
-	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	Revoked   bool      `json:"revoked" gorm:"default:false"`
-	User      User      `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	ID            int32                    `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        int32                    `json:"user_id" gorm:"not null;index"`
+	Token         string                   `json:"token" gorm:"uniqueIndex;not null;size:500"`
+	FamilyID      string                   `json:"family_id" gorm:"not null;size:64;index"`
+	ExpiresAt     time.Time                `json:"expires_at" gorm:"not null;index"`
+	CreatedAt     time.Time                `json:"created_at"`
+	Revoked       bool                     `json:"revoked" gorm:"default:false"`
+	RevokedReason RefreshTokenRevokeReason `json:"revoked_reason,omitempty" gorm:"size:20"`
+	RevokedAt     *time.Time               `json:"revoked_at,omitempty"`
+	User          User                     `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 }
 
 func (rt *RefreshToken) TableName() string {
@@ -111,6 +133,12 @@ func (rt *RefreshToken) IsExpired() bool {
 	return time.Now().After(rt.ExpiresAt)
 }
 
+// WasRotated reports whether the token was already exchanged for a newer one;
+// presenting it again means someone else has (or had) a copy
+func (rt *RefreshToken) WasRotated() bool {
+	return rt.Revoked && rt.RevokedReason == RevokeReasonRotated
+}
+
 type LoginAttempt struct {
 	ID          int32     `json:"id" gorm:"primaryKey;autoIncrement"`
 	Email       string    `json:"email" gorm:"size:100;index"`
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"users-api/internal/models"
+)
+
+type AccountTokenRepository interface {
+	// Replace stores token and deletes the earlier tokens of the user for the same
+	// purpose, so only the link of the latest email works
+	Replace(token *models.AccountToken) error
+	// GetLatest returns nil, nil when the user has no token for purpose
+	GetLatest(userID int32, purpose models.AccountTokenPurpose) (*models.AccountToken, error)
+	// Use consumes an unused token that has not expired at now; nil, nil means
+	// there was none with that hash
+	Use(tokenHash string, purpose models.AccountTokenPurpose, now time.Time) (*models.AccountToken, error)
+}
+
+type accountTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountTokenRepository(db *gorm.DB) AccountTokenRepository {
+	return &accountTokenRepository{
+		db: db,
+	}
+}
+
+func (r *accountTokenRepository) Replace(token *models.AccountToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ?", token.UserID, token.Purpose).Delete(&models.AccountToken{}).Error; err != nil {
+			return fmt.Errorf("failed to delete previous account tokens: %w", err)
+		}
+		if err := tx.Create(token).Error; err != nil {
+			return fmt.Errorf("failed to create account token: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *accountTokenRepository) GetLatest(userID int32, purpose models.AccountTokenPurpose) (*models.AccountToken, error) {
+	var token models.AccountToken
+	err := r.db.Where("user_id = ? AND purpose = ?", userID, purpose).Order("created_at DESC").First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get account token: %w", err)
+	}
+	return &token, nil
+}
+
+func (r *accountTokenRepository) Use(tokenHash string, purpose models.AccountTokenPurpose, now time.Time) (*models.AccountToken, error) {
+	// The condition makes the check and the update a single statement, so two
+	// concurrent requests cannot both use the same token
+	result := r.db.Model(&models.AccountToken{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, now).
+		Update("used_at", &now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to use account token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	var token models.AccountToken
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, fmt.Errorf("failed to get account token: %w", err)
+	}
+	return &token, nil
+}
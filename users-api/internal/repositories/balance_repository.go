@@ -0,0 +1,519 @@
+package repositories
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"users-api/internal/models"
+)
+
+type BalanceRepository interface {
+	GetSummary(userID int32) (*models.BalanceSummary, error)
+	CreateHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error)
+	AdjustHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error)
+	CaptureHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error)
+	CapturePartialHold(userID int32, orderID, fillID string, amount float64) (*models.BalanceHold, error)
+	ReleaseHold(userID int32, orderID string) (*models.BalanceHold, error)
+	ApplyTransaction(userID int32, delta float64, txType models.BalanceTransactionType, orderID, description, idempotencyKey string) (*models.BalanceTransactionResult, error)
+	SetBalance(userID int32, amount float64, description string) (*models.BalanceTransactionResult, error)
+}
+
+type balanceRepository struct {
+	db *gorm.DB
+}
+
+func NewBalanceRepository(db *gorm.DB) BalanceRepository {
+	return &balanceRepository{
+		db: db,
+	}
+}
+
+func (r *balanceRepository) GetSummary(userID int32) (*models.BalanceSummary, error) {
+	var user models.User
+	if err := r.db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return models.NewBalanceSummary(&user), nil
+}
+
+func (r *balanceRepository) CreateHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error) {
+	var hold models.BalanceHold
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		existing, err := lockHold(tx, orderID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get hold: %w", err)
+		}
+		if existing != nil {
+			if existing.UserID != userID {
+				return fmt.Errorf("hold belongs to another user")
+			}
+			hold = *existing
+			return nil
+		}
+
+		if user.AvailableBalance() < amount {
+			return fmt.Errorf("insufficient funds: required %.2f, available %.2f", amount, user.AvailableBalance())
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("reserved_balance", gorm.Expr("reserved_balance + ?", amount)).Error; err != nil {
+			return fmt.Errorf("failed to reserve funds: %w", err)
+		}
+
+		hold = models.BalanceHold{
+			UserID:  userID,
+			OrderID: orderID,
+			Amount:  amount,
+			Status:  models.HoldStatusActive,
+		}
+		if err := tx.Create(&hold).Error; err != nil {
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+// AdjustHold changes the amount of an active hold, e.g. when a pending order is
+// amended. Only the difference is moved between available and reserved.
+func (r *balanceRepository) AdjustHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error) {
+	var hold *models.BalanceHold
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		hold, err = getUserHold(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+
+		switch hold.Status {
+		case models.HoldStatusCaptured:
+			return fmt.Errorf("hold already captured")
+		case models.HoldStatusReleased:
+			return fmt.Errorf("hold already released")
+		}
+
+		diff := models.RoundCurrency(amount - hold.Amount)
+		if diff == 0 {
+			return nil
+		}
+		if diff > 0 && user.AvailableBalance() < diff {
+			return fmt.Errorf("insufficient funds: required %.2f, available %.2f", diff, user.AvailableBalance())
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("reserved_balance", gorm.Expr("reserved_balance + ?", diff)).Error; err != nil {
+			return fmt.Errorf("failed to adjust reserved funds: %w", err)
+		}
+
+		hold.Amount = amount
+		if err := tx.Save(hold).Error; err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// CaptureHold turns an active hold into a debit of amount. The captured amount may
+// differ from the held one (the order filled at a different price); the hold is
+// released in full either way. Earlier partial captures are kept in CapturedAmount.
+func (r *balanceRepository) CaptureHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error) {
+	var hold *models.BalanceHold
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		hold, err = getUserHold(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+
+		switch hold.Status {
+		case models.HoldStatusCaptured:
+			return nil
+		case models.HoldStatusReleased:
+			return fmt.Errorf("hold already released")
+		}
+
+		// Drop the reservation first so the debit can use the funds it was holding
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("reserved_balance", gorm.Expr("reserved_balance - ?", hold.Amount)).Error; err != nil {
+			return fmt.Errorf("failed to capture funds: %w", err)
+		}
+		user.ReservedBalance = models.RoundCurrency(user.ReservedBalance - hold.Amount)
+
+		result, err := postTransaction(tx, user, -amount, models.TransactionTypeBuy, orderID, fmt.Sprintf("Capture of hold for order %s", orderID), "")
+		if err != nil {
+			return err
+		}
+
+		hold.Status = models.HoldStatusCaptured
+		hold.CapturedAmount = models.RoundCurrency(hold.CapturedAmount + amount)
+		hold.TransactionID = result.TransactionID
+		if err := tx.Save(hold).Error; err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// CapturePartialHold debits amount for one fill of the order and keeps the hold active
+// for the rest. Up to amount is taken from the reservation; retries with the same
+// fillID replay the original debit instead of posting it again.
+func (r *balanceRepository) CapturePartialHold(userID int32, orderID, fillID string, amount float64) (*models.BalanceHold, error) {
+	var hold *models.BalanceHold
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		hold, err = getUserHold(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+
+		idempotencyKey := fmt.Sprintf("hold-%s-fill-%s", orderID, fillID)
+		previous, err := findByIdempotencyKey(tx, idempotencyKey)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			_, err = replayTransaction(previous, userID, -amount, models.TransactionTypeBuy)
+			return err
+		}
+
+		switch hold.Status {
+		case models.HoldStatusCaptured:
+			return fmt.Errorf("hold already captured")
+		case models.HoldStatusReleased:
+			return fmt.Errorf("hold already released")
+		}
+
+		covered := math.Min(amount, hold.Amount)
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("reserved_balance", gorm.Expr("reserved_balance - ?", covered)).Error; err != nil {
+			return fmt.Errorf("failed to capture funds: %w", err)
+		}
+		user.ReservedBalance = models.RoundCurrency(user.ReservedBalance - covered)
+
+		description := fmt.Sprintf("Partial capture of hold for order %s (fill %s)", orderID, fillID)
+		result, err := postTransaction(tx, user, -amount, models.TransactionTypeBuy, orderID, description, idempotencyKey)
+		if err != nil {
+			return err
+		}
+
+		hold.Amount = models.RoundCurrency(hold.Amount - covered)
+		hold.CapturedAmount = models.RoundCurrency(hold.CapturedAmount + amount)
+		hold.TransactionID = result.TransactionID
+		if err := tx.Save(hold).Error; err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+func (r *balanceRepository) ReleaseHold(userID int32, orderID string) (*models.BalanceHold, error) {
+	var hold *models.BalanceHold
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockUser(tx, userID); err != nil {
+			return err
+		}
+
+		var err error
+		hold, err = getUserHold(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+
+		switch hold.Status {
+		case models.HoldStatusReleased:
+			return nil
+		case models.HoldStatusCaptured:
+			return fmt.Errorf("hold already captured")
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("reserved_balance", gorm.Expr("reserved_balance - ?", hold.Amount)).Error; err != nil {
+			return fmt.Errorf("failed to release funds: %w", err)
+		}
+
+		hold.Status = models.HoldStatusReleased
+		if err := tx.Save(hold).Error; err != nil {
+			return fmt.Errorf("failed to update hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+// ApplyTransaction posts delta to the user's balance. When idempotencyKey is set and
+// was already used for this user, the original result is returned instead of posting
+// again. The user row lock serializes concurrent retries with the same key.
+func (r *balanceRepository) ApplyTransaction(userID int32, delta float64, txType models.BalanceTransactionType, orderID, description, idempotencyKey string) (*models.BalanceTransactionResult, error) {
+	var result *models.BalanceTransactionResult
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if idempotencyKey != "" {
+			previous, err := findByIdempotencyKey(tx, idempotencyKey)
+			if err != nil {
+				return err
+			}
+			if previous != nil {
+				result, err = replayTransaction(previous, userID, delta, txType)
+				return err
+			}
+		}
+
+		result, err = postTransaction(tx, user, delta, txType, orderID, description, idempotencyKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SetBalance sets an absolute balance by posting the difference as an adjustment,
+// so the ledger still explains every change.
+func (r *balanceRepository) SetBalance(userID int32, amount float64, description string) (*models.BalanceTransactionResult, error) {
+	var result *models.BalanceTransactionResult
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		delta := models.RoundCurrency(amount - user.InitialBalance)
+		if delta == 0 {
+			// Nothing to post; the balance already has the requested value
+			result = &models.BalanceTransactionResult{
+				UserID:       user.ID,
+				Type:         models.TransactionTypeAdjustment,
+				BalanceAfter: user.InitialBalance,
+			}
+			return nil
+		}
+
+		result, err = postTransaction(tx, user, delta, models.TransactionTypeAdjustment, "", description, "")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// postTransaction applies delta to a user row already locked by tx and writes the
+// two ledger entries. Debits can't touch funds reserved by holds, and the balance
+// never goes below zero.
+func postTransaction(tx *gorm.DB, user *models.User, delta float64, txType models.BalanceTransactionType, orderID, description, idempotencyKey string) (*models.BalanceTransactionResult, error) {
+	delta = models.RoundCurrency(delta)
+	newBalance := models.RoundCurrency(user.InitialBalance + delta)
+
+	if delta < 0 && newBalance < user.ReservedBalance {
+		return nil, fmt.Errorf("insufficient funds: required %.2f, available %.2f", -delta, user.AvailableBalance())
+	}
+	if newBalance < 0 {
+		return nil, fmt.Errorf("insufficient funds: balance cannot be negative")
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("initial_balance", newBalance).Error; err != nil {
+		return nil, fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	transactionID, err := newTransactionID()
+	if err != nil {
+		return nil, err
+	}
+
+	userEntry, counterEntry := models.EntryTypeCredit, models.EntryTypeDebit
+	if delta < 0 {
+		userEntry, counterEntry = models.EntryTypeDebit, models.EntryTypeCredit
+	}
+
+	amount := delta
+	if amount < 0 {
+		amount = -amount
+	}
+
+	var key *string
+	if idempotencyKey != "" {
+		key = &idempotencyKey
+	}
+
+	now := time.Now().UTC()
+	userID := user.ID
+	entries := []models.BalanceTransaction{
+		{
+			TransactionID:  transactionID,
+			Account:        models.UserAccount(user.ID),
+			UserID:         &userID,
+			EntryType:      userEntry,
+			Amount:         amount,
+			BalanceAfter:   &newBalance,
+			Type:           txType,
+			OrderID:        orderID,
+			Description:    description,
+			IdempotencyKey: key,
+			CreatedAt:      now,
+		},
+		{
+			TransactionID: transactionID,
+			Account:       models.CounterpartAccount(txType),
+			EntryType:     counterEntry,
+			Amount:        amount,
+			Type:          txType,
+			OrderID:       orderID,
+			Description:   description,
+			CreatedAt:     now,
+		},
+	}
+	if err := tx.Create(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to write ledger entries: %w", err)
+	}
+
+	user.InitialBalance = newBalance
+
+	return &models.BalanceTransactionResult{
+		TransactionID: transactionID,
+		UserID:        user.ID,
+		Type:          txType,
+		Delta:         delta,
+		BalanceAfter:  newBalance,
+		OrderID:       orderID,
+		CreatedAt:     now,
+	}, nil
+}
+
+func findByIdempotencyKey(tx *gorm.DB, idempotencyKey string) (*models.BalanceTransaction, error) {
+	var entry models.BalanceTransaction
+	if err := tx.Where("idempotency_key = ?", idempotencyKey).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to check idempotency key: %w", err)
+	}
+	return &entry, nil
+}
+
+// replayTransaction rebuilds the result of an earlier transaction. Reusing a key for
+// another user or with a different amount is a client bug, not a retry.
+func replayTransaction(entry *models.BalanceTransaction, userID int32, delta float64, txType models.BalanceTransactionType) (*models.BalanceTransactionResult, error) {
+	if entry.UserID == nil || *entry.UserID != userID ||
+		entry.Type != txType || entry.Delta() != models.RoundCurrency(delta) {
+		return nil, fmt.Errorf("idempotency key already used for a different transaction")
+	}
+
+	result := &models.BalanceTransactionResult{
+		TransactionID: entry.TransactionID,
+		UserID:        userID,
+		Type:          entry.Type,
+		Delta:         entry.Delta(),
+		OrderID:       entry.OrderID,
+		CreatedAt:     entry.CreatedAt,
+		Replayed:      true,
+	}
+	if entry.BalanceAfter != nil {
+		result.BalanceAfter = *entry.BalanceAfter
+	}
+	return result, nil
+}
+
+func newTransactionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate transaction ID: %w", err)
+	}
+	return "btx_" + hex.EncodeToString(b), nil
+}
+
+// lockUser loads the user row with SELECT ... FOR UPDATE so concurrent balance
+// changes for the same user are serialized.
+func lockUser(tx *gorm.DB, userID int32) (*models.User, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+	return &user, nil
+}
+
+func lockHold(tx *gorm.DB, orderID string) (*models.BalanceHold, error) {
+	var hold models.BalanceHold
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&hold).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func getUserHold(tx *gorm.DB, userID int32, orderID string) (*models.BalanceHold, error) {
+	hold, err := lockHold(tx, orderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("hold not found")
+		}
+		return nil, fmt.Errorf("failed to get hold: %w", err)
+	}
+	if hold.UserID != userID {
+		return nil, fmt.Errorf("hold not found")
+	}
+	return hold, nil
+}
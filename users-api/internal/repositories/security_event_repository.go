@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"users-api/internal/models"
+)
+
+type SecurityEventRepository interface {
+	Create(event *models.SecurityEvent) error
+	ListByUser(userID int32, limit int) ([]models.SecurityEvent, error)
+}
+
+type securityEventRepository struct {
+	db *gorm.DB
+}
+
+func NewSecurityEventRepository(db *gorm.DB) SecurityEventRepository {
+	return &securityEventRepository{
+		db: db,
+	}
+}
+
+func (r *securityEventRepository) Create(event *models.SecurityEvent) error {
+	if err := r.db.Create(event).Error; err != nil {
+		return fmt.Errorf("failed to create security event: %w", err)
+	}
+	return nil
+}
+
+func (r *securityEventRepository) ListByUser(userID int32, limit int) ([]models.SecurityEvent, error) {
+	var events []models.SecurityEvent
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to list security events: %w", err)
+	}
+	return events, nil
+}
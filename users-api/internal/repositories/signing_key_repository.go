@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"users-api/internal/models"
+)
+
+// ErrSigningKeyRotated is returned by Rotate when another instance already
+// replaced the active key
+var ErrSigningKeyRotated = errors.New("signing key already rotated")
+
+type SigningKeyRepository interface {
+	// ListPublished returns the active key and the retired keys that have not expired yet
+	ListPublished(now time.Time) ([]models.SigningKey, error)
+	// Rotate retires currentKID until retiredUntil and stores next as the active key.
+	// An empty currentKID creates the first key.
+	Rotate(currentKID string, next *models.SigningKey, retiredUntil time.Time) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) SigningKeyRepository {
+	return &signingKeyRepository{
+		db: db,
+	}
+}
+
+func (r *signingKeyRepository) ListPublished(now time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	err := r.db.Where("status = ? OR expires_at > ?", models.SigningKeyActive, now).
+		Order("created_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *signingKeyRepository) Rotate(currentKID string, next *models.SigningKey, retiredUntil time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		retire := map[string]interface{}{
+			"status":     models.SigningKeyRetired,
+			"retired_at": now,
+			"expires_at": retiredUntil,
+		}
+
+		if currentKID == "" {
+			var active int64
+			if err := tx.Model(&models.SigningKey{}).Where("status = ?", models.SigningKeyActive).Count(&active).Error; err != nil {
+				return fmt.Errorf("failed to count signing keys: %w", err)
+			}
+			if active > 0 {
+				return ErrSigningKeyRotated
+			}
+		} else {
+			// Conditional on the key still being active so that two instances
+			// rotating at the same time do not both add a key
+			result := tx.Model(&models.SigningKey{}).
+				Where("kid = ? AND status = ?", currentKID, models.SigningKeyActive).
+				Updates(retire)
+			if result.Error != nil {
+				return fmt.Errorf("failed to retire signing key: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return ErrSigningKeyRotated
+			}
+
+			// Instances that created the first key at the same time may have left more than one active
+			if err := tx.Model(&models.SigningKey{}).Where("status = ?", models.SigningKeyActive).Updates(retire).Error; err != nil {
+				return fmt.Errorf("failed to retire signing key: %w", err)
+			}
+		}
+
+		next.Status = models.SigningKeyActive
+		if err := tx.Create(next).Error; err != nil {
+			return fmt.Errorf("failed to create signing key: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *signingKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("status = ? AND expires_at < ?", models.SigningKeyRetired, now).Delete(&models.SigningKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired signing keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,176 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"users-api/internal/models"
+)
+
+type TwoFactorRepository interface {
+	// Get returns nil, nil when the user never started enrolling
+	Get(userID int32) (*models.TwoFactorAuth, error)
+	// Begin stores a new unconfirmed secret and recovery codes, replacing any previous setup
+	Begin(userID int32, secret string, recoveryCodeHashes []string) error
+	Enable(userID int32, step int64) error
+	Delete(userID int32) error
+	// UseStep records step as used; false means it (or a later one) was already used
+	UseStep(userID int32, step int64) (bool, error)
+	// UseRecoveryCode consumes an unused code; false means there was none with that hash
+	UseRecoveryCode(userID int32, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(userID int32, codeHashes []string) error
+	CountRecoveryCodes(userID int32) (int64, error)
+	// GetPolicy returns nil, nil when no admin saved a policy for the role
+	GetPolicy(role models.UserRole) (*models.TwoFactorPolicy, error)
+	SavePolicy(policy *models.TwoFactorPolicy) error
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{
+		db: db,
+	}
+}
+
+func (r *twoFactorRepository) Get(userID int32) (*models.TwoFactorAuth, error) {
+	var twoFactor models.TwoFactorAuth
+	if err := r.db.Where("user_id = ?", userID).First(&twoFactor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	return &twoFactor, nil
+}
+
+func (r *twoFactorRepository) Begin(userID int32, secret string, recoveryCodeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		twoFactor := models.TwoFactorAuth{
+			UserID: userID,
+			Secret: secret,
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"secret":         secret,
+				"last_used_step": 0,
+				"enabled":        false,
+				"enabled_at":     nil,
+				"updated_at":     time.Now(),
+			}),
+		}).Create(&twoFactor).Error
+		if err != nil {
+			return fmt.Errorf("failed to save two-factor settings: %w", err)
+		}
+
+		return replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+}
+
+func (r *twoFactorRepository) Enable(userID int32, step int64) error {
+	now := time.Now()
+	result := r.db.Model(&models.TwoFactorAuth{}).
+		Where("user_id = ? AND enabled = ?", userID, false).
+		Updates(map[string]interface{}{
+			"enabled":        true,
+			"enabled_at":     &now,
+			"last_used_step": step,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("two-factor enrollment not found")
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) Delete(userID int32) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorAuth{}).Error; err != nil {
+			return fmt.Errorf("failed to delete two-factor settings: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *twoFactorRepository) UseStep(userID int32, step int64) (bool, error) {
+	// The condition makes the check and the update a single statement, so two
+	// concurrent logins cannot both use the same code
+	result := r.db.Model(&models.TwoFactorAuth{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record two-factor code: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *twoFactorRepository) UseRecoveryCode(userID int32, codeHash string) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", &now)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID int32, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (r *twoFactorRepository) CountRecoveryCodes(userID int32) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.TwoFactorRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
+
+func (r *twoFactorRepository) GetPolicy(role models.UserRole) (*models.TwoFactorPolicy, error) {
+	var policy models.TwoFactorPolicy
+	if err := r.db.Where("role = ?", role).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get two-factor policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func (r *twoFactorRepository) SavePolicy(policy *models.TwoFactorPolicy) error {
+	if err := r.db.Save(policy).Error; err != nil {
+		return fmt.Errorf("failed to save two-factor policy: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID int32, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]models.TwoFactorRecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.TwoFactorRecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	if len(codes) > 0 {
+		if err := tx.Create(&codes).Error; err != nil {
+			return fmt.Errorf("failed to create recovery codes: %w", err)
+		}
+	}
+	return nil
+}
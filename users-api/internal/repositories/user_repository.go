@@ -15,10 +15,11 @@ type UserRepository interface {
 	GetByEmail(email string) (*models.User, error)
 	GetByUsername(username string) (*models.User, error)
 	Update(user *models.User) error
-	UpdateBalance(id int32, newBalance float64) error
 	Delete(id int32) error
 	List(offset, limit int, search string, role string, isActive *bool) ([]models.User, int64, error)
 	UpdateLastLogin(id int32) error
+	UpdatePassword(id int32, passwordHash string) error
+	MarkEmailVerified(id int32) error
 	Exists(id int32) (bool, error)
 }
 
@@ -82,17 +83,6 @@ func (r *userRepository) Update(user *models.User) error {
 	return nil
 }
 
-func (r *userRepository) UpdateBalance(id int32, newBalance float64) error {
-	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("initial_balance", newBalance)
-	if result.Error != nil {
-		return fmt.Errorf("failed to update balance: %w", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("user not found")
-	}
-	return nil
-}
-
 func (r *userRepository) Delete(id int32) error {
 	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("is_active", false)
 	if result.Error != nil {
@@ -142,6 +132,25 @@ func (r *userRepository) UpdateLastLogin(id int32) error {
 	return nil
 }
 
+func (r *userRepository) UpdatePassword(id int32, passwordHash string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Update("password_hash", passwordHash)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update password: %w", result.Error)
+	}
+	return nil
+}
+
+// MarkEmailVerified sets the verification time only the first time
+func (r *userRepository) MarkEmailVerified(id int32) error {
+	now := time.Now()
+	result := r.db.Model(&models.User{}).Where("id = ? AND email_verified = ?", id, false).
+		Updates(map[string]interface{}{"email_verified": true, "email_verified_at": &now})
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark email as verified: %w", result.Error)
+	}
+	return nil
+}
+
 func (r *userRepository) Exists(id int32) (bool, error) {
 	var count int64
 	if err := r.db.Model(&models.User{}).Where("id = ? AND is_active = ?", id, true).Count(&count).Error; err != nil {
@@ -150,12 +159,22 @@ func (r *userRepository) Exists(id int32) (bool, error) {
 	return count > 0, nil
 }
 
+// ErrRefreshTokenRevoked is returned by Rotate when the token was revoked (or
+// rotated by a concurrent request) after it was read
+var ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+
 type RefreshTokenRepository interface {
 	Create(token *models.RefreshToken) error
+	// GetByToken returns the token even if it was revoked, so rotated tokens can be told apart
 	GetByToken(token string) (*models.RefreshToken, error)
+	// Rotate revokes current as rotated and stores next in a single transaction
+	Rotate(current *models.RefreshToken, next *models.RefreshToken) error
+	RevokeFamily(familyID string, reason models.RefreshTokenRevokeReason) (int64, error)
 	RevokeByUserID(userID int32) error
 	RevokeByToken(token string) error
-	DeleteExpired() error
+	// DeleteExpired removes expired tokens. Revoked tokens are kept until they
+	// expire so a replayed rotated token is still recognised.
+	DeleteExpired() (int64, error)
 }
 
 type refreshTokenRepository struct {
@@ -177,7 +196,7 @@ func (r *refreshTokenRepository) Create(token *models.RefreshToken) error {
 
 func (r *refreshTokenRepository) GetByToken(token string) (*models.RefreshToken, error) {
 	var refreshToken models.RefreshToken
-	if err := r.db.Preload("User").Where("token = ? AND revoked = ?", token, false).First(&refreshToken).Error; err != nil {
+	if err := r.db.Preload("User").Where("token = ?", token).First(&refreshToken).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("refresh token not found")
 		}
@@ -186,15 +205,46 @@ func (r *refreshTokenRepository) GetByToken(token string) (*models.RefreshToken,
 	return &refreshToken, nil
 }
 
+func (r *refreshTokenRepository) Rotate(current *models.RefreshToken, next *models.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked = ?", current.ID, false).
+			Updates(revokeUpdates(models.RevokeReasonRotated))
+		if result.Error != nil {
+			return fmt.Errorf("failed to revoke refresh token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenRevoked
+		}
+
+		if err := tx.Create(next).Error; err != nil {
+			return fmt.Errorf("failed to create refresh token: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *refreshTokenRepository) RevokeFamily(familyID string, reason models.RefreshTokenRevokeReason) (int64, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked = ?", familyID, false).
+		Updates(revokeUpdates(reason))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token family: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 func (r *refreshTokenRepository) RevokeByUserID(userID int32) error {
-	if err := r.db.Model(&models.RefreshToken{}).Where("user_id = ?", userID).Update("revoked", true).Error; err != nil {
+	// Already revoked tokens keep their reason, otherwise a rotated token would
+	// no longer be recognised as one
+	if err := r.db.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked = ?", userID, false).Updates(revokeUpdates(models.RevokeReasonLogoutAll)).Error; err != nil {
 		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
 	}
 	return nil
 }
 
 func (r *refreshTokenRepository) RevokeByToken(token string) error {
-	result := r.db.Model(&models.RefreshToken{}).Where("token = ?", token).Update("revoked", true)
+	result := r.db.Model(&models.RefreshToken{}).Where("token = ? AND revoked = ?", token, false).Updates(revokeUpdates(models.RevokeReasonLogout))
 	if result.Error != nil {
 		return fmt.Errorf("failed to revoke refresh token: %w", result.Error)
 	}
@@ -204,11 +254,20 @@ func (r *refreshTokenRepository) RevokeByToken(token string) error {
 	return nil
 }
 
-func (r *refreshTokenRepository) DeleteExpired() error {
-	if err := r.db.Where("expires_at < ? OR revoked = ?", time.Now(), true).Delete(&models.RefreshToken{}).Error; err != nil {
-		return fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+func (r *refreshTokenRepository) DeleteExpired() (int64, error) {
+	result := r.db.Where("expires_at < ?", time.Now()).Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired refresh tokens: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func revokeUpdates(reason models.RefreshTokenRevokeReason) map[string]interface{} {
+	return map[string]interface{}{
+		"revoked":        true,
+		"revoked_reason": reason,
+		"revoked_at":     time.Now(),
 	}
-	return nil
 }
 
 type LoginAttemptRepository interface {
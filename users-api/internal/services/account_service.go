@@ -0,0 +1,233 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"users-api/internal/models"
+	"users-api/internal/repositories"
+	"users-api/pkg/mailer"
+	"users-api/pkg/utils"
+)
+
+// errAccountEmailThrottled means an email for the same purpose was sent less
+// than ResendInterval ago
+var errAccountEmailThrottled = errors.New("account email sent recently")
+
+// AccountService runs the flows that prove control of the email address:
+// verifying it after registration and resetting a forgotten password. Both send
+// a single-use link that expires. The request endpoints never tell whether an
+// address has an account.
+type AccountService interface {
+	SendVerificationEmail(user *models.User) error
+	ResendVerificationEmail(email string) error
+	VerifyEmail(token string) error
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) error
+}
+
+type accountService struct {
+	userRepo         repositories.UserRepository
+	accountTokenRepo repositories.AccountTokenRepository
+	tokenService     TokenService
+	mailer           mailer.Mailer
+	config           *models.AccountConfig
+}
+
+func NewAccountService(
+	userRepo repositories.UserRepository,
+	accountTokenRepo repositories.AccountTokenRepository,
+	tokenService TokenService,
+	accountMailer mailer.Mailer,
+	config *models.AccountConfig,
+) AccountService {
+	return &accountService{
+		userRepo:         userRepo,
+		accountTokenRepo: accountTokenRepo,
+		tokenService:     tokenService,
+		mailer:           accountMailer,
+		config:           config,
+	}
+}
+
+func (s *accountService) SendVerificationEmail(user *models.User) error {
+	token, err := s.issueToken(user, models.AccountTokenEmailVerification, s.config.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link:\n\n%s\n\nThe link expires in %s.\n",
+			user.Username, s.link("/verify-email", token), formatTTL(s.config.EmailVerificationTTL)),
+	})
+}
+
+// ResendVerificationEmail sends a new link to an unverified account. Unknown and
+// already verified addresses are ignored.
+func (s *accountService) ResendVerificationEmail(email string) error {
+	user, err := s.userRepo.GetByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil || !user.IsActive || user.EmailVerified {
+		return nil
+	}
+
+	if err := s.SendVerificationEmail(user); err != nil && !errors.Is(err, errAccountEmailThrottled) {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+func (s *accountService) VerifyEmail(token string) error {
+	accountToken, err := s.accountTokenRepo.Use(utils.HashToken(token), models.AccountTokenEmailVerification, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	if accountToken == nil {
+		return fmt.Errorf("invalid or expired verification token")
+	}
+
+	if err := s.userRepo.MarkEmailVerified(accountToken.UserID); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+	return nil
+}
+
+// RequestPasswordReset emails a reset link to an active account. Unknown
+// addresses are ignored so the endpoint does not reveal who has an account.
+func (s *accountService) RequestPasswordReset(email string) error {
+	user, err := s.userRepo.GetByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if err != nil || !user.IsActive {
+		return nil
+	}
+
+	token, err := s.issueToken(user, models.AccountTokenPasswordReset, s.config.PasswordResetTTL)
+	if err != nil {
+		if !errors.Is(err, errAccountEmailThrottled) {
+			log.Printf("Failed to create password reset token for user %d: %v", user.ID, err)
+		}
+		return nil
+	}
+
+	err = s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, open this link to choose a new one:\n\n%s\n\nThe link expires in %s. If you did not ask for it, you can ignore this email.\n",
+			user.Username, s.link("/reset-password", token), formatTTL(s.config.PasswordResetTTL)),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token. Every session of the
+// user is revoked, and since the link arrived by email the address counts as
+// verified.
+func (s *accountService) ResetPassword(token, newPassword string) error {
+	// Validate before using the token, so a rejected password does not burn it
+	if err := utils.ValidatePassword(newPassword); err != nil {
+		return fmt.Errorf("invalid new password: %w", err)
+	}
+
+	accountToken, err := s.accountTokenRepo.Use(utils.HashToken(token), models.AccountTokenPasswordReset, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+	if accountToken == nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	user, err := s.userRepo.GetByID(accountToken.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.IsActive {
+		return fmt.Errorf("account is deactivated")
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash new password: %w", err)
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	if err := s.userRepo.MarkEmailVerified(user.ID); err != nil {
+		log.Printf("Failed to mark email of user %d as verified: %v", user.ID, err)
+	}
+
+	if err := s.tokenService.RevokeAllUserTokens(user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	err = s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body:    fmt.Sprintf("Hi %s,\n\nThe password of your account was just reset and every session was signed out. If it was not you, contact support right away.\n", user.Username),
+	})
+	if err != nil {
+		log.Printf("Failed to send password change notice to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+// issueToken stores the hash of a new token for purpose, replacing the previous
+// one, and returns the token to put in the link
+func (s *accountService) issueToken(user *models.User, purpose models.AccountTokenPurpose, ttl time.Duration) (string, error) {
+	latest, err := s.accountTokenRepo.GetLatest(user.ID, purpose)
+	if err != nil {
+		return "", err
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < s.config.ResendInterval {
+		return "", errAccountEmailThrottled
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	now := time.Now()
+	err = s.accountTokenRepo.Replace(&models.AccountToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *accountService) link(path, token string) string {
+	return strings.TrimRight(s.config.BaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// formatTTL writes a link lifetime for the email text, e.g. "24 hours"
+func formatTTL(ttl time.Duration) string {
+	if ttl >= time.Hour {
+		hours := int(ttl.Round(time.Hour) / time.Hour)
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+
+	minutes := int(ttl.Round(time.Minute) / time.Minute)
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
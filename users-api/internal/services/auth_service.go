@@ -1,7 +1,9 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -12,31 +14,44 @@ import (
 
 type AuthService interface {
 	Authenticate(email, password string, ipAddress, userAgent string) (*models.AuthResponse, error)
-	RefreshToken(refreshToken string) (*models.TokenPair, error)
+	RefreshToken(refreshToken, ipAddress, userAgent string) (*models.TokenPair, error)
 	Logout(refreshToken string) error
 	LogoutAll(userID int32) error
 	IsRateLimited(email string) (bool, error)
+	VerifyTwoFactor(challengeToken, code, ipAddress, userAgent string) (*models.AuthResponse, error)
+	BeginTwoFactorEnrollment(challengeToken string) (*models.TwoFactorEnrollment, error)
+	ListSecurityEvents(userID int32, limit int) ([]models.SecurityEvent, error)
 }
 
 type authService struct {
-	userRepo             repositories.UserRepository
-	loginAttemptRepo     repositories.LoginAttemptRepository
-	tokenService         TokenService
-	maxFailedAttempts    int
-	rateLimitWindow      time.Duration
+	userRepo          repositories.UserRepository
+	loginAttemptRepo  repositories.LoginAttemptRepository
+	tokenService      TokenService
+	twoFactorService  TwoFactorService
+	securityEventRepo repositories.SecurityEventRepository
+	maxFailedAttempts int
+	rateLimitWindow   time.Duration
+	// requireEmailVerification keeps users out until they open the link of the verification email
+	requireEmailVerification bool
 }
 
 func NewAuthService(
 	userRepo repositories.UserRepository,
 	loginAttemptRepo repositories.LoginAttemptRepository,
 	tokenService TokenService,
+	twoFactorService TwoFactorService,
+	securityEventRepo repositories.SecurityEventRepository,
+	requireEmailVerification bool,
 ) AuthService {
 	return &authService{
-		userRepo:             userRepo,
-		loginAttemptRepo:     loginAttemptRepo,
-		tokenService:         tokenService,
-		maxFailedAttempts:    5,
-		rateLimitWindow:      15 * time.Minute,
+		userRepo:                 userRepo,
+		loginAttemptRepo:         loginAttemptRepo,
+		tokenService:             tokenService,
+		twoFactorService:         twoFactorService,
+		securityEventRepo:        securityEventRepo,
+		maxFailedAttempts:        5,
+		rateLimitWindow:          15 * time.Minute,
+		requireEmailVerification: requireEmailVerification,
 	}
 }
 
@@ -69,6 +84,105 @@ func (s *authService) Authenticate(email, password, ipAddress, userAgent string)
 		return nil, fmt.Errorf("invalid email or password")
 	}
 
+	// Checked after the password so the error does not reveal which addresses are registered
+	if s.requireEmailVerification && !user.EmailVerified {
+		return nil, fmt.Errorf("email address is not verified")
+	}
+
+	step, err := s.twoFactorService.LoginStep(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check two-factor authentication: %w", err)
+	}
+
+	// With 2FA the password only earns a challenge token; the session tokens are
+	// issued by VerifyTwoFactor once the code is checked
+	if step != models.TwoFactorStepNone {
+		challengeToken, err := s.tokenService.GenerateChallengeToken(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate challenge token: %w", err)
+		}
+
+		return &models.AuthResponse{
+			User:               user,
+			TwoFactorRequired:  true,
+			EnrollmentRequired: step == models.TwoFactorStepEnroll,
+			ChallengeToken:     challengeToken,
+		}, nil
+	}
+
+	return s.completeLogin(user, ipAddress, userAgent)
+}
+
+// VerifyTwoFactor finishes a login that Authenticate left waiting for a second
+// factor. Wrong codes count as failed login attempts for the rate limit.
+func (s *authService) VerifyTwoFactor(challengeToken, code, ipAddress, userAgent string) (*models.AuthResponse, error) {
+	user, err := s.challengeUser(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	isLimited, err := s.IsRateLimited(user.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	if isLimited {
+		s.recordLoginAttempt(user.Email, ipAddress, userAgent, false)
+		return nil, fmt.Errorf("too many failed login attempts. Please try again later")
+	}
+
+	if err := s.twoFactorService.VerifyLogin(user.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.recordLoginAttempt(user.Email, ipAddress, userAgent, false)
+			return nil, err
+		}
+		if errors.Is(err, ErrTwoFactorNotSetUp) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to verify two-factor code: %w", err)
+	}
+
+	return s.completeLogin(user, ipAddress, userAgent)
+}
+
+// BeginTwoFactorEnrollment lets a user whose role requires 2FA set it up in the
+// middle of a login, since they cannot get an access token without it
+func (s *authService) BeginTwoFactorEnrollment(challengeToken string) (*models.TwoFactorEnrollment, error) {
+	user, err := s.challengeUser(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	step, err := s.twoFactorService.LoginStep(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check two-factor authentication: %w", err)
+	}
+	if step != models.TwoFactorStepEnroll {
+		return nil, fmt.Errorf("two-factor enrollment is not pending for this login")
+	}
+
+	return s.twoFactorService.BeginEnrollment(user.ID)
+}
+
+func (s *authService) challengeUser(challengeToken string) (*models.User, error) {
+	claims, err := s.tokenService.ValidateChallengeToken(challengeToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired challenge token")
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired challenge token")
+	}
+
+	if !user.IsActive {
+		return nil, fmt.Errorf("account is deactivated")
+	}
+
+	return user, nil
+}
+
+func (s *authService) completeLogin(user *models.User, ipAddress, userAgent string) (*models.AuthResponse, error) {
 	tokenPair, err := s.tokenService.GenerateTokenPair(user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
@@ -78,7 +192,7 @@ func (s *authService) Authenticate(email, password, ipAddress, userAgent string)
 		return nil, fmt.Errorf("failed to update last login: %w", err)
 	}
 
-	s.recordLoginAttempt(email, ipAddress, userAgent, true)
+	s.recordLoginAttempt(user.Email, ipAddress, userAgent, true)
 
 	return &models.AuthResponse{
 		User:         user,
@@ -88,9 +202,13 @@ func (s *authService) Authenticate(email, password, ipAddress, userAgent string)
 	}, nil
 }
 
-func (s *authService) RefreshToken(refreshToken string) (*models.TokenPair, error) {
+func (s *authService) RefreshToken(refreshToken, ipAddress, userAgent string) (*models.TokenPair, error) {
 	tokenPair, err := s.tokenService.RefreshAccessToken(refreshToken)
 	if err != nil {
+		var reuseErr *models.RefreshTokenReuseError
+		if errors.As(err, &reuseErr) {
+			s.recordTokenReuse(reuseErr, ipAddress, userAgent)
+		}
 		return nil, fmt.Errorf("failed to refresh token: %w", err)
 	}
 
@@ -115,6 +233,19 @@ func (s *authService) LogoutAll(userID int32) error {
 	return nil
 }
 
+func (s *authService) ListSecurityEvents(userID int32, limit int) ([]models.SecurityEvent, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	events, err := s.securityEventRepo.ListByUser(userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list security events: %w", err)
+	}
+
+	return events, nil
+}
+
 func (s *authService) IsRateLimited(email string) (bool, error) {
 	since := time.Now().Add(-s.rateLimitWindow)
 
@@ -136,4 +267,23 @@ func (s *authService) recordLoginAttempt(email, ipAddress, userAgent string, suc
 	}
 
 	s.loginAttemptRepo.Create(attempt)
-}
\ No newline at end of file
+}
+
+func (s *authService) recordTokenReuse(reuseErr *models.RefreshTokenReuseError, ipAddress, userAgent string) {
+	log.Printf("Security: refresh token %d reused, revoked token family %s of user %d (ip %s)",
+		reuseErr.TokenID, reuseErr.FamilyID, reuseErr.UserID, ipAddress)
+
+	event := &models.SecurityEvent{
+		UserID:    reuseErr.UserID,
+		Type:      models.SecurityEventRefreshTokenReuse,
+		FamilyID:  reuseErr.FamilyID,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Details:   fmt.Sprintf("rotated refresh token %d presented again; token family revoked", reuseErr.TokenID),
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.securityEventRepo.Create(event); err != nil {
+		log.Printf("Failed to record security event for user %d: %v", reuseErr.UserID, err)
+	}
+}
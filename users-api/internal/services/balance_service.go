@@ -0,0 +1,142 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"users-api/internal/models"
+	"users-api/internal/repositories"
+)
+
+type BalanceService interface {
+	GetBalance(userID int32) (*models.BalanceSummary, error)
+	HoldFunds(userID int32, req *models.HoldFundsRequest) (*models.BalanceHold, error)
+	AdjustHold(userID int32, orderID string, req *models.AdjustHoldRequest) (*models.BalanceHold, error)
+	CaptureHold(userID int32, orderID string, req *models.CaptureHoldRequest) (*models.BalanceHold, error)
+	ReleaseHold(userID int32, orderID string) (*models.BalanceHold, error)
+	ApplyTransaction(userID int32, req *models.ApplyTransactionRequest) (*models.BalanceTransactionResult, error)
+	SetBalance(userID int32, amount float64) (*models.BalanceTransactionResult, error)
+}
+
+type balanceService struct {
+	balanceRepo repositories.BalanceRepository
+}
+
+func NewBalanceService(balanceRepo repositories.BalanceRepository) BalanceService {
+	return &balanceService{
+		balanceRepo: balanceRepo,
+	}
+}
+
+func (s *balanceService) GetBalance(userID int32) (*models.BalanceSummary, error) {
+	summary, err := s.balanceRepo.GetSummary(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return summary, nil
+}
+
+func (s *balanceService) HoldFunds(userID int32, req *models.HoldFundsRequest) (*models.BalanceHold, error) {
+	orderID := strings.TrimSpace(req.OrderID)
+	if orderID == "" {
+		return nil, fmt.Errorf("invalid hold: order_id is required")
+	}
+
+	amount := models.RoundCurrency(req.Amount)
+	if amount <= 0 {
+		return nil, fmt.Errorf("invalid hold: amount must be positive")
+	}
+
+	hold, err := s.balanceRepo.CreateHold(userID, orderID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hold funds: %w", err)
+	}
+	return hold, nil
+}
+
+func (s *balanceService) AdjustHold(userID int32, orderID string, req *models.AdjustHoldRequest) (*models.BalanceHold, error) {
+	amount := models.RoundCurrency(req.Amount)
+	if amount <= 0 {
+		return nil, fmt.Errorf("invalid hold: amount must be positive")
+	}
+
+	hold, err := s.balanceRepo.AdjustHold(userID, orderID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust hold: %w", err)
+	}
+	return hold, nil
+}
+
+func (s *balanceService) CaptureHold(userID int32, orderID string, req *models.CaptureHoldRequest) (*models.BalanceHold, error) {
+	amount := models.RoundCurrency(req.Amount)
+	if amount <= 0 {
+		return nil, fmt.Errorf("invalid capture: amount must be positive")
+	}
+
+	if req.FillID != "" {
+		hold, err := s.balanceRepo.CapturePartialHold(userID, orderID, req.FillID, amount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture hold: %w", err)
+		}
+		return hold, nil
+	}
+
+	hold, err := s.balanceRepo.CaptureHold(userID, orderID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture hold: %w", err)
+	}
+	return hold, nil
+}
+
+func (s *balanceService) ReleaseHold(userID int32, orderID string) (*models.BalanceHold, error) {
+	hold, err := s.balanceRepo.ReleaseHold(userID, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release hold: %w", err)
+	}
+	return hold, nil
+}
+
+func (s *balanceService) ApplyTransaction(userID int32, req *models.ApplyTransactionRequest) (*models.BalanceTransactionResult, error) {
+	delta := models.RoundCurrency(req.Delta)
+	if delta == 0 {
+		return nil, fmt.Errorf("invalid transaction: delta must not be zero")
+	}
+
+	switch req.Type {
+	case models.TransactionTypeBuy, models.TransactionTypeWithdrawal:
+		if delta > 0 {
+			return nil, fmt.Errorf("invalid transaction: %s must have a negative delta", req.Type)
+		}
+	case models.TransactionTypeSell, models.TransactionTypeDeposit:
+		if delta < 0 {
+			return nil, fmt.Errorf("invalid transaction: %s must have a positive delta", req.Type)
+		}
+	case models.TransactionTypeAdjustment:
+	default:
+		return nil, fmt.Errorf("invalid transaction: unknown type %s", req.Type)
+	}
+
+	idempotencyKey := strings.TrimSpace(req.IdempotencyKey)
+	if len(idempotencyKey) > 100 {
+		return nil, fmt.Errorf("invalid transaction: idempotency key is too long")
+	}
+
+	result, err := s.balanceRepo.ApplyTransaction(userID, delta, req.Type, strings.TrimSpace(req.OrderID), req.Description, idempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply transaction: %w", err)
+	}
+	return result, nil
+}
+
+func (s *balanceService) SetBalance(userID int32, amount float64) (*models.BalanceTransactionResult, error) {
+	amount = models.RoundCurrency(amount)
+	if amount < 0 {
+		return nil, fmt.Errorf("invalid balance: amount cannot be negative")
+	}
+
+	result, err := s.balanceRepo.SetBalance(userID, amount, "Balance set by internal service")
+	if err != nil {
+		return nil, fmt.Errorf("failed to update balance: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"users-api/internal/models"
+	"users-api/internal/repositories"
+)
+
+const (
+	rsaKeyBits = 2048
+	// keyRetentionMargin keeps retired keys published a bit longer than the
+	// tokens they signed, to absorb clock skew between services
+	keyRetentionMargin = 5 * time.Minute
+	// keyReloadMinInterval limits how often an unknown kid reloads the keys
+	keyReloadMinInterval = 10 * time.Second
+)
+
+// KeyPair is a parsed signing key
+type KeyPair struct {
+	KID        string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+}
+
+// KeyService keeps the JWT signing keys. The keys live in the database so every
+// instance signs with the same active key and publishes the same JWKS.
+type KeyService interface {
+	// SigningKey returns the active key, used to sign new tokens
+	SigningKey() (*KeyPair, error)
+	// VerificationKey returns the published key with the given kid
+	VerificationKey(kid string) (*KeyPair, error)
+	JWKS() *models.JWKS
+	// Refresh reloads the published keys, creating a new active key when there is
+	// none, the current one is older than the rotation interval or it uses
+	// another algorithm than the configured one
+	Refresh() error
+	// Start refreshes the keys and deletes expired ones every KeyRefreshInterval until ctx is cancelled
+	Start(ctx context.Context)
+}
+
+type keyService struct {
+	jwtConfig      *models.JWTConfig
+	signingKeyRepo repositories.SigningKeyRepository
+
+	refreshMu   sync.Mutex
+	mu          sync.RWMutex
+	active      *KeyPair
+	keys        map[string]*KeyPair
+	jwks        *models.JWKS
+	lastRefresh time.Time
+}
+
+func NewKeyService(jwtConfig *models.JWTConfig, signingKeyRepo repositories.SigningKeyRepository) KeyService {
+	return &keyService{
+		jwtConfig:      jwtConfig,
+		signingKeyRepo: signingKeyRepo,
+		keys:           make(map[string]*KeyPair),
+		jwks:           &models.JWKS{Keys: []models.JWK{}},
+	}
+}
+
+func (s *keyService) SigningKey() (*KeyPair, error) {
+	s.mu.RLock()
+	active := s.active
+	s.mu.RUnlock()
+	if active != nil {
+		return active, nil
+	}
+
+	if err := s.Refresh(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.active == nil {
+		return nil, fmt.Errorf("no active signing key")
+	}
+	return s.active, nil
+}
+
+func (s *keyService) VerificationKey(kid string) (*KeyPair, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	lastRefresh := s.lastRefresh
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	// Another instance may have rotated the key since the last refresh
+	if kid != "" && time.Since(lastRefresh) >= keyReloadMinInterval {
+		if err := s.Refresh(); err != nil {
+			return nil, err
+		}
+
+		s.mu.RLock()
+		key, ok = s.keys[kid]
+		s.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *keyService) JWKS() *models.JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.jwks
+}
+
+func (s *keyService) Refresh() error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	keys, err := s.signingKeyRepo.ListPublished(time.Now())
+	if err != nil {
+		return err
+	}
+
+	active := activeSigningKey(keys)
+	if s.rotationDue(active) {
+		if err := s.rotate(active); err != nil {
+			return err
+		}
+
+		if keys, err = s.signingKeyRepo.ListPublished(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	return s.load(keys)
+}
+
+// rotationDue reports whether active must be replaced: there is no key yet, it
+// is older than the rotation interval or the configured algorithm changed
+func (s *keyService) rotationDue(active *models.SigningKey) bool {
+	if active == nil || active.Algorithm != s.jwtConfig.SigningAlgorithm {
+		return true
+	}
+	return s.jwtConfig.KeyRotationInterval > 0 && time.Since(active.CreatedAt) >= s.jwtConfig.KeyRotationInterval
+}
+
+// rotate replaces current (nil when there is no key yet) with a new key. The
+// retired key stays published until every token it may have signed has expired,
+// including tokens signed by instances that have not refreshed yet.
+func (s *keyService) rotate(current *models.SigningKey) error {
+	next, err := GenerateSigningKey(s.jwtConfig.SigningAlgorithm)
+	if err != nil {
+		return err
+	}
+
+	currentKID := ""
+	if current != nil {
+		currentKID = current.KID
+	}
+
+	retiredUntil := time.Now().Add(s.keyRetention())
+	if err := s.signingKeyRepo.Rotate(currentKID, next, retiredUntil); err != nil {
+		// Another instance rotated first, its key is picked up on reload
+		if errors.Is(err, repositories.ErrSigningKeyRotated) {
+			return nil
+		}
+		return err
+	}
+
+	if current != nil {
+		log.Printf("Rotated JWT signing key %s, new key %s", current.KID, next.KID)
+	} else {
+		log.Printf("Created JWT signing key %s", next.KID)
+	}
+	return nil
+}
+
+func (s *keyService) keyRetention() time.Duration {
+	ttl := s.jwtConfig.AccessTokenTTL
+	if s.jwtConfig.ChallengeTokenTTL > ttl {
+		ttl = s.jwtConfig.ChallengeTokenTTL
+	}
+	if s.jwtConfig.ServiceTokenTTL > ttl {
+		ttl = s.jwtConfig.ServiceTokenTTL
+	}
+	return ttl + s.jwtConfig.KeyRefreshInterval + keyRetentionMargin
+}
+
+func (s *keyService) load(keys []models.SigningKey) error {
+	active := activeSigningKey(keys)
+	if active == nil {
+		return fmt.Errorf("no active signing key")
+	}
+
+	parsed := make(map[string]*KeyPair, len(keys))
+	jwks := &models.JWKS{Keys: make([]models.JWK, 0, len(keys))}
+	for i := range keys {
+		key, err := parseSigningKey(&keys[i])
+		if err != nil {
+			return err
+		}
+
+		jwk, err := toJWK(key)
+		if err != nil {
+			return err
+		}
+
+		parsed[key.KID] = key
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = parsed[active.KID]
+	s.keys = parsed
+	s.jwks = jwks
+	s.lastRefresh = time.Now()
+	return nil
+}
+
+func (s *keyService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.jwtConfig.KeyRefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(); err != nil {
+					log.Printf("Failed to refresh JWT signing keys: %v", err)
+				}
+
+				if deleted, err := s.signingKeyRepo.DeleteExpired(time.Now()); err != nil {
+					log.Printf("Failed to delete expired JWT signing keys: %v", err)
+				} else if deleted > 0 {
+					log.Printf("Deleted %d expired JWT signing keys", deleted)
+				}
+			}
+		}
+	}()
+}
+
+// activeSigningKey returns the newest active key of keys
+func activeSigningKey(keys []models.SigningKey) *models.SigningKey {
+	var active *models.SigningKey
+	for i := range keys {
+		if keys[i].Status != models.SigningKeyActive {
+			continue
+		}
+		if active == nil || keys[i].CreatedAt.After(active.CreatedAt) {
+			active = &keys[i]
+		}
+	}
+	return active
+}
+
+// GenerateSigningKey creates a new key pair for algorithm, encoded for storage
+func GenerateSigningKey(algorithm models.SigningAlgorithm) (*models.SigningKey, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch algorithm {
+	case models.SigningAlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case models.SigningAlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	kidBytes := make([]byte, 8)
+	if _, err := rand.Read(kidBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+
+	return &models.SigningKey{
+		KID:        hex.EncodeToString(kidBytes),
+		Algorithm:  algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})),
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		Status:     models.SigningKeyActive,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+func parseSigningKey(key *models.SigningKey) (*KeyPair, error) {
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s: invalid PEM", key.KID)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %s: %w", key.KID, err)
+	}
+
+	pair := &KeyPair{KID: key.KID}
+	switch privateKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.Algorithm != models.SigningAlgorithmRS256 {
+			return nil, fmt.Errorf("signing key %s: RSA key stored as %s", key.KID, key.Algorithm)
+		}
+		pair.Method = jwt.SigningMethodRS256
+		pair.PrivateKey = privateKey
+	case ed25519.PrivateKey:
+		if key.Algorithm != models.SigningAlgorithmEdDSA {
+			return nil, fmt.Errorf("signing key %s: Ed25519 key stored as %s", key.KID, key.Algorithm)
+		}
+		pair.Method = jwt.SigningMethodEdDSA
+		pair.PrivateKey = privateKey
+	default:
+		return nil, fmt.Errorf("signing key %s: unsupported key type %T", key.KID, parsed)
+	}
+	pair.PublicKey = pair.PrivateKey.Public()
+
+	return pair, nil
+}
+
+func toJWK(key *KeyPair) (models.JWK, error) {
+	jwk := models.JWK{
+		Use: "sig",
+		Alg: key.Method.Alg(),
+		Kid: key.KID,
+	}
+
+	switch publicKey := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	default:
+		return models.JWK{}, fmt.Errorf("signing key %s: unsupported key type %T", key.KID, key.PublicKey)
+	}
+
+	return jwk, nil
+}
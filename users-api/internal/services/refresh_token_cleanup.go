@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"users-api/internal/repositories"
+)
+
+// RefreshTokenCleanupJob periodically deletes expired refresh tokens. Revoked
+// tokens stay until they expire so that replaying a rotated token is still
+// detected as reuse.
+type RefreshTokenCleanupJob struct {
+	refreshTokenRepo repositories.RefreshTokenRepository
+	interval         time.Duration
+}
+
+func NewRefreshTokenCleanupJob(refreshTokenRepo repositories.RefreshTokenRepository, interval time.Duration) *RefreshTokenCleanupJob {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	return &RefreshTokenCleanupJob{
+		refreshTokenRepo: refreshTokenRepo,
+		interval:         interval,
+	}
+}
+
+// Start runs a cleanup right away and then every interval until ctx is cancelled
+func (j *RefreshTokenCleanupJob) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		j.RunOnce()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				j.RunOnce()
+			}
+		}
+	}()
+}
+
+// RunOnce deletes the expired tokens and returns how many were removed
+func (j *RefreshTokenCleanupJob) RunOnce() int64 {
+	deleted, err := j.refreshTokenRepo.DeleteExpired()
+	if err != nil {
+		log.Printf("Failed to delete expired refresh tokens: %v", err)
+		return 0
+	}
+
+	if deleted > 0 {
+		log.Printf("Deleted %d expired refresh tokens", deleted)
+	}
+	return deleted
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"users-api/internal/models"
+	"users-api/pkg/utils"
+)
+
+// ServiceAuthService issues the tokens other services use to call the internal
+// endpoints, in exchange for their own credentials.
+type ServiceAuthService interface {
+	IssueToken(service, secret, scope string) (*models.ServiceToken, error)
+}
+
+type serviceAuthService struct {
+	clients      map[string]models.ServiceClient
+	tokenTTL     time.Duration
+	tokenService TokenService
+}
+
+// NewServiceAuthService creates the service for the given clients. tokenTTL is
+// the ServiceTokenTTL the token service signs with, reported as expires_in.
+func NewServiceAuthService(clients []models.ServiceClient, tokenTTL time.Duration, tokenService TokenService) ServiceAuthService {
+	byName := make(map[string]models.ServiceClient, len(clients))
+	for _, client := range clients {
+		byName[client.Name] = client
+	}
+
+	return &serviceAuthService{
+		clients:      byName,
+		tokenTTL:     tokenTTL,
+		tokenService: tokenService,
+	}
+}
+
+// IssueToken checks the secret of service and returns a token with the requested
+// scopes, or with all the scopes of the service when scope is empty. Requesting a
+// scope the service does not have fails instead of silently dropping it.
+func (s *serviceAuthService) IssueToken(service, secret, scope string) (*models.ServiceToken, error) {
+	client, found := s.clients[service]
+	// The hash is compared even for unknown services so the response time does
+	// not reveal which services exist
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.SecretHash)) != 1 || !found {
+		log.Printf("Rejected service token request for %q: invalid credentials", service)
+		return nil, fmt.Errorf("invalid service credentials")
+	}
+
+	scopes := client.Scopes
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		for _, requestedScope := range requested {
+			if !client.HasScope(requestedScope) {
+				log.Printf("Rejected service token request for %q: scope %q not allowed", service, requestedScope)
+				return nil, fmt.Errorf("scope %q not allowed for service %s", requestedScope, service)
+			}
+		}
+		scopes = requested
+	}
+
+	token, err := s.tokenService.GenerateServiceToken(service, scopes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate service token: %w", err)
+	}
+
+	return &models.ServiceToken{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.tokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
@@ -3,7 +3,10 @@ package services
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"users-api/internal/models"
@@ -18,34 +21,56 @@ type TokenService interface {
 	RefreshAccessToken(refreshToken string) (*models.TokenPair, error)
 	RevokeRefreshToken(token string) error
 	RevokeAllUserTokens(userID int32) error
+	GenerateChallengeToken(user *models.User) (string, error)
+	ValidateChallengeToken(tokenString string) (*models.ChallengeClaims, error)
+	GenerateServiceToken(service string, scopes []string) (string, error)
+	ValidateServiceToken(tokenString string) (*models.ServiceClaims, error)
 }
 
+// accessTokenAudience is the audience of access tokens, checked by the other services
+const accessTokenAudience = "cryptosim"
+
+// signingAlgorithms are the algorithms accepted when parsing tokens
+var signingAlgorithms = []string{string(models.SigningAlgorithmRS256), string(models.SigningAlgorithmEdDSA)}
+
 type tokenService struct {
 	jwtConfig              *models.JWTConfig
 	refreshTokenRepository repositories.RefreshTokenRepository
+	keyService             KeyService
 }
 
-func NewTokenService(jwtConfig *models.JWTConfig, refreshTokenRepo repositories.RefreshTokenRepository) TokenService {
+func NewTokenService(jwtConfig *models.JWTConfig, refreshTokenRepo repositories.RefreshTokenRepository, keyService KeyService) TokenService {
 	return &tokenService{
 		jwtConfig:              jwtConfig,
 		refreshTokenRepository: refreshTokenRepo,
+		keyService:             keyService,
 	}
 }
 
+// GenerateTokenPair issues the tokens of a new login, which starts a new refresh token family
 func (s *tokenService) GenerateTokenPair(user *models.User) (*models.TokenPair, error) {
+	familyID, err := newTokenFamilyID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
 	accessToken, err := s.generateAccessToken(user)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := s.generateRefreshToken(user)
+	refreshToken, err := s.newRefreshToken(user, familyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
+	if err := s.refreshTokenRepository.Create(refreshToken); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
 	return &models.TokenPair{
 		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		RefreshToken: refreshToken.Token,
 		ExpiresIn:    int64(s.jwtConfig.AccessTokenTTL.Seconds()),
 	}, nil
 }
@@ -62,44 +87,67 @@ func (s *tokenService) generateAccessToken(user *models.User) (string, error) {
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    s.jwtConfig.Issuer,
-			Audience:  []string{"cryptosim"}, // Add audience for Orders API compatibility
+			Audience:  []string{accessTokenAudience},
 			Subject:   fmt.Sprintf("%d", user.ID),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtConfig.SecretKey))
+	return s.sign(claims)
 }
 
-func (s *tokenService) generateRefreshToken(user *models.User) (string, error) {
+// sign signs claims with the active key. The kid header tells verifiers which
+// key of the JWKS to use.
+func (s *tokenService) sign(claims jwt.Claims) (string, error) {
+	key, err := s.keyService.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
+	return token.SignedString(key.PrivateKey)
+}
+
+// verificationKey is the jwt.Keyfunc of the token parsers
+func (s *tokenService) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := s.keyService.VerificationKey(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+func (s *tokenService) newRefreshToken(user *models.User, familyID string) (*models.RefreshToken, error) {
 	tokenBytes := make([]byte, 32)
 	_, err := rand.Read(tokenBytes)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
 	}
 
-	tokenString := base64.URLEncoding.EncodeToString(tokenBytes)
-
-	refreshToken := &models.RefreshToken{
+	return &models.RefreshToken{
 		UserID:    user.ID,
-		Token:     tokenString,
+		Token:     base64.URLEncoding.EncodeToString(tokenBytes),
+		FamilyID:  familyID,
 		ExpiresAt: time.Now().Add(s.jwtConfig.RefreshTokenTTL),
-	}
+	}, nil
+}
 
-	if err := s.refreshTokenRepository.Create(refreshToken); err != nil {
-		return "", fmt.Errorf("failed to store refresh token: %w", err)
+func newTokenFamilyID() (string, error) {
+	familyBytes := make([]byte, 16)
+	if _, err := rand.Read(familyBytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
 	}
-
-	return tokenString, nil
+	return hex.EncodeToString(familyBytes), nil
 }
 
 func (s *tokenService) ValidateAccessToken(tokenString string) (*models.CustomClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &models.CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.jwtConfig.SecretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &models.CustomClaims{}, s.verificationKey,
+		jwt.WithValidMethods(signingAlgorithms), jwt.WithAudience(accessTokenAudience))
 
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
@@ -113,25 +161,63 @@ func (s *tokenService) ValidateAccessToken(tokenString string) (*models.CustomCl
 	return claims, nil
 }
 
+// RefreshAccessToken exchanges a refresh token for a new pair. The presented
+// token is revoked and the new one joins its family. Presenting a token that was
+// already rotated means it leaked (either the thief or the user already used it),
+// so the whole family is revoked and a *models.RefreshTokenReuseError returned.
 func (s *tokenService) RefreshAccessToken(refreshToken string) (*models.TokenPair, error) {
 	storedToken, err := s.refreshTokenRepository.GetByToken(refreshToken)
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
 
-	if storedToken.IsExpired() {
-		return nil, fmt.Errorf("refresh token expired")
+	if storedToken.WasRotated() {
+		return nil, s.revokeReusedFamily(storedToken)
 	}
 
 	if storedToken.Revoked {
 		return nil, fmt.Errorf("refresh token revoked")
 	}
 
-	if err := s.refreshTokenRepository.RevokeByToken(refreshToken); err != nil {
-		return nil, fmt.Errorf("failed to revoke old refresh token: %w", err)
+	if storedToken.IsExpired() {
+		return nil, fmt.Errorf("refresh token expired")
+	}
+
+	accessToken, err := s.generateAccessToken(&storedToken.User)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	nextToken, err := s.newRefreshToken(&storedToken.User, storedToken.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	if err := s.refreshTokenRepository.Rotate(storedToken, nextToken); err != nil {
+		// Another request rotated the same token between the read and the update
+		if errors.Is(err, repositories.ErrRefreshTokenRevoked) {
+			return nil, s.revokeReusedFamily(storedToken)
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: nextToken.Token,
+		ExpiresIn:    int64(s.jwtConfig.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (s *tokenService) revokeReusedFamily(token *models.RefreshToken) error {
+	if _, err := s.refreshTokenRepository.RevokeFamily(token.FamilyID, models.RevokeReasonReuseDetected); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
 	}
 
-	return s.GenerateTokenPair(&storedToken.User)
+	return &models.RefreshTokenReuseError{
+		UserID:   token.UserID,
+		FamilyID: token.FamilyID,
+		TokenID:  token.ID,
+	}
 }
 
 func (s *tokenService) RevokeRefreshToken(token string) error {
@@ -141,3 +227,77 @@ func (s *tokenService) RevokeRefreshToken(token string) error {
 func (s *tokenService) RevokeAllUserTokens(userID int32) error {
 	return s.refreshTokenRepository.RevokeByUserID(userID)
 }
+
+// GenerateChallengeToken issues the token that links the password step of a
+// login with the second factor step. It carries no role, is only valid for the
+// 2FA endpoints and expires after ChallengeTokenTTL.
+func (s *tokenService) GenerateChallengeToken(user *models.User) (string, error) {
+	now := time.Now()
+	claims := &models.ChallengeClaims{
+		UserID: user.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.jwtConfig.ChallengeTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    s.jwtConfig.Issuer,
+			Audience:  []string{models.ChallengeTokenAudience},
+			Subject:   fmt.Sprintf("%d", user.ID),
+		},
+	}
+
+	return s.sign(claims)
+}
+
+func (s *tokenService) ValidateChallengeToken(tokenString string) (*models.ChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &models.ChallengeClaims{}, s.verificationKey,
+		jwt.WithValidMethods(signingAlgorithms), jwt.WithAudience(models.ChallengeTokenAudience))
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid challenge token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*models.ChallengeClaims)
+	if !ok || !token.Valid || claims.UserID == 0 {
+		return nil, fmt.Errorf("invalid challenge token claims")
+	}
+
+	return claims, nil
+}
+
+// GenerateServiceToken issues the token a service sends to the internal
+// endpoints. It carries the service name and the granted scopes instead of a
+// user, is addressed to the services those scopes belong to, and expires after
+// ServiceTokenTTL.
+func (s *tokenService) GenerateServiceToken(service string, scopes []string) (string, error) {
+	now := time.Now()
+	claims := &models.ServiceClaims{
+		Service: service,
+		Scope:   strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.jwtConfig.ServiceTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    s.jwtConfig.Issuer,
+			Audience:  models.ServiceTokenAudiences(scopes),
+			Subject:   "service:" + service,
+		},
+	}
+
+	return s.sign(claims)
+}
+
+func (s *tokenService) ValidateServiceToken(tokenString string) (*models.ServiceClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &models.ServiceClaims{}, s.verificationKey,
+		jwt.WithValidMethods(signingAlgorithms), jwt.WithAudience(models.ServiceTokenAudience))
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid service token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*models.ServiceClaims)
+	if !ok || !token.Valid || claims.Service == "" {
+		return nil, fmt.Errorf("invalid service token claims")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,360 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"users-api/internal/models"
+	"users-api/internal/repositories"
+	"users-api/pkg/utils"
+)
+
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var (
+	// ErrInvalidTwoFactorCode is returned when a TOTP or recovery code is wrong or already used
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorNotSetUp is returned when a login asks for a second factor the user never enrolled
+	ErrTwoFactorNotSetUp = errors.New("two-factor authentication is not set up")
+)
+
+type TwoFactorService interface {
+	GetStatus(userID int32) (*models.TwoFactorStatus, error)
+	BeginEnrollment(userID int32) (*models.TwoFactorEnrollment, error)
+	ConfirmEnrollment(userID int32, code string) error
+	Disable(userID int32, password, code string) error
+	RegenerateRecoveryCodes(userID int32, code string) ([]string, error)
+	LoginStep(user *models.User) (models.TwoFactorStep, error)
+	VerifyLogin(userID int32, code string) error
+	ListPolicies() ([]models.TwoFactorPolicy, error)
+	SetPolicy(role models.UserRole, required bool, adminID int32) (*models.TwoFactorPolicy, error)
+}
+
+type twoFactorService struct {
+	twoFactorRepo   repositories.TwoFactorRepository
+	userRepo        repositories.UserRepository
+	issuer          string
+	requireForAdmin bool
+}
+
+// NewTwoFactorService creates the TOTP service. issuer is the name authenticator
+// apps show next to the code; requireForAdmin applies until an admin saves a
+// policy for the admin role.
+func NewTwoFactorService(
+	twoFactorRepo repositories.TwoFactorRepository,
+	userRepo repositories.UserRepository,
+	issuer string,
+	requireForAdmin bool,
+) TwoFactorService {
+	return &twoFactorService{
+		twoFactorRepo:   twoFactorRepo,
+		userRepo:        userRepo,
+		issuer:          issuer,
+		requireForAdmin: requireForAdmin,
+	}
+}
+
+func (s *twoFactorService) GetStatus(userID int32) (*models.TwoFactorStatus, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := s.isRequired(user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor, err := s.twoFactorRepo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatus{Required: required}
+	if twoFactor == nil {
+		return status, nil
+	}
+
+	status.Enabled = twoFactor.Enabled
+	status.Pending = !twoFactor.Enabled
+	if twoFactor.Enabled {
+		status.RecoveryCodesRemaining, err = s.twoFactorRepo.CountRecoveryCodes(userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// BeginEnrollment generates a new secret and recovery codes. Nothing changes on
+// login until ConfirmEnrollment (or VerifyLogin, for roles that require 2FA)
+// proves the user's authenticator produces valid codes.
+func (s *twoFactorService) BeginEnrollment(userID int32) (*models.TwoFactorEnrollment, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	twoFactor, err := s.twoFactorRepo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor != nil && twoFactor.Enabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate two-factor secret: %w", err)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.Begin(userID, secret, hashes); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.issuer, user.Email, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+func (s *twoFactorService) ConfirmEnrollment(userID int32, code string) error {
+	twoFactor, err := s.twoFactorRepo.Get(userID)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil {
+		return fmt.Errorf("two-factor enrollment not found")
+	}
+	if twoFactor.Enabled {
+		return fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	return s.enable(twoFactor, code)
+}
+
+func (s *twoFactorService) Disable(userID int32, password, code string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	required, err := s.isRequired(user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fmt.Errorf("two-factor authentication is required for the %s role", user.Role)
+	}
+
+	if !utils.CheckPasswordHash(password, user.PasswordHash) {
+		return fmt.Errorf("invalid password")
+	}
+
+	twoFactor, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyCode(twoFactor, code); err != nil {
+		return err
+	}
+
+	return s.twoFactorRepo.Delete(userID)
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(userID int32, code string) ([]string, error) {
+	twoFactor, err := s.enabledTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(twoFactor, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// LoginStep tells Authenticate whether the user can get tokens after the
+// password check or still has to verify (or first set up) a second factor
+func (s *twoFactorService) LoginStep(user *models.User) (models.TwoFactorStep, error) {
+	twoFactor, err := s.twoFactorRepo.Get(user.ID)
+	if err != nil {
+		return "", err
+	}
+	if twoFactor != nil && twoFactor.Enabled {
+		return models.TwoFactorStepVerify, nil
+	}
+
+	required, err := s.isRequired(user.Role)
+	if err != nil {
+		return "", err
+	}
+	if required {
+		return models.TwoFactorStepEnroll, nil
+	}
+
+	return models.TwoFactorStepNone, nil
+}
+
+// VerifyLogin checks the second factor of a login. For a user finishing a
+// required enrollment during login the code also confirms the enrollment.
+func (s *twoFactorService) VerifyLogin(userID int32, code string) error {
+	twoFactor, err := s.twoFactorRepo.Get(userID)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil {
+		return ErrTwoFactorNotSetUp
+	}
+
+	if !twoFactor.Enabled {
+		return s.enable(twoFactor, code)
+	}
+
+	return s.verifyCode(twoFactor, code)
+}
+
+func (s *twoFactorService) ListPolicies() ([]models.TwoFactorPolicy, error) {
+	roles := []models.UserRole{models.RoleNormal, models.RoleAdmin}
+	policies := make([]models.TwoFactorPolicy, 0, len(roles))
+
+	for _, role := range roles {
+		policy, err := s.twoFactorRepo.GetPolicy(role)
+		if err != nil {
+			return nil, err
+		}
+		if policy == nil {
+			policy = &models.TwoFactorPolicy{Role: role, Required: s.defaultRequired(role)}
+		}
+		policies = append(policies, *policy)
+	}
+
+	return policies, nil
+}
+
+func (s *twoFactorService) SetPolicy(role models.UserRole, required bool, adminID int32) (*models.TwoFactorPolicy, error) {
+	if role != models.RoleNormal && role != models.RoleAdmin {
+		return nil, fmt.Errorf("invalid role: %s", role)
+	}
+
+	policy := &models.TwoFactorPolicy{
+		Role:      role,
+		Required:  required,
+		UpdatedBy: &adminID,
+		UpdatedAt: time.Now(),
+	}
+	if err := s.twoFactorRepo.SavePolicy(policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (s *twoFactorService) enable(twoFactor *models.TwoFactorAuth, code string) error {
+	step, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	return s.twoFactorRepo.Enable(twoFactor.UserID, step)
+}
+
+func (s *twoFactorService) enabledTwoFactor(userID int32) (*models.TwoFactorAuth, error) {
+	twoFactor, err := s.twoFactorRepo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactor == nil || !twoFactor.Enabled {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+	return twoFactor, nil
+}
+
+// verifyCode accepts a TOTP code that was not used before or an unused recovery code
+func (s *twoFactorService) verifyCode(twoFactor *models.TwoFactorAuth, code string) error {
+	if step, ok := utils.ValidateTOTP(twoFactor.Secret, code, time.Now()); ok {
+		fresh, err := s.twoFactorRepo.UseStep(twoFactor.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return fmt.Errorf("%w: code already used", ErrInvalidTwoFactorCode)
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	used, err := s.twoFactorRepo.UseRecoveryCode(twoFactor.UserID, utils.HashToken(normalized))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func (s *twoFactorService) isRequired(role models.UserRole) (bool, error) {
+	policy, err := s.twoFactorRepo.GetPolicy(role)
+	if err != nil {
+		return false, err
+	}
+	if policy != nil {
+		return policy.Required, nil
+	}
+	return s.defaultRequired(role), nil
+}
+
+func (s *twoFactorService) defaultRequired(role models.UserRole) bool {
+	return role == models.RoleAdmin && s.requireForAdmin
+}
+
+// generateRecoveryCodes returns the codes to show the user once (xxxxx-xxxxx)
+// and the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw)[:10])
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = utils.HashToken(code)
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -15,7 +15,6 @@ type UserService interface {
 	GetUserByEmail(email string) (*models.User, error)
 	UpdateUser(id int32, req *models.UpdateUserRequest) (*models.User, error)
 	ChangePassword(id int32, req *models.ChangePasswordRequest) error
-	UpdateBalance(id int32, newBalance float64) error
 	DeactivateUser(id int32) error
 	ListUsers(page, limit int, search, role string, isActive *bool) ([]models.User, int64, error)
 	UpgradeUserToAdmin(id int32) (*models.User, error)
@@ -183,13 +182,6 @@ func (s *userService) ChangePassword(id int32, req *models.ChangePasswordRequest
 	return nil
 }
 
-func (s *userService) UpdateBalance(id int32, newBalance float64) error {
-	if err := s.userRepo.UpdateBalance(id, newBalance); err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
-	}
-	return nil
-}
-
 func (s *userService) DeactivateUser(id int32) error {
 	exists, err := s.userRepo.Exists(id)
 	if err != nil {
@@ -0,0 +1,16 @@
+ALTER TABLE users
+    ADD COLUMN reserved_balance DECIMAL(15,2) NOT NULL DEFAULT 0.00 AFTER initial_balance;
+
+CREATE TABLE balance_holds (
+    id INT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    order_id VARCHAR(64) UNIQUE NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    captured_amount DECIMAL(15,2) DEFAULT 0.00,
+    status ENUM('active', 'captured', 'released') DEFAULT 'active',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_user_id (user_id),
+    INDEX idx_status (status)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE balance_holds DROP COLUMN transaction_id;
+DROP TABLE IF EXISTS balance_transactions;
@@ -0,0 +1,20 @@
+CREATE TABLE balance_transactions (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    transaction_id VARCHAR(40) NOT NULL,
+    account VARCHAR(50) NOT NULL,
+    user_id INT NULL,
+    entry_type ENUM('debit', 'credit') NOT NULL,
+    amount DECIMAL(15,2) NOT NULL,
+    balance_after DECIMAL(15,2) NULL,
+    type VARCHAR(20) NOT NULL,
+    order_id VARCHAR(64),
+    description VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_transaction_id (transaction_id),
+    INDEX idx_account (account),
+    INDEX idx_user_id (user_id),
+    INDEX idx_order_id (order_id)
+);
+
+ALTER TABLE balance_holds
+    ADD COLUMN transaction_id VARCHAR(40) NULL AFTER status;
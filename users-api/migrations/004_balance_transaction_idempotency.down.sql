@@ -0,0 +1,3 @@
+ALTER TABLE balance_transactions
+    DROP INDEX idx_idempotency_key,
+    DROP COLUMN idempotency_key;
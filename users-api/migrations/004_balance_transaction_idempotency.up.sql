@@ -0,0 +1,3 @@
+ALTER TABLE balance_transactions
+    ADD COLUMN idempotency_key VARCHAR(100) NULL AFTER description,
+    ADD UNIQUE INDEX idx_idempotency_key (idempotency_key);
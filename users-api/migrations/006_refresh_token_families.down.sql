@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS security_events;
+
+ALTER TABLE refresh_tokens
+    DROP INDEX idx_family_id,
+    DROP COLUMN revoked_at,
+    DROP COLUMN revoked_reason,
+    DROP COLUMN family_id;
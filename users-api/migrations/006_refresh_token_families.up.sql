@@ -0,0 +1,22 @@
+ALTER TABLE refresh_tokens
+    ADD COLUMN family_id VARCHAR(64) NOT NULL DEFAULT '' AFTER token,
+    ADD COLUMN revoked_reason VARCHAR(20) NULL AFTER revoked,
+    ADD COLUMN revoked_at TIMESTAMP NULL AFTER revoked_reason,
+    ADD INDEX idx_family_id (family_id);
+
+-- Tokens issued before rotation each start their own family
+UPDATE refresh_tokens SET family_id = CONCAT('legacy-', id) WHERE family_id = '';
+
+CREATE TABLE security_events (
+    id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    user_id INT NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    family_id VARCHAR(64),
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    details VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_user_id (user_id),
+    INDEX idx_type (type),
+    INDEX idx_created_at (created_at)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE jwt_signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(10) NOT NULL,
+    private_key TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    retired_at TIMESTAMP NULL,
+    expires_at TIMESTAMP NULL,
+    INDEX idx_status (status),
+    INDEX idx_expires_at (expires_at)
+);
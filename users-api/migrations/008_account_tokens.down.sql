@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS account_tokens;
+
+ALTER TABLE users
+    DROP COLUMN email_verified_at,
+    DROP COLUMN email_verified;
@@ -61,6 +61,14 @@ func (d *Database) AutoMigrate() error {
 		&models.User{},
 		&models.RefreshToken{},
 		&models.LoginAttempt{},
+		&models.BalanceHold{},
+		&models.BalanceTransaction{},
+		&models.TwoFactorAuth{},
+		&models.TwoFactorRecoveryCode{},
+		&models.TwoFactorPolicy{},
+		&models.SecurityEvent{},
+		&models.SigningKey{},
+		&models.AccountToken{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to run auto migration: %w", err)
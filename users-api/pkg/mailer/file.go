@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer is meant for local development and tests: instead of sending, it
+// writes every email to an .eml file in dir, or to the log when dir is empty.
+type FileMailer struct {
+	from    string
+	dir     string
+	counter uint64
+}
+
+func NewFileMailer(from, dir string) *FileMailer {
+	return &FileMailer{
+		from: from,
+		dir:  dir,
+	}
+}
+
+func (m *FileMailer) Send(msg *Message) error {
+	if m.dir == "" {
+		log.Printf("[MAIL] to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	n := atomic.AddUint64(&m.counter, 1)
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s-%04d-%s.eml", time.Now().Format("20060102T150405"), n, recipient)
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, msg), 0o600); err != nil {
+		return fmt.Errorf("failed to write email to %s: %w", m.dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import "fmt"
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends the account emails (password reset, email verification)
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// Config selects and configures the mailer. Driver is "smtp", "file" or "log".
+type Config struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string
+}
+
+// New returns the mailer of config.Driver
+func New(config Config) (Mailer, error) {
+	switch config.Driver {
+	case "smtp":
+		if config.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP host is required for the smtp mail driver")
+		}
+		return NewSMTPMailer(config), nil
+	case "file":
+		return NewFileMailer(config.From, config.FileDir), nil
+	case "log":
+		return NewFileMailer(config.From, ""), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", config.Driver)
+	}
+}
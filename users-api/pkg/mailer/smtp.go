@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends emails through an SMTP server. The connection is upgraded
+// with STARTTLS when the server supports it, and authentication is only used
+// when a username is configured.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	from     string
+	username string
+	password string
+}
+
+func NewSMTPMailer(config Config) *SMTPMailer {
+	port := config.SMTPPort
+	if port == "" {
+		port = "587"
+	}
+
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(config.SMTPHost, port),
+		host:     config.SMTPHost,
+		from:     config.From,
+		username: config.SMTPUsername,
+		password: config.SMTPPassword,
+	}
+}
+
+func (m *SMTPMailer) Send(msg *Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	if err := smtp.SendMail(m.addr, auth, m.from, []string{msg.To}, formatMessage(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// formatMessage builds the RFC 5322 message. Header values come from
+// configuration and the service itself, but line breaks are stripped anyway so
+// an address can never inject headers.
+func formatMessage(from string, msg *Message) []byte {
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	b.WriteString("From: " + clean.Replace(from) + "\r\n")
+	b.WriteString("To: " + clean.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + clean.Replace(msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
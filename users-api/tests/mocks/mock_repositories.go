@@ -45,11 +45,6 @@ func (m *MockUserRepository) Update(user *models.User) error {
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) UpdateBalance(id int32, newBalance float64) error {
-	args := m.Called(id, newBalance)
-	return args.Error(0)
-}
-
 func (m *MockUserRepository) Delete(id int32) error {
 	args := m.Called(id)
 	return args.Error(0)
@@ -65,6 +60,16 @@ func (m *MockUserRepository) UpdateLastLogin(id int32) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdatePassword(id int32, passwordHash string) error {
+	args := m.Called(id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(id int32) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) Exists(id int32) (bool, error) {
 	args := m.Called(id)
 	return args.Bool(0), args.Error(1)
@@ -97,11 +102,21 @@ func (m *MockRefreshTokenRepository) RevokeByToken(token string) error {
 	return args.Error(0)
 }
 
-func (m *MockRefreshTokenRepository) DeleteExpired() error {
-	args := m.Called()
+func (m *MockRefreshTokenRepository) Rotate(current *models.RefreshToken, next *models.RefreshToken) error {
+	args := m.Called(current, next)
 	return args.Error(0)
 }
 
+func (m *MockRefreshTokenRepository) RevokeFamily(familyID string, reason models.RefreshTokenRevokeReason) (int64, error) {
+	args := m.Called(familyID, reason)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) DeleteExpired() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
 type MockLoginAttemptRepository struct {
 	mock.Mock
 }
@@ -119,4 +134,190 @@ func (m *MockLoginAttemptRepository) GetRecentAttempts(email string, since time.
 func (m *MockLoginAttemptRepository) CountFailedAttempts(email string, since time.Time) (int64, error) {
 	args := m.Called(email, since)
 	return args.Get(0).(int64), args.Error(1)
-}
\ No newline at end of file
+}
+
+type MockBalanceRepository struct {
+	mock.Mock
+}
+
+func (m *MockBalanceRepository) GetSummary(userID int32) (*models.BalanceSummary, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceSummary), args.Error(1)
+}
+
+func (m *MockBalanceRepository) CreateHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error) {
+	args := m.Called(userID, orderID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceHold), args.Error(1)
+}
+
+func (m *MockBalanceRepository) CaptureHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error) {
+	args := m.Called(userID, orderID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceHold), args.Error(1)
+}
+
+func (m *MockBalanceRepository) CapturePartialHold(userID int32, orderID, fillID string, amount float64) (*models.BalanceHold, error) {
+	args := m.Called(userID, orderID, fillID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceHold), args.Error(1)
+}
+
+func (m *MockBalanceRepository) AdjustHold(userID int32, orderID string, amount float64) (*models.BalanceHold, error) {
+	args := m.Called(userID, orderID, amount)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceHold), args.Error(1)
+}
+
+func (m *MockBalanceRepository) ReleaseHold(userID int32, orderID string) (*models.BalanceHold, error) {
+	args := m.Called(userID, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceHold), args.Error(1)
+}
+
+func (m *MockBalanceRepository) ApplyTransaction(userID int32, delta float64, txType models.BalanceTransactionType, orderID, description, idempotencyKey string) (*models.BalanceTransactionResult, error) {
+	args := m.Called(userID, delta, txType, orderID, description, idempotencyKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceTransactionResult), args.Error(1)
+}
+
+func (m *MockBalanceRepository) SetBalance(userID int32, amount float64, description string) (*models.BalanceTransactionResult, error) {
+	args := m.Called(userID, amount, description)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.BalanceTransactionResult), args.Error(1)
+}
+
+type MockTwoFactorRepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepository) Get(userID int32) (*models.TwoFactorAuth, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorAuth), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) Begin(userID int32, secret string, recoveryCodeHashes []string) error {
+	args := m.Called(userID, secret, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) Enable(userID int32, step int64) error {
+	args := m.Called(userID, step)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) Delete(userID int32) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) UseStep(userID int32, step int64) (bool, error) {
+	args := m.Called(userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) UseRecoveryCode(userID int32, codeHash string) (bool, error) {
+	args := m.Called(userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) ReplaceRecoveryCodes(userID int32, codeHashes []string) error {
+	args := m.Called(userID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepository) CountRecoveryCodes(userID int32) (int64, error) {
+	args := m.Called(userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) GetPolicy(role models.UserRole) (*models.TwoFactorPolicy, error) {
+	args := m.Called(role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorPolicy), args.Error(1)
+}
+
+func (m *MockTwoFactorRepository) SavePolicy(policy *models.TwoFactorPolicy) error {
+	args := m.Called(policy)
+	return args.Error(0)
+}
+
+type MockSecurityEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockSecurityEventRepository) Create(event *models.SecurityEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockSecurityEventRepository) ListByUser(userID int32, limit int) ([]models.SecurityEvent, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]models.SecurityEvent), args.Error(1)
+}
+
+type MockSigningKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockSigningKeyRepository) ListPublished(now time.Time) ([]models.SigningKey, error) {
+	args := m.Called(now)
+	return args.Get(0).([]models.SigningKey), args.Error(1)
+}
+
+func (m *MockSigningKeyRepository) Rotate(currentKID string, next *models.SigningKey, retiredUntil time.Time) error {
+	args := m.Called(currentKID, next, retiredUntil)
+	return args.Error(0)
+}
+
+func (m *MockSigningKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	args := m.Called(now)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockAccountTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockAccountTokenRepository) Replace(token *models.AccountToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockAccountTokenRepository) GetLatest(userID int32, purpose models.AccountTokenPurpose) (*models.AccountToken, error) {
+	args := m.Called(userID, purpose)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountToken), args.Error(1)
+}
+
+func (m *MockAccountTokenRepository) Use(tokenHash string, purpose models.AccountTokenPurpose, now time.Time) (*models.AccountToken, error) {
+	args := m.Called(tokenHash, purpose, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountToken), args.Error(1)
+}
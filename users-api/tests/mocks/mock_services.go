@@ -3,6 +3,7 @@ package mocks
 import (
 	"github.com/stretchr/testify/mock"
 	"users-api/internal/models"
+	"users-api/pkg/mailer"
 )
 
 type MockTokenService struct {
@@ -43,6 +44,32 @@ func (m *MockTokenService) RevokeAllUserTokens(userID int32) error {
 	return args.Error(0)
 }
 
+func (m *MockTokenService) GenerateChallengeToken(user *models.User) (string, error) {
+	args := m.Called(user)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTokenService) ValidateChallengeToken(tokenString string) (*models.ChallengeClaims, error) {
+	args := m.Called(tokenString)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChallengeClaims), args.Error(1)
+}
+
+func (m *MockTokenService) GenerateServiceToken(service string, scopes []string) (string, error) {
+	args := m.Called(service, scopes)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTokenService) ValidateServiceToken(tokenString string) (*models.ServiceClaims, error) {
+	args := m.Called(tokenString)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServiceClaims), args.Error(1)
+}
+
 type MockUserService struct {
 	mock.Mock
 }
@@ -122,8 +149,8 @@ func (m *MockAuthService) Authenticate(email, password, ipAddress, userAgent str
 	return args.Get(0).(*models.AuthResponse), args.Error(1)
 }
 
-func (m *MockAuthService) RefreshToken(refreshToken string) (*models.TokenPair, error) {
-	args := m.Called(refreshToken)
+func (m *MockAuthService) RefreshToken(refreshToken, ipAddress, userAgent string) (*models.TokenPair, error) {
+	args := m.Called(refreshToken, ipAddress, userAgent)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -143,4 +170,95 @@ func (m *MockAuthService) LogoutAll(userID int32) error {
 func (m *MockAuthService) IsRateLimited(email string) (bool, error) {
 	args := m.Called(email)
 	return args.Bool(0), args.Error(1)
-}
\ No newline at end of file
+}
+
+func (m *MockAuthService) VerifyTwoFactor(challengeToken, code, ipAddress, userAgent string) (*models.AuthResponse, error) {
+	args := m.Called(challengeToken, code, ipAddress, userAgent)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuthResponse), args.Error(1)
+}
+
+func (m *MockAuthService) BeginTwoFactorEnrollment(challengeToken string) (*models.TwoFactorEnrollment, error) {
+	args := m.Called(challengeToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorEnrollment), args.Error(1)
+}
+
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) GetStatus(userID int32) (*models.TwoFactorStatus, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorStatus), args.Error(1)
+}
+
+func (m *MockTwoFactorService) BeginEnrollment(userID int32) (*models.TwoFactorEnrollment, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorEnrollment), args.Error(1)
+}
+
+func (m *MockTwoFactorService) ConfirmEnrollment(userID int32, code string) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) Disable(userID int32, password, code string) error {
+	args := m.Called(userID, password, code)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) RegenerateRecoveryCodes(userID int32, code string) ([]string, error) {
+	args := m.Called(userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockTwoFactorService) LoginStep(user *models.User) (models.TwoFactorStep, error) {
+	args := m.Called(user)
+	return args.Get(0).(models.TwoFactorStep), args.Error(1)
+}
+
+func (m *MockTwoFactorService) VerifyLogin(userID int32, code string) error {
+	args := m.Called(userID, code)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorService) ListPolicies() ([]models.TwoFactorPolicy, error) {
+	args := m.Called()
+	return args.Get(0).([]models.TwoFactorPolicy), args.Error(1)
+}
+
+func (m *MockTwoFactorService) SetPolicy(role models.UserRole, required bool, adminID int32) (*models.TwoFactorPolicy, error) {
+	args := m.Called(role, required, adminID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorPolicy), args.Error(1)
+}
+
+func (m *MockAuthService) ListSecurityEvents(userID int32, limit int) ([]models.SecurityEvent, error) {
+	args := m.Called(userID, limit)
+	return args.Get(0).([]models.SecurityEvent), args.Error(1)
+}
+
+type MockMailer struct {
+	mock.Mock
+}
+
+func (m *MockMailer) Send(msg *mailer.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
@@ -0,0 +1,220 @@
+package unit
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"users-api/internal/models"
+	"users-api/internal/services"
+	"users-api/pkg/mailer"
+	"users-api/pkg/utils"
+	"users-api/tests/mocks"
+)
+
+type accountServiceMocks struct {
+	userRepo         *mocks.MockUserRepository
+	accountTokenRepo *mocks.MockAccountTokenRepository
+	tokenService     *mocks.MockTokenService
+	mailer           *mocks.MockMailer
+}
+
+func newTestAccountService() (services.AccountService, *accountServiceMocks) {
+	m := &accountServiceMocks{
+		userRepo:         new(mocks.MockUserRepository),
+		accountTokenRepo: new(mocks.MockAccountTokenRepository),
+		tokenService:     new(mocks.MockTokenService),
+		mailer:           new(mocks.MockMailer),
+	}
+	config := models.NewAccountConfig()
+	config.BaseURL = "https://cryptosim.test/"
+
+	return services.NewAccountService(m.userRepo, m.accountTokenRepo, m.tokenService, m.mailer, config), m
+}
+
+// tokenFromLink returns the token of the link in an account email
+func tokenFromLink(t *testing.T, body, path string) string {
+	start := strings.Index(body, "https://cryptosim.test"+path+"?token=")
+	require.NotEqual(t, -1, start, "no %s link in %q", path, body)
+
+	link := strings.Fields(body[start:])[0]
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	return parsed.Query().Get("token")
+}
+
+func TestAccountService_RequestPasswordReset(t *testing.T) {
+	user := &models.User{ID: 7, Username: "testuser", Email: "test@example.com", IsActive: true}
+
+	t.Run("emails a link whose token hash is stored", func(t *testing.T) {
+		service, m := newTestAccountService()
+
+		var stored *models.AccountToken
+		var sent *mailer.Message
+		m.userRepo.On("GetByEmail", "test@example.com").Return(user, nil).Once()
+		m.accountTokenRepo.On("GetLatest", int32(7), models.AccountTokenPasswordReset).Return(nil, nil).Once()
+		m.accountTokenRepo.On("Replace", mock.AnythingOfType("*models.AccountToken")).
+			Run(func(args mock.Arguments) { stored = args.Get(0).(*models.AccountToken) }).Return(nil).Once()
+		m.mailer.On("Send", mock.AnythingOfType("*mailer.Message")).
+			Run(func(args mock.Arguments) { sent = args.Get(0).(*mailer.Message) }).Return(nil).Once()
+
+		err := service.RequestPasswordReset(" Test@Example.com ")
+
+		assert.NoError(t, err)
+		require.NotNil(t, sent)
+		assert.Equal(t, "test@example.com", sent.To)
+		assert.Contains(t, sent.Body, "1 hour")
+
+		token := tokenFromLink(t, sent.Body, "/reset-password")
+		assert.Equal(t, utils.HashToken(token), stored.TokenHash)
+		assert.NotContains(t, stored.TokenHash, token)
+		assert.Equal(t, models.AccountTokenPasswordReset, stored.Purpose)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Minute)
+	})
+
+	t.Run("unknown address is not revealed", func(t *testing.T) {
+		service, m := newTestAccountService()
+		m.userRepo.On("GetByEmail", "nobody@example.com").Return(nil, fmt.Errorf("user not found")).Once()
+
+		err := service.RequestPasswordReset("nobody@example.com")
+
+		assert.NoError(t, err)
+		m.mailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+
+	t.Run("no second email within the resend interval", func(t *testing.T) {
+		service, m := newTestAccountService()
+		m.userRepo.On("GetByEmail", "test@example.com").Return(user, nil).Once()
+		m.accountTokenRepo.On("GetLatest", int32(7), models.AccountTokenPasswordReset).
+			Return(&models.AccountToken{CreatedAt: time.Now().Add(-10 * time.Second)}, nil).Once()
+
+		err := service.RequestPasswordReset("test@example.com")
+
+		assert.NoError(t, err)
+		m.accountTokenRepo.AssertNotCalled(t, "Replace", mock.Anything)
+		m.mailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+
+	t.Run("mail failures are not reported to the caller", func(t *testing.T) {
+		service, m := newTestAccountService()
+		m.userRepo.On("GetByEmail", "test@example.com").Return(user, nil).Once()
+		m.accountTokenRepo.On("GetLatest", int32(7), models.AccountTokenPasswordReset).Return(nil, nil).Once()
+		m.accountTokenRepo.On("Replace", mock.AnythingOfType("*models.AccountToken")).Return(nil).Once()
+		m.mailer.On("Send", mock.AnythingOfType("*mailer.Message")).Return(fmt.Errorf("connection refused")).Once()
+
+		assert.NoError(t, service.RequestPasswordReset("test@example.com"))
+	})
+}
+
+func TestAccountService_ResetPassword(t *testing.T) {
+	user := &models.User{ID: 7, Username: "testuser", Email: "test@example.com", IsActive: true}
+	tokenHash := utils.HashToken("reset-token")
+
+	t.Run("sets the password and signs out every session", func(t *testing.T) {
+		service, m := newTestAccountService()
+		m.accountTokenRepo.On("Use", tokenHash, models.AccountTokenPasswordReset, mock.AnythingOfType("time.Time")).
+			Return(&models.AccountToken{UserID: 7}, nil).Once()
+		m.userRepo.On("GetByID", int32(7)).Return(user, nil).Once()
+		m.userRepo.On("UpdatePassword", int32(7), mock.MatchedBy(func(hash string) bool {
+			return utils.CheckPasswordHash("NewPass123!", hash)
+		})).Return(nil).Once()
+		m.userRepo.On("MarkEmailVerified", int32(7)).Return(nil).Once()
+		m.tokenService.On("RevokeAllUserTokens", int32(7)).Return(nil).Once()
+		m.mailer.On("Send", mock.AnythingOfType("*mailer.Message")).Return(nil).Once()
+
+		err := service.ResetPassword("reset-token", "NewPass123!")
+
+		assert.NoError(t, err)
+		m.userRepo.AssertExpectations(t)
+		m.tokenService.AssertExpectations(t)
+	})
+
+	t.Run("used or expired token", func(t *testing.T) {
+		service, m := newTestAccountService()
+		m.accountTokenRepo.On("Use", tokenHash, models.AccountTokenPasswordReset, mock.AnythingOfType("time.Time")).Return(nil, nil).Once()
+
+		err := service.ResetPassword("reset-token", "NewPass123!")
+
+		assert.EqualError(t, err, "invalid or expired reset token")
+		m.userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+	})
+
+	t.Run("weak password does not use the token", func(t *testing.T) {
+		service, m := newTestAccountService()
+
+		err := service.ResetPassword("reset-token", "weak")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid new password")
+		m.accountTokenRepo.AssertNotCalled(t, "Use", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAccountService_EmailVerification(t *testing.T) {
+	user := &models.User{ID: 7, Username: "testuser", Email: "test@example.com", IsActive: true}
+
+	t.Run("verification link round trip", func(t *testing.T) {
+		service, m := newTestAccountService()
+
+		var sent *mailer.Message
+		m.accountTokenRepo.On("GetLatest", int32(7), models.AccountTokenEmailVerification).Return(nil, nil).Once()
+		m.accountTokenRepo.On("Replace", mock.AnythingOfType("*models.AccountToken")).Return(nil).Once()
+		m.mailer.On("Send", mock.AnythingOfType("*mailer.Message")).
+			Run(func(args mock.Arguments) { sent = args.Get(0).(*mailer.Message) }).Return(nil).Once()
+
+		require.NoError(t, service.SendVerificationEmail(user))
+		token := tokenFromLink(t, sent.Body, "/verify-email")
+
+		m.accountTokenRepo.On("Use", utils.HashToken(token), models.AccountTokenEmailVerification, mock.AnythingOfType("time.Time")).
+			Return(&models.AccountToken{UserID: 7}, nil).Once()
+		m.userRepo.On("MarkEmailVerified", int32(7)).Return(nil).Once()
+
+		assert.NoError(t, service.VerifyEmail(token))
+		m.userRepo.AssertExpectations(t)
+	})
+
+	t.Run("a reset token does not verify the email", func(t *testing.T) {
+		service, m := newTestAccountService()
+		m.accountTokenRepo.On("Use", utils.HashToken("reset-token"), models.AccountTokenEmailVerification, mock.AnythingOfType("time.Time")).Return(nil, nil).Once()
+
+		err := service.VerifyEmail("reset-token")
+
+		assert.EqualError(t, err, "invalid or expired verification token")
+	})
+
+	t.Run("resend skips verified accounts", func(t *testing.T) {
+		service, m := newTestAccountService()
+		verified := &models.User{ID: 8, Email: "verified@example.com", IsActive: true, EmailVerified: true}
+		m.userRepo.On("GetByEmail", "verified@example.com").Return(verified, nil).Once()
+
+		assert.NoError(t, service.ResendVerificationEmail("verified@example.com"))
+		m.mailer.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	m := mailer.NewFileMailer("CryptoSim <no-reply@cryptosim.test>", dir)
+
+	err := m.Send(&mailer.Message{To: "test@example.com\r\nBcc: someone@example.com", Subject: "Verify your email address", Body: "line 1\nline 2"})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), ".eml"))
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Subject: Verify your email address\r\n")
+	assert.Contains(t, string(content), "\r\n\r\nline 1\r\nline 2")
+	// Line breaks in header values cannot add headers
+	assert.NotContains(t, string(content), "\r\nBcc:")
+}
@@ -17,7 +17,8 @@ func TestAuthService_Authenticate(t *testing.T) {
 		mockUserRepo := new(mocks.MockUserRepository)
 		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
 		mockTokenService := new(mocks.MockTokenService)
-		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
 
 		hashedPassword, _ := utils.HashPassword("Test123!")
 		user := &models.User{
@@ -36,6 +37,7 @@ func TestAuthService_Authenticate(t *testing.T) {
 
 		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
 		mockUserRepo.On("GetByEmail", "test@example.com").Return(user, nil).Once()
+		mockTwoFactorService.On("LoginStep", user).Return(models.TwoFactorStepNone, nil).Once()
 		mockTokenService.On("GenerateTokenPair", user).Return(tokenPair, nil).Once()
 		mockUserRepo.On("UpdateLastLogin", int32(1)).Return(nil).Once()
 		mockLoginAttemptRepo.On("Create", mock.AnythingOfType("*models.LoginAttempt")).Return(nil).Once()
@@ -56,7 +58,8 @@ func TestAuthService_Authenticate(t *testing.T) {
 		mockUserRepo := new(mocks.MockUserRepository)
 		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
 		mockTokenService := new(mocks.MockTokenService)
-		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
 
 		mockLoginAttemptRepo.On("CountFailedAttempts", "notfound@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
 		mockUserRepo.On("GetByEmail", "notfound@example.com").Return(nil, fmt.Errorf("user not found")).Once()
@@ -75,7 +78,8 @@ func TestAuthService_Authenticate(t *testing.T) {
 		mockUserRepo := new(mocks.MockUserRepository)
 		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
 		mockTokenService := new(mocks.MockTokenService)
-		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
 
 		hashedPassword, _ := utils.HashPassword("Test123!")
 		user := &models.User{
@@ -103,7 +107,8 @@ func TestAuthService_Authenticate(t *testing.T) {
 		mockUserRepo := new(mocks.MockUserRepository)
 		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
 		mockTokenService := new(mocks.MockTokenService)
-		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
 
 		hashedPassword, _ := utils.HashPassword("Test123!")
 		user := &models.User{
@@ -131,7 +136,8 @@ func TestAuthService_Authenticate(t *testing.T) {
 		mockUserRepo := new(mocks.MockUserRepository)
 		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
 		mockTokenService := new(mocks.MockTokenService)
-		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
 
 		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(5), nil).Once()
 		mockLoginAttemptRepo.On("Create", mock.AnythingOfType("*models.LoginAttempt")).Return(nil).Once()
@@ -145,12 +151,128 @@ func TestAuthService_Authenticate(t *testing.T) {
 	})
 }
 
+func TestAuthService_TwoFactorLogin(t *testing.T) {
+	hashedPassword, _ := utils.HashPassword("Test123!")
+	user := &models.User{
+		ID:           1,
+		Username:     "testuser",
+		Email:        "test@example.com",
+		PasswordHash: hashedPassword,
+		IsActive:     true,
+	}
+
+	t.Run("password step returns a challenge instead of tokens", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
+
+		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+		mockUserRepo.On("GetByEmail", "test@example.com").Return(user, nil).Once()
+		mockTwoFactorService.On("LoginStep", user).Return(models.TwoFactorStepVerify, nil).Once()
+		mockTokenService.On("GenerateChallengeToken", user).Return("challenge_token", nil).Once()
+
+		authResponse, err := service.Authenticate("test@example.com", "Test123!", "192.168.1.1", "Mozilla/5.0")
+
+		assert.NoError(t, err)
+		assert.True(t, authResponse.TwoFactorRequired)
+		assert.False(t, authResponse.EnrollmentRequired)
+		assert.Equal(t, "challenge_token", authResponse.ChallengeToken)
+		assert.Empty(t, authResponse.AccessToken)
+		assert.Empty(t, authResponse.RefreshToken)
+		mockTokenService.AssertNotCalled(t, "GenerateTokenPair", mock.Anything)
+		mockUserRepo.AssertNotCalled(t, "UpdateLastLogin", mock.Anything)
+	})
+
+	t.Run("valid code issues tokens", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
+
+		tokenPair := &models.TokenPair{AccessToken: "access_token", RefreshToken: "refresh_token", ExpiresIn: 3600}
+
+		mockTokenService.On("ValidateChallengeToken", "challenge_token").Return(&models.ChallengeClaims{UserID: 1}, nil).Once()
+		mockUserRepo.On("GetByID", int32(1)).Return(user, nil).Once()
+		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+		mockTwoFactorService.On("VerifyLogin", int32(1), "123456").Return(nil).Once()
+		mockTokenService.On("GenerateTokenPair", user).Return(tokenPair, nil).Once()
+		mockUserRepo.On("UpdateLastLogin", int32(1)).Return(nil).Once()
+		mockLoginAttemptRepo.On("Create", mock.MatchedBy(func(a *models.LoginAttempt) bool { return a.Success })).Return(nil).Once()
+
+		authResponse, err := service.VerifyTwoFactor("challenge_token", "123456", "192.168.1.1", "Mozilla/5.0")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "access_token", authResponse.AccessToken)
+		assert.Equal(t, "refresh_token", authResponse.RefreshToken)
+		mockUserRepo.AssertExpectations(t)
+		mockTokenService.AssertExpectations(t)
+		mockLoginAttemptRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid code counts as a failed attempt", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
+
+		mockTokenService.On("ValidateChallengeToken", "challenge_token").Return(&models.ChallengeClaims{UserID: 1}, nil).Once()
+		mockUserRepo.On("GetByID", int32(1)).Return(user, nil).Once()
+		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+		mockTwoFactorService.On("VerifyLogin", int32(1), "000000").Return(services.ErrInvalidTwoFactorCode).Once()
+		mockLoginAttemptRepo.On("Create", mock.MatchedBy(func(a *models.LoginAttempt) bool { return !a.Success })).Return(nil).Once()
+
+		authResponse, err := service.VerifyTwoFactor("challenge_token", "000000", "192.168.1.1", "Mozilla/5.0")
+
+		assert.Nil(t, authResponse)
+		assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+		mockTokenService.AssertNotCalled(t, "GenerateTokenPair", mock.Anything)
+		mockLoginAttemptRepo.AssertExpectations(t)
+	})
+
+	t.Run("missing enrollment is not a failed attempt", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
+
+		mockTokenService.On("ValidateChallengeToken", "challenge_token").Return(&models.ChallengeClaims{UserID: 1}, nil).Once()
+		mockUserRepo.On("GetByID", int32(1)).Return(user, nil).Once()
+		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+		mockTwoFactorService.On("VerifyLogin", int32(1), "123456").Return(services.ErrTwoFactorNotSetUp).Once()
+
+		authResponse, err := service.VerifyTwoFactor("challenge_token", "123456", "192.168.1.1", "Mozilla/5.0")
+
+		assert.Nil(t, authResponse)
+		assert.ErrorIs(t, err, services.ErrTwoFactorNotSetUp)
+		mockLoginAttemptRepo.AssertNotCalled(t, "Create", mock.Anything)
+		mockTokenService.AssertNotCalled(t, "GenerateTokenPair", mock.Anything)
+	})
+
+	t.Run("expired challenge", func(t *testing.T) {
+		mockTokenService := new(mocks.MockTokenService)
+		service := services.NewAuthService(new(mocks.MockUserRepository), new(mocks.MockLoginAttemptRepository), mockTokenService, new(mocks.MockTwoFactorService), new(mocks.MockSecurityEventRepository), false)
+
+		mockTokenService.On("ValidateChallengeToken", "expired").Return(nil, fmt.Errorf("token is expired")).Once()
+
+		authResponse, err := service.VerifyTwoFactor("expired", "123456", "192.168.1.1", "Mozilla/5.0")
+
+		assert.Nil(t, authResponse)
+		assert.Contains(t, err.Error(), "invalid or expired challenge token")
+	})
+}
+
 func TestAuthService_IsRateLimited(t *testing.T) {
 	mockUserRepo := new(mocks.MockUserRepository)
 	mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
 	mockTokenService := new(mocks.MockTokenService)
+	mockTwoFactorService := new(mocks.MockTwoFactorService)
 
-	service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService)
+	service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
 
 	t.Run("not rate limited", func(t *testing.T) {
 		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(2), nil).Once()
@@ -187,8 +309,9 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	mockUserRepo := new(mocks.MockUserRepository)
 	mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
 	mockTokenService := new(mocks.MockTokenService)
+	mockTwoFactorService := new(mocks.MockTwoFactorService)
 
-	service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService)
+	service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
 
 	t.Run("successful token refresh", func(t *testing.T) {
 		tokenPair := &models.TokenPair{
@@ -199,7 +322,7 @@ func TestAuthService_RefreshToken(t *testing.T) {
 
 		mockTokenService.On("RefreshAccessToken", "refresh_token").Return(tokenPair, nil).Once()
 
-		result, err := service.RefreshToken("refresh_token")
+		result, err := service.RefreshToken("refresh_token", "192.168.1.1", "Mozilla/5.0")
 
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
@@ -211,7 +334,7 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	t.Run("invalid refresh token", func(t *testing.T) {
 		mockTokenService.On("RefreshAccessToken", "invalid_token").Return(nil, fmt.Errorf("invalid refresh token")).Once()
 
-		result, err := service.RefreshToken("invalid_token")
+		result, err := service.RefreshToken("invalid_token", "192.168.1.1", "Mozilla/5.0")
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -220,12 +343,32 @@ func TestAuthService_RefreshToken(t *testing.T) {
 	})
 }
 
+func TestAuthService_RefreshTokenReuse(t *testing.T) {
+	mockTokenService := new(mocks.MockTokenService)
+	mockSecurityEventRepo := new(mocks.MockSecurityEventRepository)
+	service := services.NewAuthService(new(mocks.MockUserRepository), new(mocks.MockLoginAttemptRepository), mockTokenService, new(mocks.MockTwoFactorService), mockSecurityEventRepo, false)
+
+	reuseErr := &models.RefreshTokenReuseError{UserID: 7, FamilyID: "family-1", TokenID: 1}
+	mockTokenService.On("RefreshAccessToken", "stolen_token").Return(nil, reuseErr).Once()
+	mockSecurityEventRepo.On("Create", mock.MatchedBy(func(event *models.SecurityEvent) bool {
+		return event.UserID == 7 && event.Type == models.SecurityEventRefreshTokenReuse &&
+			event.FamilyID == "family-1" && event.IPAddress == "10.0.0.1"
+	})).Return(nil).Once()
+
+	result, err := service.RefreshToken("stolen_token", "10.0.0.1", "curl/8.0")
+
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "revoked")
+	mockSecurityEventRepo.AssertExpectations(t)
+}
+
 func TestAuthService_Logout(t *testing.T) {
 	mockUserRepo := new(mocks.MockUserRepository)
 	mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
 	mockTokenService := new(mocks.MockTokenService)
+	mockTwoFactorService := new(mocks.MockTwoFactorService)
 
-	service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService)
+	service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), false)
 
 	t.Run("successful logout", func(t *testing.T) {
 		mockTokenService.On("RevokeRefreshToken", "refresh_token").Return(nil).Once()
@@ -245,4 +388,60 @@ func TestAuthService_Logout(t *testing.T) {
 		assert.Contains(t, err.Error(), "failed to logout")
 		mockTokenService.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+func TestAuthService_Authenticate_EmailVerification(t *testing.T) {
+	hashedPassword, _ := utils.HashPassword("Test123!")
+
+	t.Run("unverified email is rejected when verification is required", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+		mockTokenService := new(mocks.MockTokenService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, new(mocks.MockTwoFactorService), new(mocks.MockSecurityEventRepository), true)
+
+		user := &models.User{ID: 1, Email: "test@example.com", PasswordHash: hashedPassword, IsActive: true}
+		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+		mockUserRepo.On("GetByEmail", "test@example.com").Return(user, nil).Once()
+
+		authResponse, err := service.Authenticate("test@example.com", "Test123!", "192.168.1.1", "Mozilla/5.0")
+
+		assert.EqualError(t, err, "email address is not verified")
+		assert.Nil(t, authResponse)
+		mockTokenService.AssertNotCalled(t, "GenerateTokenPair", mock.Anything)
+	})
+
+	t.Run("wrong password is reported before the verification", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, new(mocks.MockTokenService), new(mocks.MockTwoFactorService), new(mocks.MockSecurityEventRepository), true)
+
+		user := &models.User{ID: 1, Email: "test@example.com", PasswordHash: hashedPassword, IsActive: true}
+		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+		mockUserRepo.On("GetByEmail", "test@example.com").Return(user, nil).Once()
+		mockLoginAttemptRepo.On("Create", mock.AnythingOfType("*models.LoginAttempt")).Return(nil).Once()
+
+		_, err := service.Authenticate("test@example.com", "Wrong123!", "192.168.1.1", "Mozilla/5.0")
+
+		assert.EqualError(t, err, "invalid email or password")
+	})
+
+	t.Run("verified email logs in", func(t *testing.T) {
+		mockUserRepo := new(mocks.MockUserRepository)
+		mockLoginAttemptRepo := new(mocks.MockLoginAttemptRepository)
+		mockTokenService := new(mocks.MockTokenService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		service := services.NewAuthService(mockUserRepo, mockLoginAttemptRepo, mockTokenService, mockTwoFactorService, new(mocks.MockSecurityEventRepository), true)
+
+		user := &models.User{ID: 1, Email: "test@example.com", PasswordHash: hashedPassword, IsActive: true, EmailVerified: true}
+		mockLoginAttemptRepo.On("CountFailedAttempts", "test@example.com", mock.AnythingOfType("time.Time")).Return(int64(0), nil).Once()
+		mockUserRepo.On("GetByEmail", "test@example.com").Return(user, nil).Once()
+		mockTwoFactorService.On("LoginStep", user).Return(models.TwoFactorStepNone, nil).Once()
+		mockTokenService.On("GenerateTokenPair", user).Return(&models.TokenPair{AccessToken: "access_token"}, nil).Once()
+		mockUserRepo.On("UpdateLastLogin", int32(1)).Return(nil).Once()
+		mockLoginAttemptRepo.On("Create", mock.AnythingOfType("*models.LoginAttempt")).Return(nil).Once()
+
+		authResponse, err := service.Authenticate("test@example.com", "Test123!", "192.168.1.1", "Mozilla/5.0")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "access_token", authResponse.AccessToken)
+	})
+}
@@ -0,0 +1,229 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"users-api/internal/models"
+	"users-api/internal/services"
+	"users-api/tests/mocks"
+)
+
+func TestBalanceService_HoldFunds(t *testing.T) {
+	mockRepo := new(mocks.MockBalanceRepository)
+	service := services.NewBalanceService(mockRepo)
+
+	t.Run("successful hold rounds amount to cents", func(t *testing.T) {
+		hold := &models.BalanceHold{ID: 1, UserID: 1, OrderID: "order-1", Amount: 150.13, Status: models.HoldStatusActive}
+		mockRepo.On("CreateHold", int32(1), "order-1", 150.13).Return(hold, nil).Once()
+
+		result, err := service.HoldFunds(1, &models.HoldFundsRequest{OrderID: " order-1 ", Amount: 150.129})
+
+		assert.NoError(t, err)
+		assert.Equal(t, hold, result)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("insufficient funds", func(t *testing.T) {
+		mockRepo.On("CreateHold", int32(1), "order-2", 500.0).Return(nil, fmt.Errorf("insufficient funds: required 500.00, available 100.00")).Once()
+
+		result, err := service.HoldFunds(1, &models.HoldFundsRequest{OrderID: "order-2", Amount: 500})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "insufficient funds")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("amount rounds to zero", func(t *testing.T) {
+		result, err := service.HoldFunds(1, &models.HoldFundsRequest{OrderID: "order-3", Amount: 0.001})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "amount must be positive")
+	})
+}
+
+func TestBalanceService_CaptureHold(t *testing.T) {
+	mockRepo := new(mocks.MockBalanceRepository)
+	service := services.NewBalanceService(mockRepo)
+
+	t.Run("successful capture", func(t *testing.T) {
+		hold := &models.BalanceHold{UserID: 1, OrderID: "order-1", Amount: 150, CapturedAmount: 149.5, Status: models.HoldStatusCaptured}
+		mockRepo.On("CaptureHold", int32(1), "order-1", 149.5).Return(hold, nil).Once()
+
+		result, err := service.CaptureHold(1, "order-1", &models.CaptureHoldRequest{Amount: 149.5})
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.HoldStatusCaptured, result.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("hold already released", func(t *testing.T) {
+		mockRepo.On("CaptureHold", int32(1), "order-2", 10.0).Return(nil, fmt.Errorf("hold already released")).Once()
+
+		result, err := service.CaptureHold(1, "order-2", &models.CaptureHoldRequest{Amount: 10})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "already released")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("fill capture keeps the hold active", func(t *testing.T) {
+		hold := &models.BalanceHold{UserID: 1, OrderID: "order-3", Amount: 100, CapturedAmount: 50.25, Status: models.HoldStatusActive}
+		mockRepo.On("CapturePartialHold", int32(1), "order-3", "order-3-1", 50.25).Return(hold, nil).Once()
+
+		result, err := service.CaptureHold(1, "order-3", &models.CaptureHoldRequest{Amount: 50.249, FillID: "order-3-1"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.HoldStatusActive, result.Status)
+		mockRepo.AssertNotCalled(t, "CaptureHold", int32(1), "order-3", 50.25)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestBalanceService_AdjustHold(t *testing.T) {
+	mockRepo := new(mocks.MockBalanceRepository)
+	service := services.NewBalanceService(mockRepo)
+
+	t.Run("resizes hold", func(t *testing.T) {
+		hold := &models.BalanceHold{UserID: 1, OrderID: "order-1", Amount: 200.5, Status: models.HoldStatusActive}
+		mockRepo.On("AdjustHold", int32(1), "order-1", 200.5).Return(hold, nil).Once()
+
+		result, err := service.AdjustHold(1, "order-1", &models.AdjustHoldRequest{Amount: 200.499})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 200.5, result.Amount)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("hold already captured", func(t *testing.T) {
+		mockRepo.On("AdjustHold", int32(1), "order-2", 50.0).Return(nil, fmt.Errorf("hold already captured")).Once()
+
+		result, err := service.AdjustHold(1, "order-2", &models.AdjustHoldRequest{Amount: 50})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "already captured")
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestBalanceService_ReleaseHold(t *testing.T) {
+	mockRepo := new(mocks.MockBalanceRepository)
+	service := services.NewBalanceService(mockRepo)
+
+	hold := &models.BalanceHold{UserID: 1, OrderID: "order-1", Amount: 150, Status: models.HoldStatusReleased}
+	mockRepo.On("ReleaseHold", int32(1), "order-1").Return(hold, nil).Once()
+
+	result, err := service.ReleaseHold(1, "order-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.HoldStatusReleased, result.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUser_AvailableBalance(t *testing.T) {
+	user := &models.User{InitialBalance: 1000, ReservedBalance: 250.25}
+
+	assert.Equal(t, 749.75, user.AvailableBalance())
+}
+
+func TestBalanceService_ApplyTransaction(t *testing.T) {
+	mockRepo := new(mocks.MockBalanceRepository)
+	service := services.NewBalanceService(mockRepo)
+
+	t.Run("successful debit", func(t *testing.T) {
+		expected := &models.BalanceTransactionResult{TransactionID: "btx_1", UserID: 1, Type: models.TransactionTypeBuy, Delta: -100.5, BalanceAfter: 899.5}
+		mockRepo.On("ApplyTransaction", int32(1), -100.5, models.TransactionTypeBuy, "order-1", "Buy BTC", "").Return(expected, nil).Once()
+
+		result, err := service.ApplyTransaction(1, &models.ApplyTransactionRequest{
+			Type:        models.TransactionTypeBuy,
+			Delta:       -100.499,
+			OrderID:     "order-1",
+			Description: "Buy BTC",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "btx_1", result.TransactionID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("replayed idempotency key", func(t *testing.T) {
+		expected := &models.BalanceTransactionResult{TransactionID: "btx_3", UserID: 1, Type: models.TransactionTypeSell, Delta: 250, Replayed: true}
+		mockRepo.On("ApplyTransaction", int32(1), 250.0, models.TransactionTypeSell, "order-3", "", "order-3-sell").Return(expected, nil).Once()
+
+		result, err := service.ApplyTransaction(1, &models.ApplyTransactionRequest{
+			Type:           models.TransactionTypeSell,
+			Delta:          250,
+			OrderID:        "order-3",
+			IdempotencyKey: " order-3-sell ",
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, result.Replayed)
+		assert.Equal(t, "btx_3", result.TransactionID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("delta sign must match type", func(t *testing.T) {
+		result, err := service.ApplyTransaction(1, &models.ApplyTransactionRequest{Type: models.TransactionTypeSell, Delta: -10})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "positive delta")
+	})
+
+	t.Run("negative balance is rejected", func(t *testing.T) {
+		mockRepo.On("ApplyTransaction", int32(1), -5000.0, models.TransactionTypeWithdrawal, "", "", "").
+			Return(nil, fmt.Errorf("insufficient funds: required 5000.00, available 100.00")).Once()
+
+		result, err := service.ApplyTransaction(1, &models.ApplyTransactionRequest{Type: models.TransactionTypeWithdrawal, Delta: -5000})
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		assert.Contains(t, err.Error(), "insufficient funds")
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestBalanceService_SetBalance(t *testing.T) {
+	mockRepo := new(mocks.MockBalanceRepository)
+	service := services.NewBalanceService(mockRepo)
+
+	t.Run("negative amount", func(t *testing.T) {
+		result, err := service.SetBalance(1, -1)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("records adjustment", func(t *testing.T) {
+		expected := &models.BalanceTransactionResult{TransactionID: "btx_2", Type: models.TransactionTypeAdjustment, BalanceAfter: 500}
+		mockRepo.On("SetBalance", int32(1), 500.0, mock.AnythingOfType("string")).Return(expected, nil).Once()
+
+		result, err := service.SetBalance(1, 500)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.TransactionTypeAdjustment, result.Type)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestBalanceTransaction_Delta(t *testing.T) {
+	debit := &models.BalanceTransaction{EntryType: models.EntryTypeDebit, Amount: 10.5}
+	credit := &models.BalanceTransaction{EntryType: models.EntryTypeCredit, Amount: 10.5}
+
+	assert.Equal(t, -10.5, debit.Delta())
+	assert.Equal(t, 10.5, credit.Delta())
+}
+
+func TestCounterpartAccount(t *testing.T) {
+	assert.Equal(t, models.AccountSystemTrading, models.CounterpartAccount(models.TransactionTypeBuy))
+	assert.Equal(t, models.AccountSystemCash, models.CounterpartAccount(models.TransactionTypeDeposit))
+	assert.Equal(t, models.AccountSystemAdjustments, models.CounterpartAccount(models.TransactionTypeAdjustment))
+	assert.Equal(t, "user:7", models.UserAccount(7))
+}
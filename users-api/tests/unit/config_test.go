@@ -0,0 +1,34 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"users-api/internal/config"
+	"users-api/pkg/mailer"
+)
+
+func TestConfig_CheckMail(t *testing.T) {
+	newConfig := func(env, driver string) *config.Config {
+		return &config.Config{Server: config.ServerConfig{Env: env}, Mail: mailer.Config{Driver: driver}}
+	}
+
+	t.Run("development falls back to the log driver", func(t *testing.T) {
+		cfg := newConfig("development", "")
+
+		assert.NoError(t, cfg.CheckMail())
+		assert.Equal(t, "log", cfg.Mail.Driver)
+	})
+
+	t.Run("production requires a driver", func(t *testing.T) {
+		err := newConfig("production", "").CheckMail()
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "MAIL_DRIVER is required in production")
+	})
+
+	t.Run("log driver can still be chosen explicitly", func(t *testing.T) {
+		assert.NoError(t, newConfig("production", "log").CheckMail())
+		assert.NoError(t, newConfig("staging", "smtp").CheckMail())
+	})
+}
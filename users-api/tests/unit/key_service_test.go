@@ -0,0 +1,138 @@
+package unit
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"users-api/internal/models"
+	"users-api/internal/repositories"
+	"users-api/internal/services"
+	"users-api/tests/mocks"
+)
+
+// newTestKeyService returns a key service loaded with a single active key of algorithm
+func newTestKeyService(t *testing.T, algorithm models.SigningAlgorithm) services.KeyService {
+	key, err := services.GenerateSigningKey(algorithm)
+	assert.NoError(t, err)
+
+	mockSigningKeyRepo := new(mocks.MockSigningKeyRepository)
+	mockSigningKeyRepo.On("ListPublished", mock.AnythingOfType("time.Time")).Return([]models.SigningKey{*key}, nil)
+
+	jwtConfig := models.NewJWTConfig()
+	jwtConfig.SigningAlgorithm = algorithm
+	keyService := services.NewKeyService(jwtConfig, mockSigningKeyRepo)
+	assert.NoError(t, keyService.Refresh())
+	return keyService
+}
+
+func TestKeyService_Refresh(t *testing.T) {
+	t.Run("creates the first key", func(t *testing.T) {
+		mockSigningKeyRepo := new(mocks.MockSigningKeyRepository)
+		keyService := services.NewKeyService(models.NewJWTConfig(), mockSigningKeyRepo)
+
+		created, _ := services.GenerateSigningKey(models.SigningAlgorithmRS256)
+		mockSigningKeyRepo.On("ListPublished", mock.AnythingOfType("time.Time")).Return([]models.SigningKey{}, nil).Once()
+		mockSigningKeyRepo.On("Rotate", "", mock.MatchedBy(func(next *models.SigningKey) bool {
+			return next.Algorithm == models.SigningAlgorithmRS256 && next.KID != "" && next.PrivateKey != ""
+		}), mock.AnythingOfType("time.Time")).Return(nil).Once()
+		mockSigningKeyRepo.On("ListPublished", mock.AnythingOfType("time.Time")).Return([]models.SigningKey{*created}, nil).Once()
+
+		assert.NoError(t, keyService.Refresh())
+
+		key, err := keyService.SigningKey()
+		assert.NoError(t, err)
+		assert.Equal(t, created.KID, key.KID)
+		assert.Equal(t, jwt.SigningMethodRS256, key.Method)
+		mockSigningKeyRepo.AssertExpectations(t)
+	})
+
+	t.Run("rotates a key older than the rotation interval", func(t *testing.T) {
+		mockSigningKeyRepo := new(mocks.MockSigningKeyRepository)
+		jwtConfig := models.NewJWTConfig()
+		keyService := services.NewKeyService(jwtConfig, mockSigningKeyRepo)
+
+		old, _ := services.GenerateSigningKey(models.SigningAlgorithmRS256)
+		old.CreatedAt = time.Now().Add(-jwtConfig.KeyRotationInterval - time.Hour)
+
+		next, _ := services.GenerateSigningKey(models.SigningAlgorithmRS256)
+		retired := *old
+		retired.Status = models.SigningKeyRetired
+
+		mockSigningKeyRepo.On("ListPublished", mock.AnythingOfType("time.Time")).Return([]models.SigningKey{*old}, nil).Once()
+		mockSigningKeyRepo.On("Rotate", old.KID, mock.AnythingOfType("*models.SigningKey"), mock.MatchedBy(func(retiredUntil time.Time) bool {
+			// Published at least as long as the tokens the old key signed are valid
+			return retiredUntil.After(time.Now().Add(jwtConfig.AccessTokenTTL))
+		})).Return(nil).Once()
+		mockSigningKeyRepo.On("ListPublished", mock.AnythingOfType("time.Time")).Return([]models.SigningKey{*next, retired}, nil).Once()
+
+		assert.NoError(t, keyService.Refresh())
+
+		key, _ := keyService.SigningKey()
+		assert.Equal(t, next.KID, key.KID)
+
+		// The retired key still verifies and is still published
+		_, err := keyService.VerificationKey(old.KID)
+		assert.NoError(t, err)
+		assert.Len(t, keyService.JWKS().Keys, 2)
+		mockSigningKeyRepo.AssertExpectations(t)
+	})
+
+	t.Run("another instance rotated first", func(t *testing.T) {
+		mockSigningKeyRepo := new(mocks.MockSigningKeyRepository)
+		keyService := services.NewKeyService(models.NewJWTConfig(), mockSigningKeyRepo)
+
+		theirs, _ := services.GenerateSigningKey(models.SigningAlgorithmRS256)
+		mockSigningKeyRepo.On("ListPublished", mock.AnythingOfType("time.Time")).Return([]models.SigningKey{}, nil).Once()
+		mockSigningKeyRepo.On("Rotate", "", mock.AnythingOfType("*models.SigningKey"), mock.AnythingOfType("time.Time")).
+			Return(repositories.ErrSigningKeyRotated).Once()
+		mockSigningKeyRepo.On("ListPublished", mock.AnythingOfType("time.Time")).Return([]models.SigningKey{*theirs}, nil).Once()
+
+		assert.NoError(t, keyService.Refresh())
+
+		key, _ := keyService.SigningKey()
+		assert.Equal(t, theirs.KID, key.KID)
+	})
+}
+
+func TestKeyService_JWKS(t *testing.T) {
+	t.Run("RSA", func(t *testing.T) {
+		keyService := newTestKeyService(t, models.SigningAlgorithmRS256)
+		key, _ := keyService.SigningKey()
+
+		jwks := keyService.JWKS()
+		assert.Len(t, jwks.Keys, 1)
+
+		jwk := jwks.Keys[0]
+		assert.Equal(t, "RSA", jwk.Kty)
+		assert.Equal(t, "RS256", jwk.Alg)
+		assert.Equal(t, "sig", jwk.Use)
+		assert.Equal(t, key.KID, jwk.Kid)
+
+		n, _ := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, _ := base64.RawURLEncoding.DecodeString(jwk.E)
+		publicKey := key.PublicKey.(*rsa.PublicKey)
+		assert.Equal(t, 0, publicKey.N.Cmp(new(big.Int).SetBytes(n)))
+		assert.Equal(t, publicKey.E, int(new(big.Int).SetBytes(e).Int64()))
+	})
+
+	t.Run("Ed25519", func(t *testing.T) {
+		keyService := newTestKeyService(t, models.SigningAlgorithmEdDSA)
+		key, _ := keyService.SigningKey()
+
+		jwk := keyService.JWKS().Keys[0]
+		assert.Equal(t, "OKP", jwk.Kty)
+		assert.Equal(t, "Ed25519", jwk.Crv)
+		assert.Equal(t, "EdDSA", jwk.Alg)
+
+		x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+		assert.Equal(t, key.PublicKey.(ed25519.PublicKey), ed25519.PublicKey(x))
+		assert.Empty(t, jwk.N)
+	})
+}
@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"users-api/internal/middleware"
+	"users-api/internal/models"
+	"users-api/internal/services"
+	"users-api/pkg/utils"
+	"users-api/tests/mocks"
+)
+
+func newTestServiceAuth(t *testing.T) (services.ServiceAuthService, services.TokenService) {
+	tokenService := services.NewTokenService(models.NewJWTConfig(), new(mocks.MockRefreshTokenRepository), newTestKeyService(t, models.SigningAlgorithmEdDSA))
+	clients := []models.ServiceClient{
+		{Name: "orders-api", SecretHash: utils.HashToken("orders-secret"), Scopes: []string{models.ScopeUserVerify, models.ScopeBalanceRead, models.ScopeBalanceWrite}},
+		{Name: "portfolio-api", SecretHash: utils.HashToken("portfolio-secret"), Scopes: []string{models.ScopeBalanceRead}},
+	}
+	return services.NewServiceAuthService(clients, 15*time.Minute, tokenService), tokenService
+}
+
+func TestServiceAuthService_IssueToken(t *testing.T) {
+	service, tokenService := newTestServiceAuth(t)
+
+	t.Run("all scopes of the service", func(t *testing.T) {
+		token, err := service.IssueToken("orders-api", "orders-secret", "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer", token.TokenType)
+		assert.Equal(t, int64(900), token.ExpiresIn)
+		assert.Equal(t, "user:verify balance:read balance:write", token.Scope)
+
+		claims, err := tokenService.ValidateServiceToken(token.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, "orders-api", claims.Service)
+		assert.True(t, claims.HasScope(models.ScopeBalanceWrite))
+	})
+
+	t.Run("requested scopes only", func(t *testing.T) {
+		token, err := service.IssueToken("orders-api", "orders-secret", "balance:read")
+
+		assert.NoError(t, err)
+		claims, err := tokenService.ValidateServiceToken(token.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{models.ScopeBalanceRead}, claims.Scopes())
+	})
+
+	t.Run("secret of another service", func(t *testing.T) {
+		token, err := service.IssueToken("portfolio-api", "orders-secret", "")
+
+		assert.EqualError(t, err, "invalid service credentials")
+		assert.Nil(t, token)
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		token, err := service.IssueToken("wallet-api", "", "")
+
+		assert.EqualError(t, err, "invalid service credentials")
+		assert.Nil(t, token)
+	})
+
+	t.Run("scope the service does not have", func(t *testing.T) {
+		token, err := service.IssueToken("portfolio-api", "portfolio-secret", "balance:read balance:write")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not allowed")
+		assert.Nil(t, token)
+	})
+}
+
+func TestTokenService_ServiceToken(t *testing.T) {
+	_, tokenService := newTestServiceAuth(t)
+	user := &models.User{ID: 7, Username: "testuser", Email: "test@example.com", Role: models.RoleAdmin}
+
+	t.Run("service token is not an access token", func(t *testing.T) {
+		token, _ := tokenService.GenerateServiceToken("orders-api", []string{models.ScopeBalanceWrite})
+
+		claims, err := tokenService.ValidateAccessToken(token)
+
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("holdings scopes are only valid for portfolio-api", func(t *testing.T) {
+		token, _ := tokenService.GenerateServiceToken("orders-api", []string{models.ScopeHoldingsRead, models.ScopeHoldingsWrite})
+
+		claims, err := tokenService.ValidateServiceToken(token)
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+
+		unverified := &models.ServiceClaims{}
+		_, _, err = jwt.NewParser().ParseUnverified(token, unverified)
+		assert.NoError(t, err)
+		assert.Equal(t, jwt.ClaimStrings{models.PortfolioServiceTokenAudience}, unverified.Audience)
+	})
+
+	t.Run("token for both services", func(t *testing.T) {
+		token, _ := tokenService.GenerateServiceToken("orders-api", []string{models.ScopeBalanceWrite, models.ScopeHoldingsWrite})
+
+		claims, err := tokenService.ValidateServiceToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, jwt.ClaimStrings{models.ServiceTokenAudience, models.PortfolioServiceTokenAudience}, claims.Audience)
+	})
+
+	t.Run("challenge token is not a service token", func(t *testing.T) {
+		challenge, _ := tokenService.GenerateChallengeToken(user)
+
+		claims, err := tokenService.ValidateServiceToken(challenge)
+
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+}
+
+func TestServiceAuthMiddleware_RequireScopes(t *testing.T) {
+	_, tokenService := newTestServiceAuth(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	internal := router.Group("/api/users")
+	internal.Use(middleware.ServiceAuthMiddleware(tokenService))
+	internal.GET("/:id/balance", middleware.RequireScopes(models.ScopeBalanceRead), func(c *gin.Context) { c.Status(http.StatusOK) })
+	internal.PUT("/:id/balance", middleware.RequireScopes(models.ScopeBalanceWrite), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	readToken, _ := tokenService.GenerateServiceToken("portfolio-api", []string{models.ScopeBalanceRead})
+
+	mockRefreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+	mockRefreshTokenRepo.On("Create", mock.AnythingOfType("*models.RefreshToken")).Return(nil)
+	userTokens, _ := services.NewTokenService(models.NewJWTConfig(), mockRefreshTokenRepo, newTestKeyService(t, models.SigningAlgorithmEdDSA)).
+		GenerateTokenPair(&models.User{ID: 1, Role: models.RoleAdmin})
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		status        int
+	}{
+		{"no token", http.MethodGet, "", http.StatusUnauthorized},
+		{"old shared API key", http.MethodGet, "internal-secret-key", http.StatusUnauthorized},
+		{"user access token", http.MethodGet, "Bearer " + userTokens.AccessToken, http.StatusUnauthorized},
+		{"scope granted", http.MethodGet, "Bearer " + readToken, http.StatusOK},
+		{"scope missing", http.MethodPut, "Bearer " + readToken, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/users/7/balance", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
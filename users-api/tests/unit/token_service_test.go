@@ -0,0 +1,196 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"users-api/internal/models"
+	"users-api/internal/repositories"
+	"users-api/internal/services"
+	"users-api/tests/mocks"
+)
+
+func TestTokenService_ChallengeToken(t *testing.T) {
+	mockRefreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+	service := services.NewTokenService(models.NewJWTConfig(), mockRefreshTokenRepo, newTestKeyService(t, models.SigningAlgorithmRS256))
+
+	user := &models.User{ID: 7, Username: "testuser", Email: "test@example.com", Role: models.RoleAdmin}
+
+	t.Run("round trip", func(t *testing.T) {
+		challenge, err := service.GenerateChallengeToken(user)
+		assert.NoError(t, err)
+
+		claims, err := service.ValidateChallengeToken(challenge)
+		assert.NoError(t, err)
+		assert.Equal(t, int32(7), claims.UserID)
+	})
+
+	t.Run("challenge token is not an access token", func(t *testing.T) {
+		challenge, _ := service.GenerateChallengeToken(user)
+
+		claims, err := service.ValidateAccessToken(challenge)
+
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("access token is not a challenge token", func(t *testing.T) {
+		mockRefreshTokenRepo.On("Create", mock.AnythingOfType("*models.RefreshToken")).Return(nil).Once()
+
+		pair, err := service.GenerateTokenPair(user)
+		assert.NoError(t, err)
+
+		claims, err := service.ValidateChallengeToken(pair.AccessToken)
+
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+}
+
+func TestTokenService_ValidateAccessToken(t *testing.T) {
+	user := &models.User{ID: 7, Username: "testuser", Email: "test@example.com", Role: models.RoleNormal}
+
+	for _, algorithm := range []models.SigningAlgorithm{models.SigningAlgorithmRS256, models.SigningAlgorithmEdDSA} {
+		t.Run(string(algorithm)+" round trip", func(t *testing.T) {
+			mockRefreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+			keyService := newTestKeyService(t, algorithm)
+			service := services.NewTokenService(models.NewJWTConfig(), mockRefreshTokenRepo, keyService)
+
+			mockRefreshTokenRepo.On("Create", mock.AnythingOfType("*models.RefreshToken")).Return(nil).Once()
+			pair, err := service.GenerateTokenPair(user)
+			assert.NoError(t, err)
+
+			// Other services pick the JWKS key by the kid header
+			key, _ := keyService.SigningKey()
+			token, _, _ := jwt.NewParser().ParseUnverified(pair.AccessToken, &models.CustomClaims{})
+			assert.Equal(t, key.KID, token.Header["kid"])
+			assert.Equal(t, string(algorithm), token.Header["alg"])
+
+			claims, err := service.ValidateAccessToken(pair.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, int32(7), claims.UserID)
+		})
+	}
+
+	t.Run("HMAC tokens are rejected", func(t *testing.T) {
+		keyService := newTestKeyService(t, models.SigningAlgorithmRS256)
+		service := services.NewTokenService(models.NewJWTConfig(), new(mocks.MockRefreshTokenRepository), keyService)
+
+		key, _ := keyService.SigningKey()
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &models.CustomClaims{
+			UserID:           7,
+			Role:             models.RoleAdmin,
+			RegisteredClaims: jwt.RegisteredClaims{Audience: []string{"cryptosim"}},
+		})
+		token.Header["kid"] = key.KID
+		signed, _ := token.SignedString([]byte("your-super-secret-key"))
+
+		claims, err := service.ValidateAccessToken(signed)
+
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+
+	t.Run("tokens of another key are rejected", func(t *testing.T) {
+		other := services.NewTokenService(models.NewJWTConfig(), new(mocks.MockRefreshTokenRepository), newTestKeyService(t, models.SigningAlgorithmRS256))
+		service := services.NewTokenService(models.NewJWTConfig(), new(mocks.MockRefreshTokenRepository), newTestKeyService(t, models.SigningAlgorithmRS256))
+
+		challenge, err := other.GenerateChallengeToken(user)
+		assert.NoError(t, err)
+
+		claims, err := service.ValidateChallengeToken(challenge)
+
+		assert.Error(t, err)
+		assert.Nil(t, claims)
+	})
+}
+
+func TestTokenService_RefreshAccessToken(t *testing.T) {
+	user := models.User{ID: 7, Username: "testuser", Email: "test@example.com", Role: models.RoleNormal}
+
+	t.Run("rotates within the family", func(t *testing.T) {
+		mockRefreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+		service := services.NewTokenService(models.NewJWTConfig(), mockRefreshTokenRepo, newTestKeyService(t, models.SigningAlgorithmRS256))
+
+		stored := &models.RefreshToken{ID: 1, UserID: 7, Token: "old", FamilyID: "family-1", ExpiresAt: time.Now().Add(time.Hour), User: user}
+		mockRefreshTokenRepo.On("GetByToken", "old").Return(stored, nil).Once()
+		mockRefreshTokenRepo.On("Rotate", stored, mock.MatchedBy(func(next *models.RefreshToken) bool {
+			return next.FamilyID == "family-1" && next.UserID == 7 && next.Token != "old"
+		})).Return(nil).Once()
+
+		pair, err := service.RefreshAccessToken("old")
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, pair.AccessToken)
+		assert.NotEqual(t, "old", pair.RefreshToken)
+		mockRefreshTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("reusing a rotated token revokes the family", func(t *testing.T) {
+		mockRefreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+		service := services.NewTokenService(models.NewJWTConfig(), mockRefreshTokenRepo, newTestKeyService(t, models.SigningAlgorithmRS256))
+
+		stored := &models.RefreshToken{
+			ID: 1, UserID: 7, Token: "old", FamilyID: "family-1", ExpiresAt: time.Now().Add(time.Hour),
+			Revoked: true, RevokedReason: models.RevokeReasonRotated, User: user,
+		}
+		mockRefreshTokenRepo.On("GetByToken", "old").Return(stored, nil).Once()
+		mockRefreshTokenRepo.On("RevokeFamily", "family-1", models.RevokeReasonReuseDetected).Return(int64(1), nil).Once()
+
+		pair, err := service.RefreshAccessToken("old")
+
+		assert.Nil(t, pair)
+		var reuseErr *models.RefreshTokenReuseError
+		assert.True(t, errors.As(err, &reuseErr))
+		assert.Equal(t, int32(7), reuseErr.UserID)
+		assert.Equal(t, "family-1", reuseErr.FamilyID)
+		mockRefreshTokenRepo.AssertNotCalled(t, "Rotate", mock.Anything, mock.Anything)
+		mockRefreshTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("concurrent rotation counts as reuse", func(t *testing.T) {
+		mockRefreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+		service := services.NewTokenService(models.NewJWTConfig(), mockRefreshTokenRepo, newTestKeyService(t, models.SigningAlgorithmRS256))
+
+		stored := &models.RefreshToken{ID: 1, UserID: 7, Token: "old", FamilyID: "family-1", ExpiresAt: time.Now().Add(time.Hour), User: user}
+		mockRefreshTokenRepo.On("GetByToken", "old").Return(stored, nil).Once()
+		mockRefreshTokenRepo.On("Rotate", stored, mock.AnythingOfType("*models.RefreshToken")).Return(repositories.ErrRefreshTokenRevoked).Once()
+		mockRefreshTokenRepo.On("RevokeFamily", "family-1", models.RevokeReasonReuseDetected).Return(int64(1), nil).Once()
+
+		_, err := service.RefreshAccessToken("old")
+
+		var reuseErr *models.RefreshTokenReuseError
+		assert.True(t, errors.As(err, &reuseErr))
+		mockRefreshTokenRepo.AssertExpectations(t)
+	})
+
+	t.Run("logged out token is rejected without revoking the family", func(t *testing.T) {
+		mockRefreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+		service := services.NewTokenService(models.NewJWTConfig(), mockRefreshTokenRepo, newTestKeyService(t, models.SigningAlgorithmRS256))
+
+		stored := &models.RefreshToken{
+			ID: 1, UserID: 7, Token: "old", FamilyID: "family-1", ExpiresAt: time.Now().Add(time.Hour),
+			Revoked: true, RevokedReason: models.RevokeReasonLogout, User: user,
+		}
+		mockRefreshTokenRepo.On("GetByToken", "old").Return(stored, nil).Once()
+
+		_, err := service.RefreshAccessToken("old")
+
+		assert.Contains(t, err.Error(), "refresh token revoked")
+		mockRefreshTokenRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRefreshTokenCleanupJob_RunOnce(t *testing.T) {
+	mockRefreshTokenRepo := new(mocks.MockRefreshTokenRepository)
+	job := services.NewRefreshTokenCleanupJob(mockRefreshTokenRepo, time.Minute)
+
+	mockRefreshTokenRepo.On("DeleteExpired").Return(int64(3), nil).Once()
+
+	assert.Equal(t, int64(3), job.RunOnce())
+	mockRefreshTokenRepo.AssertExpectations(t)
+}
@@ -0,0 +1,235 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"users-api/internal/models"
+	"users-api/internal/services"
+	"users-api/pkg/utils"
+	"users-api/tests/mocks"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 test vectors, base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, v := range vectors {
+		code, err := utils.TOTPCode(rfc6238Secret, utils.TOTPStep(time.Unix(v.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, v.code, code, "time %d", v.unix)
+	}
+
+	t.Run("accepts the previous step", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		code, _ := utils.TOTPCode(rfc6238Secret, utils.TOTPStep(now)-1)
+
+		step, ok := utils.ValidateTOTP(rfc6238Secret, code, now)
+		assert.True(t, ok)
+		assert.Equal(t, utils.TOTPStep(now)-1, step)
+	})
+
+	t.Run("rejects codes outside the window", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		code, _ := utils.TOTPCode(rfc6238Secret, utils.TOTPStep(now)-3)
+
+		_, ok := utils.ValidateTOTP(rfc6238Secret, code, now)
+		assert.False(t, ok)
+	})
+}
+
+func currentTOTP(t *testing.T, secret string) (string, int64) {
+	step := utils.TOTPStep(time.Now())
+	code, err := utils.TOTPCode(secret, step)
+	assert.NoError(t, err)
+	return code, step
+}
+
+func TestTwoFactorService_BeginEnrollment(t *testing.T) {
+	mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+	mockUserRepo := new(mocks.MockUserRepository)
+	service := services.NewTwoFactorService(mockTwoFactorRepo, mockUserRepo, "CryptoSim", false)
+
+	user := &models.User{ID: 1, Email: "test@example.com", Role: models.RoleNormal}
+
+	mockUserRepo.On("GetByID", int32(1)).Return(user, nil).Once()
+	mockTwoFactorRepo.On("Get", int32(1)).Return(nil, nil).Once()
+	mockTwoFactorRepo.On("Begin", int32(1), mock.AnythingOfType("string"), mock.AnythingOfType("[]string")).Return(nil).Once()
+
+	enrollment, err := service.BeginEnrollment(1)
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.ProvisioningURI, "otpauth://totp/CryptoSim:test@example.com?"))
+	assert.Contains(t, enrollment.ProvisioningURI, "secret="+enrollment.Secret)
+	assert.Len(t, enrollment.RecoveryCodes, 10)
+
+	// Only hashes of the recovery codes are stored
+	hashes := mockTwoFactorRepo.Calls[1].Arguments.Get(2).([]string)
+	assert.Equal(t, utils.HashToken(strings.ReplaceAll(enrollment.RecoveryCodes[0], "-", "")), hashes[0])
+	mockTwoFactorRepo.AssertExpectations(t)
+
+	t.Run("already enabled", func(t *testing.T) {
+		mockUserRepo.On("GetByID", int32(1)).Return(user, nil).Once()
+		mockTwoFactorRepo.On("Get", int32(1)).Return(&models.TwoFactorAuth{UserID: 1, Enabled: true}, nil).Once()
+
+		enrollment, err := service.BeginEnrollment(1)
+
+		assert.Nil(t, enrollment)
+		assert.Contains(t, err.Error(), "already enabled")
+	})
+}
+
+func TestTwoFactorService_ConfirmEnrollment(t *testing.T) {
+	secret, _ := utils.GenerateTOTPSecret()
+
+	t.Run("valid code enables 2FA", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", false)
+
+		code, step := currentTOTP(t, secret)
+		mockTwoFactorRepo.On("Get", int32(1)).Return(&models.TwoFactorAuth{UserID: 1, Secret: secret}, nil).Once()
+		mockTwoFactorRepo.On("Enable", int32(1), step).Return(nil).Once()
+
+		err := service.ConfirmEnrollment(1, code)
+
+		assert.NoError(t, err)
+		mockTwoFactorRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", false)
+
+		mockTwoFactorRepo.On("Get", int32(1)).Return(&models.TwoFactorAuth{UserID: 1, Secret: secret}, nil).Once()
+
+		err := service.ConfirmEnrollment(1, "000000x")
+
+		assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+		mockTwoFactorRepo.AssertNotCalled(t, "Enable", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTwoFactorService_VerifyLogin(t *testing.T) {
+	secret, _ := utils.GenerateTOTPSecret()
+	enabled := &models.TwoFactorAuth{UserID: 1, Secret: secret, Enabled: true}
+
+	t.Run("valid code", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", false)
+
+		code, step := currentTOTP(t, secret)
+		mockTwoFactorRepo.On("Get", int32(1)).Return(enabled, nil).Once()
+		mockTwoFactorRepo.On("UseStep", int32(1), step).Return(true, nil).Once()
+
+		assert.NoError(t, service.VerifyLogin(1, code))
+		mockTwoFactorRepo.AssertExpectations(t)
+	})
+
+	t.Run("code already used", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", false)
+
+		code, step := currentTOTP(t, secret)
+		mockTwoFactorRepo.On("Get", int32(1)).Return(enabled, nil).Once()
+		mockTwoFactorRepo.On("UseStep", int32(1), step).Return(false, nil).Once()
+
+		err := service.VerifyLogin(1, code)
+
+		assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", false)
+
+		mockTwoFactorRepo.On("Get", int32(1)).Return(enabled, nil).Once()
+		mockTwoFactorRepo.On("UseRecoveryCode", int32(1), utils.HashToken("abcdefghij")).Return(true, nil).Once()
+
+		assert.NoError(t, service.VerifyLogin(1, "ABCDE-FGHIJ"))
+		mockTwoFactorRepo.AssertExpectations(t)
+	})
+
+	t.Run("pending enrollment is confirmed by the login code", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", true)
+
+		code, step := currentTOTP(t, secret)
+		mockTwoFactorRepo.On("Get", int32(1)).Return(&models.TwoFactorAuth{UserID: 1, Secret: secret}, nil).Once()
+		mockTwoFactorRepo.On("Enable", int32(1), step).Return(nil).Once()
+
+		assert.NoError(t, service.VerifyLogin(1, code))
+		mockTwoFactorRepo.AssertExpectations(t)
+	})
+}
+
+func TestTwoFactorService_LoginStep(t *testing.T) {
+	admin := &models.User{ID: 2, Role: models.RoleAdmin}
+	normal := &models.User{ID: 3, Role: models.RoleNormal}
+
+	t.Run("enabled users verify a code", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", false)
+
+		mockTwoFactorRepo.On("Get", int32(3)).Return(&models.TwoFactorAuth{UserID: 3, Enabled: true}, nil).Once()
+
+		step, err := service.LoginStep(normal)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.TwoFactorStepVerify, step)
+	})
+
+	t.Run("admins must enroll when configured", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", true)
+
+		mockTwoFactorRepo.On("Get", int32(2)).Return(nil, nil).Once()
+		mockTwoFactorRepo.On("GetPolicy", models.RoleAdmin).Return(nil, nil).Once()
+
+		step, err := service.LoginStep(admin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.TwoFactorStepEnroll, step)
+	})
+
+	t.Run("saved policy overrides the configured default", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, new(mocks.MockUserRepository), "CryptoSim", true)
+
+		mockTwoFactorRepo.On("Get", int32(2)).Return(nil, nil).Once()
+		mockTwoFactorRepo.On("GetPolicy", models.RoleAdmin).Return(&models.TwoFactorPolicy{Role: models.RoleAdmin, Required: false}, nil).Once()
+
+		step, err := service.LoginStep(admin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.TwoFactorStepNone, step)
+	})
+}
+
+func TestTwoFactorService_Disable(t *testing.T) {
+	t.Run("not allowed when the role requires 2FA", func(t *testing.T) {
+		mockTwoFactorRepo := new(mocks.MockTwoFactorRepository)
+		mockUserRepo := new(mocks.MockUserRepository)
+		service := services.NewTwoFactorService(mockTwoFactorRepo, mockUserRepo, "CryptoSim", true)
+
+		mockUserRepo.On("GetByID", int32(2)).Return(&models.User{ID: 2, Role: models.RoleAdmin}, nil).Once()
+		mockTwoFactorRepo.On("GetPolicy", models.RoleAdmin).Return(nil, nil).Once()
+
+		err := service.Disable(2, "Test123!", "123456")
+
+		assert.Contains(t, err.Error(), "required for the admin role")
+		mockTwoFactorRepo.AssertNotCalled(t, "Delete", mock.Anything)
+	})
+}